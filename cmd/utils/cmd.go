@@ -202,10 +202,11 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 		}
 	}
 
-	stream := rlp.NewStream(reader, 0)
+	stream := core.NewChainImportStream(reader)
 
 	// Run actual the import.
 	blocks := make(types.Blocks, importBatchSize)
+	receipts := make([]*types.ReceiptForStorage, importBatchSize)
 	n := 0
 
 	for batch := 0; ; batch++ {
@@ -216,8 +217,8 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 
 		i := 0
 		for ; i < importBatchSize; i++ {
-			var b types.Block
-			if err := stream.Decode(&b); err == io.EOF {
+			b, receipt, err := stream.Next()
+			if err == io.EOF {
 				break
 			} else if err != nil {
 				return fmt.Errorf("at block %d: %v", n, err)
@@ -228,7 +229,8 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 				continue
 			}
 
-			blocks[i] = &b
+			blocks[i] = b
+			receipts[i] = receipt
 			n++
 		}
 
@@ -243,9 +245,7 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 		missing := missingBlocks(chain, blocks[:i])
 		if len(missing) == 0 {
 			log.Info("Skipping batch as all blocks present", "batch", batch, "first", blocks[0].Hash(), "last", blocks[i-1].Hash())
-			continue
-		}
-		if failindex, err := chain.InsertChain(missing); err != nil {
+		} else if failindex, err := chain.InsertChain(missing); err != nil {
 			var failnumber uint64
 			if failindex > 0 && failindex < len(missing) {
 				failnumber = missing[failindex].NumberU64()
@@ -254,6 +254,10 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 			}
 			return fmt.Errorf("invalid block %d: %v", failnumber, err)
 		}
+		// Restore the bor receipts of the batch, even if the blocks were present
+		if err := chain.WriteImportedBorReceipts(blocks[:i], receipts[:i]); err != nil {
+			return fmt.Errorf("failed to write bor receipts of batch %d: %v", batch, err)
+		}
 	}
 
 	return nil
@@ -1,18 +1,24 @@
 package bor
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bor/clerk"
 	"github.com/ethereum/go-ethereum/consensus/bor/valset"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 
 	lru "github.com/hashicorp/golang-lru"
@@ -25,12 +31,39 @@ var (
 	MaxCheckpointLength = uint64(math.Pow(2, 15))
 )
 
+// pendingStateSyncsTTL is the time the pending state sync events fetched from heimdall
+// are cached for, to keep the load on heimdall bounded.
+const pendingStateSyncsTTL = 5 * time.Second
+
+// maxPendingStateSyncEvents is the maximum number of pending state sync events returned
+// by a single bor_getPendingStateSyncEvents call.
+const maxPendingStateSyncEvents = 1000
+
 // API is a user facing RPC API to allow controlling the signer and voting
 // mechanisms of the proof-of-authority scheme.
+//
+// Bor specific failures are reported with the following JSON-RPC error codes:
+//
+//	-32800  span not found (SpanNotFoundError)
+//	-32801  no milestone or checkpoint available (FinalityUnavailableError)
+//	-32802  witness not available (WitnessUnavailableError)
+//	-32803  heimdall disabled or unreachable (HeimdallUnreachableError)
+//	-32804  state sync events not indexed (StateSyncNotIndexedError)
 type API struct {
 	chain         consensus.ChainHeaderReader
 	bor           *Bor
 	rootHashCache *lru.ARCCache
+
+	pendingStateSyncs      *pendingStateSyncs // Last pending state sync events fetched from heimdall
+	pendingStateSyncsLock  sync.Mutex
+	pendingStateSyncsLimit int // Maximum number of pending events returned per call, maxPendingStateSyncEvents if zero
+}
+
+// pendingStateSyncs is a cached heimdall response for the pending state sync events.
+type pendingStateSyncs struct {
+	fromID  uint64
+	events  []*clerk.EventRecordWithTime
+	expires time.Time
 }
 
 // GetSnapshot retrieves the state snapshot at a given block.
@@ -271,8 +304,284 @@ func (api *API) GetCurrentValidators() ([]*valset.Validator, error) {
 	return snap.ValidatorSet.Validators, nil
 }
 
-// GetRootHash returns the merkle root of the start to end block headers
-func (api *API) GetRootHash(start uint64, end uint64) (string, error) {
+// SpanValidator is a validator of the span covering a given block.
+type SpanValidator struct {
+	ID               uint64         `json:"id"`
+	Address          common.Address `json:"address"`
+	VotingPower      int64          `json:"votingPower"`
+	ProposerPriority int64          `json:"proposerPriority"`
+	Producer         bool           `json:"producer"` // Whether the validator is a selected producer of the span
+}
+
+// GetValidatorsAtBlock returns the full validator set of the span covering the given
+// block. The span is fetched from heimdall if it isn't cached, so it also works for
+// blocks which aren't imported yet (e.g. during snap sync) as long as their header is.
+func (api *API) GetValidatorsAtBlock(ctx context.Context, number rpc.BlockNumber) ([]*SpanValidator, error) {
+	head := api.chain.CurrentHeader().Number.Uint64()
+
+	var blockNumber uint64
+
+	switch {
+	case number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber:
+		blockNumber = head
+	case number == rpc.EarliestBlockNumber:
+		blockNumber = 0
+	case number < 0:
+		return nil, fmt.Errorf("unsupported block number %d", number)
+	default:
+		blockNumber = uint64(number)
+	}
+
+	if blockNumber > head {
+		return nil, fmt.Errorf("%w: block %d, current head %d", consensus.ErrFutureBlock, blockNumber, head)
+	}
+
+	validators, err := api.bor.spanStore.validatorsByBlockNumber(ctx, blockNumber)
+	if errors.Is(err, errSpanNotFound) {
+		return nil, &SpanNotFoundError{Err: err}
+	} else if err != nil {
+		return nil, &HeimdallUnreachableError{Err: err}
+	}
+
+	producers := make(map[common.Address]struct{}, len(validators.producers))
+	for _, producer := range validators.producers {
+		producers[producer.Address] = struct{}{}
+	}
+
+	result := make([]*SpanValidator, 0, len(validators.validatorSet.Validators))
+
+	for _, val := range validators.validatorSet.Validators {
+		_, producer := producers[val.Address]
+
+		result = append(result, &SpanValidator{
+			ID:               val.ID,
+			Address:          val.Address,
+			VotingPower:      val.VotingPower,
+			ProposerPriority: val.ProposerPriority,
+			Producer:         producer,
+		})
+	}
+
+	return result, nil
+}
+
+// GetSpanConflicts returns the recently detected conflicts between cached spans, i.e.
+// spans claiming overlapping blocks with different producers because heimdall
+// re-committed them, oldest first.
+func (api *API) GetSpanConflicts() []*SpanConflict {
+	conflicts := api.bor.spanStore.conflicts.list()
+	if conflicts == nil {
+		return []*SpanConflict{}
+	}
+
+	return conflicts
+}
+
+// ProducerDelay is the block timing configured for a height, which headers are
+// verified against.
+type ProducerDelay struct {
+	Number           uint64   `json:"number"`
+	SprintStart      bool     `json:"sprintStart"`      // Whether the producer delay applies instead of the period
+	Delay            uint64   `json:"delay"`            // Delay of the primary producer after the parent block, in seconds
+	BackupMultiplier uint64   `json:"backupMultiplier"` // Additional delay per succession of a backup producer, in seconds
+	Successions      []uint64 `json:"successions"`      // Delay after the parent block of every producer, by succession number
+}
+
+// GetProducerDelay returns the effective producer delay of the given block along with
+// the delay of every backup producer. Future heights are supported, with the backup
+// producers taken from the validator set at the current head.
+func (api *API) GetProducerDelay(number rpc.BlockNumber) (*ProducerDelay, error) {
+	head := api.chain.CurrentHeader().Number.Uint64()
+
+	var blockNumber uint64
+
+	switch {
+	case number == rpc.LatestBlockNumber:
+		blockNumber = head
+	case number == rpc.PendingBlockNumber:
+		blockNumber = head + 1
+	case number == rpc.EarliestBlockNumber:
+		blockNumber = 0
+	case number < 0:
+		return nil, fmt.Errorf("unsupported block number %d", number)
+	default:
+		blockNumber = uint64(number)
+	}
+
+	// The producers of a block are the validators of the snapshot at its parent
+	snapNumber := head
+	if blockNumber <= head && blockNumber > 0 {
+		snapNumber = blockNumber - 1
+	}
+
+	rpcSnapNumber := rpc.BlockNumber(snapNumber)
+
+	snap, err := api.GetSnapshot(&rpcSnapNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	successions := make([]uint64, len(snap.ValidatorSet.Validators))
+	for i := range successions {
+		successions[i] = CalcProducerDelay(blockNumber, i, api.bor.config)
+	}
+
+	return &ProducerDelay{
+		Number:           blockNumber,
+		SprintStart:      blockNumber%api.bor.config.CalculateSprint(blockNumber) == 0,
+		Delay:            calcPrimaryDelay(blockNumber, api.bor.config),
+		BackupMultiplier: api.bor.config.CalculateBackupMultiplier(blockNumber),
+		Successions:      successions,
+	}, nil
+}
+
+// PendingStateSyncEvent is a state sync event known to heimdall but not yet committed
+// on bor.
+type PendingStateSyncEvent struct {
+	ID             uint64         `json:"id"`
+	Contract       common.Address `json:"contract"`
+	DataSize       int            `json:"dataSize"`
+	TxHash         common.Hash    `json:"txHash"`         // Hash of the L1 transaction emitting the event
+	Time           time.Time      `json:"time"`           // Time the event was recorded on heimdall
+	EstimatedBlock uint64         `json:"estimatedBlock"` // Sprint start block the event is expected to be committed in
+}
+
+// GetPendingStateSyncEvents returns the state sync events with an id greater than the
+// last one committed at the current head, along with a best-effort estimate of the
+// block committing them. The estimate assumes the configured block period and accounts
+// for the state sync delay and the maximum number of events per block.
+//
+// At most maxPendingStateSyncEvents events are returned per call, starting from the given
+// id if any. The next ones are returned by calling it again from the id following the last
+// one returned.
+func (api *API) GetPendingStateSyncEvents(ctx context.Context, fromID *uint64) ([]*PendingStateSyncEvent, error) {
+	if api.bor.HeimdallClient == nil {
+		return nil, &HeimdallUnreachableError{Err: errHeimdallDisabled}
+	}
+
+	head := api.chain.CurrentHeader()
+
+	lastStateID, err := api.bor.GenesisContractsClient.LastStateId(nil, head.Number.Uint64(), head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := api.fetchPendingStateSyncs(ctx, lastStateID.Uint64()+1)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		config = api.bor.config
+		number = nextSprintStart(config, head.Number.Uint64())
+		count  uint64
+		limit  = maxPendingStateSyncEvents
+	)
+
+	if api.pendingStateSyncsLimit > 0 {
+		limit = api.pendingStateSyncsLimit
+	}
+
+	result := make([]*PendingStateSyncEvent, 0, min(len(events), limit))
+
+	for _, event := range events {
+		if len(result) == limit {
+			break
+		}
+
+		// Move to the next sprints until the event is old enough and the block has room for it
+		for {
+			period := max(config.CalculatePeriod(number), 1)
+			blockTime := head.Time + (number-head.Number.Uint64())*period
+			maxEvents := config.CalculateStateSyncMaxEventsPerBlock(number)
+
+			if uint64(event.Time.Unix())+config.CalculateStateSyncDelay(number) <= blockTime && (maxEvents == 0 || count < maxEvents) {
+				break
+			}
+
+			number += config.CalculateSprint(number)
+			count = 0
+		}
+
+		count++
+
+		// The events before the requested page are still needed for the estimates
+		if fromID != nil && event.ID < *fromID {
+			continue
+		}
+
+		result = append(result, &PendingStateSyncEvent{
+			ID:             event.ID,
+			Contract:       event.Contract,
+			DataSize:       len(event.Data),
+			TxHash:         event.TxHash,
+			Time:           event.Time,
+			EstimatedBlock: number,
+		})
+	}
+
+	return result, nil
+}
+
+// fetchPendingStateSyncs returns the state sync events recorded on heimdall from the
+// given id up to now, reusing the last response if it's recent enough.
+func (api *API) fetchPendingStateSyncs(ctx context.Context, fromID uint64) ([]*clerk.EventRecordWithTime, error) {
+	api.pendingStateSyncsLock.Lock()
+	defer api.pendingStateSyncsLock.Unlock()
+
+	if cached := api.pendingStateSyncs; cached != nil && cached.fromID == fromID && time.Now().Before(cached.expires) {
+		return cached.events, nil
+	}
+
+	events, err := api.bor.HeimdallClient.StateSyncEvents(ctx, fromID, time.Now().Unix())
+	if err != nil {
+		return nil, &HeimdallUnreachableError{Err: err}
+	}
+
+	// Heimdall returns the events in order, but be defensive about the already committed ones
+	pending := make([]*clerk.EventRecordWithTime, 0, len(events))
+
+	for _, event := range events {
+		if event.ID >= fromID {
+			pending = append(pending, event)
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].ID < pending[j].ID
+	})
+
+	api.pendingStateSyncs = &pendingStateSyncs{
+		fromID:  fromID,
+		events:  pending,
+		expires: time.Now().Add(pendingStateSyncsTTL),
+	}
+
+	return pending, nil
+}
+
+// nextSprintStart returns the first sprint start block after the given block.
+func nextSprintStart(config *params.BorConfig, number uint64) uint64 {
+	number++
+	for !config.IsSprintStart(number) {
+		number++
+	}
+
+	return number
+}
+
+// HeimdallStatus returns the connectivity status of heimdall
+func (api *API) HeimdallStatus() (*HeimdallStatus, error) {
+	if api.bor.heimdallHealth == nil {
+		return nil, errHeimdallHealthDisabled
+	}
+
+	return api.bor.heimdallHealth.Status(), nil
+}
+
+// GetRootHash returns the merkle root of the start to end block headers. Reading the
+// headers stops once the request is cancelled.
+func (api *API) GetRootHash(ctx context.Context, start uint64, end uint64) (string, error) {
 	if err := api.initializeRootHashCache(); err != nil {
 		return "", err
 	}
@@ -283,23 +592,141 @@ func (api *API) GetRootHash(start uint64, end uint64) (string, error) {
 		return root.(string), nil
 	}
 
-	length := end - start + 1
+	if err := api.checkRootHashRange(start, end, api.rootHashMaxRange()); err != nil {
+		return "", err
+	}
 
-	if length > MaxCheckpointLength {
-		return "", &MaxCheckpointLengthExceededError{start, end}
+	leaves, err := api.headerLeaves(ctx, start, end)
+	if err != nil {
+		return "", err
+	}
+
+	root, err := merkleRoot(leaves, nextPowerOfTwo(end-start+1))
+	if err != nil {
+		return "", err
 	}
 
+	rootHash := hex.EncodeToString(root)
+	api.rootHashCache.Add(key, rootHash)
+
+	return rootHash, nil
+}
+
+// maxRootHashPages is the maximum number of pages returned by GetRootHashPaged.
+const maxRootHashPages = 1024
+
+// RootHashPages are the roots of the consecutive subtrees of the merkle tree of a block
+// range, each covering PageSize leaves. The tree has a power of two number of leaves,
+// the headers of the range followed by zero leaves, so the roots cover the zero leaves
+// too. Hashing the roots pairwise (keccak256(left || right)) level by level yields the
+// root returned by GetRootHash for the same range.
+type RootHashPages struct {
+	Start    uint64   `json:"start"`
+	End      uint64   `json:"end"`
+	PageSize uint64   `json:"pageSize"`
+	Roots    []string `json:"roots"`
+}
+
+// GetRootHashPaged returns the merkle root of the start to end block headers as the
+// roots of its subtrees of pageSize leaves, to be combined by the caller. The page size
+// must be a power of two within the maximum range of GetRootHash, while the range
+// itself may span up to maxRootHashPages pages. The computation stops between pages
+// once the request is cancelled.
+func (api *API) GetRootHashPaged(ctx context.Context, start uint64, end uint64, pageSize uint64) (*RootHashPages, error) {
+	limit := api.rootHashMaxRange()
+
+	if pageSize == 0 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("page size %d is not a power of two", pageSize)
+	}
+
+	if pageSize > limit {
+		return nil, &MaxCheckpointLengthExceededError{Start: start, End: start + pageSize - 1, Limit: limit}
+	}
+
+	if err := api.checkRootHashRange(start, end, math.MaxUint64); err != nil {
+		return nil, err
+	}
+
+	size := nextPowerOfTwo(end - start + 1)
+	pageSize = min(pageSize, size)
+
+	if pages := size / pageSize; pages > maxRootHashPages {
+		return nil, fmt.Errorf("block range %d-%d spans %d pages of %d blocks, max %d", start, end, pages, pageSize, maxRootHashPages)
+	}
+
+	result := &RootHashPages{
+		Start:    start,
+		End:      end,
+		PageSize: pageSize,
+		Roots:    make([]string, 0, size/pageSize),
+	}
+
+	for first := start; first < start+size; first += pageSize {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		// The pages past the end of the range only hold zero leaves
+		if first > end {
+			result.Roots = append(result.Roots, hex.EncodeToString(zeroSubtreeRoot(pageSize)))
+			continue
+		}
+
+		leaves, err := api.headerLeaves(ctx, first, min(first+pageSize-1, end))
+		if err != nil {
+			return nil, err
+		}
+
+		root, err := merkleRoot(leaves, pageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Roots = append(result.Roots, hex.EncodeToString(root))
+	}
+
+	return result, nil
+}
+
+// rootHashMaxRange returns the maximum block range accepted by GetRootHash.
+func (api *API) rootHashMaxRange() uint64 {
+	if api.bor == nil || api.bor.rootHashMaxRange == 0 {
+		return MaxCheckpointLength
+	}
+
+	return api.bor.rootHashMaxRange
+}
+
+// checkRootHashRange checks that the given block range is known locally and no longer
+// than the given limit.
+func (api *API) checkRootHashRange(start uint64, end uint64, limit uint64) error {
 	currentHeaderNumber := api.chain.CurrentHeader().Number.Uint64()
 
 	if start > end || end > currentHeaderNumber {
-		return "", &valset.InvalidStartEndBlockError{Start: start, End: end, CurrentHeader: currentHeaderNumber}
+		return &valset.InvalidStartEndBlockError{Start: start, End: end, CurrentHeader: currentHeaderNumber}
+	}
+
+	if end-start+1 > limit {
+		return &MaxCheckpointLengthExceededError{Start: start, End: end, Limit: limit}
 	}
 
+	return nil
+}
+
+// headerLeaves returns the merkle tree leaves of the start to end block headers.
+func (api *API) headerLeaves(ctx context.Context, start uint64, end uint64) ([][32]byte, error) {
 	blockHeaders := make([]*types.Header, end-start+1)
 	wg := new(sync.WaitGroup)
 	concurrent := make(chan bool, 20)
 
 	for i := start; i <= end; i++ {
+		// Stop reading the headers once the request is cancelled
+		if ctx.Err() != nil {
+			break
+		}
+
 		wg.Add(1)
 		concurrent <- true
 
@@ -313,13 +740,17 @@ func (api *API) GetRootHash(start uint64, end uint64) (string, error) {
 	wg.Wait()
 	close(concurrent)
 
-	headers := make([][32]byte, nextPowerOfTwo(length))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	leaves := make([][32]byte, len(blockHeaders))
 
 	for i := 0; i < len(blockHeaders); i++ {
 		blockHeader := blockHeaders[i]
 		// Handle no header case, which is possible if ancient pruning was done
 		if blockHeader == nil {
-			return "", errUnknownBlock
+			return nil, errUnknownBlock
 		}
 		header := crypto.Keccak256(appendBytes32(
 			blockHeader.Number.Bytes(),
@@ -328,21 +759,35 @@ func (api *API) GetRootHash(start uint64, end uint64) (string, error) {
 			blockHeader.ReceiptHash.Bytes(),
 		))
 
-		var arr [32]byte
-
-		copy(arr[:], header)
-		headers[i] = arr
+		copy(leaves[i][:], header)
 	}
 
+	return leaves, nil
+}
+
+// merkleRoot returns the root of the merkle tree of the given leaves, padded with zero
+// leaves up to size leaves.
+func merkleRoot(leaves [][32]byte, size uint64) ([]byte, error) {
+	padded := make([][32]byte, size)
+	copy(padded, leaves)
+
 	tree := merkle.NewTreeWithOpts(merkle.TreeOptions{EnableHashSorting: false, DisableHashLeaves: true})
-	if err := tree.Generate(convert(headers), sha3.NewLegacyKeccak256()); err != nil {
-		return "", err
+	if err := tree.Generate(convert(padded), sha3.NewLegacyKeccak256()); err != nil {
+		return nil, err
 	}
 
-	root := hex.EncodeToString(tree.Root().Hash)
-	api.rootHashCache.Add(key, root)
+	return tree.Root().Hash, nil
+}
+
+// zeroSubtreeRoot returns the root of a merkle tree of size zero leaves, size being a
+// power of two.
+func zeroSubtreeRoot(size uint64) []byte {
+	root := make([]byte, 32)
+	for ; size > 1; size /= 2 {
+		root = crypto.Keccak256(root, root)
+	}
 
-	return root, nil
+	return root
 }
 
 func (api *API) initializeRootHashCache() error {
@@ -0,0 +1,564 @@
+package bor
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	borTypes "github.com/0xPolygon/heimdall-v2/x/bor/types"
+	stakeTypes "github.com/0xPolygon/heimdall-v2/x/stake/types"
+	"github.com/golang/mock/gomock"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bor/clerk"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall"
+	"github.com/ethereum/go-ethereum/consensus/bor/valset"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// headChainReader is a chain reader which only knows its current header
+type headChainReader struct {
+	consensus.ChainHeaderReader
+	head *types.Header
+}
+
+func (c *headChainReader) CurrentHeader() *types.Header {
+	return c.head
+}
+
+// headersChainReader is a chain reader which knows its current header and the headers
+// of a few past blocks
+type headersChainReader struct {
+	headChainReader
+	headers map[uint64]*types.Header
+}
+
+func (c *headersChainReader) GetHeaderByNumber(number uint64) *types.Header {
+	return c.headers[number]
+}
+
+// MockHeimdallClientWithProducers behaves like MockHeimdallClient but populates the
+// spans with three validators, the first two of which are selected producers.
+type MockHeimdallClientWithProducers struct {
+	MockHeimdallClient
+	fetched []uint64
+}
+
+func (h *MockHeimdallClientWithProducers) GetSpan(ctx context.Context, spanID uint64) (*borTypes.Span, error) {
+	span, err := h.MockHeimdallClient.GetSpan(ctx, spanID)
+	if err != nil {
+		return nil, err
+	}
+
+	h.fetched = append(h.fetched, spanID)
+
+	validators := make([]*stakeTypes.Validator, 3)
+	for i := range validators {
+		id := 10*spanID + uint64(i) + 1
+		validators[i] = &stakeTypes.Validator{
+			ValId:            id,
+			Signer:           common.BigToAddress(new(big.Int).SetUint64(id)).Hex(),
+			VotingPower:      int64(100 * (i + 1)),
+			ProposerPriority: int64(i) - 1,
+		}
+	}
+
+	span.ValidatorSet = stakeTypes.ValidatorSet{Validators: validators, Proposer: validators[0]}
+	span.SelectedProducers = []stakeTypes.Validator{*validators[0], *validators[1]}
+
+	return span, nil
+}
+
+func newValidatorsTestAPI(head uint64) (*API, *MockHeimdallClientWithProducers) {
+	heimdall := &MockHeimdallClientWithProducers{}
+
+	api := &API{
+		chain: &headChainReader{head: &types.Header{Number: new(big.Int).SetUint64(head)}},
+		bor:   &Bor{spanStore: NewSpanStore(heimdall, nil, "1337", nil)},
+	}
+
+	return api, heimdall
+}
+
+func TestGetValidatorsAtBlock(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	api, heimdall := newValidatorsTestAPI(20_000) // block 20000 belongs to span 4
+
+	for _, tc := range []struct {
+		number rpc.BlockNumber
+		span   uint64
+	}{
+		{number: 0, span: 0},
+		{number: 255, span: 0},
+		{number: 256, span: 1},
+		{number: 6656, span: 2},
+		{number: 13055, span: 2},
+		{number: rpc.EarliestBlockNumber, span: 0},
+		{number: rpc.LatestBlockNumber, span: 4},
+	} {
+		validators, err := api.GetValidatorsAtBlock(ctx, tc.number)
+		require.NoError(t, err, "block %d", tc.number)
+		require.Len(t, validators, 3, "block %d", tc.number)
+
+		for i, val := range validators {
+			id := 10*tc.span + uint64(i) + 1
+
+			require.Equal(t, id, val.ID, "block %d", tc.number)
+			require.Equal(t, common.BigToAddress(new(big.Int).SetUint64(id)), val.Address, "block %d", tc.number)
+			require.Equal(t, int64(100*(i+1)), val.VotingPower, "block %d", tc.number)
+			require.Equal(t, int64(i)-1, val.ProposerPriority, "block %d", tc.number)
+			require.Equal(t, i < 2, val.Producer, "block %d", tc.number)
+		}
+	}
+
+	// Spans are only fetched once, on demand
+	seen := make(map[uint64]bool)
+	for _, id := range heimdall.fetched {
+		require.False(t, seen[id], "span %d fetched twice", id)
+		seen[id] = true
+	}
+
+	require.NotContains(t, heimdall.fetched, uint64(5), "span beyond the head fetched")
+}
+
+func TestGetValidatorsAtBlockErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	api, _ := newValidatorsTestAPI(6656)
+
+	// Future blocks
+	_, err := api.GetValidatorsAtBlock(ctx, 6657)
+	require.ErrorIs(t, err, consensus.ErrFutureBlock)
+
+	_, err = api.GetValidatorsAtBlock(ctx, 1_000_000)
+	require.ErrorIs(t, err, consensus.ErrFutureBlock)
+
+	// Unsupported block tags
+	_, err = api.GetValidatorsAtBlock(ctx, rpc.FinalizedBlockNumber)
+	require.Error(t, err)
+
+	// Span can't be fetched from heimdall (span 100 fails in the mock)
+	api, _ = newValidatorsTestAPI(6400*100 + 255)
+
+	_, err = api.GetValidatorsAtBlock(ctx, 6400*100)
+	require.Error(t, err)
+}
+
+// MockHeimdallClientWithStateSyncs behaves like MockHeimdallClient but serves the given
+// state sync events.
+type MockHeimdallClientWithStateSyncs struct {
+	MockHeimdallClient
+	events []*clerk.EventRecordWithTime
+	calls  int
+}
+
+func (h *MockHeimdallClientWithStateSyncs) StateSyncEvents(ctx context.Context, fromID uint64, to int64) ([]*clerk.EventRecordWithTime, error) {
+	h.calls++
+
+	var events []*clerk.EventRecordWithTime
+
+	for _, event := range h.events {
+		if event.ID >= fromID && event.Time.Unix() <= to {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func TestGetPendingStateSyncEvents(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Events 1 to 3 are committed, 4 to 7 are pending
+	heimdall := &MockHeimdallClientWithStateSyncs{}
+	for id, recorded := range []int64{900, 950, 990, 1000, 1000, 1000, 1050} {
+		heimdall.events = append(heimdall.events, &clerk.EventRecordWithTime{
+			EventRecord: clerk.EventRecord{
+				ID:       uint64(id + 1),
+				Contract: common.BigToAddress(big.NewInt(int64(id + 1))),
+				Data:     make([]byte, 32*(id+1)),
+				TxHash:   common.BigToHash(big.NewInt(int64(id + 1))),
+			},
+			Time: time.Unix(recorded, 0),
+		})
+	}
+
+	lastStateID := int64(3)
+
+	genesisContracts := NewMockGenesisContract(ctrl)
+	genesisContracts.EXPECT().LastStateId(gomock.Any(), uint64(20), gomock.Any()).DoAndReturn(
+		func(_ any, _ uint64, _ common.Hash) (*big.Int, error) {
+			return big.NewInt(lastStateID), nil
+		}).AnyTimes()
+
+	api := &API{
+		chain: &headChainReader{head: &types.Header{Number: big.NewInt(20), Time: 1000}},
+		bor: &Bor{
+			config: &params.BorConfig{
+				Period:                     map[string]uint64{"0": 2},
+				Sprint:                     map[string]uint64{"0": 16},
+				StateSyncConfirmationDelay: map[string]uint64{"0": 10},
+				StateSyncMaxEventsPerBlock: map[string]uint64{"0": 2},
+			},
+			HeimdallClient:         heimdall,
+			GenesisContractsClient: genesisContracts,
+		},
+	}
+
+	events, err := api.GetPendingStateSyncEvents(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+
+	// Block 32 is expected at 1024, committing the events up to 1014, two at most.
+	// Block 48 is expected at 1056 and block 64 at 1088.
+	for i, block := range []uint64{32, 32, 48, 64} {
+		id := uint64(i + 4)
+
+		require.Equal(t, id, events[i].ID)
+		require.Equal(t, common.BigToAddress(new(big.Int).SetUint64(id)), events[i].Contract)
+		require.Equal(t, int(32*id), events[i].DataSize)
+		require.Equal(t, common.BigToHash(new(big.Int).SetUint64(id)), events[i].TxHash)
+		require.Equal(t, block, events[i].EstimatedBlock, "event %d", id)
+	}
+
+	// Heimdall responses are cached
+	_, err = api.GetPendingStateSyncEvents(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, heimdall.calls)
+
+	// Unless new events are committed
+	lastStateID = 5
+
+	events, err = api.GetPendingStateSyncEvents(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, uint64(6), events[0].ID)
+	require.Equal(t, uint64(32), events[0].EstimatedBlock)
+	require.Equal(t, 2, heimdall.calls)
+
+	// The events are paginated, keeping the estimates of the whole list
+	lastStateID = 3
+	api.pendingStateSyncsLimit = 3
+
+	events, err = api.GetPendingStateSyncEvents(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, uint64(6), events[2].ID)
+
+	next := events[2].ID + 1
+
+	events, err = api.GetPendingStateSyncEvents(context.Background(), &next)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, uint64(7), events[0].ID)
+	require.Equal(t, uint64(64), events[0].EstimatedBlock)
+
+	// Heimdall is required
+	_, err = (&API{chain: api.chain, bor: &Bor{}}).GetPendingStateSyncEvents(context.Background(), nil)
+	require.ErrorIs(t, err, errHeimdallDisabled)
+}
+
+func TestGetProducerDelay(t *testing.T) {
+	t.Parallel()
+
+	headers := map[uint64]*types.Header{
+		31: {Number: big.NewInt(31)},
+		70: {Number: big.NewInt(70)},
+	}
+	recents, _ := lru.NewARC(inmemorySnapshots)
+
+	// Three validators at block 31, four at the head
+	for number, count := range map[uint64]int{31: 3, 70: 4} {
+		validators := make([]*valset.Validator, count)
+		for i := range validators {
+			validators[i] = valset.NewValidator(common.BigToAddress(big.NewInt(int64(i+1))), 10)
+		}
+
+		hash := headers[number].Hash()
+		recents.Add(hash, &Snapshot{Number: number, Hash: hash, ValidatorSet: valset.NewValidatorSet(validators)})
+	}
+
+	bor := &Bor{
+		config: &params.BorConfig{
+			Period:           map[string]uint64{"0": 2},
+			ProducerDelay:    map[string]uint64{"0": 6},
+			Sprint:           map[string]uint64{"0": 16},
+			BackupMultiplier: map[string]uint64{"0": 2, "64": 5},
+		},
+		recents: recents,
+	}
+	bor.authorizedSigner.Store(&signer{})
+
+	api := &API{
+		chain: &headersChainReader{headChainReader: headChainReader{head: headers[70]}, headers: headers},
+		bor:   bor,
+	}
+
+	// Sprint start before the backup multiplier override, producers known from the chain
+	delay, err := api.GetProducerDelay(32)
+	require.NoError(t, err)
+	require.Equal(t, &ProducerDelay{
+		Number:           32,
+		SprintStart:      true,
+		Delay:            6,
+		BackupMultiplier: 2,
+		Successions:      []uint64{6, 8, 10},
+	}, delay)
+
+	// Pending block after the override, producers taken from the head
+	delay, err = api.GetProducerDelay(rpc.PendingBlockNumber)
+	require.NoError(t, err)
+	require.Equal(t, &ProducerDelay{
+		Number:           71,
+		SprintStart:      false,
+		Delay:            2,
+		BackupMultiplier: 5,
+		Successions:      []uint64{2, 7, 12, 17},
+	}, delay)
+
+	// The successions match the delays enforced when verifying headers
+	for succession, expected := range delay.Successions {
+		require.Equal(t, expected, CalcProducerDelay(71, succession, bor.config))
+	}
+
+	_, err = api.GetProducerDelay(rpc.FinalizedBlockNumber)
+	require.Error(t, err)
+}
+
+// MockHeimdallClientWithMissingSpans behaves like MockHeimdallClientWithProducers but
+// reports the spans from the given id on as not found.
+type MockHeimdallClientWithMissingSpans struct {
+	MockHeimdallClientWithProducers
+	missingFrom uint64
+}
+
+func (h *MockHeimdallClientWithMissingSpans) GetSpan(ctx context.Context, spanID uint64) (*borTypes.Span, error) {
+	if spanID >= h.missingFrom {
+		return nil, heimdall.ErrNotFound
+	}
+
+	return h.MockHeimdallClientWithProducers.GetSpan(ctx, spanID)
+}
+
+// callErrorCode calls the given bor API method over RPC, returning the code of the
+// error response.
+func callErrorCode(t *testing.T, api *API, method string, args ...interface{}) int {
+	t.Helper()
+
+	server := rpc.NewServer("", 0, 0)
+	require.NoError(t, server.RegisterName("bor", api))
+
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	var result interface{}
+
+	err := client.Call(&result, method, args...)
+	require.Error(t, err)
+
+	var rpcErr rpc.Error
+	require.ErrorAs(t, err, &rpcErr)
+
+	return rpcErr.ErrorCode()
+}
+
+func TestAPIErrorCodes(t *testing.T) {
+	t.Parallel()
+
+	// Span can't be fetched from heimdall (span 100 fails in the mock)
+	api, _ := newValidatorsTestAPI(6400*100 + 255)
+	require.Equal(t, HeimdallUnreachableErrorCode, callErrorCode(t, api, "bor_getValidatorsAtBlock", hexutil.Uint64(6400*100)))
+
+	// Spans 50 and above don't exist in heimdall
+	missing := &MockHeimdallClientWithMissingSpans{missingFrom: 50}
+	api = &API{
+		chain: &headChainReader{head: &types.Header{Number: big.NewInt(6400*50 + 255)}},
+		bor:   &Bor{spanStore: NewSpanStore(missing, nil, "1337", nil)},
+	}
+	require.Equal(t, SpanNotFoundErrorCode, callErrorCode(t, api, "bor_getValidatorsAtBlock", hexutil.Uint64(6400*50+100)))
+
+	// Heimdall is disabled
+	api = &API{bor: &Bor{}}
+	require.Equal(t, HeimdallUnreachableErrorCode, callErrorCode(t, api, "bor_getPendingStateSyncEvents"))
+}
+
+// newRootHashTestAPI returns an API over a chain of the given length with distinct
+// headers, allowing root hashes over up to limit blocks.
+func newRootHashTestAPI(length uint64, limit uint64) (*API, *headersChainReader) {
+	headers := make(map[uint64]*types.Header, length)
+	for number := uint64(0); number < length; number++ {
+		headers[number] = &types.Header{
+			Number:      new(big.Int).SetUint64(number),
+			Time:        1000 + 2*number,
+			TxHash:      common.BigToHash(new(big.Int).SetUint64(number)),
+			ReceiptHash: common.BigToHash(new(big.Int).SetUint64(number + 1)),
+		}
+	}
+
+	chain := &headersChainReader{headChainReader: headChainReader{head: headers[length-1]}, headers: headers}
+
+	return &API{chain: chain, bor: &Bor{rootHashMaxRange: limit}}, chain
+}
+
+// combineRootHashPages hashes the page roots pairwise up to a single root.
+func combineRootHashPages(t *testing.T, roots []string) string {
+	t.Helper()
+
+	level := make([][]byte, len(roots))
+	for i, root := range roots {
+		blob, err := hex.DecodeString(root)
+		require.NoError(t, err)
+
+		level[i] = blob
+	}
+
+	for len(level) > 1 {
+		next := make([][]byte, len(level)/2)
+		for i := range next {
+			next[i] = crypto.Keccak256(level[2*i], level[2*i+1])
+		}
+
+		level = next
+	}
+
+	return hex.EncodeToString(level[0])
+}
+
+func TestGetRootHashMaxRange(t *testing.T) {
+	t.Parallel()
+
+	api, _ := newRootHashTestAPI(64, 16)
+
+	_, err := api.GetRootHash(context.Background(), 0, 15)
+	require.NoError(t, err)
+
+	_, err = api.GetRootHash(context.Background(), 1, 17)
+
+	var rangeErr *MaxCheckpointLengthExceededError
+	require.ErrorAs(t, err, &rangeErr)
+	require.Equal(t, uint64(16), rangeErr.Limit)
+
+	// The default limit is the checkpoint length
+	api, _ = newRootHashTestAPI(64, 0)
+	require.Equal(t, MaxCheckpointLength, api.rootHashMaxRange())
+
+	// The configured limit can't exceed the checkpoint length
+	engine := &Bor{}
+	engine.SetRootHashMaxRange(MaxCheckpointLength + 1)
+	require.Equal(t, MaxCheckpointLength, engine.rootHashMaxRange)
+}
+
+func TestGetRootHashPaged(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	api, _ := newRootHashTestAPI(128, 0)
+
+	// Ranges of 68 blocks (128 leaves), with partial and zero pages
+	root, err := api.GetRootHash(ctx, 3, 70)
+	require.NoError(t, err)
+
+	for _, pageSize := range []uint64{1, 16, 64, 128, 1024} {
+		pages, err := api.GetRootHashPaged(ctx, 3, 70, pageSize)
+		require.NoError(t, err)
+
+		require.Equal(t, min(pageSize, 128), pages.PageSize)
+		require.Len(t, pages.Roots, int(128/pages.PageSize))
+		require.Equal(t, root, combineRootHashPages(t, pages.Roots), "page size %d", pageSize)
+	}
+
+	// Invalid page sizes
+	_, err = api.GetRootHashPaged(ctx, 3, 70, 12)
+	require.Error(t, err)
+
+	_, err = api.GetRootHashPaged(ctx, 3, 70, 0)
+	require.Error(t, err)
+
+	limited, _ := newRootHashTestAPI(128, 16)
+
+	_, err = limited.GetRootHashPaged(ctx, 3, 70, 32)
+
+	var rangeErr *MaxCheckpointLengthExceededError
+	require.ErrorAs(t, err, &rangeErr)
+
+	// Ranges beyond the limit are served in pages
+	pages, err := limited.GetRootHashPaged(ctx, 3, 70, 16)
+	require.NoError(t, err)
+	require.Equal(t, root, combineRootHashPages(t, pages.Roots))
+
+	// Too many pages
+	_, err = api.GetRootHashPaged(ctx, 0, 127, 1)
+	require.NoError(t, err)
+
+	large, _ := newRootHashTestAPI(2*maxRootHashPages, 0)
+
+	_, err = large.GetRootHashPaged(ctx, 0, 2*maxRootHashPages-1, 1)
+	require.Error(t, err)
+
+	// Unknown blocks
+	_, err = api.GetRootHashPaged(ctx, 3, 128, 16)
+	require.Error(t, err)
+}
+
+// cancellingChainReader cancels a context once a header of the given block is read,
+// recording the highest block read.
+type cancellingChainReader struct {
+	*headersChainReader
+	number  uint64
+	cancel  context.CancelFunc
+	highest atomic.Uint64
+}
+
+func (c *cancellingChainReader) GetHeaderByNumber(number uint64) *types.Header {
+	if number == c.number {
+		c.cancel()
+	}
+
+	for {
+		highest := c.highest.Load()
+		if number <= highest || c.highest.CompareAndSwap(highest, number) {
+			break
+		}
+	}
+
+	return c.headersChainReader.GetHeaderByNumber(number)
+}
+
+func TestGetRootHashPagedCancel(t *testing.T) {
+	t.Parallel()
+
+	api, chain := newRootHashTestAPI(128, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel the request while computing the second page
+	reader := &cancellingChainReader{headersChainReader: chain, number: 20, cancel: cancel}
+	api.chain = reader
+
+	_, err := api.GetRootHashPaged(ctx, 0, 127, 16)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, uint64(31), reader.highest.Load(), "pages computed after the cancellation")
+
+	// Cancelled requests don't compute anything
+	api.chain = chain
+
+	_, err = api.GetRootHashPaged(ctx, 0, 127, 16)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"runtime"
 	"sort"
 	"strconv"
 	"sync"
@@ -38,6 +39,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -110,8 +112,34 @@ var (
 
 	errUncleDetected     = errors.New("uncles not allowed")
 	errUnknownValidators = errors.New("unknown validators")
+
+	// errStateSyncDataTooLarge is returned if the data of a state sync event exceeds the
+	// configured limit.
+	errStateSyncDataTooLarge = errors.New("state sync event data too large")
+
+	// errStateSyncZeroAddress is returned if a state sync event is sent to the zero address.
+	errStateSyncZeroAddress = errors.New("state sync event sent to the zero address")
+
+	// errHeimdallHealthDisabled is returned if the heimdall status is requested while
+	// the connectivity to heimdall isn't tracked (e.g. when running without heimdall).
+	errHeimdallHealthDisabled = errors.New("heimdall health tracking is disabled")
+
+	// errHeimdallDisabled is returned by the APIs requiring heimdall when running without it.
+	errHeimdallDisabled = errors.New("heimdall is disabled")
+
+	// errStateSyncerUnsupported is returned if a block is assembled on a chain which can't
+	// take the state sync events it commits.
+	errStateSyncerUnsupported = errors.New("chain doesn't support state sync events")
 )
 
+// stateSyncSkippedCounter counts the state sync events committed without their data as
+// they violated the configured limits.
+var stateSyncSkippedCounter = metrics.NewRegisteredCounter("bor/statesync/skipped", nil)
+
+// spanCommitSkippedCounter counts the span commits skipped because the validator contract
+// already held the span.
+var spanCommitSkippedCounter = metrics.NewRegisteredCounter("bor/span/commit/skipped", nil)
+
 // SignerFn is a signer callback function to request a header to be signed by a
 // backing account.
 type SignerFn func(accounts.Account, string, []byte) ([]byte, error)
@@ -145,6 +173,43 @@ func ecrecover(header *types.Header, sigcache *lru.ARCCache, c *params.BorConfig
 	return signer, nil
 }
 
+// recoverSigners recovers the signers of the given headers in parallel into the
+// signature cache, so that verifying the headers in order finds them cached. Headers
+// whose signer can't be recovered are skipped, the error is reported when they are
+// verified. It returns once every header was processed or abort is closed.
+func recoverSigners(headers []*types.Header, sigcache *lru.ARCCache, c *params.BorConfig, abort <-chan struct{}) {
+	var (
+		next    atomic.Int64
+		workers = min(runtime.NumCPU(), len(headers))
+		wg      sync.WaitGroup
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				index := int(next.Add(1) - 1)
+				if index >= len(headers) {
+					return
+				}
+
+				select {
+				case <-abort:
+					return
+				default:
+				}
+
+				_, _ = ecrecover(headers[index], sigcache, c)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
 // SealHash returns the hash of a block prior to it being sealed.
 func SealHash(header *types.Header, c *params.BorConfig) (hash common.Hash) {
 	hasher := sha3.NewLegacyKeccak256()
@@ -186,12 +251,7 @@ func encodeSigHeader(w io.Writer, header *types.Header, c *params.BorConfig) {
 
 // CalcProducerDelay is the block delay algorithm based on block time, period, producerDelay and turn-ness of a signer
 func CalcProducerDelay(number uint64, succession int, c *params.BorConfig) uint64 {
-	// When the block is the first block of the sprint, it is expected to be delayed by `producerDelay`.
-	// That is to allow time for block propagation in the last sprint
-	delay := c.CalculatePeriod(number)
-	if number%c.CalculateSprint(number) == 0 {
-		delay = c.CalculateProducerDelay(number)
-	}
+	delay := calcPrimaryDelay(number, c)
 
 	if succession > 0 {
 		delay += uint64(succession) * c.CalculateBackupMultiplier(number)
@@ -200,6 +260,18 @@ func CalcProducerDelay(number uint64, succession int, c *params.BorConfig) uint6
 	return delay
 }
 
+// calcPrimaryDelay returns the delay of the primary producer of the given block after
+// its parent, as configured for its height.
+func calcPrimaryDelay(number uint64, c *params.BorConfig) uint64 {
+	// When the block is the first block of the sprint, it is expected to be delayed by `producerDelay`.
+	// That is to allow time for block propagation in the last sprint
+	if number%c.CalculateSprint(number) == 0 {
+		return c.CalculateProducerDelay(number)
+	}
+
+	return c.CalculatePeriod(number)
+}
+
 // BorRLP returns the rlp bytes which needs to be signed for the bor
 // sealing. The RLP to sign consists of the entire header apart from the 65 byte signature
 // contained at the end of the extra data.
@@ -231,12 +303,32 @@ type Bor struct {
 	HeimdallClient         IHeimdallClient
 	HeimdallWSClient       IHeimdallWSClient
 
-	spanStore SpanStore // Store to save previous span data from heimdall
+	spanStore      SpanStore              // Store to save previous span data from heimdall
+	heimdallHealth *HeimdallHealth        // Connectivity status of heimdall, nil if not tracked
+	clock          Clock                  // Source of time of the producer timing logic
+	milestones     MilestoneReader        // Whitelisted milestone attesting headers, nil to verify all headers fully
+	tracer         *balance_tracing.Hooks // Live tracer notified of the span commits and state syncs, nil if none
+
+	rootHashMaxRange uint64 // Maximum block range of the root hash APIs, MaxCheckpointLength if zero
+	strictSealing    bool   // Refuse to seal blocks if the signer isn't a producer of their span
+
+	sealerReportedSpan atomic.Uint64 // Id+1 of the last span the signer was reported missing from the producers
+
+	prefetchNumber uint64             // Sprint start being prefetched or last prefetched
+	prefetched     *stateSyncPrefetch // State sync events prefetched for a sprint start
+	prefetchLock   sync.Mutex         // Protects prefetchNumber and prefetched
+
+	pendingSnapshots []*Snapshot // Checkpoint snapshots to persist with the next block
+	snapshotsLock    sync.Mutex  // Protects pendingSnapshots
 
 	// The fields below are for testing only
 	fakeDiff      bool // Skip difficulty verifications
 	DevFakeAuthor bool
 
+	sealerGauge      *metrics.Gauge   // Replaces sealerNotInProducerSetGauge if set
+	spanRetryCounter *metrics.Counter // Replaces spanVerificationRetryCounter if set
+
+	quit      chan struct{} // Closed to stop the background span reconciliation
 	closeOnce sync.Once
 }
 
@@ -283,6 +375,13 @@ func New(
 		HeimdallWSClient:       heimdallWSClient,
 		spanStore:              spanStore,
 		DevFakeAuthor:          devFakeAuthor,
+		clock:                  systemClock{},
+		quit:                   make(chan struct{}),
+	}
+
+	// Spans fixed in heimdall after being cached are only noticed by reconciling them
+	if heimdallClient != nil {
+		go c.spanStore.reconcileLoop(spanReconcileInterval, c.quit)
 	}
 
 	c.authorizedSigner.Store(&signer{
@@ -293,6 +392,11 @@ func New(
 		},
 	})
 
+	// make sure the block timing schedules in the BorConfig are well formed.
+	if err := borConfig.ValidateSchedules(); err != nil {
+		panic(fmt.Sprintf("BUG: Block timing schedule in genesis is not correct: %v", err))
+	}
+
 	// make sure we can decode all the GenesisAlloc in the BorConfig.
 	for key, genesisAlloc := range c.config.BlockAlloc {
 		if _, err := decodeGenesisAlloc(genesisAlloc); err != nil {
@@ -325,13 +429,27 @@ func (c *Bor) SetSpanner(spanner Spanner) {
 // VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers. The
 // method returns a quit channel to abort the operations and a results channel to
 // retrieve the async verifications (the order is that of the input slice).
+// Headers attested by the whitelisted milestone skip the seal verification.
 func (c *Bor) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header) (chan<- struct{}, <-chan error) {
 	abort := make(chan struct{})
 	results := make(chan error, len(headers))
 
 	go func() {
+		attested := c.milestoneAttested(chain, headers)
+
+		// Recover the signers ahead of the verification, the attested headers still track
+		// theirs among the recent signers of the snapshot
+		if len(headers) > 1 {
+			go recoverSigners(headers, c.signatures, c.config, abort)
+		}
+
 		for i, header := range headers {
-			err := c.verifyHeader(chain, header, headers[:i])
+			var err error
+			if i < attested {
+				err = c.verifyAttestedHeader(chain, header, headers[:i])
+			} else {
+				err = c.verifyHeader(chain, header, headers[:i])
+			}
 
 			select {
 			case <-abort:
@@ -354,7 +472,7 @@ func (c *Bor) verifyHeader(chain consensus.ChainHeaderReader, header *types.Head
 	}
 
 	number := header.Number.Uint64()
-	now := uint64(time.Now().Unix())
+	now := uint64(c.now().Unix())
 
 	// Allow early blocks if Bhilai HF is enabled
 	if c.config.IsBhilai(header.Number) {
@@ -502,35 +620,15 @@ func (c *Bor) verifyCascadingFields(chain consensus.ChainHeaderReader, header *t
 	// validation stateless, we use the span from heimdall (via span store) instead of
 	// span from validator set genesis contract as both are supposed to be equivalent.
 	if number > zerothSpanEnd && IsSprintStart(number+1, c.config.CalculateSprint(number)) {
-		span, err := c.spanStore.spanByBlockNumber(context.Background(), number+1)
-		if err != nil {
-			return err
-		}
+		// Heimdall may be briefly unreachable, give it some time to serve the span
+		ctx, cancel := context.WithTimeout(context.Background(), spanVerificationBudget)
+		err := c.verifySpanValidators(ctx, header)
 
-		// Use producer set from span as it's equivalent to the data we get from genesis contract
-		selectedProducers := borSpan.ConvertHeimdallValidatorsToBorValidators(span.SelectedProducers)
-		newValidators := make([]*valset.Validator, len(selectedProducers))
-		for i, val := range selectedProducers {
-			newValidators[i] = &val
-		}
-		sort.Sort(valset.ValidatorsByAddress(newValidators))
+		cancel()
 
-		headerVals, err := valset.ParseValidators(header.GetValidatorBytes(c.chainConfig))
 		if err != nil {
 			return err
 		}
-
-		if len(newValidators) != len(headerVals) {
-			log.Warn("Invalid validator set", "block number", number, "newValidators", newValidators, "headerVals", headerVals)
-			return errInvalidSpanValidators
-		}
-
-		for i, val := range newValidators {
-			if !bytes.Equal(val.HeaderBytes(), headerVals[i].HeaderBytes()) {
-				log.Warn("Invalid validator set", "block number", number, "index", i, "local validator", val, "header validator", headerVals[i])
-				return errInvalidSpanValidators
-			}
-		}
 	}
 
 	// verify the validator list in the last sprint block
@@ -556,8 +654,15 @@ func (c *Bor) verifyCascadingFields(chain consensus.ChainHeaderReader, header *t
 }
 
 // snapshot retrieves the authorization snapshot at a given point in time.
-// nolint: gocognit
 func (c *Bor) snapshot(chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header) (*Snapshot, error) {
+	return c.buildSnapshot(chain, number, hash, parents, false)
+}
+
+// buildSnapshot retrieves the authorization snapshot at a given point in time, applying
+// the headers since the nearest known snapshot. If the block is attested by the whitelisted
+// milestone, so are the headers applied and their signers aren't checked.
+// nolint: gocognit
+func (c *Bor) buildSnapshot(chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header, attested bool) (*Snapshot, error) {
 	// Search for a snapshot in memory or on disk for checkpoints
 	signer := common.BytesToAddress(c.authorizedSigner.Load().signer.Bytes())
 	if c.DevFakeAuthor && signer.String() != "0x0000000000000000000000000000000000000000" {
@@ -586,6 +691,12 @@ func (c *Bor) snapshot(chain consensus.ChainHeaderReader, number uint64, hash co
 
 		// If an on-disk checkpoint snapshot can be found, use that
 		if number%checkpointInterval == 0 {
+			if s := c.pendingSnapshot(hash); s != nil {
+				snap = s
+
+				break
+			}
+
 			if s, err := loadSnapshot(c.chainConfig, c.config, c.signatures, c.db, hash); err == nil {
 				log.Trace("Loaded snapshot from disk", "number", number, "hash", hash)
 
@@ -607,14 +718,13 @@ func (c *Bor) snapshot(chain consensus.ChainHeaderReader, number uint64, hash co
 				hash := checkpoint.Hash()
 
 				// get validators from span
-				span, err := c.spanStore.spanByBlockNumber(context.Background(), number+1)
+				validators, err := c.spanStore.validatorsByBlockNumber(context.Background(), number+1)
 				if err != nil {
 					return nil, err
 				}
 
 				// new snap shot
-				borValSet := borSpan.ConvertHeimdallValSetToBorValSet(span.ValidatorSet)
-				snap = newSnapshot(c.chainConfig, c.signatures, number, hash, borValSet.Validators)
+				snap = newSnapshot(c.chainConfig, c.signatures, number, hash, validators.validatorSet.Validators)
 				if err := snap.store(c.db); err != nil {
 					return nil, err
 				}
@@ -657,20 +767,18 @@ func (c *Bor) snapshot(chain consensus.ChainHeaderReader, number uint64, hash co
 		headers[i], headers[len(headers)-1-i] = headers[len(headers)-1-i], headers[i]
 	}
 
-	snap, err := snap.apply(headers, c)
+	snap, err := snap.applyHeaders(headers, c, attested)
 	if err != nil {
 		return nil, err
 	}
 
 	c.recents.Add(snap.Hash, snap)
 
-	// If we've generated a new checkpoint snapshot, save to disk
+	// If we've generated a new checkpoint snapshot, save it along with the next block
 	if snap.Number%checkpointInterval == 0 && len(headers) > 0 {
-		if err = snap.store(c.db); err != nil {
+		if err = c.persistSnapshot(snap); err != nil {
 			return nil, err
 		}
-
-		log.Trace("Stored snapshot to disk", "number", snap.Number, "hash", snap.Hash)
 	}
 
 	return snap, err
@@ -716,7 +824,7 @@ func (c *Bor) verifySeal(chain consensus.ChainHeaderReader, header *types.Header
 
 	if !snap.ValidatorSet.HasAddress(signer) {
 		// Check the UnauthorizedSignerError.Error() msg to see why we pass number-1
-		return &UnauthorizedSignerError{number - 1, signer.Bytes()}
+		return c.spanConflictError(number, &UnauthorizedSignerError{number - 1, signer.Bytes()})
 	}
 
 	succession, err := snap.GetSignerSuccessionNumber(signer)
@@ -733,7 +841,7 @@ func (c *Bor) verifySeal(chain consensus.ChainHeaderReader, header *types.Header
 
 	// Post Bhilai HF, reject blocks form non-primary producers if they're earlier than the expected time
 	if c.config.IsBhilai(header.Number) && succession != 0 {
-		now := uint64(time.Now().Unix())
+		now := uint64(c.now().Unix())
 		if header.Time > now {
 			log.Error("Block announced too early by non-primary producer post bhilai", "number", number, "headerTime", header.Time, "now", now)
 			return consensus.ErrFutureBlock
@@ -741,20 +849,42 @@ func (c *Bor) verifySeal(chain consensus.ChainHeaderReader, header *types.Header
 	}
 
 	if IsBlockEarly(parent, header, number, succession, c.config) {
-		return &BlockTooSoonError{number, succession}
+		return &BlockTooSoonError{
+			Number:           number,
+			Succession:       succession,
+			Time:             header.Time,
+			ParentTime:       parent.Time,
+			Delay:            calcPrimaryDelay(number, c.config),
+			BackupMultiplier: c.config.CalculateBackupMultiplier(number),
+		}
 	}
 
 	// Ensure that the difficulty corresponds to the turn-ness of the signer
 	if !c.fakeDiff {
 		difficulty := Difficulty(snap.ValidatorSet, signer)
 		if header.Difficulty.Uint64() != difficulty {
-			return &WrongDifficultyError{number, difficulty, header.Difficulty.Uint64(), signer.Bytes()}
+			return c.spanConflictError(number, &WrongDifficultyError{number, difficulty, header.Difficulty.Uint64(), signer.Bytes()})
 		}
 	}
 
 	return nil
 }
 
+// spanConflictError wraps a seal verification error of the given block into a
+// SpanConflictError if the spans covering the block are known to conflict, as the
+// producers the block was verified against may have changed after it was sealed.
+func (c *Bor) spanConflictError(number uint64, err error) error {
+	conflict := c.spanStore.spanConflictForBlock(number)
+	if conflict == nil {
+		return err
+	}
+
+	log.Error("Seal verification failed for block covered by conflicting spans", "number", number,
+		"span", conflict.SpanId, "conflicting", conflict.ConflictingSpanId, "err", err)
+
+	return &SpanConflictError{Number: number, Conflict: conflict, Err: err}
+}
+
 // IsBlockEarly returns true if the header time is earlier than expected (according to consensus rules). This
 // can happen if the producer maliciously updates the header time.
 func IsBlockEarly(parent *types.Header, header *types.Header, number uint64, succession int, cfg *params.BorConfig) bool {
@@ -858,8 +988,8 @@ func (c *Bor) Prepare(chain consensus.ChainHeaderReader, header *types.Header) e
 	}
 
 	header.Time = parent.Time + CalcProducerDelay(number, succession, c.config)
-	if header.Time < uint64(time.Now().Unix()) {
-		header.Time = uint64(time.Now().Unix())
+	if now := uint64(c.now().Unix()); header.Time < now {
+		header.Time = now
 	} else {
 		// For primary validators, wait until the current block production window
 		// starts. This prevents bor from starting to build next block before time
@@ -868,7 +998,7 @@ func (c *Bor) Prepare(chain consensus.ChainHeaderReader, header *types.Header) e
 		// still keep it for safety and testing.
 		if c.config.IsBhilai(big.NewInt(int64(number))) && succession == 0 {
 			startTime := time.Unix(int64(header.Time-c.config.CalculatePeriod(number)), 0)
-			time.Sleep(time.Until(startTime))
+			<-c.after(startTime.Sub(c.now()))
 		}
 	}
 
@@ -893,20 +1023,9 @@ func (c *Bor) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 
 	if IsSprintStart(headerNumber, c.config.CalculateSprint(headerNumber)) {
 		start := time.Now()
-		cx := statefull.ChainContext{Chain: chain, Bor: c}
-		// check and commit span
-		if err := c.checkAndCommitSpan(wrappedState, header, cx); err != nil {
-			log.Error("Error while committing span", "error", err)
-			return
-		}
 
-		if c.HeimdallClient != nil {
-			// commit states
-			stateSyncData, err = c.CommitStates(wrappedState, header, cx)
-			if err != nil {
-				log.Error("Error while committing states", "error", err)
-				return
-			}
+		if stateSyncData, err = c.applySystemCalls(chain, header, wrappedState, c.tracer, false); err != nil {
+			return
 		}
 
 		// Get the underlying state for updating consensus time
@@ -922,9 +1041,45 @@ func (c *Bor) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 		return
 	}
 
-	// Set state sync data to blockchain
-	bc := chain.(*core.BlockChain)
-	bc.SetStateSync(stateSyncData)
+	// Set state sync data to blockchain. The chain may wrap the blockchain, e.g. when the
+	// block is re-executed for its witness, which decides what to do with the data.
+	if bc, ok := chain.(core.BorStateSyncer); ok {
+		bc.SetStateSync(stateSyncData)
+	}
+}
+
+// ApplySystemCalls applies the system calls of the given sprint start block on the
+// state: the commit of the next span if needed, followed by the state sync events. It
+// returns the committed state sync events. Passing a state implementing
+// statefull.SystemCallTracer allows tracing the calls.
+func (c *Bor) ApplySystemCalls(chain consensus.ChainHeaderReader, header *types.Header, state vm.StateDB) ([]*types.StateSyncData, error) {
+	return c.applySystemCalls(chain, header, state, nil, false)
+}
+
+// applySystemCalls is ApplySystemCalls notifying the given tracer, if any, of the span
+// commit and of the state syncs. The state syncs are checked more strictly if the block
+// is being assembled, see commitStates.
+func (c *Bor) applySystemCalls(chain consensus.ChainHeaderReader, header *types.Header, state vm.StateDB, tracer *balance_tracing.Hooks, assemble bool) ([]*types.StateSyncData, error) {
+	cx := statefull.ChainContext{Chain: chain, Bor: c}
+
+	// check and commit span
+	if err := c.checkAndCommitSpan(state, header, cx, tracer); err != nil {
+		log.Error("Error while committing span", "error", err)
+		return nil, err
+	}
+
+	if c.HeimdallClient == nil {
+		return nil, nil
+	}
+
+	// commit states
+	stateSyncData, err := c.commitStates(state, header, cx, tracer, assemble)
+	if err != nil {
+		log.Error("Error while committing states", "error", err)
+		return nil, err
+	}
+
+	return stateSyncData, nil
 }
 
 func decodeGenesisAlloc(i interface{}) (types.GenesisAlloc, error) {
@@ -976,28 +1131,20 @@ func (c *Bor) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *typ
 		return nil, consensus.ErrUnexpectedRequests
 	}
 
+	bc, ok := chain.(core.BorStateSyncer)
+	if !ok {
+		return nil, errStateSyncerUnsupported
+	}
+
 	var (
 		stateSyncData []*types.StateSyncData
 		err           error
 	)
 
 	if IsSprintStart(headerNumber, c.config.CalculateSprint(headerNumber)) {
-		cx := statefull.ChainContext{Chain: chain, Bor: c}
-
-		// check and commit span
-		if err = c.checkAndCommitSpan(state, header, cx); err != nil {
-			log.Error("Error while committing span", "error", err)
+		if stateSyncData, err = c.applySystemCalls(chain, header, state, nil, true); err != nil {
 			return nil, err
 		}
-
-		if c.HeimdallClient != nil {
-			// commit states
-			stateSyncData, err = c.CommitStates(state, header, cx)
-			if err != nil {
-				log.Error("Error while committing states", "error", err)
-				return nil, err
-			}
-		}
 	}
 
 	if err = c.changeContractCodeIfNeeded(headerNumber, state); err != nil {
@@ -1015,7 +1162,6 @@ func (c *Bor) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *typ
 	block := types.NewBlock(header, body, receipts, trie.NewStackTrie(nil))
 
 	// set state sync
-	bc := chain.(core.BorStateSyncer)
 	bc.SetStateSync(stateSyncData)
 
 	// return the final block for sealing
@@ -1060,6 +1206,11 @@ func (c *Bor) Seal(chain consensus.ChainHeaderReader, block *types.Block, result
 		return &UnauthorizedSignerError{number - 1, currentSigner.signer.Bytes()}
 	}
 
+	// Catch misconfigured signers before producing blocks the network rejects
+	if err := c.checkSealerProducer(currentSigner.signer, number); err != nil {
+		return err
+	}
+
 	successionNumber, err := snap.GetSignerSuccessionNumber(currentSigner.signer)
 	if err != nil {
 		return err
@@ -1069,14 +1220,14 @@ func (c *Bor) Seal(chain consensus.ChainHeaderReader, block *types.Block, result
 
 	// Sweet, the protocol permits us to sign the block, wait for our time
 	if c.config.IsBhilai(header.Number) {
-		delay = time.Until(time.Unix(int64(header.Time), 0)) // Wait until we reach header time for non-primary validators
+		delay = time.Unix(int64(header.Time), 0).Sub(c.now()) // Wait until we reach header time for non-primary validators
 		if successionNumber == 0 {
 			// For primary producers, set the delay to `header.Time - block time` instead of `header.Time`
 			// for early block announcement instead of waiting for full block time.
-			delay = time.Until(time.Unix(int64(header.Time-c.config.CalculatePeriod(number)), 0))
+			delay = time.Unix(int64(header.Time-c.config.CalculatePeriod(number)), 0).Sub(c.now())
 		}
 	} else {
-		delay = time.Until(time.Unix(int64(header.Time), 0)) // Wait until we reach header time
+		delay = time.Unix(int64(header.Time), 0).Sub(c.now()) // Wait until we reach header time
 	}
 
 	// wiggle was already accounted for in header.Time, this is just for logging
@@ -1096,7 +1247,7 @@ func (c *Bor) Seal(chain consensus.ChainHeaderReader, block *types.Block, result
 		case <-stop:
 			log.Debug("Discarding sealing operation for block", "number", number)
 			return
-		case <-time.After(delay):
+		case <-c.after(delay):
 			if wiggle > 0 {
 				log.Info(
 					"Sealing out-of-turn",
@@ -1164,12 +1315,32 @@ func (c *Bor) APIs(chain consensus.ChainHeaderReader) []rpc.API {
 	}}
 }
 
-// Close implements consensus.Engine. It's a noop for bor as there are no background threads.
+// Close implements consensus.Engine, stopping the span reconciliation and persisting the
+// pending snapshots.
 func (c *Bor) Close() error {
 	c.closeOnce.Do(func() {
+		if c.quit != nil {
+			close(c.quit)
+		}
+
+		if c.db != nil {
+			batch := c.db.NewBatch()
+			if err := c.FlushSnapshots(batch); err != nil {
+				log.Warn("Failed to flush bor snapshots", "err", err)
+			} else if err := batch.Write(); err != nil {
+				log.Warn("Failed to write bor snapshots", "err", err)
+			}
+		}
+
 		if c.HeimdallClient != nil {
 			c.HeimdallClient.Close()
 		}
+
+		if c.HeimdallWSClient != nil {
+			if err := c.HeimdallWSClient.Close(); err != nil {
+				log.Warn("Failed to close heimdall ws client", "err", err)
+			}
+		}
 	})
 
 	return nil
@@ -1179,6 +1350,7 @@ func (c *Bor) checkAndCommitSpan(
 	state vm.StateDB,
 	header *types.Header,
 	chain core.ChainContext,
+	tracer *balance_tracing.Hooks,
 ) error {
 	var ctx = context.Background()
 	headerNumber := header.Number.Uint64()
@@ -1189,7 +1361,7 @@ func (c *Bor) checkAndCommitSpan(
 	}
 
 	if c.needToCommitSpan(span, headerNumber) {
-		return c.FetchAndCommitSpan(ctx, span.Id+1, state, header, chain)
+		return c.fetchAndCommitSpan(ctx, span.Id+1, state, header, chain, tracer)
 	}
 
 	return nil
@@ -1229,6 +1401,37 @@ func (c *Bor) FetchAndCommitSpan(
 	header *types.Header,
 	chain core.ChainContext,
 ) error {
+	return c.fetchAndCommitSpan(ctx, newSpanID, state, header, chain, nil)
+}
+
+// fetchAndCommitSpan is FetchAndCommitSpan notifying the given tracer, if any, of the
+// committed span.
+func (c *Bor) fetchAndCommitSpan(
+	ctx context.Context,
+	newSpanID uint64,
+	state vm.StateDB,
+	header *types.Header,
+	chain core.ChainContext,
+	tracer *balance_tracing.Hooks,
+) error {
+	// Committing a span twice corrupts the producer selection, skip the commit if the
+	// validator contract already holds the span in the state of the block, i.e. including
+	// a commit already applied to it. The decision only depends on that state, so every
+	// validator makes the same.
+	if reader, ok := c.spanner.(SpanStateReader); ok {
+		currentSpan, err := reader.GetCurrentSpanFromState(state, header, chain)
+		if err != nil {
+			return err
+		}
+
+		if currentSpan.Id == newSpanID {
+			log.Warn("Skipping commit of the span already held by the validator contract", "number", header.Number, "spanID", newSpanID)
+			spanCommitSkippedCounter.Inc(1)
+
+			return nil
+		}
+	}
+
 	var (
 		minSpan    borTypes.Span
 		chainId    string
@@ -1291,7 +1494,20 @@ func (c *Bor) FetchAndCommitSpan(
 		)
 	}
 
-	return c.spanner.CommitSpan(ctx, minSpan, validators, producers, state, header, chain)
+	if err := c.spanner.CommitSpan(ctx, minSpan, validators, producers, state, header, chain); err != nil {
+		return err
+	}
+
+	if tracer != nil && tracer.OnSpanCommitted != nil {
+		addresses := make([]common.Address, len(producers))
+		for i, producer := range producers {
+			addresses[i] = producer.Signer
+		}
+
+		tracer.OnSpanCommitted(minSpan.Id, minSpan.StartBlock, minSpan.EndBlock, addresses)
+	}
+
+	return nil
 }
 
 // CommitStates commit states
@@ -1299,6 +1515,20 @@ func (c *Bor) CommitStates(
 	state vm.StateDB,
 	header *types.Header,
 	chain statefull.ChainContext,
+) ([]*types.StateSyncData, error) {
+	return c.commitStates(state, header, chain, nil, false)
+}
+
+// commitStates is CommitStates notifying the given tracer, if any, of every committed
+// state sync event. If the block is being assembled, an event recorded past the
+// confirmation window fails it after the StateSyncWindowCheck fork, instead of being
+// left for the next sprint.
+func (c *Bor) commitStates(
+	state vm.StateDB,
+	header *types.Header,
+	chain statefull.ChainContext,
+	tracer *balance_tracing.Hooks,
+	assemble bool,
 ) ([]*types.StateSyncData, error) {
 	fetchStart := time.Now()
 	number := header.Number.Uint64()
@@ -1347,13 +1577,15 @@ func (c *Bor) CommitStates(
 		"fromID", from,
 		"to", to.Format(time.RFC3339))
 
-	var eventRecords []*clerk.EventRecordWithTime
-	eventRecords, err = c.HeimdallClient.StateSyncEvents(context.Background(), from, to.Unix())
-	if err != nil {
-		log.Error("Error occurred when fetching state sync events", "fromID", from, "to", to.Unix(), "err", err)
+	eventRecords, prefetched := c.prefetchedStateSyncEvents(number, from, to.Unix())
+	if !prefetched {
+		eventRecords, err = c.HeimdallClient.StateSyncEvents(context.Background(), from, to.Unix())
+		if err != nil {
+			log.Error("Error occurred when fetching state sync events", "fromID", from, "to", to.Unix(), "err", err)
 
-		stateSyncs := make([]*types.StateSyncData, 0)
-		return stateSyncs, nil
+			stateSyncs := make([]*types.StateSyncData, 0)
+			return stateSyncs, nil
+		}
 	}
 
 	// This if statement checks if there are any state sync record overrides configured for the current block number.
@@ -1379,7 +1611,12 @@ func (c *Bor) CommitStates(
 	chainID := c.chainConfig.ChainID.String()
 	stateSyncs := make([]*types.StateSyncData, 0, len(eventRecords))
 
-	var gasUsed uint64
+	var (
+		gasUsed     uint64
+		maxEvents   = c.config.CalculateStateSyncMaxEventsPerBlock(number)
+		maxDataSize = c.config.CalculateStateSyncMaxDataSize(number)
+		checkAddr   = c.config.IsStateSyncAddressCheck(header.Number)
+	)
 
 	for _, eventRecord := range eventRecords {
 		if eventRecord.ID <= lastStateID {
@@ -1388,9 +1625,31 @@ func (c *Bor) CommitStates(
 
 		if err = validateEventRecord(eventRecord, number, to, lastStateID, chainID); err != nil {
 			log.Error("while validating event record", "block", number, "to", to, "stateID", lastStateID+1, "error", err.Error())
+
+			// Events past the window are only served by a faulty heimdall, don't build the
+			// block from its response. An imported block is checked by its state root.
+			if assemble && c.config.IsStateSyncWindowCheck(header.Number) && !eventRecord.Time.Before(to) {
+				return nil, err
+			}
+
 			break
 		}
 
+		// The remaining events are committed in the next sprints
+		if maxEvents > 0 && uint64(len(stateSyncs)) >= maxEvents {
+			log.Info("Maximum number of state sync events per block reached", "block", number, "max", maxEvents, "nextStateID", eventRecord.ID)
+			break
+		}
+
+		// Events violating the limits are committed without their data, keeping the state
+		// ids sequential. This mirrors how heimdall handles events with oversized data.
+		if limitErr := checkEventRecordLimits(eventRecord, maxDataSize, checkAddr); limitErr != nil {
+			log.Error("Skipping state sync event", "block", number, "stateID", eventRecord.ID, "contract", eventRecord.Contract, "err", limitErr)
+			stateSyncSkippedCounter.Inc(1)
+
+			eventRecord = skipEventRecord(eventRecord)
+		}
+
 		stateData := types.StateSyncData{
 			ID:       eventRecord.ID,
 			Contract: eventRecord.Contract,
@@ -1404,6 +1663,11 @@ func (c *Bor) CommitStates(
 		// if the receiver address is not a contract then we'll skip the most of the execution and emitting an event as well
 		// https://github.com/0xPolygon/genesis-contracts/blob/master/contracts/StateReceiver.sol#L27
 		gasUsed, err = c.GenesisContractsClient.CommitState(eventRecord, state, header, chain)
+
+		if tracer != nil && tracer.OnStateSyncApplied != nil {
+			tracer.OnStateSyncApplied(eventRecord.ID, number, err == nil)
+		}
+
 		if err != nil {
 			return nil, err
 		}
@@ -1415,7 +1679,7 @@ func (c *Bor) CommitStates(
 
 	processTime := time.Since(processStart)
 
-	log.Info("StateSyncData", "gas", totalGas, "number", number, "lastStateID", lastStateID, "total records", len(eventRecords), "fetch time", int(fetchTime.Milliseconds()), "process time", int(processTime.Milliseconds()))
+	log.Info("StateSyncData", "gas", totalGas, "number", number, "lastStateID", lastStateID, "total records", len(eventRecords), "prefetched", prefetched, "fetch time", int(fetchTime.Milliseconds()), "process time", int(processTime.Milliseconds()))
 
 	return stateSyncs, nil
 }
@@ -1429,6 +1693,92 @@ func validateEventRecord(eventRecord *clerk.EventRecordWithTime, number uint64,
 	return nil
 }
 
+// checkEventRecordLimits checks the size of the data of the event record against the given
+// limit (0 meaning no limit) and, if requested, that it isn't sent to the zero address.
+func checkEventRecordLimits(eventRecord *clerk.EventRecordWithTime, maxDataSize uint64, checkAddr bool) error {
+	if maxDataSize > 0 && uint64(len(eventRecord.Data)) > maxDataSize {
+		return fmt.Errorf("%w: %d bytes, max %d", errStateSyncDataTooLarge, len(eventRecord.Data), maxDataSize)
+	}
+
+	if checkAddr && eventRecord.Contract == (common.Address{}) {
+		return errStateSyncZeroAddress
+	}
+
+	return nil
+}
+
+// skipEventRecord returns a copy of the event record without data, so that committing it
+// only advances the state id.
+func skipEventRecord(eventRecord *clerk.EventRecordWithTime) *clerk.EventRecordWithTime {
+	skipped := *eventRecord
+	skipped.Data = nil
+
+	return &skipped
+}
+
+// HasPendingStateSyncEvents reports whether heimdall has state sync events which aren't
+// committed yet and could be committed in a block with the given header, built on top
+// of the given state.
+func (c *Bor) HasPendingStateSyncEvents(ctx context.Context, statedb *state.StateDB, header *types.Header) (bool, error) {
+	if c.HeimdallClient == nil {
+		return false, nil
+	}
+
+	number := header.Number.Uint64()
+
+	var stateDB *state.StateDB
+	if c.config.IsIndore(header.Number) {
+		stateDB = statedb.Copy()
+	}
+
+	lastStateID, err := c.GenesisContractsClient.LastStateId(stateDB, number-1, header.ParentHash)
+	if err != nil {
+		return false, err
+	}
+
+	to := header.Time
+	if delay := c.config.CalculateStateSyncDelay(number); to > delay {
+		to -= delay
+	}
+
+	events, err := c.HeimdallClient.StateSyncEvents(ctx, lastStateID.Uint64()+1, int64(to))
+	if err != nil {
+		return false, err
+	}
+
+	return len(events) > 0, nil
+}
+
+// SetTracer sets the live tracer notified of the spans committed and of the state sync
+// events committed by the imported blocks.
+func (c *Bor) SetTracer(tracer *balance_tracing.Hooks) {
+	c.tracer = tracer
+}
+
+// SetHeimdallHealth sets the tracker reporting the connectivity status of heimdall.
+func (c *Bor) SetHeimdallHealth(h *HeimdallHealth) {
+	c.heimdallHealth = h
+}
+
+// SetRootHashMaxRange sets the maximum block range the root hash APIs accept, and the
+// maximum page size of the paginated variant. It is capped at MaxCheckpointLength, zero
+// restores the default of MaxCheckpointLength.
+func (c *Bor) SetRootHashMaxRange(limit uint64) {
+	c.rootHashMaxRange = min(limit, MaxCheckpointLength)
+}
+
+// SetStrictSealing sets whether blocks are refused to be sealed if the local signer isn't
+// a producer of their span, rather than only reporting it.
+func (c *Bor) SetStrictSealing(strict bool) {
+	c.strictSealing = strict
+}
+
+// HeimdallHealth returns the tracker reporting the connectivity status of heimdall, or
+// nil if it isn't tracked.
+func (c *Bor) HeimdallHealth() *HeimdallHealth {
+	return c.heimdallHealth
+}
+
 func (c *Bor) SetHeimdallClient(h IHeimdallClient) {
 	c.HeimdallClient = h
 	// Update the heimdall client in span store
@@ -1,14 +1,24 @@
 package bor
 
 import (
+	"context"
+	"encoding/json"
 	"math/big"
+	"os"
 	"testing"
+	"time"
 
+	borTypes "github.com/0xPolygon/heimdall-v2/x/bor/types"
+	"github.com/golang/mock/gomock"
 	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/require"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil" //nolint:typecheck
+	"github.com/ethereum/go-ethereum/consensus/bor/clerk"
+	"github.com/ethereum/go-ethereum/consensus/bor/contract"
+	borSpan "github.com/ethereum/go-ethereum/consensus/bor/heimdall/span"
+	"github.com/ethereum/go-ethereum/consensus/bor/statefull"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -125,6 +135,18 @@ func TestGenesisContractChange(t *testing.T) {
 	require.Equal(t, statedb.GetBalance(addr0), uint256.NewInt(4096))
 }
 
+// Tests that assembling a block on a chain which can't take the committed state sync
+// events fails instead of panicking.
+func TestFinalizeAndAssembleWithoutStateSyncer(t *testing.T) {
+	t.Parallel()
+
+	engine := &Bor{config: &params.BorConfig{Sprint: map[string]uint64{"0": 16}}}
+	header := &types.Header{Number: big.NewInt(1)}
+
+	_, err := engine.FinalizeAndAssemble(&headChainReader{head: header}, header, nil, &types.Body{}, nil)
+	require.ErrorIs(t, err, errStateSyncerUnsupported)
+}
+
 func TestEncodeSigHeaderJaipur(t *testing.T) {
 	t.Parallel()
 
@@ -162,3 +184,277 @@ func TestEncodeSigHeaderJaipur(t *testing.T) {
 	hash = SealHash(h, &params.BorConfig{JaipurBlock: big.NewInt(10)})
 	require.Equal(t, hash, hashWithoutBaseFee)
 }
+
+// stateSyncHeimdallClient serves the given state sync events
+type stateSyncHeimdallClient struct {
+	MockHeimdallClient
+	events []*clerk.EventRecordWithTime
+}
+
+func (h *stateSyncHeimdallClient) StateSyncEvents(_ context.Context, _ uint64, _ int64) ([]*clerk.EventRecordWithTime, error) {
+	return h.events, nil
+}
+
+func TestCheckEventRecordLimits(t *testing.T) {
+	t.Parallel()
+
+	event := &clerk.EventRecordWithTime{
+		EventRecord: clerk.EventRecord{
+			ID:       1,
+			Contract: common.HexToAddress("0x1"),
+			Data:     make([]byte, 64),
+		},
+	}
+
+	require.NoError(t, checkEventRecordLimits(event, 0, true))
+	require.NoError(t, checkEventRecordLimits(event, 64, true))
+	require.ErrorIs(t, checkEventRecordLimits(event, 63, true), errStateSyncDataTooLarge)
+
+	event.Contract = common.Address{}
+	require.NoError(t, checkEventRecordLimits(event, 0, false))
+	require.ErrorIs(t, checkEventRecordLimits(event, 0, true), errStateSyncZeroAddress)
+}
+
+func TestCommitStatesSkipsOversizedEvent(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	contract := common.HexToAddress("0x1")
+	events := []*clerk.EventRecordWithTime{
+		{EventRecord: clerk.EventRecord{ID: 1, Contract: contract, Data: make([]byte, 32), ChainID: "137"}},
+		{EventRecord: clerk.EventRecord{ID: 2, Contract: contract, Data: make([]byte, 1024), ChainID: "137"}},
+		{EventRecord: clerk.EventRecord{ID: 3, Contract: contract, Data: make([]byte, 32), ChainID: "137"}},
+		{EventRecord: clerk.EventRecord{ID: 4, Contract: contract, Data: make([]byte, 32), ChainID: "137"}},
+	}
+
+	var committed []*clerk.EventRecordWithTime
+
+	genesisContracts := NewMockGenesisContract(ctrl)
+	genesisContracts.EXPECT().LastStateId(gomock.Any(), gomock.Any(), gomock.Any()).Return(big.NewInt(0), nil).AnyTimes()
+	genesisContracts.EXPECT().CommitState(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(event *clerk.EventRecordWithTime, _ vm.StateDB, _ *types.Header, _ statefull.ChainContext) (uint64, error) {
+			committed = append(committed, event)
+			return 0, nil
+		}).AnyTimes()
+
+	b := &Bor{
+		chainConfig: &params.ChainConfig{ChainID: big.NewInt(137)},
+		config: &params.BorConfig{
+			Sprint:                     map[string]uint64{"0": 16},
+			IndoreBlock:                big.NewInt(0),
+			StateSyncConfirmationDelay: map[string]uint64{"0": 0},
+			StateSyncMaxDataSize:       map[string]uint64{"0": 512},
+			StateSyncMaxEventsPerBlock: map[string]uint64{"0": 3},
+		},
+		GenesisContractsClient: genesisContracts,
+		HeimdallClient:         &stateSyncHeimdallClient{events: events},
+	}
+
+	header := &types.Header{Number: big.NewInt(16), Time: 1000}
+	for _, event := range events {
+		event.Time = time.Unix(int64(header.Time)-1, 0)
+	}
+
+	db := rawdb.NewMemoryDatabase()
+
+	commit := func() []*types.StateSyncData {
+		statedb, err := state.New(types.EmptyRootHash, state.NewDatabase(triedb.NewDatabase(db, triedb.HashDefaults), nil))
+		require.NoError(t, err)
+
+		committed = nil
+
+		stateSyncs, err := b.CommitStates(statedb, header, statefull.ChainContext{})
+		require.NoError(t, err)
+
+		return stateSyncs
+	}
+
+	stateSyncs := commit()
+
+	// Event 4 exceeds the maximum number of events per block and is left for the next sprint
+	require.Len(t, stateSyncs, 3)
+	require.Len(t, committed, 3)
+
+	for i, stateSync := range stateSyncs {
+		require.Equal(t, uint64(i+1), stateSync.ID)
+		require.Equal(t, uint64(i+1), committed[i].ID)
+	}
+
+	// The oversized event is committed without its data
+	require.Empty(t, committed[1].Data)
+	require.Empty(t, stateSyncs[1].Data)
+	require.Len(t, committed[0].Data, 32)
+	require.Len(t, committed[2].Data, 32)
+
+	// The events served by heimdall are left untouched
+	require.Len(t, events[1].Data, 1024)
+
+	// Committing the same events again yields the same result
+	first := committed
+	require.Equal(t, stateSyncs, commit())
+	require.Equal(t, first, committed)
+}
+
+func TestCommitStatesWindowCheck(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	genesisContracts := NewMockGenesisContract(ctrl)
+	genesisContracts.EXPECT().LastStateId(gomock.Any(), gomock.Any(), gomock.Any()).Return(big.NewInt(0), nil).AnyTimes()
+	genesisContracts.EXPECT().CommitState(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(uint64(0), nil).AnyTimes()
+
+	// The window of the block ends 10 seconds before its timestamp
+	header := &types.Header{Number: big.NewInt(16), Time: 1000}
+	to := time.Unix(990, 0)
+
+	newBor := func(windowCheck *big.Int, events ...time.Time) *Bor {
+		records := make([]*clerk.EventRecordWithTime, len(events))
+		for i, recordTime := range events {
+			records[i] = &clerk.EventRecordWithTime{
+				EventRecord: clerk.EventRecord{ID: uint64(i + 1), Contract: common.HexToAddress("0x1"), ChainID: "137"},
+				Time:        recordTime,
+			}
+		}
+
+		return &Bor{
+			chainConfig: &params.ChainConfig{ChainID: big.NewInt(137)},
+			config: &params.BorConfig{
+				Sprint:                     map[string]uint64{"0": 16},
+				IndoreBlock:                big.NewInt(0),
+				StateSyncConfirmationDelay: map[string]uint64{"0": 10},
+				StateSyncWindowCheckBlock:  windowCheck,
+			},
+			GenesisContractsClient: genesisContracts,
+			HeimdallClient:         &stateSyncHeimdallClient{events: records},
+		}
+	}
+
+	newState := func() *state.StateDB {
+		statedb, err := state.New(types.EmptyRootHash, state.NewDatabase(triedb.NewDatabase(rawdb.NewMemoryDatabase(), triedb.HashDefaults), nil))
+		require.NoError(t, err)
+
+		return statedb
+	}
+
+	assemble := func(b *Bor) ([]*types.StateSyncData, error) {
+		return b.commitStates(newState(), header, statefull.ChainContext{}, nil, true)
+	}
+
+	// The event recorded right before the end of the window is committed
+	stateSyncs, err := assemble(newBor(common.Big0, to.Add(-time.Nanosecond)))
+	require.NoError(t, err)
+	require.Len(t, stateSyncs, 1)
+
+	// The event recorded exactly at the end of the window is left for the next sprint
+	// before the fork
+	stateSyncs, err = assemble(newBor(big.NewInt(17), to.Add(-time.Second), to))
+	require.NoError(t, err)
+	require.Len(t, stateSyncs, 1)
+
+	// and fails building the block after it
+	_, err = assemble(newBor(common.Big0, to.Add(-time.Second), to))
+
+	var invalid *InvalidStateReceivedError
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, uint64(2), invalid.Event.ID)
+
+	// An imported block still leaves it for the next sprint after the fork, the block
+	// being checked by its state root
+	b := newBor(common.Big0, to.Add(-time.Second), to)
+
+	spanner := NewMockSpanner(ctrl)
+	spanner.EXPECT().GetCurrentSpan(gomock.Any(), gomock.Any()).Return(&borTypes.Span{Id: 1, StartBlock: 256, EndBlock: 6655}, nil).AnyTimes()
+	b.spanner = spanner
+
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, &core.Genesis{Config: &params.ChainConfig{}}, nil, b, vm.Config{}, nil, nil, nil)
+	require.NoError(t, err)
+
+	defer chain.Stop()
+
+	b.Finalize(chain, header, newState(), &types.Body{})
+	require.Len(t, chain.GetStateSync(), 1)
+	require.Equal(t, uint64(1), chain.GetStateSync()[0].ID)
+}
+
+// chainSpanHeimdallClient serves the spans of MockHeimdallClientWithProducers for the
+// given chain
+type chainSpanHeimdallClient struct {
+	MockHeimdallClientWithProducers
+	chainID string
+}
+
+func (h *chainSpanHeimdallClient) GetSpan(ctx context.Context, spanID uint64) (*borTypes.Span, error) {
+	span, err := h.MockHeimdallClientWithProducers.GetSpan(ctx, spanID)
+	if err != nil {
+		return nil, err
+	}
+
+	span.BorChainId = h.chainID
+
+	return span, nil
+}
+
+func TestFetchAndCommitSpanSkipsCommittedSpan(t *testing.T) {
+	t.Parallel()
+
+	genesisData, err := os.ReadFile("../../tests/bor/testdata/genesis.json")
+	require.NoError(t, err)
+
+	genspec := &core.Genesis{}
+	require.NoError(t, json.Unmarshal(genesisData, genspec))
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := genspec.MustCommit(db, triedb.NewDatabase(db, triedb.HashDefaults))
+
+	statedb, err := state.New(genesis.Root(), state.NewDatabase(triedb.NewDatabase(db, triedb.HashDefaults), nil))
+	require.NoError(t, err)
+
+	var (
+		chainID        = genspec.Config.ChainID.String()
+		heimdallClient = &chainSpanHeimdallClient{chainID: chainID}
+		spanner        = borSpan.NewChainSpanner(nil, contract.ValidatorSet(), genspec.Config, common.HexToAddress(genspec.Config.Bor.ValidatorContract))
+	)
+
+	b := &Bor{
+		chainConfig:    genspec.Config,
+		config:         genspec.Config.Bor,
+		spanner:        spanner,
+		HeimdallClient: heimdallClient,
+		spanStore:      NewSpanStore(heimdallClient, spanner, chainID, nil),
+	}
+
+	var (
+		header  = &types.Header{Number: big.NewInt(1), ParentHash: genesis.Hash(), Difficulty: big.NewInt(1), GasLimit: genesis.GasLimit()}
+		chain   = statefull.ChainContext{Bor: b}
+		skipped = spanCommitSkippedCounter.Snapshot().Count()
+	)
+
+	currentSpanID := func() uint64 {
+		t.Helper()
+
+		current, err := spanner.GetCurrentSpanFromState(statedb, header, chain)
+		require.NoError(t, err)
+
+		return current.Id
+	}
+
+	// The span is committed to the validator contract
+	require.Zero(t, currentSpanID())
+	require.NoError(t, b.FetchAndCommitSpan(context.Background(), 1, statedb, header, chain))
+	require.Equal(t, uint64(1), currentSpanID())
+	require.Equal(t, skipped, spanCommitSkippedCounter.Snapshot().Count())
+
+	// Committing it again in the same block is short-circuited
+	require.NoError(t, b.FetchAndCommitSpan(context.Background(), 1, statedb, header, chain))
+	require.Equal(t, uint64(1), currentSpanID())
+	require.Equal(t, skipped+1, spanCommitSkippedCounter.Snapshot().Count())
+
+	// The next span is still committed
+	require.NoError(t, b.FetchAndCommitSpan(context.Background(), 2, statedb, header, chain))
+	require.Equal(t, uint64(2), currentSpanID())
+	require.Equal(t, skipped+1, spanCommitSkippedCounter.Snapshot().Count())
+}
@@ -0,0 +1,47 @@
+package bor
+
+import "time"
+
+// Clock is the source of time of the producer timing logic of the bor engine, i.e.
+// header timestamps, future block checks and the waits before sealing. It can be
+// replaced in tests to control the passing of time instead of sleeping.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for the duration to elapse and then sends the current time on
+	// the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock backed by the system time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// SetClock replaces the clock of the engine. It must be called before the engine is
+// used.
+func (c *Bor) SetClock(clock Clock) {
+	c.clock = clock
+}
+
+// now returns the current time of the clock of the engine, falling back to the
+// system time if none is set.
+func (c *Bor) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+
+	return c.clock.Now()
+}
+
+// after waits for the duration on the clock of the engine, falling back to the
+// system time if none is set.
+func (c *Bor) after(d time.Duration) <-chan time.Time {
+	if c.clock == nil {
+		return time.After(d)
+	}
+
+	return c.clock.After(d)
+}
@@ -0,0 +1,261 @@
+package bor
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bor/valset"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// manualClock is a Clock whose time only moves when advanced explicitly.
+type manualClock struct {
+	now     time.Time
+	waiters []manualWaiter
+	lock    sync.Mutex
+}
+
+type manualWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newManualClock(now time.Time) *manualClock {
+	return &manualClock{now: now}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	c.waiters = append(c.waiters, manualWaiter{deadline: c.now.Add(d), ch: ch})
+
+	return ch
+}
+
+// Advance moves the time forward, firing the waiters whose deadline is reached.
+func (c *manualClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+
+	pending := c.waiters[:0]
+	for _, waiter := range c.waiters {
+		if waiter.deadline.After(c.now) {
+			pending = append(pending, waiter)
+			continue
+		}
+
+		waiter.ch <- c.now
+	}
+
+	c.waiters = pending
+}
+
+// Waiters returns the number of pending waits.
+func (c *manualClock) Waiters() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return len(c.waiters)
+}
+
+// newSealTestEngine creates an engine sealing with the validator of the given
+// succession, out of two validators, on top of the returned parent header.
+func newSealTestEngine(t *testing.T, config *params.BorConfig, clock Clock, succession int) (*Bor, *types.Header) {
+	t.Helper()
+
+	parent := &types.Header{Number: big.NewInt(9)}
+	snap := &Snapshot{
+		Number: 9,
+		Hash:   parent.Hash(),
+		ValidatorSet: valset.NewValidatorSet([]*valset.Validator{
+			valset.NewValidator(common.HexToAddress("0x01"), 10),
+			valset.NewValidator(common.HexToAddress("0x02"), 10),
+		}),
+	}
+
+	recents, _ := lru.NewARC(inmemorySnapshots)
+	recents.Add(parent.Hash(), snap)
+
+	engine := &Bor{config: config, recents: recents}
+	engine.SetClock(clock)
+
+	for _, validator := range snap.ValidatorSet.Validators {
+		if s, _ := snap.GetSignerSuccessionNumber(validator.Address); s == succession {
+			engine.Authorize(validator.Address, func(accounts.Account, string, []byte) ([]byte, error) {
+				return make([]byte, types.ExtraSealLength), nil
+			})
+		}
+	}
+
+	return engine, parent
+}
+
+// sealAt seals a block on top of the parent with the given timestamp and returns the
+// channel the sealed block is delivered on.
+func sealAt(t *testing.T, engine *Bor, parent *types.Header, timestamp uint64) <-chan *types.Block {
+	t.Helper()
+
+	header := &types.Header{
+		Number:     big.NewInt(10),
+		ParentHash: parent.Hash(),
+		Time:       timestamp,
+		Extra:      make([]byte, types.ExtraVanityLength+types.ExtraSealLength),
+	}
+	results := make(chan *types.Block, 1)
+
+	require.NoError(t, engine.Seal(nil, types.NewBlockWithHeader(header), results, make(chan struct{})))
+
+	return results
+}
+
+// waitForWaiter waits until the sealing goroutine started waiting on the clock.
+func waitForWaiter(t *testing.T, clock *manualClock) {
+	t.Helper()
+
+	require.Eventually(t, func() bool { return clock.Waiters() > 0 }, 5*time.Second, time.Millisecond)
+}
+
+func requireNotSealed(t *testing.T, results <-chan *types.Block) {
+	t.Helper()
+
+	select {
+	case <-results:
+		t.Fatal("block sealed too early")
+	default:
+	}
+}
+
+func requireSealed(t *testing.T, results <-chan *types.Block) {
+	t.Helper()
+
+	select {
+	case <-results:
+	case <-time.After(5 * time.Second):
+		t.Fatal("block not sealed")
+	}
+}
+
+func TestSealOutOfTurnDelay(t *testing.T) {
+	t.Parallel()
+
+	var (
+		start  = time.Unix(1700000000, 0)
+		clock  = newManualClock(start)
+		config = &params.BorConfig{
+			Period:           map[string]uint64{"0": 2},
+			ProducerDelay:    map[string]uint64{"0": 6},
+			Sprint:           map[string]uint64{"0": 16},
+			BackupMultiplier: map[string]uint64{"0": 2},
+		}
+	)
+
+	// The backup producer waits for the header time, which accounts for its wiggle
+	engine, parent := newSealTestEngine(t, config, clock, 1)
+	parent.Time = uint64(start.Unix())
+
+	results := sealAt(t, engine, parent, parent.Time+CalcProducerDelay(10, 1, config))
+	waitForWaiter(t, clock)
+
+	clock.Advance(3 * time.Second)
+	requireNotSealed(t, results)
+
+	clock.Advance(time.Second)
+	requireSealed(t, results)
+
+	// Header times already passed, e.g. due to clock skew, are sealed right away
+	results = sealAt(t, engine, parent, uint64(clock.Now().Unix())-10)
+	requireSealed(t, results)
+}
+
+func TestSealPrimaryEarlyAnnouncement(t *testing.T) {
+	t.Parallel()
+
+	var (
+		start  = time.Unix(1700000000, 0)
+		clock  = newManualClock(start)
+		config = &params.BorConfig{
+			Period:           map[string]uint64{"0": 2},
+			ProducerDelay:    map[string]uint64{"0": 6},
+			Sprint:           map[string]uint64{"0": 16},
+			BackupMultiplier: map[string]uint64{"0": 2},
+			BhilaiBlock:      big.NewInt(0),
+		}
+	)
+
+	// Post Bhilai, the primary producer announces its block one period early
+	engine, parent := newSealTestEngine(t, config, clock, 0)
+
+	results := sealAt(t, engine, parent, uint64(start.Unix())+6)
+	waitForWaiter(t, clock)
+
+	clock.Advance(3 * time.Second)
+	requireNotSealed(t, results)
+
+	clock.Advance(time.Second)
+	requireSealed(t, results)
+
+	// While the backup producers wait for the full header time
+	engine, parent = newSealTestEngine(t, config, clock, 1)
+
+	results = sealAt(t, engine, parent, uint64(clock.Now().Unix())+6)
+	waitForWaiter(t, clock)
+
+	clock.Advance(5 * time.Second)
+	requireNotSealed(t, results)
+
+	clock.Advance(time.Second)
+	requireSealed(t, results)
+}
+
+func TestVerifyHeaderFutureBlock(t *testing.T) {
+	t.Parallel()
+
+	var (
+		start  = time.Unix(1700000000, 0)
+		clock  = newManualClock(start)
+		engine = &Bor{config: &params.BorConfig{
+			Period: map[string]uint64{"0": 2},
+			Sprint: map[string]uint64{"0": 16},
+		}}
+		header = &types.Header{Number: big.NewInt(10), Time: uint64(start.Unix()) + 1}
+	)
+
+	engine.SetClock(clock)
+
+	// A block from the future of the local clock is rejected
+	err := engine.verifyHeader(nil, header, nil)
+	require.ErrorIs(t, err, consensus.ErrFutureBlock)
+
+	// Until the local clock catches up
+	clock.Advance(time.Second)
+
+	err = engine.verifyHeader(nil, header, nil)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, consensus.ErrFutureBlock), "unexpected future block error once the clock caught up")
+}
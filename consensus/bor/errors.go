@@ -4,20 +4,30 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/bor/clerk"
 )
 
+// MaxCheckpointLengthExceededError is returned if a root hash is requested for a block
+// range longer than the allowed maximum.
 type MaxCheckpointLengthExceededError struct {
 	Start uint64
 	End   uint64
+	Limit uint64 // Maximum range length, MaxCheckpointLength if zero
 }
 
 func (e *MaxCheckpointLengthExceededError) Error() string {
+	limit := e.Limit
+	if limit == 0 {
+		limit = MaxCheckpointLength
+	}
+
 	return fmt.Sprintf(
 		"Start: %d and end block: %d exceed max allowed checkpoint length: %d",
 		e.Start,
 		e.End,
-		MaxCheckpointLength,
+		limit,
 	)
 }
 
@@ -38,16 +48,28 @@ func (e *MismatchingValidatorsError) Error() string {
 	)
 }
 
+// BlockTooSoonError is returned if a block is timestamped earlier than the producer
+// delay configured for its height and the turn-ness of its signer allows.
 type BlockTooSoonError struct {
-	Number     uint64
-	Succession int
+	Number           uint64
+	Succession       int
+	Time             uint64 // Timestamp of the block
+	ParentTime       uint64 // Timestamp of the parent block
+	Delay            uint64 // Configured delay of the primary producer (period or producer delay)
+	BackupMultiplier uint64 // Configured delay per succession of a backup producer
 }
 
 func (e *BlockTooSoonError) Error() string {
 	return fmt.Sprintf(
-		"Block %d was created too soon. Signer turn-ness number is %d\n",
+		"Block %d was created too soon. Signer turn-ness number is %d, time %d, expected at least %d (parent %d + delay %d + %d * backup multiplier %d)\n",
 		e.Number,
 		e.Succession,
+		e.Time,
+		e.ParentTime+e.Delay+uint64(e.Succession)*e.BackupMultiplier,
+		e.ParentTime,
+		e.Delay,
+		e.Succession,
+		e.BackupMultiplier,
 	)
 }
 
@@ -79,6 +101,23 @@ func (e *UnauthorizedSignerError) Error() string {
 	)
 }
 
+// SignerNotInProducerSetError is returned if the local signer refuses to seal a block
+// because it isn't a producer of the span of the block.
+type SignerNotInProducerSetError struct {
+	Number uint64
+	SpanId uint64
+	Signer common.Address
+}
+
+func (e *SignerNotInProducerSetError) Error() string {
+	return fmt.Sprintf(
+		"Signer %s is not a producer of span %d at block %d, refusing to seal",
+		e.Signer,
+		e.SpanId,
+		e.Number,
+	)
+}
+
 // WrongDifficultyError is returned if the difficulty of a block doesn't match the
 // turn of the signer.
 type WrongDifficultyError struct {
@@ -98,6 +137,72 @@ func (e *WrongDifficultyError) Error() string {
 	)
 }
 
+// SpanConflictError is returned if the seal of a block covered by conflicting spans
+// fails to verify, i.e. the block may have been sealed by the producers of a span
+// heimdall re-committed afterwards.
+type SpanConflictError struct {
+	Number   uint64
+	Conflict *SpanConflict
+	Err      error
+}
+
+func (e *SpanConflictError) Error() string {
+	return fmt.Sprintf(
+		"%v (block %d is claimed by span %d and by conflicting span %d with different producers, heimdall re-committed blocks %d-%d)",
+		e.Err,
+		e.Number,
+		e.Conflict.SpanId,
+		e.Conflict.ConflictingSpanId,
+		e.Conflict.StartBlock,
+		e.Conflict.EndBlock,
+	)
+}
+
+func (e *SpanConflictError) Unwrap() error {
+	return e.Err
+}
+
+// SpanValidatorsMismatchError is returned if the producers in the extra data of a sprint
+// end block don't match the producers of the span of the next block.
+type SpanValidatorsMismatchError struct {
+	Number uint64
+	SpanId uint64
+}
+
+func (e *SpanValidatorsMismatchError) Error() string {
+	return fmt.Sprintf(
+		"%v (block %d, span %d)",
+		errInvalidSpanValidators,
+		e.Number,
+		e.SpanId,
+	)
+}
+
+func (e *SpanValidatorsMismatchError) Unwrap() error {
+	return errInvalidSpanValidators
+}
+
+// SpanUnavailableError is returned if the producers in the extra data of a sprint end
+// block can't be verified because heimdall fails to serve the span of the next block.
+// The block isn't known to be invalid, see consensus.ErrVerificationDataUnavailable.
+type SpanUnavailableError struct {
+	Number uint64
+	Err    error
+}
+
+func (e *SpanUnavailableError) Error() string {
+	return fmt.Sprintf(
+		"Span of block %d unavailable to verify the validators of block %d: %v",
+		e.Number+1,
+		e.Number,
+		e.Err,
+	)
+}
+
+func (e *SpanUnavailableError) Unwrap() []error {
+	return []error{consensus.ErrVerificationDataUnavailable, e.Err}
+}
+
 type InvalidStateReceivedError struct {
 	Number      uint64
 	LastStateID uint64
@@ -114,3 +219,62 @@ func (e *InvalidStateReceivedError) Error() string {
 		e.LastStateID,
 	)
 }
+
+// Error codes of the bor specific JSON-RPC errors, reserved in the -32800..-32899 range.
+const (
+	SpanNotFoundErrorCode        = -32800
+	FinalityUnavailableErrorCode = -32801
+	WitnessUnavailableErrorCode  = -32802
+	HeimdallUnreachableErrorCode = -32803
+	StateSyncNotIndexedErrorCode = -32804
+)
+
+// SpanNotFoundError is returned by the RPC APIs when no span covers the requested block
+// or span id, according to heimdall.
+type SpanNotFoundError struct {
+	Err error
+}
+
+func (e *SpanNotFoundError) Error() string  { return e.Err.Error() }
+func (e *SpanNotFoundError) ErrorCode() int { return SpanNotFoundErrorCode }
+func (e *SpanNotFoundError) Unwrap() error  { return e.Err }
+
+// FinalityUnavailableError is returned by the RPC APIs when no milestone or checkpoint
+// is available to answer a finality query.
+type FinalityUnavailableError struct {
+	Err error
+}
+
+func (e *FinalityUnavailableError) Error() string  { return e.Err.Error() }
+func (e *FinalityUnavailableError) ErrorCode() int { return FinalityUnavailableErrorCode }
+func (e *FinalityUnavailableError) Unwrap() error  { return e.Err }
+
+// WitnessUnavailableError is returned by the RPC APIs when the witness of a block is
+// not available locally.
+type WitnessUnavailableError struct {
+	Err error
+}
+
+func (e *WitnessUnavailableError) Error() string  { return e.Err.Error() }
+func (e *WitnessUnavailableError) ErrorCode() int { return WitnessUnavailableErrorCode }
+func (e *WitnessUnavailableError) Unwrap() error  { return e.Err }
+
+// HeimdallUnreachableError is returned by the RPC APIs when the data they serve has to
+// be fetched from heimdall and heimdall is disabled or fails to serve it.
+type HeimdallUnreachableError struct {
+	Err error
+}
+
+func (e *HeimdallUnreachableError) Error() string  { return e.Err.Error() }
+func (e *HeimdallUnreachableError) ErrorCode() int { return HeimdallUnreachableErrorCode }
+func (e *HeimdallUnreachableError) Unwrap() error  { return e.Err }
+
+// StateSyncNotIndexedError is returned by the RPC APIs when the state sync event index
+// is disabled or hasn't caught up with the requested blocks yet.
+type StateSyncNotIndexedError struct {
+	Err error
+}
+
+func (e *StateSyncNotIndexedError) Error() string  { return e.Err.Error() }
+func (e *StateSyncNotIndexedError) ErrorCode() int { return StateSyncNotIndexedErrorCode }
+func (e *StateSyncNotIndexedError) Unwrap() error  { return e.Err }
@@ -19,12 +19,18 @@ type IHeimdallClient interface {
 	FetchCheckpointCount(ctx context.Context) (int64, error)
 	FetchMilestone(ctx context.Context) (*milestone.Milestone, error)
 	FetchMilestoneCount(ctx context.Context) (int64, error)
+	FetchMilestoneList(ctx context.Context, fromNumber, toNumber int64) ([]*milestone.Milestone, error)
 	Close()
 }
 
 //go:generate mockgen -destination=../../tests/bor/mocks/IHeimdallWSClient.go -package=mocks . IHeimdallWSClient
 type IHeimdallWSClient interface {
+	// SubscribeMilestoneEvents returns a channel on which new milestones are delivered. The
+	// channel is closed once the subscription stops.
 	SubscribeMilestoneEvents(ctx context.Context) <-chan *milestone.Milestone
+	// Unsubscribe stops the subscription and waits for it to wind down or for the context
+	// to be cancelled. Calling it more than once is a no-op.
 	Unsubscribe(ctx context.Context) error
+	// Close stops the subscription and terminates the underlying connection without waiting.
 	Close() error
 }
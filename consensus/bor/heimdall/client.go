@@ -1,6 +1,7 @@
 package heimdall
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
@@ -11,6 +12,7 @@ import (
 	"path"
 	"reflect"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/0xPolygon/heimdall-v2/x/bor/types"
@@ -25,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/milestone"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/klauspost/compress/zstd"
 )
 
 var (
@@ -35,36 +38,83 @@ var (
 	ErrNotInRejectedList     = errors.New("milestoneID doesn't exist in rejected list")
 	ErrNotInMilestoneList    = errors.New("milestoneID doesn't exist in Heimdall")
 	ErrServiceUnavailable    = errors.New("service unavailable")
+	ErrNotFound              = errors.New("not found")
+	ErrRetriesExhausted      = errors.New("retries exhausted")
 )
 
 const (
 	heimdallAPIBodyLimit = 128 * 1024 * 1024 // 128 MB
+	acceptEncoding       = "gzip, zstd"
 	stateFetchLimit      = 50
-	retryCall            = 5 * time.Second
+
+	// DefaultTimeout is the default timeout of a single request to heimdall. It's kept
+	// apart from the retry interval, as slow responses shouldn't be given up on as fast
+	// as failed requests are retried.
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultRetryInterval is the default delay between two attempts of a failed request
+	DefaultRetryInterval = 5 * time.Second
 )
 
 type HeimdallClient struct {
-	urlString string
-	client    http.Client
-	closeCh   chan struct{}
+	urlString     string
+	client        http.Client
+	closeCh       chan struct{}
+	retryInterval time.Duration
+	maxRetries    uint64    // 0 means retry until success or shutdown
+	errors        *errorLog // Recent failed requests
 }
 
 type Request struct {
 	client http.Client
 	url    *url.URL
 	start  time.Time
+	errors *errorLog // Log to record the request in if it fails, if any
+}
+
+// statusError is returned for the unsuccessful responses of heimdall
+type statusError struct {
+	code int
+	err  error
 }
 
-func NewHeimdallClient(urlString string, timeout time.Duration) *HeimdallClient {
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%v: response code %d", e.err, e.code)
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+// NewHeimdallClient creates a new heimdall HTTP client. Failed requests are retried every
+// retryInterval, up to maxRetries times (or indefinitely if maxRetries is 0).
+func NewHeimdallClient(urlString string, timeout time.Duration, retryInterval time.Duration, maxRetries uint64) *HeimdallClient {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	if retryInterval <= 0 {
+		retryInterval = DefaultRetryInterval
+	}
+
 	return &HeimdallClient{
 		urlString: urlString,
 		client: http.Client{
 			Timeout: timeout,
 		},
-		closeCh: make(chan struct{}),
+		closeCh:       make(chan struct{}),
+		retryInterval: retryInterval,
+		maxRetries:    maxRetries,
+		errors:        newErrorLog(),
 	}
 }
 
+// RecentErrors returns the last failed requests to heimdall, oldest first, and the
+// number of failed requests per path since startup.
+func (h *HeimdallClient) RecentErrors() *ErrorsReport {
+	return h.errors.report()
+}
+
 const (
 	fetchStateSyncEventsFormat = "from_id=%d&to_time=%s&pagination.limit=%d"
 	fetchStateSyncEventsPath   = "clerk/time"
@@ -72,8 +122,9 @@ const (
 	fetchCheckpoint      = "/checkpoints/%s"
 	fetchCheckpointCount = "/checkpoints/count"
 
-	fetchMilestone      = "/milestones/latest"
-	fetchMilestoneCount = "/milestones/count"
+	fetchMilestone         = "/milestones/latest"
+	fetchMilestoneCount    = "/milestones/count"
+	fetchMilestoneByNumber = "/milestones/%d"
 
 	fetchSpanFormat = "bor/spans/%d"
 	fetchLatestSpan = "bor/spans/latest"
@@ -93,7 +144,7 @@ func (h *HeimdallClient) StateSyncEvents(ctx context.Context, fromID uint64, to
 
 		ctx = WithRequestType(ctx, StateSyncRequest)
 
-		request := &Request{client: h.client, url: url, start: time.Now()}
+		request := &Request{client: h.client, url: url, start: time.Now(), errors: h.errors}
 		response, err := Fetch[clerkTypes.RecordListResponse](ctx, request)
 		if err != nil {
 			return nil, err
@@ -138,9 +189,10 @@ func (h *HeimdallClient) GetSpan(ctx context.Context, spanID uint64) (*types.Spa
 		return nil, err
 	}
 
-	ctx = WithRequestType(ctx, SpanRequest)
+	// Heimdall may skip span ids, the missing ones are never served
+	ctx = withNotFoundFinal(WithRequestType(ctx, SpanRequest))
 
-	response, err := FetchWithRetry[types.QuerySpanByIdResponse](ctx, h.client, url, h.closeCh)
+	response, err := FetchWithRetry[types.QuerySpanByIdResponse](ctx, h, url)
 	if err != nil {
 		return nil, err
 	}
@@ -156,7 +208,7 @@ func (h *HeimdallClient) GetLatestSpan(ctx context.Context) (*types.Span, error)
 
 	ctx = WithRequestType(ctx, SpanRequest)
 
-	response, err := FetchWithRetry[types.QueryLatestSpanResponse](ctx, h.client, url, h.closeCh)
+	response, err := FetchWithRetry[types.QueryLatestSpanResponse](ctx, h, url)
 	if err != nil {
 		return nil, err
 	}
@@ -173,7 +225,7 @@ func (h *HeimdallClient) FetchCheckpoint(ctx context.Context, number int64) (*ch
 
 	ctx = WithRequestType(ctx, CheckpointRequest)
 
-	response, err := FetchWithRetry[checkpoint.CheckpointResponse](ctx, h.client, url, h.closeCh)
+	response, err := FetchWithRetry[checkpoint.CheckpointResponse](ctx, h, url)
 	if err != nil {
 		return nil, err
 	}
@@ -190,7 +242,7 @@ func (h *HeimdallClient) FetchMilestone(ctx context.Context) (*milestone.Milesto
 
 	ctx = WithRequestType(ctx, MilestoneRequest)
 
-	response, err := FetchWithRetry[milestone.MilestoneResponse](ctx, h.client, url, h.closeCh)
+	response, err := FetchWithRetry[milestone.MilestoneResponse](ctx, h, url)
 	if err != nil {
 		return nil, err
 	}
@@ -198,6 +250,45 @@ func (h *HeimdallClient) FetchMilestone(ctx context.Context) (*milestone.Milesto
 	return &response.Result, nil
 }
 
+// FetchMilestoneByNumber fetches the milestone with the given sequence number from heimdall
+func (h *HeimdallClient) FetchMilestoneByNumber(ctx context.Context, number int64) (*milestone.Milestone, error) {
+	url, err := milestoneByNumberURL(h.urlString, number)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = WithRequestType(ctx, MilestoneRequest)
+
+	response, err := FetchWithRetry[milestone.MilestoneResponse](ctx, h, url)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.Result, nil
+}
+
+// FetchMilestoneList fetches the milestones with sequence numbers in the inclusive range
+// [fromNumber, toNumber] from heimdall, in ascending order. Heimdall serves milestones
+// one at a time, so callers should keep the range small.
+func (h *HeimdallClient) FetchMilestoneList(ctx context.Context, fromNumber, toNumber int64) ([]*milestone.Milestone, error) {
+	if fromNumber < 1 || toNumber < fromNumber {
+		return nil, fmt.Errorf("invalid milestone range [%d, %d]", fromNumber, toNumber)
+	}
+
+	milestones := make([]*milestone.Milestone, 0, toNumber-fromNumber+1)
+
+	for number := fromNumber; number <= toNumber; number++ {
+		m, err := h.FetchMilestoneByNumber(ctx, number)
+		if err != nil {
+			return nil, err
+		}
+
+		milestones = append(milestones, m)
+	}
+
+	return milestones, nil
+}
+
 // FetchCheckpointCount fetches the checkpoint count from heimdall
 func (h *HeimdallClient) FetchCheckpointCount(ctx context.Context) (int64, error) {
 	url, err := checkpointCountURL(h.urlString)
@@ -207,7 +298,7 @@ func (h *HeimdallClient) FetchCheckpointCount(ctx context.Context) (int64, error
 
 	ctx = WithRequestType(ctx, CheckpointCountRequest)
 
-	response, err := FetchWithRetry[checkpoint.CheckpointCountResponse](ctx, h.client, url, h.closeCh)
+	response, err := FetchWithRetry[checkpoint.CheckpointCountResponse](ctx, h, url)
 	if err != nil {
 		return 0, err
 	}
@@ -224,17 +315,18 @@ func (h *HeimdallClient) FetchMilestoneCount(ctx context.Context) (int64, error)
 		return 0, err
 	}
 
-	response, err := FetchWithRetry[milestone.MilestoneCountResponse](ctx, h.client, url, h.closeCh)
+	response, err := FetchWithRetry[milestone.MilestoneCountResponse](ctx, h, url)
 	if err != nil {
 		return 0, err
 	}
 	return response.Count, nil
 }
 
-// FetchWithRetry returns data from heimdall with retry
-func FetchWithRetry[T any](ctx context.Context, client http.Client, url *url.URL, closeCh chan struct{}) (*T, error) {
+// FetchWithRetry returns data from heimdall with retry, honouring the retry interval
+// and the maximum number of retries of the client
+func FetchWithRetry[T any](ctx context.Context, h *HeimdallClient, url *url.URL) (*T, error) {
 	// request data once
-	request := &Request{client: client, url: url, start: time.Now()}
+	request := &Request{client: h.client, url: url, start: time.Now(), errors: h.errors}
 	result, err := Fetch[T](ctx, request)
 
 	if err == nil {
@@ -249,26 +341,36 @@ func FetchWithRetry[T any](ctx context.Context, client http.Client, url *url.URL
 		return nil, err
 	}
 
+	// 404 (Not Found) is a definitive answer from heimdall for some endpoints, retrying
+	// won't help. For the others, the data may not be available yet.
+	if errors.Is(err, ErrNotFound) && isNotFoundFinal(ctx) {
+		log.Debug("Data not found in Heimdall", "path", url.Path, "error", err)
+		return nil, err
+	}
+
+	// Heimdall won't change its response schema until it's upgraded, retrying won't help.
+	if errors.Is(err, ErrHeimdallSchemaMismatch) {
+		return nil, err
+	}
+
 	// attempt counter
 	attempt := 1
 
 	log.Warn("an error while trying fetching from Heimdall", "path", url.Path, "attempt", attempt, "error", err)
 
 	// create a new ticker for retrying the request
-	var ticker *time.Ticker
-	if client.Timeout != 0 {
-		ticker = time.NewTicker(client.Timeout)
-	} else {
-		// only reach here when HeimdallClient is HeimdallGRPCClient or HeimdallAppClient
-		ticker = time.NewTicker(retryCall)
-	}
+	ticker := time.NewTicker(h.retryInterval)
 	defer ticker.Stop()
 
 	const logEach = 5
 
 retryLoop:
 	for {
-		log.Info("Retrying again in 5 seconds to fetch data from Heimdall", "path", url.Path, "attempt", attempt)
+		if h.maxRetries > 0 && uint64(attempt) > h.maxRetries {
+			return nil, fmt.Errorf("%w: %d attempts: %w", ErrRetriesExhausted, attempt, err)
+		}
+
+		log.Info("Retrying again to fetch data from Heimdall", "path", url.Path, "attempt", attempt, "interval", h.retryInterval)
 
 		attempt++
 
@@ -277,12 +379,12 @@ retryLoop:
 			log.Debug("Shutdown detected, terminating request by context.Done")
 
 			return nil, ctx.Err()
-		case <-closeCh:
+		case <-h.closeCh:
 			log.Debug("Shutdown detected, terminating request by closing")
 
 			return nil, ErrShutdownDetected
 		case <-ticker.C:
-			request = &Request{client: client, url: url, start: time.Now()}
+			request = &Request{client: h.client, url: url, start: time.Now(), errors: h.errors}
 			result, err = Fetch[T](ctx, request)
 
 			if errors.Is(err, ErrServiceUnavailable) {
@@ -290,6 +392,15 @@ retryLoop:
 				return nil, err
 			}
 
+			if errors.Is(err, ErrNotFound) && isNotFoundFinal(ctx) {
+				log.Debug("Data not found in Heimdall", "path", url.Path, "error", err)
+				return nil, err
+			}
+
+			if errors.Is(err, ErrHeimdallSchemaMismatch) {
+				return nil, err
+			}
+
 			if err != nil {
 				if attempt%logEach == 0 {
 					log.Warn("an error while trying fetching from Heimdall", "path", url.Path, "attempt", attempt, "error", err)
@@ -303,8 +414,18 @@ retryLoop:
 	}
 }
 
-// Fetch returns data from heimdall
+// Fetch returns data from heimdall, recording the request in the error log of the
+// request if it fails
 func Fetch[T any](ctx context.Context, request *Request) (*T, error) {
+	result, err := fetch[T](ctx, request)
+	if err != nil {
+		request.errors.record(request.url.Path, err, time.Since(request.start))
+	}
+
+	return result, err
+}
+
+func fetch[T any](ctx context.Context, request *Request) (*T, error) {
 	isSuccessful := false
 
 	defer func() {
@@ -324,6 +445,11 @@ func Fetch[T any](ctx context.Context, request *Request) (*T, error) {
 		return nil, ErrNoResponse
 	}
 
+	body, err = normalizeResponse[T](request.url.Path, body)
+	if err != nil {
+		return nil, err
+	}
+
 	p, ok := interface{}(result).(proto.Message)
 	if ok {
 		interfaceRegistry := codectypes.NewInterfaceRegistry()
@@ -332,7 +458,7 @@ func Fetch[T any](ctx context.Context, request *Request) (*T, error) {
 
 		err = cdc.UnmarshalJSON(body, p)
 		if err != nil {
-			return nil, err
+			return nil, newSchemaMismatchError(request.url.Path, body, err)
 		}
 
 		tValue := reflect.ValueOf(result).Elem()
@@ -345,7 +471,7 @@ func Fetch[T any](ctx context.Context, request *Request) (*T, error) {
 
 	err = json.Unmarshal(body, result)
 	if err != nil {
-		return nil, err
+		return nil, newSchemaMismatchError(request.url.Path, body, err)
 	}
 
 	isSuccessful = true
@@ -387,6 +513,10 @@ func milestoneURL(urlString string) (*url.URL, error) {
 	return makeURL(urlString, url, "")
 }
 
+func milestoneByNumberURL(urlString string, number int64) (*url.URL, error) {
+	return makeURL(urlString, fmt.Sprintf(fetchMilestoneByNumber, number), "")
+}
+
 func checkpointCountURL(urlString string) (*url.URL, error) {
 	return makeURL(urlString, fetchCheckpointCount, "")
 }
@@ -414,6 +544,10 @@ func internalFetch(ctx context.Context, client http.Client, u *url.URL) ([]byte,
 		return nil, err
 	}
 
+	// Setting the header disables the transparent gzip handling of the transport,
+	// the response is decoded by readBody instead.
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+
 	res, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -422,12 +556,16 @@ func internalFetch(ctx context.Context, client http.Client, u *url.URL) ([]byte,
 	defer res.Body.Close()
 
 	if res.StatusCode == http.StatusServiceUnavailable {
-		return nil, fmt.Errorf("%w: response code %d", ErrServiceUnavailable, res.StatusCode)
+		return nil, &statusError{code: res.StatusCode, err: ErrServiceUnavailable}
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, &statusError{code: res.StatusCode, err: ErrNotFound}
 	}
 
 	// check status code
 	if res.StatusCode != 200 && res.StatusCode != 204 {
-		return nil, fmt.Errorf("%w: response code %d", ErrNotSuccessfulResponse, res.StatusCode)
+		return nil, &statusError{code: res.StatusCode, err: ErrNotSuccessfulResponse}
 	}
 
 	// unmarshall data from buffer
@@ -435,24 +573,83 @@ func internalFetch(ctx context.Context, client http.Client, u *url.URL) ([]byte,
 		return nil, nil
 	}
 
+	// get response
+	return readBody(res)
+}
+
+// readBody reads the body of a heimdall response, decompressing it according to
+// its content encoding. Both the compressed and the decompressed sizes are limited
+// to heimdallAPIBodyLimit.
+func readBody(res *http.Response) ([]byte, error) {
 	// Limit the number of bytes read from the response body
-	limitedBody := http.MaxBytesReader(nil, res.Body, heimdallAPIBodyLimit)
+	wire := &countingReader{r: http.MaxBytesReader(nil, res.Body, heimdallAPIBodyLimit)}
 
-	// get response
-	body, err := io.ReadAll(limitedBody)
+	var decoded io.Reader
+
+	encoding := strings.ToLower(strings.TrimSpace(res.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+		decoded = wire
+
+	case "gzip":
+		gz, err := gzip.NewReader(wire)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip response: %w", err)
+		}
+		defer gz.Close()
+
+		decoded = gz
+
+	case "zstd":
+		zr, err := zstd.NewReader(wire, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd response: %w", err)
+		}
+		defer zr.Close()
+
+		decoded = zr
+
+	default:
+		return nil, fmt.Errorf("%w: unsupported content encoding %q", ErrNotSuccessfulResponse, encoding)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(decoded, heimdallAPIBodyLimit+1))
 	if err != nil {
 		return nil, err
 	}
 
+	if len(body) > heimdallAPIBodyLimit {
+		return nil, fmt.Errorf("decompressed response exceeds %d bytes", heimdallAPIBodyLimit)
+	}
+
+	if decoded != wire {
+		responseCompressedBytesCounter.Inc(wire.n)
+	}
+
+	responseUncompressedBytesCounter.Inc(int64(len(body)))
+
 	return body, nil
 }
 
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
 func internalFetchWithTimeout(ctx context.Context, client http.Client, url *url.URL) ([]byte, error) {
-	if client.Timeout == 0 {
-		// If no timeout is set, use a default timeout
-		client.Timeout = 1 * time.Second
+	if client.Timeout <= 0 {
+		// If no timeout is set, use the default timeout
+		client.Timeout = DefaultTimeout
 	}
-	client.Timeout = 30 * time.Second
+
 	ctx, cancel := context.WithTimeout(ctx, client.Timeout)
 	defer cancel()
 
@@ -1,13 +1,18 @@
 package heimdall
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -15,6 +20,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/network"
 	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/checkpoint"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/require"
 )
 
@@ -153,7 +159,7 @@ func TestFetchCheckpointFromMockHeimdall(t *testing.T) {
 	require.NoError(t, err, "expect no error in starting mock heimdall server")
 
 	// Create a new heimdall client and use same port for connection
-	client := NewHeimdallClient(fmt.Sprintf("http://localhost:%d", port), 5*time.Second)
+	client := NewHeimdallClient(fmt.Sprintf("http://localhost:%d", port), 5*time.Second, 5*time.Second, 0)
 	_, err = client.FetchCheckpoint(t.Context(), -1)
 	require.NoError(t, err, "expect no error in fetching checkpoint")
 
@@ -219,7 +225,7 @@ func TestFetchMilestoneFromMockHeimdall(t *testing.T) {
 	require.NoError(t, err, "expect no error in starting mock heimdall server")
 
 	// Create a new heimdall client and use same port for connection
-	client := NewHeimdallClient(fmt.Sprintf("http://localhost:%d", port), 5*time.Second)
+	client := NewHeimdallClient(fmt.Sprintf("http://localhost:%d", port), 5*time.Second, 5*time.Second, 0)
 	_, err = client.FetchMilestone(t.Context())
 	require.NoError(t, err, "expect no error in fetching milestone")
 
@@ -274,7 +280,7 @@ func TestFetchShutdown(t *testing.T) {
 	require.NoError(t, err, "expect no error in starting mock heimdall server")
 
 	// Create a new heimdall client and use same port for connection
-	client := NewHeimdallClient(fmt.Sprintf("http://localhost:%d", port), 5*time.Second)
+	client := NewHeimdallClient(fmt.Sprintf("http://localhost:%d", port), 5*time.Second, 5*time.Second, 0)
 
 	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
 
@@ -412,6 +418,270 @@ func TestContext(t *testing.T) {
 	cancel2()
 }
 
+// TestFetchTimeout tests that the configured timeout is enforced for every attempt
+// and that the client gives up once the maximum number of retries is reached.
+func TestFetchTimeout(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+
+	// Slow server which takes longer than the client timeout to respond
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client := NewHeimdallClient(srv.URL, 50*time.Millisecond, 10*time.Millisecond, 2)
+
+	start := time.Now()
+
+	_, err := client.FetchCheckpoint(t.Context(), -1)
+	require.ErrorIs(t, err, ErrRetriesExhausted)
+	require.Less(t, time.Since(start), time.Second, "expect the timeout to be enforced on each attempt")
+	require.Equal(t, int32(3), requests.Load(), "expect one request and two retries")
+}
+
+// TestFetchRetryInterval tests that failed requests are retried at the configured interval.
+func TestFetchRetryInterval(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+
+	// Fail the first two requests and succeed afterwards
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err := json.NewEncoder(w).Encode(checkpoint.CheckpointResponse{
+			Result: checkpoint.Checkpoint{
+				EndBlock:   512,
+				BorChainID: "15001",
+			},
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewHeimdallClient(srv.URL, time.Second, 10*time.Millisecond, 0)
+
+	start := time.Now()
+
+	res, err := client.FetchCheckpoint(t.Context(), -1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(512), res.EndBlock)
+	require.Less(t, time.Since(start), time.Second, "expect retries to honour the retry interval")
+	require.Equal(t, int32(3), requests.Load())
+}
+
+// TestFetchNotFound tests that 404 responses are only final for the spans by id, the
+// data of the other endpoints may not be available yet.
+func TestFetchNotFound(t *testing.T) {
+	t.Parallel()
+
+	var spans, milestones atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bor/spans/7":
+			spans.Add(1)
+		case "/milestones/latest":
+			milestones.Add(1)
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	client := NewHeimdallClient(srv.URL, time.Second, 10*time.Millisecond, 2)
+	defer client.Close()
+
+	_, err := client.GetSpan(t.Context(), 7)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NotErrorIs(t, err, ErrRetriesExhausted)
+	require.Equal(t, int32(1), spans.Load())
+
+	_, err = client.FetchMilestone(t.Context())
+	require.ErrorIs(t, err, ErrRetriesExhausted)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Equal(t, int32(3), milestones.Load())
+}
+
+// TestFetchCompressedResponse tests that compressed responses are decoded according
+// to their content encoding, and that servers ignoring the accepted encodings are
+// still handled.
+func TestFetchCompressedResponse(t *testing.T) {
+	payload, err := json.Marshal(checkpoint.CheckpointResponse{
+		Result: checkpoint.Checkpoint{
+			EndBlock:   512,
+			BorChainID: "15001",
+		},
+	})
+	require.NoError(t, err)
+
+	var gzipped bytes.Buffer
+
+	gw := gzip.NewWriter(&gzipped)
+	_, err = gw.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+
+	zw, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+
+	zstded := zw.EncodeAll(payload, nil)
+	require.NoError(t, zw.Close())
+
+	tests := []struct {
+		name       string
+		encoding   string
+		body       []byte
+		compressed int64
+	}{
+		{"gzip", "gzip", gzipped.Bytes(), int64(gzipped.Len())},
+		{"zstd", "zstd", zstded, int64(len(zstded))},
+		{"identity", "", payload, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Accept-Encoding") != acceptEncoding {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+
+				_, _ = w.Write(tt.body)
+			}))
+			defer srv.Close()
+
+			client := NewHeimdallClient(srv.URL, time.Second, 10*time.Millisecond, 1)
+			defer client.Close()
+
+			compressed := responseCompressedBytesCounter.Snapshot().Count()
+			uncompressed := responseUncompressedBytesCounter.Snapshot().Count()
+
+			res, err := client.FetchCheckpoint(t.Context(), -1)
+			require.NoError(t, err)
+			require.Equal(t, uint64(512), res.EndBlock)
+			require.Equal(t, "15001", res.BorChainID)
+
+			require.Equal(t, tt.compressed, responseCompressedBytesCounter.Snapshot().Count()-compressed)
+			require.Equal(t, int64(len(payload)), responseUncompressedBytesCounter.Snapshot().Count()-uncompressed)
+		})
+	}
+}
+
+// TestFetchUnsupportedEncoding tests that responses in an encoding which wasn't
+// accepted are rejected instead of being decoded as plain JSON.
+func TestFetchUnsupportedEncoding(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		_, _ = w.Write([]byte("not brotli"))
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	_, err = internalFetch(t.Context(), http.Client{Timeout: time.Second}, u)
+	require.ErrorIs(t, err, ErrNotSuccessfulResponse)
+}
+
+// TestRecentErrors tests that failed requests are reported oldest first along with
+// their status code and the counts per path.
+func TestRecentErrors(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/checkpoints/latest":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/bor/spans/7":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			_, _ = w.Write([]byte(`{"count": "1"}`))
+		}
+	}))
+	defer srv.Close()
+
+	client := NewHeimdallClient(srv.URL, time.Second, 10*time.Millisecond, 1)
+	defer client.Close()
+
+	require.Empty(t, client.RecentErrors().Errors)
+
+	// One request and one retry
+	_, err := client.FetchCheckpoint(t.Context(), -1)
+	require.ErrorIs(t, err, ErrRetriesExhausted)
+
+	// Not retried
+	_, err = client.GetSpan(t.Context(), 7)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// Successful requests aren't recorded
+	_, err = client.FetchMilestoneCount(t.Context())
+	require.NoError(t, err)
+
+	report := client.RecentErrors()
+	require.Len(t, report.Errors, 3)
+
+	for i, want := range []struct {
+		path   string
+		status int
+	}{
+		{"/checkpoints/latest", http.StatusInternalServerError},
+		{"/checkpoints/latest", http.StatusInternalServerError},
+		{"/bor/spans/7", http.StatusNotFound},
+	} {
+		require.Equal(t, want.path, report.Errors[i].Path, "error %d", i)
+		require.Equal(t, want.status, report.Errors[i].StatusCode, "error %d", i)
+		require.NotEmpty(t, report.Errors[i].Error, "error %d", i)
+	}
+
+	require.False(t, report.Errors[1].Time.Before(report.Errors[0].Time))
+	require.Equal(t, map[string]uint64{"/checkpoints/latest": 2, "/bor/spans/7": 1}, report.Counts)
+}
+
+// TestErrorLogTruncation tests that only the most recent failed requests are kept,
+// while the counts cover all of them.
+func TestErrorLogTruncation(t *testing.T) {
+	t.Parallel()
+
+	log := newErrorLog()
+
+	const failures = errorLogLimit + 44
+	for i := 0; i < failures; i++ {
+		log.record(fmt.Sprintf("/path/%d", i%2), fmt.Errorf("failure %d", i), time.Millisecond)
+	}
+
+	report := log.report()
+	require.Len(t, report.Errors, errorLogLimit)
+	require.Equal(t, "failure 44", report.Errors[0].Error)
+	require.Equal(t, fmt.Sprintf("failure %d", failures-1), report.Errors[errorLogLimit-1].Error)
+	require.Equal(t, map[string]uint64{"/path/0": failures / 2, "/path/1": failures / 2}, report.Counts)
+
+	// A nil log reports nothing
+	var nilLog *errorLog
+	nilLog.record("/path", errors.New("failure"), 0)
+	require.Empty(t, nilLog.report().Errors)
+}
+
 func TestSpanURL(t *testing.T) {
 	t.Parallel()
 
@@ -427,6 +697,21 @@ func TestSpanURL(t *testing.T) {
 	}
 }
 
+func TestMilestoneByNumberURL(t *testing.T) {
+	t.Parallel()
+
+	url, err := milestoneByNumberURL("http://bor0", 42)
+	if err != nil {
+		t.Fatal("got an error", err)
+	}
+
+	const expected = "http://bor0/milestones/42"
+
+	if url.String() != expected {
+		t.Fatalf("expected URL %q, got %q", expected, url.String())
+	}
+}
+
 func TestStateSyncURL(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,93 @@
+package heimdall
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// errorLogLimit is the number of recent failed requests kept in the error log
+const errorLogLimit = 256
+
+// RequestError is a failed request to heimdall.
+type RequestError struct {
+	Time       time.Time     `json:"time"`
+	Path       string        `json:"path"`
+	StatusCode int           `json:"statusCode,omitempty"` // HTTP status code, if a response was received
+	Error      string        `json:"error"`
+	Latency    time.Duration `json:"latency"`
+}
+
+// ErrorsReport lists the recent failed requests to heimdall, oldest first, along
+// with the number of failed requests per path since startup.
+type ErrorsReport struct {
+	Errors []RequestError    `json:"errors"`
+	Counts map[string]uint64 `json:"counts"`
+}
+
+// errorLog is a ring buffer of the recent failed requests to heimdall. Nothing is
+// done for successful requests.
+type errorLog struct {
+	errors [errorLogLimit]RequestError
+	next   int
+	total  int
+	counts map[string]uint64
+	lock   sync.Mutex
+}
+
+func newErrorLog() *errorLog {
+	return &errorLog{counts: make(map[string]uint64)}
+}
+
+// record adds a failed request to the log.
+func (l *errorLog) record(path string, err error, latency time.Duration) {
+	if l == nil {
+		return
+	}
+
+	entry := RequestError{
+		Time:    time.Now(),
+		Path:    path,
+		Error:   err.Error(),
+		Latency: latency,
+	}
+
+	var status *statusError
+	if errors.As(err, &status) {
+		entry.StatusCode = status.code
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.errors[l.next] = entry
+	l.next = (l.next + 1) % errorLogLimit
+	l.total++
+	l.counts[path]++
+}
+
+// report returns the recorded failed requests, oldest first, and the counts per path.
+func (l *errorLog) report() *ErrorsReport {
+	report := &ErrorsReport{
+		Errors: []RequestError{},
+		Counts: make(map[string]uint64),
+	}
+
+	if l == nil {
+		return report
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	kept := min(l.total, errorLogLimit)
+	for i := kept; i > 0; i-- {
+		report.Errors = append(report.Errors, l.errors[(l.next-i+errorLogLimit)%errorLogLimit])
+	}
+
+	for path, count := range l.counts {
+		report.Counts[path] = count
+	}
+
+	return report
+}
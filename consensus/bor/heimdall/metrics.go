@@ -8,8 +8,9 @@ import (
 )
 
 type (
-	requestTypeKey struct{}
-	requestType    string
+	requestTypeKey   struct{}
+	notFoundFinalKey struct{}
+	requestType      string
 
 	meter struct {
 		request map[bool]*metrics.Meter // map[isSuccessful]metrics.Meter
@@ -39,7 +40,23 @@ func getRequestType(ctx context.Context) (requestType, bool) {
 	return reqType, ok
 }
 
+// withNotFoundFinal marks the request as not to be retried on 404 (Not Found) responses.
+func withNotFoundFinal(ctx context.Context) context.Context {
+	return context.WithValue(ctx, notFoundFinalKey{}, true)
+}
+
+func isNotFoundFinal(ctx context.Context) bool {
+	final, _ := ctx.Value(notFoundFinalKey{}).(bool)
+	return final
+}
+
 var (
+	// responseCompressedBytesCounter counts the bytes received for compressed responses
+	responseCompressedBytesCounter = metrics.NewRegisteredCounter("client/responses/compressed_bytes", nil)
+
+	// responseUncompressedBytesCounter counts the decoded bytes of all responses
+	responseUncompressedBytesCounter = metrics.NewRegisteredCounter("client/responses/uncompressed_bytes", nil)
+
 	requestMeters = map[requestType]meter{
 		StateSyncRequest: {
 			request: map[bool]*metrics.Meter{
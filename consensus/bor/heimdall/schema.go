@@ -0,0 +1,143 @@
+package heimdall
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ErrHeimdallSchemaMismatch is returned if the response of heimdall doesn't match the
+// expected schema, which usually means that the bor and heimdall versions don't match.
+var ErrHeimdallSchemaMismatch = errors.New("heimdall response schema mismatch")
+
+// schemaSampleLimit is the maximum length of the payload sample included in errors
+const schemaSampleLimit = 256
+
+// SchemaMismatchError is returned if the response of heimdall for the endpoint doesn't
+// match the expected schema. It matches ErrHeimdallSchemaMismatch.
+type SchemaMismatchError struct {
+	Endpoint string // Path of the request
+	Sample   string // Beginning of the response payload
+	Err      error  // Decoding error, if any
+}
+
+func newSchemaMismatchError(endpoint string, body []byte, err error) *SchemaMismatchError {
+	sample := string(body)
+	if len(sample) > schemaSampleLimit {
+		sample = sample[:schemaSampleLimit] + "..."
+	}
+
+	return &SchemaMismatchError{
+		Endpoint: endpoint,
+		Sample:   sample,
+		Err:      err,
+	}
+}
+
+func (e *SchemaMismatchError) Error() string {
+	msg := fmt.Sprintf("%v for %s, check that the bor and heimdall versions are compatible", ErrHeimdallSchemaMismatch, e.Endpoint)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	return msg + ", payload: " + e.Sample
+}
+
+func (e *SchemaMismatchError) Is(target error) bool {
+	return target == ErrHeimdallSchemaMismatch
+}
+
+func (e *SchemaMismatchError) Unwrap() error {
+	return e.Err
+}
+
+// normalizeResponse checks the envelope of a heimdall response against the fields of
+// the expected response type T. Heimdall v1 responses, wrapped in {"height", "result"},
+// are translated to the v2 shape if T has a single field. Payloads which can't be
+// matched to T result in a SchemaMismatchError.
+func normalizeResponse[T any](endpoint string, body []byte) ([]byte, error) {
+	fields := jsonFieldNames(reflect.TypeOf((*T)(nil)).Elem())
+	if len(fields) == 0 {
+		return body, nil
+	}
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, newSchemaMismatchError(endpoint, body, err)
+	}
+
+	// Nothing to check if heimdall returned an empty object
+	if len(envelope) == 0 {
+		return body, nil
+	}
+
+	for key := range envelope {
+		for _, field := range fields {
+			if key == field || key == snakeToCamel(field) {
+				return body, nil
+			}
+		}
+	}
+
+	result, hasResult := envelope["result"]
+	_, hasHeight := envelope["height"]
+
+	if hasResult && hasHeight && len(fields) == 1 {
+		translated, err := json.Marshal(map[string]json.RawMessage{fields[0]: result})
+		if err != nil {
+			return nil, newSchemaMismatchError(endpoint, body, err)
+		}
+
+		log.Debug("Translated heimdall v1 response", "endpoint", endpoint)
+
+		return translated, nil
+	}
+
+	return nil, newSchemaMismatchError(endpoint, body, nil)
+}
+
+// jsonFieldNames returns the json names of the fields of the given struct type.
+func jsonFieldNames(t reflect.Type) []string {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var names []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = field.Name
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// snakeToCamel converts a snake_case name to lowerCamelCase, the other name accepted
+// for proto fields in json.
+func snakeToCamel(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+
+	return strings.Join(parts, "")
+}
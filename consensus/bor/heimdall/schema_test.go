@@ -0,0 +1,160 @@
+package heimdall
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/milestone"
+)
+
+// newFixtureServer returns a heimdall server answering all requests with the given
+// payload, along with the number of requests it received.
+func newFixtureServer(t *testing.T, payload []byte) (*HeimdallClient, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+
+		_, _ = w.Write(payload)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewHeimdallClient(srv.URL, time.Second, 10*time.Millisecond, 0)
+	t.Cleanup(client.Close)
+
+	return client, &requests
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	payload, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+
+	return payload
+}
+
+func requireSchemaMismatch(t *testing.T, err error, endpoint string) {
+	t.Helper()
+
+	require.ErrorIs(t, err, ErrHeimdallSchemaMismatch)
+
+	var mismatch *SchemaMismatchError
+	require.True(t, errors.As(err, &mismatch))
+	require.Equal(t, endpoint, mismatch.Endpoint)
+	require.NotEmpty(t, mismatch.Sample)
+	require.LessOrEqual(t, len(mismatch.Sample), schemaSampleLimit+len("..."))
+}
+
+func TestFetchSpanSchema(t *testing.T) {
+	t.Parallel()
+
+	// Current heimdall
+	client, _ := newFixtureServer(t, readFixture(t, "span_v2.json"))
+
+	span, err := client.GetSpan(t.Context(), 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), span.Id)
+	require.Equal(t, uint64(256), span.StartBlock)
+	require.Equal(t, uint64(6655), span.EndBlock)
+	require.Equal(t, "137", span.BorChainId)
+	require.Len(t, span.ValidatorSet.Validators, 1)
+	require.Equal(t, uint64(5), span.ValidatorSet.Validators[0].ValId)
+	require.Len(t, span.SelectedProducers, 1)
+
+	// Legacy heimdall, whose span fields can't be translated
+	client, requests := newFixtureServer(t, readFixture(t, "span_v1.json"))
+
+	_, err = client.GetSpan(t.Context(), 1)
+	requireSchemaMismatch(t, err, "bor/spans/1")
+	require.Equal(t, int32(1), requests.Load(), "schema mismatches must not be retried")
+}
+
+func TestFetchMilestoneSchema(t *testing.T) {
+	t.Parallel()
+
+	expected := &milestone.Milestone{
+		Proposer:        common.HexToAddress("0x6dc2dd54f24979ec26212794c71afefed722280c"),
+		StartBlock:      68093442,
+		EndBlock:        68093459,
+		Hash:            common.HexToHash("0x4cb5ea9e1ae2b5a36b3a1ee6e4f8c3d0fd3a6bc79f7f1c8ab53da6e0e3c5ad21"),
+		BorChainID:      "137",
+		MilestoneID:     "7bb9d3b7-66a0-4c2f-a0a5-5b3cd8e0ae3c - 0x4cb5ea9e1ae2b5a36b3a1ee6e4f8c3d0fd3a6bc79f7f1c8ab53da6e0e3c5ad21",
+		Timestamp:       1739281736,
+		TotalDifficulty: 1163724,
+	}
+
+	// Current heimdall
+	v2 := readFixture(t, "milestone_v2.json")
+	client, _ := newFixtureServer(t, v2)
+
+	m, err := client.FetchMilestone(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, expected, m)
+
+	// Legacy envelope around a current milestone is translated
+	var envelope map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(v2, &envelope))
+
+	legacy, err := json.Marshal(map[string]json.RawMessage{"height": json.RawMessage(`"22515488"`), "result": envelope["milestone"]})
+	require.NoError(t, err)
+
+	client, _ = newFixtureServer(t, legacy)
+
+	m, err = client.FetchMilestone(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, expected, m)
+
+	// Legacy heimdall, whose milestone fields can't be translated
+	client, requests := newFixtureServer(t, readFixture(t, "milestone_v1.json"))
+
+	_, err = client.FetchMilestone(t.Context())
+	requireSchemaMismatch(t, err, "/milestones/latest")
+	require.Equal(t, int32(1), requests.Load(), "schema mismatches must not be retried")
+
+	// Response of another endpoint
+	client, _ = newFixtureServer(t, readFixture(t, "span_v2.json"))
+
+	_, err = client.FetchMilestone(t.Context())
+	requireSchemaMismatch(t, err, "/milestones/latest")
+}
+
+func TestNormalizeResponse(t *testing.T) {
+	t.Parallel()
+
+	type response struct {
+		Count string `json:"ack_count"`
+	}
+
+	// Fields are matched by their json name and its camel case form
+	for _, payload := range []string{`{"ack_count": "1"}`, `{"ackCount": "1"}`, `{}`} {
+		body, err := normalizeResponse[response]("/checkpoints/count", []byte(payload))
+		require.NoError(t, err, payload)
+		require.Equal(t, payload, string(body))
+	}
+
+	// Legacy envelope
+	body, err := normalizeResponse[response]("/checkpoints/count", []byte(`{"height": "1", "result": "1"}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"ack_count": "1"}`, string(body))
+
+	// Unknown envelopes, with a truncated payload sample
+	long := `{"unexpected": "` + strings.Repeat("a", 2*schemaSampleLimit) + `"}`
+
+	for _, payload := range []string{`{"result": "1"}`, `[1, 2]`, `not json`, long} {
+		_, err := normalizeResponse[response]("/checkpoints/count", []byte(payload))
+		requireSchemaMismatch(t, err, "/checkpoints/count")
+	}
+}
@@ -77,6 +77,39 @@ func (c *ChainSpanner) GetCurrentSpan(ctx context.Context, headerHash common.Has
 		return nil, err
 	}
 
+	return c.unpackCurrentSpan(method, result)
+}
+
+// GetCurrentSpanFromState gets the current span from the contract in the given state, i.e.
+// including the changes already applied by the block being processed. The state is left
+// unchanged.
+func (c *ChainSpanner) GetCurrentSpanFromState(state vm.StateDB, header *types.Header, chainContext core.ChainContext) (*borTypes.Span, error) {
+	// method
+	const method = "getCurrentSpan"
+
+	data, err := c.validatorSet.Pack(method)
+	if err != nil {
+		log.Error("Unable to pack tx for getCurrentSpan", "error", err)
+
+		return nil, err
+	}
+
+	blockContext := core.NewEVMBlockContext(header, chainContext, &header.Coinbase)
+	vmenv := vm.NewEVM(blockContext, state, c.chainConfig, vm.Config{})
+
+	snapshot := state.Snapshot()
+	defer state.RevertToSnapshot(snapshot)
+
+	result, _, err := vmenv.StaticCall(params.SystemAddress, c.validatorContractAddress, data, math.MaxUint64/2)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.unpackCurrentSpan(method, result)
+}
+
+// unpackCurrentSpan unpacks the result of the getCurrentSpan call of the contract
+func (c *ChainSpanner) unpackCurrentSpan(method string, result []byte) (*borTypes.Span, error) {
 	// span result
 	ret := new(struct {
 		Number     *big.Int
@@ -0,0 +1,257 @@
+package bor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/0xPolygon/heimdall-v2/x/bor/types"
+
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/milestone"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// HeimdallCallStatus is the outcome of the calls of a given kind made to heimdall.
+type HeimdallCallStatus struct {
+	OK          bool      `json:"ok"`                  // Whether the most recent call succeeded
+	LastSuccess time.Time `json:"lastSuccess"`         // Completion time of the most recent successful call
+	LastFailure time.Time `json:"lastFailure"`         // Completion time of the most recent failed call
+	LastError   string    `json:"lastError,omitempty"` // Error returned by the most recent failed call
+	LatencyMs   int64     `json:"latencyMs"`           // Duration of the most recent call in milliseconds
+	Pending     bool      `json:"pending"`             // Whether a call is currently in progress
+	InFlight    int       `json:"inFlight"`            // Number of calls currently in progress
+}
+
+// HeimdallStatus is the connectivity status of heimdall as seen by the node.
+type HeimdallStatus struct {
+	Healthy          bool               `json:"healthy"`                    // False if readiness mode is on and heimdall is down for too long
+	Reachable        bool               `json:"reachable"`                  // False if the most recent call to heimdall failed
+	UnreachableSince *time.Time         `json:"unreachableSince,omitempty"` // First failure since the last successful call
+	LatestSpan       HeimdallCallStatus `json:"latestSpan"`
+	Milestone        HeimdallCallStatus `json:"milestone"`
+	WSConnected      *bool              `json:"wsConnected,omitempty"` // Only set if the ws subscription is enabled
+}
+
+// HeimdallErrorsReporter is implemented by the heimdall clients tracking their failed
+// requests.
+type HeimdallErrorsReporter interface {
+	RecentErrors() *heimdall.ErrorsReport
+}
+
+// heimdallWSStatus is implemented by the heimdall ws clients which report the state of
+// their connection.
+type heimdallWSStatus interface {
+	IsConnected() bool
+}
+
+// heimdallProbe tracks the calls of a given kind made to heimdall. Concurrent calls are
+// tracked separately, so that a call completing doesn't hide the ones still in progress.
+type heimdallProbe struct {
+	status   HeimdallCallStatus
+	inFlight map[time.Time]int // number of calls in progress by start time
+	count    int               // total number of calls in progress
+}
+
+// oldest returns the start time of the oldest call in progress, zero if none.
+func (p *heimdallProbe) oldest() time.Time {
+	var oldest time.Time
+	for started := range p.inFlight {
+		if oldest.IsZero() || started.Before(oldest) {
+			oldest = started
+		}
+	}
+
+	return oldest
+}
+
+// HeimdallHealth tracks the connectivity to heimdall based on the outcome of the span
+// and milestone requests made by the node and on the state of the ws subscription.
+type HeimdallHealth struct {
+	maxUnreachable time.Duration // 0 means the node is always reported healthy
+
+	latestSpan heimdallProbe
+	milestone  heimdallProbe
+	ws         heimdallWSStatus
+
+	unreachableSince time.Time // first failure since the last successful call
+	lock             sync.RWMutex
+
+	now func() time.Time // overridden in tests
+}
+
+// NewHeimdallHealth creates a new heimdall health tracker. If maxUnreachable is positive,
+// the node is reported unhealthy once heimdall is unreachable for longer than it.
+func NewHeimdallHealth(maxUnreachable time.Duration) *HeimdallHealth {
+	return &HeimdallHealth{
+		maxUnreachable: maxUnreachable,
+		now:            time.Now,
+	}
+}
+
+// WrapClient returns a heimdall client recording the outcome of the requests made
+// through the given one.
+func (h *HeimdallHealth) WrapClient(client IHeimdallClient) IHeimdallClient {
+	return &healthTrackingHeimdallClient{
+		IHeimdallClient: client,
+		health:          h,
+	}
+}
+
+// SetWSClient sets the heimdall ws client whose connection state is reported. It's
+// ignored if the client doesn't report its connection state.
+func (h *HeimdallHealth) SetWSClient(client IHeimdallWSClient) {
+	ws, ok := client.(heimdallWSStatus)
+	if !ok {
+		return
+	}
+
+	h.lock.Lock()
+	h.ws = ws
+	h.lock.Unlock()
+}
+
+// start records the start of a call and returns its start time.
+func (h *HeimdallHealth) start(probe *heimdallProbe) time.Time {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := h.now()
+
+	if probe.inFlight == nil {
+		probe.inFlight = make(map[time.Time]int)
+	}
+
+	probe.inFlight[now]++
+	probe.count++
+
+	return now
+}
+
+// done records the outcome of a call started at the given time.
+func (h *HeimdallHealth) done(probe *heimdallProbe, started time.Time, err error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := h.now()
+
+	if probe.inFlight[started] > 0 {
+		probe.inFlight[started]--
+		if probe.inFlight[started] == 0 {
+			delete(probe.inFlight, started)
+		}
+
+		probe.count--
+	}
+
+	probe.status.LatencyMs = now.Sub(started).Milliseconds()
+	probe.status.OK = err == nil
+
+	if err != nil {
+		probe.status.LastFailure = now
+		probe.status.LastError = err.Error()
+
+		if h.unreachableSince.IsZero() {
+			h.unreachableSince = now
+		}
+
+		return
+	}
+
+	probe.status.LastSuccess = now
+	h.unreachableSince = time.Time{}
+}
+
+// Status returns the current connectivity status of heimdall.
+func (h *HeimdallHealth) Status() *HeimdallStatus {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	now := h.now()
+
+	status := &HeimdallStatus{
+		Reachable:  h.unreachableSince.IsZero(),
+		LatestSpan: h.latestSpan.status,
+		Milestone:  h.milestone.status,
+	}
+
+	status.LatestSpan.Pending, status.LatestSpan.InFlight = h.latestSpan.count > 0, h.latestSpan.count
+	status.Milestone.Pending, status.Milestone.InFlight = h.milestone.count > 0, h.milestone.count
+
+	// The requests to heimdall are retried until they succeed, so a call which
+	// hangs is as bad as a failed one.
+	down := h.unreachableSince
+	for _, started := range []time.Time{h.latestSpan.oldest(), h.milestone.oldest()} {
+		if !started.IsZero() && (down.IsZero() || started.Before(down)) {
+			down = started
+		}
+	}
+
+	if !h.unreachableSince.IsZero() {
+		since := h.unreachableSince
+		status.UnreachableSince = &since
+	}
+
+	status.Healthy = h.maxUnreachable <= 0 || down.IsZero() || now.Sub(down) <= h.maxUnreachable
+
+	if h.ws != nil {
+		connected := h.ws.IsConnected()
+		status.WSConnected = &connected
+	}
+
+	return status
+}
+
+// ServeHTTP implements http.Handler, reporting the heimdall status as JSON. The status
+// code is 503 if the node is unhealthy.
+func (h *HeimdallHealth) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	status := h.Status()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !status.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Debug("Failed to write heimdall status", "err", err)
+	}
+}
+
+// healthTrackingHeimdallClient records the outcome of the span and milestone requests
+// made to heimdall.
+type healthTrackingHeimdallClient struct {
+	IHeimdallClient
+	health *HeimdallHealth
+}
+
+func (c *healthTrackingHeimdallClient) GetLatestSpan(ctx context.Context) (*types.Span, error) {
+	started := c.health.start(&c.health.latestSpan)
+
+	span, err := c.IHeimdallClient.GetLatestSpan(ctx)
+	c.health.done(&c.health.latestSpan, started, err)
+
+	return span, err
+}
+
+func (c *healthTrackingHeimdallClient) FetchMilestone(ctx context.Context) (*milestone.Milestone, error) {
+	started := c.health.start(&c.health.milestone)
+
+	m, err := c.IHeimdallClient.FetchMilestone(ctx)
+	c.health.done(&c.health.milestone, started, err)
+
+	return m, err
+}
+
+// RecentErrors returns the failed requests tracked by the wrapped client, nil if it
+// doesn't track them.
+func (c *healthTrackingHeimdallClient) RecentErrors() *heimdall.ErrorsReport {
+	client, ok := c.IHeimdallClient.(HeimdallErrorsReporter)
+	if !ok {
+		return nil
+	}
+
+	return client.RecentErrors()
+}
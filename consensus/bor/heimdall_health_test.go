@@ -0,0 +1,269 @@
+package bor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/0xPolygon/heimdall-v2/x/bor/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/milestone"
+)
+
+var errHeimdallDown = errors.New("heimdall is down")
+
+// flakyHeimdallClient is a heimdall client which can be flipped between up and down
+type flakyHeimdallClient struct {
+	MockHeimdallClient
+	down atomic.Bool
+}
+
+func (h *flakyHeimdallClient) GetLatestSpan(_ context.Context) (*types.Span, error) {
+	if h.down.Load() {
+		return nil, errHeimdallDown
+	}
+
+	return &types.Span{Id: 1}, nil
+}
+
+func (h *flakyHeimdallClient) FetchMilestone(_ context.Context) (*milestone.Milestone, error) {
+	if h.down.Load() {
+		return nil, errHeimdallDown
+	}
+
+	return &milestone.Milestone{EndBlock: 16}, nil
+}
+
+type stubWSClient struct {
+	connected bool
+}
+
+func (c *stubWSClient) SubscribeMilestoneEvents(_ context.Context) <-chan *milestone.Milestone {
+	return nil
+}
+
+func (c *stubWSClient) Unsubscribe(_ context.Context) error { return nil }
+func (c *stubWSClient) Close() error                        { return nil }
+func (c *stubWSClient) IsConnected() bool                   { return c.connected }
+
+// newTestHeimdallHealth returns a health tracker whose clock is advanced manually
+func newTestHeimdallHealth(maxUnreachable time.Duration) (*HeimdallHealth, *time.Time) {
+	now := time.Unix(1700000000, 0)
+
+	health := NewHeimdallHealth(maxUnreachable)
+	health.now = func() time.Time { return now }
+
+	return health, &now
+}
+
+func TestHeimdallHealthStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	heimdall := &flakyHeimdallClient{}
+
+	health, now := newTestHeimdallHealth(time.Minute)
+	client := health.WrapClient(heimdall)
+
+	status := health.Status()
+	require.True(t, status.Healthy)
+	require.True(t, status.Reachable)
+	require.Nil(t, status.WSConnected)
+
+	_, err := client.GetLatestSpan(ctx)
+	require.NoError(t, err)
+	_, err = client.FetchMilestone(ctx)
+	require.NoError(t, err)
+
+	status = health.Status()
+	require.True(t, status.Healthy)
+	require.True(t, status.Reachable)
+	require.True(t, status.LatestSpan.OK)
+	require.True(t, status.Milestone.OK)
+	require.Equal(t, *now, status.LatestSpan.LastSuccess)
+
+	// Heimdall goes down
+	heimdall.down.Store(true)
+	*now = now.Add(time.Second)
+	downSince := *now
+
+	_, err = client.GetLatestSpan(ctx)
+	require.ErrorIs(t, err, errHeimdallDown)
+
+	status = health.Status()
+	require.True(t, status.Healthy)
+	require.False(t, status.Reachable)
+	require.False(t, status.LatestSpan.OK)
+	require.True(t, status.Milestone.OK)
+	require.Equal(t, errHeimdallDown.Error(), status.LatestSpan.LastError)
+	require.Equal(t, downSince, *status.UnreachableSince)
+
+	// Still failing after the readiness timeout
+	*now = now.Add(time.Minute)
+
+	_, err = client.FetchMilestone(ctx)
+	require.ErrorIs(t, err, errHeimdallDown)
+
+	status = health.Status()
+	require.False(t, status.Healthy)
+	require.False(t, status.Reachable)
+	require.False(t, status.Milestone.OK)
+	require.Equal(t, downSince, *status.UnreachableSince)
+
+	// Heimdall is back up
+	heimdall.down.Store(false)
+	*now = now.Add(time.Second)
+
+	_, err = client.GetLatestSpan(ctx)
+	require.NoError(t, err)
+
+	status = health.Status()
+	require.True(t, status.Healthy)
+	require.True(t, status.Reachable)
+	require.Nil(t, status.UnreachableSince)
+	require.True(t, status.LatestSpan.OK)
+}
+
+func TestHeimdallHealthWithoutReadiness(t *testing.T) {
+	t.Parallel()
+
+	heimdall := &flakyHeimdallClient{}
+	heimdall.down.Store(true)
+
+	health, now := newTestHeimdallHealth(0)
+	client := health.WrapClient(heimdall)
+
+	_, err := client.GetLatestSpan(context.Background())
+	require.ErrorIs(t, err, errHeimdallDown)
+
+	*now = now.Add(time.Hour)
+
+	status := health.Status()
+	require.True(t, status.Healthy)
+	require.False(t, status.Reachable)
+}
+
+func TestHeimdallHealthPendingCall(t *testing.T) {
+	t.Parallel()
+
+	health, now := newTestHeimdallHealth(time.Minute)
+
+	// A request which never returns, as it's retried until heimdall is back
+	started := health.start(&health.latestSpan)
+
+	status := health.Status()
+	require.True(t, status.Healthy)
+	require.True(t, status.LatestSpan.Pending)
+
+	*now = now.Add(2 * time.Minute)
+	require.False(t, health.Status().Healthy)
+
+	health.done(&health.latestSpan, started, nil)
+
+	status = health.Status()
+	require.True(t, status.Healthy)
+	require.False(t, status.LatestSpan.Pending)
+	require.Equal(t, (2 * time.Minute).Milliseconds(), status.LatestSpan.LatencyMs)
+}
+
+func TestHeimdallHealthConcurrentCalls(t *testing.T) {
+	t.Parallel()
+
+	health, now := newTestHeimdallHealth(time.Minute)
+
+	// Two overlapping requests, the first one returning while the second one hangs
+	first := health.start(&health.milestone)
+
+	*now = now.Add(30 * time.Second)
+	second := health.start(&health.milestone)
+
+	status := health.Status()
+	require.True(t, status.Milestone.Pending)
+	require.Equal(t, 2, status.Milestone.InFlight)
+
+	*now = now.Add(30 * time.Second)
+	health.done(&health.milestone, first, nil)
+
+	status = health.Status()
+	require.True(t, status.Healthy)
+	require.True(t, status.Milestone.Pending)
+	require.Equal(t, 1, status.Milestone.InFlight)
+
+	// The hanging request is still tracked from its own start time
+	*now = now.Add(45 * time.Second)
+	require.False(t, health.Status().Healthy)
+
+	health.done(&health.milestone, second, nil)
+
+	status = health.Status()
+	require.True(t, status.Healthy)
+	require.False(t, status.Milestone.Pending)
+	require.Equal(t, 0, status.Milestone.InFlight)
+}
+
+func TestHeimdallHealthWSConnection(t *testing.T) {
+	t.Parallel()
+
+	ws := &stubWSClient{connected: true}
+
+	health, _ := newTestHeimdallHealth(0)
+	health.SetWSClient(ws)
+
+	require.True(t, *health.Status().WSConnected)
+
+	ws.connected = false
+	require.False(t, *health.Status().WSConnected)
+}
+
+func TestHeimdallHealthHTTP(t *testing.T) {
+	t.Parallel()
+
+	heimdall := &flakyHeimdallClient{}
+
+	health, now := newTestHeimdallHealth(time.Minute)
+	client := health.WrapClient(heimdall)
+
+	get := func() (int, *HeimdallStatus) {
+		rec := httptest.NewRecorder()
+		health.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/heimdall", nil))
+
+		var status HeimdallStatus
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+
+		return rec.Code, &status
+	}
+
+	_, err := client.GetLatestSpan(context.Background())
+	require.NoError(t, err)
+
+	code, status := get()
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, status.Healthy)
+
+	heimdall.down.Store(true)
+
+	_, err = client.GetLatestSpan(context.Background())
+	require.Error(t, err)
+
+	*now = now.Add(2 * time.Minute)
+
+	code, status = get()
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.False(t, status.Healthy)
+	require.False(t, status.Reachable)
+
+	heimdall.down.Store(false)
+
+	_, err = client.GetLatestSpan(context.Background())
+	require.NoError(t, err)
+
+	code, status = get()
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, status.Healthy)
+}
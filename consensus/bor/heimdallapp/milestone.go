@@ -3,6 +3,7 @@ package heimdallapp
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/milestone"
@@ -39,6 +40,29 @@ func (h *HeimdallAppClient) FetchMilestone(_ context.Context) (*milestone.Milest
 	return milestone, nil
 }
 
+func (h *HeimdallAppClient) FetchMilestoneList(_ context.Context, fromNumber, toNumber int64) ([]*milestone.Milestone, error) {
+	if fromNumber < 1 || toNumber < fromNumber {
+		return nil, fmt.Errorf("invalid milestone range [%d, %d]", fromNumber, toNumber)
+	}
+
+	log.Debug("Fetching Milestone List", "from", fromNumber, "to", toNumber)
+
+	milestones := make([]*milestone.Milestone, 0, toNumber-fromNumber+1)
+
+	for number := fromNumber; number <= toNumber; number++ {
+		res, err := h.hApp.MilestoneKeeper.GetMilestoneByNumber(h.NewContext(), uint64(number))
+		if err != nil {
+			return nil, err
+		}
+
+		milestones = append(milestones, toBorMilestone(res))
+	}
+
+	log.Debug("Fetched Milestone List", "from", fromNumber, "to", toNumber)
+
+	return milestones, nil
+}
+
 func (h *HeimdallAppClient) FetchNoAckMilestone(_ context.Context, milestoneID string) error {
 	return errors.New("not implemented in heimdallv2")
 }
@@ -2,12 +2,15 @@ package heimdallgrpc
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/bor/heimdall"
 	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/milestone"
 	"github.com/ethereum/go-ethereum/log"
+
+	milestoneTypes "github.com/0xPolygon/heimdall-v2/x/milestone/types"
 )
 
 func (h *HeimdallGRPCClient) FetchMilestoneCount(ctx context.Context) (int64, error) {
@@ -71,3 +74,53 @@ func (h *HeimdallGRPCClient) FetchMilestone(ctx context.Context) (*milestone.Mil
 
 	return milestone, nil
 }
+
+func (h *HeimdallGRPCClient) FetchMilestoneList(ctx context.Context, fromNumber, toNumber int64) ([]*milestone.Milestone, error) {
+	if fromNumber < 1 || toNumber < fromNumber {
+		return nil, fmt.Errorf("invalid milestone range [%d, %d]", fromNumber, toNumber)
+	}
+
+	log.Debug("Fetching milestone list", "from", fromNumber, "to", toNumber)
+
+	var err error
+
+	// Start the timer and set the request type on the context.
+	start := time.Now()
+	ctx = heimdall.WithRequestType(ctx, heimdall.MilestoneRequest)
+
+	// Defer the metrics call.
+	defer func() {
+		heimdall.SendMetrics(ctx, start, err == nil)
+	}()
+
+	milestones := make([]*milestone.Milestone, 0, toNumber-fromNumber+1)
+
+	for number := fromNumber; number <= toNumber; number++ {
+		req := &milestoneTypes.QueryMilestoneRequest{
+			Number: uint64(number),
+		}
+
+		var res *milestoneTypes.QueryMilestoneResponse
+
+		res, err = h.milestoneQueryClient.GetMilestoneByNumber(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		fetchedMilestone := res.GetMilestone()
+
+		milestones = append(milestones, &milestone.Milestone{
+			Proposer:    common.HexToAddress(fetchedMilestone.Proposer),
+			StartBlock:  fetchedMilestone.StartBlock,
+			EndBlock:    fetchedMilestone.EndBlock,
+			Hash:        common.BytesToHash(fetchedMilestone.Hash),
+			BorChainID:  fetchedMilestone.BorChainId,
+			MilestoneID: fetchedMilestone.MilestoneId,
+			Timestamp:   fetchedMilestone.Timestamp,
+		})
+	}
+
+	log.Debug("Fetched milestone list", "from", fromNumber, "to", toNumber)
+
+	return milestones, nil
+}
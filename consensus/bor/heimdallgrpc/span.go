@@ -2,11 +2,16 @@ package heimdallgrpc
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
 	"github.com/ethereum/go-ethereum/consensus/bor/heimdall"
 	"github.com/ethereum/go-ethereum/log"
+	grpcRetry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
 
 	"github.com/0xPolygon/heimdall-v2/x/bor/types"
 )
@@ -32,8 +37,13 @@ func (h *HeimdallGRPCClient) GetSpan(ctx context.Context, spanID uint64) (*types
 		Id: strconv.FormatUint(spanID, 10),
 	}
 
-	res, err := h.borQueryClient.GetSpanById(ctx, req)
+	// Don't retry on NotFound here so that span gaps in heimdall can be detected by the caller
+	res, err := h.borQueryClient.GetSpanById(ctx, req, grpcRetry.WithCodes(codes.Internal, codes.Unavailable, codes.Aborted))
 	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, fmt.Errorf("%w: %v", heimdall.ErrNotFound, err)
+		}
+
 		return nil, err
 	}
 
@@ -0,0 +1,34 @@
+package heimdallsim
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// rpcBackend is a simulator backend reading the local chain from the RPC API of a
+// bor node.
+type rpcBackend struct {
+	*ethclient.Client
+	rpc *rpc.Client
+}
+
+// NewRPCBackend returns a simulator backend using the RPC API of a bor node.
+func NewRPCBackend(client *rpc.Client) Backend {
+	return &rpcBackend{
+		Client: ethclient.NewClient(client),
+		rpc:    client,
+	}
+}
+
+// GetRootHash implements Backend, calling bor_getRootHash.
+func (b *rpcBackend) GetRootHash(ctx context.Context, start, end uint64) (common.Hash, error) {
+	var root string
+	if err := b.rpc.CallContext(ctx, &root, "bor_getRootHash", start, end); err != nil {
+		return common.Hash{}, err
+	}
+
+	return common.HexToHash(root), nil
+}
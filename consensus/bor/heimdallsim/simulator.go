@@ -0,0 +1,365 @@
+package heimdallsim
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+
+	borTypes "github.com/0xPolygon/heimdall-v2/x/bor/types"
+	clerkTypes "github.com/0xPolygon/heimdall-v2/x/clerk/types"
+	stakeTypes "github.com/0xPolygon/heimdall-v2/x/stake/types"
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	"github.com/cosmos/gogoproto/proto"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// DefaultSpanLength is the default number of blocks of the spans after the zeroth one
+	DefaultSpanLength = 6400
+
+	// zerothSpanEnd is the last block of the zeroth span, which is fixed in bor
+	zerothSpanEnd = 255
+
+	// checkpointLength and milestoneLength are the number of blocks covered by each
+	// simulated checkpoint and milestone
+	checkpointLength = 256
+	milestoneLength  = 16
+
+	// votingPower is the voting power of every simulated validator
+	votingPower = 10
+)
+
+var (
+	errNoValidators  = errors.New("no validators")
+	errNoSpanLength  = errors.New("span length must be positive")
+	errNoBackend     = errors.New("no chain backend configured")
+	errNotCommitted  = errors.New("not committed yet")
+	errInvalidNumber = errors.New("invalid number")
+)
+
+// Backend provides the simulator with the state of the local chain, from which the
+// checkpoints, milestones and latest span are derived.
+type Backend interface {
+	// HeaderByNumber returns the header of the given block, or the latest one if nil.
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+
+	// GetRootHash returns the checkpoint root hash of the given range of blocks.
+	GetRootHash(ctx context.Context, start, end uint64) (common.Hash, error)
+}
+
+// Simulator is a minimal heimdall REST server for local devnets. It serves the spans,
+// state sync events, checkpoints and milestones bor requests, so that a devnet running
+// without heimdall can cross span boundaries.
+//
+// The spans are derived deterministically from the validators: all of them are selected
+// producers of every span, while the proposer rotates with the span id. The validators
+// must match the ones of the genesis validator set contract. No state sync events are
+// ever reported.
+type Simulator struct {
+	validators []common.Address
+	spanLength uint64
+	chainID    string
+	backend    Backend // Optional, checkpoints, milestones and the latest span are unavailable without it
+
+	cdc *codec.ProtoCodec
+	mux *http.ServeMux
+}
+
+// New creates a heimdall simulator for the given validators. Spans after the zeroth
+// one last spanLength blocks.
+func New(validators []common.Address, spanLength uint64, chainID string, backend Backend) (*Simulator, error) {
+	if len(validators) == 0 {
+		return nil, errNoValidators
+	}
+
+	if spanLength == 0 {
+		return nil, errNoSpanLength
+	}
+
+	interfaceRegistry := codectypes.NewInterfaceRegistry()
+	cryptocodec.RegisterInterfaces(interfaceRegistry)
+
+	s := &Simulator{
+		validators: validators,
+		spanLength: spanLength,
+		chainID:    chainID,
+		backend:    backend,
+		cdc:        codec.NewProtoCodec(interfaceRegistry),
+		mux:        http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /bor/spans/latest", s.handleLatestSpan)
+	s.mux.HandleFunc("GET /bor/spans/{id}", s.handleSpan)
+	s.mux.HandleFunc("GET /clerk/time", s.handleStateSyncEvents)
+	s.mux.HandleFunc("GET /checkpoints/count", s.handleCheckpointCount)
+	s.mux.HandleFunc("GET /checkpoints/{number}", s.handleCheckpoint)
+	s.mux.HandleFunc("GET /milestones/count", s.handleMilestoneCount)
+	s.mux.HandleFunc("GET /milestones/{number}", s.handleMilestone)
+
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("Serving simulated heimdall request", "path", r.URL.Path)
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// Span returns the span with the given id.
+func (s *Simulator) Span(id uint64) *borTypes.Span {
+	start, end := uint64(0), uint64(zerothSpanEnd)
+	if id > 0 {
+		start = zerothSpanEnd + 1 + (id-1)*s.spanLength
+		end = start + s.spanLength - 1
+	}
+
+	validators := make([]*stakeTypes.Validator, len(s.validators))
+	producers := make([]stakeTypes.Validator, len(s.validators))
+
+	for i, address := range s.validators {
+		validators[i] = &stakeTypes.Validator{
+			ValId:       uint64(i + 1),
+			VotingPower: votingPower,
+			Signer:      address.Hex(),
+		}
+		producers[i] = *validators[i]
+	}
+
+	return &borTypes.Span{
+		Id:         id,
+		StartBlock: start,
+		EndBlock:   end,
+		ValidatorSet: stakeTypes.ValidatorSet{
+			Validators: validators,
+			Proposer:   validators[id%uint64(len(validators))],
+		},
+		SelectedProducers: producers,
+		BorChainId:        s.chainID,
+	}
+}
+
+// spanID returns the id of the span containing the given block.
+func (s *Simulator) spanID(number uint64) uint64 {
+	if number <= zerothSpanEnd {
+		return 0
+	}
+
+	return 1 + (number-zerothSpanEnd-1)/s.spanLength
+}
+
+func (s *Simulator) handleLatestSpan(w http.ResponseWriter, r *http.Request) {
+	head, err := s.head(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Heimdall proposes the next span ahead of time
+	s.writeProto(w, &borTypes.QueryLatestSpanResponse{Span: *s.Span(s.spanID(head.Number.Uint64()) + 1)})
+}
+
+func (s *Simulator) handleSpan(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: span %q", errInvalidNumber, r.PathValue("id")))
+		return
+	}
+
+	s.writeProto(w, &borTypes.QuerySpanByIdResponse{Span: s.Span(id)})
+}
+
+func (s *Simulator) handleStateSyncEvents(w http.ResponseWriter, _ *http.Request) {
+	s.writeProto(w, &clerkTypes.RecordListResponse{})
+}
+
+func (s *Simulator) handleCheckpointCount(w http.ResponseWriter, r *http.Request) {
+	head, err := s.head(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]string{
+		"ack_count": strconv.FormatUint((head.Number.Uint64()+1)/checkpointLength, 10),
+	})
+}
+
+func (s *Simulator) handleCheckpoint(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	number, err := s.resolveNumber(ctx, r.PathValue("number"), checkpointLength)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	start, end := (number-1)*checkpointLength, number*checkpointLength-1
+
+	header, err := s.committedHeader(ctx, end)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	root, err := s.backend.GetRootHash(ctx, start, end)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]map[string]string{
+		"checkpoint": {
+			"proposer":     s.validators[0].Hex(),
+			"start_block":  strconv.FormatUint(start, 10),
+			"end_block":    strconv.FormatUint(end, 10),
+			"root_hash":    base64.StdEncoding.EncodeToString(root.Bytes()),
+			"bor_chain_id": s.chainID,
+			"timestamp":    strconv.FormatUint(header.Time, 10),
+		},
+	})
+}
+
+func (s *Simulator) handleMilestoneCount(w http.ResponseWriter, r *http.Request) {
+	head, err := s.head(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]string{
+		"count": strconv.FormatUint((head.Number.Uint64()+1)/milestoneLength, 10),
+	})
+}
+
+func (s *Simulator) handleMilestone(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	number, err := s.resolveNumber(ctx, r.PathValue("number"), milestoneLength)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	start, end := (number-1)*milestoneLength, number*milestoneLength-1
+
+	header, err := s.committedHeader(ctx, end)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	hash := header.Hash()
+
+	writeJSON(w, map[string]map[string]string{
+		"milestone": {
+			"proposer":         s.validators[0].Hex(),
+			"start_block":      strconv.FormatUint(start, 10),
+			"end_block":        strconv.FormatUint(end, 10),
+			"hash":             base64.StdEncoding.EncodeToString(hash.Bytes()),
+			"bor_chain_id":     s.chainID,
+			"milestone_id":     fmt.Sprintf("%d - %s", number, hash.Hex()),
+			"timestamp":        strconv.FormatUint(header.Time, 10),
+			"total_difficulty": "0",
+		},
+	})
+}
+
+// resolveNumber parses the sequence number of a checkpoint or milestone covering the
+// given number of blocks, with "latest" resolving to the last one committed.
+func (s *Simulator) resolveNumber(ctx context.Context, value string, length uint64) (uint64, error) {
+	if value != "latest" {
+		number, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || number == 0 {
+			return 0, fmt.Errorf("%w: %q", errInvalidNumber, value)
+		}
+
+		return number, nil
+	}
+
+	head, err := s.head(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	number := (head.Number.Uint64() + 1) / length
+	if number == 0 {
+		return 0, errNotCommitted
+	}
+
+	return number, nil
+}
+
+// committedHeader returns the header of the given block, failing if the block isn't
+// part of the local chain yet.
+func (s *Simulator) committedHeader(ctx context.Context, number uint64) (*types.Header, error) {
+	head, err := s.head(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if number > head.Number.Uint64() {
+		return nil, fmt.Errorf("%w: block %d", errNotCommitted, number)
+	}
+
+	return s.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+}
+
+// head returns the header of the latest block of the local chain.
+func (s *Simulator) head(ctx context.Context) (*types.Header, error) {
+	if s.backend == nil {
+		return nil, errNoBackend
+	}
+
+	return s.backend.HeaderByNumber(ctx, nil)
+}
+
+// writeProto writes a response encoded like heimdall does for its proto messages.
+func (s *Simulator) writeProto(w http.ResponseWriter, msg proto.Message) {
+	body, err := s.cdc.MarshalJSON(msg)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, err := w.Write(body); err != nil {
+		log.Debug("Failed to write simulated heimdall response", "err", err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debug("Failed to write simulated heimdall response", "err", err)
+	}
+}
+
+// writeError reports the error with the status code heimdall would use, so that bor
+// handles it the same way.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+
+	switch {
+	case errors.Is(err, errInvalidNumber):
+		status = http.StatusBadRequest
+	case errors.Is(err, errNotCommitted):
+		status = http.StatusNotFound
+	case errors.Is(err, errNoBackend):
+		status = http.StatusServiceUnavailable
+	}
+
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,132 @@
+package heimdallsim
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// testBackend is a chain backend with synthetic headers up to the given head
+type testBackend struct {
+	head uint64
+}
+
+func (b *testBackend) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		number = new(big.Int).SetUint64(b.head)
+	}
+
+	return &types.Header{Number: new(big.Int).Set(number), Time: 1000 + 2*number.Uint64()}, nil
+}
+
+func (b *testBackend) GetRootHash(_ context.Context, start, end uint64) (common.Hash, error) {
+	return common.BigToHash(new(big.Int).SetUint64(start<<32 | end)), nil
+}
+
+func newTestSimulator(t *testing.T, backend Backend) *heimdall.HeimdallClient {
+	t.Helper()
+
+	sim, err := New([]common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02")}, 256, "1337", backend)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(sim)
+	t.Cleanup(srv.Close)
+
+	client := heimdall.NewHeimdallClient(srv.URL, time.Second, 10*time.Millisecond, 1)
+	t.Cleanup(client.Close)
+
+	return client
+}
+
+func TestSimulatorSpans(t *testing.T) {
+	t.Parallel()
+
+	backend := &testBackend{head: 300}
+	client := newTestSimulator(t, backend)
+
+	// The next span is proposed ahead of time
+	span, err := client.GetLatestSpan(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), span.Id)
+	require.Equal(t, uint64(512), span.StartBlock)
+	require.Equal(t, uint64(767), span.EndBlock)
+
+	// Spans are deterministic
+	first, err := client.GetSpan(t.Context(), 7)
+	require.NoError(t, err)
+	second, err := client.GetSpan(t.Context(), 7)
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+	require.Equal(t, uint64(256+6*256), first.StartBlock)
+	require.Equal(t, common.HexToAddress("0x02"), common.HexToAddress(first.ValidatorSet.Proposer.Signer))
+
+	// No state sync events are reported
+	events, err := client.StateSyncEvents(t.Context(), 1, time.Now().Unix())
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
+
+func TestSimulatorCheckpointsAndMilestones(t *testing.T) {
+	t.Parallel()
+
+	backend := &testBackend{head: 600}
+	client := newTestSimulator(t, backend)
+
+	// Checkpoints cover 256 blocks
+	count, err := client.FetchCheckpointCount(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	checkpoint, err := client.FetchCheckpoint(t.Context(), -1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(256), checkpoint.StartBlock)
+	require.Equal(t, uint64(511), checkpoint.EndBlock)
+	require.Equal(t, common.BigToHash(big.NewInt(256<<32|511)), checkpoint.RootHash)
+	require.Equal(t, uint64(1000+2*511), checkpoint.Timestamp)
+	require.Equal(t, "1337", checkpoint.BorChainID)
+
+	// Milestones cover 16 blocks and commit to the local chain
+	mcount, err := client.FetchMilestoneCount(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, int64(37), mcount)
+
+	latest, err := client.FetchMilestone(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, uint64(576), latest.StartBlock)
+	require.Equal(t, uint64(591), latest.EndBlock)
+
+	header, _ := backend.HeaderByNumber(t.Context(), big.NewInt(591))
+	require.Equal(t, header.Hash(), latest.Hash)
+
+	milestone, err := client.FetchMilestoneByNumber(t.Context(), 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), milestone.StartBlock)
+	require.Equal(t, uint64(15), milestone.EndBlock)
+
+	// Milestones beyond the local chain don't exist yet
+	_, err = client.FetchMilestoneByNumber(t.Context(), 38)
+	require.ErrorIs(t, err, heimdall.ErrNotFound)
+}
+
+func TestSimulatorWithoutBackend(t *testing.T) {
+	t.Parallel()
+
+	client := newTestSimulator(t, nil)
+
+	// Spans are served without a backend
+	span, err := client.GetSpan(t.Context(), 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(255), span.EndBlock)
+
+	// Everything derived from the local chain isn't
+	_, err = client.FetchMilestone(t.Context())
+	require.ErrorIs(t, err, heimdall.ErrServiceUnavailable)
+}
@@ -2,40 +2,105 @@ package heimdallws
 
 import (
 	"context"
-	"encoding/json"
-	"strconv"
+	"slices"
 	"sync"
 	"time"
 
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/milestone"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/gorilla/websocket"
 )
 
 // HeimdallWSClient represents a websocket client with auto-reconnection.
 type HeimdallWSClient struct {
-	conn   *websocket.Conn
-	url    string // store the URL for reconnection
-	events chan *milestone.Milestone
-	done   chan struct{}
-	mu     sync.Mutex
+	conn    *websocket.Conn
+	url     string // store the URL for reconnection
+	events  chan *milestone.Milestone
+	done    chan struct{}
+	stopped chan struct{} // closed once the reader goroutine exits
+	mu      sync.Mutex
+
+	fetcher     MilestoneFetcher     // optional, used to backfill milestones missed while reconnecting
+	last        *milestone.Milestone // last milestone delivered to the consumer
+	reconnected bool                 // set when the connection was re-established since the last delivery
+	connected   bool                 // set while the subscription is established
+}
+
+// MilestoneFetcher fetches milestones from heimdall by their sequence number. It is
+// implemented by the heimdall HTTP client.
+type MilestoneFetcher interface {
+	FetchMilestoneCount(ctx context.Context) (int64, error)
+	FetchMilestoneByNumber(ctx context.Context, number int64) (*milestone.Milestone, error)
 }
 
+// reconnectDelay is the delay between two consecutive connection attempts.
+var reconnectDelay = 10 * time.Second
+
+// backfillTimeout bounds the backfill of the milestones missed while reconnecting. The
+// HTTP client retries until heimdall answers, the live events would be held back
+// meanwhile.
+var backfillTimeout = 30 * time.Second
+
+// defaultEventBuffer is the default number of milestone events buffered for the consumer.
+const defaultEventBuffer = 16
+
+// maxBackfillMilestones is the maximum number of milestones looked up when backfilling
+// the ones missed while reconnecting.
+const maxBackfillMilestones = 100
+
+var (
+	// milestoneLagHistogram tracks the delay in milliseconds between the emission of a
+	// milestone by heimdall and the receipt of its event.
+	milestoneLagHistogram = metrics.NewRegisteredHistogram("heimdall/ws/milestone_lag", nil, metrics.NewExpDecaySample(1028, 0.015))
+
+	// negativeLagCounter counts the milestone events received before their emission time,
+	// i.e. when the local clock is behind the one of heimdall.
+	negativeLagCounter = metrics.NewRegisteredCounter("heimdall/ws/negative_lag", nil)
+
+	// undecodableCounter counts the messages which couldn't be parsed.
+	undecodableCounter = metrics.NewRegisteredCounter("heimdall/ws/undecodable", nil)
+
+	// droppedCounter counts the milestone events dropped as already delivered.
+	droppedCounter = metrics.NewRegisteredCounter("heimdall/ws/dropped", nil)
+
+	// droppedEventsCounter counts the oldest milestone events dropped from a full buffer
+	// to make room for newer ones.
+	droppedEventsCounter = metrics.NewRegisteredCounter("heimdall/ws/dropped_events", nil)
+)
+
 // NewHeimdallWSClient creates a new WS client for Heimdall.
 func NewHeimdallWSClient(url string) (*HeimdallWSClient, error) {
 	return &HeimdallWSClient{
 		conn:   nil,
 		url:    url,
-		events: make(chan *milestone.Milestone),
+		events: make(chan *milestone.Milestone, defaultEventBuffer),
 		done:   make(chan struct{}),
 	}, nil
 }
 
+// SetMilestoneFetcher sets the fetcher used to backfill the milestones emitted while
+// the connection was down. It must be called before subscribing.
+func (c *HeimdallWSClient) SetMilestoneFetcher(fetcher MilestoneFetcher) {
+	c.fetcher = fetcher
+}
+
+// SetEventBuffer sets the number of milestone events buffered for the consumer. Once the
+// buffer is full, the oldest event is dropped in favour of the new one, so that a slow
+// consumer never stalls the connection. A size of zero disables the buffer, every
+// event then waits for the consumer. It must be called before subscribing.
+func (c *HeimdallWSClient) SetEventBuffer(size int) {
+	c.events = make(chan *milestone.Milestone, max(size, 0))
+}
+
 // SubscribeMilestoneEvents sends the subscription request and starts processing incoming messages.
 func (c *HeimdallWSClient) SubscribeMilestoneEvents(ctx context.Context) <-chan *milestone.Milestone {
 	c.tryUntilSubscribeMilestoneEvents(ctx)
 
+	c.mu.Lock()
+	c.stopped = make(chan struct{})
+	c.mu.Unlock()
+
 	// Start the goroutine to read messages.
 	go c.readMessages(ctx)
 
@@ -44,14 +109,9 @@ func (c *HeimdallWSClient) SubscribeMilestoneEvents(ctx context.Context) <-chan
 
 // retry until subscribe
 func (c *HeimdallWSClient) tryUntilSubscribeMilestoneEvents(ctx context.Context) {
-	firstTime := true
+	var delay time.Duration
 	for {
-		if !firstTime {
-			time.Sleep(10 * time.Second)
-		}
-		firstTime = false
-
-		// Check for context cancellation.
+		// Wait for the next attempt, bailing out on context cancellation or unsubscription.
 		select {
 		case <-ctx.Done():
 			log.Info("Context cancelled during reconnection")
@@ -59,15 +119,27 @@ func (c *HeimdallWSClient) tryUntilSubscribeMilestoneEvents(ctx context.Context)
 		case <-c.done:
 			log.Info("Client unsubscribed during reconnection")
 			return
-		default:
+		case <-time.After(delay):
 		}
 
+		delay = reconnectDelay
+
 		conn, _, err := websocket.DefaultDialer.Dial(c.url, nil)
 		if err != nil {
 			log.Error("failed to dial websocket on heimdall ws subscription", "err", err)
 			continue
 		}
+
 		c.mu.Lock()
+		select {
+		case <-c.done:
+			// Unsubscribed while dialing, drop the new connection.
+			c.mu.Unlock()
+			conn.Close()
+
+			return
+		default:
+		}
 		c.conn = conn
 		c.mu.Unlock()
 
@@ -79,18 +151,27 @@ func (c *HeimdallWSClient) tryUntilSubscribeMilestoneEvents(ctx context.Context)
 		}
 		req.Params.Query = "tm.event='NewBlock' AND milestone.number>0"
 
-		if err := c.conn.WriteJSON(req); err != nil {
+		if err := conn.WriteJSON(req); err != nil {
 			log.Error("failed to send subscription request on heimdall ws subscription", "err", err)
 			continue
 		}
 		log.Info("Successfully connected on heimdall ws subscription")
+
+		c.setConnected(true)
+
 		return
 	}
 }
 
 // readMessages continuously reads messages from the websocket, handling reconnections if necessary.
 func (c *HeimdallWSClient) readMessages(ctx context.Context) {
+	c.mu.Lock()
+	stopped := c.stopped
+	c.mu.Unlock()
+
+	defer close(stopped)
 	defer close(c.events)
+
 	for {
 		// Check if the context or unsubscribe signal is set.
 		select {
@@ -102,90 +183,260 @@ func (c *HeimdallWSClient) readMessages(ctx context.Context) {
 			// continue to process messages
 		}
 
-		if err := c.conn.SetReadDeadline(time.Now().Add(30 * time.Second)); err != nil {
+		c.mu.Lock()
+		conn := c.conn
+		c.mu.Unlock()
+
+		if err := conn.SetReadDeadline(time.Now().Add(30 * time.Second)); err != nil {
 			log.Error("failed to set read deadline on heimdall ws subscription", "err", err)
 
+			c.setConnected(false)
+			c.reconnected = true
 			c.tryUntilSubscribeMilestoneEvents(ctx)
 			continue
 		}
 
-		_, message, err := c.conn.ReadMessage()
+		_, message, err := conn.ReadMessage()
+		received := time.Now()
+
 		if err != nil {
 			log.Error("connection lost; will attempt to reconnect on heimdall ws subscription", "error", err)
 
+			c.setConnected(false)
+			c.reconnected = true
 			c.tryUntilSubscribeMilestoneEvents(ctx)
 			continue
 		}
 
-		var resp wsResponse
-		if err := json.Unmarshal(message, &resp); err != nil {
-			// Skip messages that don't match the expected format.
+		m, err := parseMilestone(message)
+		if err != nil {
+			undecodableCounter.Inc(1)
+			log.Warn("failed to parse message on heimdall ws subscription", "err", err)
 			continue
 		}
 
-		// Find the milestone event.
-		var milestoneEvent *wsEvent
-		for _, event := range resp.Result.Data.Value.FinalizeBlock.Events {
-			if event.Type == "milestone" {
-				// In this case their types are set to the types of the respective iteration values
-				// and their scope is the block of the "for" statement; they are re-used in each iteration.
-				e := event
-				milestoneEvent = &e
-				break
-			}
-		}
-		if milestoneEvent == nil {
+		// Skip messages which don't carry a milestone (e.g. the subscription acknowledgement)
+		if m == nil {
 			continue
 		}
 
-		// Map attributes for easier lookup.
-		attrs := make(map[string]string)
-		for _, attr := range milestoneEvent.Attributes {
-			attrs[attr.Key] = attr.Value
-		}
+		recordMilestoneLag(m, received)
 
-		// Build the Milestone object from attributes.
-		m := &milestone.Milestone{
-			Proposer:    common.HexToAddress(attrs["proposer"]),
-			Hash:        common.HexToHash(attrs["hash"]),
-			BorChainID:  attrs["bor_chain_id"],
-			MilestoneID: attrs["milestone_id"],
+		if !c.deliver(ctx, m) {
+			return
 		}
-		if startBlock, err := strconv.ParseUint(attrs["start_block"], 10, 64); err == nil {
-			m.StartBlock = startBlock
+	}
+}
+
+// deliver sends the milestone to the consumer. If the connection was re-established since
+// the last delivery, the milestones emitted in between are backfilled first. Milestones
+// which were already delivered are dropped. It returns false if the subscription stopped.
+func (c *HeimdallWSClient) deliver(ctx context.Context, m *milestone.Milestone) bool {
+	if c.isDelivered(m) {
+		droppedCounter.Inc(1)
+		return true
+	}
+
+	if c.reconnected {
+		c.reconnected = false
+
+		for _, missed := range c.backfill(ctx, m) {
+			if !c.send(ctx, missed) {
+				return false
+			}
 		}
-		if endBlock, err := strconv.ParseUint(attrs["end_block"], 10, 64); err == nil {
-			m.EndBlock = endBlock
+	}
+
+	return c.send(ctx, m)
+}
+
+// recordMilestoneLag records the delay between the emission of the milestone and the
+// receipt of its event, returning it. Negative delays caused by clock skew are clamped
+// to zero and counted separately. Milestones without a timestamp are ignored.
+func recordMilestoneLag(m *milestone.Milestone, received time.Time) time.Duration {
+	if m.Timestamp == 0 {
+		return 0
+	}
+
+	lag := received.Sub(time.Unix(int64(m.Timestamp), 0))
+	if lag < 0 {
+		negativeLagCounter.Inc(1)
+		lag = 0
+	}
+
+	milestoneLagHistogram.Update(lag.Milliseconds())
+
+	return lag
+}
+
+// send delivers a single milestone, respecting context cancellation. If the event buffer
+// is full, the oldest buffered milestone is dropped to make room for the new one.
+func (c *HeimdallWSClient) send(ctx context.Context, m *milestone.Milestone) bool {
+	if c.isDelivered(m) {
+		return true
+	}
+
+	for cap(c.events) > 0 {
+		select {
+		case c.events <- m:
+			c.last = m
+			return true
+		case <-ctx.Done():
+			return false
+		case <-c.done:
+			return false
+		default:
 		}
-		if timestamp, err := strconv.ParseUint(attrs["timestamp"], 10, 64); err == nil {
-			m.Timestamp = timestamp
+
+		// The consumer may have drained the buffer in between, retry right away then
+		select {
+		case dropped := <-c.events:
+			droppedEventsCounter.Inc(1)
+			log.Warn("Dropped oldest milestone event, consumer is lagging behind on heimdall ws subscription",
+				"dropped", dropped.MilestoneID, "end", dropped.EndBlock, "buffer", cap(c.events))
+		default:
 		}
+	}
+
+	select {
+	case c.events <- m:
+		c.last = m
+		return true
+	case <-ctx.Done():
+		return false
+	case <-c.done:
+		return false
+	}
+}
+
+// isDelivered reports whether the milestone (or a later one) was already delivered.
+func (c *HeimdallWSClient) isDelivered(m *milestone.Milestone) bool {
+	if c.last == nil {
+		return false
+	}
+
+	return m.MilestoneID == c.last.MilestoneID || m.EndBlock <= c.last.EndBlock
+}
 
-		// Deliver the milestone event, respecting context cancellation.
+// backfill fetches the milestones between the last delivered one and the given one,
+// in ascending order. Errors are logged and result in a partial (or empty) backfill, so
+// does a backfill outlasting backfillTimeout or the subscription.
+func (c *HeimdallWSClient) backfill(ctx context.Context, next *milestone.Milestone) []*milestone.Milestone {
+	// Nothing to backfill if there is no gap between the last delivered milestone and the new one
+	if c.fetcher == nil || c.last == nil || next.StartBlock <= c.last.EndBlock+1 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, backfillTimeout)
+	defer cancel()
+
+	// Abort the backfill on unsubscription
+	go func() {
 		select {
-		case c.events <- m:
+		case <-c.done:
+			cancel()
 		case <-ctx.Done():
-			return
+		}
+	}()
+
+	count, err := c.fetcher.FetchMilestoneCount(ctx)
+	if err != nil {
+		log.Warn("Failed to fetch milestone count for backfill on heimdall ws subscription", "err", err)
+		return nil
+	}
+
+	var missed []*milestone.Milestone
+
+	// Walk back from the latest milestone until the last delivered one is reached
+	for number := count; number > 0 && count-number < maxBackfillMilestones; number-- {
+		m, err := c.fetcher.FetchMilestoneByNumber(ctx, number)
+		if err != nil {
+			log.Warn("Failed to fetch milestone for backfill on heimdall ws subscription", "number", number, "err", err)
+			break
+		}
+
+		if m.EndBlock <= c.last.EndBlock {
+			break
+		}
+
+		// Skip the milestones emitted after the one being delivered
+		if m.EndBlock < next.StartBlock {
+			missed = append(missed, m)
 		}
 	}
+
+	slices.Reverse(missed)
+
+	if len(missed) > 0 {
+		log.Info("Backfilled milestones missed while reconnecting on heimdall ws subscription", "count", len(missed),
+			"from", missed[0].StartBlock, "to", missed[len(missed)-1].EndBlock)
+	}
+
+	return missed
 }
 
-// Unsubscribe signals the reader goroutine to stop.
-func (c *HeimdallWSClient) Unsubscribe(ctx context.Context) error {
+// IsConnected reports whether the subscription is currently established.
+func (c *HeimdallWSClient) IsConnected() bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+
+	return c.connected
+}
+
+func (c *HeimdallWSClient) setConnected(connected bool) {
+	c.mu.Lock()
+	c.connected = connected
+	c.mu.Unlock()
+}
+
+// Unsubscribe stops the subscription and waits for the reader goroutine to exit or for
+// the context to be cancelled. It is safe to call it multiple times and while the client
+// is reconnecting.
+func (c *HeimdallWSClient) Unsubscribe(ctx context.Context) error {
+	c.stop()
+
+	c.mu.Lock()
+	stopped := c.stopped
+	c.mu.Unlock()
+
+	// Nothing to wait for if the subscription was never started
+	if stopped == nil {
+		return nil
+	}
+
 	select {
-	case <-c.done:
-		// Already unsubscribed.
-	default:
-		close(c.done)
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	return nil
 }
 
-// Close cleanly terminates the websocket connection.
+// Close cleanly terminates the websocket connection. It doesn't wait for the reader
+// goroutine to exit, use Unsubscribe for that.
 func (c *HeimdallWSClient) Close() error {
+	return c.stop()
+}
+
+// stop signals the reader goroutine to stop and closes the current connection (if any)
+// so that a pending read returns immediately.
+func (c *HeimdallWSClient) stop() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+
+	select {
+	case <-c.done:
+		// Already stopped.
+		return nil
+	default:
+		close(c.done)
+	}
+
+	c.connected = false
+
+	if c.conn == nil {
+		return nil
+	}
+
 	return c.conn.Close()
 }
@@ -0,0 +1,442 @@
+package heimdallws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/bor"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/milestone"
+	"github.com/ethereum/go-ethereum/tests/bor/mocks"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ bor.IHeimdallWSClient = (*HeimdallWSClient)(nil)
+	_ bor.IHeimdallWSClient = (*mocks.MockIHeimdallWSClient)(nil)
+)
+
+// milestoneMessage returns a websocket message carrying a milestone event.
+func milestoneMessage(id string, startBlock, endBlock uint64) string {
+	return milestoneMessageAt(id, startBlock, endBlock, 1700000000)
+}
+
+// milestoneMessageAt returns a websocket message carrying a milestone event emitted at
+// the given unix time.
+func milestoneMessageAt(id string, startBlock, endBlock uint64, timestamp int64) string {
+	return fmt.Sprintf(`{
+	"jsonrpc": "2.0",
+	"id": 0,
+	"result": {
+		"data": {
+			"type": "tendermint/event/NewBlock",
+			"value": {
+				"result_finalize_block": {
+					"events": [{
+						"type": "milestone",
+						"attributes": [
+							{"key": "proposer", "value": "0x0000000000000000000000000000000000000001"},
+							{"key": "start_block", "value": "%d"},
+							{"key": "end_block", "value": "%d"},
+							{"key": "hash", "value": "0x0000000000000000000000000000000000000000000000000000000000000010"},
+							{"key": "bor_chain_id", "value": "137"},
+							{"key": "milestone_id", "value": "%s"},
+							{"key": "timestamp", "value": "%d"}
+						]
+					}]
+				}
+			}
+		}
+	}
+}`, startBlock, endBlock, id, timestamp)
+}
+
+// newTestServer starts a websocket server which acknowledges the subscription request
+// by sending the messages scripted for the respective connection. All connections but
+// the last one are dropped once their messages are sent, the last one is kept open until
+// the client goes away.
+func newTestServer(t *testing.T, conns ...[]string) *httptest.Server {
+	t.Helper()
+
+	server, _ := newCountingTestServer(t, conns...)
+
+	return server
+}
+
+// newCountingTestServer is newTestServer also returning the number of subscriptions
+// received by the server.
+func newCountingTestServer(t *testing.T, conns ...[]string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var (
+		upgrader = websocket.Upgrader{}
+		count    = new(atomic.Int32)
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var req subscriptionRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+
+		i := int(count.Add(1)) - 1
+		if i >= len(conns) {
+			i = len(conns) - 1
+		}
+
+		var messages []string
+		if i >= 0 {
+			messages = conns[i]
+		}
+
+		for _, msg := range messages {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+				return
+			}
+		}
+
+		// Drop the connection to trigger a reconnect
+		if i < len(conns)-1 {
+			return
+		}
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, count
+}
+
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestSubscribeMilestoneEvents(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t, []string{milestoneMessage("milestone-1", 1, 16)})
+
+	client, err := NewHeimdallWSClient(wsURL(server))
+	require.NoError(t, err)
+
+	events := client.SubscribeMilestoneEvents(context.Background())
+
+	select {
+	case m := <-events:
+		require.Equal(t, uint64(1), m.StartBlock)
+		require.Equal(t, uint64(16), m.EndBlock)
+		require.Equal(t, "milestone-1", m.MilestoneID)
+		require.Equal(t, uint64(1700000000), m.Timestamp)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for milestone event")
+	}
+
+	require.True(t, client.IsConnected())
+	require.NoError(t, client.Unsubscribe(context.Background()))
+	require.False(t, client.IsConnected())
+}
+
+func TestMilestoneLagMetrics(t *testing.T) {
+	// Don't run in parallel as the metrics are shared
+	var (
+		now         = time.Now().Unix()
+		negative    = negativeLagCounter.Snapshot().Count()
+		undecodable = undecodableCounter.Snapshot().Count()
+		dropped     = droppedCounter.Snapshot().Count()
+	)
+
+	server := newTestServer(t, []string{
+		milestoneMessageAt("milestone-1", 1, 16, now+3600), // emitted in the future, clock skew
+		"not a json message",
+		milestoneMessageAt("milestone-1", 1, 16, now+3600), // duplicate
+		milestoneMessageAt("milestone-2", 17, 32, now-30),
+	})
+
+	client, err := NewHeimdallWSClient(wsURL(server))
+	require.NoError(t, err)
+
+	events := client.SubscribeMilestoneEvents(context.Background())
+
+	for _, id := range []string{"milestone-1", "milestone-2"} {
+		select {
+		case m := <-events:
+			require.Equal(t, id, m.MilestoneID)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", id)
+		}
+	}
+
+	require.NoError(t, client.Unsubscribe(context.Background()))
+
+	require.Equal(t, negative+1, negativeLagCounter.Snapshot().Count(), "negative lag not counted")
+	require.Equal(t, undecodable+1, undecodableCounter.Snapshot().Count(), "undecodable message not counted")
+	require.Equal(t, dropped+1, droppedCounter.Snapshot().Count(), "duplicate milestone not counted")
+
+	// Lags are measured from the milestone timestamp and clamped at zero
+	received := time.Unix(1700000030, 0)
+	require.Equal(t, 30*time.Second, recordMilestoneLag(&milestone.Milestone{Timestamp: 1700000000}, received))
+	require.Equal(t, time.Duration(0), recordMilestoneLag(&milestone.Milestone{Timestamp: 1700000060}, received))
+	require.Equal(t, time.Duration(0), recordMilestoneLag(&milestone.Milestone{}, received))
+	require.Equal(t, negative+2, negativeLagCounter.Snapshot().Count(), "negative lag not counted")
+}
+
+func TestUnsubscribeTwice(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+
+	client, err := NewHeimdallWSClient(wsURL(server))
+	require.NoError(t, err)
+
+	events := client.SubscribeMilestoneEvents(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, client.Unsubscribe(ctx))
+	require.NoError(t, client.Unsubscribe(ctx))
+	require.NoError(t, client.Close())
+
+	_, ok := <-events
+	require.False(t, ok, "events channel should be closed after unsubscribing")
+}
+
+func TestUnsubscribeDuringReconnect(t *testing.T) {
+	// Don't run in parallel as the reconnect delay is modified
+	defer func(delay time.Duration) { reconnectDelay = delay }(reconnectDelay)
+	reconnectDelay = time.Hour
+
+	// Start and immediately stop a server to get an address nobody is listening on
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := wsURL(server)
+	server.Close()
+
+	client, err := NewHeimdallWSClient(url)
+	require.NoError(t, err)
+
+	subscribed := make(chan (<-chan struct{}), 1)
+
+	go func() {
+		events := client.SubscribeMilestoneEvents(context.Background())
+
+		drained := make(chan struct{})
+		go func() {
+			for range events {
+			}
+			close(drained)
+		}()
+
+		subscribed <- drained
+	}()
+
+	// Give the client some time to fail the first attempt and wait for the next one
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, client.Unsubscribe(ctx))
+
+	select {
+	case drained := <-subscribed:
+		select {
+		case <-drained:
+		case <-time.After(5 * time.Second):
+			t.Fatal("events channel wasn't closed after unsubscribing")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription didn't stop reconnecting after unsubscribing")
+	}
+
+	require.NoError(t, client.Unsubscribe(ctx))
+}
+
+func TestCloseWithoutConnection(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewHeimdallWSClient("ws://localhost:0")
+	require.NoError(t, err)
+
+	require.NoError(t, client.Close())
+	require.NoError(t, client.Close())
+	require.NoError(t, client.Unsubscribe(context.Background()))
+}
+
+// mockMilestoneFetcher serves milestones from a slice, the milestone with sequence
+// number n being at index n-1.
+type mockMilestoneFetcher struct {
+	milestones []*milestone.Milestone
+}
+
+func (f *mockMilestoneFetcher) FetchMilestoneCount(_ context.Context) (int64, error) {
+	return int64(len(f.milestones)), nil
+}
+
+func (f *mockMilestoneFetcher) FetchMilestoneByNumber(_ context.Context, number int64) (*milestone.Milestone, error) {
+	if number < 1 || number > int64(len(f.milestones)) {
+		return nil, errors.New("milestone not found")
+	}
+
+	return f.milestones[number-1], nil
+}
+
+func TestBackfillAfterReconnect(t *testing.T) {
+	// Don't run in parallel as the reconnect delay is modified
+	defer func(delay time.Duration) { reconnectDelay = delay }(reconnectDelay)
+	reconnectDelay = 10 * time.Millisecond
+
+	// The first connection delivers milestone 1 and drops. Milestones 2 and 3 are emitted
+	// while reconnecting and milestone 1 is delivered again along with milestone 4 once
+	// the new connection is established.
+	server := newTestServer(t,
+		[]string{milestoneMessage("milestone-1", 1, 16)},
+		[]string{
+			milestoneMessage("milestone-1", 1, 16),
+			milestoneMessage("milestone-4", 49, 64),
+			milestoneMessage("milestone-4", 49, 64),
+		},
+	)
+
+	client, err := NewHeimdallWSClient(wsURL(server))
+	require.NoError(t, err)
+
+	client.SetMilestoneFetcher(&mockMilestoneFetcher{
+		milestones: []*milestone.Milestone{
+			{MilestoneID: "milestone-1", StartBlock: 1, EndBlock: 16},
+			{MilestoneID: "milestone-2", StartBlock: 17, EndBlock: 32},
+			{MilestoneID: "milestone-3", StartBlock: 33, EndBlock: 48},
+			{MilestoneID: "milestone-4", StartBlock: 49, EndBlock: 64},
+			{MilestoneID: "milestone-5", StartBlock: 65, EndBlock: 80},
+		},
+	})
+
+	events := client.SubscribeMilestoneEvents(context.Background())
+
+	var ids []string
+
+	for len(ids) < 4 {
+		select {
+		case m := <-events:
+			ids = append(ids, m.MilestoneID)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for milestone events, got %v", ids)
+		}
+	}
+
+	require.Equal(t, []string{"milestone-1", "milestone-2", "milestone-3", "milestone-4"}, ids)
+
+	// Make sure no duplicates are delivered afterwards
+	select {
+	case m := <-events:
+		t.Fatalf("unexpected milestone event %s", m.MilestoneID)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, client.Unsubscribe(context.Background()))
+}
+
+// stallingMilestoneFetcher blocks until the context is done, like the HTTP client
+// retrying while heimdall is down.
+type stallingMilestoneFetcher struct{}
+
+func (stallingMilestoneFetcher) FetchMilestoneCount(ctx context.Context) (int64, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func (stallingMilestoneFetcher) FetchMilestoneByNumber(ctx context.Context, _ int64) (*milestone.Milestone, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestBackfillTimeout(t *testing.T) {
+	// Don't run in parallel as the reconnect delay and backfill timeout are modified
+	defer func(delay, timeout time.Duration) {
+		reconnectDelay, backfillTimeout = delay, timeout
+	}(reconnectDelay, backfillTimeout)
+	reconnectDelay, backfillTimeout = 10*time.Millisecond, 50*time.Millisecond
+
+	server := newTestServer(t,
+		[]string{milestoneMessage("milestone-1", 1, 16)},
+		[]string{milestoneMessage("milestone-4", 49, 64)},
+	)
+
+	client, err := NewHeimdallWSClient(wsURL(server))
+	require.NoError(t, err)
+
+	client.SetMilestoneFetcher(stallingMilestoneFetcher{})
+
+	events := client.SubscribeMilestoneEvents(context.Background())
+
+	// The live events resume once the backfill gives up
+	var ids []string
+
+	for len(ids) < 2 {
+		select {
+		case m := <-events:
+			ids = append(ids, m.MilestoneID)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for milestone events, got %v", ids)
+		}
+	}
+
+	require.Equal(t, []string{"milestone-1", "milestone-4"}, ids)
+	require.NoError(t, client.Unsubscribe(context.Background()))
+}
+
+func TestDropOldestEvents(t *testing.T) {
+	// Don't run in parallel as the metrics are shared
+	dropped := droppedEventsCounter.Snapshot().Count()
+
+	var messages []string
+	for i := 1; i <= 5; i++ {
+		messages = append(messages, milestoneMessage(fmt.Sprintf("milestone-%d", i), uint64(i-1)*16+1, uint64(i)*16))
+	}
+
+	server, subscriptions := newCountingTestServer(t, messages)
+
+	client, err := NewHeimdallWSClient(wsURL(server))
+	require.NoError(t, err)
+
+	client.SetEventBuffer(2)
+
+	// Don't consume anything until the reader went through all the messages
+	events := client.SubscribeMilestoneEvents(context.Background())
+
+	require.Eventually(t, func() bool {
+		return droppedEventsCounter.Snapshot().Count() == dropped+3
+	}, 5*time.Second, 10*time.Millisecond, "oldest events not dropped")
+
+	// The newest events survive
+	for _, id := range []string{"milestone-4", "milestone-5"} {
+		select {
+		case m := <-events:
+			require.Equal(t, id, m.MilestoneID)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", id)
+		}
+	}
+
+	// The connection stayed up while the consumer was blocked
+	require.True(t, client.IsConnected())
+	require.Equal(t, int32(1), subscriptions.Load(), "connection was re-established")
+
+	require.NoError(t, client.Unsubscribe(context.Background()))
+}
@@ -1,6 +1,19 @@
 package heimdallws
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/milestone"
+)
+
+// errMilestoneEventNotFound is returned when an event message doesn't carry a milestone
+// in any of the known formats.
+var errMilestoneEventNotFound = errors.New("milestone event not found in message")
 
 // subscriptionRequest represents the JSON-RPC request for subscribing.
 type subscriptionRequest struct {
@@ -50,6 +63,9 @@ type wsData struct {
 type wsResult struct {
 	Query string `json:"query"`
 	Data  wsData `json:"data"`
+	// Events holds the flattened "<event type>.<attribute key>" => values map
+	// emitted alongside the data.
+	Events map[string][]string `json:"events"`
 }
 
 // wsResponse is the top-level response structure from the WS subscription.
@@ -57,5 +73,75 @@ type wsResponse struct {
 	JSONRPC string   `json:"jsonrpc"`
 	ID      int      `json:"id"`
 	Result  wsResult `json:"result"`
-	// "events" field is present but not needed here.
+}
+
+// attributes returns the attributes of the first event of the given type. It first
+// looks at the flattened events map and falls back to the events array of the
+// finalized block. It returns nil if no such event exists in either shape.
+func (r *wsResponse) attributes(eventType string) map[string]string {
+	prefix := eventType + "."
+
+	attrs := make(map[string]string)
+
+	for key, values := range r.Result.Events {
+		if !strings.HasPrefix(key, prefix) || len(values) == 0 {
+			continue
+		}
+
+		attrs[strings.TrimPrefix(key, prefix)] = values[0]
+	}
+
+	if len(attrs) > 0 {
+		return attrs
+	}
+
+	for _, event := range r.Result.Data.Value.FinalizeBlock.Events {
+		if event.Type != eventType {
+			continue
+		}
+
+		for _, attr := range event.Attributes {
+			attrs[attr.Key] = attr.Value
+		}
+
+		return attrs
+	}
+
+	return nil
+}
+
+// parseMilestone extracts the milestone from a WS message. It returns nil (and no error)
+// for messages which don't carry any events, like the subscription acknowledgement.
+func parseMilestone(message []byte) (*milestone.Milestone, error) {
+	var resp wsResponse
+	if err := json.Unmarshal(message, &resp); err != nil {
+		return nil, fmt.Errorf("unexpected message format: %w", err)
+	}
+
+	attrs := resp.attributes("milestone")
+	if attrs == nil {
+		if resp.Result.Query == "" {
+			return nil, nil
+		}
+
+		return nil, errMilestoneEventNotFound
+	}
+
+	m := &milestone.Milestone{
+		Proposer:    common.HexToAddress(attrs["proposer"]),
+		Hash:        common.HexToHash(attrs["hash"]),
+		BorChainID:  attrs["bor_chain_id"],
+		MilestoneID: attrs["milestone_id"],
+	}
+	if startBlock, err := strconv.ParseUint(attrs["start_block"], 10, 64); err == nil {
+		m.StartBlock = startBlock
+	}
+	if endBlock, err := strconv.ParseUint(attrs["end_block"], 10, 64); err == nil {
+		m.EndBlock = endBlock
+	}
+	if timestamp, err := strconv.ParseUint(attrs["timestamp"], 10, 64); err == nil {
+		m.Timestamp = timestamp
+	}
+
+	return m, nil
 }
@@ -0,0 +1,61 @@
+package heimdallws
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/milestone"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMilestone(t *testing.T) {
+	t.Parallel()
+
+	expected := &milestone.Milestone{
+		Proposer:    common.HexToAddress("0x6dc2dd54f24979ec26212794c71afefed722280c"),
+		StartBlock:  68093442,
+		EndBlock:    68093459,
+		Hash:        common.HexToHash("0x4cb5ea9e1ae2b5a36b3a1ee6e4f8c3d0fd3a6bc79f7f1c8ab53da6e0e3c5ad21"),
+		BorChainID:  "137",
+		MilestoneID: "7bb9d3b7-66a0-4c2f-a0a5-5b3cd8e0ae3c - 0x4cb5ea9e1ae2b5a36b3a1ee6e4f8c3d0fd3a6bc79f7f1c8ab53da6e0e3c5ad21",
+		Timestamp:   1739281736,
+	}
+
+	tests := []struct {
+		fixture  string
+		expected *milestone.Milestone
+		err      error
+	}{
+		{fixture: "milestone_v1.json", expected: expected},
+		{fixture: "milestone_v2.json", expected: expected},
+		{fixture: "subscription_ack.json", expected: nil},
+		{fixture: "new_block_without_milestone.json", err: errMilestoneEventNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.fixture, func(t *testing.T) {
+			t.Parallel()
+
+			message, err := os.ReadFile(filepath.Join("testdata", tc.fixture))
+			require.NoError(t, err)
+
+			m, err := parseMilestone(message)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, m)
+		})
+	}
+}
+
+func TestParseMilestoneInvalidMessage(t *testing.T) {
+	t.Parallel()
+
+	_, err := parseMilestone([]byte("not json"))
+	require.Error(t, err)
+}
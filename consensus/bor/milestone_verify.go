@@ -0,0 +1,107 @@
+package bor
+
+import (
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// MilestoneReader provides the latest milestone whitelisted by the node. The block
+// of the milestone and its ancestors are final.
+type MilestoneReader interface {
+	GetWhitelistedMilestone() (bool, uint64, common.Hash)
+}
+
+// SetMilestoneReader enables the verification fast-path of the headers attested by the
+// milestones whitelisted by the given reader. It must be set before verifying headers.
+func (c *Bor) SetMilestoneReader(r MilestoneReader) {
+	c.milestones = r
+}
+
+// milestoneAttested returns the number of leading headers of the batch attested by the
+// whitelisted milestone, i.e. at or below the milestone with hashes linking up to the
+// milestone hash. The link is followed through the batch and, if the batch ends below
+// the milestone, through the local chain. Any break of the link disables the fast-path
+// for the whole batch, leaving the offending headers to the full verification.
+func (c *Bor) milestoneAttested(chain consensus.ChainHeaderReader, headers []*types.Header) int {
+	if c.milestones == nil || len(headers) == 0 {
+		return 0
+	}
+
+	exists, number, hash := c.milestones.GetWhitelistedMilestone()
+	if !exists {
+		return 0
+	}
+
+	// Find the last header of the batch not above the milestone
+	last := -1
+
+	for i, header := range headers {
+		if header.Number == nil {
+			return 0
+		}
+
+		if header.Number.Uint64() > number {
+			break
+		}
+
+		last = i
+	}
+
+	if last < 0 {
+		return 0
+	}
+
+	// Walk the local chain down from the milestone to the batch
+	for lastNumber := headers[last].Number.Uint64(); number > lastNumber; {
+		header := chain.GetHeader(hash, number)
+		if header == nil {
+			return 0
+		}
+
+		number, hash = number-1, header.ParentHash
+	}
+
+	// Walk the batch down, every header must be the parent of the one above
+	for i := last; i >= 0; i-- {
+		if headers[i].Number.Uint64() != number || headers[i].Hash() != hash {
+			return 0
+		}
+
+		number, hash = number-1, headers[i].ParentHash
+	}
+
+	return last + 1
+}
+
+// verifyAttestedHeader checks a header attested by the whitelisted milestone. Its seal,
+// difficulty and validators were verified by the validators finalizing the milestone, so
+// only its linkage to the parent is checked. The snapshot is still carried over the
+// header, tracking its signer among the recent ones, for the headers above the milestone.
+func (c *Bor) verifyAttestedHeader(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header) error {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil
+	}
+
+	if err := validateHeaderExtraField(header.Extra); err != nil {
+		return err
+	}
+
+	var parent *types.Header
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else {
+		parent = chain.GetHeader(header.ParentHash, number-1)
+	}
+
+	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
+		return consensus.ErrUnknownAncestor
+	}
+
+	_, err := c.buildSnapshot(chain, number, header.Hash(), append(slices.Clip(parents), header), true)
+
+	return err
+}
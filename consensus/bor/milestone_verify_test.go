@@ -0,0 +1,303 @@
+package bor
+
+import (
+	"math/big"
+	"testing"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor/valset"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// staticMilestone whitelists a fixed milestone
+type staticMilestone struct {
+	number uint64
+	hash   common.Hash
+}
+
+func (m *staticMilestone) GetWhitelistedMilestone() (bool, uint64, common.Hash) {
+	return true, m.number, m.hash
+}
+
+// verifyTestChainReader serves the headers of a test chain along with its config
+type verifyTestChainReader struct {
+	*signedChainReader
+	config *params.ChainConfig
+}
+
+func (c *verifyTestChainReader) Config() *params.ChainConfig {
+	return c.config
+}
+
+// newVerifyTestChain creates a chain of the given length passing the full header
+// verification, except for the headers of the given numbers which are sealed by a
+// signer outside of the validator set.
+func newVerifyTestChain(t testing.TB, length uint64, rogue ...uint64) (*params.ChainConfig, ethdb.Database, []*types.Header, *verifyTestChainReader) {
+	t.Helper()
+
+	// A sprint longer than the chain avoids validator set changes
+	config := &params.ChainConfig{
+		ChainID: big.NewInt(1337),
+		Bor: &params.BorConfig{
+			Sprint: map[string]uint64{"0": 1 << 20},
+			Period: map[string]uint64{"0": 2},
+		},
+	}
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	rogueKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	var (
+		headers = make([]*types.Header, length+1)
+		chain   = &verifyTestChainReader{
+			signedChainReader: &signedChainReader{headers: make(map[common.Hash]*types.Header)},
+			config:            config,
+		}
+	)
+
+	for i := range headers {
+		header := &types.Header{
+			Number:     new(big.Int).SetUint64(uint64(i)),
+			Time:       uint64(2 * i),
+			Difficulty: big.NewInt(1),
+			GasLimit:   8_000_000,
+			UncleHash:  types.EmptyUncleHash,
+			Extra:      make([]byte, types.ExtraVanityLength+types.ExtraSealLength),
+		}
+		if i > 0 {
+			header.ParentHash = headers[i-1].Hash()
+		}
+
+		signer := key
+		for _, number := range rogue {
+			if number == uint64(i) {
+				signer = rogueKey
+			}
+		}
+
+		signSnapshotTestHeader(t, header, config.Bor, signer)
+
+		headers[i] = header
+		chain.headers[header.Hash()] = header
+	}
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := newSnapshot(config, nil, 0, headers[0].Hash(), []*valset.Validator{
+		valset.NewValidator(crypto.PubkeyToAddress(key.PublicKey), 10),
+	})
+	require.NoError(t, genesis.store(db))
+
+	return config, db, headers, chain
+}
+
+// verifyTestHeaders verifies a batch of headers, returning the result of each.
+func verifyTestHeaders(engine *Bor, chain *verifyTestChainReader, headers []*types.Header) []error {
+	_, results := engine.VerifyHeaders(chain, headers)
+
+	errs := make([]error, len(headers))
+	for i := range errs {
+		errs[i] = <-results
+	}
+
+	return errs
+}
+
+// Tests that the headers attested by the whitelisted milestone skip the seal
+// verification, while the headers above it are still fully verified.
+func TestVerifyHeadersMilestoneAttested(t *testing.T) {
+	t.Parallel()
+
+	// Blocks 10 and 35 are sealed by an unauthorized signer, the former is attested
+	config, db, headers, chain := newVerifyTestChain(t, 40, 10, 35)
+	milestone := &staticMilestone{number: 30, hash: headers[30].Hash()}
+
+	engine := New(config, db, nil, nil, nil, nil, nil, false)
+	errs := verifyTestHeaders(engine, chain, headers[1:])
+
+	var unauthorized *UnauthorizedSignerError
+	require.ErrorAs(t, errs[9], &unauthorized, "unauthorized signer accepted by the full verification")
+
+	engine = New(config, db, nil, nil, nil, nil, nil, false)
+	engine.SetMilestoneReader(milestone)
+
+	require.Equal(t, 30, engine.milestoneAttested(chain, headers[1:]))
+	require.Equal(t, 10, engine.milestoneAttested(chain, headers[1:11]), "attestation through the local chain")
+	require.Equal(t, 0, engine.milestoneAttested(chain, headers[31:]))
+
+	// Headers above the milestone are fully verified on top of the attested ones
+	errs = verifyTestHeaders(engine, chain, headers[1:])
+	for i, err := range errs[:34] {
+		require.NoError(t, err, "block %d", i+1)
+	}
+	require.ErrorAs(t, errs[34], &unauthorized, "unauthorized signer accepted above the milestone")
+}
+
+// Tests that syncing across the whitelisted milestone in several batches tracks the same
+// recent signers as the full verification.
+func TestVerifyHeadersMilestoneRecents(t *testing.T) {
+	t.Parallel()
+
+	config, db, headers, chain := newVerifyTestChain(t, 40)
+	milestone := &staticMilestone{number: 30, hash: headers[30].Hash()}
+
+	full := New(config, db, nil, nil, nil, nil, nil, false)
+	for i, err := range verifyTestHeaders(full, chain, headers[1:]) {
+		require.NoError(t, err, "block %d", i+1)
+	}
+
+	fast := New(config, db, nil, nil, nil, nil, nil, false)
+	fast.SetMilestoneReader(milestone)
+
+	// The first batch is attested as a whole, the second one crosses the milestone
+	require.Equal(t, 24, fast.milestoneAttested(chain, headers[1:25]))
+	require.Equal(t, 6, fast.milestoneAttested(chain, headers[25:]))
+
+	for _, batch := range [][]*types.Header{headers[1:25], headers[25:]} {
+		for _, err := range verifyTestHeaders(fast, chain, batch) {
+			require.NoError(t, err)
+		}
+	}
+
+	for _, number := range []uint64{24, 30, 40} {
+		want, err := full.snapshot(chain, number, headers[number].Hash(), nil)
+		require.NoError(t, err)
+
+		have, err := fast.snapshot(chain, number, headers[number].Hash(), nil)
+		require.NoError(t, err)
+
+		require.Len(t, have.Recents, int(number), "block %d", number)
+		require.Equal(t, want.Recents, have.Recents, "block %d", number)
+	}
+}
+
+// Tests that forged headers below the whitelisted milestone which don't link up to
+// the milestone hash are still rejected.
+func TestVerifyHeadersMilestoneForged(t *testing.T) {
+	t.Parallel()
+
+	config, db, headers, chain := newVerifyTestChain(t, 40)
+	milestone := &staticMilestone{number: 30, hash: headers[30].Hash()}
+
+	rogueKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	forged := types.CopyHeader(headers[20])
+	forged.Extra[0] = 0xff
+	signSnapshotTestHeader(t, forged, config.Bor, rogueKey)
+
+	var unauthorized *UnauthorizedSignerError
+
+	// Batch ending below the milestone, linked to it through the local chain
+	batch := append(append([]*types.Header{}, headers[1:20]...), forged)
+
+	engine := New(config, db, nil, nil, nil, nil, nil, false)
+	engine.SetMilestoneReader(milestone)
+
+	require.Equal(t, 0, engine.milestoneAttested(chain, batch))
+
+	errs := verifyTestHeaders(engine, chain, batch)
+	for i, err := range errs[:19] {
+		require.NoError(t, err, "block %d", i+1)
+	}
+	require.ErrorAs(t, errs[19], &unauthorized, "forged header accepted")
+
+	// Batch containing the milestone, with the forged header in the middle
+	batch = append(append(append([]*types.Header{}, headers[1:20]...), forged), headers[21:31]...)
+
+	engine = New(config, db, nil, nil, nil, nil, nil, false)
+	engine.SetMilestoneReader(milestone)
+
+	require.Equal(t, 0, engine.milestoneAttested(chain, batch))
+
+	errs = verifyTestHeaders(engine, chain, batch)
+	require.ErrorAs(t, errs[19], &unauthorized, "forged header accepted")
+
+	// Milestone of another chain
+	engine = New(config, db, nil, nil, nil, nil, nil, false)
+	engine.SetMilestoneReader(&staticMilestone{number: 20, hash: forged.Hash()})
+
+	require.Equal(t, 0, engine.milestoneAttested(chain, headers[1:31]))
+}
+
+// Tests that a header with an invalid signature is rejected with the signature recovery
+// error while the headers before it verify, with the signers recovered in parallel.
+func TestVerifyHeadersInvalidSignature(t *testing.T) {
+	t.Parallel()
+
+	const invalidAt = 40
+
+	config, db, headers, chain := newVerifyTestChain(t, 64)
+
+	invalid := types.CopyHeader(headers[invalidAt])
+	invalid.Extra[len(invalid.Extra)-1] = 5 // Out of range recovery id
+
+	batch := append(append(append([]*types.Header{}, headers[1:invalidAt]...), invalid), headers[invalidAt+1:]...)
+
+	signatures, _ := lru.NewARC(inmemorySignatures)
+	_, want := ecrecover(invalid, signatures, config.Bor)
+	require.Error(t, want)
+
+	engine := New(config, db, nil, nil, nil, nil, nil, false)
+
+	errs := verifyTestHeaders(engine, chain, batch)
+	for i, err := range errs[:invalidAt-1] {
+		require.NoError(t, err, "block %d", i+1)
+	}
+	require.Equal(t, want, errs[invalidAt-1])
+}
+
+func BenchmarkRecoverSigners(b *testing.B) {
+	config, _, headers, _ := newVerifyTestChain(b, 10_000)
+
+	b.Run("serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			signatures, _ := lru.NewARC(len(headers))
+			for _, header := range headers[1:] {
+				if _, err := ecrecover(header, signatures, config.Bor); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			signatures, _ := lru.NewARC(len(headers))
+			recoverSigners(headers[1:], signatures, config.Bor, nil)
+		}
+	})
+}
+
+func BenchmarkVerifyHeaders(b *testing.B) {
+	config, db, headers, chain := newVerifyTestChain(b, 2048)
+	milestone := &staticMilestone{number: 2048, hash: headers[2048].Hash()}
+
+	run := func(b *testing.B, milestones MilestoneReader) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			engine := New(config, db, nil, nil, nil, nil, nil, false)
+			if milestones != nil {
+				engine.SetMilestoneReader(milestones)
+			}
+			b.StartTimer()
+
+			for _, err := range verifyTestHeaders(engine, chain, headers[1:]) {
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+
+	b.Run("full", func(b *testing.B) { run(b, nil) })
+	b.Run("attested", func(b *testing.B) { run(b, milestone) })
+}
@@ -0,0 +1,175 @@
+package bor
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor/clerk"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	// prefetchHitCounter counts the sprint starts whose state sync events were served
+	// from the prefetched events.
+	prefetchHitCounter = metrics.NewRegisteredCounter("bor/prefetch/hit", nil)
+
+	// prefetchMissCounter counts the sprint starts whose prefetched state sync events
+	// didn't cover the event window of the block, which fetches them from heimdall again.
+	prefetchMissCounter = metrics.NewRegisteredCounter("bor/prefetch/miss", nil)
+
+	// prefetchSavedTimer measures the heimdall fetch time saved by the hits.
+	prefetchSavedTimer = metrics.NewRegisteredTimer("bor/prefetch/saved", nil)
+)
+
+// prefetchRecordDelay is how long an event recorded by heimdall is assumed to take to
+// be served by it. The prefetched events only cover the events recorded at least that
+// long before the prefetch.
+const prefetchRecordDelay = 2 * time.Second
+
+// stateSyncPrefetch holds the state sync events prefetched for a sprint start.
+type stateSyncPrefetch struct {
+	number    uint64                       // Sprint start the events were prefetched for
+	from      uint64                       // First state id of the events
+	until     int64                        // All the events recorded before are prefetched
+	events    []*clerk.EventRecordWithTime // Events sorted by state id
+	fetchTime time.Duration                // Time taken to fetch the events from heimdall
+}
+
+// PrefetchSprintStart fetches in the background the span and the state sync events
+// needed to build the sprint start following the given head, if it is at most lookahead
+// blocks away, so that building it doesn't wait on heimdall. The span is cached by the
+// span store and the events are staged until the sprint start is built. Nothing is done
+// if lookahead is zero or the sprint start was already prefetched.
+func (c *Bor) PrefetchSprintStart(head *types.Header, lookahead uint64) {
+	if lookahead == 0 || c.HeimdallClient == nil {
+		return
+	}
+
+	number := head.Number.Uint64() + 1
+	if sprint := c.config.CalculateSprint(number); !IsSprintStart(number, sprint) {
+		number += sprint - number%sprint
+	}
+
+	if number-head.Number.Uint64() > lookahead {
+		return
+	}
+
+	c.prefetchLock.Lock()
+	if c.prefetchNumber == number {
+		c.prefetchLock.Unlock()
+		return
+	}
+	c.prefetchNumber = number
+	c.prefetchLock.Unlock()
+
+	go c.prefetchSprintStart(head, number)
+}
+
+// prefetchSprintStart fetches the span and the state sync events of the given sprint
+// start, built on top of the given head.
+func (c *Bor) prefetchSprintStart(head *types.Header, number uint64) {
+	ctx := context.Background()
+
+	// Warm the span store with the span committed by the sprint start, if any
+	span, err := c.spanStore.spanByBlockNumber(ctx, number)
+	if err != nil {
+		log.Debug("Failed to prefetch span", "number", number, "err", err)
+	} else if c.needToCommitSpan(span, number) {
+		if _, err := c.spanStore.spanById(ctx, span.Id+1); err != nil {
+			log.Debug("Failed to prefetch span", "number", number, "spanID", span.Id+1, "err", err)
+		}
+	}
+
+	// Only the indore event window is known in advance, the earlier one depends on the
+	// time of the previous sprint start
+	if !c.config.IsIndore(new(big.Int).SetUint64(number)) {
+		return
+	}
+
+	lastStateID, err := c.GenesisContractsClient.LastStateId(nil, head.Number.Uint64(), head.Hash())
+	if err != nil {
+		log.Debug("Failed to prefetch state sync events", "number", number, "err", err)
+		c.resetPrefetch(number)
+
+		return
+	}
+
+	var (
+		from  = lastStateID.Uint64() + 1
+		until = c.now().Add(-prefetchRecordDelay).Unix()
+		start = time.Now()
+	)
+
+	events, err := c.HeimdallClient.StateSyncEvents(ctx, from, until)
+	if err != nil {
+		log.Debug("Failed to prefetch state sync events", "number", number, "fromID", from, "err", err)
+		c.resetPrefetch(number)
+
+		return
+	}
+
+	c.prefetchLock.Lock()
+	defer c.prefetchLock.Unlock()
+
+	c.prefetched = &stateSyncPrefetch{
+		number:    number,
+		from:      from,
+		until:     until,
+		events:    events,
+		fetchTime: time.Since(start),
+	}
+
+	log.Debug("Prefetched state sync events", "number", number, "fromID", from, "events", len(events), "elapsed", common.PrettyDuration(time.Since(start)))
+}
+
+// resetPrefetch allows the given sprint start to be prefetched again after a failure.
+func (c *Bor) resetPrefetch(number uint64) {
+	c.prefetchLock.Lock()
+	defer c.prefetchLock.Unlock()
+
+	if c.prefetchNumber == number {
+		c.prefetchNumber = 0
+	}
+}
+
+// prefetchedStateSyncEvents returns the prefetched state sync events of the given
+// sprint start recorded before to, if they were prefetched. The prefetched events are
+// only used if they start at the given state id, which changes if the chain was
+// reorged since, and cover the whole window of the sprint start.
+func (c *Bor) prefetchedStateSyncEvents(number uint64, from uint64, to int64) ([]*clerk.EventRecordWithTime, bool) {
+	if !c.config.IsIndore(new(big.Int).SetUint64(number)) {
+		return nil, false
+	}
+
+	c.prefetchLock.Lock()
+	prefetching, prefetched := c.prefetchNumber, c.prefetched
+	c.prefetchLock.Unlock()
+
+	// Sprint starts which weren't prefetched are neither hits nor misses
+	if prefetching != number && (prefetched == nil || prefetched.number != number) {
+		return nil, false
+	}
+
+	if prefetched == nil || prefetched.number != number || prefetched.from != from || prefetched.until < to {
+		prefetchMissCounter.Inc(1)
+		log.Debug("Prefetched state sync events unusable", "number", number, "fromID", from, "to", to)
+
+		return nil, false
+	}
+
+	events := make([]*clerk.EventRecordWithTime, 0, len(prefetched.events))
+	for _, event := range prefetched.events {
+		if event.Time.Before(time.Unix(to, 0)) {
+			events = append(events, event)
+		}
+	}
+
+	prefetchHitCounter.Inc(1)
+	prefetchSavedTimer.Update(prefetched.fetchTime)
+
+	return events, true
+}
@@ -0,0 +1,80 @@
+package bor
+
+import (
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// sealerNotInProducerSetGauge reports whether the local signer was missing from the
+// producers of the span of the last block it sealed (1) or not (0).
+var sealerNotInProducerSetGauge = metrics.NewRegisteredGauge("bor/sealer/not_in_producer_set", nil)
+
+// checkSealerProducer checks that the given signer is a producer of the span of the
+// given block, reporting it once per span if not. The producers of the neighbouring span
+// are accepted too during the first and the last sprint of a span, as the span rotation
+// may not be applied yet on chain. An error is only returned if strict sealing is enabled.
+// Only the cached spans are checked, so that sealing never waits for heimdall, nothing is
+// checked if the span isn't cached.
+func (c *Bor) checkSealerProducer(signer common.Address, number uint64) error {
+	if c.HeimdallClient == nil || c.DevFakeAuthor {
+		return nil
+	}
+
+	span := c.spanStore.cachedSpanByBlockNumber(number)
+	if span == nil {
+		log.Debug("Span not cached, not checking the sealer", "number", number)
+		return nil
+	}
+
+	if slices.Contains(spanProducers(span), signer) {
+		c.sealerNotInProducerSet().Update(0)
+		return nil
+	}
+
+	sprint := c.config.CalculateSprint(number)
+
+	neighbours := make([]uint64, 0, 2)
+	if span.Id > 0 && number < span.StartBlock+sprint {
+		neighbours = append(neighbours, span.Id-1)
+	}
+
+	if number+sprint > span.EndBlock {
+		neighbours = append(neighbours, span.Id+1)
+	}
+
+	for _, id := range neighbours {
+		neighbour := c.spanStore.cachedSpan(id)
+		if neighbour != nil && slices.Contains(spanProducers(neighbour), signer) {
+			log.Debug("Sealer is a producer of the neighbouring span", "number", number, "spanID", span.Id, "neighbourID", id)
+			c.sealerNotInProducerSet().Update(0)
+
+			return nil
+		}
+	}
+
+	c.sealerNotInProducerSet().Update(1)
+
+	if c.sealerReportedSpan.Swap(span.Id+1) != span.Id+1 {
+		log.Error("Local signer is not a producer of the current span, the sealed blocks will be rejected", "number", number,
+			"signer", signer, "spanID", span.Id, "producers", spanProducers(span), "strict", c.strictSealing)
+	}
+
+	if c.strictSealing {
+		return &SignerNotInProducerSetError{Number: number, SpanId: span.Id, Signer: signer}
+	}
+
+	return nil
+}
+
+// sealerNotInProducerSet returns the gauge reporting the sealer missing from the producers,
+// falling back to the registered one.
+func (c *Bor) sealerNotInProducerSet() *metrics.Gauge {
+	if c.sealerGauge == nil {
+		return sealerNotInProducerSetGauge
+	}
+
+	return c.sealerGauge
+}
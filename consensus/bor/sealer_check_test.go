@@ -0,0 +1,83 @@
+package bor
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func newSealerCheckTestEngine(strict bool) *Bor {
+	client := &MockHeimdallClientWithValidators{}
+
+	return &Bor{
+		chainConfig:    &params.ChainConfig{ChainID: big.NewInt(1337)},
+		config:         &params.BorConfig{Sprint: map[string]uint64{"0": 16}},
+		HeimdallClient: client,
+		spanStore:      NewSpanStore(client, nil, "1337", nil),
+		strictSealing:  strict,
+		sealerGauge:    metrics.NewGauge(),
+	}
+}
+
+func TestCheckSealerProducer(t *testing.T) {
+	t.Parallel()
+
+	var (
+		producer = mockValidatorAddress(2) // Producer of span 2, blocks 6656 to 13055
+		stranger = common.HexToAddress("0xdead")
+	)
+
+	for _, strict := range []bool{false, true} {
+		engine := newSealerCheckTestEngine(strict)
+
+		// Nothing is checked until the spans are cached
+		require.NoError(t, engine.checkSealerProducer(stranger, 7000))
+		require.Equal(t, int64(0), engine.sealerGauge.Snapshot().Value())
+
+		for id := uint64(1); id <= 3; id++ {
+			_, err := engine.spanStore.spanById(context.Background(), id)
+			require.NoError(t, err)
+		}
+
+		// A producer of the span seals
+		require.NoError(t, engine.checkSealerProducer(producer, 7000))
+		require.Equal(t, int64(0), engine.sealerGauge.Snapshot().Value())
+
+		// Another signer is reported once per span, and refused if sealing is strict
+		err := engine.checkSealerProducer(stranger, 7000)
+		require.Equal(t, int64(1), engine.sealerGauge.Snapshot().Value())
+		require.Equal(t, uint64(3), engine.sealerReportedSpan.Load())
+
+		if strict {
+			var notProducer *SignerNotInProducerSetError
+			require.True(t, errors.As(err, &notProducer), "unexpected error: %v", err)
+			require.Equal(t, &SignerNotInProducerSetError{Number: 7000, SpanId: 2, Signer: stranger}, notProducer)
+		} else {
+			require.NoError(t, err)
+		}
+
+		// The producers of the neighbouring spans seal around the span rotation
+		require.NoError(t, engine.checkSealerProducer(mockValidatorAddress(1), 6656+15))
+		require.NoError(t, engine.checkSealerProducer(mockValidatorAddress(3), 13055-15))
+		require.Equal(t, int64(0), engine.sealerGauge.Snapshot().Value())
+
+		// But not past the first and the last sprint of the span
+		err = engine.checkSealerProducer(mockValidatorAddress(1), 6656+16)
+		require.Equal(t, strict, err != nil, "unexpected error: %v", err)
+
+		err = engine.checkSealerProducer(mockValidatorAddress(3), 13055-16)
+		require.Equal(t, strict, err != nil, "unexpected error: %v", err)
+		require.Equal(t, int64(1), engine.sealerGauge.Snapshot().Value())
+	}
+
+	// Nothing is checked for the spans not cached, even if heimdall serves them
+	engine := newSealerCheckTestEngine(true)
+	require.NoError(t, engine.checkSealerProducer(stranger, 7000))
+	require.NoError(t, engine.checkSealerProducer(stranger, 99*6400+256+500)) // Span 100
+}
@@ -9,7 +9,6 @@ import (
 	lru "github.com/hashicorp/golang-lru"
 
 	"github.com/ethereum/go-ethereum/common"
-	borSpan "github.com/ethereum/go-ethereum/consensus/bor/heimdall/span"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
@@ -49,9 +48,17 @@ func newSnapshot(
 	return snap
 }
 
+// snapshotPrefix is the database key prefix of the persisted snapshots
+var snapshotPrefix = []byte("bor-")
+
+// snapshotKey = snapshotPrefix + hash
+func snapshotKey(hash common.Hash) []byte {
+	return append(append([]byte{}, snapshotPrefix...), hash[:]...)
+}
+
 // loadSnapshot loads an existing snapshot from the database.
-func loadSnapshot(chainConfig *params.ChainConfig, config *params.BorConfig, sigcache *lru.ARCCache, db ethdb.Database, hash common.Hash) (*Snapshot, error) {
-	blob, err := db.Get(append([]byte("bor-"), hash[:]...))
+func loadSnapshot(chainConfig *params.ChainConfig, config *params.BorConfig, sigcache *lru.ARCCache, db ethdb.KeyValueReader, hash common.Hash) (*Snapshot, error) {
+	blob, err := db.Get(snapshotKey(hash))
 	if err != nil {
 		return nil, err
 	}
@@ -76,13 +83,13 @@ func loadSnapshot(chainConfig *params.ChainConfig, config *params.BorConfig, sig
 }
 
 // store inserts the snapshot into the database.
-func (s *Snapshot) store(db ethdb.Database) error {
+func (s *Snapshot) store(db ethdb.KeyValueWriter) error {
 	blob, err := json.Marshal(s)
 	if err != nil {
 		return err
 	}
 
-	return db.Put(append([]byte("bor-"), s.Hash[:]...), blob)
+	return db.Put(snapshotKey(s.Hash), blob)
 }
 
 // copy creates a deep copy of the snapshot, though not the individual votes.
@@ -103,6 +110,13 @@ func (s *Snapshot) copy() *Snapshot {
 }
 
 func (s *Snapshot) apply(headers []*types.Header, c *Bor) (*Snapshot, error) {
+	return s.applyHeaders(headers, c, false)
+}
+
+// applyHeaders creates a new snapshot by applying the given headers to the original
+// one. The signers of attested headers (ancestors of the whitelisted milestone) are
+// tracked among the recent signers, but not checked against the validator set.
+func (s *Snapshot) applyHeaders(headers []*types.Header, c *Bor, attested bool) (*Snapshot, error) {
 	// Allow passing in no headers for cleaner code
 	if len(headers) == 0 {
 		return s, nil
@@ -135,13 +149,15 @@ func (s *Snapshot) apply(headers []*types.Header, c *Bor) (*Snapshot, error) {
 			return nil, err
 		}
 
-		// check if signer is in validator set
-		if !snap.ValidatorSet.HasAddress(signer) {
-			return nil, &UnauthorizedSignerError{number, signer.Bytes()}
-		}
+		if !attested {
+			// check if signer is in validator set
+			if !snap.ValidatorSet.HasAddress(signer) {
+				return nil, &UnauthorizedSignerError{number, signer.Bytes()}
+			}
 
-		if _, err = snap.GetSignerSuccessionNumber(signer); err != nil {
-			return nil, err
+			if _, err = snap.GetSignerSuccessionNumber(signer); err != nil {
+				return nil, err
+			}
 		}
 
 		// add recents
@@ -162,11 +178,11 @@ func (s *Snapshot) apply(headers []*types.Header, c *Bor) (*Snapshot, error) {
 
 			if v.CheckEmptyId() {
 				// Fetch the validator set from span
-				span, err := c.spanStore.spanByBlockNumber(context.Background(), number+1)
+				validators, err := c.spanStore.validatorsByBlockNumber(context.Background(), number+1)
 				if err != nil {
 					return nil, err
 				}
-				v.IncludeIds(borSpan.ConvertHeimdallValSetToBorValSet(span.ValidatorSet).Validators)
+				v.IncludeIds(validators.validatorSet.Validators)
 			}
 			snap.ValidatorSet = v
 		}
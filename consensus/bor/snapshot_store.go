@@ -0,0 +1,148 @@
+package bor
+
+import (
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// maxPendingSnapshots is the number of checkpoint snapshots kept pending before
+// they're flushed on their own, without waiting for a block write.
+const maxPendingSnapshots = 16
+
+// DefaultSnapshotRetention is the retention policy of the bor snapshot pruning
+// when none is given.
+var DefaultSnapshotRetention = SnapshotRetention{
+	Keep:   16,
+	Recent: 128 * checkpointInterval,
+}
+
+// SnapshotRetention is the retention policy of the validator snapshots persisted
+// every checkpointInterval blocks. All the snapshots of the recent blocks are kept,
+// while only every Keep-th snapshot is kept below them. Pruned snapshots are rebuilt
+// on demand by replaying the headers from the nearest retained ancestor.
+type SnapshotRetention struct {
+	Keep   uint64 // Keep every Keep-th snapshot below the recent blocks (0 or 1 keeps all)
+	Recent uint64 // Number of blocks below the head keeping all their snapshots
+}
+
+// Retain reports whether the snapshot at the given block is kept for the given head.
+// The genesis snapshot is always kept as it's the ancestor of all the others.
+func (r SnapshotRetention) Retain(number, head uint64) bool {
+	if number == 0 || r.Keep <= 1 || number+r.Recent >= head {
+		return true
+	}
+
+	return (number/checkpointInterval)%r.Keep == 0
+}
+
+// persistSnapshot schedules the snapshot to be written with the next block write
+// batch. Pending snapshots are flushed directly to the database if no block is
+// written for a while, e.g. while syncing headers.
+func (c *Bor) persistSnapshot(snap *Snapshot) error {
+	c.snapshotsLock.Lock()
+	defer c.snapshotsLock.Unlock()
+
+	c.pendingSnapshots = append(c.pendingSnapshots, snap)
+	if len(c.pendingSnapshots) < maxPendingSnapshots {
+		return nil
+	}
+
+	batch := c.db.NewBatch()
+	if err := c.flushSnapshots(batch); err != nil {
+		return err
+	}
+
+	return batch.Write()
+}
+
+// pendingSnapshot returns the pending snapshot of the given block, if any.
+func (c *Bor) pendingSnapshot(hash common.Hash) *Snapshot {
+	c.snapshotsLock.Lock()
+	defer c.snapshotsLock.Unlock()
+
+	for _, snap := range c.pendingSnapshots {
+		if snap.Hash == hash {
+			return snap
+		}
+	}
+
+	return nil
+}
+
+// FlushSnapshots writes the pending checkpoint snapshots into the given batch, so
+// they're persisted atomically with the block being written.
+func (c *Bor) FlushSnapshots(db ethdb.KeyValueWriter) error {
+	c.snapshotsLock.Lock()
+	defer c.snapshotsLock.Unlock()
+
+	return c.flushSnapshots(db)
+}
+
+// flushSnapshots writes the pending snapshots, the caller must hold snapshotsLock.
+func (c *Bor) flushSnapshots(db ethdb.KeyValueWriter) error {
+	for _, snap := range c.pendingSnapshots {
+		if err := snap.store(db); err != nil {
+			return err
+		}
+
+		log.Trace("Stored snapshot to disk", "number", snap.Number, "hash", snap.Hash)
+	}
+
+	c.pendingSnapshots = nil
+
+	return nil
+}
+
+// PruneSnapshots deletes the persisted validator snapshots not retained by the
+// given policy for the given head, and returns the number of deleted snapshots.
+func PruneSnapshots(db ethdb.Database, head uint64, retention SnapshotRetention) (int, error) {
+	it := db.NewIterator(snapshotPrefix, nil)
+	defer it.Release()
+
+	var (
+		batch  = db.NewBatch()
+		pruned int
+	)
+
+	for it.Next() {
+		if len(it.Key()) != len(snapshotPrefix)+common.HashLength {
+			continue
+		}
+
+		var snap struct {
+			Number uint64 `json:"number"`
+		}
+
+		if err := json.Unmarshal(it.Value(), &snap); err != nil {
+			log.Warn("Skipping undecodable bor snapshot", "key", common.Bytes2Hex(it.Key()), "err", err)
+			continue
+		}
+
+		if retention.Retain(snap.Number, head) {
+			continue
+		}
+
+		if err := batch.Delete(it.Key()); err != nil {
+			return pruned, err
+		}
+
+		pruned++
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return pruned, err
+			}
+
+			batch.Reset()
+		}
+	}
+
+	if err := it.Error(); err != nil {
+		return pruned, err
+	}
+
+	return pruned, batch.Write()
+}
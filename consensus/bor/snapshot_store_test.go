@@ -0,0 +1,188 @@
+package bor
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bor/valset"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// signedChainReader is a chain reader serving a chain of headers by hash
+type signedChainReader struct {
+	consensus.ChainHeaderReader
+	headers map[common.Hash]*types.Header
+}
+
+func (c *signedChainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.headers[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+
+	return nil
+}
+
+// newSnapshotTestChain creates a chain of the given length signed by a single
+// validator, along with the database holding its genesis snapshot.
+func newSnapshotTestChain(t *testing.T, length uint64) (*params.ChainConfig, ethdb.Database, []*types.Header, *signedChainReader) {
+	t.Helper()
+
+	// A sprint longer than the chain avoids validator set changes
+	config := &params.ChainConfig{
+		ChainID: big.NewInt(1337),
+		Bor: &params.BorConfig{
+			Sprint: map[string]uint64{"0": 1 << 20},
+			Period: map[string]uint64{"0": 2},
+		},
+	}
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	var (
+		headers = make([]*types.Header, length+1)
+		chain   = &signedChainReader{headers: make(map[common.Hash]*types.Header)}
+	)
+
+	for i := range headers {
+		header := &types.Header{
+			Number: new(big.Int).SetUint64(uint64(i)),
+			Time:   uint64(2 * i),
+			Extra:  make([]byte, types.ExtraVanityLength+types.ExtraSealLength),
+		}
+		if i > 0 {
+			header.ParentHash = headers[i-1].Hash()
+		}
+
+		signSnapshotTestHeader(t, header, config.Bor, key)
+
+		headers[i] = header
+		chain.headers[header.Hash()] = header
+	}
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := newSnapshot(config, nil, 0, headers[0].Hash(), []*valset.Validator{
+		valset.NewValidator(crypto.PubkeyToAddress(key.PublicKey), 10),
+	})
+	require.NoError(t, genesis.store(db))
+
+	return config, db, headers, chain
+}
+
+func signSnapshotTestHeader(t testing.TB, header *types.Header, config *params.BorConfig, key *ecdsa.PrivateKey) {
+	t.Helper()
+
+	sig, err := crypto.Sign(SealHash(header, config).Bytes(), key)
+	require.NoError(t, err)
+
+	copy(header.Extra[len(header.Extra)-types.ExtraSealLength:], sig)
+}
+
+func requireSameSnapshot(t *testing.T, want, have *Snapshot) {
+	t.Helper()
+
+	wantBlob, err := json.Marshal(want)
+	require.NoError(t, err)
+
+	haveBlob, err := json.Marshal(have)
+	require.NoError(t, err)
+
+	require.JSONEq(t, string(wantBlob), string(haveBlob))
+}
+
+func TestSnapshotRetention(t *testing.T) {
+	t.Parallel()
+
+	retention := SnapshotRetention{Keep: 4, Recent: 2 * checkpointInterval}
+	head := uint64(20 * checkpointInterval)
+
+	require.True(t, retention.Retain(0, head), "genesis")
+	require.True(t, retention.Retain(4*checkpointInterval, head), "every 4th")
+	require.False(t, retention.Retain(5*checkpointInterval, head), "old")
+	require.True(t, retention.Retain(19*checkpointInterval, head), "recent")
+	require.True(t, retention.Retain(18*checkpointInterval, head), "recent boundary")
+	require.False(t, retention.Retain(17*checkpointInterval, head), "below recent")
+
+	require.True(t, SnapshotRetention{Keep: 1}.Retain(5*checkpointInterval, head), "keep all")
+}
+
+// Tests that checkpoint snapshots are persisted with the block batch, and that
+// pruned snapshots are rebuilt from the nearest retained ancestor.
+func TestSnapshotPersistAndPrune(t *testing.T) {
+	t.Parallel()
+
+	length := uint64(4*checkpointInterval + 10)
+	config, db, headers, chain := newSnapshotTestChain(t, length)
+
+	engine := New(config, db, nil, nil, nil, nil, nil, false)
+
+	// Build the checkpoint snapshots in order, as a syncing node would
+	snaps := make(map[uint64]*Snapshot)
+
+	for number := uint64(checkpointInterval); number <= length; number += checkpointInterval {
+		snap, err := engine.snapshot(chain, number, headers[number].Hash(), nil)
+		require.NoError(t, err)
+
+		snaps[number] = snap
+	}
+
+	// Checkpoint snapshots are pending until the next block batch is written
+	for number := range snaps {
+		has, err := db.Has(snapshotKey(headers[number].Hash()))
+		require.NoError(t, err)
+		require.False(t, has, "snapshot %d persisted before the block batch", number)
+	}
+
+	batch := db.NewBatch()
+	require.NoError(t, engine.FlushSnapshots(batch))
+	require.NoError(t, batch.Write())
+
+	for number := range snaps {
+		has, err := db.Has(snapshotKey(headers[number].Hash()))
+		require.NoError(t, err)
+		require.True(t, has, "snapshot %d not persisted with the block batch", number)
+	}
+
+	// Keep every 2nd snapshot below the last checkpoint interval
+	pruned, err := PruneSnapshots(db, length, SnapshotRetention{Keep: 2, Recent: checkpointInterval})
+	require.NoError(t, err)
+	require.Equal(t, 2, pruned)
+
+	for number, retained := range map[uint64]bool{
+		0:                      true,
+		checkpointInterval:     false,
+		2 * checkpointInterval: true,
+		3 * checkpointInterval: false,
+		4 * checkpointInterval: true,
+	} {
+		has, err := db.Has(snapshotKey(headers[number].Hash()))
+		require.NoError(t, err)
+		require.Equal(t, retained, has, "snapshot %d", number)
+	}
+
+	// A fresh engine rebuilds the pruned snapshots by replaying the headers
+	engine = New(config, db, nil, nil, nil, nil, nil, false)
+
+	for _, number := range []uint64{checkpointInterval, 3*checkpointInterval + 5, 3 * checkpointInterval} {
+		snap, err := engine.snapshot(chain, number, headers[number].Hash(), nil)
+		require.NoError(t, err)
+
+		want, ok := snaps[number]
+		if !ok {
+			want, err = snaps[3*checkpointInterval].apply(headers[3*checkpointInterval+1:number+1], engine)
+			require.NoError(t, err)
+		}
+
+		requireSameSnapshot(t, want, snap)
+	}
+}
@@ -20,3 +20,9 @@ type Spanner interface {
 	GetCurrentValidatorsByBlockNrOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, blockNumber uint64) ([]*valset.Validator, error)
 	CommitSpan(ctx context.Context, minimalSpan borTypes.Span, validators, producers []stakeTypes.MinimalVal, state vm.StateDB, header *types.Header, chainContext core.ChainContext) error
 }
+
+// SpanStateReader is implemented by the spanners able to read the current span from the
+// state of the block being processed, rather than from the state of a stored block.
+type SpanStateReader interface {
+	GetCurrentSpanFromState(state vm.StateDB, header *types.Header, chainContext core.ChainContext) (*borTypes.Span, error)
+}
@@ -0,0 +1,141 @@
+package bor
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// spanCache is a size bounded cache keyed by span id, optimised for concurrent reads.
+// Spans change rarely while every verified header looks them up, so readers load an
+// immutable view of the entries without locking and writers replace the view with
+// an updated copy. When full, the least recently used entry is evicted, so that the
+// spans looked up by every header stay cached while older ones are fetched.
+type spanCache[V any] struct {
+	view  atomic.Pointer[spanCacheView[V]]
+	clock atomic.Uint64 // Logical time of the latest use of an entry
+	size  int
+	lock  sync.Mutex // Serialises writers
+}
+
+// spanCacheView is an immutable set of cached entries. The entries are shared between
+// the successive views, so that their recency survives the updates of the cache.
+type spanCacheView[V any] struct {
+	entries map[uint64]*spanCacheEntry[V]
+}
+
+// spanCacheEntry is a cached value along with the logical time of its latest use.
+type spanCacheEntry[V any] struct {
+	value V
+	used  atomic.Uint64
+}
+
+// newSpanCache creates a span cache holding up to size entries.
+func newSpanCache[V any](size int) *spanCache[V] {
+	c := &spanCache[V]{size: size}
+	c.view.Store(&spanCacheView[V]{entries: make(map[uint64]*spanCacheEntry[V])})
+
+	return c
+}
+
+// Get returns the cached value of the given key, marking it as the most recently used.
+func (c *spanCache[V]) Get(key uint64) (V, bool) {
+	entry, ok := c.view.Load().entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	entry.used.Store(c.clock.Add(1))
+
+	return entry.value, true
+}
+
+// Peek returns the cached value of the given key, without updating its recency.
+func (c *spanCache[V]) Peek(key uint64) (V, bool) {
+	entry, ok := c.view.Load().entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	return entry.value, true
+}
+
+// Contains reports whether the given key is cached, without updating its recency.
+func (c *spanCache[V]) Contains(key uint64) bool {
+	_, ok := c.view.Load().entries[key]
+	return ok
+}
+
+// Keys returns the cached keys from the least to the most recently used.
+func (c *spanCache[V]) Keys() []uint64 {
+	entries := c.view.Load().entries
+
+	keys := slices.Collect(maps.Keys(entries))
+	slices.SortFunc(keys, func(a, b uint64) int {
+		return cmp.Compare(entries[a].used.Load(), entries[b].used.Load())
+	})
+
+	return keys
+}
+
+// Len returns the number of cached entries.
+func (c *spanCache[V]) Len() int {
+	return len(c.view.Load().entries)
+}
+
+// Add inserts or replaces the value of the given key, marking it as the most recently
+// used, and evicts the least recently used entries beyond the size of the cache. The
+// update is visible to all the readers once Add returns.
+func (c *spanCache[V]) Add(key uint64, value V) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry := &spanCacheEntry[V]{value: value}
+	entry.used.Store(c.clock.Add(1))
+
+	next := &spanCacheView[V]{entries: maps.Clone(c.view.Load().entries)}
+	next.entries[key] = entry
+
+	for len(next.entries) > c.size {
+		var (
+			oldest uint64
+			used   uint64
+			found  bool
+		)
+
+		for k, e := range next.entries {
+			if u := e.used.Load(); !found || u < used {
+				oldest, used, found = k, u, true
+			}
+		}
+
+		delete(next.entries, oldest)
+	}
+
+	c.view.Store(next)
+}
+
+// Remove drops the value of the given key, if the given function reports it as the one
+// to drop. The update is visible to all the readers once Remove returns.
+func (c *spanCache[V]) Remove(key uint64, match func(V) bool) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	current := c.view.Load()
+
+	entry, ok := current.entries[key]
+	if !ok || !match(entry.value) {
+		return false
+	}
+
+	next := &spanCacheView[V]{entries: maps.Clone(current.entries)}
+	delete(next.entries, key)
+
+	c.view.Store(next)
+
+	return true
+}
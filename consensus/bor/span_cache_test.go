@@ -0,0 +1,133 @@
+package bor
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanCache(t *testing.T) {
+	t.Parallel()
+
+	cache := newSpanCache[string](3)
+
+	for _, key := range []uint64{1, 2, 3} {
+		cache.Add(key, "old")
+	}
+
+	// Replacing an entry makes it the most recently used
+	cache.Add(1, "new")
+	require.Equal(t, []uint64{2, 3, 1}, cache.Keys())
+
+	value, ok := cache.Get(1)
+	require.True(t, ok)
+	require.Equal(t, "new", value)
+
+	// The least recently used entry is evicted
+	cache.Add(4, "old")
+	require.Equal(t, 3, cache.Len())
+	require.False(t, cache.Contains(2))
+	require.Equal(t, []uint64{3, 1, 4}, cache.Keys())
+
+	// Entries are only removed if they are the expected ones
+	isOld := func(value string) bool { return value == "old" }
+
+	require.False(t, cache.Remove(1, isOld))
+	require.False(t, cache.Remove(2, isOld))
+	require.True(t, cache.Remove(3, isOld))
+	require.Equal(t, []uint64{1, 4}, cache.Keys())
+	require.False(t, cache.Contains(3))
+}
+
+// Tests that the spans looked up by every header stay cached while the older spans are
+// fetched again, e.g. by the validators of a past span.
+func TestSpanCacheHotSpan(t *testing.T) {
+	t.Parallel()
+
+	cache := newSpanCache[string](3)
+
+	// The current span is cached first, then looked up while past spans are fetched
+	cache.Add(10, "current")
+
+	for _, key := range []uint64{1, 2, 3, 4, 5} {
+		_, ok := cache.Get(10)
+		require.True(t, ok, "current span evicted before adding span %d", key)
+
+		cache.Add(key, "past")
+	}
+
+	require.True(t, cache.Contains(10))
+	require.Equal(t, []uint64{4, 10, 5}, cache.Keys())
+
+	// Peeking at an entry doesn't keep it cached
+	cache.Peek(4)
+	cache.Add(6, "past")
+	require.False(t, cache.Contains(4))
+	require.Equal(t, []uint64{10, 5, 6}, cache.Keys())
+}
+
+// Tests that entries added to the cache are visible to the readers running
+// concurrently with the writers, and to all the subsequent ones.
+func TestSpanCacheConcurrentUpdates(t *testing.T) {
+	t.Parallel()
+
+	const (
+		writers = 4
+		readers = 32
+		updates = 1000
+	)
+
+	cache := newSpanCache[uint64](writers)
+
+	var wg sync.WaitGroup
+
+	for w := uint64(0); w < writers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := uint64(1); i <= updates; i++ {
+				cache.Add(w, i)
+
+				// A writer always sees its own latest update
+				if value, _ := cache.Get(w); value != i {
+					t.Errorf("writer %d: have %d, want %d", w, value, i)
+					return
+				}
+			}
+		}()
+	}
+
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// Values only move forward for the readers
+			last := make([]uint64, writers)
+
+			for i := 0; i < updates; i++ {
+				for w := uint64(0); w < writers; w++ {
+					value, _ := cache.Get(w)
+					if value < last[w] {
+						t.Errorf("reader saw key %d go back from %d to %d", w, last[w], value)
+						return
+					}
+
+					last[w] = value
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for w := uint64(0); w < writers; w++ {
+		value, ok := cache.Get(w)
+		require.True(t, ok)
+		require.Equal(t, uint64(updates), value)
+	}
+}
@@ -0,0 +1,253 @@
+package bor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	borTypes "github.com/0xPolygon/heimdall-v2/x/bor/types"
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+)
+
+// spanOverrideRecheckInterval is how often heimdall is asked for the real span of an
+// overridden span id, while the override is in use.
+const spanOverrideRecheckInterval = 30 * time.Second
+
+// spanOverrideGauge reports the number of spans currently overridden.
+var spanOverrideGauge = metrics.NewRegisteredGauge("bor/span/overrides", nil)
+
+// errSpanOverrideSignature is returned when a span override isn't signed by the trusted key.
+var errSpanOverrideSignature = errors.New("span override not signed by the trusted key")
+
+// spanOverrideFile is the format of a span override file. Spans is the list of the
+// overriding spans, in the JSON format served by heimdall, and Signature is the 65 byte
+// [R || S || V] signature of the keccak256 hash of the spans, as they appear in the file,
+// by the trusted key.
+type spanOverrideFile struct {
+	Spans     json.RawMessage `json:"spans"`
+	Signature hexutil.Bytes   `json:"signature"`
+}
+
+// parseSpanOverride decodes the spans of a span override file, checking that they are
+// signed by the trusted key and belong to the given chain.
+func parseSpanOverride(data []byte, trustedKey *ecdsa.PublicKey, chainId string) ([]*borTypes.Span, error) {
+	var file spanOverrideFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid span override: %w", err)
+	}
+
+	signer, err := crypto.SigToPub(crypto.Keccak256(file.Spans), file.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errSpanOverrideSignature, err)
+	}
+
+	if crypto.PubkeyToAddress(*signer) != crypto.PubkeyToAddress(*trustedKey) {
+		return nil, fmt.Errorf("%w: signed by %s", errSpanOverrideSignature, crypto.PubkeyToAddress(*signer))
+	}
+
+	var encoded []json.RawMessage
+	if err := json.Unmarshal(file.Spans, &encoded); err != nil {
+		return nil, fmt.Errorf("invalid span override spans: %w", err)
+	}
+
+	interfaceRegistry := codectypes.NewInterfaceRegistry()
+	cryptocodec.RegisterInterfaces(interfaceRegistry)
+	cdc := codec.NewProtoCodec(interfaceRegistry)
+
+	spans := make([]*borTypes.Span, 0, len(encoded))
+	seen := make(map[uint64]struct{}, len(encoded))
+
+	for i, enc := range encoded {
+		span := new(borTypes.Span)
+		if err := cdc.UnmarshalJSON(enc, span); err != nil {
+			return nil, fmt.Errorf("invalid span override span %d: %w", i, err)
+		}
+
+		switch {
+		case span.BorChainId != chainId:
+			return nil, fmt.Errorf("span override span %d is for chain %s, not %s", span.Id, span.BorChainId, chainId)
+		case span.StartBlock > span.EndBlock:
+			return nil, fmt.Errorf("span override span %d ends at block %d before its start %d", span.Id, span.EndBlock, span.StartBlock)
+		case len(span.SelectedProducers) == 0:
+			return nil, fmt.Errorf("span override span %d has no producers", span.Id)
+		}
+
+		if _, ok := seen[span.Id]; ok {
+			return nil, fmt.Errorf("span override span %d overridden twice", span.Id)
+		}
+		seen[span.Id] = struct{}{}
+
+		spans = append(spans, span)
+	}
+
+	return spans, nil
+}
+
+// parseTrustedKey decodes a hex encoded public key, either compressed or not.
+func parseTrustedKey(key string) (*ecdsa.PublicKey, error) {
+	raw, err := hexutil.Decode(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) == 33 {
+		return crypto.DecompressPubkey(raw)
+	}
+
+	return crypto.UnmarshalPubkey(raw)
+}
+
+// spanOverrides holds the spans overriding the ones of heimdall, keyed by span id.
+type spanOverrides struct {
+	spans   map[uint64]*borTypes.Span
+	checked map[uint64]time.Time // Last time heimdall was asked for the real span
+	lock    sync.Mutex
+}
+
+// set replaces the overriding spans.
+func (o *spanOverrides) set(spans []*borTypes.Span) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.spans = make(map[uint64]*borTypes.Span, len(spans))
+	o.checked = make(map[uint64]time.Time, len(spans))
+
+	for _, span := range spans {
+		o.spans[span.Id] = span
+	}
+
+	spanOverrideGauge.Update(int64(len(o.spans)))
+}
+
+// get returns the span overriding the given span id, if any.
+func (o *spanOverrides) get(id uint64) *borTypes.Span {
+	if o == nil {
+		return nil
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	return o.spans[id]
+}
+
+// due reports whether heimdall should be asked again for the real span of the given
+// overridden span id, marking it as asked if so.
+func (o *spanOverrides) due(id uint64, now time.Time) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if now.Sub(o.checked[id]) < spanOverrideRecheckInterval {
+		return false
+	}
+
+	o.checked[id] = now
+
+	return true
+}
+
+// purge drops the override of the given span served by heimdall, if it has the same id
+// and block range, reporting whether it was dropped.
+func (o *spanOverrides) purge(span *borTypes.Span) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	override := o.spans[span.Id]
+	if override == nil || override.StartBlock != span.StartBlock || override.EndBlock != span.EndBlock {
+		return false
+	}
+
+	delete(o.spans, span.Id)
+	delete(o.checked, span.Id)
+
+	spanOverrideGauge.Update(int64(len(o.spans)))
+
+	return true
+}
+
+// setSpanOverrides makes the given spans take precedence over the ones served by heimdall
+// until heimdall serves them again.
+func (s *SpanStore) setSpanOverrides(spans []*borTypes.Span) {
+	s.overrides.set(spans)
+
+	for _, span := range spans {
+		s.updateLatestKnownSpanId(span.Id)
+	}
+}
+
+// checkSpanOverride asks heimdall for the real span of an overridden span id, dropping
+// the override if heimdall serves a span with the same block range, and reports whether
+// it was dropped.
+func (s *SpanStore) checkSpanOverride(ctx context.Context, spanId uint64) bool {
+	ctx, cancel := context.WithTimeout(ctx, spanOverrideRecheckInterval)
+	defer cancel()
+
+	span, err := s.heimdallClient.GetSpan(ctx, spanId)
+	if err != nil || span == nil {
+		log.Debug("Overridden span still unavailable in heimdall", "id", spanId, "err", err)
+		return false
+	}
+
+	if !s.overrides.purge(span) {
+		override := s.overrides.get(spanId)
+		if override != nil {
+			log.Warn("Heimdall serves a different span than the override, keeping the override", "id", spanId,
+				"start", span.StartBlock, "end", span.EndBlock, "overrideStart", override.StartBlock, "overrideEnd", override.EndBlock)
+		}
+
+		return false
+	}
+
+	log.Warn("Heimdall serves the overridden span again, dropping the override", "id", spanId, "start", span.StartBlock, "end", span.EndBlock)
+
+	s.detectConflicts(span)
+	s.store.Add(spanId, span)
+
+	return true
+}
+
+// LoadSpanOverride loads a span override file, making its spans take precedence over
+// the ones of heimdall. It is an emergency mechanism to rotate the producers while
+// heimdall is down, so the file must be signed by the given trusted public key. Every
+// override is dropped once heimdall serves the span with the same id and block range.
+func (c *Bor) LoadSpanOverride(path string, trustedKey string) error {
+	if trustedKey == "" {
+		return errors.New("span override requires a trusted key")
+	}
+
+	key, err := parseTrustedKey(trustedKey)
+	if err != nil {
+		return fmt.Errorf("invalid span override trusted key: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	spans, err := parseSpanOverride(data, key, c.chainConfig.ChainID.String())
+	if err != nil {
+		return err
+	}
+
+	c.spanStore.setSpanOverrides(spans)
+
+	for _, span := range spans {
+		log.Error("Overriding heimdall span from the emergency span override", "path", path, "id", span.Id,
+			"start", span.StartBlock, "end", span.EndBlock, "producers", spanProducers(span))
+	}
+
+	return nil
+}
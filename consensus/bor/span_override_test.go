@@ -0,0 +1,199 @@
+package bor
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xPolygon/heimdall-v2/x/bor/types"
+	stakeTypes "github.com/0xPolygon/heimdall-v2/x/stake/types"
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+// overrideTestSpan returns a span overriding the span of the given id served by
+// MockHeimdallClientWithValidators, with the same range but another producer.
+func overrideTestSpan(id uint64) *types.Span {
+	validators := mockValidatorSet(id + 100)
+
+	return &types.Span{
+		Id:                id,
+		StartBlock:        6400*(id-1) + 256,
+		EndBlock:          6400*id + 255,
+		ValidatorSet:      validators,
+		SelectedProducers: []stakeTypes.Validator{*validators.Validators[0]},
+		BorChainId:        "1337",
+	}
+}
+
+// writeSpanOverride writes a span override file of the given spans signed by the given
+// key, returning its path.
+func writeSpanOverride(t *testing.T, key *ecdsa.PrivateKey, spans ...*types.Span) string {
+	t.Helper()
+
+	cdc := codec.NewProtoCodec(codectypes.NewInterfaceRegistry())
+
+	encoded := make([]string, len(spans))
+	for i, span := range spans {
+		enc, err := cdc.MarshalJSON(span)
+		require.NoError(t, err)
+
+		encoded[i] = string(enc)
+	}
+
+	raw := "[" + strings.Join(encoded, ",") + "]"
+
+	sig, err := crypto.Sign(crypto.Keccak256([]byte(raw)), key)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "spanoverride.json")
+	data := `{"spans": ` + raw + `, "signature": "` + hexutil.Encode(sig) + `"}`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+
+	return path
+}
+
+func newSpanOverrideTestEngine(client IHeimdallClient) *Bor {
+	return &Bor{
+		chainConfig: &params.ChainConfig{ChainID: big.NewInt(1337)},
+		spanStore:   NewSpanStore(client, nil, "1337", nil),
+	}
+}
+
+func TestSpanOverride_Load(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	path := writeSpanOverride(t, key, overrideTestSpan(2), overrideTestSpan(3))
+
+	// The trusted key is accepted both uncompressed and compressed
+	for _, trustedKey := range []string{
+		hexutil.Encode(crypto.FromECDSAPub(&key.PublicKey)),
+		hexutil.Encode(crypto.CompressPubkey(&key.PublicKey)),
+	} {
+		engine := newSpanOverrideTestEngine(&MockHeimdallClientWithValidators{})
+		require.NoError(t, engine.LoadSpanOverride(path, trustedKey))
+
+		for _, id := range []uint64{2, 3} {
+			override := engine.spanStore.overrides.get(id)
+			require.NotNil(t, override, "span %d not overridden", id)
+			require.Equal(t, spanProducers(overrideTestSpan(id)), spanProducers(override), "invalid producers of span %d", id)
+		}
+		require.Nil(t, engine.spanStore.overrides.get(1), "unexpected override of span 1")
+		require.Equal(t, uint64(3), engine.spanStore.latestKnownSpanId.Load(), "latest known span not raised")
+	}
+
+	// The override is disabled without a trusted key
+	engine := newSpanOverrideTestEngine(&MockHeimdallClientWithValidators{})
+	require.Error(t, engine.LoadSpanOverride(path, ""))
+	require.Nil(t, engine.spanStore.overrides.get(2), "override loaded without trusted key")
+}
+
+func TestSpanOverride_Signature(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	trustedKey := hexutil.Encode(crypto.FromECDSAPub(&key.PublicKey))
+
+	engine := newSpanOverrideTestEngine(&MockHeimdallClientWithValidators{})
+
+	// Signed by another key
+	err := engine.LoadSpanOverride(writeSpanOverride(t, other, overrideTestSpan(2)), trustedKey)
+	require.ErrorIs(t, err, errSpanOverrideSignature)
+
+	// Spans tampered with after signing
+	path := writeSpanOverride(t, key, overrideTestSpan(2))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(string(data), `"spans": [`, `"spans": [ `, 1)), 0600))
+
+	err = engine.LoadSpanOverride(path, trustedKey)
+	require.ErrorIs(t, err, errSpanOverrideSignature)
+
+	// Signed spans of another chain
+	span := overrideTestSpan(2)
+	span.BorChainId = "80002"
+
+	err = engine.LoadSpanOverride(writeSpanOverride(t, key, span), trustedKey)
+	require.ErrorContains(t, err, "chain")
+
+	require.Nil(t, engine.spanStore.overrides.get(2), "rejected override loaded")
+}
+
+func TestSpanOverride_Precedence(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	engine := newSpanOverrideTestEngine(&MockHeimdallClientWithValidators{})
+	spanStore := engine.spanStore
+	ctx := t.Context()
+
+	// Heimdall serves span 2 until the override is loaded
+	span, err := spanStore.spanById(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, []common.Address{mockValidatorAddress(2)}, spanProducers(span), "invalid producers of heimdall span")
+
+	path := writeSpanOverride(t, key, overrideTestSpan(2))
+	require.NoError(t, engine.LoadSpanOverride(path, hexutil.Encode(crypto.FromECDSAPub(&key.PublicKey))))
+
+	// Pretend heimdall was just asked for the real span, so it isn't in the background
+	require.True(t, spanStore.overrides.due(2, time.Now()))
+
+	span, err = spanStore.spanById(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, spanProducers(overrideTestSpan(2)), spanProducers(span), "override not preferred over cached span")
+
+	span, err = spanStore.spanByBlockNumber(ctx, 7000)
+	require.NoError(t, err)
+	require.Equal(t, spanProducers(overrideTestSpan(2)), spanProducers(span), "override not preferred for its blocks")
+
+	validators, err := spanStore.validatorsByBlockNumber(ctx, 7000)
+	require.NoError(t, err)
+	require.Equal(t, mockValidatorAddress(102), validators.producers[0].Address, "override producers not used")
+
+	// The other spans are still served by heimdall
+	span, err = spanStore.spanById(ctx, 3)
+	require.NoError(t, err)
+	require.Equal(t, []common.Address{mockValidatorAddress(3)}, spanProducers(span), "invalid producers of heimdall span")
+}
+
+func TestSpanOverride_AutoPurge(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	engine := newSpanOverrideTestEngine(&MockHeimdallClientWithConflicts{recommitted: map[uint64]uint64{2: 6000}})
+	spanStore := engine.spanStore
+	ctx := t.Context()
+
+	path := writeSpanOverride(t, key, overrideTestSpan(2))
+	require.NoError(t, engine.LoadSpanOverride(path, hexutil.Encode(crypto.FromECDSAPub(&key.PublicKey))))
+
+	// A span with another range doesn't replace the override
+	require.False(t, spanStore.checkSpanOverride(ctx, 2), "override dropped for a different span")
+	require.NotNil(t, spanStore.overrides.get(2), "override dropped for a different span")
+
+	// The override is only checked again after the recheck interval
+	now := time.Now()
+	require.True(t, spanStore.overrides.due(2, now))
+	require.False(t, spanStore.overrides.due(2, now.Add(spanOverrideRecheckInterval/2)))
+	require.True(t, spanStore.overrides.due(2, now.Add(spanOverrideRecheckInterval)))
+
+	// The real span with the same range replaces the override
+	spanStore.setHeimdallClient(&MockHeimdallClientWithValidators{})
+	require.True(t, spanStore.checkSpanOverride(ctx, 2), "override not dropped for the real span")
+	require.Nil(t, spanStore.overrides.get(2), "override not dropped for the real span")
+
+	span, err := spanStore.spanById(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, []common.Address{mockValidatorAddress(2)}, spanProducers(span), "real span not served after purge")
+}
@@ -0,0 +1,118 @@
+package bor
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	borTypes "github.com/0xPolygon/heimdall-v2/x/bor/types"
+)
+
+const (
+	// spanReconcileInterval is how often the cached spans are compared with the ones
+	// served by heimdall.
+	spanReconcileInterval = 6 * time.Hour
+
+	// spanReconcileSamples is the number of cached spans picked at random to be compared
+	// with heimdall on every reconciliation, on top of the latest span.
+	spanReconcileSamples = 2
+
+	// spanReconcileTimeout is how long a reconciliation waits for heimdall.
+	spanReconcileTimeout = time.Minute
+)
+
+// spanDivergenceCounter counts the cached spans found to differ from the ones served by
+// heimdall, as happens if heimdall fixed its data after the spans were fetched.
+var spanDivergenceCounter = metrics.NewRegisteredCounter("bor/span/divergences", nil)
+
+// reconcileLoop periodically compares some cached spans with the ones served by heimdall
+// until the given channel is closed. It must only be run with a heimdall client.
+func (s *SpanStore) reconcileLoop(interval time.Duration, quit <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), spanReconcileTimeout)
+			s.reconcile(ctx, spanReconcileSamples)
+			cancel()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// reconcile compares the latest span and the given number of cached spans picked at random
+// with the ones served by heimdall, evicting the cached spans whose block range or producers
+// differ so that they are fetched again by the next lookups. It returns the number of
+// divergent spans found.
+func (s *SpanStore) reconcile(ctx context.Context, samples int) int {
+	var spans []*borTypes.Span
+
+	latest, err := s.heimdallClient.GetLatestSpan(ctx)
+	if err != nil {
+		log.Debug("Failed to fetch latest span to reconcile cached spans", "err", err)
+	} else if latest != nil {
+		spans = append(spans, latest)
+	}
+
+	ids := s.store.Keys()
+	rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+
+	for _, id := range ids {
+		if samples == 0 {
+			break
+		}
+
+		if latest != nil && id == latest.Id {
+			continue
+		}
+
+		span, err := s.heimdallClient.GetSpan(ctx, id)
+		if err != nil {
+			log.Debug("Failed to fetch span to reconcile cached spans", "id", id, "err", err)
+			continue
+		}
+
+		if span != nil {
+			spans = append(spans, span)
+		}
+
+		samples--
+	}
+
+	var divergences int
+
+	for _, span := range spans {
+		cached, _ := s.store.Peek(span.Id)
+		if cached == nil || spansMatch(cached, span) {
+			continue
+		}
+
+		// Only evict the compared span, the lookups may have replaced it in the meantime
+		if !s.store.Remove(span.Id, func(current *borTypes.Span) bool { return current == cached }) {
+			continue
+		}
+
+		s.validators.Remove(span.Id, func(*spanValidators) bool { return true })
+
+		divergences++
+		s.divergences.Inc(1)
+
+		log.Error("Cached span differs from heimdall, evicting it", "id", span.Id,
+			"start", cached.StartBlock, "end", cached.EndBlock, "producers", spanProducers(cached),
+			"heimdallStart", span.StartBlock, "heimdallEnd", span.EndBlock, "heimdallProducers", spanProducers(span))
+	}
+
+	return divergences
+}
+
+// spansMatch reports whether two spans have the same block range and producers.
+func spansMatch(a *borTypes.Span, b *borTypes.Span) bool {
+	return a.StartBlock == b.StartBlock && a.EndBlock == b.EndBlock && slices.Equal(spanProducers(a), spanProducers(b))
+}
@@ -0,0 +1,135 @@
+package bor
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/0xPolygon/heimdall-v2/x/bor/types"
+	stakeTypes "github.com/0xPolygon/heimdall-v2/x/stake/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// fixableHeimdallClient behaves like MockHeimdallClientWithValidators, but applies the
+// registered fixes to the spans it serves, as if heimdall fixed its data.
+type fixableHeimdallClient struct {
+	MockHeimdallClientWithValidators
+	latest   uint64
+	fixes    map[uint64]func(*types.Span)
+	requests atomic.Int64
+	lock     sync.Mutex
+}
+
+func (h *fixableHeimdallClient) GetSpan(ctx context.Context, spanID uint64) (*types.Span, error) {
+	h.requests.Add(1)
+
+	span, err := h.MockHeimdallClientWithValidators.GetSpan(ctx, spanID)
+	if err != nil {
+		return nil, err
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if fix := h.fixes[spanID]; fix != nil {
+		fix(span)
+	}
+
+	return span, nil
+}
+
+func (h *fixableHeimdallClient) GetLatestSpan(ctx context.Context) (*types.Span, error) {
+	return h.GetSpan(ctx, h.latest)
+}
+
+// fix makes the client serve the given span modified by the given function.
+func (h *fixableHeimdallClient) fix(spanID uint64, fix func(*types.Span)) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.fixes == nil {
+		h.fixes = make(map[uint64]func(*types.Span))
+	}
+
+	h.fixes[spanID] = fix
+}
+
+// replaceProducers replaces the producers of a span by the ones of the span with the given id.
+func replaceProducers(id uint64) func(*types.Span) {
+	return func(span *types.Span) {
+		span.ValidatorSet = mockValidatorSet(id)
+		span.SelectedProducers = []stakeTypes.Validator{*span.ValidatorSet.Validators[0]}
+	}
+}
+
+func TestSpanStore_Reconcile(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	client := &fixableHeimdallClient{latest: 3}
+	spanStore := NewSpanStore(client, nil, "1337", nil)
+	spanStore.divergences = metrics.NewCounter()
+
+	for id := uint64(1); id <= 3; id++ {
+		_, err := spanStore.spanById(ctx, id)
+		require.NoError(t, err)
+	}
+
+	// Nothing diverges while heimdall serves the cached spans, a cycle only fetches the
+	// latest span and the sampled ones
+	requests := client.requests.Load()
+
+	require.Zero(t, spanStore.reconcile(ctx, 1))
+	require.Equal(t, requests+2, client.requests.Load())
+	require.Equal(t, []uint64{1, 2, 3}, spanStore.store.Keys())
+
+	// Heimdall fixes the producers of the latest span and the end of another one, both
+	// divergent spans are evicted
+	client.fix(3, replaceProducers(100))
+	client.fix(1, func(span *types.Span) { span.EndBlock-- })
+
+	_, err := spanStore.validatorsByBlockNumber(ctx, 6400*2+256) // First block of span 3
+	require.NoError(t, err)
+	require.True(t, spanStore.validators.Contains(3))
+
+	require.Equal(t, 2, spanStore.reconcile(ctx, 2))
+	require.Equal(t, int64(2), spanStore.divergences.Snapshot().Count())
+	require.Equal(t, []uint64{2}, spanStore.store.Keys())
+	require.False(t, spanStore.validators.Contains(3))
+
+	// The next lookups fetch the fixed spans, which don't diverge anymore
+	validators, err := spanStore.validatorsByBlockNumber(ctx, 6400*2+256)
+	require.NoError(t, err)
+	require.Equal(t, mockValidatorAddress(100), validators.producers[0].Address)
+
+	fixed, err := spanStore.spanById(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(6400+254), fixed.EndBlock)
+
+	require.Zero(t, spanStore.reconcile(ctx, 2))
+	require.Equal(t, int64(2), spanStore.divergences.Snapshot().Count())
+
+	// The background loop detects the divergences until stopped
+	client.fix(2, replaceProducers(200))
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		spanStore.reconcileLoop(10*time.Millisecond, quit)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return spanStore.divergences.Snapshot().Count() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	require.False(t, spanStore.store.Contains(2))
+
+	close(quit)
+	<-done
+}
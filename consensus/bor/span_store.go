@@ -2,14 +2,22 @@ package bor
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall"
 	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/span"
 	"github.com/ethereum/go-ethereum/consensus/bor/valset"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rpc"
-	lru "github.com/hashicorp/golang-lru"
 
 	borTypes "github.com/0xPolygon/heimdall-v2/x/bor/types"
 )
@@ -19,39 +27,72 @@ import (
 // hence we set a very high limit. It can be reduced later.
 const maxSpanFetchLimit = 10_000
 
+// spanGapCounter counts the number of times a span id was reported missing by heimdall
+// while its neighbouring spans were available.
+var spanGapCounter = metrics.NewRegisteredCounter("bor/span/gaps", nil)
+
+// maxSpanConflicts is the maximum number of span conflicts kept for inspection.
+const maxSpanConflicts = 32
+
+// spanConflictCounter counts the number of spans found to claim blocks of another span
+// with a different set of producers.
+var spanConflictCounter = metrics.NewRegisteredCounter("bor/span/conflicts", nil)
+
+// spanEstimateSlack is the number of spans past the latest known span that a span id
+// estimated from the cached spans may reach.
+const spanEstimateSlack = 16
+
+// errSpanNotFound is returned when heimdall reports that a span doesn't exist (as opposed
+// to a transient failure while fetching it).
+var errSpanNotFound = errors.New("span not found")
+
 // SpanStore acts as a simple middleware to cache span data populated from heimdall. It is used
 // in multiple places of bor consensus for verification.
 type SpanStore struct {
-	store *lru.ARCCache
+	store      *spanCache[*borTypes.Span]
+	validators *spanCache[*spanValidators]
 
 	heimdallClient IHeimdallClient
 	spanner        Spanner
 
-	latestKnownSpanId uint64
+	latestKnownSpanId *atomic.Uint64 // Highest span id fetched so far, shared by concurrent lookups
 	chainId           string
 
 	db ethdb.Database
+
+	conflicts *spanConflicts // Spans re-committed by heimdall with different producers
+	overrides *spanOverrides // Spans overriding the ones of heimdall, see LoadSpanOverride
+
+	divergences *metrics.Counter // Counter of the cached spans found to differ from heimdall
 }
 
 func NewSpanStore(heimdallClient IHeimdallClient, spanner Spanner, chainId string, db ethdb.Database) SpanStore {
-	cache, _ := lru.NewARC(10)
 	return SpanStore{
-		store:             cache,
+		store:             newSpanCache[*borTypes.Span](10),
+		validators:        newSpanCache[*spanValidators](10),
 		heimdallClient:    heimdallClient,
 		spanner:           spanner,
-		latestKnownSpanId: 0,
+		latestKnownSpanId: new(atomic.Uint64),
 		chainId:           chainId,
 		db:                db,
+		conflicts:         new(spanConflicts),
+		overrides:         new(spanOverrides),
+		divergences:       spanDivergenceCounter,
 	}
 }
 
 // spanById returns a span given its id. It fetches span from heimdall if not found in cache.
 func (s *SpanStore) spanById(ctx context.Context, spanId uint64) (*borTypes.Span, error) {
-	var currentSpan *borTypes.Span
-	if value, ok := s.store.Get(spanId); ok {
-		currentSpan, _ = value.(*borTypes.Span)
+	// Overridden spans take precedence, until heimdall serves them again
+	if override := s.overrides.get(spanId); override != nil {
+		if s.heimdallClient != nil && s.overrides.due(spanId, time.Now()) {
+			go s.checkSpanOverride(context.Background(), spanId)
+		}
+
+		return override, nil
 	}
 
+	currentSpan, _ := s.store.Get(spanId)
 	if currentSpan != nil {
 		return currentSpan, nil
 	}
@@ -70,23 +111,41 @@ func (s *SpanStore) spanById(ctx context.Context, spanId uint64) (*borTypes.Span
 	} else {
 		currentSpan, err = s.heimdallClient.GetSpan(ctx, spanId)
 		if err != nil {
+			if errors.Is(err, heimdall.ErrNotFound) {
+				log.Warn("Span not found in heimdall", "id", spanId, "err", err)
+				return nil, fmt.Errorf("%w: id %d", errSpanNotFound, spanId)
+			}
+			if errors.Is(err, heimdall.ErrHeimdallSchemaMismatch) {
+				log.Error("Unexpected span response from heimdall, bor and heimdall versions may be incompatible", "id", spanId, "err", err)
+				return nil, err
+			}
 			log.Warn("Unable to fetch span from heimdall", "id", spanId, "err", err)
 			return nil, err
 		}
 	}
 
 	if currentSpan == nil {
-		return nil, fmt.Errorf("span not found for id %d", spanId)
+		return nil, fmt.Errorf("%w: id %d", errSpanNotFound, spanId)
 	}
 
+	s.detectConflicts(currentSpan)
 	s.store.Add(spanId, currentSpan)
-	if currentSpan.Id > s.latestKnownSpanId {
-		s.latestKnownSpanId = currentSpan.Id
-	}
+	s.updateLatestKnownSpanId(currentSpan.Id)
 
 	return currentSpan, nil
 }
 
+// updateLatestKnownSpanId raises the latest known span id to the given one. Concurrent
+// lookups may fetch spans out of order, so a lower id never replaces a higher one.
+func (s *SpanStore) updateLatestKnownSpanId(id uint64) {
+	for {
+		current := s.latestKnownSpanId.Load()
+		if id <= current || s.latestKnownSpanId.CompareAndSwap(current, id) {
+			return
+		}
+	}
+}
+
 // spanByBlockNumber returns a span given a block number. It fetches span from heimdall if not found in cache. It
 // assumes that a span has been committed before (i.e. is current or past span) and returns an error if
 // asked for a future span. This is safe to assume as we don't have a way to find out span id for a future block
@@ -95,22 +154,32 @@ func (s *SpanStore) spanByBlockNumber(ctx context.Context, blockNumber uint64) (
 	// As we don't persist latest known span to db, we loose the value on restarts. This leads to multiple heimdall calls
 	// which can be avoided. Hence we estimate the span id from block number which updates the latest known span id. Note
 	// that we still check if the block number lies in the range of span before returning it.
-	estimatedSpanId := estimateSpanId(blockNumber)
-	// Ignore the return value of this span as we validate it later in the loop
+	estimatedSpanId := s.estimateSpanId(blockNumber)
+	// Ignore the return value of this span as we validate it later in the loop. A missing
+	// span is tolerated here as the loop below will skip over isolated gaps.
 	_, err := s.spanById(ctx, estimatedSpanId)
-	if err != nil {
+	if err != nil && !errors.Is(err, errSpanNotFound) {
 		return nil, err
 	}
 	// Iterate over all spans and check for number. This is to replicate the behaviour implemented in
 	// https://github.com/0xPolygon/genesis-contracts/blob/master/contracts/BorValidatorSet.template#L118-L134
 	// This logic is independent of the span length (bit extra effort but maintains equivalence) and will work
 	// for all span lengths (even if we change it in future).
-	latestKnownSpanId := s.latestKnownSpanId
+	latestKnownSpanId := s.latestKnownSpanId.Load()
+	missing := false
 	for id := int(latestKnownSpanId); id >= 0; id-- {
 		span, err := s.spanById(ctx, uint64(id))
 		if err != nil {
+			// Tolerate an isolated gap in span ids by looking at one more id before giving up
+			if errors.Is(err, errSpanNotFound) && !missing {
+				missing = true
+				spanGapCounter.Inc(1)
+				log.Warn("Span gap detected in heimdall, skipping missing span", "id", id, "block", blockNumber)
+				continue
+			}
 			return nil, err
 		}
+		missing = false
 		if blockNumber >= span.StartBlock && blockNumber <= span.EndBlock {
 			return span, nil
 		}
@@ -120,19 +189,203 @@ func (s *SpanStore) spanByBlockNumber(ctx context.Context, blockNumber uint64) (
 		}
 	}
 
-	return nil, fmt.Errorf("span not found for block %d", blockNumber)
+	return nil, fmt.Errorf("%w for block %d", errSpanNotFound, blockNumber)
+}
+
+// spanValidators holds the validators of a span converted to bor types. All headers of a
+// span share them, so converting them once per span saves a lot of work when verifying
+// large batches of headers (e.g. during snap sync).
+type spanValidators struct {
+	span         *borTypes.Span       // Span the validators were converted from
+	validatorSet *valset.ValidatorSet // Validator set of the span
+	producers    []*valset.Validator  // Selected producers of the span sorted by address
+}
+
+// validatorsByBlockNumber returns the converted validators of the span the given block number
+// belongs to. The conversion is cached by span id and redone if the underlying span changes
+// in the span cache. The returned values are shared and must not be modified.
+func (s *SpanStore) validatorsByBlockNumber(ctx context.Context, blockNumber uint64) (*spanValidators, error) {
+	currentSpan, err := s.spanByBlockNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached, _ := s.validators.Get(currentSpan.Id); cached != nil && cached.span == currentSpan {
+		return cached, nil
+	}
+
+	validatorSet := span.ConvertHeimdallValSetToBorValSet(currentSpan.ValidatorSet)
+
+	selectedProducers := span.ConvertHeimdallValidatorsToBorValidators(currentSpan.SelectedProducers)
+	producers := make([]*valset.Validator, len(selectedProducers))
+	for i, val := range selectedProducers {
+		producers[i] = &val
+	}
+	sort.Sort(valset.ValidatorsByAddress(producers))
+
+	converted := &spanValidators{
+		span:         currentSpan,
+		validatorSet: &validatorSet,
+		producers:    producers,
+	}
+	s.validators.Add(currentSpan.Id, converted)
+
+	return converted, nil
+}
+
+// SpanConflict records two spans claiming overlapping block ranges with different
+// producers, as happens when heimdall re-commits a span. Blocks of the overlap may
+// have been sealed by the producers of the older span before the newer one, which
+// takes precedence, was known.
+type SpanConflict struct {
+	SpanId               uint64           `json:"spanId"`               // Id of the newer span
+	ConflictingSpanId    uint64           `json:"conflictingSpanId"`    // Id of the older span
+	StartBlock           uint64           `json:"startBlock"`           // First block claimed by both spans
+	EndBlock             uint64           `json:"endBlock"`             // Last block claimed by both spans
+	Producers            []common.Address `json:"producers"`            // Sorted producers of the newer span
+	ConflictingProducers []common.Address `json:"conflictingProducers"` // Sorted producers of the older span
+}
+
+// spanConflicts is the list of the most recently detected span conflicts.
+type spanConflicts struct {
+	conflicts []*SpanConflict
+	lock      sync.RWMutex
+}
+
+// add records a conflict, returning false if it was already known.
+func (c *spanConflicts) add(conflict *SpanConflict) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, known := range c.conflicts {
+		if known.SpanId == conflict.SpanId && known.ConflictingSpanId == conflict.ConflictingSpanId {
+			return false
+		}
+	}
+
+	if len(c.conflicts) == maxSpanConflicts {
+		c.conflicts = c.conflicts[1:]
+	}
+	c.conflicts = append(c.conflicts, conflict)
+
+	return true
+}
+
+// list returns the recorded conflicts, oldest first.
+func (c *spanConflicts) list() []*SpanConflict {
+	if c == nil {
+		return nil
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return slices.Clone(c.conflicts)
+}
+
+// forBlock returns the most recently detected conflict covering the given block, if any.
+func (c *spanConflicts) forBlock(number uint64) *SpanConflict {
+	if c == nil {
+		return nil
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	for i := len(c.conflicts) - 1; i >= 0; i-- {
+		if conflict := c.conflicts[i]; number >= conflict.StartBlock && number <= conflict.EndBlock {
+			return conflict
+		}
+	}
+
+	return nil
+}
+
+// detectConflicts compares a span about to be cached with the cached spans, recording a
+// conflict for every one of them claiming some of its blocks with different producers.
+// Both spans are kept as they are: the newer one takes precedence in spanByBlockNumber,
+// but blocks may already have been sealed by the producers of the older one. Spans
+// already evicted from the cache are not checked.
+func (s *SpanStore) detectConflicts(currentSpan *borTypes.Span) {
+	if s.conflicts == nil {
+		return
+	}
+
+	for _, key := range s.store.Keys() {
+		cached, _ := s.store.Peek(key)
+		if cached == nil || cached.Id == currentSpan.Id {
+			continue
+		}
+
+		if currentSpan.StartBlock > cached.EndBlock || cached.StartBlock > currentSpan.EndBlock {
+			continue
+		}
+
+		newer, older := currentSpan, cached
+		if older.Id > newer.Id {
+			newer, older = older, newer
+		}
+
+		producers, conflictingProducers := spanProducers(newer), spanProducers(older)
+		if slices.Equal(producers, conflictingProducers) {
+			continue
+		}
+
+		conflict := &SpanConflict{
+			SpanId:               newer.Id,
+			ConflictingSpanId:    older.Id,
+			StartBlock:           max(newer.StartBlock, older.StartBlock),
+			EndBlock:             min(newer.EndBlock, older.EndBlock),
+			Producers:            producers,
+			ConflictingProducers: conflictingProducers,
+		}
+		if s.conflicts.add(conflict) {
+			spanConflictCounter.Inc(1)
+			log.Error("Conflicting spans committed by heimdall, blocks may have been sealed by different producers",
+				"span", conflict.SpanId, "conflicting", conflict.ConflictingSpanId,
+				"start", conflict.StartBlock, "end", conflict.EndBlock,
+				"producers", conflict.Producers, "conflictingProducers", conflict.ConflictingProducers)
+		}
+	}
+}
+
+// spanConflictForBlock returns the most recently detected span conflict covering the
+// given block, if any.
+func (s *SpanStore) spanConflictForBlock(number uint64) *SpanConflict {
+	return s.conflicts.forBlock(number)
+}
+
+// spanProducers returns the sorted signer addresses of the selected producers of a span.
+func spanProducers(span *borTypes.Span) []common.Address {
+	producers := make([]common.Address, len(span.SelectedProducers))
+	for i, producer := range span.SelectedProducers {
+		producers[i] = common.HexToAddress(producer.Signer)
+	}
+	slices.SortFunc(producers, common.Address.Cmp)
+
+	return producers
 }
 
 // getFutureSpan fetches span for future block number. It is mostly needed during snap sync.
 func getFutureSpan(ctx context.Context, id uint64, blockNumber uint64, latestKnownSpanId uint64, s *SpanStore) (*borTypes.Span, error) {
+	missing := false
 	for {
 		if id > latestKnownSpanId+maxSpanFetchLimit {
-			return nil, fmt.Errorf("span not found for block %d", blockNumber)
+			return nil, fmt.Errorf("%w for block %d", errSpanNotFound, blockNumber)
 		}
 		span, err := s.spanById(ctx, id)
 		if err != nil {
+			// Tolerate an isolated gap in span ids by looking at one more id before giving up
+			if errors.Is(err, errSpanNotFound) && !missing {
+				missing = true
+				spanGapCounter.Inc(1)
+				log.Warn("Span gap detected in heimdall, skipping missing span", "id", id, "block", blockNumber)
+				id++
+				continue
+			}
 			return nil, err
 		}
+		missing = false
 		if blockNumber >= span.StartBlock && blockNumber <= span.EndBlock {
 			return span, nil
 		}
@@ -148,6 +401,86 @@ func estimateSpanId(blockNumber uint64) uint64 {
 	return 0
 }
 
+// estimateSpanId estimates the span id of the given block number from the two most
+// recent cached spans, so that chains with a non-default span length don't walk back
+// over many spans to find the right one. The estimate is capped at spanEstimateSlack
+// spans past the latest known span. If fewer than two spans are cached, it falls back
+// to assuming the default span length.
+func (s *SpanStore) estimateSpanId(blockNumber uint64) uint64 {
+	latest, previous := s.latestCachedSpans()
+	if latest == nil || previous == nil || latest.EndBlock <= previous.EndBlock {
+		return estimateSpanId(blockNumber)
+	}
+	spanLength := (latest.EndBlock - previous.EndBlock) / (latest.Id - previous.Id)
+	if spanLength == 0 {
+		return estimateSpanId(blockNumber)
+	}
+
+	var id uint64
+
+	switch {
+	case blockNumber > latest.EndBlock:
+		id = latest.Id + 1 + (blockNumber-latest.EndBlock-1)/spanLength
+	case blockNumber < latest.StartBlock:
+		if back := (latest.StartBlock - blockNumber + spanLength - 1) / spanLength; back < latest.Id {
+			id = latest.Id - back
+		}
+	default:
+		id = latest.Id
+	}
+
+	return min(id, s.latestKnownSpanId.Load()+spanEstimateSlack)
+}
+
+// latestCachedSpans returns the two cached spans with the highest ids, if any.
+func (s *SpanStore) latestCachedSpans() (latest *borTypes.Span, previous *borTypes.Span) {
+	for _, id := range s.store.Keys() {
+		span, ok := s.store.Peek(id)
+		if !ok || span == nil {
+			continue
+		}
+		switch {
+		case latest == nil || span.Id > latest.Id:
+			latest, previous = span, latest
+		case span.Id < latest.Id && (previous == nil || span.Id > previous.Id):
+			previous = span
+		}
+	}
+
+	return latest, previous
+}
+
+// cachedSpan returns the span of the given id if it's overridden or cached, without
+// fetching it from heimdall.
+func (s *SpanStore) cachedSpan(id uint64) *borTypes.Span {
+	if override := s.overrides.get(id); override != nil {
+		return override
+	}
+
+	span, _ := s.store.Peek(id)
+
+	return span
+}
+
+// cachedSpanByBlockNumber returns the latest cached span covering the given block number,
+// without fetching anything from heimdall. It returns nil if no such span is cached.
+func (s *SpanStore) cachedSpanByBlockNumber(blockNumber uint64) *borTypes.Span {
+	var found *borTypes.Span
+
+	for _, id := range s.store.Keys() {
+		span := s.cachedSpan(id)
+		if span == nil || blockNumber < span.StartBlock || blockNumber > span.EndBlock {
+			continue
+		}
+
+		if found == nil || span.Id > found.Id {
+			found = span
+		}
+	}
+
+	return found
+}
+
 // setHeimdallClient sets the underlying heimdall client to be used. It is useful in
 // tests where mock heimdall client is set after creation of bor instance explicitly.
 func (s *SpanStore) setHeimdallClient(client IHeimdallClient) {
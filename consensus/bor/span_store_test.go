@@ -3,12 +3,22 @@ package bor
 import (
 	"context"
 	"fmt"
+	"math/big"
+	"math/rand"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/0xPolygon/heimdall-v2/x/bor/types"
+	stakeTypes "github.com/0xPolygon/heimdall-v2/x/stake/types"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/bor/clerk"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall"
 	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/checkpoint"
 	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/milestone"
+	borSpan "github.com/ethereum/go-ethereum/consensus/bor/heimdall/span"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdallsim"
 	"github.com/stretchr/testify/require"
 )
 
@@ -46,10 +56,10 @@ func TestSpanStore_SpanById(t *testing.T) {
 	require.Len(t, keys, 3, "invalid length of keys in span store")
 
 	// Ensure latest known span id is updated
-	require.Equal(t, uint64(2), spanStore.latestKnownSpanId, "invalid latest known span id in span store")
+	require.Equal(t, uint64(2), spanStore.latestKnownSpanId.Load(), "invalid latest known span id in span store")
 
 	// Ask for a few more spans
-	for i := spanStore.latestKnownSpanId; i <= 20; i++ {
+	for i := spanStore.latestKnownSpanId.Load(); i <= 20; i++ {
 		_, err := spanStore.spanById(ctx, i)
 		require.NoError(t, err, "err in spanById for id=%d", i)
 	}
@@ -59,7 +69,7 @@ func TestSpanStore_SpanById(t *testing.T) {
 	require.Len(t, keys, 10, "invalid length of keys in span store")
 
 	// Ensure latest known span id is updated
-	require.Equal(t, uint64(20), spanStore.latestKnownSpanId, "invalid latest known span id in span store")
+	require.Equal(t, uint64(20), spanStore.latestKnownSpanId.Load(), "invalid latest known span id in span store")
 
 	// Ensure we're still able to fetch old spans even though they're evicted from cache
 	span, err := spanStore.spanById(ctx, 0)
@@ -69,7 +79,7 @@ func TestSpanStore_SpanById(t *testing.T) {
 	require.Equal(t, uint64(255), span.EndBlock, "invalid end block in spanById after eviction for id=0")
 
 	// Ensure latest known span is still the old one
-	require.Equal(t, uint64(20), spanStore.latestKnownSpanId, "invalid latest known span id in span store")
+	require.Equal(t, uint64(20), spanStore.latestKnownSpanId.Load(), "invalid latest known span id in span store")
 }
 
 func TestSpanStore_SpanByBlockNumber(t *testing.T) {
@@ -84,7 +94,7 @@ func TestSpanStore_SpanByBlockNumber(t *testing.T) {
 	}
 
 	// Insert a few spans
-	for i := spanStore.latestKnownSpanId; i < 3; i++ {
+	for i := spanStore.latestKnownSpanId.Load(); i < 3; i++ {
 		_, err := spanStore.spanById(ctx, i)
 		require.NoError(t, err, "err in spanById for id=%d", i)
 	}
@@ -94,7 +104,7 @@ func TestSpanStore_SpanByBlockNumber(t *testing.T) {
 	require.Len(t, keys, 3, "invalid length of keys in span store")
 
 	// Ensure latest known span id is updated
-	require.Equal(t, uint64(2), spanStore.latestKnownSpanId, "invalid latest known span id in span store")
+	require.Equal(t, uint64(2), spanStore.latestKnownSpanId.Load(), "invalid latest known span id in span store")
 
 	// Ask for current and past spans via block number
 	testcases := []Testcase{
@@ -121,7 +131,7 @@ func TestSpanStore_SpanByBlockNumber(t *testing.T) {
 	}
 
 	// Insert a few more spans to trigger eviction
-	for i := spanStore.latestKnownSpanId; i <= 20; i++ {
+	for i := spanStore.latestKnownSpanId.Load(); i <= 20; i++ {
 		_, err := spanStore.spanById(ctx, i)
 		require.NoError(t, err, "err in spanById for id=%d", i)
 	}
@@ -131,7 +141,7 @@ func TestSpanStore_SpanByBlockNumber(t *testing.T) {
 	require.Len(t, keys, 10, "invalid length of keys in span store")
 
 	// Ensure latest known span id is updated
-	require.Equal(t, uint64(20), spanStore.latestKnownSpanId, "invalid latest known span id in span store")
+	require.Equal(t, uint64(20), spanStore.latestKnownSpanId.Load(), "invalid latest known span id in span store")
 
 	// Ask for current and past spans
 	testcases = append(testcases, Testcase{blockNumber: 57856, id: 10, startBlock: 57856, endBlock: 64255})
@@ -159,6 +169,437 @@ func TestSpanStore_SpanByBlockNumber(t *testing.T) {
 	require.Equal(t, uint64(134655), span.EndBlock, "invalid end block in spanByBlockNumber for future block 128256")
 }
 
+func TestSpanStore_SpanGap(t *testing.T) {
+	client := &MockHeimdallClientWithGap{missing: map[uint64]struct{}{5: {}}}
+	spanStore := NewSpanStore(client, nil, "1337", nil)
+	ctx := t.Context()
+
+	// Fetching the missing span directly should return a not found error
+	_, err := spanStore.spanById(ctx, 5)
+	require.ErrorIs(t, err, errSpanNotFound, "expected not found error for missing span")
+
+	// Insert spans up to 10 (skipping the missing one)
+	for i := uint64(0); i <= 10; i++ {
+		if i == 5 {
+			continue
+		}
+		_, err := spanStore.spanById(ctx, i)
+		require.NoError(t, err, "err in spanById for id=%d", i)
+	}
+
+	gapsBefore := spanGapCounter.Snapshot().Count()
+
+	// Walking backwards over the gap should still resolve spans before it
+	span, err := spanStore.spanByBlockNumber(ctx, 20000) // block 20000 belongs to span 4
+	require.NoError(t, err, "err in spanByBlockNumber across a span gap")
+	require.Equal(t, uint64(4), span.Id, "invalid id in spanByBlockNumber across a span gap")
+	require.Greater(t, spanGapCounter.Snapshot().Count(), gapsBefore, "span gap not recorded in metrics")
+
+	// Blocks after the gap are unaffected
+	span, err = spanStore.spanByBlockNumber(ctx, 38656) // block 38656 belongs to span 7
+	require.NoError(t, err, "err in spanByBlockNumber after a span gap")
+	require.Equal(t, uint64(7), span.Id, "invalid id in spanByBlockNumber after a span gap")
+
+	// Future span lookup should skip over an isolated gap
+	client.missing[12] = struct{}{}
+	span, err = getFutureSpan(ctx, 11, 83456, spanStore.latestKnownSpanId.Load(), &spanStore) // block 83456 belongs to span 14
+	require.NoError(t, err, "err in spanByBlockNumber for future span across a gap")
+	require.Equal(t, uint64(14), span.Id, "invalid id in spanByBlockNumber for future span across a gap")
+
+	// Two consecutive missing spans are not tolerated
+	spanStore = NewSpanStore(client, nil, "1337", nil)
+	client.missing[3] = struct{}{}
+	client.missing[4] = struct{}{}
+	_, err = spanStore.spanById(ctx, 10)
+	require.NoError(t, err, "err in spanById for id=10")
+	_, err = spanStore.spanByBlockNumber(ctx, 13000) // block 13000 belongs to span 2
+	require.ErrorIs(t, err, errSpanNotFound, "expected not found error for consecutive span gaps")
+}
+
+// Tests that span ids are estimated from the cached spans on chains with a non-default
+// span length.
+func TestSpanStore_EstimateSpanId(t *testing.T) {
+	t.Parallel()
+
+	for _, spanLength := range []uint64{100, 6400} {
+		spanBounds := func(id uint64) (uint64, uint64) {
+			if id == 0 {
+				return 0, zerothSpanEnd
+			}
+			start := zerothSpanEnd + 1 + (id-1)*spanLength
+			return start, start + spanLength - 1
+		}
+		addSpan := func(spanStore *SpanStore, id uint64) {
+			start, end := spanBounds(id)
+			spanStore.store.Add(id, &types.Span{Id: id, StartBlock: start, EndBlock: end})
+			spanStore.updateLatestKnownSpanId(id)
+		}
+
+		// A single known span gives no span length, the default one is assumed
+		spanStore := NewSpanStore(nil, nil, "1337", nil)
+		addSpan(&spanStore, 50)
+		require.Equal(t, estimateSpanId(20000), spanStore.estimateSpanId(20000), "span length %d: fallback estimate", spanLength)
+
+		for id := uint64(0); id < 50; id++ {
+			addSpan(&spanStore, id)
+		}
+
+		for _, id := range []uint64{0, 1, 7, 25, 41, 49, 50, 51, 60} {
+			start, end := spanBounds(id)
+			for _, number := range []uint64{start, (start + end) / 2, end} {
+				estimate := spanStore.estimateSpanId(number)
+				require.LessOrEqual(t, max(estimate, id)-min(estimate, id), uint64(1), "span length %d: estimate %d for block %d of span %d", spanLength, estimate, number, id)
+			}
+		}
+
+		// Estimates are capped past the latest known span
+		_, end := spanBounds(1000)
+		require.Equal(t, uint64(50+spanEstimateSlack), spanStore.estimateSpanId(end), "span length %d: capped estimate", spanLength)
+	}
+}
+
+// Tests that concurrent span lookups by block number resolve the right spans and never
+// move the latest known span id backwards. Meant to be run with the race detector.
+func TestSpanStore_ConcurrentSpanByBlockNumber(t *testing.T) {
+	t.Parallel()
+
+	spanStore := NewSpanStore(&MockHeimdallClient{}, nil, "1337", nil)
+	ctx := t.Context()
+
+	var (
+		wg       sync.WaitGroup
+		errCh    = make(chan error, 64)
+		maxBlock = uint64(6400*90 + 255) // Stay clear of span 100, which the mock fails to fetch
+	)
+
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+
+		go func(seed int64) {
+			defer wg.Done()
+
+			rng := rand.New(rand.NewSource(seed))
+			last := uint64(0)
+
+			for j := 0; j < 50; j++ {
+				number := rng.Uint64() % (maxBlock + 1)
+
+				span, err := spanStore.spanByBlockNumber(ctx, number)
+				if err != nil {
+					errCh <- fmt.Errorf("block %d: %w", number, err)
+					return
+				}
+
+				if number < span.StartBlock || number > span.EndBlock || span.Id != estimateSpanId(number) {
+					errCh <- fmt.Errorf("block %d: resolved to span %d [%d, %d]", number, span.Id, span.StartBlock, span.EndBlock)
+					return
+				}
+
+				latest := spanStore.latestKnownSpanId.Load()
+				if latest < last || latest < span.Id {
+					errCh <- fmt.Errorf("latest known span id went from %d to %d after resolving span %d", last, latest, span.Id)
+					return
+				}
+
+				last = latest
+			}
+		}(int64(i))
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		t.Error(err)
+	}
+}
+
+// MockHeimdallClientWithGap behaves like MockHeimdallClient but reports the given span ids
+// as not found (as heimdall does with a 404 response).
+type MockHeimdallClientWithGap struct {
+	MockHeimdallClient
+	missing map[uint64]struct{}
+}
+
+func (h *MockHeimdallClientWithGap) GetSpan(ctx context.Context, spanID uint64) (*types.Span, error) {
+	if _, ok := h.missing[spanID]; ok {
+		return nil, fmt.Errorf("%w: response code 404", heimdall.ErrNotFound)
+	}
+
+	return h.MockHeimdallClient.GetSpan(ctx, spanID)
+}
+
+func TestSpanStore_ValidatorsByBlockNumber(t *testing.T) {
+	spanStore := NewSpanStore(&MockHeimdallClientWithValidators{}, nil, "1337", nil)
+	ctx := t.Context()
+
+	// Blocks of the same span share the converted validators
+	first, err := spanStore.validatorsByBlockNumber(ctx, 6400) // block 6400 belongs to span 1
+	require.NoError(t, err, "err in validatorsByBlockNumber")
+	second, err := spanStore.validatorsByBlockNumber(ctx, 6655) // block 6655 belongs to span 1
+	require.NoError(t, err, "err in validatorsByBlockNumber")
+	require.Same(t, first, second, "expected cached validators for blocks of the same span")
+	require.Equal(t, mockValidatorAddress(1), first.validatorSet.Validators[0].Address, "invalid validator for span 1")
+	require.Equal(t, mockValidatorAddress(1), first.producers[0].Address, "invalid producer for span 1")
+
+	// Crossing the span boundary yields the validators of the next span
+	next, err := spanStore.validatorsByBlockNumber(ctx, 6656) // block 6656 belongs to span 2
+	require.NoError(t, err, "err in validatorsByBlockNumber")
+	require.NotSame(t, first, next, "expected different validators across span boundary")
+	require.Equal(t, mockValidatorAddress(2), next.validatorSet.Validators[0].Address, "invalid validator for span 2")
+	require.Equal(t, mockValidatorAddress(2), next.producers[0].Address, "invalid producer for span 2")
+
+	// Replacing the span in the span cache invalidates the converted validators
+	updated := *next.span
+	updated.ValidatorSet = mockValidatorSet(3)
+	updated.SelectedProducers = []stakeTypes.Validator{*updated.ValidatorSet.Validators[0]}
+	spanStore.store.Add(updated.Id, &updated)
+
+	refreshed, err := spanStore.validatorsByBlockNumber(ctx, 6656)
+	require.NoError(t, err, "err in validatorsByBlockNumber")
+	require.Equal(t, mockValidatorAddress(3), refreshed.validatorSet.Validators[0].Address, "stale validators after span update")
+	require.Equal(t, mockValidatorAddress(3), refreshed.producers[0].Address, "stale producers after span update")
+}
+
+func TestSpanStore_HeimdallSimulator(t *testing.T) {
+	validators := []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02")}
+
+	sim, err := heimdallsim.New(validators, 256, "1337", nil)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(sim)
+	t.Cleanup(srv.Close)
+
+	client := heimdall.NewHeimdallClient(srv.URL, time.Second, 10*time.Millisecond, 1)
+	t.Cleanup(client.Close)
+
+	spanStore := NewSpanStore(client, nil, "1337", nil)
+	ctx := t.Context()
+
+	for _, tc := range []struct {
+		blockNumber uint64
+		id          uint64
+		startBlock  uint64
+		endBlock    uint64
+	}{
+		{blockNumber: 0, id: 0, startBlock: 0, endBlock: 255},
+		{blockNumber: 255, id: 0, startBlock: 0, endBlock: 255},
+		{blockNumber: 256, id: 1, startBlock: 256, endBlock: 511},
+		{blockNumber: 511, id: 1, startBlock: 256, endBlock: 511},
+		{blockNumber: 512, id: 2, startBlock: 512, endBlock: 767},
+		{blockNumber: 767, id: 2, startBlock: 512, endBlock: 767},
+	} {
+		span, err := spanStore.spanByBlockNumber(ctx, tc.blockNumber)
+		require.NoError(t, err, "block %d", tc.blockNumber)
+		require.Equal(t, tc.id, span.Id, "block %d", tc.blockNumber)
+		require.Equal(t, tc.startBlock, span.StartBlock, "block %d", tc.blockNumber)
+		require.Equal(t, tc.endBlock, span.EndBlock, "block %d", tc.blockNumber)
+		require.Equal(t, "1337", span.BorChainId, "block %d", tc.blockNumber)
+
+		// All validators produce blocks, the proposer rotates across spans
+		converted, err := spanStore.validatorsByBlockNumber(ctx, tc.blockNumber)
+		require.NoError(t, err, "block %d", tc.blockNumber)
+		require.Len(t, converted.validatorSet.Validators, 2, "block %d", tc.blockNumber)
+		require.Len(t, converted.producers, 2, "block %d", tc.blockNumber)
+		require.Equal(t, validators[tc.id%2], converted.validatorSet.Proposer.Address, "block %d", tc.blockNumber)
+	}
+}
+
+func BenchmarkSpanStore_ValidatorsByBlockNumber(b *testing.B) {
+	const headers = 10_000
+
+	ctx := context.Background()
+
+	b.Run("cached", func(b *testing.B) {
+		spanStore := NewSpanStore(&MockHeimdallClientWithValidators{}, nil, "1337", nil)
+
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			for number := uint64(1); number <= headers; number++ {
+				if _, err := spanStore.validatorsByBlockNumber(ctx, number+1); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		spanStore := NewSpanStore(&MockHeimdallClientWithValidators{}, nil, "1337", nil)
+
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			for number := uint64(1); number <= headers; number++ {
+				currentSpan, err := spanStore.spanByBlockNumber(ctx, number+1)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				validatorSet := borSpan.ConvertHeimdallValSetToBorValSet(currentSpan.ValidatorSet)
+				producers := borSpan.ConvertHeimdallValidatorsToBorValidators(currentSpan.SelectedProducers)
+
+				_, _ = validatorSet, producers
+			}
+		}
+	})
+}
+
+// BenchmarkSpanStore_ParallelSpanByBlockNumber resolves cached spans from 32 goroutines,
+// as happens when verifying headers concurrently.
+func BenchmarkSpanStore_ParallelSpanByBlockNumber(b *testing.B) {
+	const goroutines = 32
+
+	ctx := context.Background()
+	spanStore := NewSpanStore(&MockHeimdallClient{}, nil, "1337", nil)
+
+	// Warm up the cache with the spans of the resolved blocks
+	for number := uint64(0); number < 20_000; number += 100 {
+		if _, err := spanStore.spanByBlockNumber(ctx, number); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var wg sync.WaitGroup
+
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := g; i < b.N; i += goroutines {
+				if _, err := spanStore.spanByBlockNumber(ctx, uint64(i)%20_000); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestSpanStore_SpanConflicts(t *testing.T) {
+	client := &MockHeimdallClientWithConflicts{recommitted: map[uint64]uint64{2: 6000}}
+	spanStore := NewSpanStore(client, nil, "1337", nil)
+	ctx := t.Context()
+
+	conflictsBefore := spanConflictCounter.Snapshot().Count()
+
+	// Consecutive spans don't conflict
+	_, err := spanStore.spanById(ctx, 0)
+	require.NoError(t, err, "err in spanById for id=0")
+	_, err = spanStore.spanById(ctx, 1)
+	require.NoError(t, err, "err in spanById for id=1")
+	require.Empty(t, spanStore.conflicts.list(), "unexpected conflict between consecutive spans")
+
+	// Span 2 was re-committed over the last blocks of span 1 with a different producer
+	_, err = spanStore.spanById(ctx, 2)
+	require.NoError(t, err, "err in spanById for id=2")
+
+	conflicts := spanStore.conflicts.list()
+	require.Len(t, conflicts, 1, "conflict not recorded")
+	require.Equal(t, &SpanConflict{
+		SpanId:               2,
+		ConflictingSpanId:    1,
+		StartBlock:           6000,
+		EndBlock:             6655,
+		Producers:            []common.Address{mockValidatorAddress(2)},
+		ConflictingProducers: []common.Address{mockValidatorAddress(1)},
+	}, conflicts[0], "invalid conflict record")
+	require.Equal(t, conflictsBefore+1, spanConflictCounter.Snapshot().Count(), "conflict not recorded in metrics")
+
+	// Both spans are kept, the newer one taking precedence
+	require.True(t, spanStore.store.Contains(uint64(1)), "conflicting span evicted")
+	span, err := spanStore.spanByBlockNumber(ctx, 6100)
+	require.NoError(t, err, "err in spanByBlockNumber for block=6100")
+	require.Equal(t, uint64(2), span.Id, "invalid id in spanByBlockNumber for conflicting block")
+
+	// The conflict is only reported for the blocks claimed by both spans
+	require.Same(t, conflicts[0], spanStore.spanConflictForBlock(6000), "conflict not found for first block")
+	require.Same(t, conflicts[0], spanStore.spanConflictForBlock(6655), "conflict not found for last block")
+	require.Nil(t, spanStore.spanConflictForBlock(5999), "unexpected conflict before overlap")
+	require.Nil(t, spanStore.spanConflictForBlock(6656), "unexpected conflict after overlap")
+
+	// Caching the older span again doesn't record the conflict twice
+	spanStore.store.Remove(uint64(1))
+	_, err = spanStore.spanById(ctx, 1)
+	require.NoError(t, err, "err in spanById for id=1")
+	require.Len(t, spanStore.conflicts.list(), 1, "conflict recorded twice")
+	require.Equal(t, conflictsBefore+1, spanConflictCounter.Snapshot().Count(), "conflict counted twice")
+
+	// Seal verification failures of conflicting blocks explain the conflict
+	engine := &Bor{spanStore: spanStore}
+	sealErr := &UnauthorizedSignerError{6099, mockValidatorAddress(1).Bytes()}
+
+	err = engine.spanConflictError(6100, sealErr)
+	var conflictErr *SpanConflictError
+	require.ErrorAs(t, err, &conflictErr, "seal error not wrapped for conflicting block")
+	require.Same(t, conflicts[0], conflictErr.Conflict, "invalid conflict in seal error")
+	require.ErrorIs(t, err, sealErr, "original seal error lost")
+	require.Equal(t, sealErr, engine.spanConflictError(5000, sealErr), "seal error wrapped for non conflicting block")
+}
+
+// MockHeimdallClientWithConflicts behaves like MockHeimdallClientWithValidators but
+// starts the given spans at the given blocks, as if heimdall re-committed them over
+// the end of the previous span.
+type MockHeimdallClientWithConflicts struct {
+	MockHeimdallClientWithValidators
+	recommitted map[uint64]uint64
+}
+
+func (h *MockHeimdallClientWithConflicts) GetSpan(ctx context.Context, spanID uint64) (*types.Span, error) {
+	span, err := h.MockHeimdallClientWithValidators.GetSpan(ctx, spanID)
+	if err != nil {
+		return nil, err
+	}
+
+	if start, ok := h.recommitted[spanID]; ok {
+		span.StartBlock = start
+	}
+
+	return span, nil
+}
+
+// MockHeimdallClientWithValidators behaves like MockHeimdallClient but also populates the
+// validator set and selected producers of the spans, using a distinct validator per span.
+type MockHeimdallClientWithValidators struct {
+	MockHeimdallClient
+}
+
+func (h *MockHeimdallClientWithValidators) GetSpan(ctx context.Context, spanID uint64) (*types.Span, error) {
+	span, err := h.MockHeimdallClient.GetSpan(ctx, spanID)
+	if err != nil {
+		return nil, err
+	}
+
+	span.ValidatorSet = mockValidatorSet(spanID)
+	span.SelectedProducers = []stakeTypes.Validator{*span.ValidatorSet.Validators[0]}
+
+	return span, nil
+}
+
+func mockValidatorAddress(id uint64) common.Address {
+	return common.BigToAddress(new(big.Int).SetUint64(id + 1))
+}
+
+func mockValidatorSet(id uint64) stakeTypes.ValidatorSet {
+	validator := &stakeTypes.Validator{
+		ValId:       id + 1,
+		Signer:      mockValidatorAddress(id).Hex(),
+		VotingPower: 100,
+	}
+
+	return stakeTypes.ValidatorSet{
+		Validators: []*stakeTypes.Validator{validator},
+		Proposer:   validator,
+	}
+}
+
 // Irrelevant to the tests above but necessary for interface compatibility
 func (h *MockHeimdallClient) StateSyncEvents(ctx context.Context, fromID uint64, to int64) ([]*clerk.EventRecordWithTime, error) {
 	panic("implement me")
@@ -206,6 +647,10 @@ func (h *MockHeimdallClient) FetchMilestoneCount(ctx context.Context) (int64, er
 	panic("implement me")
 }
 
+func (h *MockHeimdallClient) FetchMilestoneList(ctx context.Context, fromNumber, toNumber int64) ([]*milestone.Milestone, error) {
+	panic("implement me")
+}
+
 func (h *MockHeimdallClient) Close() {
 	panic("implement me")
 }
@@ -0,0 +1,113 @@
+package bor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall"
+	"github.com/ethereum/go-ethereum/consensus/bor/valset"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// spanVerificationBudget is how long the verification of a sprint end header waits
+	// for heimdall to serve the span it is checked against, retrying transient failures.
+	spanVerificationBudget = 30 * time.Second
+
+	// spanVerificationBackoff is the delay before the first retry of a span fetch, it is
+	// doubled with every retry up to spanVerificationMaxBackoff.
+	spanVerificationBackoff    = 250 * time.Millisecond
+	spanVerificationMaxBackoff = 4 * time.Second
+)
+
+// spanVerificationRetryCounter counts the retries of span fetches during header verification
+var spanVerificationRetryCounter = metrics.NewRegisteredCounter("bor/span/verification_retries", nil)
+
+// verifySpanValidators verifies that the producers in the extra data of the given sprint
+// end header match the producers of the span of the next block. A SpanUnavailableError is
+// returned if the span can't be fetched before the context is done, so that the header
+// isn't mistaken for an invalid one.
+func (c *Bor) verifySpanValidators(ctx context.Context, header *types.Header) error {
+	number := header.Number.Uint64()
+
+	validators, err := c.fetchSpanValidators(ctx, number+1)
+	if err != nil {
+		log.Warn("Span unavailable to verify the validator set", "number", number, "err", err)
+		return &SpanUnavailableError{Number: number, Err: err}
+	}
+
+	// Use producer set from span as it's equivalent to the data we get from genesis contract
+	newValidators := validators.producers
+
+	headerVals, err := valset.ParseValidators(header.GetValidatorBytes(c.chainConfig))
+	if err != nil {
+		return err
+	}
+
+	if len(newValidators) != len(headerVals) {
+		log.Warn("Invalid validator set", "block number", number, "newValidators", newValidators, "headerVals", headerVals)
+		return &SpanValidatorsMismatchError{Number: number, SpanId: validators.span.Id}
+	}
+
+	for i, val := range newValidators {
+		if !bytes.Equal(val.HeaderBytes(), headerVals[i].HeaderBytes()) {
+			log.Warn("Invalid validator set", "block number", number, "index", i, "local validator", val, "header validator", headerVals[i])
+			return &SpanValidatorsMismatchError{Number: number, SpanId: validators.span.Id}
+		}
+	}
+
+	return nil
+}
+
+// fetchSpanValidators returns the validators of the span of the given block, retrying
+// transient heimdall failures with backoff until the context is done.
+func (c *Bor) fetchSpanValidators(ctx context.Context, number uint64) (*spanValidators, error) {
+	backoff := spanVerificationBackoff
+
+	for attempt := 1; ; attempt++ {
+		validators, err := c.spanStore.validatorsByBlockNumber(ctx, number)
+		if err == nil {
+			return validators, nil
+		}
+
+		if c.HeimdallClient == nil || !isTransientSpanError(err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		log.Debug("Failed to fetch span to verify header, retrying", "number", number, "attempt", attempt, "backoff", backoff, "err", err)
+		c.spanVerificationRetries().Inc(1)
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+
+		backoff = min(2*backoff, spanVerificationMaxBackoff)
+	}
+}
+
+// isTransientSpanError reports whether fetching a span failed for a reason retrying may
+// overcome, unlike heimdall not knowing the span or serving it in an unexpected format.
+func isTransientSpanError(err error) bool {
+	return !errors.Is(err, errSpanNotFound) &&
+		!errors.Is(err, heimdall.ErrHeimdallSchemaMismatch) &&
+		!errors.Is(err, heimdall.ErrShutdownDetected)
+}
+
+// spanVerificationRetries returns the counter of the span fetch retries, falling back to
+// the registered one.
+func (c *Bor) spanVerificationRetries() *metrics.Counter {
+	if c.spanRetryCounter == nil {
+		return spanVerificationRetryCounter
+	}
+
+	return c.spanRetryCounter
+}
@@ -0,0 +1,105 @@
+package bor
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	borTypes "github.com/0xPolygon/heimdall-v2/x/bor/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall"
+	"github.com/ethereum/go-ethereum/consensus/bor/valset"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// failingSpanHeimdallClient fails to serve the first spans requested with the given error.
+type failingSpanHeimdallClient struct {
+	MockHeimdallClientWithValidators
+	failures atomic.Int64 // Number of span requests left to fail
+	err      error
+}
+
+func (h *failingSpanHeimdallClient) GetSpan(ctx context.Context, spanID uint64) (*borTypes.Span, error) {
+	if h.failures.Add(-1) >= 0 {
+		return nil, h.err
+	}
+
+	return h.MockHeimdallClientWithValidators.GetSpan(ctx, spanID)
+}
+
+func newSpanVerificationTestEngine(failures int64, err error) *Bor {
+	client := &failingSpanHeimdallClient{err: err}
+	client.failures.Store(failures)
+
+	return &Bor{
+		chainConfig:      &params.ChainConfig{ChainID: big.NewInt(1337)},
+		config:           &params.BorConfig{Sprint: map[string]uint64{"0": 16}},
+		HeimdallClient:   client,
+		spanStore:        NewSpanStore(client, nil, "1337", nil),
+		spanRetryCounter: metrics.NewCounter(),
+	}
+}
+
+// rotationHeader returns the last header of span 1, whose extra data embeds the given
+// producers of span 2.
+func rotationHeader(producers ...common.Address) *types.Header {
+	extra := make([]byte, types.ExtraVanityLength)
+	for _, producer := range producers {
+		extra = append(extra, (&valset.Validator{Address: producer, VotingPower: 100}).HeaderBytes()...)
+	}
+
+	extra = append(extra, make([]byte, types.ExtraSealLength)...)
+
+	return &types.Header{Number: big.NewInt(6655), Extra: extra}
+}
+
+func TestVerifySpanValidatorsFlakyHeimdall(t *testing.T) {
+	t.Parallel()
+
+	producer := mockValidatorAddress(2) // Producer of span 2, blocks 6656 to 13055
+
+	// Transient failures are retried until heimdall serves the span
+	engine := newSpanVerificationTestEngine(2, heimdall.ErrServiceUnavailable)
+	require.NoError(t, engine.verifySpanValidators(context.Background(), rotationHeader(producer)))
+	require.Equal(t, int64(2), engine.spanRetryCounter.Snapshot().Count())
+
+	// A mismatch is reported as such once the span is served
+	engine = newSpanVerificationTestEngine(1, heimdall.ErrServiceUnavailable)
+	err := engine.verifySpanValidators(context.Background(), rotationHeader(mockValidatorAddress(3)))
+
+	var mismatch *SpanValidatorsMismatchError
+	require.True(t, errors.As(err, &mismatch), "unexpected error: %v", err)
+	require.Equal(t, &SpanValidatorsMismatchError{Number: 6655, SpanId: 2}, mismatch)
+	require.ErrorIs(t, err, errInvalidSpanValidators)
+	require.NotErrorIs(t, err, consensus.ErrVerificationDataUnavailable)
+
+	// The header isn't rejected as invalid if heimdall stays unreachable
+	engine = newSpanVerificationTestEngine(1000, heimdall.ErrServiceUnavailable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = engine.verifySpanValidators(ctx, rotationHeader(producer))
+
+	var unavailable *SpanUnavailableError
+	require.True(t, errors.As(err, &unavailable), "unexpected error: %v", err)
+	require.Equal(t, uint64(6655), unavailable.Number)
+	require.ErrorIs(t, err, consensus.ErrVerificationDataUnavailable)
+	require.ErrorIs(t, err, heimdall.ErrServiceUnavailable)
+	require.NotErrorIs(t, err, errInvalidSpanValidators)
+
+	// Spans unknown to heimdall aren't retried
+	engine = newSpanVerificationTestEngine(1000, heimdall.ErrNotFound)
+	err = engine.verifySpanValidators(context.Background(), rotationHeader(producer))
+	require.ErrorIs(t, err, consensus.ErrVerificationDataUnavailable)
+	require.ErrorIs(t, err, errSpanNotFound)
+	require.Zero(t, engine.spanRetryCounter.Snapshot().Count())
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/log"
@@ -17,7 +18,10 @@ import (
 	"github.com/holiman/uint256"
 )
 
-var systemAddress = common.HexToAddress("0xffffFFFfFFffffffffffffffFfFFFfffFFFfFFfE")
+// systemAddress is the sender of the bor system calls. User transactions from it are
+// rejected by the txpool, and by the block processing from the SystemSenderCheck fork
+// on (core.ErrSystemSender).
+var systemAddress = params.SystemAddress
 
 type ChainContext struct {
 	Chain consensus.ChainHeaderReader
@@ -50,6 +54,17 @@ func (m Callmsg) Gas() uint64          { return m.CallMsg.Gas }
 func (m Callmsg) Value() *big.Int      { return m.CallMsg.Value }
 func (m Callmsg) Data() []byte         { return m.CallMsg.Data }
 
+// SystemCallTracer is implemented by the state databases tracing the system messages
+// applied on them. As the system messages are applied deep inside the consensus engine,
+// the state is what threads the tracer through to ApplyMessage.
+type SystemCallTracer interface {
+	vm.StateDB
+
+	// SystemCallHooks returns the hooks to trace the execution of the given message
+	// with, or nil to not trace it.
+	SystemCallHooks(msg Callmsg) *tracing.Hooks
+}
+
 // get system message
 func GetSystemMessage(toAddress common.Address, data []byte) Callmsg {
 	return Callmsg{
@@ -78,9 +93,24 @@ func ApplyMessage(
 	// Create a new context to be used in the EVM environment
 	blockContext := core.NewEVMBlockContext(header, chainContext, &header.Coinbase)
 
+	var hooks *tracing.Hooks
+	if tracer, ok := state.(SystemCallTracer); ok {
+		hooks = tracer.SystemCallHooks(msg)
+	}
+
 	// Create a new environment which holds all relevant information
 	// about the transaction and calling mechanisms.
-	vmenv := vm.NewEVM(blockContext, state, chainConfig, vm.Config{})
+	vmenv := vm.NewEVM(blockContext, state, chainConfig, vm.Config{Tracer: hooks})
+
+	if hooks != nil && hooks.OnTxStart != nil {
+		hooks.OnTxStart(vmenv.GetVMContext(), types.NewTx(&types.LegacyTx{
+			To:       msg.To(),
+			Gas:      msg.Gas(),
+			GasPrice: msg.GasPrice(),
+			Value:    msg.Value(),
+			Data:     msg.Data(),
+		}), msg.From())
+	}
 
 	// nolint : contextcheck
 	// Apply the transaction to the current state (included in the env)
@@ -115,6 +145,10 @@ func ApplyMessage(
 
 	gasUsed := initialGas - gasLeft
 
+	if hooks != nil && hooks.OnTxEnd != nil {
+		hooks.OnTxEnd(&types.Receipt{GasUsed: gasUsed}, err)
+	}
+
 	return gasUsed, nil
 }
 
@@ -44,4 +44,9 @@ var (
 
 	// ErrUnexpectedRequests is returned if a pre-Shanghai block has requests.
 	ErrUnexpectedRequests = errors.New("unexpected requests")
+
+	// ErrVerificationDataUnavailable is returned when a block can't be verified because
+	// data it is checked against, and which isn't part of the chain, is unavailable. The
+	// block isn't known to be invalid, so the peer which served it isn't at fault.
+	ErrVerificationDataUnavailable = errors.New("verification data unavailable")
 )
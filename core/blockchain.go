@@ -38,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/prque"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/blockstm"
 	"github.com/ethereum/go-ethereum/core/history"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -123,6 +124,8 @@ const (
 	maxFutureBlocks     = 256
 	maxTimeFutureBlocks = 30
 
+	borReceiptsMissCacheLimit = 4096
+
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	//
 	// Changelog:
@@ -171,6 +174,7 @@ type CacheConfig struct {
 	TriesInMemory       uint64        // Number of recent tries to keep in memory
 	StateHistory        uint64        // Number of blocks from head whose state histories are reserved.
 	StateScheme         string        // Scheme used to store ethereum states and merkle tree nodes on top
+	StateSyncIndex      bool          // Whether to index the state sync events committed in the chain (bor only)
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
@@ -301,17 +305,28 @@ type BlockChain struct {
 	processor                    Processor // Block transaction processor interface
 	parallelProcessor            Processor // Parallel block transaction processor interface
 	parallelSpeculativeProcesses int       // Number of parallel speculative processes
+	parallelStatsSampleRate      uint64    // Collect parallel execution stats for 1 out of N blocks
+	parallelMVHashMapShards      int       // Number of shards of the multi-version hash map of the parallel processor
 	enforceParallelProcessor     bool
+	executionDecisions           executionDecisions     // Parallel or serial execution of the recently processed blocks
+	hotContracts                 *blockstm.HotContracts // Contracts whose txs are chained upfront by the parallel processor
 	forker                       *ForkChoice
 	vmConfig                     vm.Config
 	logger                       *tracing.Hooks
 
 	// Bor related changes
-	borReceiptsCache *lru.Cache[common.Hash, *types.Receipt] // Cache for the most recent bor receipt receipts per block
-	stateSyncData    []*types.StateSyncData                  // State sync data
-	stateSyncFeed    event.Feed                              // State sync feed
-	chain2HeadFeed   event.Feed                              // Reorg/NewHead/Fork data feed
-	chainSideFeed    event.Feed                              // Side chain data feed (removed from geth but needed in bor)
+	borReceiptsCache     *lru.Cache[common.Hash, *types.Receipt] // Cache for the most recent bor receipt receipts per block
+	borReceiptsMissCache *lru.Cache[common.Hash, struct{}]       // Cache for the block hashes recently found without bor receipt
+	borReceiptsMissLock  sync.Mutex                              // Lock ordering the misses with the writes of bor receipts
+	stateSyncData        []*types.StateSyncData                  // State sync data
+	stateSyncFeed        event.Feed                              // State sync feed
+	chain2HeadFeed       event.Feed                              // Reorg/NewHead/Fork data feed
+	chainSideFeed        event.Feed                              // Side chain data feed (removed from geth but needed in bor)
+	stateSyncIndexer     *stateSyncIndexer                       // State sync event indexer, nil if disabled or the chain doesn't commit state syncs
+	witnessFeed          event.Feed                              // Generated and injected witness feed
+	witnessScope         event.SubscriptionScope                 // Witness subscriptions, tracked apart to skip encoding without them
+	witnessEvents        chan witnessEventTask                   // Generated witnesses waiting to be recorded and published
+	witnessResults       *witnessResultCache                     // Recent stateless execution results, to execute each block and witness once
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -370,8 +385,11 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		engine:        engine,
 		vmConfig:      vmConfig,
 
-		borReceiptsCache: lru.NewCache[common.Hash, *types.Receipt](receiptsCacheLimit),
-		logger:           vmConfig.Tracer,
+		borReceiptsCache:     lru.NewCache[common.Hash, *types.Receipt](receiptsCacheLimit),
+		borReceiptsMissCache: lru.NewCache[common.Hash, struct{}](borReceiptsMissCacheLimit),
+		witnessResults:       newWitnessResultCache(),
+		witnessEvents:        make(chan witnessEventTask, witnessEventQueueSize),
+		logger:               vmConfig.Tracer,
 	}
 
 	bc.hc, err = NewHeaderChain(db, chainConfig, engine, bc.insertStopped)
@@ -566,16 +584,24 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 
 		rawdb.WriteChainConfig(db, genesisHash, chainConfig)
 	}
+	// Start recording and publishing the witnesses generated while importing blocks.
+	bc.wg.Add(1)
+	go bc.witnessEventLoop()
+
 	// Start tx indexer if it's enabled.
 	if txLookupLimit != nil {
 		bc.txIndexer = newTxIndexer(*txLookupLimit, bc)
 	}
+	// Start the state sync event indexer if it's enabled and the chain commits state syncs.
+	if cacheConfig.StateSyncIndex && bc.chainConfig.Bor != nil && bc.chainConfig.Bor.StateReceiverContract != "" {
+		bc.stateSyncIndexer = newStateSyncIndexer(bc.chainConfig.Bor, bc)
+	}
 
 	return bc, nil
 }
 
 // NewParallelBlockChain , similar to NewBlockChain, creates a new blockchain object, but with a parallel state processor
-func NewParallelBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis, overrides *ChainOverrides, engine consensus.Engine, vmConfig vm.Config, shouldPreserve func(header *types.Header) bool, txLookupLimit *uint64, checker ethereum.ChainValidator, numprocs int, enforce bool) (*BlockChain, error) {
+func NewParallelBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis, overrides *ChainOverrides, engine consensus.Engine, vmConfig vm.Config, shouldPreserve func(header *types.Header) bool, txLookupLimit *uint64, checker ethereum.ChainValidator, numprocs int, enforce bool, statsSampleRate uint64, mvhShards int) (*BlockChain, error) {
 	bc, err := NewBlockChain(db, cacheConfig, genesis, overrides, engine, vmConfig, shouldPreserve, txLookupLimit, checker)
 
 	if err != nil {
@@ -585,10 +611,55 @@ func NewParallelBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis
 	bc.parallelProcessor = NewParallelStateProcessor(bc.chainConfig, bc, engine)
 	bc.parallelSpeculativeProcesses = numprocs
 	bc.enforceParallelProcessor = enforce
+	bc.parallelStatsSampleRate = statsSampleRate
+	bc.parallelMVHashMapShards = mvhShards
+
+	if mvhShards <= 0 {
+		bc.parallelMVHashMapShards = blockstm.DefaultMVHashMapShards
+	}
 
 	return bc, nil
 }
 
+// SetHotContracts sets the contracts whose transactions are chained upfront by the
+// parallel processor, as they are likely to conflict. If learn is set, the contracts
+// conflicting in the executed blocks are marked as hot too, starting from the ones
+// learned before the last shutdown.
+func (bc *BlockChain) SetHotContracts(contracts []common.Address, learn bool) {
+	hotContracts := blockstm.NewHotContracts(contracts, learn)
+
+	if learn {
+		learned := make(map[common.Address]uint64)
+		for _, contract := range rawdb.ReadHotContracts(bc.db) {
+			learned[contract.Address] = contract.Score
+		}
+
+		hotContracts.SetLearned(learned)
+	}
+
+	bc.hotContracts = hotContracts
+}
+
+// writeHotContracts persists the hot contracts learned by the parallel processor.
+func (bc *BlockChain) writeHotContracts() {
+	if bc.hotContracts == nil || !bc.hotContracts.Learning() {
+		return
+	}
+
+	learned := bc.hotContracts.Learned()
+
+	contracts := make([]rawdb.HotContract, 0, len(learned))
+	for addr, score := range learned {
+		contracts = append(contracts, rawdb.HotContract{Address: addr, Score: score})
+	}
+
+	slices.SortFunc(contracts, func(a, b rawdb.HotContract) int {
+		return a.Address.Cmp(b.Address)
+	})
+
+	rawdb.WriteHotContracts(bc.db, contracts)
+}
+
 func (bc *BlockChain) ProcessBlock(block *types.Block, parent *types.Header, witness *stateless.Witness) (_ types.Receipts, _ []*types.Log, _ uint64, _ *state.StateDB, vtime time.Duration, blockEndErr error) {
 	// Process the block using processor and parallelProcessor at the same time, take the one which finishes first, cancel the other, and return the result
 	ctx, cancel := context.WithCancel(context.Background())
@@ -618,6 +689,7 @@ func (bc *BlockChain) ProcessBlock(block *types.Block, parent *types.Header, wit
 		statedb  *state.StateDB
 		counter  *metrics.Counter
 		parallel bool
+		decision *ExecutionDecision
 	}
 
 	var resultChanLen int = 2
@@ -650,7 +722,7 @@ func (bc *BlockChain) ProcessBlock(block *types.Block, parent *types.Header, wit
 			if res == nil {
 				res = &ProcessResult{}
 			}
-			resultChan <- Result{res.Receipts, res.Logs, res.GasUsed, err, parallelStatedb, blockExecutionParallelCounter, true}
+			resultChan <- Result{res.Receipts, res.Logs, res.GasUsed, err, parallelStatedb, blockExecutionParallelCounter, true, res.decision}
 		}()
 	}
 
@@ -675,25 +747,60 @@ func (bc *BlockChain) ProcessBlock(block *types.Block, parent *types.Header, wit
 			if res == nil {
 				res = &ProcessResult{}
 			}
-			resultChan <- Result{res.Receipts, res.Logs, res.GasUsed, err, statedb, blockExecutionSerialCounter, false}
+			resultChan <- Result{res.Receipts, res.Logs, res.GasUsed, err, statedb, blockExecutionSerialCounter, false, res.decision}
 		}()
 	}
 
 	result := <-resultChan
 
+	var (
+		parallelFailed bool
+		skipped        *parallelSkippedError
+	)
+
 	if result.parallel && result.err != nil {
-		log.Warn("Parallel state processor failed", "err", result.err)
-		blockExecutionParallelErrorCounter.Inc(1)
-		// If the parallel processor failed, we will fallback to the serial processor if enabled
+		if !errors.As(result.err, &skipped) {
+			log.Warn("Parallel state processor failed", "err", result.err)
+			blockExecutionParallelErrorCounter.Inc(1)
+		}
+		// If the parallel processor failed or skipped the block, we will fallback to the serial processor if enabled
 		if processorCount == 2 {
 			result = <-resultChan
 			result.statedb.StopPrefetcher()
 			processorCount--
+			parallelFailed = true
 		}
 	}
 
 	result.counter.Inc(1)
 
+	// Record whether the block ended up being executed in parallel or serially
+	if result.err == nil {
+		decision := result.decision
+
+		switch {
+		case decision != nil:
+		case bc.parallelProcessor == nil:
+			decision = newSerialDecision(block, serialReasonDisabled)
+		case skipped != nil:
+			decision = newSerialDecision(block, skipped.reason)
+		case parallelFailed:
+			decision = newSerialDecision(block, serialReasonParallelError)
+		case !result.parallel:
+			decision = newSerialDecision(block, serialReasonSerialFirst)
+		default:
+			decision = &ExecutionDecision{
+				Number:  block.NumberU64(),
+				Hash:    block.Hash(),
+				Mode:    ExecutionModeParallel,
+				Txs:     len(block.Transactions()),
+				Workers: bc.parallelSpeculativeProcesses,
+			}
+		}
+
+		bc.executionDecisions.add(decision)
+	}
+
 	// Make sure we are not leaking any prefetchers
 	if processorCount == 2 {
 		go func() {
@@ -1220,6 +1327,7 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 			rawdb.DeleteReceipts(db, hash, num)
 			rawdb.DeleteBorReceipt(db, hash, num)
 			rawdb.DeleteBorTxLookupEntry(db, hash, num)
+			rawdb.DeleteStateSyncL1Block(bc.db, db, hash, num)
 		}
 		// Todo(rjl493456442) txlookup, log index, etc
 	}
@@ -1247,6 +1355,7 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 	bc.blockCache.Purge()
 	bc.txLookupCache.Purge()
 	bc.borReceiptsCache.Purge()
+	bc.borReceiptsMissCache.Purge()
 
 	// Clear safe block, finalized block if needed
 	if safe := bc.CurrentSafeBlock(); safe != nil && head < safe.Number.Uint64() {
@@ -1347,7 +1456,8 @@ func (bc *BlockChain) Export(w io.Writer) error {
 	return bc.ExportN(w, uint64(0), bc.CurrentBlock().Number.Uint64())
 }
 
-// ExportN writes a subset of the active chain to the given writer.
+// ExportN writes a subset of the active chain to the given writer. On bor chains, each
+// block is followed by its bor receipt, see ChainImportStream.
 func (bc *BlockChain) ExportN(w io.Writer, first uint64, last uint64) error {
 	if first > last {
 		return fmt.Errorf("export failed: first (%d) is greater than last (%d)", first, last)
@@ -1355,6 +1465,14 @@ func (bc *BlockChain) ExportN(w io.Writer, first uint64, last uint64) error {
 
 	log.Info("Exporting batch of blocks", "count", last-first+1)
 
+	// Bor chains export the bor receipt of each block along with it
+	bor := bc.chainConfig.Bor != nil
+	if bor {
+		if err := writeBorExportMarker(w); err != nil {
+			return err
+		}
+	}
+
 	var (
 		parentHash common.Hash
 		start      = time.Now()
@@ -1377,6 +1495,12 @@ func (bc *BlockChain) ExportN(w io.Writer, first uint64, last uint64) error {
 			return err
 		}
 
+		if bor {
+			if err := bc.exportBorReceipt(w, block); err != nil {
+				return err
+			}
+		}
+
 		if time.Since(reported) >= statsReportLimit {
 			log.Info("Exporting blocks", "exported", block.NumberU64()-first, "elapsed", common.PrettyDuration(time.Since(start)))
 			reported = time.Now()
@@ -1413,6 +1537,9 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 
 	bc.currentBlock.Store(block.Header())
 	headBlockGauge.Update(int64(block.NumberU64()))
+
+	// The block is canonical, its witness doesn't need to be verified anymore
+	bc.witnessResults.evict(block.Hash())
 }
 
 // stopWithoutSaving stops the blockchain service. If any imports are currently in progress
@@ -1429,8 +1556,13 @@ func (bc *BlockChain) stopWithoutSaving() {
 	if bc.txIndexer != nil {
 		bc.txIndexer.close()
 	}
+	// Signal shutdown state sync event indexer.
+	if bc.stateSyncIndexer != nil {
+		bc.stateSyncIndexer.close()
+	}
 	// Unsubscribe all subscriptions registered from blockchain.
 	bc.scope.Close()
+	bc.witnessScope.Close()
 
 	// Signal shutdown to all goroutines.
 	close(bc.quit)
@@ -1451,6 +1583,9 @@ func (bc *BlockChain) stopWithoutSaving() {
 func (bc *BlockChain) Stop() {
 	bc.stopWithoutSaving()
 
+	// Persist the hot contracts learned by the parallel processor
+	bc.writeHotContracts()
+
 	// Ensure that the entirety of the state snapshot is journaled to disk.
 	var snapBase common.Hash
 
@@ -1798,6 +1933,10 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 				return 0, err
 			}
 		}
+		// The bodies complete the bor receipts looked up before they were written
+		for _, block := range blockChain {
+			bc.forgetBorReceiptMisses(block.Hash())
+		}
 
 		updateHead(blockChain[len(blockChain)-1], headers)
 
@@ -1918,22 +2057,30 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 			// DeriveFieldsForBorLogs will fill those fields for websocket subscriptions
 			types.DeriveFieldsForBorLogs(stateSyncLogs, block.Hash(), block.NumberU64(), uint(len(receipts)), uint(len(logs)))
 
-			// Write bor receipt
-			rawdb.WriteBorReceipt(blockBatch, block.Hash(), block.NumberU64(), &types.ReceiptForStorage{
+			// Write bor receipt along with the bor tx and state sync reverse lookups
+			// into the block batch, so they're persisted atomically with the block
+			rawdb.WriteBorBlockData(blockBatch, block.Hash(), block.NumberU64(), &types.ReceiptForStorage{
 				Status: types.ReceiptStatusSuccessful, // make receipt status successful
 				Logs:   stateSyncLogs,
-			})
-
-			// Write bor tx reverse lookup
-			rawdb.WriteBorTxLookupEntry(blockBatch, block.Hash(), block.NumberU64())
+			}, bc.stateSyncData)
 		}
 	}
 
 	rawdb.WritePreimages(blockBatch, statedb.Preimages())
 
+	// Persist the pending validator snapshots of the engine atomically with the block
+	if flusher, ok := bc.engine.(BorSnapshotFlusher); ok {
+		if err := flusher.FlushSnapshots(blockBatch); err != nil {
+			return []*types.Log{}, err
+		}
+	}
+
 	if err := blockBatch.Write(); err != nil {
 		log.Crit("Failed to write block into disk", "err", err)
 	}
+	// The bor receipt of the block may have been looked up before it was written
+	bc.forgetBorReceiptMisses(block.Hash())
+
 	// Commit all cached state changes into underlying memory database.
 	root, err := statedb.Commit(block.NumberU64(), bc.chainConfig.IsEIP158(block.Number()), bc.chainConfig.IsCancun(block.Number()))
 	if err != nil {
@@ -2289,6 +2436,15 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool, makeWitness
 		// If there are any still remaining, mark as ignored
 		return nil, it.index, err
 
+	// The first block couldn't be verified for lack of data (e.g. heimdall being
+	// unreachable), abort without reporting it as bad as it isn't known to be.
+	case errors.Is(err, consensus.ErrVerificationDataUnavailable):
+		stats.ignored += len(it.chain)
+
+		log.Warn("Unable to verify block, aborting import", "number", block.Number(), "hash", block.Hash(), "err", err)
+
+		return nil, it.index, err
+
 	// Some other error(except ErrKnownBlock) occurred, abort.
 	// ErrKnownBlock is allowed here since some known blocks
 	// still need re-execution to generate snapshots that are missing
@@ -2465,6 +2621,10 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool, makeWitness
 			return nil, it.index, err
 		}
 
+		if witness != nil {
+			bc.queueWitnessEvent(block, witness, time.Since(pstart))
+		}
+
 		// BOR state sync feed related changes
 		for _, data := range bc.stateSyncData {
 			bc.stateSyncFeed.Send(StateSyncEvent{Data: data})
@@ -2537,6 +2697,10 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool, makeWitness
 		stats.processed++
 		stats.usedGas += usedGas
 
+		if bc.parallelProcessor != nil {
+			stats.recordExecution(bc.executionDecisions.get(block.Hash()))
+		}
+
 		var snapDiffItems, snapBufItems common.StorageSize
 		if bc.snaps != nil {
 			snapDiffItems, snapBufItems = bc.snaps.Size()
@@ -2670,18 +2834,12 @@ func (bc *BlockChain) processBlock(block *types.Block, statedb *state.StateDB, s
 	if witness := statedb.Witness(); witness != nil && bc.vmConfig.StatelessSelfValidation {
 		log.Warn("Running stateless self-validation", "block", block.Number(), "hash", block.Hash())
 
-		// Remove critical computed fields from the block to force true recalculation
-		context := block.Header()
-		context.Root = common.Hash{}
-		context.ReceiptHash = common.Hash{}
-
-		task := types.NewBlockWithHeader(context).WithBody(*block.Body())
-
 		// Run the stateless self-cross-validation
-		crossStateRoot, crossReceiptRoot, err := ExecuteStateless(bc.chainConfig, bc.vmConfig, task, witness)
+		crossStateRoot, crossReceiptRoot, usage, err := bc.ExecuteWitness(context.Background(), block, witness)
 		if err != nil {
 			return nil, fmt.Errorf("stateless self-validation failed: %v", err)
 		}
+		log.Debug("Stateless self-validation witness usage", "block", block.Number(), "unused", len(usage.UnusedNodes), "bytes", usage.UnusedBytes)
 		if crossStateRoot != block.Root() {
 			return nil, fmt.Errorf("stateless self-validation root mismatch (cross: %x local: %x)", crossStateRoot, block.Root())
 		}
@@ -2954,15 +3112,17 @@ func (bc *BlockChain) collectLogs(b *types.Block, removed bool) []*types.Log {
 	}
 	receipts := rawdb.ReadRawReceipts(bc.db, b.Hash(), b.NumberU64())
 
-	// Append bor receipt
+	if err := receipts.DeriveFields(bc.chainConfig, b.Hash(), b.NumberU64(), b.Time(), b.BaseFee(), blobGasPrice, b.Transactions()); err != nil {
+		log.Error("Failed to derive block receipts fields", "hash", b.Hash(), "number", b.NumberU64(), "err", err)
+	}
+
+	// Append bor receipt after deriving the fields of the others, as it has no
+	// matching transaction. Its fields are derived when it's read.
 	borReceipt := rawdb.ReadBorReceipt(bc.db, b.Hash(), b.NumberU64(), bc.chainConfig)
 	if borReceipt != nil {
 		receipts = append(receipts, borReceipt)
 	}
 
-	if err := receipts.DeriveFields(bc.chainConfig, b.Hash(), b.NumberU64(), b.Time(), b.BaseFee(), blobGasPrice, b.Transactions()); err != nil {
-		log.Error("Failed to derive block receipts fields", "hash", b.Hash(), "number", b.NumberU64(), "err", err)
-	}
 	var logs []*types.Log
 
 	for _, receipt := range receipts {
@@ -3154,6 +3314,14 @@ func (bc *BlockChain) reorg(oldHead *types.Header, newHead *types.Header) error
 	for _, tx := range types.HashDifference(deletedTxs, rebirthTxs) {
 		rawdb.DeleteTxLookupEntry(batch, tx)
 	}
+	// Swap the state sync L1 lookups of the reorged out blocks for those of the new
+	// canonical ones, which may have been dropped when they were reorged out before.
+	for _, header := range oldChain {
+		rawdb.DeleteStateSyncL1Lookups(bc.db, batch, header.Hash(), header.Number.Uint64())
+	}
+	for _, header := range newChain {
+		rawdb.WriteStateSyncL1Lookups(bc.db, batch, header.Hash(), header.Number.Uint64())
+	}
 	// Delete all hash markers that are not part of the new canonical chain.
 	// Because the reorg function does not handle new chain head, all hash
 	// markers greater than or equal to new chain head should be deleted.
@@ -2,6 +2,8 @@ package core
 
 import (
 	"math/big"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/triedb"
 )
@@ -141,3 +144,279 @@ func TestChain2HeadEvent(t *testing.T) {
 			replacementBlocks[3].Hash(),
 		}})
 }
+
+func TestGetStateSyncL1Lookups(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db        = rawdb.NewMemoryDatabase()
+		bc        = &BlockChain{db: db}
+		l1Hash    = common.HexToHash("0x01")
+		canonical = common.HexToHash("0x10")
+		fork      = common.HexToHash("0x11")
+	)
+
+	rawdb.WriteCanonicalHash(db, canonical, 64)
+
+	// The same events were committed in a canonical and a side chain block
+	rawdb.WriteStateSyncL1Lookup(db, l1Hash, canonical, 64, 7)
+	rawdb.WriteStateSyncL1Lookup(db, l1Hash, fork, 64, 7)
+	rawdb.WriteStateSyncL1Lookup(db, l1Hash, canonical, 64, 8)
+
+	lookups := bc.GetStateSyncL1Lookups(l1Hash)
+	if len(lookups) != 2 {
+		t.Fatalf("lookup count mismatch: have %d, want 2", len(lookups))
+	}
+
+	for i, id := range []uint64{7, 8} {
+		want := rawdb.StateSyncL1Lookup{BlockHash: canonical, BlockNumber: 64, EventID: id}
+		if lookups[i] != want {
+			t.Errorf("lookup %d mismatch: have %+v, want %+v", i, lookups[i], want)
+		}
+	}
+
+	// Reorg the side chain block in
+	rawdb.WriteCanonicalHash(db, fork, 64)
+
+	lookups = bc.GetStateSyncL1Lookups(l1Hash)
+	if len(lookups) != 1 || lookups[0].BlockHash != fork || lookups[0].EventID != 7 {
+		t.Fatalf("unexpected lookups after reorg: %+v", lookups)
+	}
+}
+
+// Tests that the logs of a block committing state sync events are all derived when
+// collected on reorgs, the state sync logs being appended after the others.
+func TestCollectLogsWithStateSyncs(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{Config: params.TestChainConfig, Alloc: types.GenesisAlloc{addr: {Balance: big.NewInt(10000000000000000)}}}
+		signer = types.LatestSigner(gspec.Config)
+
+		stateReceiver = common.HexToAddress("0x0000000000000000000000000000000000001001")
+		stateSynced   = common.HexToHash("0x01")
+	)
+
+	blockchain, _ := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil, nil)
+	defer blockchain.Stop()
+
+	// Block 4 is a sprint start with a log emitting transaction
+	_, chain, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 4, func(i int, gen *BlockGen) {
+		if i == 3 {
+			tx, err := types.SignTx(types.NewContractCreation(gen.TxNonce(addr), new(big.Int), 1000000, gen.header.BaseFee, logCode), signer, key)
+			if err != nil {
+				t.Fatalf("failed to create tx: %v", err)
+			}
+
+			gen.AddTx(tx)
+		}
+	})
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+
+	block := chain[3]
+	rawdb.WriteBorReceipt(blockchain.db, block.Hash(), block.NumberU64(), &types.ReceiptForStorage{
+		Status: types.ReceiptStatusSuccessful,
+		Logs:   []*types.Log{{Address: stateReceiver, Topics: []common.Hash{stateSynced}}},
+	})
+
+	logs := blockchain.collectLogs(block, true)
+	if len(logs) != 2 {
+		t.Fatalf("log count mismatch: have %d, want 2", len(logs))
+	}
+
+	want := []struct {
+		address common.Address
+		txHash  common.Hash
+	}{
+		{crypto.CreateAddress(addr, 0), block.Transactions()[0].Hash()},
+		{stateReceiver, types.GetDerivedBorTxHash(types.BorReceiptKey(block.NumberU64(), block.Hash()))},
+	}
+
+	for i, log := range logs {
+		if log.Address != want[i].address || log.TxHash != want[i].txHash {
+			t.Errorf("log %d mismatch: have address %x tx %x, want address %x tx %x", i, log.Address, log.TxHash, want[i].address, want[i].txHash)
+		}
+
+		if log.BlockHash != block.Hash() || log.BlockNumber != block.NumberU64() || log.TxIndex != uint(i) || log.Index != uint(i) {
+			t.Errorf("log %d position not derived: %+v", i, log)
+		}
+
+		if !log.Removed {
+			t.Errorf("log %d not flagged as removed", i)
+		}
+	}
+}
+
+// Tests that the bor receipts looked up before being written are served once written,
+// either along with their block or afterwards.
+func TestBorReceiptMissCache(t *testing.T) {
+	var (
+		gspec        = &Genesis{Config: params.TestChainConfig, BaseFee: big.NewInt(params.InitialBaseFee)}
+		_, blocks, _ = GenerateChainWithGenesis(gspec, ethash.NewFaker(), 8, nil)
+		receipt      = &types.ReceiptForStorage{
+			Status: types.ReceiptStatusSuccessful,
+			Logs: []*types.Log{{
+				Address: common.HexToAddress("0x0000000000000000000000000000000000001001"),
+				Topics:  []common.Hash{StateCommittedTopic, common.BigToHash(common.Big1)},
+			}},
+		}
+	)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), DefaultCacheConfigWithScheme(rawdb.HashScheme), gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks[:7]); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	misses, hits := borReceiptMissCounter.Snapshot().Count(), borReceiptNegativeHitCounter.Snapshot().Count()
+
+	// Missing bor receipts are only looked up once in the database
+	sprintStart := blocks[3]
+	for i := 0; i < 3; i++ {
+		if chain.GetBorReceiptByHash(sprintStart.Hash()) != nil {
+			t.Fatalf("unexpected bor receipt")
+		}
+	}
+	if have := borReceiptMissCounter.Snapshot().Count() - misses; have != 1 {
+		t.Errorf("miss count mismatch: have %d, want 1", have)
+	}
+	if have := borReceiptNegativeHitCounter.Snapshot().Count() - hits; have != 2 {
+		t.Errorf("negative hit count mismatch: have %d, want 2", have)
+	}
+	// A bor receipt written after the miss is served
+	if err := chain.WriteImportedBorReceipts([]*types.Block{sprintStart}, []*types.ReceiptForStorage{receipt}); err != nil {
+		t.Fatalf("failed to write bor receipt: %v", err)
+	}
+	if chain.GetBorReceiptByHash(sprintStart.Hash()) == nil {
+		t.Fatalf("bor receipt written after a miss not served")
+	}
+
+	// The bor receipt of a block looked up before its import is served once imported
+	head := blocks[7]
+	if chain.GetBorReceiptByHash(head.Hash()) != nil {
+		t.Fatalf("unexpected bor receipt of unknown block")
+	}
+	rawdb.WriteBorReceipt(chain.db, head.Hash(), head.NumberU64(), receipt)
+
+	if chain.GetBorReceiptByHash(head.Hash()) != nil {
+		t.Fatalf("missing bor receipt looked up again before the import")
+	}
+	if _, err := chain.InsertChain(blocks[7:]); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+	if chain.GetBorReceiptByHash(head.Hash()) == nil {
+		t.Fatalf("bor receipt of block imported after a miss not served")
+	}
+}
+
+// Tests that the bor receipts written while being looked up concurrently are served
+// once written, i.e. a lookup missing them doesn't cache the miss after the write.
+func TestBorReceiptMissCacheConcurrentWrite(t *testing.T) {
+	t.Parallel()
+
+	var (
+		gspec        = &Genesis{Config: params.TestChainConfig, BaseFee: big.NewInt(params.InitialBaseFee)}
+		_, blocks, _ = GenerateChainWithGenesis(gspec, ethash.NewFaker(), 32, nil)
+		receipt      = &types.ReceiptForStorage{Status: types.ReceiptStatusSuccessful}
+	)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), DefaultCacheConfigWithScheme(rawdb.HashScheme), gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	for _, block := range blocks {
+		var (
+			wg   sync.WaitGroup
+			done atomic.Bool
+		)
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for !done.Load() {
+					chain.GetBorReceiptByHash(block.Hash())
+				}
+			}()
+		}
+		if err := chain.WriteImportedBorReceipts([]*types.Block{block}, []*types.ReceiptForStorage{receipt}); err != nil {
+			t.Fatalf("failed to write bor receipt: %v", err)
+		}
+		done.Store(true)
+		wg.Wait()
+
+		if chain.GetBorReceiptByHash(block.Hash()) == nil {
+			t.Fatalf("bor receipt of block %d written during concurrent lookups not served", block.NumberU64())
+		}
+	}
+}
+
+// readCountingDB counts the database reads.
+type readCountingDB struct {
+	ethdb.Database
+	reads atomic.Int64
+}
+
+func (db *readCountingDB) Get(key []byte) ([]byte, error) {
+	db.reads.Add(1)
+	return db.Database.Get(key)
+}
+
+func (db *readCountingDB) Has(key []byte) (bool, error) {
+	db.reads.Add(1)
+	return db.Database.Has(key)
+}
+
+// Benchmarks the lookups of the bor receipts of blocks without any, with and without
+// caching the misses.
+func BenchmarkGetBorReceiptByHashMiss(b *testing.B) {
+	var (
+		gspec        = &Genesis{Config: params.TestChainConfig, BaseFee: big.NewInt(params.InitialBaseFee)}
+		_, blocks, _ = GenerateChainWithGenesis(gspec, ethash.NewFaker(), 64, nil)
+		db           = &readCountingDB{Database: rawdb.NewMemoryDatabase()}
+	)
+	chain, err := NewBlockChain(db, DefaultCacheConfigWithScheme(rawdb.HashScheme), gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil, nil)
+	if err != nil {
+		b.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		b.Fatalf("failed to insert blocks: %v", err)
+	}
+	// Look up known blocks without bor receipt and unknown hashes alike
+	hashes := make([]common.Hash, 0, 2*len(blocks))
+	for i, block := range blocks {
+		hashes = append(hashes, block.Hash(), common.BigToHash(big.NewInt(int64(i))))
+	}
+	for _, cached := range []bool{false, true} {
+		name := "uncached"
+		if cached {
+			name = "cached"
+		}
+		b.Run(name, func(b *testing.B) {
+			reads := db.reads.Load()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if !cached {
+					chain.borReceiptsMissCache.Purge()
+				}
+				chain.GetBorReceiptByHash(hashes[i%len(hashes)])
+			}
+			b.ReportMetric(float64(db.reads.Load()-reads)/float64(b.N), "reads/op")
+		})
+	}
+}
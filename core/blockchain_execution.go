@@ -0,0 +1,101 @@
+package core
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Execution modes of the blocks
+const (
+	ExecutionModeParallel = "parallel"
+	ExecutionModeSerial   = "serial"
+)
+
+// Reasons of the serial execution of blocks decided when racing the processors
+const (
+	serialReasonDisabled      = "disabled"       // No parallel processor configured
+	serialReasonSerialFirst   = "serial_first"   // Serial execution finished before the parallel one
+	serialReasonParallelError = "parallel_error" // Parallel execution failed, serial execution used instead
+)
+
+// executionDecisionsLimit is the number of recent execution decisions kept in memory
+const executionDecisionsLimit = 128
+
+var (
+	blockstmParallelBlocksCounter = metrics.NewRegisteredCounter("blockstm/blocks_parallel", nil)
+	blockstmSerialBlocksCounter   = metrics.NewRegisteredCounter("blockstm/blocks_serial", nil)
+)
+
+// ExecutionDecision records whether the transactions of a block were executed in
+// parallel (block-stm) or serially, and why.
+type ExecutionDecision struct {
+	Number   uint64      `json:"number"`
+	Hash     common.Hash `json:"hash"`
+	Mode     string      `json:"mode"`
+	Reason   string      `json:"reason,omitempty"` // Why the block was executed serially
+	Txs      int         `json:"txs"`
+	Workers  int         `json:"workers"`
+	Metadata bool        `json:"metadata"`          // Whether the dependency metadata of the block was used
+	Speedup  float64     `json:"speedup,omitempty"` // Estimated speedup of an ideal parallel execution, only for profiled blocks
+}
+
+// newSerialDecision creates the decision of executing the given block serially.
+func newSerialDecision(block *types.Block, reason string) *ExecutionDecision {
+	return &ExecutionDecision{
+		Number:  block.NumberU64(),
+		Hash:    block.Hash(),
+		Mode:    ExecutionModeSerial,
+		Reason:  reason,
+		Txs:     len(block.Transactions()),
+		Workers: 1,
+	}
+}
+
+// executionDecisions is a ring buffer of the execution decisions of the recently
+// processed blocks.
+type executionDecisions struct {
+	decisions [executionDecisionsLimit]*ExecutionDecision
+	next      int
+	lock      sync.RWMutex
+}
+
+// add records the execution decision of a block and updates the metrics.
+func (e *executionDecisions) add(decision *ExecutionDecision) {
+	if decision.Mode == ExecutionModeParallel {
+		blockstmParallelBlocksCounter.Inc(1)
+	} else {
+		blockstmSerialBlocksCounter.Inc(1)
+		metrics.GetOrRegisterCounter("blockstm/blocks_serial/"+decision.Reason, nil).Inc(1)
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.decisions[e.next] = decision
+	e.next = (e.next + 1) % executionDecisionsLimit
+}
+
+// get returns the most recent execution decision of the given block, if still kept.
+func (e *executionDecisions) get(hash common.Hash) *ExecutionDecision {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	for i := 1; i <= executionDecisionsLimit; i++ {
+		decision := e.decisions[(e.next-i+executionDecisionsLimit)%executionDecisionsLimit]
+		if decision != nil && decision.Hash == hash {
+			return decision
+		}
+	}
+
+	return nil
+}
+
+// GetExecutionDecision returns whether the given block was executed in parallel or
+// serially when it was processed. Only the decisions of the recently processed blocks
+// are kept, nil is returned for the others.
+func (bc *BlockChain) GetExecutionDecision(hash common.Hash) *ExecutionDecision {
+	return bc.executionDecisions.get(hash)
+}
@@ -28,11 +28,24 @@ import (
 // insertStats tracks and reports on block insertion.
 type insertStats struct {
 	queued, processed, ignored int
+	parallel, serial           int // Blocks executed in parallel and serially (block-stm only)
 	usedGas                    uint64
 	lastIndex                  int
 	startTime                  mclock.AbsTime
 }
 
+// recordExecution counts the block with the given execution decision as executed in
+// parallel or serially.
+func (st *insertStats) recordExecution(decision *ExecutionDecision) {
+	switch {
+	case decision == nil:
+	case decision.Mode == ExecutionModeParallel:
+		st.parallel++
+	default:
+		st.serial++
+	}
+}
+
 // statsReportLimit is the time limit during import and export after which we
 // always print out progress. This avoids the user wondering what's going on.
 const statsReportLimit = 8 * time.Second
@@ -75,6 +88,10 @@ func (st *insertStats) report(chain []*types.Block, index int, snapDiffItems, sn
 		}
 		context = append(context, []interface{}{"triedirty", triebufNodes}...)
 
+		if st.parallel > 0 || st.serial > 0 {
+			context = append(context, []interface{}{"parallel", st.parallel, "serial", st.serial}...)
+		}
+
 		if st.queued > 0 {
 			context = append(context, []interface{}{"queued", st.queued}...)
 		}
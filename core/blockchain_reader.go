@@ -539,6 +539,12 @@ type BorStateSyncer interface {
 	SubscribeStateSyncEvent(ch chan<- StateSyncEvent) event.Subscription
 }
 
+// BorSnapshotFlusher is implemented by consensus engines persisting their validator
+// snapshots along with the blocks.
+type BorSnapshotFlusher interface {
+	FlushSnapshots(db ethdb.KeyValueWriter) error
+}
+
 // SetStateSync set sync data in state_data
 func (bc *BlockChain) SetStateSync(stateData []*types.StateSyncData) {
 	bc.stateSyncData = stateData
@@ -552,3 +558,8 @@ func (bc *BlockChain) GetStateSync() []*types.StateSyncData {
 func (bc *BlockChain) SubscribeStateSyncEvent(ch chan<- StateSyncEvent) event.Subscription {
 	return bc.scope.Track(bc.stateSyncFeed.Subscribe(ch))
 }
+
+// SubscribeWitnessEvent registers a subscription of WitnessEvent.
+func (bc *BlockChain) SubscribeWitnessEvent(ch chan<- WitnessEvent) event.Subscription {
+	return bc.witnessScope.Track(bc.witnessFeed.Subscribe(ch))
+}
@@ -1,6 +1,7 @@
 package blockstm
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -176,6 +177,25 @@ func (d DAG) LongestPath(stats map[int]ExecutionStat) ([]int, uint64) {
 	return path, maxPathWeight
 }
 
+// CriticalPathRatio returns the ratio (in percent) of the total serial execution time
+// to the execution time of the longest path in the DAG. It approximates the speedup an
+// ideal parallel execution of the block could achieve. Zero is returned if no execution
+// time was recorded.
+func (d DAG) CriticalPathRatio(stats map[int]ExecutionStat) uint64 {
+	_, weight := d.LongestPath(stats)
+	if weight == 0 {
+		return 0
+	}
+
+	serialWeight := uint64(0)
+
+	for i := 0; i < len(d.GetVertices()); i++ {
+		serialWeight += stats[i].End - stats[i].Start
+	}
+
+	return serialWeight * 100 / weight
+}
+
 func (d DAG) Report(stats map[int]ExecutionStat, out func(string)) {
 	longestPath, weight := d.LongestPath(stats)
 
@@ -198,4 +218,35 @@ func (d DAG) Report(stats map[int]ExecutionStat, out func(string)) {
 
 	out(fmt.Sprintf("Longest path ideal execution time: %v of %v (serial total), %v%%", time.Duration(weight),
 		time.Duration(serialWeight), fmt.Sprintf("%.1f", float64(weight)*100.0/float64(serialWeight))))
+
+	for _, worker := range WorkerUtilizations(stats) {
+		out(fmt.Sprintf("Worker %d: %d tasks, busy %v, idle %v (%.1f%% busy), %.1f%% re-executed", worker.Worker, worker.Tasks,
+			worker.Busy, worker.Idle, worker.BusyRatio*100, worker.AbortShare*100))
+	}
+}
+
+// ExecutionReport is the machine readable form of the report of a profiled execution.
+type ExecutionReport struct {
+	LongestPath       []int               `json:"longestPath"`
+	LongestPathWeight time.Duration       `json:"longestPathWeight"`
+	SerialWeight      time.Duration       `json:"serialWeight"`
+	Workers           []WorkerUtilization `json:"workers"`
+}
+
+// ReportJSON returns the report of a profiled execution encoded as JSON.
+func (d DAG) ReportJSON(stats map[int]ExecutionStat) ([]byte, error) {
+	longestPath, weight := d.LongestPath(stats)
+
+	serialWeight := uint64(0)
+
+	for i := 0; i < len(d.GetVertices()); i++ {
+		serialWeight += stats[i].End - stats[i].Start
+	}
+
+	return json.Marshal(&ExecutionReport{
+		LongestPath:       longestPath,
+		LongestPathWeight: time.Duration(weight),
+		SerialWeight:      time.Duration(serialWeight),
+		Workers:           WorkerUtilizations(stats),
+	})
 }
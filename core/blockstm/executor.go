@@ -3,6 +3,7 @@ package blockstm
 import (
 	"container/heap"
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -63,6 +64,12 @@ func (e ErrExecAbortError) Error() string {
 	}
 }
 
+// ErrMaxExecutionsExceeded is returned if the execution is aborted by MaxExecutionsCheck.
+var ErrMaxExecutionsExceeded = errors.New("maximum number of executions exceeded")
+
+// ErrExecutionPanicked is returned if the execution of a task panicked in a worker.
+var ErrExecutionPanicked = errors.New("task execution panicked")
+
 type ParallelExecFailedError struct {
 	Msg string
 }
@@ -160,10 +167,11 @@ func (pq *SafePriorityQueue) Len() int {
 }
 
 type ParallelExecutionResult struct {
-	TxIO    *TxnInputOutput
-	Stats   *map[int]ExecutionStat
-	Deps    *DAG
-	AllDeps map[int]map[int]bool
+	TxIO      *TxnInputOutput
+	Stats     *map[int]ExecutionStat
+	Deps      *DAG
+	AllDeps   map[int]map[int]bool
+	Conflicts map[common.Address]int // Conflicts per contract to learn the hot contracts from, nil without hot contracts
 }
 
 const numGoProcs = 1
@@ -171,7 +179,8 @@ const numGoProcs = 1
 type ParallelExecutor struct {
 	tasks []ExecTask
 
-	// Stores the execution statistics for the last incarnation of each task
+	// Stores the execution statistics for the last incarnation of each task. It is only
+	// allocated when profiling is enabled for this execution.
 	stats map[int]ExecutionStat
 
 	// Number of workers that execute transactions speculatively
@@ -194,6 +203,10 @@ type ParallelExecutor struct {
 	// Channel to signal that a transaction has finished executing
 	chResults chan struct{}
 
+	// Channel closed when the execution is aborted, so that the workers still executing a
+	// transaction don't block on delivering their result
+	chAbort chan struct{}
+
 	// A priority queue that stores the transaction index of results, so we can validate the results in order
 	resultQueue SafeQueue
 
@@ -240,6 +253,12 @@ type ParallelExecutor struct {
 	// Enable profiling
 	profile bool
 
+	// Contracts whose transactions are chained upfront, nil if disabled
+	hotContracts *HotContracts
+
+	// The hot contract called by each transaction chained upfront
+	hotContractTxs map[int]common.Address
+
 	// Worker wait group
 	workerWg sync.WaitGroup
 }
@@ -253,6 +272,12 @@ type ExecutionStat struct {
 }
 
 func NewParallelExecutor(tasks []ExecTask, profile bool, metadata bool, numProcs int) *ParallelExecutor {
+	return newParallelExecutor(tasks, profile, metadata, numProcs, DefaultMVHashMapShards)
+}
+
+// newParallelExecutor is like NewParallelExecutor, but shards the multi-version hash map
+// of the execution in the given number of shards.
+func newParallelExecutor(tasks []ExecTask, profile bool, metadata bool, numProcs int, mvhShards int) *ParallelExecutor {
 	numTasks := len(tasks)
 
 	var resultQueue SafeQueue
@@ -267,14 +292,20 @@ func NewParallelExecutor(tasks []ExecTask, profile bool, metadata bool, numProcs
 		specTaskQueue = NewSafePriorityQueue(numTasks)
 	}
 
+	var stats map[int]ExecutionStat
+	if profile {
+		stats = make(map[int]ExecutionStat, numTasks)
+	}
+
 	pe := &ParallelExecutor{
 		tasks:               tasks,
 		numSpeculativeProcs: numProcs,
-		stats:               make(map[int]ExecutionStat, numTasks),
+		stats:               stats,
 		chTasks:             make(chan ExecVersionView, numTasks),
 		chSpeculativeTasks:  make(chan struct{}, numTasks),
 		chSettle:            make(chan int, numTasks),
 		chResults:           make(chan struct{}, numTasks),
+		chAbort:             make(chan struct{}),
 		specTaskQueue:       specTaskQueue,
 		resultQueue:         resultQueue,
 		lastSettled:         -1,
@@ -283,11 +314,12 @@ func NewParallelExecutor(tasks []ExecTask, profile bool, metadata bool, numProcs
 		validateTasks:       makeStatusManager(0),
 		diagExecSuccess:     make([]int, numTasks),
 		diagExecAbort:       make([]int, numTasks),
-		mvh:                 MakeMVHashMap(),
+		mvh:                 MakeMVHashMapWithShards(mvhShards),
 		lastTxIO:            MakeTxnInputOutput(numTasks),
 		txIncarnations:      make([]int, numTasks),
 		estimateDeps:        make(map[int][]int),
 		preValidated:        make(map[int]bool),
+		hotContractTxs:      make(map[int]common.Address),
 		begin:               time.Now(),
 		profile:             profile,
 	}
@@ -298,6 +330,7 @@ func NewParallelExecutor(tasks []ExecTask, profile bool, metadata bool, numProcs
 // nolint: gocognit
 func (pe *ParallelExecutor) Prepare() error {
 	prevSenderTx := make(map[common.Address]int)
+	prevHotContractTx := make(map[common.Address]int)
 
 	for i, t := range pe.tasks {
 		clearPendingFlag := false
@@ -324,6 +357,18 @@ func (pe *ParallelExecutor) Prepare() error {
 			}
 
 			prevSenderTx[t.Sender()] = i
+
+			// Transactions calling the same hot contract are likely to conflict, chain
+			// them instead of discovering the conflicts through aborts
+			if to := pe.hotContract(t); to != nil {
+				if tx, ok := prevHotContractTx[*to]; ok {
+					pe.execTasks.addDependencies(tx, i)
+					pe.execTasks.clearPending(i)
+				}
+
+				prevHotContractTx[*to] = i
+				pe.hotContractTxs[i] = *to
+			}
 		}
 	}
 
@@ -340,14 +385,18 @@ func (pe *ParallelExecutor) Prepare() error {
 					start = time.Since(pe.begin)
 				}
 
-				res := task.Execute()
+				res := executeSafe(task)
 
 				if res.err == nil {
 					pe.mvh.FlushMVWriteSet(res.txAllOut)
 				}
 
 				pe.resultQueue.Push(res.ver.TxnIndex, res)
-				pe.chResults <- struct{}{}
+
+				select {
+				case pe.chResults <- struct{}{}:
+				case <-pe.chAbort:
+				}
 
 				if pe.profile {
 					end := time.Since(pe.begin)
@@ -400,6 +449,27 @@ func (pe *ParallelExecutor) Prepare() error {
 	return nil
 }
 
+// executeSafe executes the task, turning a panic into an ErrExecutionPanicked result
+// instead of crashing the process.
+func executeSafe(task ExecVersionView) (res ExecResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = ExecResult{ver: task.ver, err: fmt.Errorf("%w: tx %d: %v", ErrExecutionPanicked, task.ver.TxnIndex, r)}
+		}
+	}()
+
+	return task.Execute()
+}
+
+// abort stops the execution without waiting for the workers, some of which may be stuck
+// executing a transaction. The results they deliver afterwards are dropped. The settling
+// of the tasks is waited for, as it writes to the final state.
+func (pe *ParallelExecutor) abort() {
+	close(pe.chAbort)
+	pe.Close(false)
+	pe.settleWg.Wait()
+}
+
 func (pe *ParallelExecutor) Close(wait bool) {
 	close(pe.chTasks)
 	close(pe.chSpeculativeTasks)
@@ -558,16 +628,24 @@ func (pe *ParallelExecutor) Step(res *ExecResult) (result ParallelExecutionResul
 
 		pe.Close(true)
 
+		var conflicts map[common.Address]int
+		if pe.hotContracts != nil {
+			conflicts = pe.conflicts()
+		}
+
 		var allDeps map[int]map[int]bool
 
 		var deps DAG
 
+		var stats *map[int]ExecutionStat
+
 		if pe.profile {
 			allDeps = GetDep(*pe.lastTxIO)
 			deps = BuildDAG(*pe.lastTxIO)
+			stats = &pe.stats
 		}
 
-		return ParallelExecutionResult{pe.lastTxIO, &pe.stats, &deps, allDeps}, err
+		return ParallelExecutionResult{pe.lastTxIO, stats, &deps, allDeps, conflicts}, err
 	}
 
 	// Send the next immediate pending transaction to be executed
@@ -601,12 +679,25 @@ func (pe *ParallelExecutor) Step(res *ExecResult) (result ParallelExecutionResul
 
 type PropertyCheck func(*ParallelExecutor) error
 
-func executeParallelWithCheck(tasks []ExecTask, profile bool, check PropertyCheck, metadata bool, numProcs int, interruptCtx context.Context) (result ParallelExecutionResult, err error) {
+// MaxExecutionsCheck returns a property check aborting the execution once the tasks were
+// executed (including re-executions) more than factor times their number.
+func MaxExecutionsCheck(factor int) PropertyCheck {
+	return func(pe *ParallelExecutor) error {
+		if pe.cntExec > factor*len(pe.tasks) {
+			return fmt.Errorf("%w: %d executions of %d tasks", ErrMaxExecutionsExceeded, pe.cntExec, len(pe.tasks))
+		}
+
+		return nil
+	}
+}
+
+func executeParallelWithCheck(tasks []ExecTask, profile bool, check PropertyCheck, metadata bool, numProcs int, mvhShards int, hotContracts *HotContracts, interruptCtx context.Context) (result ParallelExecutionResult, err error) {
 	if len(tasks) == 0 {
-		return ParallelExecutionResult{MakeTxnInputOutput(len(tasks)), nil, nil, nil}, nil
+		return ParallelExecutionResult{MakeTxnInputOutput(len(tasks)), nil, nil, nil, nil}, nil
 	}
 
-	pe := NewParallelExecutor(tasks, profile, metadata, numProcs)
+	pe := newParallelExecutor(tasks, profile, metadata, numProcs, mvhShards)
+	pe.hotContracts = hotContracts
 	err = pe.Prepare()
 
 	if err != nil {
@@ -614,14 +705,27 @@ func executeParallelWithCheck(tasks []ExecTask, profile bool, check PropertyChec
 		return
 	}
 
-	for range pe.chResults {
-		if interruptCtx != nil && interruptCtx.Err() != nil {
-			pe.Close(true)
+	var interrupt <-chan struct{}
+	if interruptCtx != nil {
+		interrupt = interruptCtx.Done()
+	}
+
+	for {
+		// Don't wait for the next result once interrupted, the pending executions may never end
+		select {
+		case <-pe.chResults:
+		case <-interrupt:
+			pe.abort()
 			return result, interruptCtx.Err()
 		}
 
 		res := pe.resultQueue.Pop().(ExecResult)
 
+		if errors.Is(res.err, ErrExecutionPanicked) {
+			pe.abort()
+			return result, res.err
+		}
+
 		result, err = pe.Step(&res)
 
 		if err != nil {
@@ -633,13 +737,30 @@ func executeParallelWithCheck(tasks []ExecTask, profile bool, check PropertyChec
 		}
 
 		if result.TxIO != nil || err != nil {
+			// The executor is only closed on completion, stop it if the check failed earlier
+			if result.TxIO == nil {
+				pe.abort()
+			}
+
 			return result, err
 		}
 	}
-
-	return
 }
 
 func ExecuteParallel(tasks []ExecTask, profile bool, metadata bool, numProcs int, interruptCtx context.Context) (result ParallelExecutionResult, err error) {
-	return executeParallelWithCheck(tasks, profile, nil, metadata, numProcs, interruptCtx)
+	return executeParallelWithCheck(tasks, profile, nil, metadata, numProcs, DefaultMVHashMapShards, nil, interruptCtx)
+}
+
+// ExecuteParallelWithCheck is like ExecuteParallel, but runs the given check after every
+// step and aborts the execution if it fails.
+func ExecuteParallelWithCheck(tasks []ExecTask, profile bool, check PropertyCheck, metadata bool, numProcs int, interruptCtx context.Context) (result ParallelExecutionResult, err error) {
+	return executeParallelWithCheck(tasks, profile, check, metadata, numProcs, DefaultMVHashMapShards, nil, interruptCtx)
+}
+
+// ExecuteParallelWithHints is like ExecuteParallelWithCheck, but chains upfront the
+// transactions calling the same hot contract, and returns the conflicts of the execution
+// to learn the hot contracts from. They are left to the caller to learn, once per block.
+// The multi-version hash map of the execution is sharded in the given number of shards.
+func ExecuteParallelWithHints(tasks []ExecTask, profile bool, check PropertyCheck, metadata bool, numProcs int, mvhShards int, hotContracts *HotContracts, interruptCtx context.Context) (result ParallelExecutionResult, err error) {
+	return executeParallelWithCheck(tasks, profile, check, metadata, numProcs, mvhShards, hotContracts, interruptCtx)
 }
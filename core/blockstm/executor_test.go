@@ -40,6 +40,7 @@ type testExecTask struct {
 	readMap      map[Key]ReadDescriptor
 	writeMap     map[Key]WriteDescriptor
 	sender       common.Address
+	to           *common.Address
 	nonce        int
 	dependencies []int
 }
@@ -164,6 +165,10 @@ func (t *testExecTask) Sender() common.Address {
 	return t.sender
 }
 
+func (t *testExecTask) To() *common.Address {
+	return t.to
+}
+
 func (t *testExecTask) Hash() common.Hash {
 	return common.BytesToHash([]byte(fmt.Sprintf("%d", t.txIdx)))
 }
@@ -429,7 +434,7 @@ func runParallel(t *testing.T, tasks []ExecTask, validation PropertyCheck, metad
 	profile := false
 
 	start := time.Now()
-	result, err := executeParallelWithCheck(tasks, false, validation, metadata, numProcs, nil)
+	result, err := executeParallelWithCheck(tasks, false, validation, metadata, numProcs, DefaultMVHashMapShards, nil, nil)
 
 	if result.Deps != nil && profile {
 		result.Deps.Report(*result.Stats, func(str string) { fmt.Println(str) })
@@ -462,7 +467,7 @@ func runParallel(t *testing.T, tasks []ExecTask, validation PropertyCheck, metad
 func runParallelGetMetadata(t *testing.T, tasks []ExecTask, validation PropertyCheck) map[int]map[int]bool {
 	t.Helper()
 
-	res, err := executeParallelWithCheck(tasks, true, validation, false, numProcs, nil)
+	res, err := executeParallelWithCheck(tasks, true, validation, false, numProcs, DefaultMVHashMapShards, nil, nil)
 
 	assert.NoError(t, err, "error occur during parallel execution")
 
@@ -986,3 +991,88 @@ func TestBreakFromPartialCircularDependency(t *testing.T) {
 		t.Error("Expected cancel error")
 	}
 }
+
+func TestStatsCollectedOnlyWhenProfiling(t *testing.T) {
+	t.Parallel()
+	rand.New(rand.NewSource(0))
+
+	sender := func(i int) common.Address { return common.BigToAddress(big.NewInt(int64(i % 2))) }
+
+	// Non-profiled executions should not allocate any stats
+	tasks, _ := taskFactory(50, sender, 5, 5, 10, randomPathGenerator, readTime, writeTime, nonIOTime)
+	result, err := ExecuteParallel(tasks, false, false, numProcs, nil)
+	assert.NoError(t, err, "error occur during parallel execution")
+	assert.Nil(t, result.Stats, "stats should not be collected when profiling is disabled")
+
+	pe := NewParallelExecutor(tasks, false, false, numProcs)
+	assert.Nil(t, pe.stats, "stats should not be allocated when profiling is disabled")
+
+	// Profiled executions should record stats for every transaction
+	tasks, _ = taskFactory(50, sender, 5, 5, 10, randomPathGenerator, readTime, writeTime, nonIOTime)
+	result, err = ExecuteParallel(tasks, true, false, numProcs, nil)
+	assert.NoError(t, err, "error occur during parallel execution")
+	assert.NotNil(t, result.Stats, "stats should be collected when profiling is enabled")
+	assert.Len(t, *result.Stats, len(tasks), "stats should be recorded for every transaction")
+	assert.NotZero(t, result.Deps.CriticalPathRatio(*result.Stats), "critical path ratio should be computed for profiled executions")
+}
+
+func TestMaxExecutionsCheck(t *testing.T) {
+	t.Parallel()
+	rand.New(rand.NewSource(0))
+
+	sender := func(i int) common.Address { return common.BigToAddress(big.NewInt(int64(i % 2))) }
+
+	// The execution is aborted as soon as the first transaction is executed
+	tasks, _ := taskFactory(50, sender, 5, 5, 10, randomPathGenerator, readTime, writeTime, nonIOTime)
+	_, err := ExecuteParallelWithCheck(tasks, false, MaxExecutionsCheck(0), false, numProcs, nil)
+	assert.ErrorIs(t, err, ErrMaxExecutionsExceeded)
+
+	// A generous cap doesn't get in the way
+	tasks, _ = taskFactory(50, sender, 5, 5, 10, randomPathGenerator, readTime, writeTime, nonIOTime)
+	result, err := ExecuteParallelWithCheck(tasks, false, MaxExecutionsCheck(1000), false, numProcs, nil)
+	assert.NoError(t, err, "error occur during parallel execution")
+	assert.NotNil(t, result.TxIO)
+}
+
+// faultyExecTask wraps a task whose execution panics, or blocks until released.
+type faultyExecTask struct {
+	ExecTask
+	release chan struct{}
+}
+
+func (t *faultyExecTask) Execute(mvh *MVHashMap, incarnation int) error {
+	if t.release == nil {
+		panic("boom")
+	}
+
+	<-t.release
+
+	return ErrExecAbortError{Dependency: -1}
+}
+
+func TestExecutionFaults(t *testing.T) {
+	t.Parallel()
+	rand.New(rand.NewSource(0))
+
+	sender := func(i int) common.Address { return common.BigToAddress(big.NewInt(int64(i % 2))) }
+
+	// A panicking task fails the execution instead of crashing the process
+	tasks, _ := taskFactory(20, sender, 5, 5, 10, randomPathGenerator, readTime, writeTime, nonIOTime)
+	tasks[5] = &faultyExecTask{ExecTask: tasks[5]}
+
+	_, err := ExecuteParallel(tasks, false, false, numProcs, nil)
+	assert.ErrorIs(t, err, ErrExecutionPanicked)
+
+	// A hung task doesn't prevent the execution from being interrupted
+	release := make(chan struct{})
+	defer close(release)
+
+	tasks, _ = taskFactory(20, sender, 5, 5, 10, randomPathGenerator, readTime, writeTime, nonIOTime)
+	tasks[5] = &faultyExecTask{ExecTask: tasks[5], release: release}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = ExecuteParallel(tasks, false, false, numProcs, ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
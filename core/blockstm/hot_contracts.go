@@ -0,0 +1,204 @@
+package blockstm
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ContractTask is implemented by the tasks of transactions calling a contract, which may
+// be chained upfront with the other transactions calling it if it is hot.
+type ContractTask interface {
+	To() *common.Address
+}
+
+const (
+	// hotContractConflictScore is the score added to a contract for every transaction
+	// of a block conflicting on it, see conflicts.
+	hotContractConflictScore = 100
+
+	// hotContractThreshold is the score from which a learned contract is hot.
+	hotContractThreshold = 200
+
+	// hotContractMinScore is the score under which a learned contract is forgotten, the
+	// scores decaying by a 16th per block.
+	hotContractMinScore = 16
+
+	// hotContractsLimit is the maximum number of learned contracts, the ones with the
+	// lowest score are forgotten first.
+	hotContractsLimit = 256
+)
+
+// HotContracts tracks the contracts whose transactions are likely to conflict, either
+// configured or learned from the conflicts of the previous blocks. The learned scores
+// decay with every block, so that contracts which cooled down are forgotten.
+type HotContracts struct {
+	static  map[common.Address]struct{}
+	learned map[common.Address]uint64 // Scores of the learned contracts
+	learn   bool
+	lock    sync.RWMutex
+}
+
+// NewHotContracts creates a hot contract tracker with the given configured contracts,
+// which learns the hot contracts from the conflicts of the executed blocks if learn is
+// set.
+func NewHotContracts(static []common.Address, learn bool) *HotContracts {
+	h := &HotContracts{
+		static:  make(map[common.Address]struct{}, len(static)),
+		learned: make(map[common.Address]uint64),
+		learn:   learn,
+	}
+
+	for _, addr := range static {
+		h.static[addr] = struct{}{}
+	}
+
+	return h
+}
+
+// IsHot reports whether the given contract is configured or learned as hot.
+func (h *HotContracts) IsHot(addr common.Address) bool {
+	if h == nil {
+		return false
+	}
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	if _, ok := h.static[addr]; ok {
+		return true
+	}
+
+	return h.learned[addr] >= hotContractThreshold
+}
+
+// Learn decays the scores of the learned contracts and adds the given number of
+// conflicts of the executed block per contract. It does nothing if learning is disabled.
+func (h *HotContracts) Learn(conflicts map[common.Address]int) {
+	if h == nil || !h.learn {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for addr, score := range h.learned {
+		if score -= score / 16; score < hotContractMinScore {
+			delete(h.learned, addr)
+		} else {
+			h.learned[addr] = score
+		}
+	}
+
+	for addr, n := range conflicts {
+		h.learned[addr] += uint64(n) * hotContractConflictScore
+	}
+
+	if len(h.learned) > hotContractsLimit {
+		for _, addr := range sortedByScore(h.learned)[hotContractsLimit:] {
+			delete(h.learned, addr)
+		}
+	}
+}
+
+// Learning reports whether the hot contracts are learned from the conflicts.
+func (h *HotContracts) Learning() bool {
+	return h.learn
+}
+
+// Learned returns the scores of the learned contracts.
+func (h *HotContracts) Learned() map[common.Address]uint64 {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	learned := make(map[common.Address]uint64, len(h.learned))
+	for addr, score := range h.learned {
+		learned[addr] = score
+	}
+
+	return learned
+}
+
+// SetLearned replaces the scores of the learned contracts, e.g. with the ones persisted
+// by a previous run.
+func (h *HotContracts) SetLearned(learned map[common.Address]uint64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.learned = make(map[common.Address]uint64, len(learned))
+	for addr, score := range learned {
+		h.learned[addr] = score
+	}
+}
+
+// sortedByScore returns the given contracts from the highest score to the lowest one,
+// contracts with the same score being sorted by address.
+func sortedByScore(scores map[common.Address]uint64) []common.Address {
+	addrs := make([]common.Address, 0, len(scores))
+	for addr := range scores {
+		addrs = append(addrs, addr)
+	}
+
+	sort.Slice(addrs, func(i, j int) bool {
+		if scores[addrs[i]] != scores[addrs[j]] {
+			return scores[addrs[i]] > scores[addrs[j]]
+		}
+
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+
+	return addrs
+}
+
+// hotContract returns the contract called by the given task if it is hot, nil otherwise.
+func (pe *ParallelExecutor) hotContract(t ExecTask) *common.Address {
+	if pe.hotContracts == nil {
+		return nil
+	}
+
+	task, ok := t.(ContractTask)
+	if !ok {
+		return nil
+	}
+
+	if to := task.To(); to != nil && pe.hotContracts.IsHot(*to) {
+		return to
+	}
+
+	return nil
+}
+
+// conflicts returns, per contract, the number of transactions which finally read a value
+// of the contract written by a previous transaction, after being aborted. The transactions
+// chained upfront on a hot contract are counted too if they depended on it, so that the
+// contract stays hot while its conflicts are avoided.
+func (pe *ParallelExecutor) conflicts() map[common.Address]int {
+	conflicts := make(map[common.Address]int)
+
+	for tx := range pe.tasks {
+		hot, chained := pe.hotContractTxs[tx]
+		if pe.diagExecAbort[tx] == 0 && !chained {
+			continue
+		}
+
+		seen := make(map[common.Address]bool)
+
+		for _, read := range pe.lastTxIO.ReadSet(tx) {
+			if read.Kind != ReadKindMap || read.V.TxnIndex < 0 || read.V.TxnIndex >= tx {
+				continue
+			}
+
+			addr := read.Path.GetAddress()
+			if seen[addr] || (pe.diagExecAbort[tx] == 0 && addr != hot) {
+				continue
+			}
+
+			seen[addr] = true
+			conflicts[addr]++
+		}
+	}
+
+	return conflicts
+}
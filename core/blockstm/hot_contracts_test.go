@@ -0,0 +1,237 @@
+package blockstm
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var testHotContract = common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+// hotContractTasks creates transactions of distinct senders, two out of three calling
+// the given hot contract and reading then writing the same slot of it, the other ones
+// calling their own contract.
+func hotContractTasks(numTx int, hot common.Address) []ExecTask {
+	tasks := make([]ExecTask, 0, numTx)
+
+	for i := 0; i < numTx; i++ {
+		sender := common.BigToAddress(big.NewInt(int64(1000 + i)))
+
+		to := hotContractTaskCallee(i, hot)
+
+		ops := []Op{
+			{opType: readType, key: NewSubpathKey(sender, 2), duration: readTime(i, 0)},
+			{opType: writeType, key: NewSubpathKey(sender, 2), val: 1},
+			{opType: otherType, duration: 20 * time.Microsecond},
+			{opType: readType, key: NewStateKey(to, common.Hash{}), duration: readTime(i, 3)},
+			{opType: otherType, duration: 20 * time.Microsecond},
+			{opType: writeType, key: NewStateKey(to, common.Hash{}), val: i},
+		}
+
+		task := NewTestExecTask(i, ops, sender, 0)
+		task.to = &to
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks
+}
+
+// hotContractTaskCallee returns the contract called by the given transaction of
+// hotContractTasks.
+func hotContractTaskCallee(tx int, hot common.Address) common.Address {
+	if tx%3 == 2 {
+		return common.BigToAddress(big.NewInt(int64(5000 + tx)))
+	}
+
+	return hot
+}
+
+// executeHotContractTasks executes the given tasks with the given hot contracts, learns
+// from their conflicts and returns the number of aborted executions.
+func executeHotContractTasks(tasks []ExecTask, hotContracts *HotContracts) (ParallelExecutionResult, int, error) {
+	var aborts int
+
+	check := func(pe *ParallelExecutor) error {
+		aborts = pe.cntAbort
+		return nil
+	}
+
+	result, err := executeParallelWithCheck(tasks, false, check, false, numProcs, DefaultMVHashMapShards, hotContracts, nil)
+	if err == nil {
+		hotContracts.Learn(result.Conflicts)
+	}
+
+	return result, aborts, err
+}
+
+// readVersions returns the transaction which wrote each value read by each transaction.
+func readVersions(txio *TxnInputOutput, numTx int) []map[Key]int {
+	versions := make([]map[Key]int, numTx)
+
+	for tx := 0; tx < numTx; tx++ {
+		versions[tx] = make(map[Key]int)
+		for _, read := range txio.ReadSet(tx) {
+			versions[tx][read.Path] = read.V.TxnIndex
+		}
+	}
+
+	return versions
+}
+
+func TestHotContractsLearning(t *testing.T) {
+	t.Parallel()
+
+	var (
+		configured = common.Address{0x01}
+		learned    = common.Address{0x02}
+	)
+
+	h := NewHotContracts([]common.Address{configured}, true)
+	assert.True(t, h.IsHot(configured))
+	assert.False(t, h.IsHot(learned))
+
+	// A single conflict isn't enough to mark a contract as hot
+	h.Learn(map[common.Address]int{learned: 1})
+	assert.False(t, h.IsHot(learned))
+
+	h.Learn(map[common.Address]int{learned: 3})
+	assert.True(t, h.IsHot(learned))
+
+	// The score decays with the blocks without conflicts, until the contract is forgotten
+	h.Learn(nil)
+	assert.True(t, h.IsHot(learned))
+
+	for i := 0; i < 10; i++ {
+		h.Learn(nil)
+	}
+
+	assert.False(t, h.IsHot(learned))
+	assert.Contains(t, h.Learned(), learned)
+
+	for i := 0; i < 100; i++ {
+		h.Learn(nil)
+	}
+
+	assert.NotContains(t, h.Learned(), learned)
+	assert.True(t, h.IsHot(configured))
+
+	// Only the contracts with the highest scores are kept
+	conflicts := make(map[common.Address]int)
+	for i := 0; i < hotContractsLimit+10; i++ {
+		conflicts[common.BigToAddress(big.NewInt(int64(i)))] = i + 1
+	}
+
+	h.Learn(conflicts)
+	assert.Len(t, h.Learned(), hotContractsLimit)
+	assert.NotContains(t, h.Learned(), common.BigToAddress(big.NewInt(9)))
+	assert.Contains(t, h.Learned(), common.BigToAddress(big.NewInt(10)))
+
+	// Nothing is learned if learning is disabled
+	h = NewHotContracts(nil, false)
+	h.Learn(map[common.Address]int{learned: 10})
+	assert.False(t, h.IsHot(learned))
+	assert.Empty(t, h.Learned())
+
+	// Nothing is hot without hot contracts
+	assert.False(t, (*HotContracts)(nil).IsHot(configured))
+}
+
+func TestHotContractHints(t *testing.T) {
+	t.Parallel()
+
+	const numTx = 60
+
+	// The transactions calling the hot contract are chained, none of them aborts
+	tasks := hotContractTasks(numTx, testHotContract)
+	_, aborts, err := executeHotContractTasks(tasks, NewHotContracts([]common.Address{testHotContract}, false))
+	require.NoError(t, err)
+	assert.Zero(t, aborts)
+
+	// The hot contract is learned from the conflicts, and stays hot once they are avoided
+	hotContracts := NewHotContracts(nil, true)
+	for i := 0; i < 10 && !hotContracts.IsHot(testHotContract); i++ {
+		_, _, err := executeHotContractTasks(hotContractTasks(numTx, testHotContract), hotContracts)
+		require.NoError(t, err)
+	}
+
+	require.True(t, hotContracts.IsHot(testHotContract), "hot contract not learned")
+
+	for i := 0; i < 20; i++ {
+		_, aborts, err := executeHotContractTasks(hotContractTasks(numTx, testHotContract), hotContracts)
+		require.NoError(t, err)
+		assert.Zero(t, aborts)
+	}
+
+	assert.True(t, hotContracts.IsHot(testHotContract))
+}
+
+func TestHotContractHintsDeterminism(t *testing.T) {
+	t.Parallel()
+
+	const numTx = 60
+
+	result, _, err := executeHotContractTasks(hotContractTasks(numTx, testHotContract), nil)
+	require.NoError(t, err)
+
+	want := readVersions(result.TxIO, numTx)
+
+	// Every transaction reads the values written by the previous transaction calling the
+	// same contract, or by none
+	for tx := 0; tx < numTx; tx++ {
+		slot := NewStateKey(hotContractTaskCallee(tx, testHotContract), common.Hash{})
+
+		switch {
+		case tx%3 == 2, tx == 0:
+			assert.Equal(t, -1, want[tx][slot], "tx %d", tx)
+		case tx%3 == 0:
+			assert.Equal(t, tx-2, want[tx][slot], "tx %d", tx)
+		default:
+			assert.Equal(t, tx-1, want[tx][slot], "tx %d", tx)
+		}
+	}
+
+	// The hints only change the scheduling, not the results
+	for _, hotContracts := range []*HotContracts{
+		NewHotContracts([]common.Address{testHotContract}, false),
+		NewHotContracts([]common.Address{testHotContract, common.BigToAddress(big.NewInt(5002))}, true),
+	} {
+		for i := 0; i < 5; i++ {
+			result, _, err := executeHotContractTasks(hotContractTasks(numTx, testHotContract), hotContracts)
+			require.NoError(t, err)
+			assert.Equal(t, want, readVersions(result.TxIO, numTx))
+		}
+	}
+}
+
+func BenchmarkHotContractHints(b *testing.B) {
+	for _, bench := range []struct {
+		name         string
+		hotContracts *HotContracts
+	}{
+		{"nohints", nil},
+		{"hints", NewHotContracts([]common.Address{testHotContract}, false)},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			var aborts int
+
+			for i := 0; i < b.N; i++ {
+				tasks := hotContractTasks(200, testHotContract)
+
+				_, n, err := executeHotContractTasks(tasks, bench.hotContracts)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				aborts += n
+			}
+
+			b.ReportMetric(float64(aborts)/float64(b.N), "aborts/op")
+		})
+	}
+}
@@ -2,6 +2,7 @@ package blockstm
 
 import (
 	"fmt"
+	"hash/maphash"
 	"sync"
 
 	"github.com/emirpasic/gods/maps/treemap"
@@ -72,13 +73,49 @@ func NewSubpathKey(addr common.Address, subpath byte) Key {
 	return newKey(addr, common.Hash{}, subpath, subpathType)
 }
 
+// DefaultMVHashMapShards is the default number of independently locked segments
+// the multi-version hash map is split into.
+const DefaultMVHashMapShards = 64
+
+// MVHashMap is a multi-version hash map which is sharded by key hash into a number
+// of independently locked segments to reduce lock contention between workers.
 type MVHashMap struct {
-	m sync.Map
-	s sync.Map
+	shards []mvShard
+	seed   maphash.Seed
+}
+
+// mvShard is a single segment of the multi-version hash map. The maps are lazily
+// allocated on first write.
+type mvShard struct {
+	lock    sync.RWMutex
+	cells   map[Key]*TxnIndexCells
+	storage map[Key]any
 }
 
 func MakeMVHashMap() *MVHashMap {
-	return &MVHashMap{}
+	return MakeMVHashMapWithShards(DefaultMVHashMapShards)
+}
+
+// MakeMVHashMapWithShards creates a multi-version hash map split into the given
+// number of shards. A non-positive number results in a single shard.
+func MakeMVHashMapWithShards(shards int) *MVHashMap {
+	if shards <= 0 {
+		shards = 1
+	}
+
+	return &MVHashMap{
+		shards: make([]mvShard, shards),
+		seed:   maphash.MakeSeed(),
+	}
+}
+
+// shard returns the segment responsible for the given key.
+func (mv *MVHashMap) shard(k Key) *mvShard {
+	if len(mv.shards) == 1 {
+		return &mv.shards[0]
+	}
+
+	return &mv.shards[maphash.Bytes(mv.seed, k[:])%uint64(len(mv.shards))]
 }
 
 type WriteCell struct {
@@ -98,12 +135,14 @@ type Version struct {
 }
 
 func (mv *MVHashMap) getKeyCells(k Key, fNoKey func(kenc Key) *TxnIndexCells) (cells *TxnIndexCells) {
-	val, ok := mv.m.Load(k)
+	sh := mv.shard(k)
+
+	sh.lock.RLock()
+	cells, ok := sh.cells[k]
+	sh.lock.RUnlock()
 
 	if !ok {
 		cells = fNoKey(k)
-	} else {
-		cells = val.(*TxnIndexCells)
 	}
 
 	return
@@ -111,12 +150,25 @@ func (mv *MVHashMap) getKeyCells(k Key, fNoKey func(kenc Key) *TxnIndexCells) (c
 
 func (mv *MVHashMap) Write(k Key, v Version, data interface{}) {
 	cells := mv.getKeyCells(k, func(kenc Key) (cells *TxnIndexCells) {
-		n := &TxnIndexCells{
+		sh := mv.shard(kenc)
+
+		sh.lock.Lock()
+		defer sh.lock.Unlock()
+
+		// Another writer might have created the cells in the meantime
+		if existing, ok := sh.cells[kenc]; ok {
+			return existing
+		}
+
+		if sh.cells == nil {
+			sh.cells = make(map[Key]*TxnIndexCells)
+		}
+
+		cells = &TxnIndexCells{
 			rw: sync.RWMutex{},
 			tm: treemap.NewWithIntComparator(),
 		}
-		val, _ := mv.m.LoadOrStore(kenc, n)
-		cells = val.(*TxnIndexCells)
+		sh.cells[kenc] = cells
 
 		return
 	})
@@ -141,12 +193,32 @@ func (mv *MVHashMap) Write(k Key, v Version, data interface{}) {
 }
 
 func (mv *MVHashMap) ReadStorage(k Key, fallBack func() any) any {
-	data, ok := mv.s.Load(string(k[:]))
-	if !ok {
-		data = fallBack()
-		data, _ = mv.s.LoadOrStore(string(k[:]), data)
+	sh := mv.shard(k)
+
+	sh.lock.RLock()
+	data, ok := sh.storage[k]
+	sh.lock.RUnlock()
+
+	if ok {
+		return data
 	}
 
+	// Call the fallback outside of the lock as it may be slow
+	data = fallBack()
+
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+
+	if existing, ok := sh.storage[k]; ok {
+		return existing
+	}
+
+	if sh.storage == nil {
+		sh.storage = make(map[Key]any)
+	}
+
+	sh.storage[k] = data
+
 	return data
 }
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/big"
 	"math/rand"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -342,3 +343,117 @@ func TestReadTimeSameLocation(t *testing.T) {
 		mvh1.Read(ap1, 2)
 	}
 }
+
+func TestConcurrentShardedAccess(t *testing.T) {
+	t.Parallel()
+
+	for _, shards := range []int{1, DefaultMVHashMapShards} {
+		mvh := MakeMVHashMapWithShards(shards)
+
+		const (
+			workers = 16
+			keys    = 256
+			txs     = 64
+		)
+
+		var wg sync.WaitGroup
+
+		// Every worker writes a disjoint set of transaction indexes for all keys
+		// while concurrently reading and flushing write sets.
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+
+			go func(w int) {
+				defer wg.Done()
+
+				for tx := w; tx < txs; tx += workers {
+					writes := make([]WriteDescriptor, 0, keys)
+
+					for k := 0; k < keys; k++ {
+						key := NewStateKey(common.BigToAddress(big.NewInt(int64(k))), common.BigToHash(big.NewInt(int64(k))))
+						writes = append(writes, WriteDescriptor{Path: key, V: Version{tx, 0}, Val: valueFor(tx, 0)})
+
+						mvh.Read(key, tx)
+						mvh.ReadStorage(key, func() any { return k })
+					}
+
+					mvh.FlushMVWriteSet(writes)
+				}
+			}(w)
+		}
+
+		wg.Wait()
+
+		// Every key must observe the latest write below the requested index
+		for k := 0; k < keys; k++ {
+			key := NewStateKey(common.BigToAddress(big.NewInt(int64(k))), common.BigToHash(big.NewInt(int64(k))))
+
+			res := mvh.Read(key, txs)
+			require.Equal(t, MVReadResultDone, res.Status(), "shards=%d key=%d", shards, k)
+			require.Equal(t, txs-1, res.DepIdx(), "shards=%d key=%d", shards, k)
+			require.Equal(t, valueFor(txs-1, 0), res.Value(), "shards=%d key=%d", shards, k)
+
+			require.Equal(t, k, mvh.ReadStorage(key, func() any { return -1 }), "shards=%d key=%d", shards, k)
+
+			mvh.MarkEstimate(key, txs-1)
+			res = mvh.Read(key, txs)
+			require.Equal(t, MVReadResultDependency, res.Status(), "shards=%d key=%d", shards, k)
+
+			mvh.Delete(key, txs-1)
+			res = mvh.Read(key, txs)
+			require.Equal(t, txs-2, res.DepIdx(), "shards=%d key=%d", shards, k)
+		}
+	}
+}
+
+func benchmarkConcurrentAccess(b *testing.B, shards int) {
+	const (
+		workers = 16
+		keys    = 1024
+	)
+
+	paths := make([]Key, keys)
+	for i := range paths {
+		paths[i] = NewStateKey(common.BigToAddress(big.NewInt(int64(i))), common.BigToHash(big.NewInt(int64(i))))
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		mvh := MakeMVHashMapWithShards(shards)
+
+		var wg sync.WaitGroup
+
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+
+			go func(w int) {
+				defer wg.Done()
+
+				writes := make([]WriteDescriptor, 0, keys/workers)
+
+				for k := w; k < keys; k += workers {
+					writes = append(writes, WriteDescriptor{Path: paths[k], V: Version{w, 0}, Val: w})
+				}
+
+				mvh.FlushMVWriteSet(writes)
+
+				for _, path := range paths {
+					mvh.Read(path, workers)
+				}
+			}(w)
+		}
+
+		wg.Wait()
+	}
+}
+
+// BenchmarkConcurrentAccessShards compares the concurrent access to the multi-version
+// hash map for the different numbers of shards, to tune parallelevm.mvhashmapshards.
+func BenchmarkConcurrentAccessShards(b *testing.B) {
+	for _, shards := range []int{1, 4, 16, DefaultMVHashMapShards, 256} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			benchmarkConcurrentAccess(b, shards)
+		})
+	}
+}
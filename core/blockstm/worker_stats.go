@@ -0,0 +1,77 @@
+package blockstm
+
+import (
+	"sort"
+	"time"
+)
+
+// WorkerUtilization summarises the work done by a worker during the parallel execution
+// of a block. Only the last incarnation of every transaction is recorded in the stats,
+// so the busy time excludes the time spent on aborted incarnations.
+type WorkerUtilization struct {
+	Worker     int           `json:"worker"`
+	Tasks      int           `json:"tasks"`      // Transactions whose last incarnation ran on the worker
+	Reexecuted int           `json:"reexecuted"` // Tasks which were aborted at least once before
+	Busy       time.Duration `json:"busy"`
+	Idle       time.Duration `json:"idle"`
+	BusyRatio  float64       `json:"busyRatio"`  // Busy time over the execution span of the block
+	AbortShare float64       `json:"abortShare"` // Re-executed tasks over all the tasks of the worker
+}
+
+// WorkerUtilizations aggregates the execution stats of a block per worker, sorted by
+// worker number. The execution span of the block runs from the earliest start to the
+// latest end recorded, and a worker is idle for the part of it it wasn't busy with.
+// Workers without any recorded task are not reported.
+func WorkerUtilizations(stats map[int]ExecutionStat) []WorkerUtilization {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	var (
+		first   = ^uint64(0)
+		last    uint64
+		workers = make(map[int]*WorkerUtilization)
+	)
+
+	for _, stat := range stats {
+		first = min(first, stat.Start)
+		last = max(last, stat.End)
+
+		worker, ok := workers[stat.Worker]
+		if !ok {
+			worker = &WorkerUtilization{Worker: stat.Worker}
+			workers[stat.Worker] = worker
+		}
+
+		worker.Tasks++
+		if stat.Incarnation > 0 {
+			worker.Reexecuted++
+		}
+
+		if stat.End > stat.Start {
+			worker.Busy += time.Duration(stat.End - stat.Start)
+		}
+	}
+
+	span := time.Duration(last - first)
+	utilizations := make([]WorkerUtilization, 0, len(workers))
+
+	for _, worker := range workers {
+		if worker.Busy < span {
+			worker.Idle = span - worker.Busy
+		}
+
+		if span > 0 {
+			worker.BusyRatio = float64(worker.Busy) / float64(span)
+		}
+
+		worker.AbortShare = float64(worker.Reexecuted) / float64(worker.Tasks)
+		utilizations = append(utilizations, *worker)
+	}
+
+	sort.Slice(utilizations, func(i, j int) bool {
+		return utilizations[i].Worker < utilizations[j].Worker
+	})
+
+	return utilizations
+}
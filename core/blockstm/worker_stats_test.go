@@ -0,0 +1,53 @@
+package blockstm
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/heimdalr/dag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// workerTestStats is a hand-built execution of four transactions on two workers over
+// a span of 400ns.
+var workerTestStats = map[int]ExecutionStat{
+	0: {TxIdx: 0, Incarnation: 0, Start: 0, End: 100, Worker: 0},
+	1: {TxIdx: 1, Incarnation: 0, Start: 0, End: 50, Worker: 1},
+	2: {TxIdx: 2, Incarnation: 0, Start: 150, End: 250, Worker: 0},
+	3: {TxIdx: 3, Incarnation: 2, Start: 200, End: 400, Worker: 1},
+}
+
+func TestWorkerUtilizations(t *testing.T) {
+	t.Parallel()
+
+	want := []WorkerUtilization{
+		{Worker: 0, Tasks: 2, Reexecuted: 0, Busy: 200, Idle: 200, BusyRatio: 0.5, AbortShare: 0},
+		{Worker: 1, Tasks: 2, Reexecuted: 1, Busy: 250, Idle: 150, BusyRatio: 0.625, AbortShare: 0.5},
+	}
+	assert.Equal(t, want, WorkerUtilizations(workerTestStats))
+
+	assert.Nil(t, WorkerUtilizations(nil), "no utilization should be reported without stats")
+}
+
+func TestReportJSON(t *testing.T) {
+	t.Parallel()
+
+	d := DAG{dag.NewDAG()}
+	for i := 0; i < len(workerTestStats); i++ {
+		_, err := d.AddVertex(i)
+		require.NoError(t, err)
+	}
+
+	blob, err := d.ReportJSON(workerTestStats)
+	require.NoError(t, err)
+
+	var report ExecutionReport
+	require.NoError(t, json.Unmarshal(blob, &report))
+
+	assert.Equal(t, []int{3}, report.LongestPath)
+	assert.Equal(t, 200*time.Nanosecond, report.LongestPathWeight)
+	assert.Equal(t, 450*time.Nanosecond, report.SerialWeight)
+	assert.Equal(t, WorkerUtilizations(workerTestStats), report.Workers)
+}
@@ -4,6 +4,16 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	// borReceiptMissCounter counts the bor receipt lookups which missed in the database.
+	borReceiptMissCounter = metrics.NewRegisteredCounter("chain/borreceipts/miss", nil)
+
+	// borReceiptNegativeHitCounter counts the bor receipt lookups answered by the cache
+	// of the recent misses, without reading the database.
+	borReceiptNegativeHitCounter = metrics.NewRegisteredCounter("chain/borreceipts/negative/hit", nil)
 )
 
 // GetBorReceiptByHash retrieves the bor block receipt in a given block.
@@ -12,15 +22,24 @@ func (bc *BlockChain) GetBorReceiptByHash(hash common.Hash) *types.Receipt {
 		return receipt
 	}
 
+	// Most lookups are for blocks without bor receipt, don't hit the database again
+	// until the block is written
+	if bc.borReceiptsMissCache.Contains(hash) {
+		borReceiptNegativeHitCounter.Inc(1)
+		return nil
+	}
+
 	// read header from hash
 	number := rawdb.ReadHeaderNumber(bc.db, hash)
 	if number == nil {
+		bc.missBorReceipt(hash)
 		return nil
 	}
 
 	// read bor receipt by hash and number
 	receipt := rawdb.ReadBorReceipt(bc.db, hash, *number, bc.chainConfig)
 	if receipt == nil {
+		bc.missBorReceipt(hash)
 		return nil
 	}
 
@@ -29,3 +48,42 @@ func (bc *BlockChain) GetBorReceiptByHash(hash common.Hash) *types.Receipt {
 
 	return receipt
 }
+
+// missBorReceipt records that the given block has no bor receipt in the database, until
+// the block is written. The database is checked again under the lock, as the receipt may
+// have been written, and the block removed from the cache, since it was looked up.
+func (bc *BlockChain) missBorReceipt(hash common.Hash) {
+	borReceiptMissCounter.Inc(1)
+
+	bc.borReceiptsMissLock.Lock()
+	defer bc.borReceiptsMissLock.Unlock()
+
+	if number := rawdb.ReadHeaderNumber(bc.db, hash); number != nil && rawdb.ReadBorReceiptRLP(bc.db, hash, *number) != nil {
+		return
+	}
+
+	bc.borReceiptsMissCache.Add(hash, struct{}{})
+}
+
+// forgetBorReceiptMisses removes the given blocks from the cache of the bor receipt
+// misses, once their bor receipts are written in the database.
+func (bc *BlockChain) forgetBorReceiptMisses(hashes ...common.Hash) {
+	bc.borReceiptsMissLock.Lock()
+	defer bc.borReceiptsMissLock.Unlock()
+
+	for _, hash := range hashes {
+		bc.borReceiptsMissCache.Remove(hash)
+	}
+}
+
+// GetStateSyncL1Lookups retrieves the locations of the state sync events originating from
+// the given L1 transaction which were committed in canonical blocks.
+func (bc *BlockChain) GetStateSyncL1Lookups(l1Hash common.Hash) []rawdb.StateSyncL1Lookup {
+	return rawdb.ReadStateSyncL1Lookups(bc.db, l1Hash)
+}
+
+// GetWitnessInfo returns the sizes of the witness generated or injected for the given
+// block, or nil if the node never handled its witness.
+func (bc *BlockChain) GetWitnessInfo(hash common.Hash) *rawdb.WitnessInfo {
+	return rawdb.ReadWitnessInfo(bc.db, hash)
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// borExportMagic identifies the marker preceding the blocks of the chain exports of bor
+// chains. Exports without the marker only contain blocks.
+var borExportMagic = []byte("bor-chain-export")
+
+// borExportVersion is the version of the bor chain export format. In version 1, every
+// block is followed by its bor receipt, or by an empty string if it has none.
+const borExportVersion = 1
+
+// borExportMarker precedes the blocks of a bor chain export. Its first field being a
+// string tells it apart from a block, whose first field is the header list.
+type borExportMarker struct {
+	Magic   []byte
+	Version uint64
+}
+
+// writeBorExportMarker writes the marker of the current bor chain export format.
+func writeBorExportMarker(w io.Writer) error {
+	return rlp.Encode(w, &borExportMarker{Magic: borExportMagic, Version: borExportVersion})
+}
+
+// decodeBorExportMarker decodes the given item of a chain export as a bor export marker,
+// returning false if it isn't one.
+func decodeBorExportMarker(data []byte) (*borExportMarker, bool) {
+	content, _, err := rlp.SplitList(data)
+	if err != nil {
+		return nil, false
+	}
+
+	if kind, _, _, err := rlp.Split(content); err != nil || kind != rlp.String {
+		return nil, false
+	}
+
+	marker := new(borExportMarker)
+	if err := rlp.DecodeBytes(data, marker); err != nil || !bytes.Equal(marker.Magic, borExportMagic) {
+		return nil, false
+	}
+
+	return marker, true
+}
+
+// exportBorReceipt writes the bor receipt of the given block, or an empty string if the
+// block has none.
+func (bc *BlockChain) exportBorReceipt(w io.Writer, block *types.Block) error {
+	// Ancient blocks without a bor receipt store an empty list
+	data := rawdb.ReadBorReceiptRLP(bc.db, block.Hash(), block.NumberU64())
+	if len(data) == 0 || bytes.Equal(data, rlp.EmptyList) {
+		data = rlp.EmptyString
+	}
+
+	_, err := w.Write(data)
+
+	return err
+}
+
+// ChainImportStream decodes the blocks of a chain export, along with their bor receipts
+// if the export carries them.
+type ChainImportStream struct {
+	stream  *rlp.Stream
+	version uint64 // Version of the bor export being decoded, 0 for plain blocks
+}
+
+// NewChainImportStream creates a stream decoding the chain export read from r.
+func NewChainImportStream(r io.Reader) *ChainImportStream {
+	return &ChainImportStream{stream: rlp.NewStream(r, 0)}
+}
+
+// Next decodes the next block of the export and its bor receipt, which is nil if the
+// block has none or the export doesn't carry bor receipts. It returns io.EOF at the end
+// of the export.
+func (s *ChainImportStream) Next() (*types.Block, *types.ReceiptForStorage, error) {
+	for {
+		data, err := s.stream.Raw()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Appended exports carry a marker each
+		if marker, ok := decodeBorExportMarker(data); ok {
+			if marker.Version > borExportVersion {
+				return nil, nil, fmt.Errorf("unsupported bor chain export version %d", marker.Version)
+			}
+
+			s.version = marker.Version
+
+			continue
+		}
+
+		block := new(types.Block)
+		if err := rlp.DecodeBytes(data, block); err != nil {
+			return nil, nil, err
+		}
+
+		if s.version == 0 {
+			return block, nil, nil
+		}
+
+		data, err = s.stream.Raw()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("bor receipt of block %d: %w", block.NumberU64(), err)
+		}
+
+		if bytes.Equal(data, rlp.EmptyString) {
+			return block, nil, nil
+		}
+
+		receipt := new(types.ReceiptForStorage)
+		if err := rlp.DecodeBytes(data, receipt); err != nil {
+			return nil, nil, fmt.Errorf("bor receipt of block %d: %w", block.NumberU64(), err)
+		}
+
+		return block, receipt, nil
+	}
+}
+
+// WriteImportedBorReceipts stores the bor receipts imported along with the given blocks,
+// for the canonical blocks which don't have one yet (e.g. the state sync events weren't
+// replayed or the blocks were already present). The receipts are matched to the blocks
+// by index, nil entries are skipped.
+func (bc *BlockChain) WriteImportedBorReceipts(blocks []*types.Block, receipts []*types.ReceiptForStorage) error {
+	var (
+		batch   = bc.db.NewBatch()
+		written []common.Hash
+	)
+
+	for i, block := range blocks {
+		if i >= len(receipts) || receipts[i] == nil {
+			continue
+		}
+
+		hash, number := block.Hash(), block.NumberU64()
+		if rawdb.ReadCanonicalHash(bc.db, number) != hash {
+			continue
+		}
+
+		if data := rawdb.ReadBorReceiptRLP(bc.db, hash, number); len(data) > 0 && !bytes.Equal(data, rlp.EmptyList) {
+			continue
+		}
+
+		rawdb.WriteBorBlockData(batch, hash, number, receipts[i], nil)
+		written = append(written, hash)
+	}
+
+	if err := batch.Write(); err != nil {
+		return err
+	}
+
+	bc.forgetBorReceiptMisses(written...)
+
+	return nil
+}
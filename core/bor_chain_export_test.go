@@ -0,0 +1,151 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// importTestChain imports a chain export into the given chain, returning the number of
+// bor receipts the export carried.
+func importTestChain(t *testing.T, chain *BlockChain, r io.Reader) int {
+	t.Helper()
+
+	var (
+		stream   = NewChainImportStream(r)
+		blocks   []*types.Block
+		receipts []*types.ReceiptForStorage
+		carried  int
+	)
+	for {
+		block, receipt, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to decode export: %v", err)
+		}
+		if block.NumberU64() == 0 {
+			continue
+		}
+		if receipt != nil {
+			carried++
+		}
+		blocks = append(blocks, block)
+		receipts = append(receipts, receipt)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	if err := chain.WriteImportedBorReceipts(blocks, receipts); err != nil {
+		t.Fatalf("failed to write bor receipts: %v", err)
+	}
+	return carried
+}
+
+// Tests that the bor receipts of a chain export are restored when importing it into a
+// fresh database, and that exports without bor receipts still import.
+func TestBorChainExportImport(t *testing.T) {
+	t.Parallel()
+
+	var (
+		gspec        = &Genesis{Config: params.TestChainConfig, BaseFee: big.NewInt(params.InitialBaseFee)}
+		_, blocks, _ = GenerateChainWithGenesis(gspec, ethash.NewFaker(), 16, nil)
+		receiver     = common.HexToAddress("0x0000000000000000000000000000000000001001")
+	)
+	newChain := func() *BlockChain {
+		chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), DefaultCacheConfigWithScheme(rawdb.HashScheme), gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create chain: %v", err)
+		}
+		return chain
+	}
+	source := newChain()
+	defer source.Stop()
+
+	if _, err := source.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	// Commit a state sync event in every sprint start block
+	committed := make(map[common.Hash]uint64)
+	for _, block := range blocks {
+		if !params.TestChainConfig.Bor.IsSprintStart(block.NumberU64()) {
+			continue
+		}
+		rawdb.WriteBorBlockData(source.db, block.Hash(), block.NumberU64(), &types.ReceiptForStorage{
+			Status: types.ReceiptStatusSuccessful,
+			Logs: []*types.Log{{
+				Address: receiver,
+				Topics:  []common.Hash{StateCommittedTopic, common.BigToHash(block.Number())},
+				Data:    []byte{0x01},
+			}},
+		}, nil)
+		committed[block.Hash()] = block.NumberU64()
+	}
+	var export bytes.Buffer
+	if err := source.Export(&export); err != nil {
+		t.Fatalf("failed to export chain: %v", err)
+	}
+	enc := export.Bytes()
+
+	// Import the export into a fresh chain and check the bor receipts
+	target := newChain()
+	defer target.Stop()
+
+	if carried := importTestChain(t, target, bytes.NewReader(enc)); carried != len(committed) {
+		t.Fatalf("carried bor receipts mismatch: have %d, want %d", carried, len(committed))
+	}
+	for _, block := range blocks {
+		receipt := target.GetBorReceiptByHash(block.Hash())
+
+		number, ok := committed[block.Hash()]
+		if !ok {
+			if receipt != nil {
+				t.Errorf("block %d: unexpected bor receipt", block.NumberU64())
+			}
+			continue
+		}
+		if receipt == nil {
+			t.Fatalf("block %d: missing bor receipt", number)
+		}
+		if len(receipt.Logs) != 1 || receipt.Logs[0].Topics[1] != common.BigToHash(block.Number()) {
+			t.Errorf("block %d: bor receipt logs mismatch: %v", number, receipt.Logs)
+		}
+		if lookup := rawdb.ReadBorTxLookupEntry(target.db, receipt.TxHash); lookup == nil || *lookup != number {
+			t.Errorf("block %d: bor transaction lookup mismatch: %v", number, lookup)
+		}
+	}
+	// Exports without bor receipts import as plain blocks
+	var plain bytes.Buffer
+	for _, block := range blocks {
+		if err := block.EncodeRLP(&plain); err != nil {
+			t.Fatalf("failed to encode block: %v", err)
+		}
+	}
+	legacy := newChain()
+	defer legacy.Stop()
+
+	if carried := importTestChain(t, legacy, &plain); carried != 0 {
+		t.Fatalf("plain export carried %d bor receipts", carried)
+	}
+	if head := legacy.CurrentBlock().Number.Uint64(); head != 16 {
+		t.Fatalf("plain export head mismatch: have %d, want 16", head)
+	}
+	// Exports of unknown future versions are rejected
+	future, err := rlp.EncodeToBytes(&borExportMarker{Magic: borExportMagic, Version: borExportVersion + 1})
+	if err != nil {
+		t.Fatalf("failed to encode marker: %v", err)
+	}
+	if _, _, err := NewChainImportStream(bytes.NewReader(append(future, enc...))).Next(); err == nil {
+		t.Fatalf("export of a future version decoded")
+	}
+}
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
@@ -21,3 +24,18 @@ type Chain2HeadEvent struct {
 	OldChain []*types.Header
 	Type     string
 }
+
+// Witness event sources
+const (
+	WitnessSourceGenerated = "generated" // Collected while importing a block
+	WitnessSourceInjected  = "injected"  // Supplied externally, e.g. through debug_executeWitness
+)
+
+// WitnessEvent is posted when a block witness is generated or injected.
+type WitnessEvent struct {
+	BlockHash   common.Hash   `json:"blockHash"`
+	Number      uint64        `json:"number"`
+	Source      string        `json:"source"`
+	EncodedSize int           `json:"encodedSize"` // Size of the RLP encoded witness
+	Duration    time.Duration `json:"duration"`    // Time spent executing the block against the witness
+}
@@ -0,0 +1,324 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// StateCommittedTopic is the topic of the StateCommitted(uint256 indexed stateId, bool success)
+// event emitted by the state receiver contract for every committed state sync event.
+var StateCommittedTopic = crypto.Keccak256Hash([]byte("StateCommitted(uint256,bool)"))
+
+// errStateSyncIndexDisabled is returned when querying the state sync event index while
+// it's not enabled, or on a chain not committing state sync events.
+var errStateSyncIndexDisabled = errors.New("state sync event index not enabled")
+
+// CommittedStateSyncEvents returns the state sync events committed by the state receiver
+// contract according to the given bor receipt logs.
+func CommittedStateSyncEvents(logs []*types.Log, receiver common.Address) []rawdb.StateSyncIndexEntry {
+	var events []rawdb.StateSyncIndexEntry
+
+	for i, l := range logs {
+		if l.Address != receiver || len(l.Topics) < 2 || l.Topics[0] != StateCommittedTopic {
+			continue
+		}
+
+		events = append(events, rawdb.StateSyncIndexEntry{
+			EventID:  l.Topics[1].Big().Uint64(),
+			LogIndex: uint64(i),
+		})
+	}
+
+	return events
+}
+
+// stateSyncIndexer maintains the index of the state sync events committed in the
+// canonical chain, mapping the event ids to the blocks committing them and back.
+// The index follows the chain head: blocks dropped by reorgs or rewinds of the chain
+// are unindexed, and indexing resumes from the last indexed block after restarts.
+type stateSyncIndexer struct {
+	config   *params.BorConfig
+	receiver common.Address
+	db       ethdb.Database
+	term     chan chan struct{}
+	closed   chan struct{}
+}
+
+// newStateSyncIndexer initializes the state sync event indexer.
+func newStateSyncIndexer(config *params.BorConfig, chain *BlockChain) *stateSyncIndexer {
+	indexer := &stateSyncIndexer{
+		config:   config,
+		receiver: common.HexToAddress(config.StateReceiverContract),
+		db:       chain.db,
+		term:     make(chan chan struct{}),
+		closed:   make(chan struct{}),
+	}
+	go indexer.loop(chain)
+
+	log.Info("Initialized state sync event indexer", "receiver", indexer.receiver)
+
+	return indexer
+}
+
+// loop schedules the indexing runs, starting one whenever the chain head changes and no
+// run is in progress. A head change during a run triggers another run once it's done.
+func (indexer *stateSyncIndexer) loop(chain *BlockChain) {
+	defer close(indexer.closed)
+
+	var (
+		stop    chan struct{} // Non-nil if background routine is active
+		done    chan struct{} // Non-nil if background routine is active
+		pending bool          // Whether the head changed during the active run
+		headCh  = make(chan ChainHeadEvent)
+		sub     = chain.SubscribeChainHeadEvent(headCh)
+	)
+	defer sub.Unsubscribe()
+
+	launch := func() {
+		stop = make(chan struct{})
+		done = make(chan struct{})
+
+		go indexer.run(stop, done)
+	}
+	launch()
+
+	for {
+		select {
+		case <-headCh:
+			if done == nil {
+				launch()
+			} else {
+				pending = true
+			}
+
+		case <-done:
+			stop, done = nil, nil
+			if pending {
+				pending = false
+				launch()
+			}
+
+		case ch := <-indexer.term:
+			if stop != nil {
+				close(stop)
+			}
+			if done != nil {
+				log.Info("Waiting background state sync event indexer to exit")
+				<-done
+			}
+			close(ch)
+			return
+		}
+	}
+}
+
+// run brings the index in line with the current canonical chain. The blocks indexed
+// from a chain segment which is no longer canonical are unindexed first, then the
+// canonical blocks above the last indexed one are indexed.
+func (indexer *stateSyncIndexer) run(stop chan struct{}, done chan struct{}) {
+	defer close(done)
+
+	headHash := rawdb.ReadHeadBlockHash(indexer.db)
+	if headHash == (common.Hash{}) {
+		return
+	}
+	head := rawdb.ReadHeaderNumber(indexer.db, headHash)
+	if head == nil {
+		return
+	}
+	number, err := indexer.unwind(*head)
+	if err != nil {
+		log.Error("Failed to unindex state sync events", "err", err)
+		return
+	}
+	if err := indexer.index(number+1, *head, stop); err != nil {
+		log.Error("Failed to index state sync events", "err", err)
+	}
+}
+
+// unwind unindexes the indexed blocks which are not part of the canonical chain anymore,
+// walking back from the last indexed block to the last canonical one, whose number is
+// returned. If the indexed chain segment can't be walked back, the index is purged.
+func (indexer *stateSyncIndexer) unwind(head uint64) (uint64, error) {
+	hash := rawdb.ReadStateSyncIndexHead(indexer.db)
+	if hash == (common.Hash{}) {
+		return 0, nil
+	}
+	number := rawdb.ReadHeaderNumber(indexer.db, hash)
+	if number == nil {
+		log.Warn("Purging state sync event index, unknown index head", "hash", hash)
+		return 0, rawdb.DeleteStateSyncIndex(indexer.db)
+	}
+	var (
+		batch   = indexer.db.NewBatch()
+		current = *number
+		unwound int
+		events  int
+	)
+	for current > 0 && (current > head || rawdb.ReadCanonicalHash(indexer.db, current) != hash) {
+		if block := rawdb.ReadStateSyncIndexBlock(indexer.db, current); block != nil && block.Hash == hash {
+			rawdb.DeleteStateSyncIndexBlock(batch, block)
+			events += len(block.Events)
+		}
+		header := rawdb.ReadHeader(indexer.db, hash, current)
+		if header == nil {
+			log.Warn("Purging state sync event index, missing indexed header", "number", current, "hash", hash)
+			return 0, rawdb.DeleteStateSyncIndex(indexer.db)
+		}
+		hash, current = header.ParentHash, current-1
+		unwound++
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			rawdb.WriteStateSyncIndexHead(batch, hash)
+			if err := batch.Write(); err != nil {
+				return 0, err
+			}
+			batch.Reset()
+		}
+	}
+	if unwound == 0 {
+		return current, nil
+	}
+	rawdb.WriteStateSyncIndexHead(batch, hash)
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	log.Info("Unindexed state sync events", "blocks", unwound, "events", events, "head", current)
+
+	return current, nil
+}
+
+// index indexes the state sync events committed in the canonical blocks of the given
+// inclusive range, persisting the progress along the way so that it can be resumed.
+func (indexer *stateSyncIndexer) index(from uint64, to uint64, stop chan struct{}) error {
+	if from > to {
+		return nil
+	}
+	var (
+		batch   = indexer.db.NewBatch()
+		written []*rawdb.StateSyncIndexBlock // Blocks indexed in the pending batch
+		indexed int
+		start   = time.Now()
+		logged  = time.Now()
+
+		lastNumber = from - 1                                 // Number of the persisted index head
+		lastHash   = rawdb.ReadStateSyncIndexHead(indexer.db) // Hash of the persisted index head
+	)
+	// flush persists the pending batch along with the given index head, as long as
+	// the persisted index head and the blocks indexed in the batch are still canonical.
+	// Otherwise the batch is dropped and the next run unwinds to the new canonical
+	// chain first.
+	flush := func(number uint64) (bool, error) {
+		hash := rawdb.ReadCanonicalHash(indexer.db, number)
+		if hash == (common.Hash{}) {
+			return false, nil
+		}
+		if lastHash != (common.Hash{}) && rawdb.ReadCanonicalHash(indexer.db, lastNumber) != lastHash {
+			return false, nil
+		}
+		for _, block := range written {
+			if rawdb.ReadCanonicalHash(indexer.db, block.Number) != block.Hash {
+				return false, nil
+			}
+		}
+		rawdb.WriteStateSyncIndexHead(batch, hash)
+		if err := batch.Write(); err != nil {
+			return false, err
+		}
+		batch.Reset()
+		written = written[:0]
+		lastNumber, lastHash = number, hash
+
+		return true, nil
+	}
+	for number := from; number <= to; number++ {
+		select {
+		case <-stop:
+			if number > from {
+				_, err := flush(number - 1)
+				return err
+			}
+			return nil
+		default:
+		}
+		if indexer.config.IsSprintStart(number) {
+			hash := rawdb.ReadCanonicalHash(indexer.db, number)
+			if hash == (common.Hash{}) {
+				return fmt.Errorf("canonical block %d not found", number)
+			}
+			if receipt := rawdb.ReadRawBorReceipt(indexer.db, hash, number); receipt != nil {
+				if events := CommittedStateSyncEvents(receipt.Logs, indexer.receiver); len(events) > 0 {
+					block := &rawdb.StateSyncIndexBlock{Number: number, Hash: hash, Events: events}
+					rawdb.WriteStateSyncIndexBlock(batch, block)
+
+					written = append(written, block)
+					indexed += len(events)
+				}
+			}
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize || number == to {
+			if ok, err := flush(number); !ok {
+				return err
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Indexing state sync events", "block", number, "target", to, "indexed", indexed, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if to-from >= 1024 {
+		log.Info("Indexed state sync events", "from", from, "to", to, "indexed", indexed, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	return nil
+}
+
+// indexedHead returns the number of the last block whose state sync events are indexed.
+func (indexer *stateSyncIndexer) indexedHead() (uint64, bool) {
+	hash := rawdb.ReadStateSyncIndexHead(indexer.db)
+	if hash == (common.Hash{}) {
+		return 0, false
+	}
+	number := rawdb.ReadHeaderNumber(indexer.db, hash)
+	if number == nil {
+		return 0, false
+	}
+	return *number, true
+}
+
+// close shuts down the indexer. Safe to be called for multiple times.
+func (indexer *stateSyncIndexer) close() {
+	ch := make(chan struct{})
+	select {
+	case indexer.term <- ch:
+		<-ch
+	case <-indexer.closed:
+	}
+}
+
+// GetStateSyncEventsByBlockRange returns the state sync events committed in the canonical
+// blocks of the given inclusive range, according to the state sync event index. The range
+// must be fully indexed.
+func (bc *BlockChain) GetStateSyncEventsByBlockRange(from uint64, to uint64) ([]*rawdb.StateSyncIndexBlock, error) {
+	if bc.stateSyncIndexer == nil {
+		return nil, errStateSyncIndexDisabled
+	}
+	if head, ok := bc.stateSyncIndexer.indexedHead(); !ok || head < to {
+		return nil, fmt.Errorf("state sync events of block %d not indexed yet", to)
+	}
+	var blocks []*rawdb.StateSyncIndexBlock
+	for _, block := range rawdb.ReadStateSyncIndexBlocks(bc.db, from, to) {
+		if rawdb.ReadCanonicalHash(bc.db, block.Number) == block.Hash {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks, nil
+}
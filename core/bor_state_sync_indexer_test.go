@@ -0,0 +1,162 @@
+package core
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var stateSyncTestReceiver = common.HexToAddress("0x0000000000000000000000000000000000001001")
+
+// writeStateSyncTestChain writes a canonical chain of headers on top of the given parent,
+// with every sprint start block committing two state sync events with ids starting from
+// firstID, and sets its last block as the head. The ids of the committed events are
+// returned by block number.
+func writeStateSyncTestChain(db ethdb.Database, config *params.BorConfig, parent *types.Header, length int, fork byte, firstID uint64) ([]*types.Header, map[uint64][]uint64) {
+	var (
+		headers = make([]*types.Header, 0, length)
+		events  = make(map[uint64][]uint64)
+		nextID  = firstID
+	)
+	for i := 0; i < length; i++ {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Extra:      []byte{fork},
+		}
+		number, hash := header.Number.Uint64(), header.Hash()
+
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, hash, number)
+
+		if config.IsSprintStart(number) {
+			// An unrelated log precedes the state sync ones
+			logs := []*types.Log{{Address: common.HexToAddress("0xdead"), Topics: []common.Hash{StateCommittedTopic, common.BigToHash(big.NewInt(1))}}}
+			for j := 0; j < 2; j++ {
+				logs = append(logs, &types.Log{
+					Address: stateSyncTestReceiver,
+					Topics:  []common.Hash{StateCommittedTopic, common.BigToHash(new(big.Int).SetUint64(nextID))},
+				})
+				events[number] = append(events[number], nextID)
+				nextID++
+			}
+			rawdb.WriteBorReceipt(db, hash, number, &types.ReceiptForStorage{Status: types.ReceiptStatusSuccessful, Logs: logs})
+		}
+		headers = append(headers, header)
+		parent = header
+	}
+	rawdb.WriteHeadBlockHash(db, parent.Hash())
+
+	return headers, events
+}
+
+// runStateSyncIndexer runs the indexer to completion.
+func runStateSyncIndexer(indexer *stateSyncIndexer) {
+	indexer.run(make(chan struct{}), make(chan struct{}))
+}
+
+// checkStateSyncIndex checks that exactly the given events are indexed for the canonical
+// blocks up to head, and that the given stale events are not.
+func checkStateSyncIndex(t *testing.T, bc *BlockChain, head uint64, want map[uint64][]uint64, stale []uint64) {
+	t.Helper()
+
+	blocks, err := bc.GetStateSyncEventsByBlockRange(0, head)
+	if err != nil {
+		t.Fatalf("failed to query state sync events: %v", err)
+	}
+	have := make(map[uint64][]uint64)
+	for _, block := range blocks {
+		if block.Hash != rawdb.ReadCanonicalHash(bc.db, block.Number) {
+			t.Errorf("block %d: non-canonical hash %x", block.Number, block.Hash)
+		}
+		for i, event := range block.Events {
+			if event.LogIndex != uint64(i+1) {
+				t.Errorf("event %d: log index mismatch: have %d, want %d", event.EventID, event.LogIndex, i+1)
+			}
+			location := rawdb.ReadStateSyncEventLocation(bc.db, event.EventID)
+			if location == nil || location.BlockNumber != block.Number || location.LogIndex != event.LogIndex {
+				t.Errorf("event %d: location mismatch: have %+v, want block %d", event.EventID, location, block.Number)
+			}
+			have[block.Number] = append(have[block.Number], event.EventID)
+		}
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("indexed events mismatch:\nhave %v\nwant %v", have, want)
+	}
+	for _, id := range stale {
+		if location := rawdb.ReadStateSyncEventLocation(bc.db, id); location != nil {
+			t.Errorf("stale event %d still indexed at block %d", id, location.BlockNumber)
+		}
+	}
+	if _, err := bc.GetStateSyncEventsByBlockRange(0, head+1); err == nil {
+		t.Errorf("query above the indexed head succeeded")
+	}
+}
+
+// Tests that the state sync event index follows the canonical chain through reorgs
+// and rewinds, and that it can be resumed and rebuilt from scratch.
+func TestStateSyncIndexer(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		config = &params.BorConfig{
+			Sprint:                map[string]uint64{"0": 4},
+			StateReceiverContract: stateSyncTestReceiver.Hex(),
+		}
+		genesis = &types.Header{Number: big.NewInt(0)}
+		indexer = &stateSyncIndexer{config: config, receiver: stateSyncTestReceiver, db: db}
+		bc      = &BlockChain{db: db, stateSyncIndexer: indexer}
+	)
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	// Index the first sprints, then resume up to the head
+	chainA, eventsA := writeStateSyncTestChain(db, config, genesis, 20, 'a', 1)
+
+	if err := indexer.index(1, 9, make(chan struct{})); err != nil {
+		t.Fatalf("failed to index: %v", err)
+	}
+	if head, ok := indexer.indexedHead(); !ok || head != 9 {
+		t.Fatalf("indexed head mismatch: have %d, want 9", head)
+	}
+	runStateSyncIndexer(indexer)
+	checkStateSyncIndex(t, bc, 20, eventsA, nil)
+
+	// Reorg to a longer fork branching off at block 10, committing other events
+	chainB, eventsB := writeStateSyncTestChain(db, config, chainA[9], 12, 'b', 100)
+	runStateSyncIndexer(indexer)
+
+	want := map[uint64][]uint64{4: eventsA[4], 8: eventsA[8]}
+	for number, ids := range eventsB {
+		want[number] = ids
+	}
+	checkStateSyncIndex(t, bc, 22, want, append(append(eventsA[12], eventsA[16]...), eventsA[20]...))
+
+	// Rewind the chain below the last sprints of the fork
+	for number := uint64(15); number <= 22; number++ {
+		rawdb.DeleteCanonicalHash(db, number)
+	}
+	rawdb.WriteHeadBlockHash(db, chainB[3].Hash()) // block 14
+	runStateSyncIndexer(indexer)
+
+	delete(want, 16)
+	delete(want, 20)
+	checkStateSyncIndex(t, bc, 14, want, append(eventsB[16], eventsB[20]...))
+
+	// Rebuild the index from scratch
+	if err := rawdb.DeleteStateSyncIndex(db); err != nil {
+		t.Fatalf("failed to delete index: %v", err)
+	}
+	if _, err := bc.GetStateSyncEventsByBlockRange(0, 0); err == nil {
+		t.Fatalf("query of a deleted index succeeded")
+	}
+	runStateSyncIndexer(indexer)
+	checkStateSyncIndex(t, bc, 14, want, nil)
+}
@@ -43,6 +43,10 @@ var (
 // - If the pre-checking happens in the block processing procedure, then a "BAD BLOCk"
 // error should be emitted.
 var (
+	// ErrSystemSender is returned if the sender of a transaction is the system
+	// address, which is reserved for the system calls (e.g. the bor state syncs).
+	ErrSystemSender = errors.New("transaction sender is the system address")
+
 	// ErrNonceTooLow is returned if the nonce of a transaction is lower than the
 	// one present in the local chain.
 	ErrNonceTooLow = errors.New("nonce too low")
@@ -293,7 +293,8 @@ func (w *chainValidatorFake) IsValidChain(current *types.Header, headers []*type
 	return w.validate(current, headers)
 }
 func (w *chainValidatorFake) ProcessCheckpoint(endBlockNum uint64, endBlockHash common.Hash) {}
-func (w *chainValidatorFake) ProcessMilestone(endBlockNum uint64, endBlockHash common.Hash)  {}
+func (w *chainValidatorFake) ProcessMilestone(milestoneId string, endBlockNum uint64, endBlockHash common.Hash) {
+}
 func (w *chainValidatorFake) ProcessFutureMilestone(num uint64, hash common.Hash) {
 }
 func (w *chainValidatorFake) GetWhitelistedCheckpoint() (bool, uint64, common.Hash) {
@@ -18,8 +18,10 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -41,6 +43,10 @@ type ParallelEVMConfig struct {
 	Enable               bool
 	SpeculativeProcesses int
 	Enforce              bool
+	StatsSampleRate      uint64           // Collect execution stats for 1 out of N blocks (0 = disabled)
+	HotContracts         []common.Address // Contracts whose transactions are chained upfront
+	LearnHotContracts    bool             // Learn the hot contracts from the conflicts of the executed blocks
+	MVHashMapShards      int              // Number of shards of the multi-version hash map
 }
 
 // StateProcessor is a basic Processor, which takes care of transitioning
@@ -51,15 +57,38 @@ type ParallelStateProcessor struct {
 	config *params.ChainConfig // Chain configuration options
 	bc     *BlockChain         // Canonical block chain
 	engine consensus.Engine    // Consensus engine used for block rewards
+
+	timeout       time.Duration     // Maximum duration of the parallel execution of a block
+	maxExecutions int               // Maximum number of executions of a block, as a multiple of its number of txs
+	minTxs        int               // Minimum number of txs of a block to execute it in parallel
+	execute       parallelExecuteFn // Parallel executor, overridden in tests
+
+	fallbacks     []uint64 // Numbers of the recent blocks which fell back to serial execution
+	disabledUntil uint64   // Number of the first block executed in parallel again after repeated fallbacks
+	fallbackLock  sync.Mutex
 }
 
+type parallelExecuteFn func(tasks []blockstm.ExecTask, profile bool, check blockstm.PropertyCheck, metadata bool, numProcs int, interruptCtx context.Context) (blockstm.ParallelExecutionResult, error)
+
 // NewParallelStateProcessor initialises a new StateProcessor.
 func NewParallelStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consensus.Engine) *ParallelStateProcessor {
-	return &ParallelStateProcessor{
-		config: config,
-		bc:     bc,
-		engine: engine,
+	p := &ParallelStateProcessor{
+		config:        config,
+		bc:            bc,
+		engine:        engine,
+		timeout:       parallelExecutionTimeout,
+		maxExecutions: parallelMaxExecutions,
+		minTxs:        parallelMinTxs,
 	}
+	p.execute = p.executeWithHints
+
+	return p
+}
+
+// executeWithHints executes the tasks in parallel, chaining upfront the transactions
+// calling the same hot contract of the chain.
+func (p *ParallelStateProcessor) executeWithHints(tasks []blockstm.ExecTask, profile bool, check blockstm.PropertyCheck, metadata bool, numProcs int, interruptCtx context.Context) (blockstm.ParallelExecutionResult, error) {
+	return blockstm.ExecuteParallelWithHints(tasks, profile, check, metadata, numProcs, p.bc.parallelMVHashMapShards, p.bc.hotContracts, interruptCtx)
 }
 
 type ExecutionTask struct {
@@ -168,6 +197,10 @@ func (task *ExecutionTask) Sender() common.Address {
 	return task.sender
 }
 
+func (task *ExecutionTask) To() *common.Address {
+	return task.msg.To
+}
+
 func (task *ExecutionTask) Hash() common.Hash {
 	return task.tx.Hash()
 }
@@ -255,7 +288,86 @@ func (task *ExecutionTask) Settle() {
 	*task.allLogs = append(*task.allLogs, receipt.Logs...)
 }
 
-var parallelizabilityTimer = metrics.NewRegisteredTimer("block/parallelizability", nil)
+var (
+	parallelizabilityTimer    = metrics.NewRegisteredTimer("block/parallelizability", nil)
+	parallelismRatioHistogram = metrics.NewRegisteredHistogram("blockstm/parallelism_ratio", nil, metrics.NewExpDecaySample(1028, 0.015))
+	fallbackSerialCounter     = metrics.NewRegisteredCounter("blockstm/fallback_serial", nil)
+	workerBusyRatioHistogram  = metrics.NewRegisteredHistogram("blockstm/worker/busy_ratio", nil, metrics.NewExpDecaySample(1028, 0.015))
+)
+
+const (
+	// parallelExecutionTimeout is the maximum duration of the parallel execution of a
+	// block before it falls back to serial execution.
+	parallelExecutionTimeout = 30 * time.Second
+
+	// parallelMaxExecutions is the maximum number of executions of the transactions of a
+	// block (including re-executions), as a multiple of their number.
+	parallelMaxExecutions = 20
+
+	// parallelMinTxs is the minimum number of transactions of a block to execute it in
+	// parallel, smaller blocks gain nothing from it.
+	parallelMinTxs = 2
+
+	// Parallel execution is disabled for parallelFallbackCooldown blocks once
+	// parallelFallbackLimit blocks out of parallelFallbackWindow fell back to serial
+	// execution.
+	parallelFallbackLimit    = 3
+	parallelFallbackWindow   = 100
+	parallelFallbackCooldown = 1000
+)
+
+// errParallelExecutionPanic is returned if the parallel execution of a block panicked.
+var errParallelExecutionPanic = errors.New("parallel execution panicked")
+
+// Reasons of the fallbacks to serial execution, reported in the logs and metrics
+const (
+	fallbackReasonPanic       = "panic"
+	fallbackReasonTimeout     = "timeout"
+	fallbackReasonCapExceeded = "cap_exceeded"
+	fallbackReasonFailed      = "exec_failed"
+
+	// Blocks executed serially without attempting parallel execution
+	serialReasonTooFewTxs = "too_few_txs"
+	serialReasonCooldown  = "cooldown"
+)
+
+// parallelSkippedError is returned by the parallel processor for the blocks it leaves to
+// the serial processor running alongside it.
+type parallelSkippedError struct {
+	reason string // Reason the block isn't executed in parallel
+}
+
+func (e *parallelSkippedError) Error() string {
+	return "parallel execution skipped: " + e.reason
+}
+
+// parallelFallbackReason returns the reason to fall back to serial execution after the
+// parallel execution failed with the given error, or an empty string if the error is
+// final (e.g. an invalid transaction) or the execution was interrupted by the caller.
+func parallelFallbackReason(err error, interruptCtx context.Context) string {
+	var execErr blockstm.ParallelExecFailedError
+
+	switch {
+	case interruptCtx.Err() != nil:
+		return ""
+	case errors.Is(err, errParallelExecutionPanic), errors.Is(err, blockstm.ErrExecutionPanicked):
+		return fallbackReasonPanic
+	case errors.Is(err, context.DeadlineExceeded):
+		return fallbackReasonTimeout
+	case errors.Is(err, blockstm.ErrMaxExecutionsExceeded):
+		return fallbackReasonCapExceeded
+	case errors.As(err, &execErr):
+		return fallbackReasonFailed
+	default:
+		return ""
+	}
+}
+
+// shouldSampleStats reports whether full execution stats should be collected for the
+// given block number, i.e. whether it is the 1 out of every rate blocks to be sampled.
+func shouldSampleStats(number uint64, rate uint64) bool {
+	return rate > 0 && number%rate == 0
+}
 
 // Process processes the state changes according to the Ethereum rules by running
 // the transaction messages using the statedb and applying any rewards to both
@@ -264,8 +376,145 @@ var parallelizabilityTimer = metrics.NewRegisteredTimer("block/parallelizability
 // Process returns the receipts and logs accumulated during the process and
 // returns the amount of gas that was used in the process. If any of the
 // transactions failed to execute due to insufficient gas it will return an error.
-// nolint:gocognit
+//
+// If the parallel execution panics, times out or re-executes the transactions too
+// many times, the block is executed again serially. Parallel execution is disabled
+// for a while after repeated fallbacks.
 func (p *ParallelStateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg vm.Config, interruptCtx context.Context) (*ProcessResult, error) {
+	// Set an empty context if nil
+	if interruptCtx == nil {
+		interruptCtx = context.Background()
+	}
+
+	if len(block.Transactions()) < p.minTxs {
+		return p.skipParallel(block, statedb, cfg, interruptCtx, serialReasonTooFewTxs)
+	}
+
+	if p.parallelDisabled(block.NumberU64()) {
+		return p.skipParallel(block, statedb, cfg, interruptCtx, serialReasonCooldown)
+	}
+
+	backupStateDB := statedb.Copy()
+
+	res, err := p.processParallelSafe(block, statedb, cfg, interruptCtx)
+	if err == nil {
+		return res, nil
+	}
+
+	reason := parallelFallbackReason(err, interruptCtx)
+	if reason == "" {
+		return nil, err
+	}
+
+	log.Warn("Parallel execution failed, falling back to serial execution", "number", block.NumberU64(), "hash", block.Hash(), "reason", reason, "err", err)
+
+	fallbackSerialCounter.Inc(1)
+	metrics.GetOrRegisterCounter("blockstm/fallback_serial/"+reason, nil).Inc(1)
+	p.recordFallback(block.NumberU64())
+
+	// nolint
+	*statedb = *backupStateDB
+
+	res, err = p.processSerial(block, statedb, cfg, interruptCtx, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the serial execution produced the expected state
+	header := block.Header()
+	if root := statedb.IntermediateRoot(p.config.IsEIP158(header.Number)); root != header.Root {
+		return nil, fmt.Errorf("invalid merkle root after serial fallback (remote: %x local: %x)", header.Root, root)
+	}
+
+	return res, nil
+}
+
+// skipParallel executes the block serially for the given reason, unless the serial
+// processor of the chain executes it too, in which case a parallelSkippedError is
+// returned right away so that the block isn't executed twice.
+func (p *ParallelStateProcessor) skipParallel(block *types.Block, statedb *state.StateDB, cfg vm.Config, interruptCtx context.Context, reason string) (*ProcessResult, error) {
+	if p.bc != nil && p.bc.processor != nil && !p.bc.enforceParallelProcessor {
+		return nil, &parallelSkippedError{reason: reason}
+	}
+
+	return p.processSerial(block, statedb, cfg, interruptCtx, reason)
+}
+
+// processSerial executes the block with the serial processor, recording the reason
+// it wasn't executed in parallel.
+func (p *ParallelStateProcessor) processSerial(block *types.Block, statedb *state.StateDB, cfg vm.Config, interruptCtx context.Context, reason string) (*ProcessResult, error) {
+	res, err := p.bc.processor.Process(block, statedb, cfg, interruptCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	res.decision = newSerialDecision(block, reason)
+
+	return res, nil
+}
+
+// processParallelSafe executes the block in parallel, turning panics into errors.
+func (p *ParallelStateProcessor) processParallelSafe(block *types.Block, statedb *state.StateDB, cfg vm.Config, interruptCtx context.Context) (res *ProcessResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			res, err = nil, fmt.Errorf("%w: %v", errParallelExecutionPanic, r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(interruptCtx, p.timeout)
+	defer cancel()
+
+	return p.processParallel(block, statedb, cfg, ctx)
+}
+
+// parallelDisabled reports whether the block must be executed serially because of
+// repeated fallbacks in the previous blocks.
+func (p *ParallelStateProcessor) parallelDisabled(number uint64) bool {
+	p.fallbackLock.Lock()
+	defer p.fallbackLock.Unlock()
+
+	if p.disabledUntil == 0 {
+		return false
+	}
+
+	if number < p.disabledUntil {
+		return true
+	}
+
+	log.Info("Re-enabling parallel execution", "number", number)
+
+	p.disabledUntil = 0
+
+	return false
+}
+
+// recordFallback records a fallback to serial execution and disables parallel
+// execution if there were too many of them recently.
+func (p *ParallelStateProcessor) recordFallback(number uint64) {
+	p.fallbackLock.Lock()
+	defer p.fallbackLock.Unlock()
+
+	fallbacks := p.fallbacks[:0]
+	for _, n := range p.fallbacks {
+		if n < number && number-n < parallelFallbackWindow {
+			fallbacks = append(fallbacks, n)
+		}
+	}
+
+	p.fallbacks = append(fallbacks, number)
+
+	if len(p.fallbacks) >= parallelFallbackLimit {
+		p.disabledUntil = number + parallelFallbackCooldown + 1
+		p.fallbacks = nil
+
+		log.Warn("Disabling parallel execution after repeated fallbacks to serial execution", "number", number,
+			"fallbacks", parallelFallbackLimit, "window", parallelFallbackWindow, "until", p.disabledUntil)
+	}
+}
+
+// processParallel executes the transactions of the block in parallel.
+// nolint:gocognit
+func (p *ParallelStateProcessor) processParallel(block *types.Block, statedb *state.StateDB, cfg vm.Config, interruptCtx context.Context) (*ProcessResult, error) {
 	var (
 		receipts    types.Receipts
 		header      = block.Header()
@@ -276,11 +525,6 @@ func (p *ParallelStateProcessor) Process(block *types.Block, statedb *state.Stat
 		metadata    bool
 	)
 
-	// Set an empty context if nil
-	if interruptCtx == nil {
-		interruptCtx = context.Background()
-	}
-
 	// Mutate the block and state according to any hard-fork specs
 	if p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0 {
 		misc.ApplyDAOHardFork(statedb)
@@ -324,6 +568,9 @@ func (p *ParallelStateProcessor) Process(block *types.Block, statedb *state.Stat
 			log.Error("error creating message", "err", err)
 			return nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
 		}
+		if err := validateSender(p.config, header.Number, msg.From); err != nil {
+			return nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+		}
 
 		cleansdb := statedb.Copy()
 
@@ -359,19 +606,31 @@ func (p *ParallelStateProcessor) Process(block *types.Block, statedb *state.Stat
 
 	backupStateDB := statedb.Copy()
 
-	profile := false
-	result, err := blockstm.ExecuteParallel(tasks, profile, metadata, p.bc.parallelSpeculativeProcesses, interruptCtx)
+	// Only collect execution stats for sampled blocks as it is too costly to do for every block
+	profile := shouldSampleStats(blockNumber.Uint64(), p.bc.parallelStatsSampleRate)
+	check := blockstm.MaxExecutionsCheck(p.maxExecutions)
+	result, err := p.execute(tasks, profile, check, metadata, p.bc.parallelSpeculativeProcesses, interruptCtx)
+
+	decision := &ExecutionDecision{
+		Number:   blockNumber.Uint64(),
+		Hash:     blockHash,
+		Mode:     ExecutionModeParallel,
+		Txs:      len(tasks),
+		Workers:  p.bc.parallelSpeculativeProcesses,
+		Metadata: metadata,
+	}
+
+	if err == nil && profile && result.Deps != nil && result.Stats != nil {
+		ratio := result.Deps.CriticalPathRatio(*result.Stats)
 
-	if err == nil && profile && result.Deps != nil {
-		_, weight := result.Deps.LongestPath(*result.Stats)
+		parallelizabilityTimer.Update(time.Duration(ratio))
+		parallelismRatioHistogram.Update(int64(ratio))
 
-		serialWeight := uint64(0)
+		decision.Speedup = float64(ratio) / 100
 
-		for i := 0; i < len(result.Deps.GetVertices()); i++ {
-			serialWeight += (*result.Stats)[i].End - (*result.Stats)[i].Start
+		for _, worker := range blockstm.WorkerUtilizations(*result.Stats) {
+			workerBusyRatioHistogram.Update(int64(worker.BusyRatio * 100))
 		}
-
-		parallelizabilityTimer.Update(time.Duration(serialWeight * 100 / weight))
 	}
 
 	for _, task := range tasks {
@@ -394,7 +653,7 @@ func (p *ParallelStateProcessor) Process(block *types.Block, statedb *state.Stat
 				t.totalUsedGas = usedGas
 			}
 
-			_, err = blockstm.ExecuteParallel(tasks, false, metadata, p.bc.parallelSpeculativeProcesses, interruptCtx)
+			result, err = p.execute(tasks, false, check, metadata, p.bc.parallelSpeculativeProcesses, interruptCtx)
 
 			break
 		}
@@ -404,6 +663,9 @@ func (p *ParallelStateProcessor) Process(block *types.Block, statedb *state.Stat
 		return nil, err
 	}
 
+	// Learn the hot contracts once per block, from the final execution
+	p.bc.hotContracts.Learn(result.Conflicts)
+
 	// Polygon/bor: EIP-6110, EIP-7002, and EIP-7251 are not supported
 	var requests [][]byte
 
@@ -415,6 +677,7 @@ func (p *ParallelStateProcessor) Process(block *types.Block, statedb *state.Stat
 		Requests: requests,
 		Logs:     allLogs,
 		GasUsed:  *usedGas,
+		decision: decision,
 	}, nil
 }
 
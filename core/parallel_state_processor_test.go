@@ -1,9 +1,26 @@
 package core
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/blockstm"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 func TestMetadata(t *testing.T) {
@@ -28,3 +45,392 @@ func TestMetadata(t *testing.T) {
 	temp = GetDeps(wrongTxDependencyOutOfRange)
 	assert.Equal(t, false, VerifyDeps(temp))
 }
+
+func TestShouldSampleStats(t *testing.T) {
+	t.Parallel()
+
+	const blocks = 10_000
+
+	for _, rate := range []uint64{0, 1, 7, 100} {
+		sampled := 0
+
+		for number := uint64(1); number <= blocks; number++ {
+			if shouldSampleStats(number, rate) {
+				sampled++
+			}
+		}
+
+		if rate == 0 {
+			assert.Equal(t, 0, sampled, "no blocks should be sampled when sampling is disabled")
+			continue
+		}
+
+		assert.InDelta(t, blocks/int(rate), sampled, 1, "unexpected number of sampled blocks for rate %d", rate)
+	}
+}
+
+func TestParallelFallbackReason(t *testing.T) {
+	t.Parallel()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		err    error
+		ctx    context.Context
+		reason string
+	}{
+		{fmt.Errorf("%w: boom", errParallelExecutionPanic), context.Background(), fallbackReasonPanic},
+		{fmt.Errorf("%w: tx 1: boom", blockstm.ErrExecutionPanicked), context.Background(), fallbackReasonPanic},
+		{context.DeadlineExceeded, context.Background(), fallbackReasonTimeout},
+		{fmt.Errorf("%w: 100 executions of 2 tasks", blockstm.ErrMaxExecutionsExceeded), context.Background(), fallbackReasonCapExceeded},
+		{blockstm.ParallelExecFailedError{Msg: "no executable transactions due to bad dependency"}, context.Background(), fallbackReasonFailed},
+		// Invalid transactions fail the serial execution as well
+		{errors.New("could not apply tx 0: nonce too low"), context.Background(), ""},
+		// Interrupted by the caller, e.g. because the serial execution finished first
+		{context.Canceled, cancelled, ""},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.reason, parallelFallbackReason(test.err, test.ctx), "error: %v", test.err)
+	}
+}
+
+// faultyTask injects a fault into the execution of a block-stm task.
+type faultyTask struct {
+	blockstm.ExecTask
+	panics  bool          // Panic when executed
+	release chan struct{} // Block the execution until closed, if set
+	deps    []int         // Dependencies replacing the ones of the task, if set
+}
+
+func (t *faultyTask) Execute(mvh *blockstm.MVHashMap, incarnation int) error {
+	if t.panics {
+		panic("boom")
+	}
+
+	if t.release != nil {
+		<-t.release
+		return blockstm.ErrExecAbortError{Dependency: -1}
+	}
+
+	return t.ExecTask.Execute(mvh, incarnation)
+}
+
+func (t *faultyTask) Dependencies() []int {
+	if t.deps != nil {
+		return t.deps
+	}
+
+	return t.ExecTask.Dependencies()
+}
+
+// Tests that the blocks whose parallel execution panics, hangs, exceeds the executions
+// cap or fails are executed serially, running the real block-stm executor.
+func TestParallelFallbackToSerial(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	tests := []struct {
+		reason string
+		capped bool                           // Cap the executions below the number of txs
+		fault  func(i int, n int) *faultyTask // Fault of the i-th of n tasks, if any
+	}{
+		{
+			reason: fallbackReasonPanic,
+			fault: func(i int, _ int) *faultyTask {
+				return &faultyTask{panics: i == 1}
+			},
+		},
+		{
+			reason: fallbackReasonTimeout,
+			fault: func(i int, _ int) *faultyTask {
+				if i == 1 {
+					return &faultyTask{release: release}
+				}
+
+				return &faultyTask{}
+			},
+		},
+		{
+			reason: fallbackReasonCapExceeded,
+			capped: true,
+		},
+		{
+			reason: fallbackReasonFailed,
+			fault: func(i int, n int) *faultyTask {
+				// Circular dependencies leave no transaction to execute
+				return &faultyTask{deps: []int{(i + n - 1) % n}}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.reason, func(t *testing.T) {
+			testParallelFallbackToSerial(t, test.reason, test.capped, test.fault)
+		})
+	}
+}
+
+func testParallelFallbackToSerial(t *testing.T, reason string, capped bool, fault func(i int, n int) *faultyTask) {
+	t.Helper()
+
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  types.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), parallelFallbackLimit+2, func(i int, b *BlockGen) {
+		for j := 0; j < parallelMinTxs+1; j++ {
+			tx, err := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{byte(j + 1)}, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, key)
+			require.NoError(t, err)
+			b.AddTx(tx)
+		}
+	})
+
+	// Only rely on the parallel processor, so that the blocks are imported thanks to the fallback
+	blockchain, err := NewParallelBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil, nil, 8, true, 0, blockstm.DefaultMVHashMapShards)
+	require.NoError(t, err)
+
+	defer blockchain.Stop()
+
+	var calls int
+
+	processor := blockchain.parallelProcessor.(*ParallelStateProcessor)
+	processor.timeout = 50 * time.Millisecond
+
+	if capped {
+		processor.maxExecutions = 0
+	}
+
+	processor.execute = func(tasks []blockstm.ExecTask, profile bool, check blockstm.PropertyCheck, metadata bool, numProcs int, ctx context.Context) (blockstm.ParallelExecutionResult, error) {
+		calls++
+
+		// Inject the faults into copies of the tasks, the processor reads the originals back
+		if fault != nil {
+			faulty := make([]blockstm.ExecTask, len(tasks))
+
+			for i, task := range tasks {
+				f := fault(i, len(tasks))
+				f.ExecTask = task
+				faulty[i] = f
+			}
+
+			tasks = faulty
+		}
+
+		return processor.executeWithHints(tasks, profile, check, metadata, numProcs, ctx)
+	}
+
+	counter := metrics.GetOrRegisterCounter("blockstm/fallback_serial/"+reason, nil)
+	before := counter.Snapshot().Count()
+
+	n, err := blockchain.InsertChain(blocks)
+	require.NoError(t, err)
+	require.Equal(t, len(blocks), n)
+	require.Equal(t, blocks[len(blocks)-1].Hash(), blockchain.CurrentBlock().Hash())
+
+	// Parallel execution is disabled after the first fallbacks
+	require.Equal(t, parallelFallbackLimit, calls)
+	require.True(t, processor.parallelDisabled(blocks[len(blocks)-1].NumberU64()))
+
+	if metrics.Enabled() {
+		require.Equal(t, int64(parallelFallbackLimit), counter.Snapshot().Count()-before)
+	}
+}
+
+func TestParallelFallbackCooldown(t *testing.T) {
+	t.Parallel()
+
+	processor := &ParallelStateProcessor{}
+
+	// Fallbacks spread over more blocks than the window don't disable parallel execution
+	for i := uint64(0); i < 2*parallelFallbackLimit; i++ {
+		processor.recordFallback(1 + i*parallelFallbackWindow)
+	}
+
+	require.False(t, processor.parallelDisabled(2*parallelFallbackLimit*parallelFallbackWindow))
+
+	// Too many fallbacks within the window disable it for the cool-down period
+	for i := uint64(0); i < parallelFallbackLimit; i++ {
+		processor.recordFallback(1000 + i)
+	}
+
+	last := uint64(1000 + parallelFallbackLimit - 1)
+
+	require.True(t, processor.parallelDisabled(last+1))
+	require.True(t, processor.parallelDisabled(last+parallelFallbackCooldown))
+	require.False(t, processor.parallelDisabled(last+parallelFallbackCooldown+1))
+	require.False(t, processor.parallelDisabled(last+2))
+}
+
+func TestParallelExecutionDecisions(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  types.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+
+	// Alternate blocks below and above the parallel execution threshold
+	txs := []int{parallelMinTxs - 1, parallelMinTxs + 1, 0, parallelMinTxs}
+
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), len(txs), func(i int, b *BlockGen) {
+		for j := 0; j < txs[i]; j++ {
+			tx, err := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{byte(j + 1)}, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, key)
+			require.NoError(t, err)
+			b.AddTx(tx)
+		}
+	})
+
+	blockchain, err := NewParallelBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil, nil, 8, true, 0, blockstm.DefaultMVHashMapShards)
+	require.NoError(t, err)
+
+	defer blockchain.Stop()
+
+	counter := metrics.GetOrRegisterCounter("blockstm/blocks_serial/"+serialReasonTooFewTxs, nil)
+	before := counter.Snapshot().Count()
+
+	n, err := blockchain.InsertChain(blocks)
+	require.NoError(t, err)
+	require.Equal(t, len(blocks), n)
+
+	for i, block := range blocks {
+		decision := blockchain.GetExecutionDecision(block.Hash())
+		require.NotNil(t, decision, "block %d", block.NumberU64())
+		require.Equal(t, block.NumberU64(), decision.Number)
+		require.Equal(t, txs[i], decision.Txs)
+
+		if txs[i] < parallelMinTxs {
+			require.Equal(t, ExecutionModeSerial, decision.Mode, "block %d", block.NumberU64())
+			require.Equal(t, serialReasonTooFewTxs, decision.Reason)
+		} else {
+			require.Equal(t, ExecutionModeParallel, decision.Mode, "block %d", block.NumberU64())
+			require.Empty(t, decision.Reason)
+			require.Equal(t, 8, decision.Workers)
+			require.False(t, decision.Metadata) // Generated blocks carry no dependency metadata
+		}
+	}
+
+	if metrics.Enabled() {
+		require.Equal(t, int64(2), counter.Snapshot().Count()-before)
+	}
+
+	// Blocks which weren't processed have no decision
+	require.Nil(t, blockchain.GetExecutionDecision(blockchain.Genesis().Hash()))
+}
+
+// Tests that the blocks not executed in parallel are left to the serial processor running
+// alongside, rather than being executed serially twice.
+func TestParallelSkippedBlocks(t *testing.T) {
+	t.Parallel()
+
+	gspec := &Genesis{Config: params.TestChainConfig}
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 2, nil)
+
+	blockchain, err := NewParallelBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil, nil, 8, false, 0, blockstm.DefaultMVHashMapShards)
+	require.NoError(t, err)
+
+	defer blockchain.Stop()
+
+	processor := blockchain.parallelProcessor.(*ParallelStateProcessor)
+	processor.minTxs = 0
+
+	// Disable parallel execution as after repeated fallbacks
+	for i := uint64(0); i < parallelFallbackLimit; i++ {
+		processor.recordFallback(i)
+	}
+
+	statedb, err := state.New(blockchain.Genesis().Root(), blockchain.statedb)
+	require.NoError(t, err)
+
+	res, err := processor.Process(blocks[0], statedb, vm.Config{}, nil)
+	require.Nil(t, res)
+
+	var skipped *parallelSkippedError
+	require.True(t, errors.As(err, &skipped), "unexpected error: %v", err)
+	require.Equal(t, serialReasonCooldown, skipped.reason)
+
+	// The blocks are imported with the serial result
+	n, err := blockchain.InsertChain(blocks)
+	require.NoError(t, err)
+	require.Equal(t, len(blocks), n)
+
+	for _, block := range blocks {
+		decision := blockchain.GetExecutionDecision(block.Hash())
+		require.NotNil(t, decision, "block %d", block.NumberU64())
+		require.Equal(t, ExecutionModeSerial, decision.Mode)
+		require.Contains(t, []string{serialReasonCooldown, serialReasonSerialFirst}, decision.Reason)
+	}
+}
+
+// Tests that the hot contracts are learned once per block, even if the block is executed
+// again without the delayed fee calculation.
+func TestParallelHotContractsLearnedOnce(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  types.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		hot    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	)
+
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 1, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), hot, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+
+	blockchain, err := NewParallelBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil, nil, 8, false, 0, blockstm.DefaultMVHashMapShards)
+	require.NoError(t, err)
+
+	defer blockchain.Stop()
+
+	blockchain.SetHotContracts(nil, true)
+
+	// Every execution reports a conflict on the contract, the first one asks for a rerun
+	var calls int
+
+	processor := blockchain.parallelProcessor.(*ParallelStateProcessor)
+	processor.minTxs = 0
+	processor.execute = func(tasks []blockstm.ExecTask, profile bool, check blockstm.PropertyCheck, metadata bool, numProcs int, ctx context.Context) (blockstm.ParallelExecutionResult, error) {
+		calls++
+
+		result, err := processor.executeWithHints(tasks, profile, check, metadata, numProcs, ctx)
+		result.Conflicts = map[common.Address]int{hot: 1}
+
+		if calls == 1 {
+			tasks[0].(*ExecutionTask).shouldRerunWithoutFeeDelay = true
+		}
+
+		return result, err
+	}
+
+	statedb, err := state.New(blockchain.Genesis().Root(), blockchain.statedb)
+	require.NoError(t, err)
+
+	_, err = processor.Process(blocks[0], statedb, vm.Config{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+
+	want := blockstm.NewHotContracts(nil, true)
+	want.Learn(map[common.Address]int{hot: 1})
+
+	require.Equal(t, want.Learned(), blockchain.hotContracts.Learned())
+}
@@ -0,0 +1,110 @@
+package rawdb
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/snappy"
+)
+
+// badWitnessKey tracks the witnesses of the last blocks which failed stateless execution
+var badWitnessKey = []byte("matic-bor-bad-witnesses")
+
+// badWitnessesToKeep is the number of bad witnesses kept, the oldest ones are dropped
+// first. Witnesses are large, so fewer are kept than bad blocks.
+const badWitnessesToKeep = 4
+
+// BadWitness is the witness of a block whose stateless execution failed, along with the
+// roots computed by the execution and the ones of the block.
+type BadWitness struct {
+	Number              uint64
+	Hash                common.Hash
+	Witness             []byte // Snappy compressed RLP encoded witness
+	StateRoot           common.Hash
+	ExpectedStateRoot   common.Hash
+	ReceiptRoot         common.Hash
+	ExpectedReceiptRoot common.Hash
+}
+
+// readBadWitnesses retrieves the stored bad witnesses, from the oldest to the newest.
+func readBadWitnesses(db ethdb.KeyValueReader) []*BadWitness {
+	blob, err := db.Get(badWitnessKey)
+	if err != nil || len(blob) == 0 {
+		return nil
+	}
+
+	var witnesses []*BadWitness
+	if err := rlp.DecodeBytes(blob, &witnesses); err != nil {
+		log.Error("Invalid bad witness list", "err", err)
+		return nil
+	}
+
+	return witnesses
+}
+
+// ReadBadWitness retrieves the bad witness of the block with the given hash, or nil if
+// none was stored.
+func ReadBadWitness(db ethdb.KeyValueReader, hash common.Hash) *BadWitness {
+	for _, witness := range readBadWitnesses(db) {
+		if witness.Hash == hash {
+			return witness
+		}
+	}
+
+	return nil
+}
+
+// ReadBadWitnessData retrieves the RLP encoded bad witness of the block with the given
+// hash, or nil if none was stored.
+func ReadBadWitnessData(db ethdb.KeyValueReader, hash common.Hash) []byte {
+	witness := ReadBadWitness(db, hash)
+	if witness == nil {
+		return nil
+	}
+
+	data, err := snappy.Decode(nil, witness.Witness)
+	if err != nil {
+		log.Error("Invalid bad witness", "hash", hash, "err", err)
+		return nil
+	}
+
+	return data
+}
+
+// WriteBadWitness stores the given RLP encoded witness of a block which failed stateless
+// execution, compressed. It replaces the witness previously stored for the block, and
+// the oldest witness is dropped if more than badWitnessesToKeep are stored.
+func WriteBadWitness(db ethdb.KeyValueStore, witness *BadWitness, data []byte) {
+	witnesses := readBadWitnesses(db)
+	for i, w := range witnesses {
+		if w.Hash == witness.Hash {
+			witnesses = append(witnesses[:i], witnesses[i+1:]...)
+			break
+		}
+	}
+
+	entry := *witness
+	entry.Witness = snappy.Encode(nil, data)
+
+	witnesses = append(witnesses, &entry)
+	if len(witnesses) > badWitnessesToKeep {
+		witnesses = witnesses[len(witnesses)-badWitnessesToKeep:]
+	}
+
+	blob, err := rlp.EncodeToBytes(witnesses)
+	if err != nil {
+		log.Crit("Failed to encode bad witnesses", "err", err)
+	}
+
+	if err := db.Put(badWitnessKey, blob); err != nil {
+		log.Crit("Failed to write bad witnesses", "err", err)
+	}
+}
+
+// DeleteBadWitnesses deletes all the bad witnesses.
+func DeleteBadWitnesses(db ethdb.KeyValueWriter) {
+	if err := db.Delete(badWitnessKey); err != nil {
+		log.Crit("Failed to delete bad witnesses", "err", err)
+	}
+}
@@ -0,0 +1,78 @@
+package rawdb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestBadWitnessStorage(t *testing.T) {
+	t.Parallel()
+
+	db := NewMemoryDatabase()
+
+	badWitness := func(i int) (*BadWitness, []byte) {
+		return &BadWitness{
+			Number:            uint64(i),
+			Hash:              common.Hash{byte(i + 1)},
+			StateRoot:         common.Hash{0xaa, byte(i)},
+			ExpectedStateRoot: common.Hash{0xbb, byte(i)},
+		}, bytes.Repeat([]byte{byte(i)}, 1024)
+	}
+
+	// Missing entries
+	witness, data := badWitness(0)
+	if entry := ReadBadWitness(db, witness.Hash); entry != nil {
+		t.Fatalf("unexpected bad witness before writing: %+v", entry)
+	}
+
+	// Written witnesses are compressed and returned as written
+	WriteBadWitness(db, witness, data)
+
+	entry := ReadBadWitness(db, witness.Hash)
+	if entry == nil {
+		t.Fatalf("bad witness not found")
+	}
+	if entry.Number != witness.Number || entry.StateRoot != witness.StateRoot || entry.ExpectedStateRoot != witness.ExpectedStateRoot {
+		t.Fatalf("bad witness mismatch: have %+v, want %+v", entry, witness)
+	}
+	if len(entry.Witness) >= len(data) {
+		t.Fatalf("bad witness not compressed: %d bytes", len(entry.Witness))
+	}
+	if have := ReadBadWitnessData(db, witness.Hash); !bytes.Equal(have, data) {
+		t.Fatalf("bad witness data mismatch")
+	}
+
+	// Only the most recent witnesses are kept
+	for i := 1; i <= badWitnessesToKeep; i++ {
+		witness, data := badWitness(i)
+		WriteBadWitness(db, witness, data)
+	}
+	if entry := ReadBadWitness(db, witness.Hash); entry != nil {
+		t.Fatalf("oldest bad witness not dropped")
+	}
+	for i := 1; i <= badWitnessesToKeep; i++ {
+		witness, data := badWitness(i)
+		if have := ReadBadWitnessData(db, witness.Hash); !bytes.Equal(have, data) {
+			t.Fatalf("bad witness %d mismatch", i)
+		}
+	}
+
+	// Rewriting a witness replaces it, without dropping another one
+	witness, data = badWitness(1)
+	witness.StateRoot = common.Hash{0xcc}
+	WriteBadWitness(db, witness, data)
+
+	if entry := ReadBadWitness(db, witness.Hash); entry == nil || entry.StateRoot != witness.StateRoot {
+		t.Fatalf("bad witness not replaced: %+v", entry)
+	}
+	if len(readBadWitnesses(db)) != badWitnessesToKeep {
+		t.Fatalf("bad witness count mismatch: have %d, want %d", len(readBadWitnesses(db)), badWitnessesToKeep)
+	}
+
+	DeleteBadWitnesses(db)
+	if entry := ReadBadWitness(db, witness.Hash); entry != nil {
+		t.Fatalf("bad witness not deleted")
+	}
+}
@@ -0,0 +1,46 @@
+package rawdb
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// hotContractsKey tracks the hot contracts learned by the parallel processor
+var hotContractsKey = []byte("matic-bor-hot-contracts")
+
+// HotContract is a contract learned as hot by the parallel processor, along with its
+// conflict score.
+type HotContract struct {
+	Address common.Address
+	Score   uint64
+}
+
+// ReadHotContracts retrieves the learned hot contracts.
+func ReadHotContracts(db ethdb.KeyValueReader) []HotContract {
+	blob, err := db.Get(hotContractsKey)
+	if err != nil || len(blob) == 0 {
+		return nil
+	}
+
+	var contracts []HotContract
+	if err := rlp.DecodeBytes(blob, &contracts); err != nil {
+		log.Error("Invalid hot contract list", "err", err)
+		return nil
+	}
+
+	return contracts
+}
+
+// WriteHotContracts stores the learned hot contracts.
+func WriteHotContracts(db ethdb.KeyValueWriter, contracts []HotContract) {
+	blob, err := rlp.EncodeToBytes(contracts)
+	if err != nil {
+		log.Crit("Failed to encode hot contracts", "err", err)
+	}
+
+	if err := db.Put(hotContractsKey, blob); err != nil {
+		log.Crit("Failed to write hot contracts", "err", err)
+	}
+}
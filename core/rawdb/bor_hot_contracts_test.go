@@ -0,0 +1,35 @@
+package rawdb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestHotContractStorage(t *testing.T) {
+	t.Parallel()
+
+	db := NewMemoryDatabase()
+
+	if contracts := ReadHotContracts(db); contracts != nil {
+		t.Fatalf("unexpected hot contracts before writing: %v", contracts)
+	}
+
+	contracts := []HotContract{
+		{Address: common.Address{0x01}, Score: 1000},
+		{Address: common.Address{0x02}, Score: 250},
+	}
+	WriteHotContracts(db, contracts)
+
+	if have := ReadHotContracts(db); !reflect.DeepEqual(have, contracts) {
+		t.Fatalf("hot contracts mismatch: have %v, want %v", have, contracts)
+	}
+
+	// Writing replaces the stored contracts
+	WriteHotContracts(db, contracts[1:])
+
+	if have := ReadHotContracts(db); !reflect.DeepEqual(have, contracts[1:]) {
+		t.Fatalf("hot contracts mismatch: have %v, want %v", have, contracts[1:])
+	}
+}
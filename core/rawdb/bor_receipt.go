@@ -1,6 +1,8 @@
 package rawdb
 
 import (
+	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -15,13 +17,24 @@ var (
 	// bor receipt key
 	borReceiptKey = types.BorReceiptKey
 
+	// borReceiptPrefix + num (uint64 big endian) + hash -> bor block receipt, as laid out by types.BorReceiptKey
+	borReceiptPrefix = []byte("matic-bor-receipt-")
+
 	// borTxLookupPrefix + hash -> transaction/receipt lookup metadata
 	borTxLookupPrefix = []byte(borTxLookupPrefixStr)
+
+	// stateSyncL1LookupPrefix + L1 tx hash + event id + bor block hash -> bor block number
+	stateSyncL1LookupPrefix = []byte(stateSyncL1LookupPrefixStr)
+
+	// stateSyncL1BlockPrefix + num (uint64 big endian) + hash -> L1 tx hash + event id of every state sync event committed in the block
+	stateSyncL1BlockPrefix = []byte("matic-bor-state-sync-l1-block-")
 )
 
 const (
 	borTxLookupPrefixStr = "matic-bor-tx-lookup-"
 
+	stateSyncL1LookupPrefixStr = "matic-bor-state-sync-l1-lookup-"
+
 	// freezerBorReceiptTable indicates the name of the freezer bor receipts table.
 	freezerBorReceiptTable = "matic-bor-receipts"
 )
@@ -31,21 +44,57 @@ func borTxLookupKey(hash common.Hash) []byte {
 	return append(borTxLookupPrefix, hash.Bytes()...)
 }
 
+// stateSyncL1LookupKey = stateSyncL1LookupPrefix + L1 tx hash + event id (uint64 big endian) + bor block hash
+func stateSyncL1LookupKey(l1Hash common.Hash, eventID uint64, blockHash common.Hash) []byte {
+	key := make([]byte, 0, len(stateSyncL1LookupPrefix)+common.HashLength+8+common.HashLength)
+	key = append(key, stateSyncL1LookupPrefix...)
+	key = append(key, l1Hash.Bytes()...)
+	key = append(key, encodeBlockNumber(eventID)...)
+
+	return append(key, blockHash.Bytes()...)
+}
+
+// stateSyncL1BlockKey = stateSyncL1BlockPrefix + num (uint64 big endian) + hash
+func stateSyncL1BlockKey(number uint64, hash common.Hash) []byte {
+	return append(append(append([]byte{}, stateSyncL1BlockPrefix...), encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// ReadBorReceiptRLP retrieves the bor receipt of a block in RLP encoding. The number of
+// frozen blocks decides which store is checked: the receipts of the blocks not frozen yet
+// are only in the key-value store, those of the canonical frozen blocks in the freezer
+// unless pruned from it. The key-value store is still checked for the frozen blocks missing
+// it, as it holds the receipts of the side chains and the imported ones.
 func ReadBorReceiptRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
 	var data []byte
 
-	// First, try to fetch from KV db
-	data, _ = db.Get(borReceiptKey(number, hash))
-	if data != nil {
-		return data
-	}
-
 	err := db.ReadAncients(func(reader ethdb.AncientReaderOp) error {
-		// Check if the data is in ancients
-		if isCanon(reader, number, hash) {
+		frozen, err := reader.Ancients()
+		if err != nil || number >= frozen {
+			data, _ = db.Get(borReceiptKey(number, hash))
+			if len(data) > 0 {
+				return nil
+			}
+
+			// The block might have been frozen and deleted from the key-value store in
+			// the meantime if the freezer doesn't block writes while reading
+			if frozen, err = reader.Ancients(); err != nil || number >= frozen {
+				return nil
+			}
+		}
+
+		// Frozen blocks below the ancient offset or the tail are gone from the freezer. The
+		// blocks inserted as ancients are frozen with an empty list if their receipt wasn't
+		// known yet.
+		tail, _ := reader.Tail()
+		if number >= max(reader.AncientOffSet(), tail) && isCanon(reader, number, hash) {
 			data, _ = reader.Ancient(freezerBorReceiptTable, number)
+			if len(data) > 0 && !bytes.Equal(data, rlp.EmptyList) {
+				return nil
+			}
+		}
 
-			return nil
+		if kvData, _ := db.Get(borReceiptKey(number, hash)); len(kvData) > 0 {
+			data = kvData
 		}
 
 		return nil
@@ -125,6 +174,15 @@ func WriteBorReceipt(db ethdb.KeyValueWriter, hash common.Hash, number uint64, b
 	}
 }
 
+// WriteBorBlockData stores the bor receipt of a block along with the reverse lookups of
+// its bor transaction and of the state sync events it committed. The writer is meant to
+// be the batch of the block, so that all entries are persisted together with it.
+func WriteBorBlockData(db ethdb.KeyValueWriter, hash common.Hash, number uint64, borReceipt *types.ReceiptForStorage, stateSyncs []*types.StateSyncData) {
+	WriteBorReceipt(db, hash, number, borReceipt)
+	WriteBorTxLookupEntry(db, hash, number)
+	WriteStateSyncL1Origins(db, hash, number, stateSyncs)
+}
+
 // DeleteBorReceipt removes receipt data associated with a block hash.
 func DeleteBorReceipt(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	key := borReceiptKey(number, hash)
@@ -175,6 +233,21 @@ func ReadBorTransaction(db ethdb.Reader, hash common.Hash) (*types.Transaction,
 	return types.NewBorTransaction(), blockHash, *blockNumber, uint64(len(body.Transactions))
 }
 
+// IterateBorReceipts calls fn with the block number, hash and RLP encoding of the bor
+// receipts in the key-value store, the frozen ones aside, until it returns false. The
+// encoding is only valid during the call.
+func IterateBorReceipts(db ethdb.Iteratee, fn func(number uint64, hash common.Hash, data []byte) bool) {
+	iter := NewKeyLengthIterator(db.NewIterator(borReceiptPrefix, nil), len(borReceiptPrefix)+8+common.HashLength)
+	defer iter.Release()
+
+	for iter.Next() {
+		key := iter.Key()[len(borReceiptPrefix):]
+		if !fn(binary.BigEndian.Uint64(key[:8]), common.BytesToHash(key[8:]), iter.Value()) {
+			return
+		}
+	}
+}
+
 //
 // Indexes for reverse lookup
 //
@@ -206,9 +279,248 @@ func DeleteBorTxLookupEntry(db ethdb.KeyValueWriter, hash common.Hash, number ui
 	DeleteBorTxLookupEntryByTxHash(db, txHash)
 }
 
+// indexBorTransaction writes the bor transaction lookup and the state sync L1 lookups of the
+// canonical block with the given number, if the block has a bor receipt. Bor transaction
+// lookups are maintained along with the regular transaction lookups, so that they follow
+// the same retention.
+func indexBorTransaction(db ethdb.Reader, batch ethdb.KeyValueWriter, number uint64) {
+	hash := ReadCanonicalHash(db, number)
+	if hash == (common.Hash{}) {
+		return
+	}
+
+	// Ancient blocks without a bor receipt store an empty list
+	if data := ReadBorReceiptRLP(db, hash, number); len(data) == 0 || bytes.Equal(data, rlp.EmptyList) {
+		return
+	}
+
+	WriteBorTxLookupEntry(batch, hash, number)
+	WriteStateSyncL1Lookups(db, batch, hash, number)
+}
+
+// unindexBorTransaction removes the bor transaction lookup and the state sync L1 lookups of
+// the canonical block with the given number, if any.
+func unindexBorTransaction(db ethdb.Reader, batch ethdb.KeyValueWriter, number uint64) {
+	hash := ReadCanonicalHash(db, number)
+	if hash == (common.Hash{}) {
+		return
+	}
+
+	txHash := types.GetDerivedBorTxHash(borReceiptKey(number, hash))
+	if has, _ := db.Has(borTxLookupKey(txHash)); has {
+		DeleteBorTxLookupEntryByTxHash(batch, txHash)
+	}
+
+	DeleteStateSyncL1Lookups(db, batch, hash, number)
+}
+
+// IterateBorTxLookupEntries calls fn with the bor tx hash and the block number of the bor
+// transaction lookups until it returns false. Entries without a block number are passed
+// a nil one.
+func IterateBorTxLookupEntries(db ethdb.Iteratee, fn func(txHash common.Hash, number *uint64) bool) {
+	iter := NewKeyLengthIterator(db.NewIterator(borTxLookupPrefix, nil), common.HashLength+len(borTxLookupPrefix))
+	defer iter.Release()
+
+	for iter.Next() {
+		var number *uint64
+
+		if data := iter.Value(); len(data) > 0 {
+			n := new(big.Int).SetBytes(data).Uint64()
+			number = &n
+		}
+
+		if !fn(common.BytesToHash(iter.Key()[len(borTxLookupPrefix):]), number) {
+			return
+		}
+	}
+}
+
+// DeleteAllBorTxLookupEntries purges the bor transaction lookups matching the condition, or
+// all of them if no condition is given.
+func DeleteAllBorTxLookupEntries(db ethdb.KeyValueStore, condition func(common.Hash, []byte) bool) {
+	iter := NewKeyLengthIterator(db.NewIterator(borTxLookupPrefix, nil), common.HashLength+len(borTxLookupPrefix))
+	defer iter.Release()
+
+	batch := db.NewBatch()
+
+	for iter.Next() {
+		txhash := common.BytesToHash(iter.Key()[len(borTxLookupPrefix):])
+		if condition == nil || condition(txhash, iter.Value()) {
+			if err := batch.Delete(iter.Key()); err != nil {
+				log.Crit("Failed to delete bor transaction lookup entries", "err", err)
+			}
+		}
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to delete bor transaction lookup entries", "err", err)
+			}
+
+			batch.Reset()
+		}
+	}
+
+	if batch.ValueSize() > 0 {
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to delete bor transaction lookup entries", "err", err)
+		}
+	}
+}
+
 // DeleteBorTxLookupEntryByTxHash removes bor transaction data associated with a bor tx hash.
 func DeleteBorTxLookupEntryByTxHash(db ethdb.KeyValueWriter, txHash common.Hash) {
 	if err := db.Delete(borTxLookupKey(txHash)); err != nil {
 		log.Crit("Failed to delete bor transaction lookup entry", "err", err)
 	}
 }
+
+// StateSyncL1Lookup locates a state sync event committed in a bor block.
+type StateSyncL1Lookup struct {
+	BlockHash   common.Hash
+	BlockNumber uint64
+	EventID     uint64
+}
+
+// ReadStateSyncL1Lookups retrieves the locations of the state sync events originating from
+// the given L1 transaction. Only the entries of canonical blocks are returned, those left
+// behind by side chains are skipped.
+func ReadStateSyncL1Lookups(db ethdb.Database, l1Hash common.Hash) []StateSyncL1Lookup {
+	prefix := append(append([]byte{}, stateSyncL1LookupPrefix...), l1Hash.Bytes()...)
+
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	var lookups []StateSyncL1Lookup
+
+	for it.Next() {
+		key, value := it.Key(), it.Value()
+		if len(key) != len(prefix)+8+common.HashLength || len(value) != 8 {
+			continue
+		}
+
+		lookup := StateSyncL1Lookup{
+			BlockHash:   common.BytesToHash(key[len(prefix)+8:]),
+			BlockNumber: binary.BigEndian.Uint64(value),
+			EventID:     binary.BigEndian.Uint64(key[len(prefix) : len(prefix)+8]),
+		}
+		if ReadCanonicalHash(db, lookup.BlockNumber) != lookup.BlockHash {
+			continue
+		}
+
+		lookups = append(lookups, lookup)
+	}
+
+	return lookups
+}
+
+// WriteStateSyncL1Lookup stores the location of a state sync event, identified by the L1
+// transaction it originates from and its event id, committed in the given bor block.
+func WriteStateSyncL1Lookup(db ethdb.KeyValueWriter, l1Hash common.Hash, blockHash common.Hash, blockNumber uint64, eventID uint64) {
+	if err := db.Put(stateSyncL1LookupKey(l1Hash, eventID, blockHash), encodeBlockNumber(blockNumber)); err != nil {
+		log.Crit("Failed to store state sync L1 lookup entry", "err", err)
+	}
+}
+
+// WriteStateSyncL1Origins stores the L1 tx hashes and the event ids of the state sync events
+// committed in the given block, along with their L1 lookups. The origins are kept with the
+// block, so that the lookups can be removed and restored later on.
+func WriteStateSyncL1Origins(db ethdb.KeyValueWriter, hash common.Hash, number uint64, stateSyncs []*types.StateSyncData) {
+	if len(stateSyncs) == 0 {
+		return
+	}
+
+	blob := make([]byte, 0, len(stateSyncs)*(common.HashLength+8))
+	for _, data := range stateSyncs {
+		WriteStateSyncL1Lookup(db, data.TxHash, hash, number, data.ID)
+		blob = append(append(blob, data.TxHash.Bytes()...), encodeBlockNumber(data.ID)...)
+	}
+
+	if err := db.Put(stateSyncL1BlockKey(number, hash), blob); err != nil {
+		log.Crit("Failed to store state sync origins", "err", err)
+	}
+}
+
+// readStateSyncL1Origins retrieves the L1 tx hashes and the event ids of the state sync
+// events committed in the given block, stored along with its bor data.
+func readStateSyncL1Origins(db ethdb.KeyValueReader, hash common.Hash, number uint64) ([]common.Hash, []uint64) {
+	blob, _ := db.Get(stateSyncL1BlockKey(number, hash))
+	if len(blob)%(common.HashLength+8) != 0 {
+		log.Error("Invalid state sync origins", "number", number, "hash", hash, "size", len(blob))
+		return nil, nil
+	}
+
+	var (
+		l1Hashes = make([]common.Hash, 0, len(blob)/(common.HashLength+8))
+		eventIDs = make([]uint64, 0, len(blob)/(common.HashLength+8))
+	)
+
+	for ; len(blob) > 0; blob = blob[common.HashLength+8:] {
+		l1Hashes = append(l1Hashes, common.BytesToHash(blob[:common.HashLength]))
+		eventIDs = append(eventIDs, binary.BigEndian.Uint64(blob[common.HashLength:common.HashLength+8]))
+	}
+
+	return l1Hashes, eventIDs
+}
+
+// WriteStateSyncL1Lookups restores the state sync L1 lookups of the events committed in
+// the given block, from the origins stored along with its bor data.
+func WriteStateSyncL1Lookups(db ethdb.KeyValueReader, batch ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	l1Hashes, eventIDs := readStateSyncL1Origins(db, hash, number)
+	for i, l1Hash := range l1Hashes {
+		WriteStateSyncL1Lookup(batch, l1Hash, hash, number, eventIDs[i])
+	}
+}
+
+// DeleteStateSyncL1Lookups removes the state sync L1 lookups of the events committed in
+// the given block. The origins of the events are kept, so that the lookups can be restored
+// if the block is indexed again.
+func DeleteStateSyncL1Lookups(db ethdb.KeyValueReader, batch ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	l1Hashes, eventIDs := readStateSyncL1Origins(db, hash, number)
+	for i, l1Hash := range l1Hashes {
+		if err := batch.Delete(stateSyncL1LookupKey(l1Hash, eventIDs[i], hash)); err != nil {
+			log.Crit("Failed to delete state sync L1 lookup entry", "err", err)
+		}
+	}
+}
+
+// DeleteStateSyncL1Block removes the state sync L1 lookups of the events committed in the
+// given block along with their origins, once the block itself is deleted.
+func DeleteStateSyncL1Block(db ethdb.KeyValueReader, batch ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	DeleteStateSyncL1Lookups(db, batch, hash, number)
+
+	if err := batch.Delete(stateSyncL1BlockKey(number, hash)); err != nil {
+		log.Crit("Failed to delete state sync origins", "err", err)
+	}
+}
+
+// DeleteAllStateSyncL1Lookups purges the state sync L1 lookups whose bor block number matches
+// the condition, or all of them if no condition is given. The origins stored along with the
+// blocks are kept, so that the lookups can be restored when the blocks are indexed again.
+func DeleteAllStateSyncL1Lookups(db ethdb.KeyValueStore, condition func(number uint64) bool) {
+	iter := NewKeyLengthIterator(db.NewIterator(stateSyncL1LookupPrefix, nil), len(stateSyncL1LookupPrefix)+common.HashLength+8+common.HashLength)
+	defer iter.Release()
+
+	batch := db.NewBatch()
+
+	for iter.Next() {
+		if value := iter.Value(); condition == nil || (len(value) == 8 && condition(binary.BigEndian.Uint64(value))) {
+			if err := batch.Delete(iter.Key()); err != nil {
+				log.Crit("Failed to delete state sync L1 lookup entries", "err", err)
+			}
+		}
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to delete state sync L1 lookup entries", "err", err)
+			}
+
+			batch.Reset()
+		}
+	}
+
+	if batch.ValueSize() > 0 {
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to delete state sync L1 lookup entries", "err", err)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package rawdb
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestStateSyncL1Lookup(t *testing.T) {
+	t.Parallel()
+
+	db := NewMemoryDatabase()
+
+	var (
+		l1Hash     = common.HexToHash("0x01")
+		otherHash  = common.HexToHash("0x02")
+		blockHash  = common.HexToHash("0x10")
+		forkHash   = common.HexToHash("0x11")
+		otherBlock = common.HexToHash("0x12")
+	)
+
+	// Missing entries
+	if lookups := ReadStateSyncL1Lookups(db, l1Hash); len(lookups) != 0 {
+		t.Fatalf("unexpected lookups before writing: %v", lookups)
+	}
+
+	// Two events emitted by the same L1 tx, one of them committed in two forks
+	WriteCanonicalHash(db, blockHash, 64)
+	WriteCanonicalHash(db, otherBlock, 80)
+
+	WriteStateSyncL1Lookup(db, l1Hash, blockHash, 64, 7)
+	WriteStateSyncL1Lookup(db, l1Hash, blockHash, 64, 8)
+	WriteStateSyncL1Lookup(db, l1Hash, forkHash, 64, 8)
+	WriteStateSyncL1Lookup(db, otherHash, otherBlock, 80, 9)
+
+	// The entries of the side chain block are filtered out
+	want := []StateSyncL1Lookup{
+		{BlockHash: blockHash, BlockNumber: 64, EventID: 7},
+		{BlockHash: blockHash, BlockNumber: 64, EventID: 8},
+	}
+
+	lookups := ReadStateSyncL1Lookups(db, l1Hash)
+	if len(lookups) != len(want) {
+		t.Fatalf("lookup count mismatch: have %d, want %d", len(lookups), len(want))
+	}
+
+	for i := range want {
+		if lookups[i] != want[i] {
+			t.Errorf("lookup %d mismatch: have %+v, want %+v", i, lookups[i], want[i])
+		}
+	}
+
+	lookups = ReadStateSyncL1Lookups(db, otherHash)
+	if len(lookups) != 1 || lookups[0] != (StateSyncL1Lookup{BlockHash: otherBlock, BlockNumber: 80, EventID: 9}) {
+		t.Fatalf("unexpected lookups of other L1 tx: %v", lookups)
+	}
+
+	// Reorg the side chain block in
+	WriteCanonicalHash(db, forkHash, 64)
+
+	lookups = ReadStateSyncL1Lookups(db, l1Hash)
+	if len(lookups) != 1 || lookups[0] != (StateSyncL1Lookup{BlockHash: forkHash, BlockNumber: 64, EventID: 8}) {
+		t.Fatalf("unexpected lookups after reorg: %v", lookups)
+	}
+}
+
+// Tests that the state sync L1 lookups of a block are removed and restored from the
+// origins stored along with its bor data, and purged along with the tx index tail.
+func TestDeleteStateSyncL1Lookups(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db                  = NewMemoryDatabase()
+		hash                = common.HexToHash("0x10")
+		number              = uint64(64)
+		receipt, stateSyncs = makeBorBlockData(3)
+	)
+
+	WriteCanonicalHash(db, hash, number)
+	WriteBorBlockData(db, hash, number, receipt, stateSyncs)
+
+	check := func(indexed bool) {
+		t.Helper()
+
+		for _, data := range stateSyncs {
+			if has, _ := db.Has(stateSyncL1LookupKey(data.TxHash, data.ID, hash)); has != indexed {
+				t.Fatalf("state sync lookup of event %d mismatch: have %v, want %v", data.ID, has, indexed)
+			}
+		}
+	}
+	check(true)
+
+	// Unindexing the block drops its lookups, indexing it again restores them
+	batch := db.NewBatch()
+	unindexBorTransaction(db, batch, number)
+
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	check(false)
+
+	batch.Reset()
+	indexBorTransaction(db, batch, number)
+
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	check(true)
+
+	// Purging the lookups below the block number keeps them, those of the block drops them
+	DeleteAllStateSyncL1Lookups(db, func(n uint64) bool { return n < number })
+	check(true)
+
+	DeleteAllStateSyncL1Lookups(db, func(n uint64) bool { return n <= number })
+	check(false)
+
+	WriteStateSyncL1Lookups(db, db, hash, number)
+	check(true)
+
+	// Deleting the block drops its lookups for good
+	DeleteStateSyncL1Block(db, db, hash, number)
+	check(false)
+
+	WriteStateSyncL1Lookups(db, db, hash, number)
+	check(false)
+}
+
+// makeBorBlockData creates a bor receipt and the state sync data of the given number of
+// state sync events.
+func makeBorBlockData(events int) (*types.ReceiptForStorage, []*types.StateSyncData) {
+	receipt := &types.ReceiptForStorage{Status: types.ReceiptStatusSuccessful}
+	stateSyncs := make([]*types.StateSyncData, events)
+
+	for i := range events {
+		receipt.Logs = append(receipt.Logs, &types.Log{
+			Address: common.HexToAddress("0x0000000000000000000000000000000000001001"),
+			Topics:  []common.Hash{common.BigToHash(common.Big1)},
+			Data:    make([]byte, 128),
+		})
+		stateSyncs[i] = &types.StateSyncData{
+			ID:     uint64(i + 1),
+			TxHash: common.BigToHash(big.NewInt(int64(i + 1))),
+		}
+	}
+
+	return receipt, stateSyncs
+}
+
+// Tests that the bor data of a block written into a batch is either entirely
+// present or entirely missing, depending on whether the batch was written.
+func TestWriteBorBlockDataAtomic(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db                  = NewMemoryDatabase()
+		hash                = common.HexToHash("0x10")
+		number              = uint64(64)
+		receipt, stateSyncs = makeBorBlockData(200)
+		txHash              = types.GetDerivedBorTxHash(borReceiptKey(number, hash))
+	)
+
+	WriteCanonicalHash(db, hash, number)
+
+	// Simulate a failure before the batch is written, nothing must be persisted
+	batch := db.NewBatch()
+	WriteBorBlockData(batch, hash, number, receipt, stateSyncs)
+
+	if ReadBorReceiptRLP(db, hash, number) != nil {
+		t.Fatal("bor receipt persisted before batch write")
+	}
+
+	if ReadBorTxLookupEntry(db, txHash) != nil {
+		t.Fatal("bor tx lookup entry persisted before batch write")
+	}
+
+	for _, data := range stateSyncs {
+		if lookups := ReadStateSyncL1Lookups(db, data.TxHash); len(lookups) != 0 {
+			t.Fatalf("state sync lookup of event %d persisted before batch write", data.ID)
+		}
+	}
+
+	// Once the batch is written, everything must be persisted
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+
+	if have := ReadRawBorReceipt(db, hash, number); have == nil || len(have.Logs) != len(receipt.Logs) {
+		t.Fatal("bor receipt missing after batch write")
+	}
+
+	if have := ReadBorTxLookupEntry(db, txHash); have == nil || *have != number {
+		t.Fatalf("bor tx lookup entry mismatch after batch write: have %v, want %d", have, number)
+	}
+
+	for _, data := range stateSyncs {
+		lookups := ReadStateSyncL1Lookups(db, data.TxHash)
+		if len(lookups) != 1 || lookups[0] != (StateSyncL1Lookup{BlockHash: hash, BlockNumber: number, EventID: data.ID}) {
+			t.Fatalf("state sync lookup of event %d mismatch after batch write: %v", data.ID, lookups)
+		}
+	}
+}
+
+// This compares writing the bor data of a block with many state sync events through a
+// single batch against writing every entry directly to disk.
+func BenchmarkWriteBorBlockData(b *testing.B) {
+	receipt, stateSyncs := makeBorBlockData(200)
+
+	run := func(b *testing.B, batched bool) {
+		kvdb, err := leveldb.New(b.TempDir(), 16, 16, "", false)
+		if err != nil {
+			b.Fatalf("failed to create database: %v", err)
+		}
+
+		db := NewDatabase(kvdb)
+		defer db.Close()
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			hash := common.BigToHash(big.NewInt(int64(i + 1)))
+
+			var writer ethdb.KeyValueWriter = db
+
+			batch := db.NewBatch()
+			if batched {
+				writer = batch
+			}
+
+			WriteBorBlockData(writer, hash, uint64(i), receipt, stateSyncs)
+
+			if err := batch.Write(); err != nil {
+				b.Fatalf("failed to write batch: %v", err)
+			}
+		}
+	}
+
+	b.Run("batch", func(b *testing.B) { run(b, true) })
+	b.Run("direct", func(b *testing.B) { run(b, false) })
+}
+
+// countingKeyValueStore is a key-value store counting the reads hitting it.
+type countingKeyValueStore struct {
+	ethdb.KeyValueStore
+	gets int
+}
+
+func (s *countingKeyValueStore) Get(key []byte) ([]byte, error) {
+	s.gets++
+	return s.KeyValueStore.Get(key)
+}
+
+// borReceiptTestHash returns the hash of the canonical test block of the given number.
+func borReceiptTestHash(number uint64) common.Hash {
+	return common.BigToHash(new(big.Int).SetUint64(number + 1))
+}
+
+// newBorReceiptTestDB creates a database backed by a memory freezer, holding the bor
+// receipts of the given number of canonical blocks, the first ones of which are frozen.
+func newBorReceiptTestDB(tb testing.TB, blocks uint64, frozen uint64) (ethdb.Database, *countingKeyValueStore) {
+	tb.Helper()
+
+	kvdb := &countingKeyValueStore{KeyValueStore: NewMemoryDatabase()}
+
+	db, err := NewDatabaseWithFreezer(kvdb, "", "", false, true, false)
+	if err != nil {
+		tb.Fatalf("failed to create database: %v", err)
+	}
+
+	receipt, _ := makeBorBlockData(1)
+
+	for number := uint64(0); number < blocks; number++ {
+		WriteBorReceipt(db, borReceiptTestHash(number), number, receipt)
+	}
+
+	freezeBorReceiptTestBlocks(tb, db, frozen)
+
+	return db, kvdb
+}
+
+// freezeBorReceiptTestBlocks moves the test blocks up to the given number (excluded) to the
+// freezer, deleting their bor receipts from the key-value store like the chain freezer.
+func freezeBorReceiptTestBlocks(tb testing.TB, db ethdb.Database, limit uint64) {
+	tb.Helper()
+
+	frozen, _ := db.Ancients()
+	hashes := make([]common.Hash, 0, limit-frozen)
+
+	_, err := db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for number := frozen; number < limit; number++ {
+			hash := borReceiptTestHash(number)
+			receipt, _ := db.Get(borReceiptKey(number, hash))
+
+			for _, table := range []string{ChainFreezerHeaderTable, ChainFreezerBodiesTable, ChainFreezerReceiptTable, ChainFreezerDifficultyTable} {
+				if err := op.AppendRaw(table, number, []byte{0xc0}); err != nil {
+					return err
+				}
+			}
+
+			if err := op.AppendRaw(ChainFreezerHashTable, number, hash[:]); err != nil {
+				return err
+			}
+
+			if err := op.AppendRaw(freezerBorReceiptTable, number, receipt); err != nil {
+				return err
+			}
+
+			hashes = append(hashes, hash)
+		}
+
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("failed to freeze blocks: %v", err)
+	}
+
+	for i, hash := range hashes {
+		DeleteBorReceipt(db, hash, frozen+uint64(i))
+	}
+}
+
+// Tests that the bor receipts are read from the right store around the freezing boundary,
+// without probing the stores which can't hold them.
+func TestReadBorReceiptRLPFreezingBoundary(t *testing.T) {
+	t.Parallel()
+
+	db, kvdb := newBorReceiptTestDB(t, 16, 8)
+	receipt, _ := makeBorBlockData(1)
+
+	want, err := rlp.EncodeToBytes(receipt)
+	if err != nil {
+		t.Fatalf("failed to encode receipt: %v", err)
+	}
+
+	read := func(hash common.Hash, number uint64, wantGets int) []byte {
+		t.Helper()
+
+		gets := kvdb.gets
+		data := ReadBorReceiptRLP(db, hash, number)
+
+		if have := kvdb.gets - gets; have != wantGets {
+			t.Errorf("block %d: key-value reads mismatch: have %d, want %d", number, have, wantGets)
+		}
+
+		return data
+	}
+
+	// The frozen blocks are only read from the freezer, the recent ones from the key-value store
+	for _, number := range []uint64{0, 7} {
+		if data := read(borReceiptTestHash(number), number, 0); !bytes.Equal(data, want) {
+			t.Errorf("frozen block %d: receipt mismatch: have %x, want %x", number, data, want)
+		}
+	}
+
+	for _, number := range []uint64{8, 15} {
+		if data := read(borReceiptTestHash(number), number, 1); !bytes.Equal(data, want) {
+			t.Errorf("recent block %d: receipt mismatch: have %x, want %x", number, data, want)
+		}
+	}
+
+	if data := read(borReceiptTestHash(16), 16, 1); data != nil {
+		t.Errorf("unknown block: unexpected receipt %x", data)
+	}
+
+	// Side chain receipts of frozen blocks are read from the key-value store
+	side := common.HexToHash("0xdead")
+	WriteBorReceipt(db, side, 4, receipt)
+
+	if data := read(side, 4, 1); !bytes.Equal(data, want) {
+		t.Errorf("side chain block: receipt mismatch: have %x, want %x", data, want)
+	}
+
+	// Moving the boundary moves the reads to the freezer
+	freezeBorReceiptTestBlocks(t, db, 12)
+
+	if data := read(borReceiptTestHash(11), 11, 0); !bytes.Equal(data, want) {
+		t.Errorf("newly frozen block: receipt mismatch: have %x, want %x", data, want)
+	}
+
+	if data := read(borReceiptTestHash(12), 12, 1); !bytes.Equal(data, want) {
+		t.Errorf("first recent block: receipt mismatch: have %x, want %x", data, want)
+	}
+
+	// Frozen blocks without receipt fall back to the key-value store, holding the imported ones
+	DeleteBorReceipt(db, borReceiptTestHash(12), 12)
+	freezeBorReceiptTestBlocks(t, db, 13)
+
+	if data := read(borReceiptTestHash(12), 12, 1); data != nil {
+		t.Errorf("frozen block without receipt: unexpected receipt %x", data)
+	}
+
+	WriteBorReceipt(db, borReceiptTestHash(12), 12, receipt)
+
+	if data := read(borReceiptTestHash(12), 12, 1); !bytes.Equal(data, want) {
+		t.Errorf("imported receipt: receipt mismatch: have %x, want %x", data, want)
+	}
+
+	// The receipts pruned from the freezer are only looked up in the key-value store
+	if _, err := db.TruncateTail(4); err != nil {
+		t.Fatalf("failed to prune freezer: %v", err)
+	}
+
+	if data := read(borReceiptTestHash(3), 3, 1); data != nil {
+		t.Errorf("pruned block: unexpected receipt %x", data)
+	}
+
+	if data := read(borReceiptTestHash(4), 4, 0); !bytes.Equal(data, want) {
+		t.Errorf("first unpruned block: receipt mismatch: have %x, want %x", data, want)
+	}
+}
+
+func BenchmarkReadBorReceiptRLP(b *testing.B) {
+	const (
+		blocks = 1024
+		frozen = 512
+	)
+
+	db, _ := newBorReceiptTestDB(b, blocks, frozen)
+	defer db.Close()
+
+	run := func(b *testing.B, from uint64, to uint64) {
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			number := from + uint64(i)%(to-from)
+
+			if ReadBorReceiptRLP(db, borReceiptTestHash(number), number) == nil {
+				b.Fatalf("missing receipt of block %d", number)
+			}
+		}
+	}
+
+	b.Run("recent", func(b *testing.B) { run(b, frozen, blocks) })
+	b.Run("old", func(b *testing.B) { run(b, 0, frozen) })
+}
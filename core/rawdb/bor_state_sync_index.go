@@ -0,0 +1,213 @@
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// stateSyncEventIndexPrefix + event id (uint64 big endian) -> block number (uint64 big endian) + log index (uint64 big endian)
+	stateSyncEventIndexPrefix = []byte("matic-bor-state-sync-event-")
+
+	// stateSyncBlockIndexPrefix + block number (uint64 big endian) -> block hash + (event id + log index)*
+	stateSyncBlockIndexPrefix = []byte("matic-bor-state-sync-block-")
+
+	// stateSyncIndexHeadKey tracks the hash of the last block whose state sync events are indexed
+	stateSyncIndexHeadKey = []byte("matic-bor-state-sync-index-head")
+)
+
+// StateSyncIndexEntry is a state sync event committed in a block, located by the index of
+// the corresponding log in the bor receipt of the block.
+type StateSyncIndexEntry struct {
+	EventID  uint64
+	LogIndex uint64
+}
+
+// StateSyncIndexBlock lists the state sync events committed in a block.
+type StateSyncIndexBlock struct {
+	Number uint64
+	Hash   common.Hash
+	Events []StateSyncIndexEntry
+}
+
+// StateSyncEventLocation locates an indexed state sync event.
+type StateSyncEventLocation struct {
+	BlockNumber uint64
+	LogIndex    uint64
+}
+
+// stateSyncEventIndexKey = stateSyncEventIndexPrefix + event id (uint64 big endian)
+func stateSyncEventIndexKey(eventID uint64) []byte {
+	return append(append([]byte{}, stateSyncEventIndexPrefix...), encodeBlockNumber(eventID)...)
+}
+
+// stateSyncBlockIndexKey = stateSyncBlockIndexPrefix + block number (uint64 big endian)
+func stateSyncBlockIndexKey(number uint64) []byte {
+	return append(append([]byte{}, stateSyncBlockIndexPrefix...), encodeBlockNumber(number)...)
+}
+
+// decodeStateSyncIndexBlock decodes the block index entry of the given block number.
+func decodeStateSyncIndexBlock(number uint64, data []byte) *StateSyncIndexBlock {
+	if len(data) < common.HashLength || (len(data)-common.HashLength)%16 != 0 {
+		log.Error("Invalid state sync block index entry", "number", number, "len", len(data))
+		return nil
+	}
+
+	block := &StateSyncIndexBlock{
+		Number: number,
+		Hash:   common.BytesToHash(data[:common.HashLength]),
+	}
+
+	for data = data[common.HashLength:]; len(data) > 0; data = data[16:] {
+		block.Events = append(block.Events, StateSyncIndexEntry{
+			EventID:  binary.BigEndian.Uint64(data[:8]),
+			LogIndex: binary.BigEndian.Uint64(data[8:16]),
+		})
+	}
+
+	return block
+}
+
+// ReadStateSyncIndexBlock retrieves the indexed state sync events of the given block number.
+func ReadStateSyncIndexBlock(db ethdb.KeyValueReader, number uint64) *StateSyncIndexBlock {
+	data, _ := db.Get(stateSyncBlockIndexKey(number))
+	if len(data) == 0 {
+		return nil
+	}
+
+	return decodeStateSyncIndexBlock(number, data)
+}
+
+// ReadStateSyncIndexBlocks retrieves the indexed state sync events of the blocks in the
+// given inclusive range, in ascending block order. Blocks without events are skipped.
+func ReadStateSyncIndexBlocks(db ethdb.Iteratee, from uint64, to uint64) []*StateSyncIndexBlock {
+	it := db.NewIterator(stateSyncBlockIndexPrefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	var blocks []*StateSyncIndexBlock
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(stateSyncBlockIndexPrefix)+8 {
+			continue
+		}
+
+		number := binary.BigEndian.Uint64(key[len(stateSyncBlockIndexPrefix):])
+		if number > to {
+			break
+		}
+
+		if block := decodeStateSyncIndexBlock(number, it.Value()); block != nil {
+			blocks = append(blocks, block)
+		}
+	}
+
+	return blocks
+}
+
+// WriteStateSyncIndexBlock stores the state sync events committed in a block, along with
+// the lookup of each event by id.
+func WriteStateSyncIndexBlock(db ethdb.KeyValueWriter, block *StateSyncIndexBlock) {
+	data := make([]byte, 0, common.HashLength+16*len(block.Events))
+	data = append(data, block.Hash.Bytes()...)
+
+	for _, event := range block.Events {
+		data = binary.BigEndian.AppendUint64(data, event.EventID)
+		data = binary.BigEndian.AppendUint64(data, event.LogIndex)
+
+		location := make([]byte, 0, 16)
+		location = binary.BigEndian.AppendUint64(location, block.Number)
+		location = binary.BigEndian.AppendUint64(location, event.LogIndex)
+
+		if err := db.Put(stateSyncEventIndexKey(event.EventID), location); err != nil {
+			log.Crit("Failed to store state sync event index entry", "err", err)
+		}
+	}
+
+	if err := db.Put(stateSyncBlockIndexKey(block.Number), data); err != nil {
+		log.Crit("Failed to store state sync block index entry", "err", err)
+	}
+}
+
+// DeleteStateSyncIndexBlock removes the state sync events committed in a block from the
+// index, along with the lookup of each event by id.
+func DeleteStateSyncIndexBlock(db ethdb.KeyValueWriter, block *StateSyncIndexBlock) {
+	for _, event := range block.Events {
+		if err := db.Delete(stateSyncEventIndexKey(event.EventID)); err != nil {
+			log.Crit("Failed to delete state sync event index entry", "err", err)
+		}
+	}
+
+	if err := db.Delete(stateSyncBlockIndexKey(block.Number)); err != nil {
+		log.Crit("Failed to delete state sync block index entry", "err", err)
+	}
+}
+
+// ReadStateSyncEventLocation retrieves the location of the indexed state sync event with
+// the given id.
+func ReadStateSyncEventLocation(db ethdb.KeyValueReader, eventID uint64) *StateSyncEventLocation {
+	data, _ := db.Get(stateSyncEventIndexKey(eventID))
+	if len(data) != 16 {
+		return nil
+	}
+
+	return &StateSyncEventLocation{
+		BlockNumber: binary.BigEndian.Uint64(data[:8]),
+		LogIndex:    binary.BigEndian.Uint64(data[8:]),
+	}
+}
+
+// ReadStateSyncIndexHead retrieves the hash of the last block whose state sync events
+// are indexed.
+func ReadStateSyncIndexHead(db ethdb.KeyValueReader) common.Hash {
+	data, _ := db.Get(stateSyncIndexHeadKey)
+	if len(data) != common.HashLength {
+		return common.Hash{}
+	}
+
+	return common.BytesToHash(data)
+}
+
+// WriteStateSyncIndexHead stores the hash of the last block whose state sync events are
+// indexed.
+func WriteStateSyncIndexHead(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Put(stateSyncIndexHeadKey, hash.Bytes()); err != nil {
+		log.Crit("Failed to store state sync index head", "err", err)
+	}
+}
+
+// DeleteStateSyncIndex removes the whole state sync event index.
+func DeleteStateSyncIndex(db ethdb.Database) error {
+	batch := db.NewBatch()
+
+	for _, prefix := range [][]byte{stateSyncEventIndexPrefix, stateSyncBlockIndexPrefix} {
+		it := db.NewIterator(prefix, nil)
+
+		for it.Next() {
+			if err := batch.Delete(it.Key()); err != nil {
+				it.Release()
+				return err
+			}
+
+			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return err
+				}
+
+				batch.Reset()
+			}
+		}
+
+		it.Release()
+	}
+
+	if err := batch.Delete(stateSyncIndexHeadKey); err != nil {
+		return err
+	}
+
+	return batch.Write()
+}
@@ -0,0 +1,60 @@
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// witnessInfoPrefix + block hash -> encoded size (uint64 big endian) + compressed size (uint64 big endian)
+var witnessInfoPrefix = []byte("matic-bor-witness-info-")
+
+// WitnessInfo describes the witness of a block generated or injected by the node.
+type WitnessInfo struct {
+	Size           uint64 // Size of the RLP encoded witness
+	CompressedSize uint64 // Size of the snappy compressed RLP encoding
+}
+
+// witnessInfoKey = witnessInfoPrefix + block hash
+func witnessInfoKey(hash common.Hash) []byte {
+	return append(append([]byte{}, witnessInfoPrefix...), hash.Bytes()...)
+}
+
+// ReadWitnessInfo retrieves the witness sizes recorded for the given block, or nil if
+// none were recorded.
+func ReadWitnessInfo(db ethdb.KeyValueReader, hash common.Hash) *WitnessInfo {
+	data, _ := db.Get(witnessInfoKey(hash))
+	if len(data) == 0 {
+		return nil
+	}
+
+	if len(data) != 16 {
+		log.Error("Invalid witness info entry", "hash", hash, "len", len(data))
+		return nil
+	}
+
+	return &WitnessInfo{
+		Size:           binary.BigEndian.Uint64(data[:8]),
+		CompressedSize: binary.BigEndian.Uint64(data[8:]),
+	}
+}
+
+// WriteWitnessInfo stores the witness sizes of the given block.
+func WriteWitnessInfo(db ethdb.KeyValueWriter, hash common.Hash, info *WitnessInfo) {
+	data := make([]byte, 0, 16)
+	data = binary.BigEndian.AppendUint64(data, info.Size)
+	data = binary.BigEndian.AppendUint64(data, info.CompressedSize)
+
+	if err := db.Put(witnessInfoKey(hash), data); err != nil {
+		log.Crit("Failed to store witness info", "err", err)
+	}
+}
+
+// DeleteWitnessInfo removes the witness sizes recorded for the given block.
+func DeleteWitnessInfo(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Delete(witnessInfoKey(hash)); err != nil {
+		log.Crit("Failed to delete witness info", "err", err)
+	}
+}
@@ -254,6 +254,7 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 			delivery := queue.PopItem()
 			lastNum = delivery.number
 			WriteTxLookupEntries(batch, delivery.number, delivery.hashes)
+			indexBorTransaction(db, batch, delivery.number)
 
 			blocks++
 			txs += len(delivery.hashes)
@@ -357,6 +358,7 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 			delivery := queue.PopItem()
 			nextNum = delivery.number + 1
 			DeleteTxLookupEntries(batch, delivery.hashes)
+			unindexBorTransaction(db, batch, delivery.number)
 			txs += len(delivery.hashes)
 			blocks++
 
@@ -441,6 +443,12 @@ func PruneTransactionIndex(db ethdb.Database, pruneBlock uint64) {
 		}
 		return false
 	})
+	DeleteAllBorTxLookupEntries(db, func(txhash common.Hash, v []byte) bool {
+		return len(v) <= 8 && decodeNumber(v) < pruneBlock
+	})
+	DeleteAllStateSyncL1Lookups(db, func(number uint64) bool {
+		return number < pruneBlock
+	})
 	WriteTxIndexTail(db, pruneBlock)
 }
 
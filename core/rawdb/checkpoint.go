@@ -8,7 +8,8 @@ import (
 )
 
 var (
-	lastCheckpoint = []byte("LastCheckpoint")
+	lastCheckpoint       = []byte("LastCheckpoint")
+	lastCheckpointStatus = []byte("LastCheckpointStatus")
 
 	ErrEmptyLastFinality                    = errors.New("empty response while getting last finality")
 	ErrIncorrectFinality                    = errors.New("last checkpoint in the DB is incorrect")
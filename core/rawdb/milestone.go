@@ -13,9 +13,10 @@ import (
 )
 
 var (
-	lastMilestone      = []byte("LastMilestone")
-	lockFieldKey       = []byte("LockField")
-	futureMilestoneKey = []byte("FutureMilestoneField")
+	lastMilestone       = []byte("LastMilestone")
+	lockFieldKey        = []byte("LockField")
+	futureMilestoneKey  = []byte("FutureMilestoneField")
+	lastMilestoneStatus = []byte("LastMilestoneStatus")
 )
 
 type Finality struct {
@@ -23,6 +24,14 @@ type Finality struct {
 	Hash  common.Hash
 }
 
+// FinalityStatus records when the last milestone or checkpoint was processed locally
+// along with the timestamp of the block it finalizes.
+type FinalityStatus struct {
+	Block       uint64 // End block of the milestone or checkpoint
+	BlockTime   uint64 // Timestamp of the end block
+	ProcessedAt uint64 // Unix time at which the entry was processed
+}
+
 type LockField struct {
 	Val    bool
 	Block  uint64
@@ -71,6 +80,14 @@ func PurgeWhitelistedEntriesFromDb(db ethdb.KeyValueWriter) {
 	if err != nil {
 		log.Error("Error deleting last checkpoint entry", "err", err)
 	}
+	err = db.Delete(lastMilestoneStatus)
+	if err != nil {
+		log.Error("Error deleting last milestone status entry", "err", err)
+	}
+	err = db.Delete(lastCheckpointStatus)
+	if err != nil {
+		log.Error("Error deleting last checkpoint status entry", "err", err)
+	}
 }
 
 func ReadFinality[T BlockFinality[T]](db ethdb.KeyValueReader) (uint64, common.Hash, error) {
@@ -118,6 +135,50 @@ func WriteLastFinality[T BlockFinality[T]](db ethdb.KeyValueWriter, block uint64
 	return nil
 }
 
+// ReadFinalityStatus retrieves the processing status of the last milestone or checkpoint.
+func ReadFinalityStatus[T BlockFinality[T]](db ethdb.KeyValueReader) (*FinalityStatus, error) {
+	key := getStatusKey[T]()
+
+	data, err := db.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: empty response for %s", err, string(key))
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w for %s", ErrEmptyLastFinality, string(key))
+	}
+
+	var status FinalityStatus
+	if err = json.Unmarshal(data, &status); err != nil {
+		log.Error(fmt.Sprintf("Unable to unmarshal the %s in database", string(key)), "err", err)
+
+		return nil, fmt.Errorf("%w(%v) for %s, data %v(%q)",
+			ErrIncorrectFinality, err, string(key), data, string(data))
+	}
+
+	return &status, nil
+}
+
+// WriteFinalityStatus stores the processing status of the last milestone or checkpoint.
+func WriteFinalityStatus[T BlockFinality[T]](db ethdb.KeyValueWriter, status *FinalityStatus) error {
+	key := getStatusKey[T]()
+
+	enc, err := json.Marshal(status)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to marshal the %s struct", string(key)), "err", err)
+
+		return fmt.Errorf("%w: %v for %s struct", ErrIncorrectFinalityToStore, err, string(key))
+	}
+
+	if err := db.Put(key, enc); err != nil {
+		log.Error(fmt.Sprintf("Failed to store the %s struct", string(key)), "err", err)
+
+		return fmt.Errorf("%w: %v for %s struct", ErrDBNotResponding, err, string(key))
+	}
+
+	return nil
+}
+
 type BlockFinality[T any] interface {
 	set(block uint64, hash common.Hash)
 	clone() T
@@ -240,3 +301,16 @@ func ReadFutureMilestoneList(db ethdb.KeyValueReader) ([]uint64, map[uint64]comm
 
 	return order, list, nil
 }
+
+func getStatusKey[T BlockFinality[T]]() []byte {
+	lastT := generics.Empty[T]().clone()
+
+	switch any(lastT).(type) {
+	case *Milestone:
+		return lastMilestoneStatus
+	case *Checkpoint:
+		return lastCheckpointStatus
+	}
+
+	return nil
+}
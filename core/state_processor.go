@@ -24,23 +24,29 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	cmath "github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
 )
 
+// systemSenderTxCounter counts the transactions rejected while processing blocks for
+// being sent from the system address.
+var systemSenderTxCounter = metrics.NewRegisteredCounter("chain/txs/systemsender", nil)
+
 // StateProcessor is a basic Processor, which takes care of transitioning
 // state from one point to another.
 //
 // StateProcessor implements Processor.
 type StateProcessor struct {
-	config     *params.ChainConfig // Chain configuration options
-	chain      *HeaderChain        // Canonical header chain
-	blockchain *BlockChain
+	config     *params.ChainConfig         // Chain configuration options
+	chain      *HeaderChain                // Canonical header chain
+	blockchain consensus.ChainHeaderReader // Chain handed to the consensus engine
 }
 
 // NewStateProcessor initialises a new StateProcessor.
@@ -110,6 +116,9 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		}
 
 		msg, err := TransactionToMessage(tx, signer, header.BaseFee)
+		if err == nil {
+			err = validateSender(p.config, header.Number, msg.From)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
 		}
@@ -286,10 +295,27 @@ func ApplyTransaction(evm *vm.EVM, gp *GasPool, statedb *state.StateDB, header *
 	if err != nil {
 		return nil, err
 	}
+	if err := validateSender(evm.ChainConfig(), header.Number, msg.From); err != nil {
+		return nil, err
+	}
 	// Create a new context to be used in the EVM environment
 	return ApplyTransactionWithEVM(msg, gp, statedb, header.Number, header.Hash(), tx, usedGas, evm, interrupt)
 }
 
+// validateSender rejects user transactions sent from the system address from the bor
+// SystemSenderCheck fork on. Results of system calls would be indistinguishable from the
+// ones of such transactions.
+func validateSender(config *params.ChainConfig, number *big.Int, from common.Address) error {
+	if config.Bor == nil || !config.Bor.IsSystemSenderCheck(number) {
+		return nil
+	}
+	if from == params.SystemAddress {
+		systemSenderTxCounter.Inc(1)
+		return ErrSystemSender
+	}
+	return nil
+}
+
 // ProcessBeaconBlockRoot applies the EIP-4788 system call to the beacon block root
 // contract. This method is exported to be used in tests.
 func ProcessBeaconBlockRoot(beaconRoot common.Hash, evm *vm.EVM) {
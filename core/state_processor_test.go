@@ -18,6 +18,7 @@ package core
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"math"
 	"math/big"
 	"testing"
@@ -34,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/testtx"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/holiman/uint256"
@@ -357,6 +359,45 @@ func TestStateProcessorErrors(t *testing.T) {
 	}
 }
 
+// TestStateProcessorSystemSender tests that blocks containing transactions sent from
+// the system address are rejected from the bor SystemSenderCheck fork on.
+func TestStateProcessorSystemSender(t *testing.T) {
+	config := *params.MergedTestChainConfig
+	config.Bor = &params.BorConfig{SystemSenderCheckBlock: big.NewInt(1)}
+
+	if err := validateSender(&config, big.NewInt(0), params.SystemAddress); err != nil {
+		t.Fatalf("system sender rejected before the fork: %v", err)
+	}
+	var (
+		signer = types.LatestSigner(&config)
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		db     = rawdb.NewMemoryDatabase()
+		gspec  = &Genesis{
+			Config: &config,
+			Alloc: types.GenesisAlloc{
+				params.SystemAddress: types.Account{Balance: big.NewInt(1000000000000000000)},
+			},
+		}
+		blockchain, _ = NewBlockChain(db, nil, gspec, nil, beacon.New(ethash.NewFaker()), vm.Config{}, nil, nil, nil)
+	)
+	defer blockchain.Stop()
+
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(0), params.TxGas, big.NewInt(875000000), nil), signer, key)
+
+	// Forge the signature so that the transaction recovers to the system address
+	testtx.ForgeSender(t, tx, signer, params.SystemAddress)
+
+	rejected := systemSenderTxCounter.Snapshot().Count()
+
+	block := GenerateBadBlock(gspec.ToBlock(), beacon.New(ethash.NewFaker()), types.Transactions{tx}, gspec.Config, false)
+	if _, err := blockchain.InsertChain(types.Blocks{block}); !errors.Is(err, ErrSystemSender) {
+		t.Fatalf("have %v, want %v", err, ErrSystemSender)
+	}
+	if have := systemSenderTxCounter.Snapshot().Count(); have != rejected+1 {
+		t.Errorf("rejection not counted: have %d, want %d", have, rejected+1)
+	}
+}
+
 // GenerateBadBlock constructs a "block" which contains the transactions. The transactions are not expected to be
 // valid, and no proper post-state can be made. But from the perspective of the blockchain, the block is sufficiently
 // valid to be considered for import:
@@ -18,21 +18,55 @@ package core
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/beacon"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/ethereum/go-ethereum/triedb"
 )
 
+var (
+	witnessUnusedNodesHistogram = metrics.NewRegisteredHistogram("stateless/witness/unused_nodes", nil, metrics.NewExpDecaySample(1028, 0.015))
+	witnessUnusedBytesHistogram = metrics.NewRegisteredHistogram("stateless/witness/unused_bytes", nil, metrics.NewExpDecaySample(1028, 0.015))
+
+	// witnessEventDropMeter counts the generated witnesses dropped because the queue was
+	// full, e.g. because of slow witness event subscribers.
+	witnessEventDropMeter = metrics.NewRegisteredMeter("stateless/witness/events/dropped", nil)
+
+	witnessResultHitCounter  = metrics.NewRegisteredCounter("stateless/results/hit", nil)
+	witnessResultMissCounter = metrics.NewRegisteredCounter("stateless/results/miss", nil)
+)
+
+const (
+	// witnessResultCacheTTL is how long the result of a stateless execution is kept
+	// for executions of the same block with the same witness.
+	witnessResultCacheTTL = time.Minute
+
+	// witnessResultCacheLimit is the maximum number of stateless execution results kept.
+	witnessResultCacheLimit = 64
+)
+
+// WitnessUsage reports the witness state nodes which a stateless execution never
+// resolved, i.e. which were collected into the witness needlessly.
+type WitnessUsage struct {
+	UnusedNodes []common.Hash // Hashes of the unused state nodes, sorted
+	UnusedBytes int           // Total size of the unused state nodes
+}
+
 // ExecuteStateless runs a stateless execution based on a witness, verifies
 // everything it can locally and returns the state root and receipt root, that
 // need the other side to explicitly check.
@@ -43,6 +77,16 @@ import (
 //
 // TODO(karalabe): Would be nice to resolve both issues above somehow and move it.
 func ExecuteStateless(config *params.ChainConfig, vmconfig vm.Config, block *types.Block, witness *stateless.Witness) (common.Hash, common.Hash, error) {
+	stateRoot, receiptRoot, _, err := executeStateless(context.Background(), config, vmconfig, block, witness, beacon.New(ethash.NewFaker()), nil)
+	return stateRoot, receiptRoot, err
+}
+
+// executeStateless is ExecuteStateless with a custom consensus engine. Bor executes system
+// calls (span commits and state syncs) at sprint boundaries which need access to the
+// blockchain (e.g. for fetching state sync events), while all the state they touch is
+// still read from the witness. It also reports the witness state nodes left unused by
+// the execution.
+func executeStateless(ctx context.Context, config *params.ChainConfig, vmconfig vm.Config, block *types.Block, witness *stateless.Witness, engine consensus.Engine, blockchain *BlockChain) (common.Hash, common.Hash, *WitnessUsage, error) {
 	// Sanity check if the supplied block accidentally contains a set root or
 	// receipt hash. If so, be very loud, but still continue.
 	if block.Root() != (common.Hash{}) {
@@ -52,31 +96,292 @@ func ExecuteStateless(config *params.ChainConfig, vmconfig vm.Config, block *typ
 		log.Error("stateless runner received receipt root it's expected to calculate (faulty consensus client)", "block", block.Number())
 	}
 	// Create and populate the state database to serve as the stateless backend
-	memdb := witness.MakeHashDB()
+	memdb, tracker := witness.MakeTrackedHashDB()
 	db, err := state.New(witness.Root(), state.NewDatabase(triedb.NewDatabase(memdb, triedb.HashDefaults), nil))
 	if err != nil {
-		return common.Hash{}, common.Hash{}, err
+		return common.Hash{}, common.Hash{}, nil, err
 	}
 	// Create a blockchain that is idle, but can be used to access headers through
 	headerChain := &HeaderChain{
 		config:      config,
 		chainDb:     memdb,
 		headerCache: lru.NewCache[common.Hash, *types.Header](256),
-		engine:      beacon.New(ethash.NewFaker()),
+		engine:      engine,
+	}
+	processor := NewStateProcessor(config, headerChain, blockchain)
+	if blockchain != nil {
+		processor.blockchain = witnessChain{blockchain}
 	}
-	processor := NewStateProcessor(config, headerChain, nil)
 	validator := NewBlockValidator(config, nil) // No chain, we only validate the state, not the block
 
 	// Run the stateless blocks processing and self-validate certain fields
-	res, err := processor.Process(block, db, vmconfig, context.Background())
+	res, err := processor.Process(block, db, vmconfig, ctx)
 	if err != nil {
-		return common.Hash{}, common.Hash{}, err
+		return common.Hash{}, common.Hash{}, nil, err
 	}
 	if err = validator.ValidateState(block, db, res, true); err != nil {
-		return common.Hash{}, common.Hash{}, err
+		return common.Hash{}, common.Hash{}, nil, err
 	}
 	// Almost everything validated, but receipt and state root needs to be returned
 	receiptRoot := types.DeriveSha(res.Receipts, trie.NewStackTrie(nil))
 	stateRoot := db.IntermediateRoot(config.IsEIP158(block.Number()))
-	return stateRoot, receiptRoot, nil
+
+	// All the state the block touches was resolved, anything left over in the
+	// witness is garbage
+	unused, size := tracker.Unused()
+	witnessUnusedNodesHistogram.Update(int64(len(unused)))
+	witnessUnusedBytesHistogram.Update(int64(size))
+
+	return stateRoot, receiptRoot, &WitnessUsage{UnusedNodes: unused, UnusedBytes: size}, nil
+}
+
+// GenerateWitness re-executes the given block on top of its parent state and returns
+// the witness collected during the execution. The parent state must be available. The
+// execution is aborted once the context is cancelled.
+func (bc *BlockChain) GenerateWitness(ctx context.Context, block *types.Block) (*stateless.Witness, error) {
+	witness, err := stateless.NewWitness(block.Header(), bc)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := bc.StateAt(witness.Root())
+	if err != nil {
+		return nil, err
+	}
+	statedb.StartPrefetcher("witness", witness)
+	defer statedb.StopPrefetcher()
+
+	processor := NewStateProcessor(bc.chainConfig, bc.hc, bc)
+	processor.blockchain = witnessChain{bc}
+
+	res, err := processor.Process(block, statedb, bc.vmConfig, ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Validating the state computes the post state root, which pulls the modified
+	// trie nodes into the witness
+	if err := bc.validator.ValidateState(block, statedb, res, false); err != nil {
+		return nil, err
+	}
+	return witness, nil
+}
+
+// witnessChain is the chain handed to the consensus engine when a block is re-executed
+// to generate or verify a witness. The re-execution runs alongside the block imports,
+// so the state sync events bor sets while finalizing the block are dropped instead of
+// overwriting the ones of the block being imported.
+type witnessChain struct {
+	*BlockChain
+}
+
+// SetStateSync implements BorStateSyncer, discarding the state sync events.
+func (witnessChain) SetStateSync([]*types.StateSyncData) {}
+
+// ExecuteWitness executes the given block statelessly against the witness and returns
+// the computed state root and receipt root, along with the witness state nodes left
+// unused. The state and receipt roots of the block are ignored, it's up to the caller
+// to compare them against the returned ones. The execution is aborted once the context
+// is cancelled.
+func (bc *BlockChain) ExecuteWitness(ctx context.Context, block *types.Block, witness *stateless.Witness) (common.Hash, common.Hash, *WitnessUsage, error) {
+	// The same block may be verified with the same witness from multiple sources,
+	// only execute it once. The witness hash covers all its contents, so a result
+	// is never reused for a different witness.
+	witnessHash, err := witness.Hash()
+	if err != nil {
+		return common.Hash{}, common.Hash{}, nil, err
+	}
+	key := witnessResultKey{block: block.Hash(), witness: witnessHash}
+
+	if res := bc.witnessResults.get(key, time.Now()); res != nil {
+		witnessResultHitCounter.Inc(1)
+		return res.stateRoot, res.receiptRoot, res.usage, nil
+	}
+	witnessResultMissCounter.Inc(1)
+
+	stateRoot, receiptRoot, usage, err := bc.executeWitness(ctx, block, witness)
+	if err != nil {
+		return common.Hash{}, common.Hash{}, nil, err
+	}
+	bc.witnessResults.add(key, &witnessResult{stateRoot: stateRoot, receiptRoot: receiptRoot, usage: usage}, time.Now())
+
+	if stateRoot != block.Root() || receiptRoot != block.ReceiptHash() {
+		bc.reportBadWitness(block, witness, stateRoot, receiptRoot)
+	}
+	return stateRoot, receiptRoot, usage, nil
+}
+
+// reportBadWitness records the block and the witness of a stateless execution which
+// computed different roots than the ones of the block, for debugging.
+func (bc *BlockChain) reportBadWitness(block *types.Block, witness *stateless.Witness, stateRoot common.Hash, receiptRoot common.Hash) {
+	log.Error("Stateless execution root mismatch", "number", block.Number(), "hash", block.Hash(),
+		"stateRoot", stateRoot, "expectedStateRoot", block.Root(), "receiptRoot", receiptRoot, "expectedReceiptRoot", block.ReceiptHash())
+
+	data, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		log.Error("Failed to encode bad witness", "number", block.Number(), "hash", block.Hash(), "err", err)
+		return
+	}
+	rawdb.WriteBadBlock(bc.db, block)
+	rawdb.WriteBadWitness(bc.db, &rawdb.BadWitness{
+		Number:              block.NumberU64(),
+		Hash:                block.Hash(),
+		StateRoot:           stateRoot,
+		ExpectedStateRoot:   block.Root(),
+		ReceiptRoot:         receiptRoot,
+		ExpectedReceiptRoot: block.ReceiptHash(),
+	}, data)
+}
+
+// executeWitness executes the given block statelessly against the witness.
+func (bc *BlockChain) executeWitness(ctx context.Context, block *types.Block, witness *stateless.Witness) (common.Hash, common.Hash, *WitnessUsage, error) {
+	// Remove critical computed fields from the block to force true recalculation
+	header := block.Header()
+	header.Root = common.Hash{}
+	header.ReceiptHash = common.Hash{}
+
+	task := types.NewBlockWithHeader(header).WithBody(*block.Body())
+
+	// Bor needs its own engine to replay the system calls at sprint boundaries
+	// against the witness.
+	if bc.chainConfig.Bor != nil {
+		return executeStateless(ctx, bc.chainConfig, bc.vmConfig, task, witness, bc.engine, bc)
+	}
+	return executeStateless(ctx, bc.chainConfig, bc.vmConfig, task, witness, beacon.New(ethash.NewFaker()), nil)
+}
+
+// SendWitnessEvent records the encoded sizes of the witness of the given block and
+// publishes the witness to the witness event subscribers.
+func (bc *BlockChain) SendWitnessEvent(block *types.Block, witness *stateless.Witness, source string, duration time.Duration) {
+	size, err := witness.EncodedSize()
+	if err != nil {
+		log.Debug("Failed to encode witness", "number", block.Number(), "hash", block.Hash(), "err", err)
+		return
+	}
+	compressed, err := witness.CompressedSize()
+	if err != nil {
+		log.Debug("Failed to compress witness", "number", block.Number(), "hash", block.Hash(), "err", err)
+		return
+	}
+	rawdb.WriteWitnessInfo(bc.db, block.Hash(), &rawdb.WitnessInfo{Size: uint64(size), CompressedSize: uint64(compressed)})
+
+	if bc.witnessScope.Count() == 0 {
+		return
+	}
+	bc.witnessFeed.Send(WitnessEvent{
+		BlockHash:   block.Hash(),
+		Number:      block.NumberU64(),
+		Source:      source,
+		EncodedSize: size,
+		Duration:    duration,
+	})
+}
+
+// witnessEventQueueSize is the number of generated witnesses waiting to be recorded and
+// published, beyond which new ones are dropped rather than delaying the block imports.
+const witnessEventQueueSize = 64
+
+// witnessEventTask is a generated witness waiting to be recorded and published.
+type witnessEventTask struct {
+	block    *types.Block
+	witness  *stateless.Witness
+	duration time.Duration
+}
+
+// queueWitnessEvent hands the witness generated while importing the given block over to
+// the witness event loop, without blocking. The witness is dropped if the loop can't
+// keep up.
+func (bc *BlockChain) queueWitnessEvent(block *types.Block, witness *stateless.Witness, duration time.Duration) {
+	select {
+	case bc.witnessEvents <- witnessEventTask{block: block, witness: witness, duration: duration}:
+	default:
+		witnessEventDropMeter.Mark(1)
+		log.Debug("Dropping witness event, queue full", "number", block.Number(), "hash", block.Hash())
+	}
+}
+
+// witnessEventLoop records and publishes the witnesses generated while importing blocks,
+// off the import path, until the chain is stopped.
+func (bc *BlockChain) witnessEventLoop() {
+	defer bc.wg.Done()
+
+	for {
+		select {
+		case task := <-bc.witnessEvents:
+			bc.SendWitnessEvent(task.block, task.witness, WitnessSourceGenerated, task.duration)
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+// witnessResultKey identifies a stateless execution by the block and the witness.
+type witnessResultKey struct {
+	block   common.Hash
+	witness common.Hash
+}
+
+// witnessResult is the result of a successful stateless execution.
+type witnessResult struct {
+	stateRoot   common.Hash
+	receiptRoot common.Hash
+	usage       *WitnessUsage
+	expiry      time.Time
+}
+
+// witnessResultCache holds the recent stateless execution results, until their block
+// becomes canonical or they expire.
+type witnessResultCache struct {
+	results map[witnessResultKey]*witnessResult
+	lock    sync.Mutex
+}
+
+func newWitnessResultCache() *witnessResultCache {
+	return &witnessResultCache{results: make(map[witnessResultKey]*witnessResult)}
+}
+
+// get returns the unexpired result of the given execution, if any.
+func (c *witnessResultCache) get(key witnessResultKey, now time.Time) *witnessResult {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	res := c.results[key]
+	if res == nil {
+		return nil
+	}
+	if now.After(res.expiry) {
+		delete(c.results, key)
+		return nil
+	}
+	return res
+}
+
+// add caches the result of the given execution, dropping the expired results. The
+// result isn't cached if the cache is still full afterwards.
+func (c *witnessResultCache) add(key witnessResultKey, res *witnessResult, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.results) >= witnessResultCacheLimit {
+		for k, r := range c.results {
+			if now.After(r.expiry) {
+				delete(c.results, k)
+			}
+		}
+		if len(c.results) >= witnessResultCacheLimit {
+			return
+		}
+	}
+	res.expiry = now.Add(witnessResultCacheTTL)
+	c.results[key] = res
+}
+
+// evict drops the results of the executions of the given block.
+func (c *witnessResultCache) evict(block common.Hash) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for key := range c.results {
+		if key.block == block {
+			delete(c.results, key)
+		}
+	}
 }
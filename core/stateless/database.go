@@ -17,6 +17,9 @@
 package stateless
 
 import (
+	"slices"
+	"sync"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -34,6 +37,23 @@ import (
 //
 // Acceleration structures built would need to explicitly validate the witness.
 func (w *Witness) MakeHashDB() ethdb.Database {
+	return w.makeHashDB(nil)
+}
+
+// MakeTrackedHashDB is MakeHashDB, additionally returning a tracker recording which
+// of the witness state nodes are resolved from the database.
+func (w *Witness) MakeTrackedHashDB() (ethdb.Database, *NodeTracker) {
+	tracker := &NodeTracker{
+		nodes: make(map[common.Hash]int, len(w.State)),
+		used:  make(map[common.Hash]struct{}),
+	}
+	tracker.Database = w.makeHashDB(tracker.nodes)
+	return tracker, tracker
+}
+
+// makeHashDB implements MakeHashDB, gathering the sizes of the state nodes keyed
+// by hash into sizes if it's non-nil.
+func (w *Witness) makeHashDB(sizes map[common.Hash]int) ethdb.Database {
 	var (
 		memdb  = rawdb.NewMemoryDatabase()
 		hasher = crypto.NewKeccakState()
@@ -62,6 +82,54 @@ func (w *Witness) MakeHashDB() ethdb.Database {
 		hasher.Read(hash)
 
 		rawdb.WriteLegacyTrieNode(memdb, common.BytesToHash(hash), blob)
+		if sizes != nil {
+			sizes[common.BytesToHash(hash)] = len(blob)
+		}
 	}
 	return memdb
 }
+
+// NodeTracker is a database wrapper recording which of the witness state nodes are
+// resolved during a stateless execution. Witness state nodes never resolved were
+// over-collected by the witness generator.
+type NodeTracker struct {
+	ethdb.Database
+
+	nodes map[common.Hash]int      // Sizes of the witness state nodes, keyed by hash
+	used  map[common.Hash]struct{} // Witness state nodes resolved so far
+	lock  sync.Mutex
+}
+
+// Get implements ethdb.KeyValueReader, recording the resolution of witness state
+// nodes, which are keyed by their plain hash.
+func (t *NodeTracker) Get(key []byte) ([]byte, error) {
+	if len(key) == common.HashLength {
+		hash := common.BytesToHash(key)
+		if _, ok := t.nodes[hash]; ok {
+			t.lock.Lock()
+			t.used[hash] = struct{}{}
+			t.lock.Unlock()
+		}
+	}
+	return t.Database.Get(key)
+}
+
+// Unused returns the hashes of the witness state nodes not resolved so far, sorted,
+// along with their total size in bytes.
+func (t *NodeTracker) Unused() ([]common.Hash, int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var (
+		hashes []common.Hash
+		size   int
+	)
+	for hash, n := range t.nodes {
+		if _, ok := t.used[hash]; !ok {
+			hashes = append(hashes, hash)
+			size += n
+		}
+	}
+	slices.SortFunc(hashes, common.Hash.Cmp)
+	return hashes, size
+}
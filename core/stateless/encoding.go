@@ -18,30 +18,46 @@ package stateless
 
 import (
 	"io"
+	"slices"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/snappy"
 )
 
 // toExtWitness converts our internal witness representation to the consensus one.
-func (w *Witness) toExtWitness() *extWitness {
-	ext := &extWitness{
+//
+// The codes and trie nodes are sorted so that the same witness always encodes to
+// the same bytes, regardless of the map iteration order. They are kept as strings,
+// which RLP encodes exactly like byte slices, to avoid copying every item.
+func (w *Witness) toExtWitness() *extWitnessEnc {
+	ext := &extWitnessEnc{
 		Headers: w.Headers,
 	}
-	ext.Codes = make([][]byte, 0, len(w.Codes))
+	ext.Codes = make([]string, 0, len(w.Codes))
 	for code := range w.Codes {
-		ext.Codes = append(ext.Codes, []byte(code))
+		ext.Codes = append(ext.Codes, code)
 	}
-	ext.State = make([][]byte, 0, len(w.State))
+	slices.Sort(ext.Codes)
+
+	ext.State = make([]string, 0, len(w.State))
 	for node := range w.State {
-		ext.State = append(ext.State, []byte(node))
+		ext.State = append(ext.State, node)
 	}
+	slices.Sort(ext.State)
+
 	return ext
 }
 
 // fromExtWitness converts the consensus witness format into our internal one.
 func (w *Witness) fromExtWitness(ext *extWitness) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
 	w.Headers = ext.Headers
+	w.encoded = nil
 
 	w.Codes = make(map[string]struct{}, len(ext.Codes))
 	for _, code := range ext.Codes {
@@ -55,8 +71,61 @@ func (w *Witness) fromExtWitness(ext *extWitness) error {
 }
 
 // EncodeRLP serializes a witness as RLP.
+//
+// The encoding is cached until the witness is extended again, so serving the same
+// witness to multiple peers only serializes it once.
 func (w *Witness) EncodeRLP(wr io.Writer) error {
-	return rlp.Encode(wr, w.toExtWitness())
+	enc, err := w.encodeRLP()
+	if err != nil {
+		return err
+	}
+	_, err = wr.Write(enc)
+	return err
+}
+
+// encodeRLP returns the cached RLP encoding of the witness, creating it if needed.
+// The returned slice must not be modified.
+func (w *Witness) encodeRLP() ([]byte, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.encoded == nil {
+		enc, err := rlp.EncodeToBytes(w.toExtWitness())
+		if err != nil {
+			return nil, err
+		}
+		w.encoded = enc
+	}
+	return w.encoded, nil
+}
+
+// EncodedSize returns the size of the RLP encoding of the witness.
+func (w *Witness) EncodedSize() (int, error) {
+	enc, err := w.encodeRLP()
+	if err != nil {
+		return 0, err
+	}
+	return len(enc), nil
+}
+
+// CompressedSize returns the size of the snappy compressed RLP encoding of the witness.
+func (w *Witness) CompressedSize() (int, error) {
+	enc, err := w.encodeRLP()
+	if err != nil {
+		return 0, err
+	}
+	return len(snappy.Encode(nil, enc)), nil
+}
+
+// Hash returns the keccak256 hash of the RLP encoding of the witness. As the encoding
+// is canonical, witnesses with the same contents have the same hash regardless of the
+// order they were assembled in.
+func (w *Witness) Hash() (common.Hash, error) {
+	enc, err := w.encodeRLP()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(enc), nil
 }
 
 // DecodeRLP decodes a witness from RLP.
@@ -74,3 +143,11 @@ type extWitness struct {
 	Codes   [][]byte
 	State   [][]byte
 }
+
+// extWitnessEnc is the encoding counterpart of extWitness, with the codes and trie
+// nodes referencing the witness strings instead of copies of them.
+type extWitnessEnc struct {
+	Headers []*types.Header
+	Codes   []string
+	State   []string
+}
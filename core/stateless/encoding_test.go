@@ -0,0 +1,226 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stateless
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func newTestWitness() *Witness {
+	parent := &types.Header{
+		ParentHash: common.HexToHash("0x01"),
+		Number:     big.NewInt(100),
+		Root:       common.HexToHash("0x02"),
+		Difficulty: big.NewInt(1),
+		GasLimit:   30_000_000,
+	}
+	w := &Witness{
+		Headers: []*types.Header{parent},
+		Codes:   make(map[string]struct{}),
+		State:   make(map[string]struct{}),
+	}
+	for i := 0; i < 16; i++ {
+		w.AddCode([]byte{0x60, byte(i), 0x60, 0x00, 0x55})
+		w.AddState(map[string]struct{}{string(bytes.Repeat([]byte{byte(i)}, 32+i)): {}})
+	}
+	return w
+}
+
+// Tests that a witness written to a file and read back re-encodes to the exact
+// same bytes.
+func TestWitnessFileRoundTrip(t *testing.T) {
+	witness := newTestWitness()
+
+	enc, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "witness.rlp")
+	if err := os.WriteFile(path, enc, 0600); err != nil {
+		t.Fatalf("failed to write witness: %v", err)
+	}
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read witness: %v", err)
+	}
+	var decoded Witness
+	if err := rlp.DecodeBytes(blob, &decoded); err != nil {
+		t.Fatalf("failed to decode witness: %v", err)
+	}
+	if decoded.Root() != witness.Root() {
+		t.Errorf("pre-state root mismatch: have %x, want %x", decoded.Root(), witness.Root())
+	}
+	if len(decoded.Codes) != len(witness.Codes) {
+		t.Errorf("code count mismatch: have %d, want %d", len(decoded.Codes), len(witness.Codes))
+	}
+	if len(decoded.State) != len(witness.State) {
+		t.Errorf("state node count mismatch: have %d, want %d", len(decoded.State), len(witness.State))
+	}
+	reenc, err := rlp.EncodeToBytes(&decoded)
+	if err != nil {
+		t.Fatalf("failed to re-encode witness: %v", err)
+	}
+	if !bytes.Equal(enc, reenc) {
+		t.Fatalf("re-encoded witness differs from the original")
+	}
+}
+
+// Tests that encoding the same witness multiple times yields the same bytes.
+func TestWitnessEncodingDeterministic(t *testing.T) {
+	witness := newTestWitness()
+
+	want, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		have, err := rlp.EncodeToBytes(witness.Copy())
+		if err != nil {
+			t.Fatalf("failed to encode witness: %v", err)
+		}
+		if !bytes.Equal(have, want) {
+			t.Fatalf("encoding %d differs from the first one", i)
+		}
+	}
+}
+
+// Tests that the cached encoding is dropped whenever the witness is extended.
+func TestWitnessEncodingCacheInvalidation(t *testing.T) {
+	witness := newTestWitness()
+
+	before, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	witness.AddState(map[string]struct{}{string(bytes.Repeat([]byte{0xff}, 64)): {}})
+
+	after, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	if bytes.Equal(before, after) {
+		t.Fatalf("encoding not updated after adding state")
+	}
+	witness.AddCode([]byte{0x60, 0xff})
+
+	code, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	if bytes.Equal(after, code) {
+		t.Fatalf("encoding not updated after adding code")
+	}
+	// The cached encoding must match a fresh one
+	fresh, err := rlp.EncodeToBytes(witness.Copy())
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	if !bytes.Equal(code, fresh) {
+		t.Fatalf("cached encoding differs from a fresh one")
+	}
+}
+
+// Tests that a witness can be encoded while it's being extended.
+func TestWitnessEncodingConcurrent(t *testing.T) {
+	witness := newTestWitness()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := rlp.EncodeToBytes(witness); err != nil {
+					t.Errorf("failed to encode witness: %v", err)
+					return
+				}
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				witness.AddState(map[string]struct{}{string([]byte{byte(i), byte(j)}): {}})
+				witness.AddCode([]byte{0x60, byte(i), byte(j)})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var decoded Witness
+	enc, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	if err := rlp.DecodeBytes(enc, &decoded); err != nil {
+		t.Fatalf("failed to decode witness: %v", err)
+	}
+	if len(decoded.State) != len(witness.State) {
+		t.Errorf("state node count mismatch: have %d, want %d", len(decoded.State), len(witness.State))
+	}
+	if len(decoded.Codes) != len(witness.Codes) {
+		t.Errorf("code count mismatch: have %d, want %d", len(decoded.Codes), len(witness.Codes))
+	}
+}
+
+// newLargeTestWitness creates a witness with the given number of trie nodes, sized
+// like typical branch and leaf nodes.
+func newLargeTestWitness(nodes int) *Witness {
+	witness := newTestWitness()
+
+	state := make(map[string]struct{}, nodes)
+	for i := 0; i < nodes; i++ {
+		node := make([]byte, 128+i%400)
+		binary.BigEndian.PutUint64(node, uint64(i))
+		state[string(node)] = struct{}{}
+	}
+	witness.AddState(state)
+
+	return witness
+}
+
+func BenchmarkWitnessEncode(b *testing.B) {
+	witness := newLargeTestWitness(100_000)
+
+	b.Run("fresh", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			witness.AddState(map[string]struct{}{string([]byte{0xff}): {}}) // drop the cached encoding
+			if err := witness.EncodeRLP(io.Discard); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := witness.EncodeRLP(io.Discard); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // HeaderReader is an interface to pull in headers in place of block hashes for
@@ -35,6 +36,9 @@ type HeaderReader interface {
 
 // Witness encompasses the state required to apply a set of transactions and
 // derive a post state/receipt root.
+//
+// The exported fields are kept for encoding and within-package use, their layout is
+// not stable. External code should go through the accessor methods instead.
 type Witness struct {
 	context *types.Header // Header to which this witness belongs to, with rootHash and receiptHash zeroed out
 
@@ -42,8 +46,9 @@ type Witness struct {
 	Codes   map[string]struct{} // Set of bytecodes ran or accessed
 	State   map[string]struct{} // Set of MPT state trie nodes (account and storage together)
 
-	chain HeaderReader // Chain reader to convert block hash ops to header proofs
-	lock  sync.Mutex   // Lock to allow concurrent state insertions
+	chain   HeaderReader // Chain reader to convert block hash ops to header proofs
+	encoded []byte       // Cached RLP encoding, reset whenever the witness is extended
+	lock    sync.Mutex   // Lock to allow concurrent state insertions
 }
 
 // NewWitness creates an empty witness ready for population.
@@ -72,10 +77,14 @@ func NewWitness(context *types.Header, chain HeaderReader) (*Witness, error) {
 // chain head. Under the hood, this method actually pulls in enough headers from
 // the chain to cover the block being added.
 func (w *Witness) AddBlockHash(number uint64) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
 	// Keep pulling in headers until this hash is populated
 	for int(w.context.Number.Uint64()-number) > len(w.Headers) {
 		tail := w.Headers[len(w.Headers)-1]
 		w.Headers = append(w.Headers, w.chain.GetHeader(tail.ParentHash, tail.Number.Uint64()-1))
+		w.encoded = nil
 	}
 }
 
@@ -84,7 +93,11 @@ func (w *Witness) AddCode(code []byte) {
 	if len(code) == 0 {
 		return
 	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
 	w.Codes[string(code)] = struct{}{}
+	w.encoded = nil
 }
 
 // AddState inserts a batch of MPT trie nodes into the witness.
@@ -96,6 +109,76 @@ func (w *Witness) AddState(nodes map[string]struct{}) {
 	defer w.lock.Unlock()
 
 	maps.Copy(w.State, nodes)
+	w.encoded = nil
+}
+
+// ContextHeader returns a copy of the header of the block the witness belongs to,
+// or nil for decoded witnesses.
+func (w *Witness) ContextHeader() *types.Header {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.context == nil {
+		return nil
+	}
+	return types.CopyHeader(w.context)
+}
+
+// PastHeaders returns copies of the past headers in the witness, in reverse order
+// (0=parent, 1=parent's-parent, etc).
+//
+// Note, the method can't be named Headers as it would clash with the field.
+func (w *Witness) PastHeaders() []*types.Header {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	headers := make([]*types.Header, len(w.Headers))
+	for i, header := range w.Headers {
+		headers[i] = types.CopyHeader(header)
+	}
+	return headers
+}
+
+// StateNodes returns copies of the MPT trie nodes in the witness, sorted bytewise.
+func (w *Witness) StateNodes() [][]byte {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	keys := slices.Sorted(maps.Keys(w.State))
+
+	nodes := make([][]byte, len(keys))
+	for i, node := range keys {
+		nodes[i] = []byte(node)
+	}
+	return nodes
+}
+
+// CodeHashes returns the hashes of the bytecodes in the witness, sorted.
+func (w *Witness) CodeHashes() []common.Hash {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	hashes := make([]common.Hash, 0, len(w.Codes))
+	for code := range w.Codes {
+		hashes = append(hashes, crypto.Keccak256Hash([]byte(code)))
+	}
+	slices.SortFunc(hashes, common.Hash.Cmp)
+
+	return hashes
+}
+
+// Sizes returns the total size in bytes of the trie nodes and bytecodes in the witness.
+func (w *Witness) Sizes() (stateBytes int, codeBytes int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for node := range w.State {
+		stateBytes += len(node)
+	}
+	for code := range w.Codes {
+		codeBytes += len(code)
+	}
+	return stateBytes, codeBytes
 }
 
 // Copy deep-copies the witness object.  Witness.Block isn't deep-copied as it
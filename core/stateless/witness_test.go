@@ -0,0 +1,123 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stateless
+
+import (
+	"bytes"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that the witness accessors reflect the mutations done through the
+// AddState and AddCode methods.
+func TestWitnessAccessors(t *testing.T) {
+	witness := newTestWitness()
+
+	nodes := witness.StateNodes()
+	if len(nodes) != 16 {
+		t.Fatalf("state node count mismatch: have %d, want %d", len(nodes), 16)
+	}
+	if !slices.IsSortedFunc(nodes, bytes.Compare) {
+		t.Errorf("state nodes not sorted")
+	}
+	hashes := witness.CodeHashes()
+	if len(hashes) != 16 {
+		t.Fatalf("code hash count mismatch: have %d, want %d", len(hashes), 16)
+	}
+	if !slices.IsSortedFunc(hashes, common.Hash.Cmp) {
+		t.Errorf("code hashes not sorted")
+	}
+	stateBytes, codeBytes := witness.Sizes()
+	if want := 16*32 + 15*16/2; stateBytes != want {
+		t.Errorf("state size mismatch: have %d, want %d", stateBytes, want)
+	}
+	if want := 16 * 5; codeBytes != want {
+		t.Errorf("code size mismatch: have %d, want %d", codeBytes, want)
+	}
+	// Extend the witness, including with duplicates, and check the accessors again
+	var (
+		node = []byte{0xff, 0xfe, 0xfd}
+		code = []byte{0x60, 0xff, 0x00}
+	)
+	witness.AddState(map[string]struct{}{string(node): {}, string(nodes[0]): {}})
+	witness.AddCode(code)
+	witness.AddCode(code)
+
+	if nodes := witness.StateNodes(); len(nodes) != 17 || !bytes.Equal(nodes[16], node) {
+		t.Errorf("added state node missing: have %d nodes, last %x", len(nodes), nodes[len(nodes)-1])
+	}
+	if hashes := witness.CodeHashes(); len(hashes) != 17 || !slices.Contains(hashes, crypto.Keccak256Hash(code)) {
+		t.Errorf("added code hash missing: have %d hashes", len(hashes))
+	}
+	if have, _ := witness.Sizes(); have != stateBytes+len(node) {
+		t.Errorf("state size mismatch after insertion: have %d, want %d", have, stateBytes+len(node))
+	}
+	if _, have := witness.Sizes(); have != codeBytes+len(code) {
+		t.Errorf("code size mismatch after insertion: have %d, want %d", have, codeBytes+len(code))
+	}
+	// Decoded witnesses have no context header
+	if header := witness.ContextHeader(); header != nil {
+		t.Errorf("unexpected context header: %v", header)
+	}
+}
+
+// Tests that mutating the values returned by the witness accessors doesn't
+// corrupt the witness.
+func TestWitnessAccessorsCopy(t *testing.T) {
+	witness := newTestWitness()
+	witness.context = &types.Header{Number: big.NewInt(101), Difficulty: big.NewInt(1)}
+
+	var (
+		root    = witness.Root()
+		nodes   = witness.StateNodes()
+		headers = witness.PastHeaders()
+		context = witness.ContextHeader()
+	)
+	want := make([][]byte, len(nodes))
+	for i, node := range nodes {
+		want[i] = bytes.Clone(node)
+	}
+	// Scribble over everything returned
+	for _, node := range nodes {
+		for i := range node {
+			node[i] = 0xff
+		}
+	}
+	nodes[0] = nil
+
+	headers[0].Root = common.HexToHash("0xdead")
+	headers[0] = nil
+	context.Number.SetUint64(1)
+
+	if have := witness.StateNodes(); !slices.EqualFunc(have, want, bytes.Equal) {
+		t.Errorf("state nodes corrupted by caller mutation")
+	}
+	if have := witness.PastHeaders(); len(have) != 1 || have[0] == nil || have[0].Root != root {
+		t.Errorf("past headers corrupted by caller mutation")
+	}
+	if witness.Root() != root {
+		t.Errorf("pre-state root corrupted: have %x, want %x", witness.Root(), root)
+	}
+	if have := witness.ContextHeader().Number.Uint64(); have != 101 {
+		t.Errorf("context header corrupted: have number %d, want %d", have, 101)
+	}
+}
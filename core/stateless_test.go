@@ -0,0 +1,287 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that stateless execution reports the witness state nodes it never resolved:
+// none for a witness generated by executing the block, and exactly the injected ones
+// for a witness carrying extra nodes.
+func TestExecuteWitnessUnusedNodes(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 2, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{byte(i + 1)}, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	block := blocks[1]
+
+	// A witness collected while executing the block is minimal
+	witness, err := chain.GenerateWitness(t.Context(), block)
+	if err != nil {
+		t.Fatalf("failed to generate witness: %v", err)
+	}
+	// Executions are aborted once the context is cancelled
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := chain.GenerateWitness(ctx, block); !errors.Is(err, context.Canceled) {
+		t.Fatalf("witness generation error mismatch: have %v, want %v", err, context.Canceled)
+	}
+	if _, _, _, err := chain.ExecuteWitness(ctx, block, witness); !errors.Is(err, context.Canceled) {
+		t.Fatalf("witness execution error mismatch: have %v, want %v", err, context.Canceled)
+	}
+	stateRoot, _, usage, err := chain.ExecuteWitness(t.Context(), block, witness)
+	if err != nil {
+		t.Fatalf("failed to execute witness: %v", err)
+	}
+	if stateRoot != block.Root() {
+		t.Fatalf("state root mismatch: have %x, want %x", stateRoot, block.Root())
+	}
+	if len(usage.UnusedNodes) != 0 || usage.UnusedBytes != 0 {
+		t.Fatalf("unused nodes in minimal witness: have %d (%d bytes)", len(usage.UnusedNodes), usage.UnusedBytes)
+	}
+	// Nodes injected into the witness are never resolved
+	extra := [][]byte{
+		[]byte("unused witness node"),
+		[]byte("another unused witness node"),
+	}
+	for _, node := range extra {
+		witness.AddState(map[string]struct{}{string(node): {}})
+	}
+	_, _, usage, err = chain.ExecuteWitness(t.Context(), block, witness)
+	if err != nil {
+		t.Fatalf("failed to execute witness: %v", err)
+	}
+	if len(usage.UnusedNodes) != len(extra) {
+		t.Fatalf("unused node count mismatch: have %d, want %d", len(usage.UnusedNodes), len(extra))
+	}
+	for _, node := range extra {
+		hash := crypto.Keccak256Hash(node)
+		if _, found := slices.BinarySearchFunc(usage.UnusedNodes, hash, common.Hash.Cmp); !found {
+			t.Errorf("injected node %x not reported unused", hash)
+		}
+	}
+	if want := len(extra[0]) + len(extra[1]); usage.UnusedBytes != want {
+		t.Errorf("unused size mismatch: have %d, want %d", usage.UnusedBytes, want)
+	}
+}
+
+// Tests that executing a block twice with the same witness reuses the result of the
+// first execution, but never across different witnesses, nor once the block is
+// canonical or the result expired.
+func TestExecuteWitnessResultCache(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 2, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{byte(i + 1)}, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	block := blocks[1]
+
+	witness, err := chain.GenerateWitness(t.Context(), block)
+	if err != nil {
+		t.Fatalf("failed to generate witness: %v", err)
+	}
+	execute := func(witness *stateless.Witness, wantHit bool) {
+		t.Helper()
+
+		hits, misses := witnessResultHitCounter.Snapshot().Count(), witnessResultMissCounter.Snapshot().Count()
+
+		stateRoot, _, _, err := chain.ExecuteWitness(t.Context(), block, witness)
+		if err != nil {
+			t.Fatalf("failed to execute witness: %v", err)
+		}
+		if stateRoot != block.Root() {
+			t.Fatalf("state root mismatch: have %x, want %x", stateRoot, block.Root())
+		}
+		hit := witnessResultHitCounter.Snapshot().Count() - hits
+		miss := witnessResultMissCounter.Snapshot().Count() - misses
+
+		if wantHit && (hit != 1 || miss != 0) {
+			t.Fatalf("execution not served from the cache: hits %d, misses %d", hit, miss)
+		}
+		if !wantHit && (hit != 0 || miss != 1) {
+			t.Fatalf("execution unexpectedly served from the cache: hits %d, misses %d", hit, miss)
+		}
+	}
+	// The second execution with an identical witness is a cache hit
+	execute(witness, false)
+	execute(witness.Copy(), true)
+
+	// A witness with other contents is executed again
+	extended := witness.Copy()
+	extended.AddState(map[string]struct{}{"unused witness node": {}})
+	execute(extended, false)
+	execute(extended, true)
+
+	// The results are dropped once the block is canonical
+	chain.writeHeadBlock(block)
+	execute(witness, false)
+
+	// The results expire
+	witnessHash, err := witness.Hash()
+	if err != nil {
+		t.Fatalf("failed to hash witness: %v", err)
+	}
+	key := witnessResultKey{block: block.Hash(), witness: witnessHash}
+	if chain.witnessResults.get(key, time.Now()) == nil {
+		t.Fatalf("missing execution result")
+	}
+	if chain.witnessResults.get(key, time.Now().Add(2*witnessResultCacheTTL)) != nil {
+		t.Fatalf("expired execution result served")
+	}
+}
+
+// stateSyncEngine is an ethash faker setting state sync events on the chain of every
+// block it finalizes, like bor.
+type stateSyncEngine struct {
+	consensus.Engine
+	events []*types.StateSyncData
+}
+
+func (e *stateSyncEngine) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state vm.StateDB, body *types.Body) {
+	e.Engine.Finalize(chain, header, state, body)
+
+	if syncer, ok := chain.(BorStateSyncer); ok {
+		syncer.SetStateSync(e.events)
+	}
+}
+
+// Tests that re-executing a block to generate its witness leaves the state sync events
+// of the chain untouched, as they belong to the block being imported.
+func TestGenerateWitnessKeepsStateSync(t *testing.T) {
+	t.Parallel()
+
+	var (
+		gspec  = &Genesis{Config: params.TestChainConfig}
+		engine = &stateSyncEngine{Engine: ethash.NewFaker()}
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 2, nil)
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	live := []*types.StateSyncData{{ID: 1}}
+	chain.SetStateSync(live)
+
+	engine.events = []*types.StateSyncData{{ID: 2}}
+	if _, err := chain.GenerateWitness(t.Context(), blocks[1]); err != nil {
+		t.Fatalf("failed to generate witness: %v", err)
+	}
+	if have := chain.GetStateSync(); len(have) != 1 || have[0].ID != 1 {
+		t.Fatalf("state sync events overwritten: have %v, want %v", have, live)
+	}
+}
+
+// Tests that the witnesses generated while importing blocks are queued without blocking
+// the imports, even if a subscriber stops reading the witness events.
+func TestWitnessEventsSlowSubscriber(t *testing.T) {
+	gspec := &Genesis{Config: params.TestChainConfig}
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 1, nil)
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	witness, err := chain.GenerateWitness(t.Context(), blocks[0])
+	if err != nil {
+		t.Fatalf("failed to generate witness: %v", err)
+	}
+	// A subscriber not reading its events stalls the witness event loop
+	events := make(chan WitnessEvent)
+	sub := chain.SubscribeWitnessEvent(events)
+	defer sub.Unsubscribe()
+
+	dropped := witnessEventDropMeter.Snapshot().Count()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for i := 0; i < witnessEventQueueSize+2; i++ {
+			chain.queueWitnessEvent(blocks[0], witness, 0)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("queueing witness events blocked")
+	}
+	if have := witnessEventDropMeter.Snapshot().Count() - dropped; have == 0 {
+		t.Fatalf("no witness event dropped")
+	}
+	// The subscriber still gets the queued events once it reads them
+	select {
+	case ev := <-events:
+		if ev.BlockHash != blocks[0].Hash() || ev.Source != WitnessSourceGenerated {
+			t.Fatalf("witness event mismatch: %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no witness event published")
+	}
+}
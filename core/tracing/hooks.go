@@ -186,6 +186,21 @@ type (
 
 	// BlockHashReadHook is called when EVM reads the blockhash of a block.
 	BlockHashReadHook = func(blockNumber uint64, hash common.Hash)
+
+	/*
+		- Bor consensus events -
+	*/
+
+	// SpanCommittedHook is called when a bor block being imported commits a new span
+	// to the validator contract, along with the producers selected for the span.
+	SpanCommittedHook = func(spanId uint64, startBlock uint64, endBlock uint64, producers []common.Address)
+
+	// MilestoneProcessedHook is called when a bor milestone is whitelisted.
+	MilestoneProcessedHook = func(number uint64, hash common.Hash)
+
+	// StateSyncAppliedHook is called when a bor block being imported commits a state
+	// sync event. `success` is false if the call to the state receiver failed.
+	StateSyncAppliedHook = func(eventId uint64, blockNumber uint64, success bool)
 )
 
 type Hooks struct {
@@ -216,6 +231,10 @@ type Hooks struct {
 	OnLog           LogHook
 	// Block hash read
 	OnBlockHashRead BlockHashReadHook
+	// Bor consensus events
+	OnSpanCommitted      SpanCommittedHook
+	OnMilestoneProcessed MilestoneProcessedHook
+	OnStateSyncApplied   StateSyncAppliedHook
 }
 
 // BalanceChangeReason is used to indicate the reason for a balance change, useful
@@ -18,6 +18,7 @@ package core
 
 import (
 	"fmt"
+	"math/big"
 	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -169,6 +170,8 @@ func (indexer *txIndexer) repair(head uint64) {
 		indexer.tail.Store(nil)
 		rawdb.DeleteTxIndexTail(indexer.db)
 		rawdb.DeleteAllTxLookupEntries(indexer.db, nil)
+		rawdb.DeleteAllBorTxLookupEntries(indexer.db, nil)
+		rawdb.DeleteAllStateSyncL1Lookups(indexer.db, nil)
 		log.Warn("Purge transaction indexes", "head", head, "tail", *tail)
 		return
 	}
@@ -190,6 +193,8 @@ func (indexer *txIndexer) repair(head uint64) {
 		indexer.tail.Store(nil)
 		rawdb.DeleteTxIndexTail(indexer.db)
 		rawdb.DeleteAllTxLookupEntries(indexer.db, nil)
+		rawdb.DeleteAllBorTxLookupEntries(indexer.db, nil)
+		rawdb.DeleteAllStateSyncL1Lookups(indexer.db, nil)
 		log.Warn("Purge transaction indexes", "head", head, "cutoff", indexer.cutoff)
 		return
 	}
@@ -207,6 +212,12 @@ func (indexer *txIndexer) repair(head uint64) {
 			n := rawdb.DecodeTxLookupEntry(blob, indexer.db)
 			return n != nil && *n < indexer.cutoff
 		})
+		rawdb.DeleteAllBorTxLookupEntries(indexer.db, func(txhash common.Hash, blob []byte) bool {
+			return len(blob) <= 8 && new(big.Int).SetBytes(blob).Uint64() < indexer.cutoff
+		})
+		rawdb.DeleteAllStateSyncL1Lookups(indexer.db, func(number uint64) bool {
+			return number < indexer.cutoff
+		})
 		log.Warn("Purge transaction indexes below cutoff", "tail", *tail, "cutoff", indexer.cutoff)
 	}
 }
@@ -138,6 +138,63 @@ func TestTxIndexer(t *testing.T) {
 	}
 }
 
+// Tests that the bor transaction lookups of the state sync transactions follow the
+// retention of the regular transaction lookups.
+func TestTxIndexerBorLookups(t *testing.T) {
+	var (
+		gspec = &Genesis{
+			Config:  params.TestChainConfig,
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		chainHead = uint64(128)
+	)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, ethash.NewFaker(), int(chainHead), func(i int, gen *BlockGen) {})
+
+	// Every 16th block commits state syncs
+	borReceipts := make([]types.Receipts, len(receipts))
+	for i, block := range blocks {
+		if block.NumberU64()%16 == 0 {
+			borReceipts[i] = types.Receipts{{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{}}}
+		}
+	}
+	db, _ := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false, false, false)
+	defer db.Close()
+
+	_, _ = rawdb.WriteAncientBlocks(db, append([]*types.Block{gspec.ToBlock()}, blocks...), append([]types.Receipts{{}}, receipts...), append([]types.Receipts{{}}, borReceipts...), big.NewInt(0))
+
+	verifyBorLookups := func(tail uint64) {
+		t.Helper()
+
+		for _, block := range blocks {
+			number := block.NumberU64()
+			lookup := rawdb.ReadBorTxLookupEntry(db, types.GetDerivedBorTxHash(types.BorReceiptKey(number, block.Hash())))
+
+			switch want := number%16 == 0 && number >= tail; {
+			case want && (lookup == nil || *lookup != number):
+				t.Fatalf("block %d: missing bor tx lookup (tail %d)", number, tail)
+			case !want && lookup != nil:
+				t.Fatalf("block %d: unexpected bor tx lookup (tail %d)", number, tail)
+			}
+		}
+	}
+	indexer := &txIndexer{db: db}
+	for _, c := range []struct {
+		limit uint64
+		tail  uint64
+	}{
+		{limit: 0, tail: 0},
+		{limit: 64, tail: 65},
+		{limit: 16, tail: 113},
+		{limit: 0, tail: 0},
+	} {
+		indexer.limit = c.limit
+		indexer.run(chainHead, make(chan struct{}), make(chan struct{}))
+
+		verify(t, db, blocks, c.tail)
+		verifyBorLookups(c.tail)
+	}
+}
+
 func TestTxIndexerRepair(t *testing.T) {
 	var (
 		testBankKey, _  = crypto.GenerateKey()
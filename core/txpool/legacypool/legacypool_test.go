@@ -42,6 +42,8 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/testtx"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/holiman/uint256"
@@ -472,6 +474,40 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that transactions impersonating the sender of the system calls are rejected.
+func TestSystemSenderTransactions(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	testAddBalance(pool, params.SystemAddress, big.NewInt(0xffffffffffffff))
+
+	counter := metrics.GetOrRegisterCounter("txpool/invalid/systemsender", nil)
+	rejected := counter.Snapshot().Count()
+
+	tx := transaction(0, 100000, key)
+	testtx.ForgeSender(t, tx, pool.signer, params.SystemAddress)
+
+	if err, want := pool.addRemote(tx), core.ErrSystemSender; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+	if pool.all.Count() != 0 {
+		t.Errorf("system sender transaction added to the pool")
+	}
+	if have := counter.Snapshot().Count(); have <= rejected {
+		t.Errorf("rejection not counted: have %d, previously %d", have, rejected)
+	}
+	// Transactions of regular senders are still accepted
+	tx = transaction(0, 100000, key)
+	from, _ := deriveSender(tx)
+	testAddBalance(pool, from, big.NewInt(0xffffffffffffff))
+
+	if err := pool.addRemoteSync(tx); err != nil {
+		t.Errorf("failed to add regular transaction: %v", err)
+	}
+}
+
 func TestQueue(t *testing.T) {
 	t.Parallel()
 
@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -35,6 +36,10 @@ var (
 	// blobTxMinBlobGasPrice is the big.Int version of the configured protocol
 	// parameter to avoid constructing a new big integer for every transaction.
 	blobTxMinBlobGasPrice = big.NewInt(params.BlobTxMinBlobGasprice)
+
+	// systemSenderCounter counts the transactions rejected for being sent from the
+	// system address.
+	systemSenderCounter = metrics.NewRegisteredCounter("txpool/invalid/systemsender", nil)
 )
 
 // ValidationOptions define certain differences between transaction validation
@@ -109,9 +114,15 @@ func ValidateTransaction(tx *types.Transaction, head *types.Header, signer types
 		return core.ErrTipAboveFeeCap
 	}
 	// Make sure the transaction is signed properly
-	if _, err := types.Sender(signer, tx); err != nil && !opts.AllowUnprotectedTxs {
+	from, err := types.Sender(signer, tx)
+	if err != nil && !opts.AllowUnprotectedTxs {
 		return fmt.Errorf("%w: %v", ErrInvalidSender, err)
 	}
+	// Make sure the transaction doesn't impersonate the sender of the system calls
+	if err == nil && from == params.SystemAddress {
+		systemSenderCounter.Inc(1)
+		return core.ErrSystemSender
+	}
 	// Ensure the transaction has more gas than the bare minimum needed to cover
 	// the transaction metadata
 	intrGas, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), tx.SetCodeAuthorizations(), tx.To() == nil, true, rules.IsIstanbul, rules.IsShanghai)
@@ -58,4 +58,6 @@ type ProcessResult struct {
 	Requests [][]byte
 	Logs     []*types.Log
 	GasUsed  uint64
+
+	decision *ExecutionDecision // Parallel or serial execution of the block, only set by the parallel processor
 }
@@ -26,7 +26,6 @@ import (
 
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/rlp"
 )
 
 // AdminAPI is the collection of Ethereum full node related APIs for node
@@ -106,14 +105,14 @@ func (api *AdminAPI) ImportChain(file string) (bool, error) {
 	}
 
 	// Run actual the import in pre-configured batches
-	stream := rlp.NewStream(reader, 0)
+	stream := core.NewChainImportStream(reader)
 
-	blocks, index := make([]*types.Block, 0, 2500), 0
+	blocks, receipts, index := make([]*types.Block, 0, 2500), make([]*types.ReceiptForStorage, 0, 2500), 0
 	for batch := 0; ; batch++ {
 		// Load a batch of blocks from the input file
 		for len(blocks) < cap(blocks) {
-			block := new(types.Block)
-			if err := stream.Decode(block); err == io.EOF {
+			block, receipt, err := stream.Next()
+			if err == io.EOF {
 				break
 			} else if err != nil {
 				return false, fmt.Errorf("block %d: failed to parse: %v", index, err)
@@ -123,21 +122,24 @@ func (api *AdminAPI) ImportChain(file string) (bool, error) {
 				continue
 			}
 			blocks = append(blocks, block)
+			receipts = append(receipts, receipt)
 			index++
 		}
 		if len(blocks) == 0 {
 			break
 		}
 
-		if hasAllBlocks(api.eth.BlockChain(), blocks) {
-			blocks = blocks[:0]
-			continue
+		if !hasAllBlocks(api.eth.BlockChain(), blocks) {
+			// Import the batch
+			if _, err := api.eth.BlockChain().InsertChain(blocks); err != nil {
+				return false, fmt.Errorf("batch %d: failed to insert: %v", batch, err)
+			}
 		}
-		// Import the batch and reset the buffer
-		if _, err := api.eth.BlockChain().InsertChain(blocks); err != nil {
-			return false, fmt.Errorf("batch %d: failed to insert: %v", batch, err)
+		// Restore the bor receipts of the batch, even if the blocks were present
+		if err := api.eth.BlockChain().WriteImportedBorReceipts(blocks, receipts); err != nil {
+			return false, fmt.Errorf("batch %d: failed to write bor receipts: %v", batch, err)
 		}
-		blocks = blocks[:0]
+		blocks, receipts = blocks[:0], receipts[:0]
 	}
 	return true, nil
 }
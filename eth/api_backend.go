@@ -489,6 +489,10 @@ func (b *EthAPIBackend) RPCRpcReturnDataLimit() uint64 {
 	return b.eth.config.RPCReturnDataLimit
 }
 
+func (b *EthAPIBackend) RPCBorBlockTx() bool {
+	return b.eth.config.BorBlockTx
+}
+
 func (b *EthAPIBackend) RPCEVMTimeout() time.Duration {
 	return b.eth.config.RPCEVMTimeout
 }
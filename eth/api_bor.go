@@ -0,0 +1,286 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/bor"
+	"github.com/ethereum/go-ethereum/consensus/bor/clerk"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/history"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/downloader/whitelist"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// errWhitelistUnavailable is returned when the whitelist service isn't running.
+var errWhitelistUnavailable = errors.New("whitelist service not available")
+
+// maxStateSyncEventsBlockRange is the maximum number of blocks which state sync events
+// can be queried at once.
+const maxStateSyncEventsBlockRange = 100_000
+
+// BorAPI provides bor specific information about the node which is only available
+// in the full node (e.g. derived from heimdall data). Failures are reported with the
+// bor JSON-RPC error codes documented on bor.API.
+type BorAPI struct {
+	eth *Ethereum
+}
+
+// NewBorAPI creates a new BorAPI instance.
+func NewBorAPI(eth *Ethereum) *BorAPI {
+	return &BorAPI{eth: eth}
+}
+
+// FinalityStatus is the result of bor_getFinalityStatus.
+type FinalityStatus struct {
+	Milestone  *whitelist.FinalityStatus `json:"milestone"`
+	Checkpoint *whitelist.FinalityStatus `json:"checkpoint"`
+	StateSync  *StateSyncWatermark       `json:"stateSync,omitempty"` // Last state sync event applied by the head, if known
+}
+
+// GetFinalityStatus returns when the latest milestone and checkpoint were processed, the
+// timestamps of the blocks they finalize and the resulting finality lag in seconds, along
+// with the id of the last state sync event applied by the chain head.
+func (api *BorAPI) GetFinalityStatus() (*FinalityStatus, error) {
+	if api.eth.checker == nil {
+		return nil, &bor.FinalityUnavailableError{Err: errWhitelistUnavailable}
+	}
+
+	milestone, checkpoint := api.eth.checker.GetFinalityStatus()
+
+	status := &FinalityStatus{
+		Milestone:  milestone,
+		Checkpoint: checkpoint,
+	}
+
+	if api.eth.stateSyncChecker != nil {
+		head := api.eth.blockchain.CurrentBlock()
+
+		watermark, err := api.eth.stateSyncChecker.watermark(head)
+		if err != nil {
+			log.Debug("Failed to read last state sync id of the head", "number", head.Number, "err", err)
+		}
+
+		status.StateSync = watermark
+	}
+
+	return status, nil
+}
+
+// StateSyncTx locates a state sync event committed on bor.
+type StateSyncTx struct {
+	BlockHash   common.Hash    `json:"blockHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	EventID     hexutil.Uint64 `json:"eventId"`
+	TxHash      common.Hash    `json:"txHash"` // Hash of the bor state sync transaction of the block
+}
+
+// GetStateSyncTxByL1Hash returns the bor blocks in which the state sync events emitted by the
+// given L1 transaction were committed. A single L1 transaction can emit several events, an
+// empty result means none of them has been committed on the canonical chain yet.
+func (api *BorAPI) GetStateSyncTxByL1Hash(l1Hash common.Hash) []*StateSyncTx {
+	lookups := api.eth.blockchain.GetStateSyncL1Lookups(l1Hash)
+
+	txs := make([]*StateSyncTx, 0, len(lookups))
+	for _, lookup := range lookups {
+		txs = append(txs, &StateSyncTx{
+			BlockHash:   lookup.BlockHash,
+			BlockNumber: hexutil.Uint64(lookup.BlockNumber),
+			EventID:     hexutil.Uint64(lookup.EventID),
+			TxHash:      types.GetDerivedBorTxHash(types.BorReceiptKey(lookup.BlockNumber, lookup.BlockHash)),
+		})
+	}
+
+	return txs
+}
+
+// StateSyncEvent locates a state sync event in the bor receipt of the block it was committed in.
+type StateSyncEvent struct {
+	EventID     hexutil.Uint64 `json:"eventId"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	LogIndex    hexutil.Uint64 `json:"logIndex"` // Index of the StateCommitted log in the bor receipt
+	TxHash      common.Hash    `json:"txHash"`   // Hash of the bor state sync transaction of the block
+}
+
+// GetStateSyncEventsByBlockRange returns the state sync events committed in the canonical blocks
+// between start and end (inclusive), in the order they were committed. The events are read from
+// the state sync event index, which must be enabled (--bor.statesyncindex) and have caught up
+// with the end of the range.
+func (api *BorAPI) GetStateSyncEventsByBlockRange(start hexutil.Uint64, end hexutil.Uint64) ([]*StateSyncEvent, error) {
+	if start > end {
+		return nil, fmt.Errorf("invalid block range: start %d above end %d", start, end)
+	}
+
+	if end-start >= maxStateSyncEventsBlockRange {
+		return nil, fmt.Errorf("block range too large: %d blocks, max %d", end-start+1, maxStateSyncEventsBlockRange)
+	}
+
+	blocks, err := api.eth.blockchain.GetStateSyncEventsByBlockRange(uint64(start), uint64(end))
+	if err != nil {
+		return nil, &bor.StateSyncNotIndexedError{Err: err}
+	}
+
+	events := make([]*StateSyncEvent, 0)
+	for _, block := range blocks {
+		txHash := types.GetDerivedBorTxHash(types.BorReceiptKey(block.Number, block.Hash))
+
+		for _, event := range block.Events {
+			events = append(events, &StateSyncEvent{
+				EventID:     hexutil.Uint64(event.EventID),
+				BlockHash:   block.Hash,
+				BlockNumber: hexutil.Uint64(block.Number),
+				LogIndex:    hexutil.Uint64(event.LogIndex),
+				TxHash:      txHash,
+			})
+		}
+	}
+
+	return events, nil
+}
+
+// BlockWitnessInfo is the result of bor_getBlockWitnessInfo.
+type BlockWitnessInfo struct {
+	Number         hexutil.Uint64 `json:"number"`
+	Hash           common.Hash    `json:"hash"`
+	Size           hexutil.Uint64 `json:"size"`           // Size of the RLP encoded witness
+	CompressedSize hexutil.Uint64 `json:"compressedSize"` // Size of the snappy compressed RLP encoding
+	Exportable     bool           `json:"exportable"`     // Whether debug_exportWitness can currently regenerate the witness
+}
+
+// GetBlockWitnessInfo returns the sizes of the witness of the given block, recorded when
+// the node generated it while importing the block or when it was injected through
+// debug_executeWitness. The result is null if the node never handled the witness of the
+// block.
+func (api *BorAPI) GetBlockWitnessInfo(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*BlockWitnessInfo, error) {
+	header, err := api.eth.APIBackend.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if header == nil {
+		return nil, nil
+	}
+
+	info := api.eth.blockchain.GetWitnessInfo(header.Hash())
+	if info == nil {
+		return nil, nil
+	}
+
+	// Witnesses are regenerated on top of the parent state
+	parent := api.eth.blockchain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+
+	return &BlockWitnessInfo{
+		Number:         hexutil.Uint64(header.Number.Uint64()),
+		Hash:           header.Hash(),
+		Size:           hexutil.Uint64(info.Size),
+		CompressedSize: hexutil.Uint64(info.CompressedSize),
+		Exportable:     parent != nil && api.eth.blockchain.HasState(parent.Root),
+	}, nil
+}
+
+// CommittedStateSync is a state sync event committed in a block, as returned by
+// bor_getStateSyncEventsByBlock.
+type CommittedStateSync struct {
+	ID       hexutil.Uint64  `json:"id"`
+	Contract *common.Address `json:"contract,omitempty"` // Receiver of the event on bor, if heimdall could be reached
+	Data     hexutil.Bytes   `json:"data,omitempty"`     // Data of the event, if heimdall could be reached
+	Success  bool            `json:"success"`            // Whether the receiver accepted the event
+	LogIndex hexutil.Uint64  `json:"logIndex"`           // Index of the StateCommitted log in the bor receipt
+}
+
+// GetStateSyncEventsByBlock returns the state sync events committed in the given block, in
+// the order they were committed. The events are derived from the StateCommitted logs of the
+// bor receipt of the block, their contract and data are fetched from heimdall on a best-effort
+// basis. The result is empty for the blocks not starting a sprint, and null for unknown
+// blocks.
+func (api *BorAPI) GetStateSyncEventsByBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*CommittedStateSync, error) {
+	header, err := api.eth.APIBackend.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if header == nil {
+		return nil, nil
+	}
+
+	var (
+		config = api.eth.blockchain.Config().Bor
+		number = header.Number.Uint64()
+		events = make([]*CommittedStateSync, 0)
+	)
+
+	if config == nil || !config.IsSprintStart(number) {
+		return events, nil
+	}
+
+	receipt := api.eth.blockchain.GetBorReceiptByHash(header.Hash())
+	if receipt == nil {
+		if number < api.eth.APIBackend.HistoryPruningCutoff() {
+			return nil, &history.PrunedHistoryError{}
+		}
+
+		return events, nil
+	}
+
+	// Locate the events through the state sync event index if it covers the block
+	var entries []rawdb.StateSyncIndexEntry
+	if indexed := rawdb.ReadStateSyncIndexBlock(api.eth.chainDb, number); indexed != nil && indexed.Hash == header.Hash() {
+		entries = indexed.Events
+	} else {
+		entries = core.CommittedStateSyncEvents(receipt.Logs, common.HexToAddress(config.StateReceiverContract))
+	}
+
+	for _, entry := range entries {
+		if entry.LogIndex >= uint64(len(receipt.Logs)) {
+			return nil, fmt.Errorf("state sync event %d of block %d not found in bor receipt", entry.EventID, number)
+		}
+
+		// The success flag is the ABI encoded bool of the StateCommitted log
+		data := receipt.Logs[entry.LogIndex].Data
+
+		events = append(events, &CommittedStateSync{
+			ID:       hexutil.Uint64(entry.EventID),
+			Success:  len(data) > 0 && data[len(data)-1] != 0,
+			LogIndex: hexutil.Uint64(entry.LogIndex),
+		})
+	}
+
+	api.fillStateSyncRecords(ctx, header, events)
+
+	return events, nil
+}
+
+// fillStateSyncRecords fills the contract and data of the given events committed in the given
+// block from the events recorded on heimdall, leaving them empty if heimdall can't be reached.
+func (api *BorAPI) fillStateSyncRecords(ctx context.Context, header *types.Header, events []*CommittedStateSync) {
+	engine, ok := api.eth.engine.(*bor.Bor)
+	if !ok || engine.HeimdallClient == nil || len(events) == 0 {
+		return
+	}
+
+	// All events committed in a block were recorded in heimdall before the block time
+	records, err := engine.HeimdallClient.StateSyncEvents(ctx, uint64(events[0].ID), int64(header.Time))
+	if err != nil {
+		log.Debug("Failed to fetch committed state sync events", "number", header.Number, "err", err)
+		return
+	}
+
+	byID := make(map[uint64]*clerk.EventRecordWithTime, len(records))
+	for _, record := range records {
+		byID[record.ID] = record
+	}
+
+	for _, event := range events {
+		if record, ok := byID[uint64(event.ID)]; ok {
+			contract := record.Contract
+			event.Contract, event.Data = &contract, record.Data
+		}
+	}
+}
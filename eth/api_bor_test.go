@@ -0,0 +1,298 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/bor"
+	"github.com/ethereum/go-ethereum/consensus/bor/clerk"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/snappy"
+)
+
+// Tests that the bor API reports its failures with the bor JSON-RPC error codes.
+func TestBorAPIErrorCodes(t *testing.T) {
+	t.Parallel()
+
+	server := rpc.NewServer("", 0, 0)
+	if err := server.RegisterName("bor", NewBorAPI(&Ethereum{blockchain: &core.BlockChain{}})); err != nil {
+		t.Fatalf("failed to register bor API: %v", err)
+	}
+
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	tests := []struct {
+		method string
+		args   []interface{}
+		code   int
+	}{
+		// The whitelist service isn't running
+		{method: "bor_getFinalityStatus", code: bor.FinalityUnavailableErrorCode},
+		// The state sync event index isn't enabled
+		{method: "bor_getStateSyncEventsByBlockRange", args: []interface{}{hexutil.Uint64(1), hexutil.Uint64(16)}, code: bor.StateSyncNotIndexedErrorCode},
+	}
+	for _, tt := range tests {
+		var result interface{}
+
+		err := client.Call(&result, tt.method, tt.args...)
+
+		var rpcErr rpc.Error
+		if !errors.As(err, &rpcErr) {
+			t.Errorf("%s: expected an rpc error, got %v", tt.method, err)
+			continue
+		}
+		if code := rpcErr.ErrorCode(); code != tt.code {
+			t.Errorf("%s: error code mismatch: have %d, want %d", tt.method, code, tt.code)
+		}
+	}
+}
+
+// Tests that the sizes of the witnesses generated while importing a block and injected
+// through debug_executeWitness are reported by bor_getBlockWitnessInfo.
+func TestGetBlockWitnessInfo(t *testing.T) {
+	genesis := &core.Genesis{
+		Config:    params.AllEthashProtocolChanges,
+		Timestamp: 9000,
+	}
+	_, blocks, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 2, func(i int, g *core.BlockGen) {
+		g.OffsetTime(5)
+	})
+
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("can't create node: %v", err)
+	}
+	defer stack.Close()
+
+	ethservice, err := New(stack, &ethconfig.Config{Genesis: genesis, RPCGasCap: 1000000})
+	if err != nil {
+		t.Fatalf("can't create ethereum service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("can't start node: %v", err)
+	}
+	client := stack.Attach()
+	defer client.Close()
+
+	witnessInfo := func(block *types.Block) *BlockWitnessInfo {
+		t.Helper()
+
+		var info *BlockWitnessInfo
+		if err := client.Call(&info, "bor_getBlockWitnessInfo", rpc.BlockNumberOrHashWithHash(block.Hash(), false)); err != nil {
+			t.Fatalf("can't get witness info of block %d: %v", block.NumberU64(), err)
+		}
+		return info
+	}
+	// The first block is imported with witness collection, the second one without
+	if _, err := ethservice.BlockChain().InsertBlockWithoutSetHead(blocks[0], true); err != nil {
+		t.Fatalf("can't import block: %v", err)
+	}
+	if _, err := ethservice.BlockChain().SetCanonical(blocks[0]); err != nil {
+		t.Fatalf("can't set canonical head: %v", err)
+	}
+	if _, err := ethservice.BlockChain().InsertChain(blocks[1:]); err != nil {
+		t.Fatalf("can't import block: %v", err)
+	}
+	// The generated witness is reported once recorded in the background, the blocks
+	// without witness aren't
+	info := witnessInfo(blocks[0])
+	for deadline := time.Now().Add(5 * time.Second); info == nil && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		info = witnessInfo(blocks[0])
+	}
+	if info == nil {
+		t.Fatalf("generated witness not reported")
+	}
+	if info.Hash != blocks[0].Hash() || info.Size == 0 || info.CompressedSize == 0 || !info.Exportable {
+		t.Fatalf("generated witness info mismatch: %+v", info)
+	}
+	if info := witnessInfo(blocks[1]); info != nil {
+		t.Fatalf("witness reported for block imported without witness: %+v", info)
+	}
+	if info := witnessInfo(ethservice.BlockChain().Genesis()); info != nil {
+		t.Fatalf("witness reported for genesis: %+v", info)
+	}
+	// Executing the witness of the second block records its sizes
+	var enc hexutil.Bytes
+	if err := client.Call(&enc, "debug_exportWitness", blocks[1].Hash()); err != nil {
+		t.Fatalf("can't export witness: %v", err)
+	}
+	var result WitnessExecutionResult
+	if err := client.Call(&result, "debug_executeWitness", enc); err != nil {
+		t.Fatalf("can't execute witness: %v", err)
+	}
+	want := &BlockWitnessInfo{
+		Number:         hexutil.Uint64(blocks[1].NumberU64()),
+		Hash:           blocks[1].Hash(),
+		Size:           hexutil.Uint64(len(enc)),
+		CompressedSize: hexutil.Uint64(len(snappy.Encode(nil, enc))),
+		Exportable:     true,
+	}
+	if info := witnessInfo(blocks[1]); info == nil || *info != *want {
+		t.Fatalf("injected witness info mismatch: have %+v, want %+v", info, want)
+	}
+}
+
+// stateSyncRecordsClient is a heimdall client only serving a fixed set of state sync events.
+type stateSyncRecordsClient struct {
+	bor.IHeimdallClient
+	records []*clerk.EventRecordWithTime
+}
+
+func (c *stateSyncRecordsClient) StateSyncEvents(_ context.Context, fromID uint64, _ int64) ([]*clerk.EventRecordWithTime, error) {
+	var records []*clerk.EventRecordWithTime
+	for _, record := range c.records {
+		if record.ID >= fromID {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// stateCommittedLog returns the StateCommitted log of the given event.
+func stateCommittedLog(receiver common.Address, id uint64, success bool) *types.Log {
+	data := make([]byte, 32)
+	if success {
+		data[31] = 1
+	}
+	return &types.Log{
+		Address: receiver,
+		Topics:  []common.Hash{core.StateCommittedTopic, common.BigToHash(new(big.Int).SetUint64(id))},
+		Data:    data,
+	}
+}
+
+// Tests that bor_getStateSyncEventsByBlock returns the state sync events committed in the
+// given block according to its bor receipt.
+func TestGetStateSyncEventsByBlock(t *testing.T) {
+	t.Parallel()
+
+	// The test chain config has sprints of 4 blocks
+	var (
+		receiver = common.HexToAddress("0x0000000000000000000000000000000000001001")
+		config   = *params.TestChainConfig
+		borCfg   = *config.Bor
+	)
+	borCfg.StateReceiverContract = receiver.Hex()
+	config.Bor = &borCfg
+
+	var (
+		gspec        = &core.Genesis{Config: &config, BaseFee: big.NewInt(params.InitialBaseFee)}
+		db           = rawdb.NewMemoryDatabase()
+		_, blocks, _ = core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 12, nil)
+	)
+	chain, err := core.NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	// Block 4 commits events 1 and 2, the latter rejected by its receiver, along with an
+	// unrelated log. Block 8 commits nothing and block 12 commits event 3, located
+	// through the state sync event index.
+	block4, block8, block12 := blocks[3], blocks[7], blocks[11]
+
+	rawdb.WriteBorBlockData(db, block4.Hash(), 4, &types.ReceiptForStorage{
+		Status: types.ReceiptStatusSuccessful,
+		Logs: []*types.Log{
+			stateCommittedLog(receiver, 1, true),
+			{Address: common.HexToAddress("0xdead"), Topics: []common.Hash{common.HexToHash("0x01")}},
+			stateCommittedLog(receiver, 2, false),
+		},
+	}, nil)
+	rawdb.WriteBorBlockData(db, block12.Hash(), 12, &types.ReceiptForStorage{
+		Status: types.ReceiptStatusSuccessful,
+		Logs:   []*types.Log{stateCommittedLog(receiver, 3, true)},
+	}, nil)
+	rawdb.WriteStateSyncIndexBlock(db, &rawdb.StateSyncIndexBlock{
+		Number: 12,
+		Hash:   block12.Hash(),
+		Events: []rawdb.StateSyncIndexEntry{{EventID: 3, LogIndex: 0}},
+	})
+	// Blocks not starting a sprint don't commit events, whatever their bor receipt
+	rawdb.WriteBorBlockData(db, blocks[4].Hash(), 5, &types.ReceiptForStorage{
+		Status: types.ReceiptStatusSuccessful,
+		Logs:   []*types.Log{stateCommittedLog(receiver, 4, true)},
+	}, nil)
+
+	eth := &Ethereum{blockchain: chain, chainDb: db}
+	eth.APIBackend = &EthAPIBackend{eth: eth}
+
+	server := rpc.NewServer("", 0, 0)
+	if err := server.RegisterName("bor", NewBorAPI(eth)); err != nil {
+		t.Fatalf("failed to register bor API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	events := func(block rpc.BlockNumberOrHash) []*CommittedStateSync {
+		t.Helper()
+
+		var events []*CommittedStateSync
+		if err := client.Call(&events, "bor_getStateSyncEventsByBlock", block); err != nil {
+			t.Fatalf("can't get state sync events of block %v: %v", block, err)
+		}
+		return events
+	}
+	byNumber := func(number int64) rpc.BlockNumberOrHash {
+		return rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(number))
+	}
+	tests := []struct {
+		block rpc.BlockNumberOrHash
+		want  []*CommittedStateSync
+	}{
+		{byNumber(4), []*CommittedStateSync{{ID: 1, Success: true, LogIndex: 0}, {ID: 2, Success: false, LogIndex: 2}}},
+		{rpc.BlockNumberOrHashWithHash(block4.Hash(), true), []*CommittedStateSync{{ID: 1, Success: true, LogIndex: 0}, {ID: 2, Success: false, LogIndex: 2}}},
+		{byNumber(5), []*CommittedStateSync{}},
+		{rpc.BlockNumberOrHashWithHash(block8.Hash(), false), []*CommittedStateSync{}},
+		{byNumber(int64(rpc.LatestBlockNumber)), []*CommittedStateSync{{ID: 3, Success: true, LogIndex: 0}}},
+		{byNumber(0), []*CommittedStateSync{}},
+	}
+	for _, tt := range tests {
+		if have := events(tt.block); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("block %v: events mismatch: have %v, want %v", tt.block, have, tt.want)
+		}
+	}
+	// Unknown blocks are reported as null
+	if events := events(byNumber(100)); events != nil {
+		t.Errorf("events reported for unknown block: %v", events)
+	}
+	// The contract and data of the events are filled from heimdall when reachable
+	contract := common.HexToAddress("0xbeef")
+	eth.engine = &bor.Bor{HeimdallClient: &stateSyncRecordsClient{records: []*clerk.EventRecordWithTime{
+		{EventRecord: clerk.EventRecord{ID: 1, Contract: contract, Data: []byte{0x01, 0x02}}},
+		{EventRecord: clerk.EventRecord{ID: 2, Contract: contract, Data: []byte{0x03}}},
+		{EventRecord: clerk.EventRecord{ID: 3, Contract: contract, Data: []byte{0x04}}},
+	}}}
+	want := []*CommittedStateSync{
+		{ID: 1, Contract: &contract, Data: hexutil.Bytes{0x01, 0x02}, Success: true, LogIndex: 0},
+		{ID: 2, Contract: &contract, Data: hexutil.Bytes{0x03}, Success: false, LogIndex: 2},
+	}
+	if have := events(byNumber(4)); !reflect.DeepEqual(have, want) {
+		t.Errorf("events with heimdall records mismatch: have %v, want %v", have, want)
+	}
+	// The state sync event index isn't enabled by default, even if the chain commits events
+	var rpcErr rpc.Error
+	err = client.Call(new(interface{}), "bor_getStateSyncEventsByBlockRange", hexutil.Uint64(1), hexutil.Uint64(12))
+	if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != bor.StateSyncNotIndexedErrorCode {
+		t.Errorf("unexpected error of the disabled state sync event index: %v", err)
+	}
+}
@@ -24,8 +24,12 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/bor"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -97,9 +101,27 @@ func (api *DebugAPI) Preimage(ctx context.Context, hash common.Hash) (hexutil.By
 
 // BadBlockArgs represents the entries in the list returned when bad blocks are queried.
 type BadBlockArgs struct {
-	Hash  common.Hash            `json:"hash"`
-	Block map[string]interface{} `json:"block"`
-	RLP   string                 `json:"rlp"`
+	Hash      common.Hash            `json:"hash"`
+	Block     map[string]interface{} `json:"block"`
+	RLP       string                 `json:"rlp"`
+	Stateless *BadStatelessArgs      `json:"stateless,omitempty"` // Set if the stateless execution of the block failed
+}
+
+// BadStatelessArgs represents the roots computed by the failed stateless execution of
+// a bad block, whose witness is returned by debug_getBadBlockWitness.
+type BadStatelessArgs struct {
+	StateRoot           common.Hash `json:"stateRoot"`
+	ExpectedStateRoot   common.Hash `json:"expectedStateRoot"`
+	ReceiptRoot         common.Hash `json:"receiptRoot"`
+	ExpectedReceiptRoot common.Hash `json:"expectedReceiptRoot"`
+}
+
+// BadBlockWitnessResult is the result of debug_getBadBlockWitness.
+type BadBlockWitnessResult struct {
+	Number  hexutil.Uint64 `json:"number"`
+	Hash    common.Hash    `json:"hash"`
+	Witness hexutil.Bytes  `json:"witness"` // RLP encoded witness
+	BadStatelessArgs
 }
 
 // GetBadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
@@ -120,15 +142,48 @@ func (api *DebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs, error)
 			blockRlp = fmt.Sprintf("%#x", rlpBytes)
 		}
 		blockJSON = ethapi.RPCMarshalBlock(block, true, true, api.eth.APIBackend.ChainConfig(), api.eth.chainDb)
-		results = append(results, &BadBlockArgs{
+		args := &BadBlockArgs{
 			Hash:  block.Hash(),
 			RLP:   blockRlp,
 			Block: blockJSON,
-		})
+		}
+		if witness := rawdb.ReadBadWitness(api.eth.chainDb, block.Hash()); witness != nil {
+			args.Stateless = newBadStatelessArgs(witness)
+		}
+		results = append(results, args)
 	}
 	return results, nil
 }
 
+func newBadStatelessArgs(witness *rawdb.BadWitness) *BadStatelessArgs {
+	return &BadStatelessArgs{
+		StateRoot:           witness.StateRoot,
+		ExpectedStateRoot:   witness.ExpectedStateRoot,
+		ReceiptRoot:         witness.ReceiptRoot,
+		ExpectedReceiptRoot: witness.ExpectedReceiptRoot,
+	}
+}
+
+// GetBadBlockWitness returns the witness of the given bad block whose stateless
+// execution failed, along with the roots computed by the execution. Only the
+// witnesses of the last few failures are kept.
+func (api *DebugAPI) GetBadBlockWitness(hash common.Hash) (*BadBlockWitnessResult, error) {
+	witness := rawdb.ReadBadWitness(api.eth.chainDb, hash)
+	if witness == nil {
+		return nil, fmt.Errorf("no bad witness for block %s", hash.Hex())
+	}
+	data := rawdb.ReadBadWitnessData(api.eth.chainDb, hash)
+	if data == nil {
+		return nil, fmt.Errorf("invalid bad witness for block %s", hash.Hex())
+	}
+	return &BadBlockWitnessResult{
+		Number:           hexutil.Uint64(witness.Number),
+		Hash:             witness.Hash,
+		Witness:          data,
+		BadStatelessArgs: *newBadStatelessArgs(witness),
+	}, nil
+}
+
 // AccountRangeMaxResults is the maximum number of results to be returned per call
 const AccountRangeMaxResults = 256
 
@@ -436,6 +491,45 @@ func (api *DebugAPI) SetTrieFlushInterval(interval string) error {
 	return nil
 }
 
+// GetBlockExecutionMode returns whether the given canonical block was executed in
+// parallel (block-stm) or serially when it was imported, and why. Only the recently
+// imported blocks are tracked.
+func (api *DebugAPI) GetBlockExecutionMode(blockNr rpc.BlockNumber) (*core.ExecutionDecision, error) {
+	var header *types.Header
+	if blockNr == rpc.LatestBlockNumber {
+		header = api.eth.blockchain.CurrentBlock()
+	} else if blockNr >= 0 {
+		header = api.eth.blockchain.GetHeaderByNumber(uint64(blockNr))
+	} else {
+		return nil, fmt.Errorf("unsupported block number %d", blockNr)
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	decision := api.eth.blockchain.GetExecutionDecision(header.Hash())
+	if decision == nil {
+		return nil, fmt.Errorf("no execution mode recorded for block #%d, only recently imported blocks are tracked", header.Number)
+	}
+	return decision, nil
+}
+
+// HeimdallErrors returns the last failed requests to heimdall, oldest first, along
+// with the number of failed requests per path since startup.
+func (api *DebugAPI) HeimdallErrors() (*heimdall.ErrorsReport, error) {
+	engine, ok := api.eth.engine.(*bor.Bor)
+	if !ok {
+		return nil, errors.New("bor consensus engine not in use")
+	}
+	var report *heimdall.ErrorsReport
+	if client, ok := engine.HeimdallClient.(bor.HeimdallErrorsReporter); ok {
+		report = client.RecentErrors()
+	}
+	if report == nil {
+		return nil, errors.New("heimdall errors are only tracked for the heimdall HTTP client")
+	}
+	return report, nil
+}
+
 // GetTrieFlushInterval gets the current value of in-memory trie flush interval
 func (api *DebugAPI) GetTrieFlushInterval() (string, error) {
 	if api.eth.blockchain.TrieDB().Scheme() == rawdb.PathScheme {
@@ -443,3 +537,115 @@ func (api *DebugAPI) GetTrieFlushInterval() (string, error) {
 	}
 	return api.eth.blockchain.GetTrieFlushInterval().String(), nil
 }
+
+// ExportWitness regenerates the witness of the given block by re-executing it on top of
+// its parent state and returns it RLP encoded. The parent state must be available.
+func (api *DebugAPI) ExportWitness(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
+	block := api.eth.blockchain.GetBlockByHash(hash)
+	if block == nil {
+		return nil, fmt.Errorf("block %s not found", hash.Hex())
+	}
+	witness, err := api.eth.blockchain.GenerateWitness(ctx, block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate witness for block %s: %w", hash.Hex(), err)
+	}
+	return rlp.EncodeToBytes(witness)
+}
+
+// WitnessExecutionResult is the result of debug_executeWitness.
+type WitnessExecutionResult struct {
+	Number           hexutil.Uint64 `json:"number"`
+	Hash             common.Hash    `json:"hash"`
+	StateRoot        common.Hash    `json:"stateRoot"`        // State root computed from the witness
+	LocalStateRoot   common.Hash    `json:"localStateRoot"`   // State root of the local block
+	ReceiptRoot      common.Hash    `json:"receiptRoot"`      // Receipt root computed from the witness
+	LocalReceiptRoot common.Hash    `json:"localReceiptRoot"` // Receipt root of the local block
+	UnusedNodes      hexutil.Uint64 `json:"unusedNodes"`      // Number of witness state nodes never resolved
+	UnusedBytes      hexutil.Uint64 `json:"unusedBytes"`      // Total size of the unused witness state nodes
+}
+
+// ExecuteWitness decodes the given RLP encoded witness, checks that its pre-state is
+// part of the local chain and statelessly executes the canonical block built on top
+// of it. The roots computed from the witness are returned along with the local ones.
+func (api *DebugAPI) ExecuteWitness(ctx context.Context, enc hexutil.Bytes) (*WitnessExecutionResult, error) {
+	var witness stateless.Witness
+	if err := rlp.DecodeBytes(enc, &witness); err != nil {
+		return nil, fmt.Errorf("invalid witness: %w", err)
+	}
+	if len(witness.Headers) == 0 {
+		return nil, errors.New("invalid witness: missing parent header")
+	}
+	parent := witness.Headers[0]
+	if api.eth.blockchain.GetHeaderByHash(parent.Hash()) == nil {
+		return nil, fmt.Errorf("pre-state block %s not found", parent.Hash().Hex())
+	}
+	block := api.eth.blockchain.GetBlockByNumber(parent.Number.Uint64() + 1)
+	if block == nil || block.ParentHash() != parent.Hash() {
+		return nil, fmt.Errorf("no canonical block on top of pre-state block %s", parent.Hash().Hex())
+	}
+	start := time.Now()
+	stateRoot, receiptRoot, usage, err := api.eth.blockchain.ExecuteWitness(ctx, block, &witness)
+	if err != nil {
+		return nil, fmt.Errorf("stateless execution of block %s failed: %w", block.Hash().Hex(), err)
+	}
+	api.eth.blockchain.SendWitnessEvent(block, &witness, core.WitnessSourceInjected, time.Since(start))
+	return &WitnessExecutionResult{
+		Number:           hexutil.Uint64(block.NumberU64()),
+		Hash:             block.Hash(),
+		StateRoot:        stateRoot,
+		LocalStateRoot:   block.Root(),
+		ReceiptRoot:      receiptRoot,
+		LocalReceiptRoot: block.ReceiptHash(),
+		UnusedNodes:      hexutil.Uint64(len(usage.UnusedNodes)),
+		UnusedBytes:      hexutil.Uint64(usage.UnusedBytes),
+	}, nil
+}
+
+// WitnessEvents sends a notification each time a witness is generated while importing
+// a block, or injected through debug_executeWitness.
+func (api *DebugAPI) WitnessEvents(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan core.WitnessEvent, 16)
+		sub := api.eth.blockchain.SubscribeWitnessEvent(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				notifier.Notify(rpcSub.ID, ev)
+			case <-sub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// WitnessUnusedNodes regenerates the witness of the given block, executes the block
+// statelessly against it and returns the hashes of the witness state nodes which the
+// execution never resolved, i.e. the ones the witness generation over-collected.
+func (api *DebugAPI) WitnessUnusedNodes(ctx context.Context, hash common.Hash) ([]common.Hash, error) {
+	block := api.eth.blockchain.GetBlockByHash(hash)
+	if block == nil {
+		return nil, fmt.Errorf("block %s not found", hash.Hex())
+	}
+	witness, err := api.eth.blockchain.GenerateWitness(ctx, block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate witness for block %s: %w", hash.Hex(), err)
+	}
+	_, _, usage, err := api.eth.blockchain.ExecuteWitness(ctx, block, witness)
+	if err != nil {
+		return nil, fmt.Errorf("stateless execution of block %s failed: %w", hash.Hex(), err)
+	}
+	return usage.UnusedNodes, nil
+}
@@ -18,19 +18,34 @@ package eth
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/bor"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/triedb"
 	"github.com/holiman/uint256"
 )
@@ -228,3 +243,215 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that witnesses generated while importing a block and witnesses injected
+// through debug_executeWitness are published on the witness event subscription.
+func TestWitnessEventsSubscription(t *testing.T) {
+	genesis := &core.Genesis{
+		Config:    params.AllEthashProtocolChanges,
+		Timestamp: 9000,
+	}
+	_, blocks, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 1, func(i int, g *core.BlockGen) {
+		g.OffsetTime(5)
+	})
+	block := blocks[0]
+
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("can't create node: %v", err)
+	}
+	defer stack.Close()
+
+	ethservice, err := New(stack, &ethconfig.Config{Genesis: genesis, RPCGasCap: 1000000})
+	if err != nil {
+		t.Fatalf("can't create ethereum service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("can't start node: %v", err)
+	}
+	client := stack.Attach()
+	defer client.Close()
+
+	events := make(chan core.WitnessEvent, 4)
+	sub, err := client.Subscribe(context.Background(), "debug", events, "witnessEvents")
+	if err != nil {
+		t.Fatalf("can't subscribe to witness events: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	nextEvent := func(source string) core.WitnessEvent {
+		t.Helper()
+
+		select {
+		case ev := <-events:
+			if ev.Source != source {
+				t.Fatalf("source mismatch: have %s, want %s", ev.Source, source)
+			}
+			if ev.BlockHash != block.Hash() || ev.Number != block.NumberU64() {
+				t.Fatalf("block mismatch: have #%d %x, want #%d %x", ev.Number, ev.BlockHash, block.NumberU64(), block.Hash())
+			}
+			return ev
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %s witness event", source)
+		}
+		return core.WitnessEvent{}
+	}
+	// Importing a single block with witness collection publishes the witness
+	if _, err := ethservice.BlockChain().InsertBlockWithoutSetHead(block, true); err != nil {
+		t.Fatalf("can't import block: %v", err)
+	}
+	if _, err := ethservice.BlockChain().SetCanonical(block); err != nil {
+		t.Fatalf("can't set canonical head: %v", err)
+	}
+	if ev := nextEvent(core.WitnessSourceGenerated); ev.EncodedSize == 0 {
+		t.Fatalf("generated witness reported empty")
+	}
+	// Executing an exported witness publishes it as injected, regenerating it doesn't
+	var enc hexutil.Bytes
+	if err := client.Call(&enc, "debug_exportWitness", block.Hash()); err != nil {
+		t.Fatalf("can't export witness: %v", err)
+	}
+	var result WitnessExecutionResult
+	if err := client.Call(&result, "debug_executeWitness", enc); err != nil {
+		t.Fatalf("can't execute witness: %v", err)
+	}
+	if ev := nextEvent(core.WitnessSourceInjected); ev.EncodedSize != len(enc) {
+		t.Fatalf("injected witness size mismatch: have %d, want %d", ev.EncodedSize, len(enc))
+	}
+}
+
+// Tests that the witness of a block whose stateless execution computed a different
+// state root is recorded along with the bad block and served by debug_getBadBlockWitness.
+func TestGetBadBlockWitness(t *testing.T) {
+	var (
+		contract = common.HexToAddress("0xbb")
+		genesis  = &core.Genesis{
+			Config:    params.AllEthashProtocolChanges,
+			Timestamp: 9000,
+			Alloc: types.GenesisAlloc{
+				testAddr: {Balance: big.NewInt(params.Ether)},
+				// Stores the hash of the grandparent block: SSTORE(0, BLOCKHASH(NUMBER - 2))
+				contract: {Balance: common.Big0, Code: common.FromHex("0x6002430340600055")},
+			},
+		}
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 2, func(i int, g *core.BlockGen) {
+		g.OffsetTime(5)
+		if i == 1 {
+			tx := types.MustSignNewTx(testKey, types.LatestSigner(genesis.Config), &types.LegacyTx{
+				To:       &contract,
+				Gas:      100000,
+				GasPrice: g.BaseFee(),
+			})
+			g.AddTx(tx)
+		}
+	})
+	block := blocks[1]
+
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("can't create node: %v", err)
+	}
+	defer stack.Close()
+
+	ethservice, err := New(stack, &ethconfig.Config{Genesis: genesis, RPCGasCap: 1000000})
+	if err != nil {
+		t.Fatalf("can't create ethereum service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("can't start node: %v", err)
+	}
+	client := stack.Attach()
+	defer client.Close()
+
+	if _, err := ethservice.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("can't import blocks: %v", err)
+	}
+	// Tamper with the grandparent header of the witness, so that BLOCKHASH resolves
+	// to a different hash during the stateless execution
+	var enc hexutil.Bytes
+	if err := client.Call(&enc, "debug_exportWitness", block.Hash()); err != nil {
+		t.Fatalf("can't export witness: %v", err)
+	}
+	var witness stateless.Witness
+	if err := rlp.DecodeBytes(enc, &witness); err != nil {
+		t.Fatalf("can't decode witness: %v", err)
+	}
+	if len(witness.Headers) < 2 {
+		t.Fatalf("witness misses the grandparent header: %d headers", len(witness.Headers))
+	}
+	witness.Headers[1].Extra = []byte("tampered")
+
+	tampered, err := rlp.EncodeToBytes(&witness)
+	if err != nil {
+		t.Fatalf("can't encode witness: %v", err)
+	}
+	// No bad witness is recorded before the stateless execution fails
+	var bad *BadBlockWitnessResult
+	if err := client.Call(&bad, "debug_getBadBlockWitness", block.Hash()); err == nil {
+		t.Fatalf("bad witness reported before the failure: %+v", bad)
+	}
+	var result WitnessExecutionResult
+	if err := client.Call(&result, "debug_executeWitness", hexutil.Bytes(tampered)); err != nil {
+		t.Fatalf("can't execute witness: %v", err)
+	}
+	if result.StateRoot == result.LocalStateRoot {
+		t.Fatalf("tampered witness computed the local state root")
+	}
+	// The witness and the roots are recorded along with the bad block
+	if err := client.Call(&bad, "debug_getBadBlockWitness", block.Hash()); err != nil {
+		t.Fatalf("can't get bad witness: %v", err)
+	}
+	want := BadStatelessArgs{
+		StateRoot:           result.StateRoot,
+		ExpectedStateRoot:   block.Root(),
+		ReceiptRoot:         result.ReceiptRoot,
+		ExpectedReceiptRoot: block.ReceiptHash(),
+	}
+	if bad.Number != hexutil.Uint64(block.NumberU64()) || bad.Hash != block.Hash() || bad.BadStatelessArgs != want {
+		t.Fatalf("bad witness mismatch: have %+v, want roots %+v", bad, want)
+	}
+	if !bytes.Equal(bad.Witness, tampered) {
+		t.Fatalf("bad witness content mismatch")
+	}
+	var badBlocks []*BadBlockArgs
+	if err := client.Call(&badBlocks, "debug_getBadBlocks"); err != nil {
+		t.Fatalf("can't get bad blocks: %v", err)
+	}
+	if len(badBlocks) != 1 || badBlocks[0].Hash != block.Hash() || badBlocks[0].Stateless == nil || *badBlocks[0].Stateless != want {
+		t.Fatalf("bad block mismatch: %s", dumper.Sdump(badBlocks))
+	}
+}
+
+// Tests that the failed heimdall requests are reported through the health tracking
+// client the bor engine is set up with.
+func TestHeimdallErrors(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	client := heimdall.NewHeimdallClient(srv.URL, time.Second, 10*time.Millisecond, 1)
+	defer client.Close()
+
+	engine := &bor.Bor{HeimdallClient: bor.NewHeimdallHealth(0).WrapClient(client)}
+	api := NewDebugAPI(&Ethereum{engine: engine})
+
+	if _, err := engine.HeimdallClient.FetchCheckpointCount(context.Background()); err == nil {
+		t.Fatal("expected the heimdall request to fail")
+	}
+	report, err := api.HeimdallErrors()
+	if err != nil {
+		t.Fatalf("failed to get the heimdall errors: %v", err)
+	}
+	if have := report.Counts["/checkpoints/count"]; have != 2 {
+		t.Fatalf("failed requests mismatch: have %d, want 2", have)
+	}
+	if len(report.Errors) != 2 {
+		t.Fatalf("reported errors mismatch: have %d, want 2", len(report.Errors))
+	}
+}
@@ -116,6 +116,12 @@ type Ethereum struct {
 
 	closeCh chan struct{} // Channel to signal the background processes to exit
 
+	checker *whitelist.Service // Whitelist service tracking the latest milestone and checkpoint
+
+	autoUnwind *autoUnwind // Unwinds the chain to the parent of conflicting milestones, nil if disabled
+
+	stateSyncChecker *stateSyncReorgChecker // Detects reorgs regressing the applied state sync events, nil if not on bor
+
 	shutdownTracker *shutdowncheck.ShutdownTracker // Tracks if and when the node has shutdown ungracefully
 }
 
@@ -243,6 +249,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			StateScheme:         scheme,
 			TriesInMemory:       config.TriesInMemory,
 			ChainHistoryMode:    config.HistoryMode,
+			StateSyncIndex:      config.BorStateSyncIndex,
 		}
 	)
 
@@ -259,6 +266,11 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}
 
 	checker := whitelist.NewService(chainDb, config.DisableBlindForkValidation, config.MaxBlindForkValidationLimit)
+	if config.FinalityLagThreshold > 0 {
+		checker.SetFinalityLagThreshold(config.FinalityLagThreshold)
+	}
+	checker.SetTracer(vmConfig.Tracer)
+	eth.checker = checker
 
 	// Override the chain config with provided settings.
 	var overrides core.ChainOverrides
@@ -272,7 +284,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	// check if Parallel EVM is enabled
 	// if enabled, use parallel state processor
 	if config.ParallelEVM.Enable {
-		eth.blockchain, err = core.NewParallelBlockChain(chainDb, cacheConfig, config.Genesis, &overrides, eth.engine, vmConfig, eth.shouldPreserve, &config.TransactionHistory, checker, config.ParallelEVM.SpeculativeProcesses, config.ParallelEVM.Enforce)
+		eth.blockchain, err = core.NewParallelBlockChain(chainDb, cacheConfig, config.Genesis, &overrides, eth.engine, vmConfig, eth.shouldPreserve, &config.TransactionHistory, checker, config.ParallelEVM.SpeculativeProcesses, config.ParallelEVM.Enforce, config.ParallelEVM.StatsSampleRate, config.ParallelEVM.MVHashMapShards)
 	} else {
 		eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, config.Genesis, &overrides, eth.engine, vmConfig, eth.shouldPreserve, &config.TransactionHistory, checker)
 	}
@@ -281,6 +293,10 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		return nil, err
 	}
 
+	if config.ParallelEVM.Enable && (len(config.ParallelEVM.HotContracts) > 0 || config.ParallelEVM.LearnHotContracts) {
+		eth.blockchain.SetHotContracts(config.ParallelEVM.HotContracts, config.ParallelEVM.LearnHotContracts)
+	}
+
 	// Set blockchain reference for fork detection in whitelist service
 	checker.SetBlockchain(eth.blockchain)
 
@@ -362,6 +378,32 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		return nil, err
 	}
 
+	// Unwind the chain stuck on a fork conflicting with the milestones
+	if config.BorAutoUnwind {
+		eth.autoUnwind = newAutoUnwind(config.BorAutoUnwindDepth)
+	}
+
+	// Skip the seal verification of the headers attested by the whitelisted milestone
+	if borEngine, ok := eth.engine.(*bor.Bor); ok && !config.BorVerifyFull {
+		borEngine.SetMilestoneReader(eth.handler.downloader.ChainValidator)
+	}
+
+	if borEngine, ok := eth.engine.(*bor.Bor); ok {
+		borEngine.SetRootHashMaxRange(config.BorRootHashMaxRange)
+		borEngine.SetStrictSealing(config.BorStrictSealing)
+		borEngine.SetTracer(vmConfig.Tracer)
+
+		if config.BorSpanOverride != "" {
+			if err := borEngine.LoadSpanOverride(config.BorSpanOverride, config.BorSpanOverrideKey); err != nil {
+				return nil, fmt.Errorf("failed to load span override: %w", err)
+			}
+		}
+
+		if borEngine.GenesisContractsClient != nil {
+			eth.stateSyncChecker = newStateSyncReorgChecker(borEngine.GenesisContractsClient, eth.blockchain)
+		}
+	}
+
 	eth.dropper = newDropper(eth.p2pServer.MaxDialedConns(), eth.p2pServer.MaxInboundConns())
 	eth.miner = miner.New(eth, &config.Miner, eth.blockchain.Config(), eth.EventMux(), eth.engine, eth.isLocalBlock)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
@@ -442,6 +484,9 @@ func (s *Ethereum) APIs() []rpc.API {
 		}, {
 			Namespace: "debug",
 			Service:   NewDebugAPI(s),
+		}, {
+			Namespace: "bor",
+			Service:   NewBorAPI(s),
 		}, {
 			Namespace: "net",
 			Service:   s.netRPCService,
@@ -664,6 +709,11 @@ func (s *Ethereum) Start() error {
 
 	go s.startCheckpointWhitelistService()
 	go s.startMilestoneWhitelistService()
+	go s.startFinalityLagReporter()
+
+	if s.stateSyncChecker != nil {
+		s.stateSyncChecker.start(s.blockchain, s.closeCh)
+	}
 
 	// start log indexer
 	s.filterMaps.Start()
@@ -702,6 +752,11 @@ func (s *Ethereum) startMilestoneWhitelistService() {
 		tickerDuration = 2 * time.Second
 	)
 
+	// Catch up with the milestones emitted while the node was offline
+	if bor != nil {
+		s.backfillMilestones(ethHandler, bor)
+	}
+
 	// If heimdall ws is available use WS subscription to new milestone events instead of polling
 	if bor != nil && bor.HeimdallWSClient != nil {
 		for {
@@ -722,6 +777,28 @@ func (s *Ethereum) startMilestoneWhitelistService() {
 	s.retryHeimdallHandler(s.fetchAndHandleMilestone, tickerDuration, whitelistTimeout)
 }
 
+// startFinalityLagReporter periodically reports the lag of the latest milestone and
+// checkpoint behind the wall clock.
+func (s *Ethereum) startFinalityLagReporter() {
+	const tickerDuration = 30 * time.Second
+
+	if s.checker == nil {
+		return
+	}
+
+	ticker := time.NewTicker(tickerDuration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.checker.ReportFinalityLag()
+		case <-s.closeCh:
+			return
+		}
+	}
+}
+
 func (s *Ethereum) retryHeimdallHandler(fn heimdallHandler, tickerDuration time.Duration, timeout time.Duration) {
 	retryHeimdallHandler(fn, tickerDuration, timeout, s.closeCh, s.getHandler)
 }
@@ -795,6 +872,33 @@ func (s *Ethereum) fetchAndHandleMilestone(ctx context.Context, ethHandler *ethH
 	return ethHandler.handleMilestone(ctx, s, milestone, verifier)
 }
 
+// backfillMilestones processes, in order, the milestones emitted since the last one
+// whitelisted before the node was stopped.
+func (s *Ethereum) backfillMilestones(ethHandler *ethHandler, bor *bor.Bor) {
+	exists, lastEndBlock, _ := ethHandler.downloader.GetWhitelistedMilestone()
+	if !exists {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), whitelistTimeout)
+	defer cancel()
+
+	verifier := newBorVerifier()
+
+	err := ethHandler.backfillMilestones(ctx, bor, lastEndBlock, func(m *milestone.Milestone) error {
+		// Keep going on failures, the milestones which can't be whitelisted yet are
+		// queued as future milestones
+		if err := ethHandler.handleMilestone(ctx, s, m, verifier); err != nil {
+			log.Debug("Failed to handle backfilled milestone", "start", m.StartBlock, "end", m.EndBlock, "err", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Warn("Failed to backfill milestones", "err", err)
+	}
+}
+
 func (s *Ethereum) subscribeAndHandleMilestone(ctx context.Context, ethHandler *ethHandler, bor *bor.Bor) error {
 	milestoneEvents := bor.HeimdallWSClient.SubscribeMilestoneEvents(ctx)
 
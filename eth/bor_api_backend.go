@@ -32,7 +32,7 @@ func (b *EthAPIBackend) GetRootHash(ctx context.Context, starBlockNr uint64, end
 		return "", errBorEngineNotAvailable
 	}
 
-	root, err := api.GetRootHash(starBlockNr, endBlockNr)
+	root, err := api.GetRootHash(ctx, starBlockNr, endBlockNr)
 	if err != nil {
 		return "", err
 	}
@@ -131,3 +131,8 @@ func (b *EthAPIBackend) SubscribeStateSyncEvent(ch chan<- core.StateSyncEvent) e
 func (b *EthAPIBackend) SubscribeChain2HeadEvent(ch chan<- core.Chain2HeadEvent) event.Subscription {
 	return b.eth.BlockChain().SubscribeChain2HeadEvent(ch)
 }
+
+// SubscribeWitnessEvent subscribes to generated and injected witness events
+func (b *EthAPIBackend) SubscribeWitnessEvent(ch chan<- core.WitnessEvent) event.Subscription {
+	return b.eth.BlockChain().SubscribeWitnessEvent(ch)
+}
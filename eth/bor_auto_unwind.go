@@ -0,0 +1,98 @@
+package eth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// defaultAutoUnwindCooldown is the minimum time between two automatic unwinds, so that
+// conflicting milestones don't make the node flap between forks.
+const defaultAutoUnwindCooldown = 5 * time.Minute
+
+// autoUnwindCounter counts the unwinds of the local chain to a conflicting milestone
+var autoUnwindCounter = metrics.NewRegisteredCounter("bor/auto_unwind", nil)
+
+// autoUnwind unwinds the local chain to the parent of a milestone conflicting with it,
+// i.e. when the node is stuck on a minority fork, so that it syncs the milestone fork
+// again. The unwinds are bounded in depth and rate limited.
+type autoUnwind struct {
+	maxDepth uint64           // Maximum number of blocks to unwind
+	cooldown time.Duration    // Minimum time between two unwinds
+	clock    func() time.Time // Source of the current time, replaceable in tests
+	last     time.Time        // Time of the last unwind
+	lock     sync.Mutex
+}
+
+func newAutoUnwind(maxDepth uint64) *autoUnwind {
+	return &autoUnwind{
+		maxDepth: maxDepth,
+		cooldown: defaultAutoUnwindCooldown,
+		clock:    time.Now,
+	}
+}
+
+// allow reports whether the chain can be unwound from the given head to the given block,
+// i.e. the unwind isn't deeper than the max depth and the cooldown of the last one is over.
+// The unwind is then accounted for.
+func (u *autoUnwind) allow(head uint64, rewindTo uint64) bool {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	now := u.clock()
+
+	if head-rewindTo > u.maxDepth {
+		log.Error("Local chain conflicts with the milestone, refusing to unwind beyond the max depth", "head", head,
+			"rewindTo", rewindTo, "maxDepth", u.maxDepth)
+		return false
+	}
+
+	if !u.last.IsZero() && now.Sub(u.last) < u.cooldown {
+		log.Warn("Local chain conflicts with the milestone, waiting for the unwind cooldown", "head", head,
+			"rewindTo", rewindTo, "last", u.last)
+		return false
+	}
+
+	u.last = now
+
+	autoUnwindCounter.Inc(1)
+
+	return true
+}
+
+// unwind unwinds the chain from the given head to the parent of the conflicting milestone
+// [start, end] ending with the given hash, unless refused by allow. The blocks of the
+// milestone are inserted right away if they are known locally, otherwise the downloader
+// syncs them from the peers.
+func (u *autoUnwind) unwind(eth *Ethereum, head uint64, start uint64, end uint64, hash common.Hash) bool {
+	var rewindTo uint64
+	if start > 0 {
+		rewindTo = start - 1
+	}
+
+	if !u.allow(head, rewindTo) {
+		return false
+	}
+
+	log.Warn("Unwinding the chain to the parent of the conflicting milestone", "head", head, "rewindTo", rewindTo,
+		"start", start, "end", end, "hash", hash)
+
+	length := int(end - rewindTo)
+
+	milestoneChain := eth.BlockChain().GetBlocksFromHash(hash, length)
+	if len(milestoneChain) != length {
+		milestoneChain = nil
+	}
+
+	// Reverse the milestone chain
+	for i, j := 0, len(milestoneChain)-1; i < j; i, j = i+1, j-1 {
+		milestoneChain[i], milestoneChain[j] = milestoneChain[j], milestoneChain[i]
+	}
+
+	reorgToFinalized(eth, head, rewindTo, milestoneChain)
+
+	return true
+}
@@ -0,0 +1,122 @@
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall/milestone"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/downloader/whitelist"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that milestones conflicting with the local chain unwind it to their parent and
+// reorg it to the milestone fork, once per cooldown and not beyond the max depth.
+func TestHandleMilestoneAutoUnwind(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		gspec  = &core.Genesis{Config: params.TestChainConfig}
+		engine = ethash.NewFaker()
+	)
+
+	// The milestones are on a fork of the local chain after block 10
+	_, local, _ := core.GenerateChainWithGenesis(gspec, engine, 22, nil)
+	_, remote, _ := core.GenerateChainWithGenesis(gspec, engine, 17, func(i int, gen *core.BlockGen) {
+		if i >= 10 {
+			gen.SetExtra([]byte("remote"))
+		}
+	})
+
+	chain, err := core.NewBlockChain(db, nil, gspec, nil, engine, vm.Config{}, nil, nil, nil)
+	require.NoError(t, err)
+	defer chain.Stop()
+
+	insert := func(blocks []*types.Block) {
+		t.Helper()
+
+		_, err := chain.InsertChain(blocks)
+		require.NoError(t, err)
+	}
+	insert(local[:20])
+
+	// The shorter milestone fork is known as a side chain
+	insert(remote)
+
+	eth := &Ethereum{blockchain: chain, chainDb: db, config: &ethconfig.Config{}}
+	eth.APIBackend = &EthAPIBackend{eth: eth}
+
+	eth.handler, err = newHandler(&handlerConfig{
+		Database:   db,
+		Chain:      chain,
+		TxPool:     newTestTxPool(),
+		Network:    1,
+		Sync:       downloader.FullSync,
+		BloomCache: 1,
+		EthAPI:     ethapi.NewBlockChainAPI(eth.APIBackend),
+		checker:    whitelist.NewService(db, false, 0),
+	})
+	require.NoError(t, err)
+
+	now := time.Unix(1_000_000, 0)
+
+	eth.autoUnwind = newAutoUnwind(10)
+	eth.autoUnwind.clock = func() time.Time { return now }
+
+	var (
+		handler  = (*ethHandler)(eth.handler)
+		verifier = newBorVerifier()
+		unwinds  = autoUnwindCounter.Snapshot().Count()
+	)
+
+	handle := func(id string, start uint64, end uint64, hash common.Hash) error {
+		return handler.handleMilestone(t.Context(), eth, &milestone.Milestone{
+			MilestoneID: id,
+			StartBlock:  start,
+			EndBlock:    end,
+			Hash:        hash,
+		}, verifier)
+	}
+	head := func() uint64 {
+		return chain.CurrentBlock().Number.Uint64()
+	}
+
+	// A milestone agreeing with the local chain is whitelisted without unwinding it
+	require.NoError(t, handle("milestone1", 1, 10, local[9].Hash()))
+	require.Equal(t, uint64(20), head())
+	require.Equal(t, unwinds, autoUnwindCounter.Snapshot().Count())
+
+	// A conflicting milestone unwinds it to its parent, then the milestone fork is inserted
+	require.ErrorIs(t, handle("milestone2", 11, 15, remote[14].Hash()), errHashMismatch)
+	require.Equal(t, uint64(15), head())
+	require.Equal(t, remote[14].Hash(), chain.CurrentBlock().Hash())
+	require.Equal(t, unwinds+1, autoUnwindCounter.Snapshot().Count())
+
+	// The next conflicting milestones don't unwind it again during the cooldown
+	insert(local[10:20])
+	require.Equal(t, local[19].Hash(), chain.CurrentBlock().Hash())
+
+	require.ErrorIs(t, handle("milestone3", 11, 16, remote[15].Hash()), errHashMismatch)
+	require.Equal(t, uint64(20), head())
+	require.Equal(t, unwinds+1, autoUnwindCounter.Snapshot().Count())
+
+	// Once the cooldown is over, unwinds deeper than the max depth are refused
+	now = now.Add(defaultAutoUnwindCooldown)
+
+	insert(local[20:22])
+
+	require.ErrorIs(t, handle("milestone4", 11, 17, remote[16].Hash()), errHashMismatch)
+	require.Equal(t, uint64(22), head())
+	require.Equal(t, unwinds+1, autoUnwindCounter.Snapshot().Count())
+}
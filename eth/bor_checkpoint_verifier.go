@@ -103,6 +103,12 @@ func borVerify(ctx context.Context, eth *Ethereum, handler *ethHandler, start ui
 			log.Warn("End block hash mismatch while whitelisting milestone", "expected", localHash, "got", hash)
 		}
 
+		// With auto unwind, the chain is unwound to the parent of the conflicting milestone
+		if !isCheckpoint && eth.autoUnwind != nil {
+			eth.autoUnwind.unwind(eth, head, start, end, common.HexToHash(hash))
+			return hash, errHashMismatch
+		}
+
 		ethHandler := (*ethHandler)(eth.handler)
 
 		var (
@@ -178,13 +184,13 @@ func borVerify(ctx context.Context, eth *Ethereum, handler *ethHandler, start ui
 // reorgToFinalized stops the miner if the mining process is running and rewinds back the chain
 // and inserts the chain finalized by checkpoint/milestone.
 func reorgToFinalized(eth *Ethereum, head uint64, rewindTo uint64, canonicalChain []*types.Block) {
-	if eth.Miner().Mining() {
+	if miner := eth.Miner(); miner != nil && miner.Mining() {
 		ch := make(chan struct{})
-		eth.Miner().Stop(ch)
+		miner.Stop(ch)
 
 		<-ch
 
-		defer eth.Miner().Start()
+		defer miner.Start()
 	}
 
 	rewind(eth, head, rewindTo)
@@ -0,0 +1,139 @@
+package eth
+
+import (
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// stateSyncRegressionCounter counts the reorgs whose new head applied fewer state sync
+// events than the old one.
+var stateSyncRegressionCounter = metrics.NewRegisteredCounter("bor/statesync/reorg/regressions", nil)
+
+// StateSyncWatermark is the id of the last state sync event applied by the chain head.
+type StateSyncWatermark struct {
+	Number      uint64      `json:"number"`
+	Hash        common.Hash `json:"hash"`
+	LastStateID uint64      `json:"lastStateId"`
+	Regressions uint64      `json:"regressions"` // Reorgs which regressed the last applied id since startup
+}
+
+// chain2HeadSubscriber is the part of the blockchain the state sync reorg checker
+// follows.
+type chain2HeadSubscriber interface {
+	SubscribeChain2HeadEvent(ch chan<- core.Chain2HeadEvent) event.Subscription
+}
+
+// stateReader opens the states the last applied state sync ids are read from.
+type stateReader interface {
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// stateSyncReorgChecker checks that the reorgs of the chain don't regress the id of the
+// last state sync event applied, which happens if both branches committed a sprint but
+// heimdall served them different batches of events.
+type stateSyncReorgChecker struct {
+	contracts   bor.GenesisContract
+	states      stateReader
+	regressions atomic.Uint64
+}
+
+func newStateSyncReorgChecker(contracts bor.GenesisContract, states stateReader) *stateSyncReorgChecker {
+	return &stateSyncReorgChecker{contracts: contracts, states: states}
+}
+
+// start subscribes to the chain and checks its reorgs in the background until quit is
+// closed. The reorgs posted once it returns are checked.
+func (c *stateSyncReorgChecker) start(chain chain2HeadSubscriber, quit chan struct{}) {
+	events := make(chan core.Chain2HeadEvent, 16)
+	sub := chain.SubscribeChain2HeadEvent(events)
+
+	go c.loop(events, sub, quit)
+}
+
+// loop checks the posted reorgs until quit is closed.
+func (c *stateSyncReorgChecker) loop(events chan core.Chain2HeadEvent, sub event.Subscription, quit chan struct{}) {
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type == core.Chain2HeadReorgEvent && len(ev.OldChain) > 0 && len(ev.NewChain) > 0 {
+				c.check(ev.OldChain[0], ev.NewChain[0])
+			}
+		case <-sub.Err():
+			return
+		case <-quit:
+			return
+		}
+	}
+}
+
+// check compares the ids of the last state sync event applied by the old and new heads
+// of a reorg, reporting whether the new head regresses it.
+func (c *stateSyncReorgChecker) check(oldHead *types.Header, newHead *types.Header) bool {
+	oldID, err := c.lastStateID(oldHead)
+	if err != nil {
+		log.Debug("Failed to read last state sync id of the old head", "number", oldHead.Number, "hash", oldHead.Hash(), "err", err)
+		return false
+	}
+
+	newID, err := c.lastStateID(newHead)
+	if err != nil {
+		log.Debug("Failed to read last state sync id of the new head", "number", newHead.Number, "hash", newHead.Hash(), "err", err)
+		return false
+	}
+
+	if newID >= oldID {
+		return false
+	}
+
+	c.regressions.Add(1)
+	stateSyncRegressionCounter.Inc(1)
+
+	log.Warn("Reorg regressed the last applied state sync event",
+		"oldNumber", oldHead.Number, "oldHash", oldHead.Hash(), "oldID", oldID,
+		"newNumber", newHead.Number, "newHash", newHead.Hash(), "newID", newID)
+
+	return true
+}
+
+// watermark returns the id of the last state sync event applied by the given head.
+func (c *stateSyncReorgChecker) watermark(head *types.Header) (*StateSyncWatermark, error) {
+	id, err := c.lastStateID(head)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StateSyncWatermark{
+		Number:      head.Number.Uint64(),
+		Hash:        head.Hash(),
+		LastStateID: id,
+		Regressions: c.regressions.Load(),
+	}, nil
+}
+
+// lastStateID reads the id of the last state sync event applied at the given block from
+// the state receiver contract. The call runs on the state of the block itself rather
+// than on the canonical block of the same number, which is the other branch after a
+// reorg.
+func (c *stateSyncReorgChecker) lastStateID(header *types.Header) (uint64, error) {
+	statedb, err := c.states.StateAt(header.Root)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := c.contracts.LastStateId(statedb, header.Number.Uint64(), header.Hash())
+	if err != nil {
+		return 0, err
+	}
+
+	return id.Uint64(), nil
+}
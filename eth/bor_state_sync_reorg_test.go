@@ -0,0 +1,147 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/bor/contract"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// stateReceiverCode stores the word sent to it as the last state sync id, and returns
+// it to any other call such as lastStateId().
+var stateReceiverCode = hexutil.MustDecode("0x3660201460125760005460005260206000f35b60003560005500")
+
+// Tests that reorgs to a branch which applied fewer state sync events are detected, reading
+// the ids from the state receiver contract at both heads.
+func TestStateSyncReorgChecker(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		receiver = common.HexToAddress("0x0000000000000000000000000000000000001001")
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				addr:     {Balance: big.NewInt(params.Ether)},
+				receiver: {Code: stateReceiverCode},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		engine = ethash.NewFaker()
+		db     = rawdb.NewMemoryDatabase()
+	)
+
+	// commit returns a generator applying state sync events up to the given id in the blocks
+	commit := func(ids map[int]uint64) func(int, *core.BlockGen) {
+		return func(i int, b *core.BlockGen) {
+			id, ok := ids[i]
+			if !ok {
+				return
+			}
+
+			data := common.BigToHash(new(big.Int).SetUint64(id)).Bytes()
+			tx, err := types.SignTx(types.NewTransaction(b.TxNonce(addr), receiver, common.Big0, 100_000, b.BaseFee(), data), signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign tx: %v", err)
+			}
+
+			b.AddTx(tx)
+		}
+	}
+
+	// All the branches apply the events up to 10 at block 1, then a different batch at block 2
+	_, branchA, _ := core.GenerateChainWithGenesis(gspec, engine, 3, commit(map[int]uint64{0: 10, 1: 15}))
+	_, branchB, _ := core.GenerateChainWithGenesis(gspec, engine, 4, commit(map[int]uint64{0: 10, 1: 13}))
+	_, branchC, _ := core.GenerateChainWithGenesis(gspec, engine, 5, commit(map[int]uint64{0: 10, 1: 15}))
+
+	chain, err := core.NewBlockChain(db, nil, gspec, nil, engine, vm.Config{}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	eth := &Ethereum{blockchain: chain, chainDb: db, engine: engine, config: &ethconfig.Config{}}
+	eth.APIBackend = &EthAPIBackend{eth: eth}
+
+	var (
+		client  = contract.NewGenesisContractsClient(gspec.Config, "", receiver.Hex(), ethapi.NewBlockChainAPI(eth.APIBackend))
+		checker = newStateSyncReorgChecker(client, chain)
+		quit    = make(chan struct{})
+	)
+	defer close(quit)
+
+	regressions := stateSyncRegressionCounter.Snapshot().Count()
+
+	// Reorgs of the chain are checked in the background
+	checker.start(chain, quit)
+
+	insert := func(blocks []*types.Block) {
+		t.Helper()
+
+		if _, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("failed to insert blocks: %v", err)
+		}
+	}
+	head := func(blocks []*types.Block) *types.Header {
+		return blocks[len(blocks)-1].Header()
+	}
+
+	// Reorging to a branch which applied fewer events is a regression
+	insert(branchA)
+	insert(branchB)
+
+	if have := chain.CurrentBlock().Hash(); have != head(branchB).Hash() {
+		t.Fatalf("chain not reorged: have head %x, want %x", have, head(branchB).Hash())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for checker.regressions.Load() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("regression of the reorg not detected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Reorging to a branch which applied as many events is not
+	insert(branchC)
+
+	if checker.check(head(branchB), head(branchC)) {
+		t.Fatalf("unexpected regression detected")
+	}
+	// The ids are read from the heads themselves, not from the canonical blocks of their numbers
+	if !checker.check(head(branchA), head(branchB)) {
+		t.Fatalf("regression of non-canonical heads not detected")
+	}
+	// Unknown states are not reported as regressions
+	if checker.check(head(branchA), &types.Header{Number: big.NewInt(3), Root: common.Hash{0x01}}) {
+		t.Fatalf("unexpected regression detected for an unknown state")
+	}
+
+	if have := checker.regressions.Load(); have != 2 {
+		t.Fatalf("regression count mismatch: have %d, want 2", have)
+	}
+	if have := stateSyncRegressionCounter.Snapshot().Count(); have != regressions+2 {
+		t.Errorf("regression metric mismatch: have %d, want %d", have, regressions+2)
+	}
+
+	// The watermark reports the head and the regressions so far
+	watermark, err := checker.watermark(head(branchB))
+	if err != nil {
+		t.Fatalf("failed to read watermark: %v", err)
+	}
+	want := &StateSyncWatermark{Number: 4, Hash: head(branchB).Hash(), LastStateID: 13, Regressions: 2}
+	if *watermark != *want {
+		t.Errorf("watermark mismatch: have %+v, want %+v", watermark, want)
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -1480,7 +1481,7 @@ func (d *Downloader) processHeaders(origin uint64, td, ttd *big.Int, beaconMode
 						if n, err := d.lightchain.InsertHeaderChain(chunkHeaders); err != nil {
 							log.Warn("Invalid header encountered", "number", chunkHeaders[n].Number, "hash", chunkHashes[n], "parent", chunkHeaders[n].ParentHash, "err", err)
 
-							return fmt.Errorf("%w: %v", errInvalidChain, err)
+							return invalidChainError(err)
 						}
 					}
 
@@ -1641,12 +1642,24 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 			return fmt.Errorf("%v: %w", errInvalidChain, err)
 		}
 
-		return fmt.Errorf("%w: %v", errInvalidChain, err)
+		return invalidChainError(err)
 	}
 
 	return nil
 }
 
+// invalidChainError returns the error of a failed chain import, flagged as an invalid
+// chain so that the peer which served it is dropped. Chains which couldn't be verified
+// for lack of local data (e.g. heimdall being unreachable) are no fault of the peer, so
+// their errors are returned as is and the sync is retried.
+func invalidChainError(err error) error {
+	if errors.Is(err, consensus.ErrVerificationDataUnavailable) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %v", errInvalidChain, err)
+}
+
 // processSnapSyncContent takes fetch results from the queue and writes them to the
 // database. It also controls the synchronisation of state nodes of the pivot block.
 func (d *Downloader) processSnapSyncContent() error {
@@ -1505,8 +1505,8 @@ func (w *whitelistFake) GetWhitelistedCheckpoint() (bool, uint64, common.Hash) {
 }
 func (w *whitelistFake) PurgeWhitelistedCheckpoint() {}
 
-func (w *whitelistFake) ProcessMilestone(_ uint64, _ common.Hash)       {}
-func (w *whitelistFake) ProcessFutureMilestone(_ uint64, _ common.Hash) {}
+func (w *whitelistFake) ProcessMilestone(_ string, _ uint64, _ common.Hash) {}
+func (w *whitelistFake) ProcessFutureMilestone(_ uint64, _ common.Hash)     {}
 func (w *whitelistFake) GetWhitelistedMilestone() (bool, uint64, common.Hash) {
 	return false, 0, common.Hash{}
 }
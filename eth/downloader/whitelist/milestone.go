@@ -23,6 +23,9 @@ type milestone struct {
 	Locked                bool                //
 	LockedMilestoneIDs    map[string]struct{} //list of milestone ids
 
+	LastMilestoneID  string // ID of the last whitelisted milestone
+	LastMilestoneSeq uint64 // Number of milestones whitelisted since startup
+
 	FutureMilestoneList  map[uint64]common.Hash // Future Milestone list
 	FutureMilestoneOrder []uint64               // Future Milestone Order
 	MaxCapacity          int                    //Capacity of future Milestone list
@@ -40,6 +43,7 @@ type milestoneService interface {
 	UnlockMutex(doLock bool, milestoneId string, endBlockNum uint64, endBlockHash common.Hash)
 	UnlockSprint(endBlockNum uint64)
 	ProcessFutureMilestone(num uint64, hash common.Hash)
+	ProcessMilestone(milestoneId string, endBlockNum uint64, endBlockHash common.Hash) bool
 }
 
 var (
@@ -57,6 +61,9 @@ var (
 
 	//Metrics for collecting the number of valid peers received
 	MilestonePeerMeter = metrics.NewRegisteredMeter("chain/milestone/isvalidpeer", nil)
+
+	//Metrics for collecting the number of milestones rejected for regressing the whitelisted one
+	MilestoneRegressionMeter = metrics.NewRegisteredMeter("chain/milestone/regressions", nil)
 )
 
 // IsValidChain checks the validity of chain by comparing it
@@ -139,9 +146,30 @@ func (m *milestone) IsValidPeer(fetchHeadersByNumber func(number uint64, amount
 }
 
 func (m *milestone) Process(block uint64, hash common.Hash) {
+	m.ProcessMilestone("", block, hash)
+}
+
+// ProcessMilestone whitelists the milestone with the given id and end block, reporting
+// whether it was accepted. A milestone ending before the whitelisted one is rejected as
+// a replay of a stale milestone, e.g. served by heimdall after a rollback, unless the
+// whitelisted milestone was purged first.
+func (m *milestone) ProcessMilestone(milestoneId string, block uint64, hash common.Hash) bool {
 	m.finality.Lock()
 	defer m.finality.Unlock()
 
+	if m.doExist && block < m.Number {
+		log.Warn("Rejecting milestone behind the whitelisted one", "milestoneID", milestoneId, "endBlock", block, "hash", hash,
+			"whitelistedID", m.LastMilestoneID, "whitelistedNumber", m.Number, "whitelistedHash", m.Hash)
+		MilestoneRegressionMeter.Mark(1)
+
+		return false
+	}
+
+	if milestoneId != "" && milestoneId != m.LastMilestoneID {
+		m.LastMilestoneID = milestoneId
+		m.LastMilestoneSeq++
+	}
+
 	m.finality.Process(block, hash)
 
 	for i := 0; i < len(m.FutureMilestoneOrder); i++ {
@@ -155,6 +183,8 @@ func (m *milestone) Process(block uint64, hash common.Hash) {
 	whitelistedMilestoneMeter.Update(int64(block))
 
 	m.UnlockSprint(block)
+
+	return true
 }
 
 // This function will Lock the mutex at the time of voting
@@ -178,6 +208,12 @@ func (m *milestone) LockMutex(endBlockNum uint64) bool {
 // This function will unlock the mutex locked in LockMutex
 // fixme: get rid of it
 func (m *milestone) UnlockMutex(doLock bool, milestoneId string, endBlockNum uint64, endBlockHash common.Hash) {
+	// Don't lock a milestone which was already whitelisted
+	if doLock && milestoneId != "" && milestoneId == m.LastMilestoneID {
+		log.Warn("Milestone already whitelisted, not locking it", "milestoneID", milestoneId, "endBlock", endBlockNum)
+		doLock = false
+	}
+
 	m.Locked = m.Locked || doLock
 
 	if doLock {
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
@@ -37,6 +39,13 @@ type Service struct {
 	lastValidForkBlock         uint64 // Last known valid block for fork correctness check
 	forkValidationCache        map[common.Hash]bool
 	forkValidationCacheMu      sync.RWMutex
+
+	milestoneStatus      *finalityTracker[*rawdb.Milestone]  // Processing status of the latest milestone
+	checkpointStatus     *finalityTracker[*rawdb.Checkpoint] // Processing status of the latest checkpoint
+	finalityLagThreshold time.Duration                       // Finality lag after which a warning is logged
+	blockchain           ChainReader                         // Blockchain access for block timestamps
+	clock                func() time.Time                    // Source of the current time, replaceable in tests
+	tracer               *tracing.Hooks                      // Live tracer notified of the whitelisted milestones, nil if none
 }
 
 func NewService(db ethdb.Database, disableBlindForkValidation bool, maxBlindForkValidationLimit uint64) *Service {
@@ -119,11 +128,22 @@ func NewService(db ethdb.Database, disableBlindForkValidation bool, maxBlindFork
 		maxForkCorrectnessLimit:    maxBlindForkValidationLimit,
 		lastValidForkBlock:         0,
 		forkValidationCache:        make(map[common.Hash]bool, forkValidationCacheSize),
+		milestoneStatus:            newFinalityTracker[*rawdb.Milestone](db, milestoneLagGauge, "milestone"),
+		checkpointStatus:           newFinalityTracker[*rawdb.Checkpoint](db, checkpointLagGauge, "checkpoint"),
+		finalityLagThreshold:       DefaultFinalityLagThreshold,
+		clock:                      time.Now,
 	}
 }
 
+// SetTracer sets the live tracer notified of the whitelisted milestones.
+func (s *Service) SetTracer(tracer *tracing.Hooks) {
+	s.tracer = tracer
+}
+
 // SetBlockchain sets the blockchain reference for the milestone service
 func (s *Service) SetBlockchain(blockchain ChainReader) {
+	s.blockchain = blockchain
+
 	if milestone, ok := s.milestoneService.(*milestone); ok {
 		milestone.blockchain = blockchain
 	}
@@ -161,14 +181,35 @@ func (s *Service) GetWhitelistedMilestone() (bool, uint64, common.Hash) {
 	return s.milestoneService.Get()
 }
 
-func (s *Service) ProcessMilestone(endBlockNum uint64, endBlockHash common.Hash) {
-	s.milestoneService.Process(endBlockNum, endBlockHash)
+func (s *Service) ProcessMilestone(milestoneId string, endBlockNum uint64, endBlockHash common.Hash) {
+	if !s.milestoneService.ProcessMilestone(milestoneId, endBlockNum, endBlockHash) {
+		return
+	}
+
 	s.resetForkValidationCache()
+
+	blockTime, ok := s.blockTime(endBlockNum)
+	if !ok {
+		log.Debug("Milestone end block not available locally, its finality lag is unknown", "number", endBlockNum)
+	}
+
+	s.milestoneStatus.record(endBlockNum, blockTime, s.clock())
+
+	if s.tracer != nil && s.tracer.OnMilestoneProcessed != nil {
+		s.tracer.OnMilestoneProcessed(endBlockNum, endBlockHash)
+	}
 }
 
 func (s *Service) ProcessCheckpoint(endBlockNum uint64, endBlockHash common.Hash) {
 	s.checkpointService.Process(endBlockNum, endBlockHash)
 	s.resetForkValidationCache()
+
+	blockTime, ok := s.blockTime(endBlockNum)
+	if !ok {
+		log.Debug("Checkpoint end block not available locally, its finality lag is unknown", "number", endBlockNum)
+	}
+
+	s.checkpointStatus.record(endBlockNum, blockTime, s.clock())
 }
 
 func (s *Service) IsValidChain(currentHeader *types.Header, chain []*types.Header) (bool, error) {
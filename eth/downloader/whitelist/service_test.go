@@ -73,6 +73,10 @@ func NewMockService(db ethdb.Database) *Service {
 		maxForkCorrectnessLimit: 10,
 		lastValidForkBlock:      0,
 		forkValidationCache:     make(map[common.Hash]bool, 10),
+		milestoneStatus:         newFinalityTracker[*rawdb.Milestone](db, milestoneLagGauge, "milestone"),
+		checkpointStatus:        newFinalityTracker[*rawdb.Checkpoint](db, checkpointLagGauge, "checkpoint"),
+		finalityLagThreshold:    DefaultFinalityLagThreshold,
+		clock:                   time.Now,
 	}
 }
 
@@ -200,7 +204,7 @@ func TestMilestone(t *testing.T) {
 	require.Equal(t, len(milestone.LockedMilestoneIDs), 1, "expected 1 as previous milestonesIDs has been removed in previous step")
 
 	//Adding the milestone
-	s.ProcessMilestone(11, common.Hash{})
+	s.ProcessMilestone("", 11, common.Hash{})
 
 	require.True(t, milestone.Locked, "expected true as locked sprint is of number 15")
 	require.Equal(t, milestone.doExist, true, "expected true as milestone exist")
@@ -223,7 +227,7 @@ func TestMilestone(t *testing.T) {
 	milestone.UnlockMutex(false, "", uint64(11), common.Hash{}) //Unlock is required after every lock to release the mutex
 
 	//Adding the milestone
-	s.ProcessMilestone(51, common.Hash{})
+	s.ProcessMilestone("", 51, common.Hash{})
 	require.False(t, milestone.Locked, "expected false as lock from sprint number 15 is removed")
 	require.Equal(t, milestone.doExist, true, "expected true as milestone exist")
 	require.Equal(t, len(milestone.LockedMilestoneIDs), 0, "expected 0 as all the milestones have been removed")
@@ -241,7 +245,7 @@ func TestMilestone(t *testing.T) {
 	require.Equal(t, milestone.doExist, false, "expected false as no milestone exist at this point")
 
 	//Removing the milestone
-	s.ProcessMilestone(11, common.Hash{1})
+	s.ProcessMilestone("", 11, common.Hash{1})
 
 	doExist, number, hash := s.GetWhitelistedMilestone()
 
@@ -303,7 +307,7 @@ func TestIsValidPeer(t *testing.T) {
 	s.ProcessCheckpoint(uint64(1), common.Hash{})
 
 	// add milestone entry and mock fetchHeadersByNumber function
-	s.ProcessMilestone(uint64(1), common.Hash{})
+	s.ProcessMilestone("", uint64(1), common.Hash{})
 
 	checkpoint := s.checkpointService.(*checkpoint)
 	milestone := s.milestoneService.(*milestone)
@@ -390,14 +394,16 @@ func TestIsValidPeer(t *testing.T) {
 		}
 	}
 
-	s.ProcessMilestone(uint64(3), common.Hash{})
+	s.ProcessMilestone("", uint64(3), common.Hash{})
 
 	//Case5: correct fetchHeadersByNumber function provided with hash mismatch, should consider the chain as invalid
 	res, err = s.IsValidPeer(fetchHeadersByNumber)
 	require.Equal(t, err, ErrMismatch, "expected milestone mismatch error")
 	require.Equal(t, res, false, "expected chain to be invalid")
 
-	s.ProcessMilestone(uint64(2), common.Hash{})
+	// Milestones behind the whitelisted one are only accepted after a purge
+	s.PurgeWhitelistedMilestone()
+	s.ProcessMilestone("", uint64(2), common.Hash{})
 
 	// create a mock function, returning the required header
 	fetchHeadersByNumber = func(number uint64, _ int, _ int, _ bool) ([]*types.Header, []common.Hash, error) {
@@ -452,7 +458,7 @@ func TestIsValidPeer(t *testing.T) {
 	}
 
 	//Add one more milestone in the list
-	s.ProcessMilestone(uint64(3), common.Hash{})
+	s.ProcessMilestone("", uint64(3), common.Hash{})
 
 	// case7: correct fetchHeadersByNumber function provided with wrong header for block 3, should consider the chain as invalid
 	res, err = s.IsValidPeer(fetchHeadersByNumber)
@@ -462,7 +468,7 @@ func TestIsValidPeer(t *testing.T) {
 	//require.Equal(t, milestone.length(), 3, "expected 3 items in milestoneList")
 
 	//Add one more milestone in the list
-	s.ProcessMilestone(uint64(4), common.Hash{})
+	s.ProcessMilestone("", uint64(4), common.Hash{})
 
 	// case8: correct fetchHeadersByNumber function provided with wrong hash for block 3, should consider the chain as valid
 	res, err = s.IsValidPeer(fetchHeadersByNumber)
@@ -510,7 +516,7 @@ func TestIsValidChain(t *testing.T) {
 	require.Equal(t, res, false, "expected chain to be invalid ")
 
 	// add mock milestone entry
-	s.ProcessMilestone(tempChain[1].Number.Uint64(), tempChain[1].Hash())
+	s.ProcessMilestone("", tempChain[1].Number.Uint64(), tempChain[1].Hash())
 
 	//Case4A: As the received chain and current tip of local chain is behind the oldest whitelisted block entry, should consider
 	// the chain as valid
@@ -593,7 +599,7 @@ func TestIsValidChain(t *testing.T) {
 	require.Equal(t, res, true, "expected chain to be valid")
 
 	// add mock milestone entries
-	s.ProcessMilestone(tempChain[1].Number.Uint64(), tempChain[1].Hash())
+	s.ProcessMilestone("", tempChain[1].Number.Uint64(), tempChain[1].Hash())
 
 	// case10: Try importing a past chain having valid checkpoint, should
 	// consider the chain as invalid as still latest milestone is ahead of the chain.
@@ -602,7 +608,7 @@ func TestIsValidChain(t *testing.T) {
 	require.Equal(t, res, false, "expected chain to be invalid")
 
 	// add mock milestone entries
-	s.ProcessMilestone(chainA[19].Number.Uint64(), chainA[19].Hash())
+	s.ProcessMilestone("", chainA[19].Number.Uint64(), chainA[19].Hash())
 
 	// case12: Try importing a chain having valid checkpoint and milestone, should
 	// consider the chain as valid
@@ -611,7 +617,7 @@ func TestIsValidChain(t *testing.T) {
 	require.Equal(t, res, true, "expected chain to be invalid")
 
 	// add mock milestone entries
-	s.ProcessMilestone(chainA[19].Number.Uint64(), chainA[19].Hash())
+	s.ProcessMilestone("", chainA[19].Number.Uint64(), chainA[19].Hash())
 
 	// case13: Try importing a past chain having valid checkpoint and milestone, should
 	// consider the chain as valid
@@ -620,7 +626,7 @@ func TestIsValidChain(t *testing.T) {
 	require.Equal(t, res, true, "expected chain to be valid")
 
 	// add mock milestone entries with wrong hash
-	s.ProcessMilestone(chainA[19].Number.Uint64(), chainA[18].Hash())
+	s.ProcessMilestone("", chainA[19].Number.Uint64(), chainA[18].Hash())
 
 	// case14: Try importing a past chain having valid checkpoint and milestone with wrong hash, should
 	// consider the chain as invalid
@@ -629,7 +635,8 @@ func TestIsValidChain(t *testing.T) {
 	require.Equal(t, res, false, "expected chain to be invalid as hash mismatches")
 
 	// Clear milestone and add blocks A15 in whitelist
-	s.ProcessMilestone(chainA[15].Number.Uint64(), chainA[15].Hash())
+	s.PurgeWhitelistedMilestone()
+	s.ProcessMilestone("", chainA[15].Number.Uint64(), chainA[15].Hash())
 
 	// case16: Try importing a past chain having valid checkpoint, should
 	// consider the chain as valid
@@ -1203,7 +1210,7 @@ func TestMilestoneForkDetection(t *testing.T) {
 	// Set up a milestone
 	milestoneNumber := uint64(10)
 	milestoneHash := common.HexToHash("0x1234567890abcdef")
-	service.ProcessMilestone(milestoneNumber, milestoneHash)
+	service.ProcessMilestone("", milestoneNumber, milestoneHash)
 
 	// Test case 1: Fork detected - milestone and local block have different hashes
 	forkHeader := &types.Header{
@@ -1242,7 +1249,7 @@ func TestMilestoneForkDetectionEdgeCases(t *testing.T) {
 	// Set up a milestone and make it exist
 	milestoneNumber := uint64(20)
 	milestoneHash := common.HexToHash("0x1234567890abcdef")
-	service.ProcessMilestone(milestoneNumber, milestoneHash)
+	service.ProcessMilestone("", milestoneNumber, milestoneHash)
 
 	// Test case 1: No blockchain set - should fall through to normal validation
 	milestone.blockchain = nil
@@ -1323,7 +1330,7 @@ func TestForkCorrectness(t *testing.T) {
 
 	t.Run("long fork: more recent checkpoint than milestone", func(t *testing.T) {
 		// Whitelist block 4 as milestone
-		s.ProcessMilestone(chainA[4].Number.Uint64(), chainA[4].Hash())
+		s.ProcessMilestone("", chainA[4].Number.Uint64(), chainA[4].Hash())
 
 		res := s.checkForkCorrectness(chainA[16:]) // 12 blocks ahead of last milestone
 		require.Equal(t, true, res, "expected chain to be valid")
@@ -1367,7 +1374,7 @@ func TestForkCorrectness(t *testing.T) {
 
 	t.Run("incoming chain ahead of last whitelisted entry", func(t *testing.T) {
 		// Whitelist last block of the chain as milestone
-		s.ProcessMilestone(chainA[19].Number.Uint64(), chainA[19].Hash())
+		s.ProcessMilestone("", chainA[19].Number.Uint64(), chainA[19].Hash())
 
 		res := s.checkForkCorrectness(chain1)
 		require.Equal(t, true, res, "expected chain to be valid")
@@ -1443,7 +1450,7 @@ func TestForkCorrectness(t *testing.T) {
 	})
 
 	t.Run("cache clearance on new milestone", func(t *testing.T) {
-		s.ProcessMilestone(chain3[1].Number.Uint64(), chain3[1].Hash())
+		s.ProcessMilestone("", chain3[1].Number.Uint64(), chain3[1].Hash())
 		require.Equal(t, 0, len(s.forkValidationCache), "expected cache to be cleared on new milestone")
 	})
 
@@ -1476,3 +1483,113 @@ func TestForkCorrectness(t *testing.T) {
 		require.Equal(t, chain3[1].Number.Uint64(), s.lastValidForkBlock, "expected last known valid block to be unchanged")
 	})
 }
+
+// TestFinalityStatus tests the tracking and persistence of the milestone and checkpoint
+// processing status along with the lag gauges.
+func TestFinalityStatus(t *testing.T) {
+	t.Parallel()
+
+	db := rawdb.NewMemoryDatabase()
+	blockchain := NewMockChainReader()
+	service := NewMockServiceWithBlockchain(db, blockchain)
+
+	// Control the clock used by the service
+	now := time.Unix(1_000_000, 0)
+	service.clock = func() time.Time { return now }
+
+	// Nothing is reported before any entry is processed
+	milestoneStatus, checkpointStatus := service.GetFinalityStatus()
+	require.Nil(t, milestoneStatus, "expected no milestone status before processing")
+	require.Nil(t, checkpointStatus, "expected no checkpoint status before processing")
+
+	blockchain.SetBlock(100, types.NewBlockWithHeader(&types.Header{Number: big.NewInt(100), Time: uint64(now.Unix()) - 30}))
+	blockchain.SetBlock(64, types.NewBlockWithHeader(&types.Header{Number: big.NewInt(64), Time: uint64(now.Unix()) - 600}))
+
+	service.ProcessMilestone("", 100, common.Hash{0x1})
+	service.ProcessCheckpoint(64, common.Hash{0x2})
+
+	milestoneStatus, checkpointStatus = service.GetFinalityStatus()
+	require.Equal(t, &FinalityStatus{Block: 100, BlockTime: uint64(now.Unix()) - 30, ProcessedAt: uint64(now.Unix()), Lag: 30}, milestoneStatus)
+	require.Equal(t, &FinalityStatus{Block: 64, BlockTime: uint64(now.Unix()) - 600, ProcessedAt: uint64(now.Unix()), Lag: 600}, checkpointStatus)
+
+	// Advance the clock and ensure the lag is reflected in the gauges
+	now = now.Add(time.Minute)
+	service.ReportFinalityLag()
+
+	require.Equal(t, int64(90), milestoneLagGauge.Snapshot().Value(), "unexpected milestone lag")
+	require.Equal(t, int64(660), checkpointLagGauge.Snapshot().Value(), "unexpected checkpoint lag")
+
+	// Ensure the status is persisted and restored by a new service
+	restored := NewService(db, false, 0)
+	restored.clock = func() time.Time { return now }
+
+	milestoneStatus, checkpointStatus = restored.GetFinalityStatus()
+	require.Equal(t, uint64(100), milestoneStatus.Block, "unexpected persisted milestone block")
+	require.Equal(t, uint64(90), milestoneStatus.Lag, "unexpected persisted milestone lag")
+	require.Equal(t, uint64(64), checkpointStatus.Block, "unexpected persisted checkpoint block")
+	require.Equal(t, uint64(660), checkpointStatus.Lag, "unexpected persisted checkpoint lag")
+
+	// The lag of a milestone ending at a block not available locally is unknown, the
+	// gauge keeps the last known lag rather than reporting none
+	service.ProcessMilestone("", 200, common.Hash{0x3})
+	service.ReportFinalityLag()
+
+	milestoneStatus, _ = service.GetFinalityStatus()
+	require.Equal(t, &FinalityStatus{Block: 200, ProcessedAt: uint64(now.Unix())}, milestoneStatus)
+	require.Equal(t, int64(90), milestoneLagGauge.Snapshot().Value(), "unexpected milestone lag")
+}
+
+// TestMilestoneReplay tests that a stale milestone replayed after a newer one doesn't
+// regress the whitelisted milestone, unless the whitelist was purged first.
+// nolint : paralleltest
+func TestMilestoneReplay(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	s := NewMockService(db)
+	milestone := s.milestoneService.(*milestone)
+
+	regressions := MilestoneRegressionMeter.Snapshot().Count()
+
+	s.ProcessMilestone("milestoneID1", 10, common.Hash{0x1})
+	s.ProcessMilestone("milestoneID2", 20, common.Hash{0x2})
+
+	require.Equal(t, "milestoneID2", milestone.LastMilestoneID, "expected the latest milestone id to be tracked")
+	require.Equal(t, uint64(2), milestone.LastMilestoneSeq, "expected 2 milestones to be whitelisted")
+
+	// Replaying the older milestone is rejected
+	s.ProcessMilestone("milestoneID1", 10, common.Hash{0x1})
+
+	doExist, number, hash := s.GetWhitelistedMilestone()
+	require.True(t, doExist, "expected milestone to exist")
+	require.Equal(t, uint64(20), number, "expected the whitelisted milestone to be kept")
+	require.Equal(t, common.Hash{0x2}, hash, "expected the whitelisted milestone to be kept")
+	require.Equal(t, "milestoneID2", milestone.LastMilestoneID, "expected the latest milestone id to be kept")
+	require.Equal(t, uint64(2), milestone.LastMilestoneSeq, "expected the replayed milestone not to be counted")
+	require.Equal(t, regressions+1, MilestoneRegressionMeter.Snapshot().Count(), "expected the regression to be reported")
+
+	dbNumber, dbHash, err := rawdb.ReadFinality[*rawdb.Milestone](db)
+	require.Nil(t, err)
+	require.Equal(t, uint64(20), dbNumber, "expected the persisted milestone to be kept")
+	require.Equal(t, common.Hash{0x2}, dbHash, "expected the persisted milestone to be kept")
+
+	// Reprocessing the whitelisted milestone is a no-op
+	s.ProcessMilestone("milestoneID2", 20, common.Hash{0x2})
+	require.Equal(t, uint64(2), milestone.LastMilestoneSeq, "expected the reprocessed milestone not to be counted")
+	require.Equal(t, regressions+1, MilestoneRegressionMeter.Snapshot().Count(), "expected no regression to be reported")
+
+	// The whitelisted milestone can't be locked again
+	require.True(t, milestone.LockMutex(30), "expected the sprint to be lockable")
+	milestone.UnlockMutex(true, "milestoneID2", 30, common.Hash{0x3})
+	require.False(t, milestone.Locked, "expected the whitelisted milestone not to be locked")
+	require.Equal(t, 0, len(milestone.GetMilestoneIDsList()), "expected no locked milestone ids")
+
+	// The older milestone is accepted after an explicit purge
+	s.PurgeWhitelistedMilestone()
+	s.ProcessMilestone("milestoneID1", 10, common.Hash{0x1})
+
+	doExist, number, hash = s.GetWhitelistedMilestone()
+	require.True(t, doExist, "expected milestone to exist")
+	require.Equal(t, uint64(10), number, "expected the purged milestone to be replaced")
+	require.Equal(t, common.Hash{0x1}, hash, "expected the purged milestone to be replaced")
+	require.Equal(t, "milestoneID1", milestone.LastMilestoneID, "expected the latest milestone id to be tracked")
+	require.Equal(t, regressions+1, MilestoneRegressionMeter.Snapshot().Count(), "expected no regression to be reported")
+}
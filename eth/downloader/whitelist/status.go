@@ -0,0 +1,151 @@
+package whitelist
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// DefaultFinalityLagThreshold is the default lag between the wall clock and the timestamp of
+// the last finalized block after which a warning is logged.
+const DefaultFinalityLagThreshold = 10 * time.Minute
+
+var (
+	//Metrics for collecting the lag (in seconds) between the wall clock and the block finalized by the latest milestone
+	milestoneLagGauge = metrics.NewRegisteredGauge("bor/milestone/lag_seconds", nil)
+
+	//Metrics for collecting the lag (in seconds) between the wall clock and the block finalized by the latest checkpoint
+	checkpointLagGauge = metrics.NewRegisteredGauge("bor/checkpoint/lag_seconds", nil)
+)
+
+// FinalityStatus describes when the last milestone or checkpoint was processed and how far
+// the finalized block lags behind the wall clock.
+type FinalityStatus struct {
+	Block       uint64 `json:"block"`       // End block of the milestone or checkpoint
+	BlockTime   uint64 `json:"blockTime"`   // Timestamp of the end block, 0 if it wasn't available locally
+	ProcessedAt uint64 `json:"processedAt"` // Unix time at which the entry was processed
+	Lag         uint64 `json:"lag"`         // Seconds elapsed since the timestamp of the end block
+}
+
+// finalityTracker keeps track of the processing status of the latest milestone or
+// checkpoint and persists it in the database.
+type finalityTracker[T rawdb.BlockFinality[T]] struct {
+	lock   sync.RWMutex
+	db     ethdb.Database
+	status *rawdb.FinalityStatus
+	gauge  *metrics.Gauge
+	name   string // Name of the tracked entry (checkpoint or milestone)
+}
+
+func newFinalityTracker[T rawdb.BlockFinality[T]](db ethdb.Database, gauge *metrics.Gauge, name string) *finalityTracker[T] {
+	// Ignore the error as the status won't exist until the first entry is processed
+	status, _ := rawdb.ReadFinalityStatus[T](db)
+
+	return &finalityTracker[T]{
+		db:     db,
+		status: status,
+		gauge:  gauge,
+		name:   name,
+	}
+}
+
+// record stores the status of a newly processed entry.
+func (t *finalityTracker[T]) record(block uint64, blockTime uint64, now time.Time) {
+	status := &rawdb.FinalityStatus{
+		Block:       block,
+		BlockTime:   blockTime,
+		ProcessedAt: uint64(now.Unix()),
+	}
+
+	t.lock.Lock()
+	t.status = status
+	t.lock.Unlock()
+
+	if err := rawdb.WriteFinalityStatus[T](t.db, status); err != nil {
+		log.Error("Error in writing finality status to db", "name", t.name, "err", err)
+	}
+}
+
+// get returns the status of the last processed entry along with its lag relative to
+// the given time. It returns nil if no entry has been processed yet.
+func (t *finalityTracker[T]) get(now time.Time) *FinalityStatus {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.status == nil {
+		return nil
+	}
+
+	status := &FinalityStatus{
+		Block:       t.status.Block,
+		BlockTime:   t.status.BlockTime,
+		ProcessedAt: t.status.ProcessedAt,
+	}
+
+	if t.status.BlockTime != 0 && uint64(now.Unix()) > t.status.BlockTime {
+		status.Lag = uint64(now.Unix()) - t.status.BlockTime
+	}
+
+	return status
+}
+
+// report updates the lag gauge and logs a warning if the lag exceeds the threshold.
+func (t *finalityTracker[T]) report(now time.Time, threshold time.Duration) {
+	status := t.get(now)
+	if status == nil {
+		return
+	}
+
+	// The lag is unknown if the end block wasn't available locally, don't report it as none
+	if status.BlockTime == 0 {
+		return
+	}
+
+	t.gauge.Update(int64(status.Lag))
+
+	if threshold > 0 && time.Duration(status.Lag)*time.Second > threshold {
+		log.Warn("Finality lag exceeds threshold, please check the heimdall connection", "name", t.name,
+			"block", status.Block, "lag", time.Duration(status.Lag)*time.Second, "threshold", threshold)
+	}
+}
+
+// SetFinalityLagThreshold sets the lag after which a warning is logged when reporting
+// the finality status.
+func (s *Service) SetFinalityLagThreshold(threshold time.Duration) {
+	s.finalityLagThreshold = threshold
+}
+
+// GetFinalityStatus returns the processing status of the latest milestone and checkpoint.
+// Either of them can be nil if no entry was processed yet.
+func (s *Service) GetFinalityStatus() (*FinalityStatus, *FinalityStatus) {
+	now := s.clock()
+
+	return s.milestoneStatus.get(now), s.checkpointStatus.get(now)
+}
+
+// ReportFinalityLag updates the finality lag gauges and warns if the lag exceeds the
+// configured threshold. It is meant to be called periodically.
+func (s *Service) ReportFinalityLag() {
+	now := s.clock()
+
+	s.milestoneStatus.report(now, s.finalityLagThreshold)
+	s.checkpointStatus.report(now, s.finalityLagThreshold)
+}
+
+// blockTime returns the timestamp of the given block, and false if it isn't available locally.
+func (s *Service) blockTime(number uint64) (uint64, bool) {
+	if s.blockchain == nil {
+		return 0, false
+	}
+
+	block := s.blockchain.GetBlockByNumber(number)
+	if block == nil {
+		return 0, false
+	}
+
+	return block.Time(), true
+}
@@ -77,6 +77,8 @@ var Defaults = Config{
 	RPCEVMTimeout:      5 * time.Second,
 	GPO:                FullNodeGPO,
 	RPCTxFeeCap:        1, // 1 ether
+	BorBlockTx:         true,
+	BorAutoUnwindDepth: 128,
 }
 
 //go:generate go run github.com/fjl/gencodec -type Config -formats toml -out gen_config.go
@@ -184,6 +186,12 @@ type Config struct {
 	// timeout in heimdall requests
 	HeimdallTimeout time.Duration
 
+	// delay between two attempts of a failed heimdall request
+	HeimdallRetryInterval time.Duration
+
+	// maximum number of retries of a failed heimdall request (0 = unlimited)
+	HeimdallMaxRetries uint64
+
 	// No heimdall service
 	WithoutHeimdall bool
 
@@ -202,15 +210,48 @@ type Config struct {
 	// Use child heimdall process to fetch data, Only works when RunHeimdall is true
 	UseHeimdallApp bool
 
+	// Report the node unhealthy when heimdall is unreachable for longer than HeimdallReadinessTimeout
+	HeimdallReadiness bool
+
+	// maximum duration heimdall can be unreachable before the node is reported unhealthy
+	HeimdallReadinessTimeout time.Duration
+
 	// Bor logs flag
 	BorLogs bool
 
+	// BorBlockTx appends the bor transaction to the transactions of the block RPC responses
+	BorBlockTx bool
+
 	// Parallel EVM (Block-STM) related config
 	ParallelEVM core.ParallelEVMConfig `toml:",omitempty"`
 
 	// Develop Fake Author mode to produce blocks without authorisation
 	DevFakeAuthor bool `hcl:"devfakeauthor,optional" toml:"devfakeauthor,optional"`
 
+	// BorVerifyFull verifies the seal of every header, including those attested by the whitelisted milestone
+	BorVerifyFull bool
+
+	// BorRootHashMaxRange is the maximum block range of the bor root hash RPC APIs
+	BorRootHashMaxRange uint64
+
+	// BorSpanOverride is the path of a signed span override file, taking precedence over heimdall
+	BorSpanOverride string
+
+	// BorSpanOverrideKey is the hex encoded public key the span override must be signed with
+	BorSpanOverrideKey string
+
+	// BorStrictSealing refuses to seal blocks if the signer isn't a producer of their span
+	BorStrictSealing bool
+
+	// BorStateSyncIndex indexes the state sync events committed in the chain
+	BorStateSyncIndex bool
+
+	// BorAutoUnwind unwinds the chain to the parent of a milestone conflicting with it
+	BorAutoUnwind bool
+
+	// BorAutoUnwindDepth is the maximum number of blocks unwound automatically
+	BorAutoUnwindDepth uint64
+
 	// OverrideVerkle (TODO: remove after the fork)
 	OverrideVerkle *big.Int `toml:",omitempty"`
 
@@ -222,6 +263,9 @@ type Config struct {
 
 	// MaxBlindForkValidationLimit denotes the maximum number of blocks to traverse back in the database when validating blind forks
 	MaxBlindForkValidationLimit uint64
+
+	// FinalityLagThreshold is the lag of the latest milestone/checkpoint after which a warning is logged
+	FinalityLagThreshold time.Duration
 }
 
 // CreateConsensusEngine creates a consensus engine for the given chain configuration.
@@ -251,19 +295,36 @@ func CreateConsensusEngine(chainConfig *params.ChainConfig, ethConfig *Config, d
 			} else if ethConfig.HeimdallgRPCAddress != "" {
 				heimdallClient = heimdallgrpc.NewHeimdallGRPCClient(ethConfig.HeimdallgRPCAddress)
 			} else {
-				heimdallClient = heimdall.NewHeimdallClient(ethConfig.HeimdallURL, ethConfig.HeimdallTimeout)
+				heimdallClient = heimdall.NewHeimdallClient(ethConfig.HeimdallURL, ethConfig.HeimdallTimeout, ethConfig.HeimdallRetryInterval, ethConfig.HeimdallMaxRetries)
+			}
+
+			var maxUnreachable time.Duration
+			if ethConfig.HeimdallReadiness {
+				maxUnreachable = ethConfig.HeimdallReadinessTimeout
 			}
 
+			heimdallHealth := bor.NewHeimdallHealth(maxUnreachable)
+
 			var heimdallWSClient bor.IHeimdallWSClient
-			var err error
 			if ethConfig.HeimdallWSAddress != "" {
-				heimdallWSClient, err = heimdallws.NewHeimdallWSClient(ethConfig.HeimdallWSAddress)
+				wsClient, err := heimdallws.NewHeimdallWSClient(ethConfig.HeimdallWSAddress)
 				if err != nil {
 					return nil, err
 				}
+
+				// Backfill the milestones missed while reconnecting if the client supports it
+				if fetcher, ok := heimdallClient.(heimdallws.MilestoneFetcher); ok {
+					wsClient.SetMilestoneFetcher(fetcher)
+				}
+
+				heimdallWSClient = wsClient
+				heimdallHealth.SetWSClient(wsClient)
 			}
 
-			return bor.New(chainConfig, db, blockchainAPI, spanner, heimdallClient, heimdallWSClient, genesisContractsClient, false), nil
+			engine := bor.New(chainConfig, db, blockchainAPI, spanner, heimdallHealth.WrapClient(heimdallClient), heimdallWSClient, genesisContractsClient, false)
+			engine.SetHeimdallHealth(heimdallHealth)
+
+			return engine, nil
 		}
 	}
 	return beacon.New(ethash.NewFaker()), nil
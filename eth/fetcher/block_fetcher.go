@@ -883,7 +883,11 @@ func (f *BlockFetcher) importHeaders(peer string, header *types.Header) {
 		// Validate the header and if something went wrong, drop the peer
 		if err := f.verifyHeader(header); err != nil && err != consensus.ErrFutureBlock {
 			log.Debug("Propagated header verification failed", "peer", peer, "number", header.Number, "hash", hash, "err", err)
-			f.dropPeer(peer)
+
+			// The header couldn't be verified locally, it isn't known to be invalid
+			if !errors.Is(err, consensus.ErrVerificationDataUnavailable) {
+				f.dropPeer(peer)
+			}
 
 			return
 		}
@@ -929,9 +933,13 @@ func (f *BlockFetcher) importBlocks(peer string, block *types.Block) {
 			// Weird future block, don't fail, but neither propagate
 
 		default:
-			// Something went very wrong, drop the peer
 			log.Debug("Propagated block verification failed", "peer", peer, "number", block.Number(), "hash", hash, "err", err)
-			f.dropPeer(peer)
+
+			// Something went very wrong, drop the peer, unless the block couldn't be
+			// verified locally and isn't known to be invalid
+			if !errors.Is(err, consensus.ErrVerificationDataUnavailable) {
+				f.dropPeer(peer)
+			}
 
 			return
 		}
@@ -5,6 +5,9 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	types "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
@@ -155,3 +158,84 @@ func TestBorFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+// Tests filtering the state sync logs of a generated chain by the state receiver
+// address and by topic, over block ranges and single blocks.
+func TestBorFiltersStateSyncLogs(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db            = rawdb.NewMemoryDatabase()
+		backend       = &TestBackend{DB: db}
+		borConfig     = &params.BorConfig{Sprint: map[string]uint64{"0": 4}}
+		stateReceiver = common.HexToAddress("0x0000000000000000000000000000000000001001")
+		otherAddr     = common.HexToAddress("0x0000000000000000000000000000000000001010")
+
+		topic1 = common.BytesToHash([]byte("stateSynced1"))
+		topic2 = common.BytesToHash([]byte("stateSynced2"))
+	)
+
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 8, nil)
+
+	// Sprint start blocks 4 and 8 commit state sync events
+	topics := map[uint64]common.Hash{4: topic1, 8: topic2}
+
+	for _, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), nil)
+
+		if topic, ok := topics[block.NumberU64()]; ok {
+			rawdb.WriteBorReceipt(db, block.Hash(), block.NumberU64(), &types.ReceiptForStorage{
+				Status: types.ReceiptStatusSuccessful,
+				Logs:   []*types.Log{{Address: stateReceiver, Topics: []common.Hash{topic}}},
+			})
+		}
+	}
+
+	// Filter by state receiver address over the whole chain
+	logs, err := NewBorBlockLogsRangeFilter(backend, borConfig, 0, -1, []common.Address{stateReceiver}, nil).Logs(t.Context())
+	if err != nil {
+		t.Fatalf("failed to filter logs: %v", err)
+	}
+
+	if len(logs) != 2 {
+		t.Fatalf("log count mismatch: have %d, want 2", len(logs))
+	}
+
+	for i, number := range []uint64{4, 8} {
+		block := chain[number-1]
+		if logs[i].BlockNumber != number || logs[i].BlockHash != block.Hash() {
+			t.Errorf("log %d block mismatch: have %d (%x), want %d (%x)", i, logs[i].BlockNumber, logs[i].BlockHash, number, block.Hash())
+		}
+
+		if want := types.GetDerivedBorTxHash(types.BorReceiptKey(number, block.Hash())); logs[i].TxHash != want {
+			t.Errorf("log %d tx hash mismatch: have %x, want %x", i, logs[i].TxHash, want)
+		}
+	}
+
+	// Filter by topic
+	logs, _ = NewBorBlockLogsRangeFilter(backend, borConfig, 0, -1, nil, [][]common.Hash{{topic2}}).Logs(t.Context())
+	if len(logs) != 1 || logs[0].BlockNumber != 8 {
+		t.Fatalf("unexpected logs filtered by topic: %v", logs)
+	}
+
+	// Filter a single block by hash
+	logs, _ = NewBorBlockLogsFilter(backend, borConfig, chain[3].Hash(), []common.Address{stateReceiver}, [][]common.Hash{{topic1}}).Logs(t.Context())
+	if len(logs) != 1 || logs[0].BlockHash != chain[3].Hash() {
+		t.Fatalf("unexpected logs filtered by block hash: %v", logs)
+	}
+
+	logs, _ = NewBorBlockLogsFilter(backend, borConfig, chain[3].Hash(), []common.Address{otherAddr}, nil).Logs(t.Context())
+	if len(logs) != 0 {
+		t.Fatalf("unexpected logs of other address filtered by block hash: %v", logs)
+	}
+
+	// Blocks without state sync events have no logs
+	logs, _ = NewBorBlockLogsFilter(backend, borConfig, chain[4].Hash(), nil, nil).Logs(t.Context())
+	if len(logs) != 0 {
+		t.Fatalf("unexpected logs in block without state sync events: %v", logs)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -23,6 +24,20 @@ var (
 	// errMilestone is returned when we are unable to fetch the
 	// latest milestone from the local heimdall.
 	errMilestone = errors.New("failed to fetch latest milestone")
+
+	// errInvalidMilestoneList is returned when the milestones returned by heimdall
+	// while backfilling are malformed or out of order.
+	errInvalidMilestoneList = errors.New("invalid milestone list")
+)
+
+const (
+	// milestoneBackfillPageSize is the number of milestones requested from heimdall
+	// at once while backfilling.
+	milestoneBackfillPageSize = 16
+
+	// maxMilestoneBackfill is the maximum number of milestones backfilled on startup,
+	// older ones are covered by the checkpoints.
+	maxMilestoneBackfill = 256
 )
 
 // fetchWhitelistCheckpoint fetches the latest checkpoint from it's local heimdall
@@ -125,7 +140,89 @@ func (h *ethHandler) handleMilestone(ctx context.Context, eth *Ethereum, milesto
 		start += 1
 	}
 
-	h.downloader.ProcessMilestone(num, hash)
+	h.downloader.ProcessMilestone(milestone.MilestoneID, num, hash)
+
+	return nil
+}
+
+// backfillMilestones fetches the milestones emitted after the one ending at the given
+// block, e.g. while the node was offline, and passes them to the handler in ascending
+// order. Heimdall is walked back from the latest milestone, one page at a time, until
+// the last known milestone or the backfill limit is reached.
+func (h *ethHandler) backfillMilestones(ctx context.Context, bor *bor.Bor, lastEndBlock uint64, handle func(*milestone.Milestone) error) error {
+	count, err := bor.HeimdallClient.FetchMilestoneCount(ctx)
+	if err = reportCommonErrors("milestone count", err, errMilestone); err != nil {
+		return err
+	}
+
+	// Collect the missed milestones, latest first
+	var missed []*milestone.Milestone
+
+	for to, done := count, false; to > 0 && !done && len(missed) < maxMilestoneBackfill; {
+		from := max(to-milestoneBackfillPageSize+1, 1)
+
+		page, err := bor.HeimdallClient.FetchMilestoneList(ctx, from, to)
+		if err = reportCommonErrors("milestone list", err, errMilestone, "from", from, "to", to); err != nil {
+			return err
+		}
+
+		if err := validateMilestoneList(page, from, to); err != nil {
+			return err
+		}
+
+		if len(missed) > 0 && page[len(page)-1].EndBlock >= missed[len(missed)-1].StartBlock {
+			return fmt.Errorf("%w: milestone %d overlaps with milestone %d", errInvalidMilestoneList, to, to+1)
+		}
+
+		for i := len(page) - 1; i >= 0; i-- {
+			if page[i].EndBlock <= lastEndBlock || len(missed) == maxMilestoneBackfill {
+				done = true
+				break
+			}
+
+			missed = append(missed, page[i])
+		}
+
+		to = from - 1
+	}
+
+	if len(missed) == 0 {
+		return nil
+	}
+
+	log.Info("Backfilling milestones", "count", len(missed), "last", lastEndBlock, "latest", missed[0].EndBlock)
+
+	slices.Reverse(missed)
+
+	for _, m := range missed {
+		if err := handle(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateMilestoneList checks that the milestones returned by heimdall for the range
+// [from, to] are complete, well formed and strictly ordered.
+func validateMilestoneList(milestones []*milestone.Milestone, from, to int64) error {
+	if int64(len(milestones)) != to-from+1 {
+		return fmt.Errorf("%w: got %d milestones for range [%d, %d]", errInvalidMilestoneList, len(milestones), from, to)
+	}
+
+	for i, m := range milestones {
+		if m == nil {
+			return fmt.Errorf("%w: milestone %d missing", errInvalidMilestoneList, from+int64(i))
+		}
+
+		if m.EndBlock < m.StartBlock {
+			return fmt.Errorf("%w: milestone %d ends at %d before its start %d", errInvalidMilestoneList, from+int64(i), m.EndBlock, m.StartBlock)
+		}
+
+		if i > 0 && m.StartBlock <= milestones[i-1].EndBlock {
+			return fmt.Errorf("%w: milestone %d overlaps with milestone %d", errInvalidMilestoneList, from+int64(i), from+int64(i)-1)
+		}
+	}
 
 	return nil
 }
@@ -142,7 +239,9 @@ func reportCommonErrors(msg string, err error, wrapError error, ctx ...interface
 
 	ctx = append(ctx, "err", err)
 
-	if strings.Contains(err.Error(), "context deadline exceeded") {
+	if errors.Is(err, heimdall.ErrHeimdallSchemaMismatch) {
+		log.Error(fmt.Sprintf("Unexpected %s response from heimdall, bor and heimdall versions may be incompatible", msg), ctx...)
+	} else if strings.Contains(err.Error(), "context deadline exceeded") {
 		log.Warn(fmt.Sprintf("Failed to fetch %s, please check the heimdall endpoint and status of your heimdall node", msg), ctx...)
 	} else {
 		log.Warn(fmt.Sprintf("Failed to fetch %s", msg), ctx...)
@@ -2,6 +2,8 @@ package eth
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"testing"
 	"time"
 
@@ -20,6 +22,7 @@ type mockHeimdall struct {
 	fetchCheckpointCount func(ctx context.Context) (int64, error)
 	fetchMilestone       func(ctx context.Context) (*milestone.Milestone, error)
 	fetchMilestoneCount  func(ctx context.Context) (int64, error)
+	fetchMilestoneList   func(ctx context.Context, fromNumber, toNumber int64) ([]*milestone.Milestone, error)
 }
 
 func (m *mockHeimdall) StateSyncEvents(ctx context.Context, fromID uint64, to int64) ([]*clerk.EventRecordWithTime, error) {
@@ -50,6 +53,10 @@ func (m *mockHeimdall) FetchMilestoneCount(ctx context.Context) (int64, error) {
 	return m.fetchMilestoneCount(ctx)
 }
 
+func (m *mockHeimdall) FetchMilestoneList(ctx context.Context, fromNumber, toNumber int64) ([]*milestone.Milestone, error) {
+	return m.fetchMilestoneList(ctx, fromNumber, toNumber)
+}
+
 func (m *mockHeimdall) Close() {}
 
 func TestFetchWhitelistCheckpointAndMilestone(t *testing.T) {
@@ -140,6 +147,138 @@ func fetchMilestoneTest(t *testing.T, heimdall *mockHeimdall, bor *bor.Bor, hand
 	require.Equal(t, milestones[len(milestones)-1].Hash, hash)
 }
 
+// serveMockMilestones makes the mock heimdall serve the given milestones, numbered
+// from 1, and returns the requested ranges.
+func serveMockMilestones(heimdall *mockHeimdall, milestones []*milestone.Milestone) *[][2]int64 {
+	var requested [][2]int64
+
+	heimdall.fetchMilestoneCount = func(_ context.Context) (int64, error) {
+		return int64(len(milestones)), nil
+	}
+	heimdall.fetchMilestoneList = func(_ context.Context, fromNumber, toNumber int64) ([]*milestone.Milestone, error) {
+		requested = append(requested, [2]int64{fromNumber, toNumber})
+		return slices.Clone(milestones[fromNumber-1 : toNumber]), nil
+	}
+
+	return &requested
+}
+
+func TestBackfillMilestones(t *testing.T) {
+	t.Parallel()
+
+	var (
+		heimdall   mockHeimdall
+		handler    = &ethHandler{}
+		bor        = &bor.Bor{HeimdallClient: &heimdall}
+		milestones = createMockMilestones(40)
+		handled    []*milestone.Milestone
+	)
+
+	handle := func(m *milestone.Milestone) error {
+		handled = append(handled, m)
+		return nil
+	}
+
+	requested := serveMockMilestones(&heimdall, milestones)
+
+	// Milestones after the last known one are handled in order, fetching pages from the latest
+	err := handler.backfillMilestones(t.Context(), bor, milestones[9].EndBlock, handle)
+	require.NoError(t, err)
+	require.Equal(t, milestones[10:], handled)
+	require.Equal(t, [][2]int64{{25, 40}, {9, 24}}, *requested)
+
+	// Nothing to do if the node is up to date
+	handled = nil
+
+	err = handler.backfillMilestones(t.Context(), bor, milestones[39].EndBlock, handle)
+	require.NoError(t, err)
+	require.Empty(t, handled)
+
+	// Only the latest milestones are backfilled after a long downtime
+	milestones = createMockMilestones(maxMilestoneBackfill + 20)
+	serveMockMilestones(&heimdall, milestones)
+
+	handled = nil
+
+	err = handler.backfillMilestones(t.Context(), bor, 0, handle)
+	require.NoError(t, err)
+	require.Equal(t, milestones[20:], handled)
+
+	// Handler failures abort the backfill
+	errHandle := errors.New("handle failed")
+
+	err = handler.backfillMilestones(t.Context(), bor, 0, func(*milestone.Milestone) error { return errHandle })
+	require.ErrorIs(t, err, errHandle)
+}
+
+func TestBackfillMilestonesInvalidList(t *testing.T) {
+	t.Parallel()
+
+	var (
+		heimdall mockHeimdall
+		handler  = &ethHandler{}
+		bor      = &bor.Bor{HeimdallClient: &heimdall}
+	)
+
+	tests := map[string]func(milestones []*milestone.Milestone) []*milestone.Milestone{
+		"missing": func(milestones []*milestone.Milestone) []*milestone.Milestone {
+			return milestones[1:]
+		},
+		"nil": func(milestones []*milestone.Milestone) []*milestone.Milestone {
+			milestones[3] = nil
+			return milestones
+		},
+		"reversed": func(milestones []*milestone.Milestone) []*milestone.Milestone {
+			slices.Reverse(milestones)
+			return milestones
+		},
+		"swapped": func(milestones []*milestone.Milestone) []*milestone.Milestone {
+			milestones[2], milestones[3] = milestones[3], milestones[2]
+			return milestones
+		},
+		"corrupted": func(milestones []*milestone.Milestone) []*milestone.Milestone {
+			milestones[5].EndBlock = milestones[5].StartBlock - 1
+			return milestones
+		},
+		"overlapping": func(milestones []*milestone.Milestone) []*milestone.Milestone {
+			milestones[5].StartBlock = milestones[4].EndBlock
+			return milestones
+		},
+		"overlapping pages": func(milestones []*milestone.Milestone) []*milestone.Milestone {
+			milestones[milestoneBackfillPageSize-1].EndBlock = milestones[milestoneBackfillPageSize].StartBlock
+			return milestones
+		},
+	}
+
+	for name, corrupt := range tests {
+		milestones := corrupt(createMockMilestones(2 * milestoneBackfillPageSize))
+
+		heimdall.fetchMilestoneCount = func(_ context.Context) (int64, error) {
+			return 2 * milestoneBackfillPageSize, nil
+		}
+		heimdall.fetchMilestoneList = func(_ context.Context, fromNumber, toNumber int64) ([]*milestone.Milestone, error) {
+			return slices.Clone(milestones[max(fromNumber-1, 0):min(toNumber, int64(len(milestones)))]), nil
+		}
+
+		var handled int
+
+		err := handler.backfillMilestones(t.Context(), bor, 0, func(*milestone.Milestone) error {
+			handled++
+			return nil
+		})
+		require.ErrorIs(t, err, errInvalidMilestoneList, name)
+		require.Zero(t, handled, name)
+	}
+
+	// Heimdall failures are reported
+	heimdall.fetchMilestoneList = func(_ context.Context, _, _ int64) ([]*milestone.Milestone, error) {
+		return nil, errors.New("heimdall down")
+	}
+
+	err := handler.backfillMilestones(t.Context(), bor, 0, func(*milestone.Milestone) error { return nil })
+	require.ErrorIs(t, err, errMilestone)
+}
+
 func createMockCheckpoints(count int) []*checkpoint.Checkpoint {
 	var (
 		checkpoints []*checkpoint.Checkpoint = make([]*checkpoint.Checkpoint, count)
@@ -2,16 +2,25 @@ package tracers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor"
+	"github.com/ethereum/go-ethereum/consensus/bor/clerk"
+	"github.com/ethereum/go-ethereum/consensus/bor/contract"
 	"github.com/ethereum/go-ethereum/consensus/bor/statefull"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -19,6 +28,9 @@ type BlockTraceResult struct {
 	// Trace of each transaction executed
 	Transactions []*TxTraceResult `json:"transactions,omitempty"`
 
+	// Trace of each bor system call executed after the transactions
+	SystemCalls []*SystemCallTraceResult `json:"systemCalls,omitempty"`
+
 	// Block that we are executing on the trace
 	Block interface{} `json:"block"`
 }
@@ -34,6 +46,51 @@ type TxTraceResult struct {
 	IntermediateHash common.Hash `json:"intermediatehash"`
 }
 
+// Types of the bor system calls
+const (
+	systemCallCommitSpan  = "commitSpan"
+	systemCallCommitState = "commitState"
+	systemCallUnknown     = "unknown"
+)
+
+type SystemCallTraceResult struct {
+	// Type of the system call, commitSpan or commitState
+	Type string `json:"type"`
+
+	// Id of the span committed, for commitSpan calls
+	SpanID *uint64 `json:"spanId,omitempty"`
+
+	// Id of the state sync event committed, for commitState calls
+	StateSyncID *uint64 `json:"stateSyncId,omitempty"`
+
+	// Trace results produced by the tracer
+	Result interface{} `json:"result,omitempty"`
+
+	// Trace failure produced by the tracer
+	Error string `json:"error,omitempty"`
+}
+
+// newBorTracer creates the tracer requested by the config, defaulting to the struct
+// logger.
+func (api *API) newBorTracer(txctx *Context, config *TraceConfig) (*Tracer, error) {
+	if config == nil || config.Tracer == nil {
+		var logConfig *logger.Config
+		if config != nil {
+			logConfig = config.Config
+		}
+
+		structLogger := logger.NewStructLogger(logConfig)
+
+		return &Tracer{
+			Hooks:     structLogger.Hooks(),
+			GetResult: structLogger.GetResult,
+			Stop:      structLogger.Stop,
+		}, nil
+	}
+
+	return DefaultDirectory.New(*config.Tracer, txctx, config.TracerConfig, api.backend.ChainConfig())
+}
+
 func (api *API) traceBorBlock(ctx context.Context, block *types.Block, config *TraceConfig) (*BlockTraceResult, error) {
 	if block.NumberU64() == 0 {
 		return nil, fmt.Errorf("genesis is not traceable")
@@ -66,95 +123,244 @@ func (api *API) traceBorBlock(ctx context.Context, block *types.Block, config *T
 
 	defer release()
 
-	// Execute all the transaction contained within the block concurrently
+	// Execute all the transaction contained within the block sequentially
 	var (
-		signer                               = types.MakeSigner(api.backend.ChainConfig(), block.Number(), block.Time())
-		txs, stateSyncPresent, stateSyncHash = api.getAllBlockTransactions(ctx, block)
-		deleteEmptyObjects                   = api.backend.ChainConfig().IsEIP158(block.Number())
+		signer             = types.MakeSigner(api.backend.ChainConfig(), block.Number(), block.Time())
+		deleteEmptyObjects = api.backend.ChainConfig().IsEIP158(block.Number())
 	)
 
 	blockCtx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
 
-	traceTxn := func(indx int, tx *types.Transaction, borTx bool, stateSyncHash common.Hash) *TxTraceResult {
-		message, _ := core.TransactionToMessage(tx, signer, block.BaseFee())
-		txHash := tx.Hash()
-		if borTx {
-			txHash = stateSyncHash
+	traceTxn := func(indx int, tx *types.Transaction) *TxTraceResult {
+		message, err := core.TransactionToMessage(tx, signer, block.BaseFee())
+		if err != nil {
+			return &TxTraceResult{
+				Error: err.Error(),
+			}
 		}
 
-		tracer := logger.NewStructLogger(config.Config)
+		tracer, err := api.newBorTracer(&Context{
+			BlockHash:   block.Hash(),
+			BlockNumber: block.Number(),
+			TxIndex:     indx,
+			TxHash:      tx.Hash(),
+		}, config)
+		if err != nil {
+			return &TxTraceResult{
+				Error: err.Error(),
+			}
+		}
 
 		// Run the transaction with tracing enabled.
-		vmenv := vm.NewEVM(blockCtx, statedb, api.backend.ChainConfig(), vm.Config{Tracer: tracer.Hooks(), NoBaseFee: true})
+		vmenv := vm.NewEVM(blockCtx, state.NewHookedState(statedb, tracer.Hooks), api.backend.ChainConfig(), vm.Config{Tracer: tracer.Hooks, NoBaseFee: true})
 
 		// Call Prepare to clear out the statedb access list
-		// Not sure if we need to do this
-		statedb.SetTxContext(txHash, indx)
-
-		var execRes *core.ExecutionResult
+		statedb.SetTxContext(tx.Hash(), indx)
 
-		if borTx {
-			callmsg := prepareCallMessage(*message)
-			execRes, err = statefull.ApplyBorMessage(vmenv, callmsg)
-		} else {
-			execRes, err = core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.GasLimit), nil)
+		if _, err = core.ApplyTransactionWithEVM(message, new(core.GasPool).AddGas(message.GasLimit), statedb, block.Number(), block.Hash(), tx, new(uint64), vmenv, nil); err != nil {
+			return &TxTraceResult{
+				Error: err.Error(),
+			}
 		}
 
+		result, err := tracer.GetResult()
 		if err != nil {
 			return &TxTraceResult{
 				Error: err.Error(),
 			}
 		}
 
-		result := &logger.ExecutionResult{
-			Gas:         execRes.UsedGas,
-			Failed:      execRes.Failed(),
-			ReturnValue: execRes.Return(),
-			StructLogs:  tracer.StructLogs(),
-		}
-		res := &TxTraceResult{
+		return &TxTraceResult{
 			Result:           result,
 			IntermediateHash: statedb.IntermediateRoot(deleteEmptyObjects),
 		}
-
-		return res
 	}
 
-	for indx, tx := range txs {
-		if stateSyncPresent && indx == len(txs)-1 {
-			res.Transactions = append(res.Transactions, traceTxn(indx, tx, true, stateSyncHash))
-		} else {
-			res.Transactions = append(res.Transactions, traceTxn(indx, tx, false, stateSyncHash))
+	for indx, tx := range block.Transactions() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
+
+		res.Transactions = append(res.Transactions, traceTxn(indx, tx))
 	}
 
+	// Replay the system calls of the consensus engine, tracing each of them separately.
+	// The state sync events are fetched from heimdall again, exactly like when the block
+	// was imported.
+	borEngine, ok := api.backend.Engine().(*bor.Bor)
+	if !ok || !api.backend.ChainConfig().Bor.IsSprintStart(block.NumberU64()) {
+		return res, nil
+	}
+
+	tracingState := &systemCallTracer{
+		StateDB: statedb,
+		config:  config,
+		api:     api,
+		block:   block,
+	}
+
+	statedb.SetTxContext(types.GetDerivedBorTxHash(types.BorReceiptKey(block.NumberU64(), block.Hash())), len(block.Transactions()))
+
+	if _, err := borEngine.ApplySystemCalls(newChainHeaderReader(ctx, api.backend), block.Header(), tracingState); err != nil {
+		return nil, fmt.Errorf("failed to replay the system calls: %w", err)
+	}
+
+	res.SystemCalls = tracingState.results
+
 	return res, nil
 }
 
-type TraceBlockRequest struct {
-	Number     int64
-	Hash       string
-	IsBadBlock bool
-	Config     *TraceConfig
+// systemCallTracer is a state database creating a new tracer for each bor system call
+// applied on it, and collecting their results.
+type systemCallTracer struct {
+	vm.StateDB
+
+	config  *TraceConfig
+	api     *API
+	block   *types.Block
+	results []*SystemCallTraceResult
 }
 
-// If you use context as first parameter this function gets exposed automatically on rpc endpoint
-func (api *API) TraceBorBlock(req *TraceBlockRequest) (*BlockTraceResult, error) {
-	ctx := context.Background()
+// SystemCallHooks implements statefull.SystemCallTracer.
+func (t *systemCallTracer) SystemCallHooks(msg statefull.Callmsg) *tracing.Hooks {
+	result := describeSystemCall(t.api.backend.ChainConfig(), msg)
+	t.results = append(t.results, result)
 
-	var blockNumber rpc.BlockNumber
-	if req.Number == -1 {
-		blockNumber = rpc.LatestBlockNumber
-	} else {
-		blockNumber = rpc.BlockNumber(req.Number)
+	tracer, err := t.api.newBorTracer(&Context{
+		BlockHash:   t.block.Hash(),
+		BlockNumber: t.block.Number(),
+		TxIndex:     len(t.block.Transactions()),
+	}, t.config)
+	if err != nil {
+		result.Error = err.Error()
+		return nil
 	}
 
-	log.Debug("Tracing Bor Block", "block number", blockNumber)
+	// Collect the result of the tracer once the call is done
+	hooks := *tracer.Hooks
+	onTxEnd := hooks.OnTxEnd
+	hooks.OnTxEnd = func(receipt *types.Receipt, err error) {
+		if onTxEnd != nil {
+			onTxEnd(receipt, err)
+		}
+
+		res, err := tracer.GetResult()
+		if err != nil {
+			result.Error = err.Error()
+			return
+		}
+
+		result.Result = res
+	}
+
+	return &hooks
+}
+
+// describeSystemCall returns the trace result of a system call, labeled with its type
+// and the id of the span or state sync event it commits.
+func describeSystemCall(config *params.ChainConfig, msg statefull.Callmsg) *SystemCallTraceResult {
+	data := msg.Data()
+	if config.Bor == nil || msg.To() == nil || len(data) < 4 {
+		return &SystemCallTraceResult{Type: systemCallUnknown}
+	}
+
+	switch *msg.To() {
+	case common.HexToAddress(config.Bor.ValidatorContract):
+		result := &SystemCallTraceResult{Type: systemCallCommitSpan}
+
+		args, err := contract.ValidatorSet().Methods["commitSpan"].Inputs.Unpack(data[4:])
+		if err == nil && len(args) > 0 {
+			if id, ok := args[0].(*big.Int); ok && id.IsUint64() {
+				spanID := id.Uint64()
+				result.SpanID = &spanID
+			}
+		}
+
+		return result
+
+	case common.HexToAddress(config.Bor.StateReceiverContract):
+		result := &SystemCallTraceResult{Type: systemCallCommitState}
+
+		args, err := contract.StateReceiver().Methods["commitState"].Inputs.Unpack(data[4:])
+		if err == nil && len(args) > 1 {
+			var record clerk.EventRecord
+			if recordBytes, ok := args[1].([]byte); ok && rlp.DecodeBytes(recordBytes, &record) == nil {
+				result.StateSyncID = &record.ID
+			}
+		}
+
+		return result
+	}
+
+	return &SystemCallTraceResult{Type: systemCallUnknown}
+}
+
+// chainHeaderReader exposes the headers of the backend to the consensus engine
+type chainHeaderReader struct {
+	ctx     context.Context
+	backend Backend
+}
+
+func newChainHeaderReader(ctx context.Context, backend Backend) *chainHeaderReader {
+	return &chainHeaderReader{ctx: ctx, backend: backend}
+}
+
+func (r *chainHeaderReader) Config() *params.ChainConfig {
+	return r.backend.ChainConfig()
+}
+
+func (r *chainHeaderReader) CurrentHeader() *types.Header {
+	header, _ := r.backend.HeaderByNumber(r.ctx, rpc.LatestBlockNumber)
+	return header
+}
+
+func (r *chainHeaderReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header := r.GetHeaderByHash(hash)
+	if header == nil || header.Number.Uint64() != number {
+		return nil
+	}
+
+	return header
+}
+
+func (r *chainHeaderReader) GetHeaderByNumber(number uint64) *types.Header {
+	header, _ := r.backend.HeaderByNumber(r.ctx, rpc.BlockNumber(number))
+	return header
+}
+
+func (r *chainHeaderReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	header, _ := r.backend.HeaderByHash(r.ctx, hash)
+	return header
+}
+
+func (r *chainHeaderReader) GetTd(common.Hash, uint64) *big.Int {
+	return nil
+}
+
+// TraceBorBlock replays the given block and traces its transactions followed by the
+// bor system calls (span and state sync commits) with the configured tracer, which
+// defaults to the struct logger. Each system call is traced separately and labeled
+// with the span or state sync event it commits. Replaying the state sync commits
+// requires heimdall to be reachable.
+func (api *API) TraceBorBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *TraceConfig) (*BlockTraceResult, error) {
+	var (
+		block *types.Block
+		err   error
+	)
+
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		block, err = api.blockByHash(ctx, hash)
+	} else if number, ok := blockNrOrHash.Number(); ok {
+		block, err = api.blockByNumber(ctx, number)
+	} else {
+		return nil, errors.New("invalid arguments; neither block number nor hash specified")
+	}
 
-	block, err := api.blockByNumber(ctx, blockNumber)
 	if err != nil {
 		return nil, err
 	}
 
-	return api.traceBorBlock(ctx, block, req.Config)
+	log.Debug("Tracing Bor Block", "block number", block.NumberU64())
+
+	return api.traceBorBlock(ctx, block, config)
 }
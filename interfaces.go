@@ -282,7 +282,7 @@ type ChainValidator interface {
 	GetWhitelistedCheckpoint() (bool, uint64, common.Hash)
 	GetWhitelistedMilestone() (bool, uint64, common.Hash)
 	ProcessCheckpoint(endBlockNum uint64, endBlockHash common.Hash)
-	ProcessMilestone(endBlockNum uint64, endBlockHash common.Hash)
+	ProcessMilestone(milestoneId string, endBlockNum uint64, endBlockHash common.Hash)
 	ProcessFutureMilestone(num uint64, hash common.Hash)
 	PurgeWhitelistedCheckpoint()
 	PurgeWhitelistedMilestone()
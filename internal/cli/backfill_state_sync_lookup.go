@@ -0,0 +1,261 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor/clerk"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/cli/flagset"
+	"github.com/ethereum/go-ethereum/internal/cli/server"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// backfillHeimdallMaxRetries is the number of times a failed heimdall request is retried
+// before the backfill is aborted.
+const backfillHeimdallMaxRetries = 10
+
+// stateSyncEventFetcher fetches state sync events from heimdall.
+type stateSyncEventFetcher interface {
+	StateSyncEvents(ctx context.Context, fromID uint64, to int64) ([]*clerk.EventRecordWithTime, error)
+}
+
+// BackfillStateSyncLookupCommand is the command to backfill the lookup of state sync events by L1 tx hash
+type BackfillStateSyncLookupCommand struct {
+	*Meta
+
+	datadirAncient string
+	heimdallURL    string
+	from           uint64
+	to             uint64
+}
+
+// MarkDown implements cli.MarkDown interface
+func (c *BackfillStateSyncLookupCommand) MarkDown() string {
+	items := []string{
+		"# Backfill state sync lookup",
+		"The ```bor backfill-state-sync-lookup``` command indexes the state sync events committed in existing blocks by the hash of the L1 transaction they originate from. " +
+			"The ids of the events are read from the bor receipts and their L1 transaction hashes are fetched from heimdall. The node must be stopped while running it.",
+		c.Flags().MarkDown(),
+	}
+
+	return strings.Join(items, "\n\n")
+}
+
+// Help implements the cli.Command interface
+func (c *BackfillStateSyncLookupCommand) Help() string {
+	return `Usage: bor backfill-state-sync-lookup --datadir <datadir> [--from <number>] [--to <number>]
+
+  This command indexes the state sync events committed in existing blocks by L1 tx hash` + c.Flags().Help()
+}
+
+// Synopsis implements the cli.Command interface
+func (c *BackfillStateSyncLookupCommand) Synopsis() string {
+	return "Index the committed state sync events by L1 tx hash"
+}
+
+func (c *BackfillStateSyncLookupCommand) Flags() *flagset.Flagset {
+	flags := c.NewFlagSet("backfill-state-sync-lookup")
+
+	flags.StringFlag(&flagset.StringFlag{
+		Name:    "datadir.ancient",
+		Value:   &c.datadirAncient,
+		Usage:   "Path of the ancient data directory",
+		Default: "",
+	})
+	flags.StringFlag(&flagset.StringFlag{
+		Name:    "bor.heimdall",
+		Value:   &c.heimdallURL,
+		Usage:   "URL of Heimdall service",
+		Default: "http://localhost:1317",
+	})
+	flags.Uint64Flag(&flagset.Uint64Flag{
+		Name:    "from",
+		Value:   &c.from,
+		Usage:   "Block number to start indexing from",
+		Default: 0,
+	})
+	flags.Uint64Flag(&flagset.Uint64Flag{
+		Name:    "to",
+		Value:   &c.to,
+		Usage:   "Block number to stop indexing at (0 for the current head)",
+		Default: 0,
+	})
+
+	return flags
+}
+
+// Run implements the cli.Command interface
+func (c *BackfillStateSyncLookupCommand) Run(args []string) int {
+	flags := c.Flags()
+
+	if err := flags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	datadir := c.dataDir
+	if datadir == "" {
+		c.UI.Error("datadir is required")
+		return 1
+	}
+
+	// Create the node
+	node, err := node.New(&node.Config{
+		DataDir: datadir,
+	})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	defer node.Close()
+
+	dbHandles, err := server.MakeDatabaseHandles(0)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	chaindb, err := node.OpenDatabaseWithFreezer(chaindataPath, 1024, dbHandles, c.datadirAncient, "", false, false, false)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	defer chaindb.Close()
+
+	config := rawdb.ReadChainConfig(chaindb, rawdb.ReadCanonicalHash(chaindb, 0))
+	if config == nil || config.Bor == nil {
+		c.UI.Error("Bor chain config not found in the database")
+		return 1
+	}
+
+	to := c.to
+	if to == 0 {
+		number := rawdb.ReadHeaderNumber(chaindb, rawdb.ReadHeadBlockHash(chaindb))
+		if number == nil {
+			c.UI.Error("Head block not found in the database")
+			return 1
+		}
+
+		to = *number
+	}
+
+	client := heimdall.NewHeimdallClient(c.heimdallURL, heimdall.DefaultTimeout, heimdall.DefaultRetryInterval, backfillHeimdallMaxRetries)
+	defer client.Close()
+
+	indexed, err := backfillStateSyncL1Lookups(context.Background(), chaindb, config.Bor, client, c.from, to)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Output(fmt.Sprintf("Indexed %d state sync events between blocks %d and %d", indexed, c.from, to))
+
+	return 0
+}
+
+// backfillStateSyncL1Lookups indexes the state sync events committed in the canonical blocks
+// of the given range by L1 tx hash and returns the number of indexed events.
+func backfillStateSyncL1Lookups(ctx context.Context, db ethdb.Database, config *params.BorConfig, fetcher stateSyncEventFetcher, from uint64, to uint64) (int, error) {
+	var (
+		receiver = common.HexToAddress(config.StateReceiverContract)
+		batch    = db.NewBatch()
+		indexed  int
+		start    = time.Now()
+		logged   = time.Now()
+	)
+
+	for number := from; number <= to; number++ {
+		if !config.IsSprintStart(number) {
+			continue
+		}
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Indexing state sync events", "block", number, "target", to, "indexed", indexed, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+
+		hash := rawdb.ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return indexed, fmt.Errorf("canonical block %d not found", number)
+		}
+
+		receipt := rawdb.ReadRawBorReceipt(db, hash, number)
+		if receipt == nil {
+			continue
+		}
+
+		ids := committedStateSyncIDs(receipt.Logs, receiver)
+		if len(ids) == 0 {
+			continue
+		}
+
+		header := rawdb.ReadHeader(db, hash, number)
+		if header == nil {
+			return indexed, fmt.Errorf("header of block %d not found", number)
+		}
+
+		// All events committed in a block were recorded in heimdall before the block time
+		events, err := fetcher.StateSyncEvents(ctx, ids[0], int64(header.Time))
+		if err != nil {
+			return indexed, fmt.Errorf("failed to fetch state sync events of block %d: %w", number, err)
+		}
+
+		txHashes := make(map[uint64]common.Hash, len(events))
+		for _, event := range events {
+			txHashes[event.ID] = event.TxHash
+		}
+
+		stateSyncs := make([]*types.StateSyncData, 0, len(ids))
+
+		for _, id := range ids {
+			txHash, ok := txHashes[id]
+			if !ok {
+				log.Warn("State sync event not found in heimdall", "id", id, "block", number)
+				continue
+			}
+
+			stateSyncs = append(stateSyncs, &types.StateSyncData{ID: id, TxHash: txHash})
+		}
+
+		rawdb.WriteStateSyncL1Origins(batch, hash, number, stateSyncs)
+		indexed += len(stateSyncs)
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return indexed, err
+			}
+
+			batch.Reset()
+		}
+	}
+
+	if err := batch.Write(); err != nil {
+		return indexed, err
+	}
+
+	log.Info("Indexed state sync events", "from", from, "to", to, "indexed", indexed, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	return indexed, nil
+}
+
+// committedStateSyncIDs returns the ids of the state sync events committed by the state
+// receiver contract according to the given bor receipt logs.
+func committedStateSyncIDs(logs []*types.Log, receiver common.Address) []uint64 {
+	var ids []uint64
+
+	for _, event := range core.CommittedStateSyncEvents(logs, receiver) {
+		ids = append(ids, event.EventID)
+	}
+
+	return ids
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor/clerk"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mockStateSyncEventFetcher serves state sync events from a slice, filtering them the same
+// way heimdall does.
+type mockStateSyncEventFetcher struct {
+	events []*clerk.EventRecordWithTime
+}
+
+func (f *mockStateSyncEventFetcher) StateSyncEvents(_ context.Context, fromID uint64, to int64) ([]*clerk.EventRecordWithTime, error) {
+	var events []*clerk.EventRecordWithTime
+
+	for _, event := range f.events {
+		if event.ID >= fromID && event.Time.Unix() < to {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func stateCommittedLog(receiver common.Address, id uint64) *types.Log {
+	return &types.Log{
+		Address: receiver,
+		Topics:  []common.Hash{core.StateCommittedTopic, common.BigToHash(new(big.Int).SetUint64(id))},
+		Data:    common.LeftPadBytes([]byte{1}, 32),
+	}
+}
+
+func TestBackfillStateSyncL1Lookups(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db       = rawdb.NewMemoryDatabase()
+		receiver = common.HexToAddress("0x0000000000000000000000000000000000001001")
+		borCfg   = &params.BorConfig{
+			Sprint:                map[string]uint64{"0": 4},
+			StateReceiverContract: receiver.Hex(),
+		}
+		l1Hashes = []common.Hash{common.HexToHash("0xa1"), common.HexToHash("0xa2")}
+		fetcher  = &mockStateSyncEventFetcher{}
+		hashes   = make(map[uint64]common.Hash)
+	)
+
+	// Build a chain of 12 blocks where events 1 and 2 are committed in block 4 and event 3
+	// in block 8. Events 1 and 3 originate from the same L1 transaction.
+	committed := map[uint64][]uint64{4: {1, 2}, 8: {3}}
+	origins := map[uint64]common.Hash{1: l1Hashes[0], 2: l1Hashes[1], 3: l1Hashes[0]}
+
+	for number := uint64(0); number <= 12; number++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(number), Time: 1000 + number*2}
+		hashes[number] = header.Hash()
+
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), number)
+
+		ids := committed[number]
+		if len(ids) == 0 {
+			continue
+		}
+
+		var logs []*types.Log
+		for _, id := range ids {
+			logs = append(logs, stateCommittedLog(receiver, id))
+			fetcher.events = append(fetcher.events, &clerk.EventRecordWithTime{
+				EventRecord: clerk.EventRecord{ID: id, TxHash: origins[id]},
+				Time:        time.Unix(int64(header.Time)-1, 0),
+			})
+		}
+
+		rawdb.WriteBorReceipt(db, header.Hash(), number, &types.ReceiptForStorage{
+			Status: types.ReceiptStatusSuccessful,
+			Logs:   logs,
+		})
+	}
+
+	indexed, err := backfillStateSyncL1Lookups(context.Background(), db, borCfg, fetcher, 0, 12)
+	require.NoError(t, err)
+	require.Equal(t, 3, indexed)
+
+	require.Equal(t, []rawdb.StateSyncL1Lookup{
+		{BlockHash: hashes[4], BlockNumber: 4, EventID: 1},
+		{BlockHash: hashes[8], BlockNumber: 8, EventID: 3},
+	}, rawdb.ReadStateSyncL1Lookups(db, l1Hashes[0]))
+
+	require.Equal(t, []rawdb.StateSyncL1Lookup{
+		{BlockHash: hashes[4], BlockNumber: 4, EventID: 2},
+	}, rawdb.ReadStateSyncL1Lookups(db, l1Hashes[1]))
+}
@@ -99,6 +99,11 @@ func Commands() map[string]MarkDownCommandFactory {
 				Meta2: meta2,
 			}, nil
 		},
+		"debug heimdall-sim": func() (MarkDownCommand, error) {
+			return &DebugHeimdallSimCommand{
+				UI: ui,
+			}, nil
+		},
 		"chain": func() (MarkDownCommand, error) {
 			return &ChainCommand{
 				UI: ui,
@@ -206,11 +211,41 @@ func Commands() map[string]MarkDownCommandFactory {
 				Meta: meta,
 			}, nil
 		},
+		"snapshot prune-bor-snapshots": func() (MarkDownCommand, error) {
+			return &PruneBorSnapshotsCommand{
+				Meta: meta,
+			}, nil
+		},
+		"snapshot verify-bor": func() (MarkDownCommand, error) {
+			return &VerifyBorCommand{
+				Meta: meta,
+			}, nil
+		},
 		"purge-whitelisted-entries": func() (MarkDownCommand, error) {
 			return &PurgeWhitelistedEntriesCommand{
 				Meta: meta,
 			}, nil
 		},
+		"backfill-state-sync-lookup": func() (MarkDownCommand, error) {
+			return &BackfillStateSyncLookupCommand{
+				Meta: meta,
+			}, nil
+		},
+		"witness": func() (MarkDownCommand, error) {
+			return &WitnessCommand{
+				UI: ui,
+			}, nil
+		},
+		"witness export": func() (MarkDownCommand, error) {
+			return &WitnessExportCommand{
+				UI: ui,
+			}, nil
+		},
+		"witness import": func() (MarkDownCommand, error) {
+			return &WitnessImportCommand{
+				UI: ui,
+			}, nil
+		},
 	}
 }
 
@@ -50,6 +50,7 @@ func (c *DebugCommand) MarkDown() string {
 		"The ```bor debug``` command takes a debug dump of the running client.",
 		"- [```bor debug pprof```](./debug_pprof.md): Dumps bor pprof traces.",
 		"- [```bor debug block <number>```](./debug_block.md): Dumps bor block traces.",
+		"- [```bor debug heimdall-sim```](./debug_heimdall-sim.md): Runs a heimdall simulator for local devnets.",
 	}
 	items = append(items, examples...)
 
@@ -68,7 +69,11 @@ func (c *DebugCommand) Help() string {
 
 	Get the block traces:
 
-		$ bor debug block <number>`
+		$ bor debug block <number>
+
+	Run a heimdall simulator for local devnets:
+
+		$ bor debug heimdall-sim --validators <addresses>`
 }
 
 // Synopsis implements the cli.Command interface
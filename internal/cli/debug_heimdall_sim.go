@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/cli"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdallsim"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/internal/cli/flagset"
+)
+
+// DebugHeimdallSimCommand is the command to run a heimdall simulator for local devnets
+type DebugHeimdallSimCommand struct {
+	UI cli.Ui
+
+	validators []string
+	spanLength uint64
+	listen     string
+	endpoint   string
+	chainID    string
+}
+
+// MarkDown implements cli.MarkDown interface
+func (c *DebugHeimdallSimCommand) MarkDown() string {
+	items := []string{
+		"# Debug heimdall-sim",
+		"The ```debug heimdall-sim``` command runs a minimal heimdall simulator for local devnets. " +
+			"It serves deterministic spans built from the given validators, no state sync events, and checkpoints " +
+			"and milestones derived from the chain of the bor node at ```--endpoint```. " +
+			"The validators must match the ones of the genesis validator set contract.",
+		CodeBlock([]string{
+			"$ bor debug heimdall-sim --validators 0x1234...,0x5678... --span-length 256 --listen :1317",
+		}),
+		c.Flags().MarkDown(),
+	}
+
+	return strings.Join(items, "\n\n")
+}
+
+// Help implements the cli.Command interface
+func (c *DebugHeimdallSimCommand) Help() string {
+	return `Usage: bor debug heimdall-sim --validators <addresses> [--span-length <blocks>] [--listen <address>]
+
+  Run a heimdall simulator for local devnets
+
+  ` + c.Flags().Help()
+}
+
+func (c *DebugHeimdallSimCommand) Flags() *flagset.Flagset {
+	flags := flagset.NewFlagSet("debug heimdall-sim")
+
+	flags.SliceStringFlag(&flagset.SliceStringFlag{
+		Name:  "validators",
+		Usage: "Comma separated signer addresses or hex encoded private keys of the validators",
+		Value: &c.validators,
+	})
+	flags.Uint64Flag(&flagset.Uint64Flag{
+		Name:    "span-length",
+		Usage:   "Number of blocks of the spans after the zeroth one",
+		Value:   &c.spanLength,
+		Default: heimdallsim.DefaultSpanLength,
+	})
+	flags.StringFlag(&flagset.StringFlag{
+		Name:    "listen",
+		Usage:   "Address to serve the heimdall REST API on",
+		Value:   &c.listen,
+		Default: ":1317",
+	})
+	flags.StringFlag(&flagset.StringFlag{
+		Name:  "endpoint",
+		Usage: "IPC path or RPC endpoint of the bor node, used for checkpoints and milestones (defaults to the bor IPC endpoint)",
+		Value: &c.endpoint,
+	})
+	flags.StringFlag(&flagset.StringFlag{
+		Name:  "chain-id",
+		Usage: "Chain id of the spans (defaults to the chain id of the bor node)",
+		Value: &c.chainID,
+	})
+
+	return flags
+}
+
+// Synopsis implements the cli.Command interface
+func (c *DebugHeimdallSimCommand) Synopsis() string {
+	return "Run a heimdall simulator for local devnets"
+}
+
+// Run implements the cli.Command interface
+func (c *DebugHeimdallSimCommand) Run(args []string) int {
+	flags := c.Flags()
+	if err := flags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	validators, err := parseSimValidators(c.validators)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	client, err := dialRPC(c.endpoint)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	defer client.Close()
+
+	if c.chainID == "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		chainID, err := ethclient.NewClient(client).ChainID(ctx)
+
+		cancel()
+
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Failed to fetch the chain id, set it with --chain-id: %v", err))
+			return 1
+		}
+
+		c.chainID = chainID.String()
+	}
+
+	sim, err := heimdallsim.New(validators, c.spanLength, c.chainID, heimdallsim.NewRPCBackend(client))
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	srv := &http.Server{
+		Addr:              c.listen,
+		Handler:           sim,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	trapSignal(cancel)
+
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	c.UI.Output(fmt.Sprintf("Heimdall simulator listening on %s (validators: %d, span length: %d, chain id: %s)", c.listen, len(validators), c.spanLength, c.chainID))
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+// parseSimValidators parses the validators of the heimdall simulator, given either as
+// signer addresses or as hex encoded private keys.
+func parseSimValidators(values []string) ([]common.Address, error) {
+	if len(values) == 0 {
+		return nil, errors.New("no validators given, set them with --validators")
+	}
+
+	validators := make([]common.Address, len(values))
+
+	for i, value := range values {
+		if common.IsHexAddress(value) {
+			validators[i] = common.HexToAddress(value)
+			continue
+		}
+
+		key, err := crypto.HexToECDSA(strings.TrimPrefix(value, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid validator %d: neither an address nor a private key", i+1)
+		}
+
+		validators[i] = crypto.PubkeyToAddress(key.PublicKey)
+	}
+
+	return validators, nil
+}
@@ -63,8 +63,8 @@ func checkConfigFlag(args []string) string {
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
 
-		// Check for single or double dashes
-		if strings.HasPrefix(arg, "-config") || strings.HasPrefix(arg, "--config") {
+		// Check for single or double dashes, other config.* flags aren't the config file
+		if arg == "-config" || arg == "--config" || strings.HasPrefix(arg, "-config=") || strings.HasPrefix(arg, "--config=") {
 			parts := strings.SplitN(arg, "=", 2)
 			if len(parts) == 2 {
 				return parts[1]
@@ -229,6 +229,14 @@ func (c *Command) Run(args []string) int {
 		return 1
 	}
 
+	issues, err := checkConfig(c.config)
+	c.UI.Output(configSummary(c.config, issues))
+
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	if c.config.Heimdall.RunHeimdall {
 		// TODO HV2: Find a way to pass the shutdown ctx to heimdall process
 		_, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
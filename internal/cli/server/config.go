@@ -24,6 +24,8 @@ import (
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
+	"github.com/ethereum/go-ethereum/consensus/bor/heimdall"
+	"github.com/ethereum/go-ethereum/core/blockstm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/downloader/whitelist"
@@ -80,6 +82,15 @@ type Config struct {
 	// Maximum size (in bytes) a result of an rpc request could have (default=100000, use 0 for no limits)
 	RPCReturnDataLimit uint64 `hcl:"rpc.returndatalimit,optional" toml:"rpc.returndatalimit,optional"`
 
+	// Maximum block range of the bor root hash rpc requests (default=32768)
+	RPCRootHashMaxRange uint64 `hcl:"rpc.roothashmaxrange,optional" toml:"rpc.roothashmaxrange,optional"`
+
+	// RPCBorBlockTx appends the bor transaction to the transactions of the block rpc responses (default=true)
+	RPCBorBlockTx bool `hcl:"rpc.borblocktx,optional" toml:"rpc.borblocktx,optional"`
+
+	// RPCTimeouts is a list of execution timeouts of the rpc methods, keyed by method name or namespace
+	RPCTimeouts map[string]string `hcl:"rpc.timeouts,optional" toml:"rpc.timeouts,optional"`
+
 	// SyncMode selects the sync protocol
 	SyncMode string `hcl:"syncmode,optional" toml:"syncmode,optional"`
 
@@ -104,6 +115,10 @@ type Config struct {
 	// MaxBlindForkValidationLimit denotes the maximum number of blocks to traverse back in the database when validating blind forks
 	MaxBlindForkValidationLimit uint64 `hcl:"max-blind-fork-validation-limit,optional" toml:"max-blind-fork-validation-limit,optional"`
 
+	// FinalityLagThreshold is the lag of the latest milestone/checkpoint after which a warning is logged
+	FinalityLagThreshold    time.Duration `hcl:"-,optional" toml:"-"`
+	FinalityLagThresholdRaw string        `hcl:"finality-lag-threshold,optional" toml:"finality-lag-threshold,optional"`
+
 	// Logging has the logging related settings
 	Logging *LoggingConfig `hcl:"log,block" toml:"log,block"`
 
@@ -148,6 +163,30 @@ type Config struct {
 	// Develop Fake Author mode to produce blocks without authorisation
 	DevFakeAuthor bool `hcl:"devfakeauthor,optional" toml:"devfakeauthor,optional"`
 
+	// ConfigStrict refuses to start on contradictory settings instead of logging them
+	ConfigStrict bool `hcl:"config.strict,optional" toml:"config.strict,optional"`
+
+	// VerifyFull verifies the seal of every header, including those attested by the whitelisted milestone
+	VerifyFull bool `hcl:"bor.verify.full,optional" toml:"bor.verify.full,optional"`
+
+	// SpanOverride is the path of a signed span override file to use while heimdall is down
+	SpanOverride string `hcl:"bor.spanoverride,optional" toml:"bor.spanoverride,optional"`
+
+	// SpanOverrideKey is the hex encoded public key the span override file must be signed with
+	SpanOverrideKey string `hcl:"bor.spanoverride.key,optional" toml:"bor.spanoverride.key,optional"`
+
+	// StrictSealing refuses to seal blocks if the signer isn't a producer of their span
+	StrictSealing bool `hcl:"bor.strictsealing,optional" toml:"bor.strictsealing,optional"`
+
+	// StateSyncIndex indexes the state sync events committed in the chain
+	StateSyncIndex bool `hcl:"bor.statesyncindex,optional" toml:"bor.statesyncindex,optional"`
+
+	// AutoUnwind unwinds the chain to the parent of a milestone conflicting with it
+	AutoUnwind bool `hcl:"bor.autounwind,optional" toml:"bor.autounwind,optional"`
+
+	// AutoUnwindDepth is the maximum number of blocks unwound automatically
+	AutoUnwindDepth uint64 `hcl:"bor.autounwind.depth,optional" toml:"bor.autounwind.depth,optional"`
+
 	// Pprof has the pprof related settings
 	Pprof *PprofConfig `hcl:"pprof,block" toml:"pprof,block"`
 
@@ -284,8 +323,15 @@ type HeimdallConfig struct {
 	// URL is the url of the heimdall server
 	URL string `hcl:"url,optional" toml:"url,optional"`
 
+	// Timeout is the timeout of a single request to heimdall
 	Timeout time.Duration `hcl:"timeout,optional" toml:"timeout,optional"`
 
+	// RetryInterval is the delay between two attempts of a failed request to heimdall
+	RetryInterval time.Duration `hcl:"retry-interval,optional" toml:"retry-interval,optional"`
+
+	// MaxRetries is the maximum number of retries of a failed request to heimdall (0 = unlimited)
+	MaxRetries uint64 `hcl:"max-retries,optional" toml:"max-retries,optional"`
+
 	// Without is used to disable remote heimdall during testing
 	Without bool `hcl:"bor.without,optional" toml:"bor.without,optional"`
 
@@ -303,6 +349,12 @@ type HeimdallConfig struct {
 
 	// UseHeimdallApp is used to fetch data from heimdall app when running heimdall as a child process
 	UseHeimdallApp bool `hcl:"bor.useheimdallapp,optional" toml:"bor.useheimdallapp,optional"`
+
+	// Readiness makes the node report unhealthy when heimdall is unreachable for longer than ReadinessTimeout
+	Readiness bool `hcl:"readiness,optional" toml:"readiness,optional"`
+
+	// ReadinessTimeout is the maximum duration heimdall can be unreachable before the node is reported unhealthy
+	ReadinessTimeout time.Duration `hcl:"readiness-timeout,optional" toml:"readiness-timeout,optional"`
 }
 
 type TxPoolConfig struct {
@@ -364,6 +416,12 @@ type SealerConfig struct {
 	RecommitRaw string        `hcl:"recommit,optional" toml:"recommit,optional"`
 
 	CommitInterruptFlag bool `hcl:"commitinterrupt,optional" toml:"commitinterrupt,optional"`
+
+	// EmptyBlocks is the policy for sealing blocks without transactions: always, skip or heartbeat:N
+	EmptyBlocks string `hcl:"emptyblocks,optional" toml:"emptyblocks,optional"`
+
+	// PrefetchBlocks is the number of blocks before a sprint start its span and state sync events are prefetched (0 = disabled)
+	PrefetchBlocks uint64 `hcl:"prefetchblocks,optional" toml:"prefetchblocks,optional"`
 }
 
 type JsonRPCConfig struct {
@@ -634,6 +692,17 @@ type ParallelEVMConfig struct {
 	SpeculativeProcesses int `hcl:"procs,optional" toml:"procs,optional"`
 
 	Enforce bool `hcl:"enforce,optional" toml:"enforce,optional"`
+
+	StatsSampleRate uint64 `hcl:"statssamplerate,optional" toml:"statssamplerate,optional"`
+
+	// HotContracts are the contracts whose transactions are chained upfront, as they are likely to conflict
+	HotContracts []string `hcl:"hotcontracts,optional" toml:"hotcontracts,optional"`
+
+	// LearnHotContracts enables learning the hot contracts from the conflicts of the executed blocks
+	LearnHotContracts bool `hcl:"learnhotcontracts,optional" toml:"learnhotcontracts,optional"`
+
+	// MVHashMapShards is the number of independently locked shards of the multi-version hash map
+	MVHashMapShards int `hcl:"mvhashmapshards,optional" toml:"mvhashmapshards,optional"`
 }
 
 func DefaultConfig() *Config {
@@ -650,6 +719,7 @@ func DefaultConfig() *Config {
 		KeyStoreDir:                 "",
 		DisableBlindForkValidation:  false,
 		MaxBlindForkValidationLimit: whitelist.DefaultMaxForkCorrectnessLimit,
+		FinalityLagThreshold:        whitelist.DefaultFinalityLagThreshold,
 		Logging: &LoggingConfig{
 			Vmodule:             "",
 			Json:                false,
@@ -657,8 +727,11 @@ func DefaultConfig() *Config {
 			Debug:               false,
 			EnableBlockTracking: false,
 		},
-		RPCBatchLimit:      100,
-		RPCReturnDataLimit: 100000,
+		RPCBatchLimit:       100,
+		RPCReturnDataLimit:  100000,
+		RPCRootHashMaxRange: 32768,
+		RPCBorBlockTx:       true,
+		RPCTimeouts:         map[string]string{},
 		P2P: &P2PConfig{
 			MaxPeers:           50,
 			MaxPendPeers:       50,
@@ -681,11 +754,15 @@ func DefaultConfig() *Config {
 			},
 		},
 		Heimdall: &HeimdallConfig{
-			URL:         "http://localhost:1317",
-			Timeout:     5 * time.Second,
-			Without:     false,
-			GRPCAddress: "",
-			WSAddress:   "",
+			URL:              "http://localhost:1317",
+			Timeout:          heimdall.DefaultTimeout,
+			RetryInterval:    heimdall.DefaultRetryInterval,
+			MaxRetries:       0,
+			Without:          false,
+			GRPCAddress:      "",
+			WSAddress:        "",
+			Readiness:        false,
+			ReadinessTimeout: time.Minute,
 		},
 		SyncMode:    "full",
 		GcMode:      "full",
@@ -713,6 +790,8 @@ func DefaultConfig() *Config {
 			ExtraData:           "",
 			Recommit:            125 * time.Second,
 			CommitInterruptFlag: true,
+			EmptyBlocks:         "always",
+			PrefetchBlocks:      2,
 		},
 		Gpo: &GpoConfig{
 			Blocks:           20,
@@ -824,7 +903,13 @@ func DefaultConfig() *Config {
 			Period:   0,
 			GasLimit: 11500000,
 		},
-		DevFakeAuthor: false,
+		DevFakeAuthor:   false,
+		VerifyFull:      false,
+		StrictSealing:   false,
+		StateSyncIndex:  false,
+		AutoUnwind:      false,
+		AutoUnwindDepth: 128,
+		ConfigStrict:    false,
 		Pprof: &PprofConfig{
 			Enabled:          false,
 			Port:             6060,
@@ -837,6 +922,10 @@ func DefaultConfig() *Config {
 			Enable:               true,
 			SpeculativeProcesses: 8,
 			Enforce:              false,
+			StatsSampleRate:      0,
+			HotContracts:         []string{},
+			LearnHotContracts:    false,
+			MVHashMapShards:      blockstm.DefaultMVHashMapShards,
 		},
 		History: &HistoryConfig{
 			TransactionHistory: ethconfig.Defaults.TransactionHistory,
@@ -899,6 +988,7 @@ func (c *Config) fillTimeDurations() error {
 		{"txpool.rejournal", &c.TxPool.Rejournal, &c.TxPool.RejournalRaw},
 		{"cache.timeout", &c.Cache.TrieTimeout, &c.Cache.TrieTimeoutRaw},
 		{"p2p.txarrivalwait", &c.P2P.TxArrivalWait, &c.P2P.TxArrivalWaitRaw},
+		{"finality-lag-threshold", &c.FinalityLagThreshold, &c.FinalityLagThresholdRaw},
 	}
 
 	for _, x := range tds {
@@ -975,18 +1065,45 @@ func (c *Config) buildEth(stack *node.Node, accountManager *accounts.Manager) (*
 		n.Genesis = c.chain.Genesis
 	}
 
+	if !c.Heimdall.Without {
+		if c.Heimdall.Timeout <= 0 {
+			return nil, fmt.Errorf("heimdall timeout must be positive, got %v", c.Heimdall.Timeout)
+		}
+
+		if c.Heimdall.RetryInterval <= 0 {
+			return nil, fmt.Errorf("heimdall retry interval must be positive, got %v", c.Heimdall.RetryInterval)
+		}
+
+		if c.Heimdall.Readiness && c.Heimdall.ReadinessTimeout <= 0 {
+			return nil, fmt.Errorf("heimdall readiness timeout must be positive, got %v", c.Heimdall.ReadinessTimeout)
+		}
+	}
+
 	n.HeimdallURL = c.Heimdall.URL
 	n.HeimdallTimeout = c.Heimdall.Timeout
+	n.HeimdallRetryInterval = c.Heimdall.RetryInterval
+	n.HeimdallMaxRetries = c.Heimdall.MaxRetries
 	n.WithoutHeimdall = c.Heimdall.Without
 	n.HeimdallgRPCAddress = c.Heimdall.GRPCAddress
 	n.HeimdallWSAddress = c.Heimdall.WSAddress
 	n.RunHeimdall = c.Heimdall.RunHeimdall
 	n.RunHeimdallArgs = c.Heimdall.RunHeimdallArgs
 	n.UseHeimdallApp = c.Heimdall.UseHeimdallApp
+	n.HeimdallReadiness = c.Heimdall.Readiness
+	n.HeimdallReadinessTimeout = c.Heimdall.ReadinessTimeout
 
 	// Developer Fake Author for producing blocks without authorisation on bor consensus
 	n.DevFakeAuthor = c.DevFakeAuthor
 
+	n.BorVerifyFull = c.VerifyFull
+
+	n.BorSpanOverride = c.SpanOverride
+	n.BorSpanOverrideKey = c.SpanOverrideKey
+	n.BorStrictSealing = c.StrictSealing
+	n.BorStateSyncIndex = c.StateSyncIndex
+	n.BorAutoUnwind = c.AutoUnwind
+	n.BorAutoUnwindDepth = c.AutoUnwindDepth
+
 	// Developer Fake Author for producing blocks without authorisation on bor consensus
 	n.DevFakeAuthor = c.DevFakeAuthor
 
@@ -1024,6 +1141,14 @@ func (c *Config) buildEth(stack *node.Node, accountManager *accounts.Manager) (*
 		n.Miner.ExtraData = []byte(c.Sealer.ExtraData)
 		n.Miner.CommitInterruptFlag = c.Sealer.CommitInterruptFlag
 
+		emptyBlocks, err := miner.ParseEmptyBlockPolicy(c.Sealer.EmptyBlocks)
+		if err != nil {
+			return nil, err
+		}
+
+		n.Miner.EmptyBlocks = emptyBlocks
+		n.Miner.PrefetchBlocks = c.Sealer.PrefetchBlocks
+
 		if etherbase := c.Sealer.Etherbase; etherbase != "" {
 			if !common.IsHexAddress(etherbase) {
 				return nil, fmt.Errorf("etherbase is not an address: %s", etherbase)
@@ -1258,7 +1383,21 @@ func (c *Config) buildEth(stack *node.Node, accountManager *accounts.Manager) (*
 	n.ParallelEVM.Enable = c.ParallelEVM.Enable
 	n.ParallelEVM.SpeculativeProcesses = c.ParallelEVM.SpeculativeProcesses
 	n.ParallelEVM.Enforce = c.ParallelEVM.Enforce
+	n.ParallelEVM.StatsSampleRate = c.ParallelEVM.StatsSampleRate
+	n.ParallelEVM.LearnHotContracts = c.ParallelEVM.LearnHotContracts
+	n.ParallelEVM.MVHashMapShards = c.ParallelEVM.MVHashMapShards
+
+	for _, contract := range c.ParallelEVM.HotContracts {
+		if !common.IsHexAddress(contract) {
+			return nil, fmt.Errorf("hot contract is not an address: %s", contract)
+		}
+
+		n.ParallelEVM.HotContracts = append(n.ParallelEVM.HotContracts, common.HexToAddress(contract))
+	}
+
 	n.RPCReturnDataLimit = c.RPCReturnDataLimit
+	n.BorRootHashMaxRange = c.RPCRootHashMaxRange
+	n.BorBlockTx = c.RPCBorBlockTx
 
 	if c.Ancient != "" {
 		n.DatabaseFreezer = c.Ancient
@@ -1269,6 +1408,7 @@ func (c *Config) buildEth(stack *node.Node, accountManager *accounts.Manager) (*
 	// Blind fork acceptance configs
 	n.DisableBlindForkValidation = c.DisableBlindForkValidation
 	n.MaxBlindForkValidationLimit = c.MaxBlindForkValidationLimit
+	n.FinalityLagThreshold = c.FinalityLagThreshold
 
 	return &n, nil
 }
@@ -1425,6 +1565,7 @@ func (c *Config) buildNode() (*node.Config, error) {
 		AuthAddr:                               c.JsonRPC.Auth.Addr,
 		AuthVirtualHosts:                       c.JsonRPC.Auth.VHosts,
 		RPCBatchLimit:                          c.RPCBatchLimit,
+		RPCMethodTimeouts:                      map[string]time.Duration{},
 		WSJsonRPCExecutionPoolSize:             c.JsonRPC.Ws.ExecutionPoolSize,
 		WSJsonRPCExecutionPoolRequestTimeout:   c.JsonRPC.Ws.ExecutionPoolRequestTimeout,
 		HTTPJsonRPCExecutionPoolSize:           c.JsonRPC.Http.ExecutionPoolSize,
@@ -1440,6 +1581,15 @@ func (c *Config) buildNode() (*node.Config, error) {
 		cfg.P2P.NetRestrict = list
 	}
 
+	for k, v := range c.RPCTimeouts {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rpc timeout %s of %s: %v", v, k, err)
+		}
+
+		cfg.RPCMethodTimeouts[k] = timeout
+	}
+
 	key := getNodeKey(c.P2P.NodeKeyHex, c.P2P.NodeKey)
 	if key != nil {
 		cfg.P2P.PrivateKey = key
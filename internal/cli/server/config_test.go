@@ -158,3 +158,40 @@ func TestConfigStateScheme(t *testing.T) {
 	_, err = config.buildEth(nil, nil)
 	assert.Error(t, err)
 }
+
+func TestConfigHeimdallRetry(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Heimdall.Timeout = 10 * time.Second
+		config.Heimdall.RetryInterval = 2 * time.Second
+		config.Heimdall.MaxRetries = 3
+
+		assert.NoError(t, config.loadChain())
+
+		ethConfig, err := config.buildEth(nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 10*time.Second, ethConfig.HeimdallTimeout)
+		assert.Equal(t, 2*time.Second, ethConfig.HeimdallRetryInterval)
+		assert.Equal(t, uint64(3), ethConfig.HeimdallMaxRetries)
+	})
+
+	t.Run("zero timeout", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Heimdall.Timeout = 0
+
+		assert.NoError(t, config.loadChain())
+
+		_, err := config.buildEth(nil, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("negative retry interval", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Heimdall.RetryInterval = -time.Second
+
+		assert.NoError(t, config.loadChain())
+
+		_, err := config.buildEth(nil, nil)
+		assert.Error(t, err)
+	})
+}
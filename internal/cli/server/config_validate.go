@@ -0,0 +1,167 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ryanuber/columnize"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// ConfigIssueCode identifies a contradictory or questionable combination of settings.
+type ConfigIssueCode string
+
+const (
+	ConfigIssueUnknownSyncMode        ConfigIssueCode = "unknown-sync-mode"
+	ConfigIssueSnapSyncUnsupported    ConfigIssueCode = "snap-sync-unsupported"
+	ConfigIssueUnknownGcMode          ConfigIssueCode = "unknown-gc-mode"
+	ConfigIssueUnknownStateScheme     ConfigIssueCode = "unknown-state-scheme"
+	ConfigIssueArchivePathScheme      ConfigIssueCode = "archive-path-scheme"
+	ConfigIssueStateHistoryHashScheme ConfigIssueCode = "state-history-hash-scheme"
+	ConfigIssueAncientInChaindata     ConfigIssueCode = "ancient-in-chaindata"
+)
+
+// ConfigIssue is a problem found in the configuration. Fatal issues are contradictory
+// settings the node can't honour, the others are settings which are ignored or
+// overridden at startup.
+type ConfigIssue struct {
+	Code    ConfigIssueCode
+	Fatal   bool
+	Message string
+}
+
+// errConfigIssues is returned when refusing to start on fatal configuration issues.
+var errConfigIssues = errors.New("contradictory configuration, refusing to start with config.strict")
+
+// configIssuesGauge reports the number of issues found in the configuration at startup
+var configIssuesGauge = metrics.NewRegisteredGauge("config/issues", nil)
+
+// validate cross-checks the storage and sync related settings, returning the issues
+// found in them.
+func (c *Config) validate() []ConfigIssue {
+	var issues []ConfigIssue
+
+	fatal := func(code ConfigIssueCode, format string, args ...interface{}) {
+		issues = append(issues, ConfigIssue{Code: code, Fatal: true, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(code ConfigIssueCode, format string, args ...interface{}) {
+		issues = append(issues, ConfigIssue{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch c.SyncMode {
+	case "full":
+	case "snap":
+		warn(ConfigIssueSnapSyncUnsupported, "snap sync is momentarily disabled in bor, full sync is used instead")
+	default:
+		fatal(ConfigIssueUnknownSyncMode, "sync mode '%s' not found", c.SyncMode)
+	}
+
+	switch c.StateScheme {
+	case "path", "hash":
+	default:
+		fatal(ConfigIssueUnknownStateScheme, "state scheme '%s' not found, the hash scheme would be used", c.StateScheme)
+	}
+
+	switch c.GcMode {
+	case "full":
+	case "archive":
+		if c.StateScheme == "path" {
+			fatal(ConfigIssueArchivePathScheme, "path state scheme is not supported in archive mode, please use hash instead")
+		}
+	default:
+		fatal(ConfigIssueUnknownGcMode, "gcmode '%s' not found", c.GcMode)
+	}
+
+	if c.StateScheme == "hash" && c.History != nil && c.History.StateHistory != DefaultConfig().History.StateHistory {
+		warn(ConfigIssueStateHistoryHashScheme, "history.state is only relevant with the path state scheme and is ignored")
+	}
+
+	if c.Ancient != "" && c.DataDir != "" {
+		chaindata := filepath.Join(c.DataDir, clientIdentifier, "chaindata")
+
+		ancient := c.Ancient
+		if !filepath.IsAbs(ancient) {
+			ancient = filepath.Join(chaindata, ancient)
+		}
+
+		if filepath.Clean(ancient) == filepath.Clean(chaindata) {
+			fatal(ConfigIssueAncientInChaindata, "ancient directory '%s' is the chaindata directory, the freezer must be in a separate directory", c.Ancient)
+		}
+	}
+
+	return issues
+}
+
+// configSummary renders a one-page summary of the storage and sync settings, followed
+// by the issues found in them.
+func configSummary(c *Config, issues []ConfigIssue) string {
+	ancient := c.Ancient
+	if ancient == "" {
+		ancient = "(inside chaindata)"
+	}
+
+	var history uint64
+	if c.History != nil {
+		history = c.History.StateHistory
+	}
+
+	rows := []string{
+		"Setting|Value",
+		fmt.Sprintf("datadir|%s", c.DataDir),
+		fmt.Sprintf("ancient|%s", ancient),
+		fmt.Sprintf("syncmode|%s", c.SyncMode),
+		fmt.Sprintf("gcmode|%s", c.GcMode),
+		fmt.Sprintf("state.scheme|%s", c.StateScheme),
+		fmt.Sprintf("history.state|%d", history),
+		fmt.Sprintf("snapshot|%t", c.Snapshot),
+		fmt.Sprintf("config.strict|%t", c.ConfigStrict),
+	}
+
+	summary := columnize.SimpleFormat(rows)
+	if len(issues) == 0 {
+		return summary + "\n\nNo configuration issues found"
+	}
+
+	rows = []string{"Severity|Code|Issue"}
+
+	for _, issue := range issues {
+		severity := "warning"
+		if issue.Fatal {
+			severity = "fatal"
+		}
+
+		rows = append(rows, strings.Join([]string{severity, string(issue.Code), issue.Message}, "|"))
+	}
+
+	return summary + "\n\n" + columnize.SimpleFormat(rows)
+}
+
+// checkConfig validates the configuration at startup and reports the issues found in it.
+// Fatal issues only prevent the startup with config.strict, as the node otherwise keeps
+// its historical behaviour of overriding or rejecting the settings later on.
+func checkConfig(c *Config) ([]ConfigIssue, error) {
+	issues := c.validate()
+	configIssuesGauge.Update(int64(len(issues)))
+
+	fatal := 0
+
+	for _, issue := range issues {
+		if issue.Fatal {
+			fatal++
+
+			log.Error("Contradictory configuration", "code", issue.Code, "issue", issue.Message)
+		} else {
+			log.Warn("Questionable configuration", "code", issue.Code, "issue", issue.Message)
+		}
+	}
+
+	if fatal > 0 && c.ConfigStrict {
+		return issues, fmt.Errorf("%w: %d fatal issues", errConfigIssues, fatal)
+	}
+
+	return issues, nil
+}
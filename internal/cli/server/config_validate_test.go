@@ -0,0 +1,150 @@
+package server
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// issueCodes returns the codes of the given issues, in order.
+func issueCodes(issues []ConfigIssue) []ConfigIssueCode {
+	var codes []ConfigIssueCode
+	for _, issue := range issues {
+		codes = append(codes, issue.Code)
+	}
+
+	return codes
+}
+
+func TestConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		modify func(c *Config)
+		codes  []ConfigIssueCode
+		fatal  bool
+	}{
+		{
+			name:   "default",
+			modify: func(c *Config) {},
+		},
+		{
+			name: "archive with path scheme",
+			modify: func(c *Config) {
+				c.GcMode = "archive"
+				c.StateScheme = "path"
+			},
+			codes: []ConfigIssueCode{ConfigIssueArchivePathScheme},
+			fatal: true,
+		},
+		{
+			name: "archive with hash scheme",
+			modify: func(c *Config) {
+				c.GcMode = "archive"
+				c.StateScheme = "hash"
+			},
+		},
+		{
+			name: "unknown modes",
+			modify: func(c *Config) {
+				c.SyncMode = "light"
+				c.GcMode = "none"
+				c.StateScheme = "tree"
+			},
+			codes: []ConfigIssueCode{ConfigIssueUnknownSyncMode, ConfigIssueUnknownStateScheme, ConfigIssueUnknownGcMode},
+			fatal: true,
+		},
+		{
+			name: "snap sync",
+			modify: func(c *Config) {
+				c.SyncMode = "snap"
+			},
+			codes: []ConfigIssueCode{ConfigIssueSnapSyncUnsupported},
+		},
+		{
+			name: "state history with hash scheme",
+			modify: func(c *Config) {
+				c.StateScheme = "hash"
+				c.History.StateHistory = 1024
+			},
+			codes: []ConfigIssueCode{ConfigIssueStateHistoryHashScheme},
+		},
+		{
+			name: "ancient in chaindata",
+			modify: func(c *Config) {
+				c.DataDir = "/data"
+				c.Ancient = filepath.Join("/data", clientIdentifier, "chaindata")
+			},
+			codes: []ConfigIssueCode{ConfigIssueAncientInChaindata},
+			fatal: true,
+		},
+		{
+			name: "relative ancient in chaindata",
+			modify: func(c *Config) {
+				c.DataDir = "/data"
+				c.Ancient = "."
+			},
+			codes: []ConfigIssueCode{ConfigIssueAncientInChaindata},
+			fatal: true,
+		},
+		{
+			name: "separate ancient",
+			modify: func(c *Config) {
+				c.DataDir = "/data"
+				c.Ancient = "/ancient"
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := DefaultConfig()
+			tc.modify(c)
+
+			issues := c.validate()
+			assert.Equal(t, tc.codes, issueCodes(issues))
+
+			fatal := false
+			for _, issue := range issues {
+				fatal = fatal || issue.Fatal
+			}
+			assert.Equal(t, tc.fatal, fatal)
+		})
+	}
+}
+
+func TestCheckConfigStrict(t *testing.T) {
+	t.Parallel()
+
+	c := DefaultConfig()
+	c.GcMode = "archive"
+	c.StateScheme = "path"
+
+	// Fatal issues are only reported without config.strict
+	issues, err := checkConfig(c)
+	assert.NoError(t, err)
+	assert.Len(t, issues, 1)
+
+	c.ConfigStrict = true
+
+	_, err = checkConfig(c)
+	assert.True(t, errors.Is(err, errConfigIssues))
+
+	// Warnings never prevent the startup
+	c = DefaultConfig()
+	c.ConfigStrict = true
+	c.SyncMode = "snap"
+
+	issues, err = checkConfig(c)
+	assert.NoError(t, err)
+	assert.Equal(t, []ConfigIssueCode{ConfigIssueSnapSyncUnsupported}, issueCodes(issues))
+
+	assert.Contains(t, configSummary(c, issues), string(ConfigIssueSnapSyncUnsupported))
+}
@@ -81,6 +81,30 @@ func (c *Command) Flags(config *Config) *flagset.Flagset {
 		Value:   &c.cliConfig.RPCReturnDataLimit,
 		Default: c.cliConfig.RPCReturnDataLimit,
 	})
+	f.Uint64Flag(&flagset.Uint64Flag{
+		Name:    "rpc.roothashmaxrange",
+		Usage:   "Maximum block range of the bor root hash rpc requests, capped at the checkpoint length (must cover the checkpoints to verify)",
+		Value:   &c.cliConfig.RPCRootHashMaxRange,
+		Default: c.cliConfig.RPCRootHashMaxRange,
+	})
+	f.BoolFlag(&flagset.BoolFlag{
+		Name:    "rpc.borblocktx",
+		Usage:   "Append the bor transaction of the blocks committing state syncs to their transactions in the eth_getBlockByNumber and eth_getBlockByHash responses",
+		Value:   &c.cliConfig.RPCBorBlockTx,
+		Default: c.cliConfig.RPCBorBlockTx,
+	})
+	f.MapStringFlag(&flagset.MapStringFlag{
+		Name:    "rpc.timeouts",
+		Usage:   "Comma separated execution timeouts of the rpc methods or namespaces (<method|namespace>=<duration>)",
+		Value:   &c.cliConfig.RPCTimeouts,
+		Default: c.cliConfig.RPCTimeouts,
+	})
+	f.BoolFlag(&flagset.BoolFlag{
+		Name:    "config.strict",
+		Usage:   "Refuse to start on contradictory storage and sync settings (e.g. archive gcmode with the path state scheme)",
+		Value:   &c.cliConfig.ConfigStrict,
+		Default: c.cliConfig.ConfigStrict,
+	})
 	f.StringFlag(&flagset.StringFlag{
 		Name:  "config",
 		Usage: "Path to the TOML configuration file",
@@ -134,6 +158,12 @@ func (c *Command) Flags(config *Config) *flagset.Flagset {
 		Value:   &c.cliConfig.MaxBlindForkValidationLimit,
 		Default: c.cliConfig.MaxBlindForkValidationLimit,
 	})
+	f.DurationFlag(&flagset.DurationFlag{
+		Name:    "finality-lag-threshold",
+		Usage:   `Lag of the latest milestone/checkpoint behind the wall clock after which a warning is logged`,
+		Value:   &c.cliConfig.FinalityLagThreshold,
+		Default: c.cliConfig.FinalityLagThreshold,
+	})
 
 	// logging related flags (log-level and verbosity is present above, it will be removed soon)
 	f.StringFlag(&flagset.StringFlag{
@@ -185,6 +215,18 @@ func (c *Command) Flags(config *Config) *flagset.Flagset {
 		Value:   &c.cliConfig.Heimdall.Timeout,
 		Default: c.cliConfig.Heimdall.Timeout,
 	})
+	f.DurationFlag(&flagset.DurationFlag{
+		Name:    "bor.heimdallretryinterval",
+		Usage:   "Delay between two attempts of a failed request to heimdall",
+		Value:   &c.cliConfig.Heimdall.RetryInterval,
+		Default: c.cliConfig.Heimdall.RetryInterval,
+	})
+	f.Uint64Flag(&flagset.Uint64Flag{
+		Name:    "bor.heimdallmaxretries",
+		Usage:   "Maximum number of retries of a failed request to heimdall (0 = unlimited)",
+		Value:   &c.cliConfig.Heimdall.MaxRetries,
+		Default: c.cliConfig.Heimdall.MaxRetries,
+	})
 	f.BoolFlag(&flagset.BoolFlag{
 		Name:    "bor.withoutheimdall",
 		Usage:   "Run without Heimdall service (for testing purpose)",
@@ -197,6 +239,48 @@ func (c *Command) Flags(config *Config) *flagset.Flagset {
 		Value:   &c.cliConfig.DevFakeAuthor,
 		Default: c.cliConfig.DevFakeAuthor,
 	})
+	f.BoolFlag(&flagset.BoolFlag{
+		Name:    "bor.verify.full",
+		Usage:   "Verify the seal of every header, including the headers attested by the whitelisted milestone",
+		Value:   &c.cliConfig.VerifyFull,
+		Default: c.cliConfig.VerifyFull,
+	})
+	f.StringFlag(&flagset.StringFlag{
+		Name:    "bor.spanoverride",
+		Usage:   "Path of a signed span override file taking precedence over heimdall, for emergency producer rotation while heimdall is down",
+		Value:   &c.cliConfig.SpanOverride,
+		Default: c.cliConfig.SpanOverride,
+	})
+	f.StringFlag(&flagset.StringFlag{
+		Name:    "bor.spanoverride.key",
+		Usage:   "Hex encoded public key the span override file must be signed with",
+		Value:   &c.cliConfig.SpanOverrideKey,
+		Default: c.cliConfig.SpanOverrideKey,
+	})
+	f.BoolFlag(&flagset.BoolFlag{
+		Name:    "bor.strictsealing",
+		Usage:   "Refuse to seal blocks if the local signer is not a producer of their span, instead of only logging it",
+		Value:   &c.cliConfig.StrictSealing,
+		Default: c.cliConfig.StrictSealing,
+	})
+	f.BoolFlag(&flagset.BoolFlag{
+		Name:    "bor.statesyncindex",
+		Usage:   "Index the committed state sync events, serving bor_getStateSyncEventsByBlockRange",
+		Value:   &c.cliConfig.StateSyncIndex,
+		Default: c.cliConfig.StateSyncIndex,
+	})
+	f.BoolFlag(&flagset.BoolFlag{
+		Name:    "bor.autounwind",
+		Usage:   "Unwind the chain to the parent of a milestone conflicting with it and sync the milestone fork again, at most once every 5 minutes and no deeper than bor.autounwind.depth",
+		Value:   &c.cliConfig.AutoUnwind,
+		Default: c.cliConfig.AutoUnwind,
+	})
+	f.Uint64Flag(&flagset.Uint64Flag{
+		Name:    "bor.autounwind.depth",
+		Usage:   "Maximum number of blocks unwound automatically on a conflicting milestone",
+		Value:   &c.cliConfig.AutoUnwindDepth,
+		Default: c.cliConfig.AutoUnwindDepth,
+	})
 	f.StringFlag(&flagset.StringFlag{
 		Name:    "bor.heimdallgRPC",
 		Usage:   "Address of Heimdall gRPC service",
@@ -227,6 +311,18 @@ func (c *Command) Flags(config *Config) *flagset.Flagset {
 		Value:   &c.cliConfig.Heimdall.UseHeimdallApp,
 		Default: c.cliConfig.Heimdall.UseHeimdallApp,
 	})
+	f.BoolFlag(&flagset.BoolFlag{
+		Name:    "bor.heimdallreadiness",
+		Usage:   "Report the node unhealthy on /debug/heimdall when heimdall is unreachable for longer than bor.heimdallreadinesstimeout",
+		Value:   &c.cliConfig.Heimdall.Readiness,
+		Default: c.cliConfig.Heimdall.Readiness,
+	})
+	f.DurationFlag(&flagset.DurationFlag{
+		Name:    "bor.heimdallreadinesstimeout",
+		Usage:   "Maximum duration heimdall can be unreachable before the node is reported unhealthy (with bor.heimdallreadiness)",
+		Value:   &c.cliConfig.Heimdall.ReadinessTimeout,
+		Default: c.cliConfig.Heimdall.ReadinessTimeout,
+	})
 
 	// txpool options
 	f.SliceStringFlag(&flagset.SliceStringFlag{
@@ -308,6 +404,20 @@ func (c *Command) Flags(config *Config) *flagset.Flagset {
 	})
 
 	// sealer options
+	f.StringFlag(&flagset.StringFlag{
+		Name:    "bor.emptyblocks",
+		Usage:   "Policy for sealing blocks without transactions: 'always', 'skip' (unless they start a sprint with pending state sync events) or 'heartbeat:N' (at most one empty block every N seconds). Skipping requires a chain config allowing it",
+		Value:   &c.cliConfig.Sealer.EmptyBlocks,
+		Default: c.cliConfig.Sealer.EmptyBlocks,
+		Group:   "Sealer",
+	})
+	f.Uint64Flag(&flagset.Uint64Flag{
+		Name:    "bor.prefetchblocks",
+		Usage:   "Number of blocks before a sprint start its span and state sync events are fetched from heimdall in the background (use 0 to disable)",
+		Value:   &c.cliConfig.Sealer.PrefetchBlocks,
+		Default: c.cliConfig.Sealer.PrefetchBlocks,
+		Group:   "Sealer",
+	})
 	f.BoolFlag(&flagset.BoolFlag{
 		Name:    "mine",
 		Usage:   "Enable mining",
@@ -1023,6 +1133,30 @@ func (c *Command) Flags(config *Config) *flagset.Flagset {
 		Value:   &c.cliConfig.ParallelEVM.Enforce,
 		Default: c.cliConfig.ParallelEVM.Enforce,
 	})
+	f.Uint64Flag(&flagset.Uint64Flag{
+		Name:    "parallelevm.statssamplerate",
+		Usage:   "Collect Block STM execution stats for 1 out of N blocks (0 = disabled)",
+		Value:   &c.cliConfig.ParallelEVM.StatsSampleRate,
+		Default: c.cliConfig.ParallelEVM.StatsSampleRate,
+	})
+	f.SliceStringFlag(&flagset.SliceStringFlag{
+		Name:    "parallelevm.hotcontracts",
+		Usage:   "Comma separated contracts whose transactions are chained upfront in Block STM, as they are likely to conflict",
+		Value:   &c.cliConfig.ParallelEVM.HotContracts,
+		Default: c.cliConfig.ParallelEVM.HotContracts,
+	})
+	f.BoolFlag(&flagset.BoolFlag{
+		Name:    "parallelevm.learnhotcontracts",
+		Usage:   "Learn the hot contracts of Block STM from the conflicts of the executed blocks",
+		Value:   &c.cliConfig.ParallelEVM.LearnHotContracts,
+		Default: c.cliConfig.ParallelEVM.LearnHotContracts,
+	})
+	f.IntFlag(&flagset.IntFlag{
+		Name:    "parallelevm.mvhashmapshards",
+		Usage:   "Number of independently locked shards of the Block STM multi-version hash map",
+		Value:   &c.cliConfig.ParallelEVM.MVHashMapShards,
+		Default: c.cliConfig.ParallelEVM.MVHashMapShards,
+	})
 
 	f.Uint64Flag(&flagset.Uint64Flag{
 		Name:    "dev.gaslimit",
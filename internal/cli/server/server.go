@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
@@ -60,6 +61,9 @@ type Server struct {
 
 	// tracerAPI to trace block executions
 	tracerAPI *tracers.API
+
+	// heimdallHealth reports the connectivity to heimdall on the metrics server
+	heimdallHealth atomic.Pointer[bor.HeimdallHealth]
 }
 
 type serverOption func(srv *Server, config *Config) error
@@ -111,8 +115,12 @@ func VerbosityStringToInt(loglevel string) int {
 
 //nolint:gocognit
 func NewServer(config *Config, opts ...serverOption) (*Server, error) {
+	srv := &Server{
+		config: config,
+	}
+
 	// Enable metric collection if requested
-	if err := setupMetrics(config.Telemetry); err != nil {
+	if err := setupMetrics(config.Telemetry, http.HandlerFunc(srv.serveHeimdallStatus)); err != nil {
 		return nil, err
 	}
 
@@ -125,10 +133,6 @@ func NewServer(config *Config, opts ...serverOption) (*Server, error) {
 
 	runtime.SetMutexProfileFraction(5)
 
-	srv := &Server{
-		config: config,
-	}
-
 	// start the logger
 	setupLogger(config.Verbosity, *config.Logging)
 
@@ -262,6 +266,10 @@ func NewServer(config *Config, opts ...serverOption) (*Server, error) {
 	// set the auth status in backend
 	srv.backend.SetAuthorized(authorized)
 
+	if engine, ok := srv.backend.Engine().(*bor.Bor); ok && engine.HeimdallHealth() != nil {
+		srv.heimdallHealth.Store(engine.HeimdallHealth())
+	}
+
 	filterSystem := utils.RegisterFilterAPI(stack, srv.backend.APIBackend, ethCfg)
 
 	// debug tracing is enabled by default
@@ -322,7 +330,18 @@ func (s *Server) Stop() {
 	}
 }
 
-func setupMetrics(config *TelemetryConfig) error {
+// serveHeimdallStatus reports the connectivity to heimdall, see bor.HeimdallHealth.
+func (s *Server) serveHeimdallStatus(w http.ResponseWriter, r *http.Request) {
+	health := s.heimdallHealth.Load()
+	if health == nil {
+		http.Error(w, "heimdall status not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	health.ServeHTTP(w, r)
+}
+
+func setupMetrics(config *TelemetryConfig, heimdallStatus http.Handler) error {
 	if !config.Enabled {
 		return nil
 	}
@@ -363,6 +382,7 @@ func setupMetrics(config *TelemetryConfig) error {
 		prometheusMux := http.NewServeMux()
 
 		prometheusMux.Handle("/debug/metrics/prometheus", prometheus.Handler(metrics.DefaultRegistry))
+		prometheusMux.Handle("/debug/heimdall", heimdallStatus)
 
 		timeouts := rpc.DefaultHTTPTimeouts
 
@@ -20,6 +20,7 @@ import (
 	"github.com/ethereum/go-ethereum/internal/cli/server/proto"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 const chunkSize = 1024 * 1024 * 1024
@@ -229,16 +230,18 @@ func headerToProtoHeader(h *types.Header) *proto.Header {
 }
 
 func (s *Server) DebugBlock(req *proto.DebugBlockRequest, stream proto.Bor_DebugBlockServer) error {
-	traceReq := &tracers.TraceBlockRequest{
-		Number: req.Number,
-		Config: &tracers.TraceConfig{
-			Config: &logger.Config{
-				EnableMemory: true,
-			},
+	blockNumber := rpc.BlockNumber(req.Number)
+	if req.Number == -1 {
+		blockNumber = rpc.LatestBlockNumber
+	}
+
+	config := &tracers.TraceConfig{
+		Config: &logger.Config{
+			EnableMemory: true,
 		},
 	}
 
-	res, err := s.tracerAPI.TraceBorBlock(traceReq)
+	res, err := s.tracerAPI.TraceBorBlock(stream.Context(), rpc.BlockNumberOrHashWithNumber(blockNumber), config)
 	if err != nil {
 		return err
 	}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
@@ -39,6 +40,8 @@ func (c *SnapshotCommand) MarkDown() string {
 		"- [```snapshot prune-state```](./snapshot_prune-state.md): Prune state databases at the given datadir location.",
 		"- [```snapshot prune-block```](./snapshot_prune-block.md): Prune ancient chaindata at the given datadir location.",
 		"- [```snapshot inspect-ancient-db```](./snapshot_inspect-ancient-db.md): Inspect few fields in ancient datastore.",
+		"- [```snapshot prune-bor-snapshots```](./snapshot_prune-bor-snapshots.md): Prune bor validator snapshots at the given datadir location.",
+		"- [```snapshot verify-bor```](./snapshot_verify-bor.md): Check the integrity of the bor specific tables at the given datadir location.",
 	}
 
 	return strings.Join(items, "\n\n")
@@ -60,7 +63,15 @@ func (c *SnapshotCommand) Help() string {
 
   Inspect ancient DB pruning related fields:
 
-    $ bor snapshot inspect-ancient-db`
+    $ bor snapshot inspect-ancient-db
+
+  Prune the bor validator snapshots:
+
+    $ bor snapshot prune-bor-snapshots
+
+  Check the bor specific tables:
+
+    $ bor snapshot verify-bor`
 }
 
 // Synopsis implements the cli.Command interface
@@ -657,3 +668,119 @@ func (c *InspectAncientDbCommand) inspectAncientDb(stack *node.Node, dbHandles i
 
 	return rawdb.AncientInspect(chaindb)
 }
+
+// PruneBorSnapshotsCommand is the command to prune the persisted bor validator snapshots
+type PruneBorSnapshotsCommand struct {
+	*Meta
+
+	datadirAncient string
+	keep           uint64
+	recent         uint64
+}
+
+// MarkDown implements cli.MarkDown interface
+func (c *PruneBorSnapshotsCommand) MarkDown() string {
+	items := []string{
+		"# Prune bor snapshots",
+		"The ```bor snapshot prune-bor-snapshots``` command deletes old validator snapshots persisted by the bor consensus engine. " +
+			"All the snapshots of the recent blocks are kept, while only every ```keep```-th snapshot is kept below them. " +
+			"Pruned snapshots are rebuilt on demand by replaying the headers from the nearest retained one. The node must be stopped while running it.",
+		c.Flags().MarkDown(),
+	}
+
+	return strings.Join(items, "\n\n")
+}
+
+// Help implements the cli.Command interface
+func (c *PruneBorSnapshotsCommand) Help() string {
+	return `Usage: bor snapshot prune-bor-snapshots --datadir <datadir> [--keep <n>] [--recent <blocks>]
+
+  This command will prune the bor validator snapshots at the given datadir location` + c.Flags().Help()
+}
+
+// Synopsis implements the cli.Command interface
+func (c *PruneBorSnapshotsCommand) Synopsis() string {
+	return "Prune bor validator snapshots"
+}
+
+func (c *PruneBorSnapshotsCommand) Flags() *flagset.Flagset {
+	flags := c.NewFlagSet("prune-bor-snapshots")
+
+	flags.StringFlag(&flagset.StringFlag{
+		Name:    "datadir.ancient",
+		Value:   &c.datadirAncient,
+		Usage:   "Path of the ancient data directory",
+		Default: "",
+	})
+	flags.Uint64Flag(&flagset.Uint64Flag{
+		Name:    "keep",
+		Value:   &c.keep,
+		Usage:   "Keep every Nth snapshot below the recent blocks (0 or 1 keeps all)",
+		Default: bor.DefaultSnapshotRetention.Keep,
+	})
+	flags.Uint64Flag(&flagset.Uint64Flag{
+		Name:    "recent",
+		Value:   &c.recent,
+		Usage:   "Number of blocks below the head keeping all their snapshots",
+		Default: bor.DefaultSnapshotRetention.Recent,
+	})
+
+	return flags
+}
+
+// Run implements the cli.Command interface
+func (c *PruneBorSnapshotsCommand) Run(args []string) int {
+	flags := c.Flags()
+
+	if err := flags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	datadir := c.dataDir
+	if datadir == "" {
+		c.UI.Error("datadir is required")
+		return 1
+	}
+
+	// Create the node
+	node, err := node.New(&node.Config{
+		DataDir: datadir,
+	})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	defer node.Close()
+
+	dbHandles, err := server.MakeDatabaseHandles(0)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	chaindb, err := node.OpenDatabaseWithFreezer(chaindataPath, 1024, dbHandles, c.datadirAncient, "", false, false, false)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	defer chaindb.Close()
+
+	head := rawdb.ReadHeadHeader(chaindb)
+	if head == nil {
+		c.UI.Error("Head header not found in the database")
+		return 1
+	}
+
+	retention := bor.SnapshotRetention{Keep: c.keep, Recent: c.recent}
+
+	pruned, err := bor.PruneSnapshots(chaindb, head.Number.Uint64(), retention)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Failed to prune bor snapshots: %v", err))
+		return 1
+	}
+
+	c.UI.Output(fmt.Sprintf("Pruned %d bor snapshots (head: %d, keep: %d, recent: %d)", pruned, head.Number.Uint64(), c.keep, c.recent))
+
+	return 0
+}
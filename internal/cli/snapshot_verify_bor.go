@@ -0,0 +1,373 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/cli/flagset"
+	"github.com/ethereum/go-ethereum/internal/cli/server"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// VerifyBorCommand is the command to check the integrity of the bor specific tables
+type VerifyBorCommand struct {
+	*Meta
+
+	datadirAncient string
+	fix            bool
+}
+
+// MarkDown implements cli.MarkDown interface
+func (c *VerifyBorCommand) MarkDown() string {
+	items := []string{
+		"# Verify bor",
+		"The ```bor snapshot verify-bor``` command checks the integrity of the bor specific tables. " +
+			"It decodes the bor receipts and checks that their blocks exist, checks that the bor transaction lookups point at canonical blocks " +
+			"and that the persisted checkpoint, milestone, lock and future milestone entries are consistent. " +
+			"With ```fix```, the bor receipts of missing blocks and the bor transaction lookups not matching a canonical block are deleted, " +
+			"the other issues are only reported. " +
+			"The node must be stopped while running it.",
+		c.Flags().MarkDown(),
+	}
+
+	return strings.Join(items, "\n\n")
+}
+
+// Help implements the cli.Command interface
+func (c *VerifyBorCommand) Help() string {
+	return `Usage: bor snapshot verify-bor --datadir <datadir> [--fix]
+
+  This command will check the integrity of the bor specific tables at the given datadir location` + c.Flags().Help()
+}
+
+// Synopsis implements the cli.Command interface
+func (c *VerifyBorCommand) Synopsis() string {
+	return "Check the integrity of the bor specific tables"
+}
+
+func (c *VerifyBorCommand) Flags() *flagset.Flagset {
+	flags := c.NewFlagSet("verify-bor")
+
+	flags.StringFlag(&flagset.StringFlag{
+		Name:    "datadir.ancient",
+		Value:   &c.datadirAncient,
+		Usage:   "Path of the ancient data directory",
+		Default: "",
+	})
+	flags.BoolFlag(&flagset.BoolFlag{
+		Name:    "fix",
+		Value:   &c.fix,
+		Usage:   "Delete the bor receipts of missing blocks and the bor transaction lookups not matching a canonical block",
+		Default: false,
+	})
+
+	return flags
+}
+
+// Run implements the cli.Command interface
+func (c *VerifyBorCommand) Run(args []string) int {
+	flags := c.Flags()
+
+	if err := flags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	datadir := c.dataDir
+	if datadir == "" {
+		c.UI.Error("datadir is required")
+		return 1
+	}
+
+	// Create the node
+	node, err := node.New(&node.Config{
+		DataDir: datadir,
+	})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	defer node.Close()
+
+	dbHandles, err := server.MakeDatabaseHandles(0)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	chaindb, err := node.OpenDatabaseWithFreezer(chaindataPath, 1024, dbHandles, c.datadirAncient, "", false, false, false)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	defer chaindb.Close()
+
+	report, err := verifyBorData(chaindb, c.fix)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Failed to verify the bor tables: %v", err))
+		return 1
+	}
+
+	for _, issue := range report.whitelistIssues {
+		c.UI.Output(fmt.Sprintf("Whitelist issue: %s", issue))
+	}
+
+	c.UI.Output(report.String())
+
+	if report.issues() > report.fixed {
+		return 1
+	}
+
+	return 0
+}
+
+// borVerifyReport sums up the integrity check of the bor specific tables.
+type borVerifyReport struct {
+	receipts        int      // Number of bor receipts checked
+	corruptReceipts int      // Number of bor receipts failing to decode
+	orphanReceipts  int      // Number of bor receipts of missing blocks
+	lookups         int      // Number of bor transaction lookups checked
+	orphanLookups   int      // Number of bor transaction lookups not pointing at their canonical block
+	whitelistIssues []string // Inconsistencies found in the persisted whitelist entries
+	fixed           int      // Number of orphaned entries deleted
+}
+
+// issues returns the number of issues found.
+func (r *borVerifyReport) issues() int {
+	return r.corruptReceipts + r.orphanReceipts + r.orphanLookups + len(r.whitelistIssues)
+}
+
+// String implements the fmt.Stringer interface
+func (r *borVerifyReport) String() string {
+	return formatKV([]string{
+		fmt.Sprintf("Bor receipts|%d", r.receipts),
+		fmt.Sprintf("Corrupt bor receipts|%d", r.corruptReceipts),
+		fmt.Sprintf("Orphaned bor receipts|%d", r.orphanReceipts),
+		fmt.Sprintf("Bor tx lookups|%d", r.lookups),
+		fmt.Sprintf("Orphaned bor tx lookups|%d", r.orphanLookups),
+		fmt.Sprintf("Whitelist issues|%d", len(r.whitelistIssues)),
+		fmt.Sprintf("Fixed entries|%d", r.fixed),
+	})
+}
+
+// verifyBorData checks the bor receipts, the bor transaction lookups and the persisted
+// whitelist entries. If fix is set, the bor receipts of missing blocks and the bor
+// transaction lookups not pointing at their canonical block are deleted, as they can't be
+// served anyway and are written again if the blocks are imported. The other issues are
+// only reported.
+func verifyBorData(db ethdb.Database, fix bool) (*borVerifyReport, error) {
+	var (
+		report  = new(borVerifyReport)
+		orphans []common.Hash
+		numbers []uint64
+		lookups []common.Hash
+	)
+
+	rawdb.IterateBorReceipts(db, func(number uint64, hash common.Hash, data []byte) bool {
+		report.receipts++
+
+		// The content of the receipts of missing blocks doesn't matter, they're deleted anyway
+		if !rawdb.HasHeader(db, hash, number) {
+			report.orphanReceipts++
+
+			orphans = append(orphans, hash)
+			numbers = append(numbers, number)
+
+			return true
+		}
+
+		if err := rlp.DecodeBytes(data, new(types.ReceiptForStorage)); err != nil {
+			report.corruptReceipts++
+		}
+
+		return true
+	})
+
+	rawdb.IterateBorTxLookupEntries(db, func(txHash common.Hash, number *uint64) bool {
+		report.lookups++
+
+		if number != nil {
+			hash := rawdb.ReadCanonicalHash(db, *number)
+			if hash != (common.Hash{}) && types.GetDerivedBorTxHash(types.BorReceiptKey(*number, hash)) == txHash {
+				return true
+			}
+		}
+
+		report.orphanLookups++
+
+		lookups = append(lookups, txHash)
+
+		return true
+	})
+
+	report.whitelistIssues = verifyWhitelistEntries(db)
+
+	if !fix {
+		return report, nil
+	}
+
+	// Delete the orphans once the iterations are done, so that they don't see the deletions
+	batch := db.NewBatch()
+
+	flush := func(force bool) error {
+		if !force && batch.ValueSize() < ethdb.IdealBatchSize {
+			return nil
+		}
+
+		if err := batch.Write(); err != nil {
+			return err
+		}
+
+		batch.Reset()
+
+		return nil
+	}
+
+	for i, hash := range orphans {
+		rawdb.DeleteBorReceipt(batch, hash, numbers[i])
+
+		if err := flush(false); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, txHash := range lookups {
+		rawdb.DeleteBorTxLookupEntryByTxHash(batch, txHash)
+
+		if err := flush(false); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := flush(true); err != nil {
+		return nil, err
+	}
+
+	report.fixed = len(orphans) + len(lookups)
+
+	return report, nil
+}
+
+// verifyWhitelistEntries checks the persisted checkpoint, milestone, lock and future
+// milestone entries for consistency, among them and with the canonical chain, and returns
+// the issues found. Missing entries aren't an issue, they're written as the node runs.
+func verifyWhitelistEntries(db ethdb.Database) []string {
+	var issues []string
+
+	checkpoint, checkpointOk, issue := readWhitelistFinality[*rawdb.Checkpoint](db, "checkpoint")
+	if issue != "" {
+		issues = append(issues, issue)
+	}
+
+	milestone, milestoneOk, issue := readWhitelistFinality[*rawdb.Milestone](db, "milestone")
+	if issue != "" {
+		issues = append(issues, issue)
+	}
+
+	if issue := verifyFinalityStatus[*rawdb.Checkpoint](db, "checkpoint", checkpoint, checkpointOk); issue != "" {
+		issues = append(issues, issue)
+	}
+
+	if issue := verifyFinalityStatus[*rawdb.Milestone](db, "milestone", milestone, milestoneOk); issue != "" {
+		issues = append(issues, issue)
+	}
+
+	locked, lockBlock, lockHash, lockIDs, err := rawdb.ReadLockField(db)
+
+	switch {
+	case errors.Is(err, rawdb.ErrIncorrectLockField):
+		issues = append(issues, fmt.Sprintf("lock is corrupt: %v", err))
+	case err != nil || !locked:
+	case lockHash == (common.Hash{}):
+		issues = append(issues, fmt.Sprintf("lock of block %d has no hash", lockBlock))
+	case len(lockIDs) == 0:
+		issues = append(issues, fmt.Sprintf("lock of block %d has no milestone id", lockBlock))
+	case milestoneOk && lockBlock <= milestone.Block:
+		issues = append(issues, fmt.Sprintf("lock of block %d is not past the last milestone at block %d", lockBlock, milestone.Block))
+	}
+
+	order, list, err := rawdb.ReadFutureMilestoneList(db)
+
+	switch {
+	case errors.Is(err, rawdb.ErrIncorrectFutureMilestoneField) || errors.Is(err, rawdb.ErrIncorrectLockField):
+		issues = append(issues, fmt.Sprintf("future milestone list is corrupt: %v", err))
+	case err != nil:
+	default:
+		seen := make(map[uint64]struct{}, len(order))
+
+		for _, number := range order {
+			if _, ok := seen[number]; ok {
+				issues = append(issues, fmt.Sprintf("future milestone list has block %d more than once", number))
+				continue
+			}
+
+			seen[number] = struct{}{}
+
+			hash, ok := list[number]
+
+			switch {
+			case !ok:
+				issues = append(issues, fmt.Sprintf("future milestone list has no hash for block %d", number))
+			case hash == (common.Hash{}):
+				issues = append(issues, fmt.Sprintf("future milestone list has an empty hash for block %d", number))
+			}
+		}
+
+		for _, number := range slices.Sorted(maps.Keys(list)) {
+			if _, ok := seen[number]; !ok {
+				issues = append(issues, fmt.Sprintf("future milestone list has block %d missing from its order", number))
+			}
+		}
+	}
+
+	return issues
+}
+
+// readWhitelistFinality reads the last checkpoint or milestone, reporting whether it's
+// found along with the issue found in it, if any.
+func readWhitelistFinality[T rawdb.BlockFinality[T]](db ethdb.Database, name string) (rawdb.Finality, bool, string) {
+	block, hash, err := rawdb.ReadFinality[T](db)
+
+	switch {
+	case errors.Is(err, rawdb.ErrIncorrectFinality) || errors.Is(err, rawdb.ErrEmptyLastFinality):
+		return rawdb.Finality{}, false, fmt.Sprintf("last %s is corrupt: %v", name, err)
+	case err != nil:
+		return rawdb.Finality{}, false, ""
+	case hash == (common.Hash{}):
+		return rawdb.Finality{}, false, fmt.Sprintf("last %s of block %d has no hash", name, block)
+	}
+
+	finality := rawdb.Finality{Block: block, Hash: hash}
+
+	// Only the blocks known locally can be checked
+	if canonical := rawdb.ReadCanonicalHash(db, block); canonical != (common.Hash{}) && canonical != hash {
+		return finality, true, fmt.Sprintf("last %s of block %d has hash %s, the canonical block %s", name, block, hash, canonical)
+	}
+
+	return finality, true, ""
+}
+
+// verifyFinalityStatus checks that the processing status of the last checkpoint or
+// milestone matches it, returning the issue found, if any.
+func verifyFinalityStatus[T rawdb.BlockFinality[T]](db ethdb.Database, name string, finality rawdb.Finality, ok bool) string {
+	status, err := rawdb.ReadFinalityStatus[T](db)
+
+	switch {
+	case errors.Is(err, rawdb.ErrIncorrectFinality) || errors.Is(err, rawdb.ErrEmptyLastFinality):
+		return fmt.Sprintf("last %s status is corrupt: %v", name, err)
+	case err != nil || !ok:
+		return ""
+	case status.Block != finality.Block:
+		return fmt.Sprintf("last %s status is for block %d, the last %s is at block %d", name, status.Block, name, finality.Block)
+	}
+
+	return ""
+}
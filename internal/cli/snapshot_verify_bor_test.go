@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyBorData(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		hashes = make(map[uint64]common.Hash)
+	)
+
+	// Build a chain of 4 blocks where blocks 2 and 4 have a bor receipt, along with
+	// consistent whitelist entries
+	for number := uint64(0); number <= 4; number++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(number), Time: 1000 + number*2}
+		hashes[number] = header.Hash()
+
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), number)
+
+		if number%2 == 0 && number > 0 {
+			rawdb.WriteBorBlockData(db, header.Hash(), number, &types.ReceiptForStorage{Status: types.ReceiptStatusSuccessful}, nil)
+		}
+	}
+
+	require.NoError(t, rawdb.WriteLastFinality[*rawdb.Checkpoint](db, 2, hashes[2]))
+	require.NoError(t, rawdb.WriteFinalityStatus[*rawdb.Checkpoint](db, &rawdb.FinalityStatus{Block: 2}))
+	require.NoError(t, rawdb.WriteLastFinality[*rawdb.Milestone](db, 3, hashes[3]))
+	require.NoError(t, rawdb.WriteFinalityStatus[*rawdb.Milestone](db, &rawdb.FinalityStatus{Block: 3}))
+	require.NoError(t, rawdb.WriteLockField(db, true, 4, hashes[4], map[string]struct{}{"id": {}}))
+	require.NoError(t, rawdb.WriteFutureMilestoneList(db, []uint64{8, 12}, map[uint64]common.Hash{8: {0x8}, 12: {0xc}}))
+
+	report, err := verifyBorData(db, false)
+	require.NoError(t, err)
+	require.Equal(t, &borVerifyReport{receipts: 2, lookups: 2}, report)
+
+	// Corrupt the receipt of block 4, add the receipt and lookup of a missing block and a
+	// lookup of a block which isn't canonical anymore
+	var (
+		missing = common.HexToHash("0xdead")
+		side    = common.HexToHash("0xbeef")
+	)
+
+	require.NoError(t, db.Put(types.BorReceiptKey(4, hashes[4]), []byte{0xff, 0x01}))
+
+	rawdb.WriteBorBlockData(db, missing, 7, &types.ReceiptForStorage{Status: types.ReceiptStatusSuccessful}, nil)
+	rawdb.WriteBorTxLookupEntry(db, side, 3)
+
+	// Make the whitelist entries inconsistent
+	require.NoError(t, rawdb.WriteLastFinality[*rawdb.Milestone](db, 3, side))
+	require.NoError(t, rawdb.WriteLockField(db, true, 2, hashes[2], map[string]struct{}{"id": {}}))
+	require.NoError(t, rawdb.WriteFutureMilestoneList(db, []uint64{8, 8}, map[uint64]common.Hash{8: {0x8}, 12: {0xc}}))
+
+	report, err = verifyBorData(db, false)
+	require.NoError(t, err)
+	require.Equal(t, 3, report.receipts)
+	require.Equal(t, 1, report.corruptReceipts)
+	require.Equal(t, 1, report.orphanReceipts)
+	require.Equal(t, 4, report.lookups)
+	require.Equal(t, 2, report.orphanLookups)
+	require.Len(t, report.whitelistIssues, 4)
+	require.Zero(t, report.fixed)
+
+	// Nothing is deleted without fix
+	require.NotNil(t, rawdb.ReadBorReceiptRLP(db, missing, 7))
+
+	// The orphans are deleted by fix, the other issues are left as is
+	report, err = verifyBorData(db, true)
+	require.NoError(t, err)
+	require.Equal(t, 3, report.fixed)
+
+	require.Nil(t, rawdb.ReadBorReceiptRLP(db, missing, 7))
+	require.Nil(t, rawdb.ReadBorTxLookupEntry(db, types.GetDerivedBorTxHash(types.BorReceiptKey(7, missing))))
+	require.Nil(t, rawdb.ReadBorTxLookupEntry(db, side))
+	require.NotNil(t, rawdb.ReadBorTxLookupEntry(db, types.GetDerivedBorTxHash(types.BorReceiptKey(4, hashes[4]))))
+
+	report, err = verifyBorData(db, false)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.receipts)
+	require.Equal(t, 1, report.corruptReceipts)
+	require.Zero(t, report.orphanReceipts)
+	require.Equal(t, 2, report.lookups)
+	require.Zero(t, report.orphanLookups)
+	require.Len(t, report.whitelistIssues, 4)
+}
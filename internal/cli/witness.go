@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+// WitnessCommand is the command to group the witness commands
+type WitnessCommand struct {
+	UI cli.Ui
+}
+
+// MarkDown implements cli.MarkDown interface
+func (c *WitnessCommand) MarkDown() string {
+	items := []string{
+		"# Witness",
+		"The ```witness``` command groups actions to export and import block witnesses for offline debugging:",
+		"- [```witness export```](./witness_export.md): Export the witness of a block to a file.",
+		"- [```witness import```](./witness_import.md): Import a witness from a file and optionally execute it.",
+	}
+
+	return strings.Join(items, "\n\n")
+}
+
+// Help implements the cli.Command interface
+func (c *WitnessCommand) Help() string {
+	return `Usage: bor witness <subcommand>
+
+  This command groups actions to export and import block witnesses.
+
+  Export the witness of a block:
+
+    $ bor witness export --block <hash> --out <file>
+
+  Import a witness and execute it against the local chain:
+
+    $ bor witness import --file <file> --execute`
+}
+
+// Synopsis implements the cli.Command interface
+func (c *WitnessCommand) Synopsis() string {
+	return "Export and import block witnesses"
+}
+
+// Run implements the cli.Command interface
+func (c *WitnessCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
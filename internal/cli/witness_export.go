@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/internal/cli/flagset"
+	"github.com/mitchellh/cli"
+)
+
+// WitnessExportCommand is the command to export the witness of a block to a file
+type WitnessExportCommand struct {
+	UI cli.Ui
+
+	endpoint string
+	block    string
+	out      string
+}
+
+// MarkDown implements cli.MarkDown interface
+func (c *WitnessExportCommand) MarkDown() string {
+	items := []string{
+		"# Witness export",
+		"The ```witness export``` command regenerates the witness of a block on a running node and writes it RLP encoded to a file.",
+		c.Flags().MarkDown(),
+	}
+
+	return strings.Join(items, "\n\n")
+}
+
+// Help implements the cli.Command interface
+func (c *WitnessExportCommand) Help() string {
+	return `Usage: bor witness export --block <hash> --out <file>
+
+  Export the witness of a block to a file
+
+  ` + c.Flags().Help()
+}
+
+func (c *WitnessExportCommand) Flags() *flagset.Flagset {
+	flags := flagset.NewFlagSet("witness export")
+
+	flags.StringFlag(&flagset.StringFlag{
+		Name:  "endpoint",
+		Usage: "IPC path or RPC endpoint of the node (defaults to the bor IPC endpoint)",
+		Value: &c.endpoint,
+	})
+	flags.StringFlag(&flagset.StringFlag{
+		Name:  "block",
+		Usage: "Hash of the block to export the witness of",
+		Value: &c.block,
+	})
+	flags.StringFlag(&flagset.StringFlag{
+		Name:  "out",
+		Usage: "Path of the file to write the witness to",
+		Value: &c.out,
+	})
+
+	return flags
+}
+
+// Synopsis implements the cli.Command interface
+func (c *WitnessExportCommand) Synopsis() string {
+	return "Export the witness of a block to a file"
+}
+
+// Run implements the cli.Command interface
+func (c *WitnessExportCommand) Run(args []string) int {
+	flags := c.Flags()
+	if err := flags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if c.block == "" || c.out == "" {
+		c.UI.Error("Both block and out are required")
+		return 1
+	}
+
+	var hash common.Hash
+	if err := hash.UnmarshalText([]byte(c.block)); err != nil {
+		c.UI.Error(fmt.Sprintf("Invalid block hash: %v", err))
+		return 1
+	}
+
+	client, err := dialRPC(c.endpoint)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	defer client.Close()
+
+	var witness hexutil.Bytes
+	if err := client.CallContext(context.Background(), &witness, "debug_exportWitness", hash); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := os.WriteFile(c.out, witness, 0600); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Output(fmt.Sprintf("Witness of block %s written to %s (%d bytes)", hash.Hex(), c.out, len(witness)))
+
+	return 0
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/internal/cli/flagset"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/mitchellh/cli"
+)
+
+// WitnessImportCommand is the command to import a witness from a file
+type WitnessImportCommand struct {
+	UI cli.Ui
+
+	endpoint string
+	file     string
+	execute  bool
+}
+
+// MarkDown implements cli.MarkDown interface
+func (c *WitnessImportCommand) MarkDown() string {
+	items := []string{
+		"# Witness import",
+		"The ```witness import``` command decodes a witness from a file and validates its pre-state against the chain of a running node. " +
+			"With ```--execute``` the block built on top of the pre-state is executed statelessly and the resulting roots are compared to the local ones.",
+		c.Flags().MarkDown(),
+	}
+
+	return strings.Join(items, "\n\n")
+}
+
+// Help implements the cli.Command interface
+func (c *WitnessImportCommand) Help() string {
+	return `Usage: bor witness import --file <file> [--execute]
+
+  Import a witness from a file and optionally execute it
+
+  ` + c.Flags().Help()
+}
+
+func (c *WitnessImportCommand) Flags() *flagset.Flagset {
+	flags := flagset.NewFlagSet("witness import")
+
+	flags.StringFlag(&flagset.StringFlag{
+		Name:  "endpoint",
+		Usage: "IPC path or RPC endpoint of the node (defaults to the bor IPC endpoint)",
+		Value: &c.endpoint,
+	})
+	flags.StringFlag(&flagset.StringFlag{
+		Name:  "file",
+		Usage: "Path of the file to read the witness from",
+		Value: &c.file,
+	})
+	flags.BoolFlag(&flagset.BoolFlag{
+		Name:    "execute",
+		Usage:   "Statelessly execute the block on top of the witness pre-state and compare the resulting roots",
+		Value:   &c.execute,
+		Default: false,
+	})
+
+	return flags
+}
+
+// Synopsis implements the cli.Command interface
+func (c *WitnessImportCommand) Synopsis() string {
+	return "Import a witness from a file"
+}
+
+// Run implements the cli.Command interface
+func (c *WitnessImportCommand) Run(args []string) int {
+	flags := c.Flags()
+	if err := flags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if c.file == "" {
+		c.UI.Error("No witness file provided")
+		return 1
+	}
+
+	blob, err := os.ReadFile(c.file)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	var witness stateless.Witness
+	if err := rlp.DecodeBytes(blob, &witness); err != nil {
+		c.UI.Error(fmt.Sprintf("Invalid witness: %v", err))
+		return 1
+	}
+
+	headers := witness.PastHeaders()
+	if len(headers) == 0 {
+		c.UI.Error("Invalid witness: missing parent header")
+		return 1
+	}
+
+	parent := headers[0]
+	stateBytes, codeBytes := witness.Sizes()
+
+	c.UI.Output(formatKV([]string{
+		fmt.Sprintf("Pre-state block|%d", parent.Number.Uint64()),
+		fmt.Sprintf("Pre-state hash|%s", parent.Hash().Hex()),
+		fmt.Sprintf("Pre-state root|%s", parent.Root.Hex()),
+		fmt.Sprintf("Headers|%d", len(headers)),
+		fmt.Sprintf("Codes|%d (%d bytes)", len(witness.CodeHashes()), codeBytes),
+		fmt.Sprintf("State nodes|%d (%d bytes)", len(witness.StateNodes()), stateBytes),
+	}))
+
+	client, err := dialRPC(c.endpoint)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	local, err := ethclient.NewClient(client).HeaderByHash(ctx, parent.Hash())
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Pre-state block %s not found in the local chain: %v", parent.Hash().Hex(), err))
+		return 1
+	}
+
+	if local.Root != parent.Root {
+		c.UI.Error(fmt.Sprintf("Pre-state root mismatch (witness: %s local: %s)", parent.Root.Hex(), local.Root.Hex()))
+		return 1
+	}
+
+	c.UI.Output("\nPre-state matches the local chain")
+
+	if !c.execute {
+		return 0
+	}
+
+	var res eth.WitnessExecutionResult
+	if err := client.CallContext(ctx, &res, "debug_executeWitness", hexutil.Bytes(blob)); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Output(printWitnessExecution(&res))
+
+	if res.StateRoot != res.LocalStateRoot || res.ReceiptRoot != res.LocalReceiptRoot {
+		return 1
+	}
+
+	return 0
+}
+
+func printWitnessExecution(res *eth.WitnessExecutionResult) string {
+	roots := []string{
+		"Root|Witness|Local|Match",
+		fmt.Sprintf("State|%s|%s|%v", res.StateRoot.Hex(), res.LocalStateRoot.Hex(), res.StateRoot == res.LocalStateRoot),
+		fmt.Sprintf("Receipt|%s|%s|%v", res.ReceiptRoot.Hex(), res.LocalReceiptRoot.Hex(), res.ReceiptRoot == res.LocalReceiptRoot),
+	}
+
+	full := []string{
+		"\nExecuted Block",
+		formatKV([]string{
+			fmt.Sprintf("Number|%d", uint64(res.Number)),
+			fmt.Sprintf("Hash|%s", res.Hash.Hex()),
+		}),
+		"\nRoots",
+		formatList(roots),
+	}
+
+	return strings.Join(full, "\n")
+}
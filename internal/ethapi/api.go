@@ -732,11 +732,17 @@ func (api *BlockChainAPI) GetStorageAt(ctx context.Context, address common.Addre
 
 // GetBlockReceipts returns the block receipts for the given block hash or number or tag.
 func (api *BlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
-	block, err := api.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	return getBlockReceipts(ctx, api.b, blockNrOrHash)
+}
+
+// getBlockReceipts returns the receipts of the given block, followed by its state
+// sync receipt if any, marshalled like eth_getTransactionReceipt.
+func getBlockReceipts(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	block, err := b.BlockByNumberOrHash(ctx, blockNrOrHash)
 	if block == nil || err != nil {
 		return nil, err
 	}
-	receipts, err := api.b.GetReceipts(ctx, block.Hash())
+	receipts, err := b.GetReceipts(ctx, block.Hash())
 	if err != nil {
 		return nil, err
 	}
@@ -746,19 +752,19 @@ func (api *BlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rp
 	}
 
 	// Derive the sender.
-	signer := types.MakeSigner(api.b.ChainConfig(), block.Number(), block.Time())
+	signer := types.MakeSigner(b.ChainConfig(), block.Number(), block.Time())
 
 	result := make([]map[string]interface{}, len(receipts))
 	for i, receipt := range receipts {
 		result[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), signer, txs[i], i, false)
 	}
 
-	stateSyncReceipt, err := api.b.GetBorBlockReceipt(ctx, block.Hash())
+	stateSyncReceipt, err := b.GetBorBlockReceipt(ctx, block.Hash())
 	if err != nil && err != ethereum.NotFound {
 		return nil, err
 	}
 	if stateSyncReceipt != nil {
-		tx, _, _, _ := rawdb.ReadBorTransaction(api.b.ChainDb(), stateSyncReceipt.TxHash)
+		tx, _, _, _ := rawdb.ReadBorTransaction(b.ChainDb(), stateSyncReceipt.TxHash)
 		result = append(result, marshalReceipt(stateSyncReceipt, block.Hash(), block.NumberU64(), signer, tx, len(result), true))
 	}
 
@@ -1717,6 +1723,10 @@ func (api *TransactionAPI) GetTransactionByHash(ctx context.Context, hash common
 	if tx := api.b.GetPoolTransaction(hash); tx != nil {
 		return NewRPCPendingTransaction(tx, api.b.CurrentHeader(), api.b.ChainConfig()), nil
 	}
+	// If also not in the pool there is a chance the tx indexer is still in progress.
+	if !api.b.TxIndexDone() {
+		return nil, NewTxIndexingError()
+	}
 
 	// Transaction unknown, return as such
 	return nil, nil
@@ -1753,13 +1763,15 @@ func (api *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash commo
 			return nil, err
 		}
 		borTx = true
-		// // Make sure indexer is done.
-		// if !api.b.TxIndexDone() {
-		// 	return nil, NewTxIndexingError()
-		// }
 	}
 
 	if tx == nil {
+		// Make sure indexer is done, neither bor nor regular transactions are
+		// looked up below the index tail.
+		if !api.b.TxIndexDone() {
+			return nil, NewTxIndexingError()
+		}
+
 		return nil, nil
 	}
 
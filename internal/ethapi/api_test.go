@@ -432,11 +432,12 @@ func newTestAccountManager(t *testing.T) (*accounts.Manager, accounts.Account) {
 }
 
 type testBackend struct {
-	db      ethdb.Database
-	chain   *core.BlockChain
-	pending *types.Block
-	accman  *accounts.Manager
-	acc     accounts.Account
+	db         ethdb.Database
+	chain      *core.BlockChain
+	pending    *types.Block
+	accman     *accounts.Manager
+	acc        accounts.Account
+	borBlockTx bool
 }
 
 func newTestBackend(t *testing.T, n int, gspec *core.Genesis, engine consensus.Engine, generator func(i int, b *core.BlockGen)) *testBackend {
@@ -485,6 +486,7 @@ func (b testBackend) AccountManager() *accounts.Manager        { return b.accman
 func (b testBackend) ExtRPCEnabled() bool                      { return false }
 func (b testBackend) RPCGasCap() uint64                        { return 10000000 }
 func (b testBackend) RPCEVMTimeout() time.Duration             { return time.Second }
+func (b testBackend) RPCBorBlockTx() bool                      { return b.borBlockTx }
 func (b testBackend) RPCTxFeeCap() float64                     { return 0 }
 func (b testBackend) UnprotectedAllowed() bool                 { return false }
 func (b testBackend) SetHead(number uint64)                    {}
@@ -3943,6 +3945,102 @@ func TestRPCGetTransactionByBlockHashAndIndex(t *testing.T) {
 	testRPCResponseWithFile(t, 1, stateSyncTx, "eth_getTransactionByBlockHashAndIndex", "state-sync-tx")
 }
 
+func TestRPCGetBlockWithBorTx(t *testing.T) {
+	var (
+		api, txHashes, _ = setupTransactionsToApiTest(t)
+		backend          = api.b.(*testBackend)
+		chainAPI         = NewBlockChainAPI(backend)
+		block            = backend.CurrentBlock()
+		borTxHash        = txHashes[len(txHashes)-1]
+		stateReceiver    = common.HexToAddress("0x0000000000000000000000000000000000001001")
+	)
+	// Don't alter the bor config shared with the other tests
+	borConfig := *backend.ChainConfig().Bor
+	borConfig.StateReceiverContract = stateReceiver.Hex()
+	backend.ChainConfig().Bor = &borConfig
+
+	getBlocks := func(fullTx bool) []map[string]interface{} {
+		t.Helper()
+
+		byNumber, err := chainAPI.GetBlockByNumber(t.Context(), rpc.BlockNumber(block.Number.Int64()), fullTx)
+		if err != nil {
+			t.Fatalf("failed to get block by number: %v", err)
+		}
+		byHash, err := chainAPI.GetBlockByHash(t.Context(), block.Hash(), fullTx)
+		if err != nil {
+			t.Fatalf("failed to get block by hash: %v", err)
+		}
+		var blocks []map[string]interface{}
+		for _, response := range []map[string]interface{}{byNumber, byHash} {
+			data, err := json.Marshal(response)
+			if err != nil {
+				t.Fatalf("json marshal error: %v", err)
+			}
+			var decoded map[string]interface{}
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("json unmarshal error: %v", err)
+			}
+			if decoded["hash"] != block.Hash().Hex() {
+				t.Fatalf("block hash mismatch: have %v, want %v", decoded["hash"], block.Hash())
+			}
+			blocks = append(blocks, decoded)
+		}
+		return blocks
+	}
+
+	// The bor transaction is omitted when disabled
+	for _, fullTx := range []bool{false, true} {
+		for _, decoded := range getBlocks(fullTx) {
+			if txs := decoded["transactions"].([]interface{}); len(txs) != 1 {
+				t.Fatalf("fullTx=%v: transaction count mismatch: have %d, want 1", fullTx, len(txs))
+			}
+		}
+	}
+
+	// The bor transaction is appended when enabled
+	backend.borBlockTx = true
+
+	for _, decoded := range getBlocks(false) {
+		txs := decoded["transactions"].([]interface{})
+		if len(txs) != 2 || txs[1] != borTxHash.Hex() {
+			t.Fatalf("bor transaction hash not appended: %v", txs)
+		}
+	}
+	// The full bor transaction has no recipient, even with a state receiver configured,
+	// the same as in the eth_getTransactionByHash responses
+	byHash, err := api.GetTransactionByHash(t.Context(), borTxHash)
+	if err != nil {
+		t.Fatalf("failed to get bor transaction: %v", err)
+	}
+	if byHash == nil || byHash.To != nil {
+		t.Fatalf("unexpected bor transaction by hash: %+v", byHash)
+	}
+	for _, decoded := range getBlocks(true) {
+		txs := decoded["transactions"].([]interface{})
+		if len(txs) != 2 {
+			t.Fatalf("transaction count mismatch: have %d, want 2", len(txs))
+		}
+		borTx := txs[1].(map[string]interface{})
+		if to, ok := borTx["to"]; !ok || to != nil {
+			t.Errorf("bor transaction recipient mismatch: have %v, want null", to)
+		}
+		want := map[string]interface{}{
+			"hash":             borTxHash.Hex(),
+			"from":             common.Address{}.Hex(),
+			"gas":              "0x0",
+			"gasPrice":         "0x0",
+			"value":            "0x0",
+			"blockHash":        block.Hash().Hex(),
+			"transactionIndex": "0x1",
+		}
+		for field, value := range want {
+			if have := borTx[field]; !strings.EqualFold(fmt.Sprint(have), value.(string)) {
+				t.Errorf("bor transaction %s mismatch: have %v, want %v", field, have, value)
+			}
+		}
+	}
+}
+
 func testRPCResponseWithFile(t *testing.T, testid int, result interface{}, rpc string, file string) {
 	data, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
@@ -3998,6 +4096,50 @@ func TestRPCGetBlockReceipts(t *testing.T) {
 	}
 }
 
+// Tests that bor_getTransactionReceiptsByBlock returns the receipts of blocks with
+// and without state sync events, with the same fields as eth_getTransactionReceipt.
+func TestRPCBorGetTransactionReceiptsByBlock(t *testing.T) {
+	api, txHashes, _ := setupTransactionsToApiTest(t)
+	borAPI := NewBorAPI(api.b)
+
+	head := api.b.CurrentBlock()
+	tests := []struct {
+		blockNrOrHash rpc.BlockNumberOrHash
+		txHashes      []common.Hash
+	}{
+		// Block without state sync events
+		{rpc.BlockNumberOrHashWithNumber(1), txHashes[:1]},
+		// Latest block, with a state sync event
+		{rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), txHashes[len(txHashes)-2:]},
+		// Same block by hash
+		{rpc.BlockNumberOrHashWithHash(head.Hash(), false), txHashes[len(txHashes)-2:]},
+	}
+
+	for i, tt := range tests {
+		receipts, err := borAPI.GetTransactionReceiptsByBlock(t.Context(), tt.blockNrOrHash)
+		require.NoError(t, err, "test %d", i)
+		require.Len(t, receipts, len(tt.txHashes), "test %d", i)
+
+		for j, txHash := range tt.txHashes {
+			want, err := api.GetTransactionReceipt(t.Context(), txHash)
+			require.NoError(t, err, "test %d, receipt %d", i, j)
+
+			wantJSON, err := json.Marshal(want)
+			require.NoError(t, err)
+
+			haveJSON, err := json.Marshal(receipts[j])
+			require.NoError(t, err)
+
+			require.JSONEq(t, string(wantJSON), string(haveJSON), "test %d, receipt %d", i, j)
+		}
+	}
+
+	// Unknown blocks have no receipts
+	receipts, err := borAPI.GetTransactionReceiptsByBlock(t.Context(), rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(head.Number.Int64()+1)))
+	require.NoError(t, err)
+	require.Nil(t, receipts)
+}
+
 func setupBlocksToApiTest(t *testing.T) (*BlockChainAPI, rpc.BlockNumberOrHash, []struct {
 	txHash common.Hash
 	want   string
@@ -51,6 +51,7 @@ type Backend interface {
 	ExtRPCEnabled() bool
 	RPCGasCap() uint64             // global gas cap for eth_call over rpc: DoS protection
 	RPCRpcReturnDataLimit() uint64 // Maximum size (in bytes) a result of an rpc request could have
+	RPCBorBlockTx() bool           // appends the bor transaction to the transactions of the blocks
 	RPCEVMTimeout() time.Duration  // global timeout for eth_call over rpc: DoS protection
 	RPCTxFeeCap() float64          // global tx fee cap for all transaction related APIs
 	UnprotectedAllowed() bool      // allows only for EIP155 transactions.
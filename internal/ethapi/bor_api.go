@@ -31,8 +31,12 @@ func (s *BlockChainAPI) GetVoteOnHash(ctx context.Context, starBlockNr uint64, e
 // Bor transaction utils
 //
 
+// appendRPCMarshalBorTransaction appends the bor transaction of the block, if it has a
+// bor receipt and the node is configured to, to the transactions of its rpc response.
+// The transaction is synthetic: it is sent from the zero address, has no recipient and
+// uses no gas, the same as in the eth_getTransactionByHash responses.
 func (s *BlockChainAPI) appendRPCMarshalBorTransaction(ctx context.Context, block *types.Block, fields map[string]interface{}, fullTx bool) map[string]interface{} {
-	if block != nil {
+	if block != nil && s.b.RPCBorBlockTx() {
 		txHash := types.GetDerivedBorTxHash(types.BorReceiptKey(block.Number().Uint64(), block.Hash()))
 
 		borTx, blockHash, blockNumber, txIndex, _ := s.b.GetBorBlockTransactionWithBlockHash(ctx, txHash, block.Hash())
@@ -45,6 +49,7 @@ func (s *BlockChainAPI) appendRPCMarshalBorTransaction(ctx context.Context, bloc
 				// In case of bor block tx, we need simple derived tx hash (same as function argument) instead of RLP hash
 				marshalledTx.Hash = txHash
 				marshalledTx.ChainID = nil
+
 				fields["transactions"] = append(formattedTxs, marshalledTx)
 			} else {
 				fields["transactions"] = append(formattedTxs, txHash)
@@ -106,6 +111,14 @@ func (api *BorAPI) SendRawTransactionConditional(ctx context.Context, input hexu
 	return SubmitTransaction(ctx, api.b, tx)
 }
 
+// GetTransactionReceiptsByBlock returns all the receipts of the given block, including
+// its state sync receipt if any, in a single call. The receipts have the same fields
+// as the ones of eth_getTransactionReceipt, and the finalized tag resolves to the
+// latest block finalized by a milestone.
+func (api *BorAPI) GetTransactionReceiptsByBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	return getBlockReceipts(ctx, api.b, blockNrOrHash)
+}
+
 func (api *BorAPI) GetVoteOnHash(ctx context.Context, starBlockNr uint64, endBlockNr uint64, hash string, milestoneId string) (bool, error) {
 	return api.b.GetVoteOnHash(ctx, starBlockNr, endBlockNr, hash, milestoneId)
 }
@@ -0,0 +1,33 @@
+package testtx
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// forgedSenderSigner is a signer recovering all transactions to the given sender. It's
+// equal to the signer it wraps, so that the forged sender ends up in the sender cache
+// of the transactions.
+type forgedSenderSigner struct {
+	types.Signer
+	from common.Address
+}
+
+func (s forgedSenderSigner) Sender(*types.Transaction) (common.Address, error) {
+	return s.from, nil
+}
+
+// ForgeSender makes the transaction recover to the given sender with the given signer,
+// e.g. to impersonate addresses no key is known for.
+func ForgeSender(t testing.TB, tx *types.Transaction, signer types.Signer, from common.Address) {
+	t.Helper()
+
+	if _, err := types.Sender(forgedSenderSigner{Signer: signer, from: from}, tx); err != nil {
+		t.Fatal(err)
+	}
+	if sender, _ := types.Sender(signer, tx); sender != from {
+		t.Fatalf("sender not forged: have %v, want %v", sender, from)
+	}
+}
@@ -71,6 +71,57 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null]
 		}),
+		new web3._extend.Method({
+			name: 'getStateSyncTxByL1Hash',
+			call: 'bor_getStateSyncTxByL1Hash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getStateSyncEventsByBlockRange',
+			call: 'bor_getStateSyncEventsByBlockRange',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getStateSyncEventsByBlock',
+			call: 'bor_getStateSyncEventsByBlock',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getValidatorsAtBlock',
+			call: 'bor_getValidatorsAtBlock',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'getSpanConflicts',
+			call: 'bor_getSpanConflicts',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getPendingStateSyncEvents',
+			call: 'bor_getPendingStateSyncEvents',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'getProducerDelay',
+			call: 'bor_getProducerDelay',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionReceiptsByBlock',
+			call: 'bor_getTransactionReceiptsByBlock',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'heimdallStatus',
+			call: 'bor_heimdallStatus',
+			params: 0
+		}),
 	]
 });
 `
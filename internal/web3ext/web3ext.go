@@ -434,6 +434,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'traceBorBlock',
+			call: 'debug_traceBorBlock',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'traceTransaction',
 			call: 'debug_traceTransaction',
@@ -457,6 +463,11 @@ web3._extend({
 			call: 'debug_getBadBlocks',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'getBadBlockWitness',
+			call: 'debug_getBadBlockWitness',
+			params: 1,
+		}),
 		new web3._extend.Method({
 			name: 'storageRangeAt',
 			call: 'debug_storageRangeAt',
@@ -540,6 +551,32 @@ web3._extend({
 			call: 'debug_peerStats',
 			params: 0
 		}),
+		new web3._extend.Method({
+			name: 'getBlockExecutionMode',
+			call: 'debug_getBlockExecutionMode',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'heimdallErrors',
+			call: 'debug_heimdallErrors',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'exportWitness',
+			call: 'debug_exportWitness',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'executeWitness',
+			call: 'debug_executeWitness',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'witnessUnusedNodes',
+			call: 'debug_witnessUnusedNodes',
+			params: 1
+		}),
 	],
 	properties: []
 });
@@ -0,0 +1,156 @@
+package miner
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/bor"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	emptyBlocksAlways    = "always"
+	emptyBlocksSkip      = "skip"
+	emptyBlocksHeartbeat = "heartbeat:"
+
+	// pendingStateSyncTimeout is the maximum time spent checking heimdall for pending
+	// state sync events before deciding whether to skip an empty block.
+	pendingStateSyncTimeout = 5 * time.Second
+)
+
+// EmptyBlockPolicy defines whether blocks without transactions are sealed.
+type EmptyBlockPolicy struct {
+	Skip      bool          // Don't seal blocks without transactions, unless they start a sprint with pending state sync events
+	Heartbeat time.Duration // If skipping, still seal an empty block once this time passed since the parent (0 = never)
+}
+
+// ParseEmptyBlockPolicy parses an empty block policy, which is one of "always",
+// "skip" or "heartbeat:N" with N the number of seconds between empty blocks.
+func ParseEmptyBlockPolicy(s string) (EmptyBlockPolicy, error) {
+	switch {
+	case s == "" || s == emptyBlocksAlways:
+		return EmptyBlockPolicy{}, nil
+
+	case s == emptyBlocksSkip:
+		return EmptyBlockPolicy{Skip: true}, nil
+
+	case strings.HasPrefix(s, emptyBlocksHeartbeat):
+		seconds, err := strconv.ParseUint(strings.TrimPrefix(s, emptyBlocksHeartbeat), 10, 32)
+		if err != nil || seconds == 0 {
+			return EmptyBlockPolicy{}, fmt.Errorf("invalid empty block heartbeat %q: must be a positive number of seconds", s)
+		}
+
+		return EmptyBlockPolicy{Skip: true, Heartbeat: time.Duration(seconds) * time.Second}, nil
+	}
+
+	return EmptyBlockPolicy{}, fmt.Errorf("invalid empty block policy %q: must be %q, %q or %q", s, emptyBlocksAlways, emptyBlocksSkip, emptyBlocksHeartbeat+"N")
+}
+
+// String implements the stringer interface.
+func (p EmptyBlockPolicy) String() string {
+	switch {
+	case !p.Skip:
+		return emptyBlocksAlways
+	case p.Heartbeat > 0:
+		return emptyBlocksHeartbeat + strconv.FormatUint(uint64(p.Heartbeat/time.Second), 10)
+	default:
+		return emptyBlocksSkip
+	}
+}
+
+// emptyBlockPolicy returns the empty block policy to apply on the given chain. Empty
+// blocks can only be skipped on bor chains allowing it, as it leaves gaps in block times.
+func emptyBlockPolicy(policy EmptyBlockPolicy, chainConfig *params.ChainConfig) EmptyBlockPolicy {
+	if !policy.Skip {
+		return policy
+	}
+
+	if chainConfig.Bor == nil || !chainConfig.Bor.SkipEmptyBlocks {
+		log.Warn("Empty blocks can't be skipped on this chain, sealing them", "policy", policy)
+		return EmptyBlockPolicy{}
+	}
+
+	log.Info("Skipping empty blocks", "policy", policy)
+
+	return policy
+}
+
+// skipEmptyBlock reports whether the sealing of the block being built in the given
+// environment should be skipped according to the empty block policy. Only sprint start
+// blocks can commit state sync events, so heimdall is only asked for pending events for
+// them, the other empty blocks are skipped right away.
+func (w *worker) skipEmptyBlock(env *environment) bool {
+	if !w.emptyBlocks.Skip || env.tcount > 0 {
+		return false
+	}
+
+	borEngine, ok := w.engine.(*bor.Bor)
+	if !ok {
+		return false
+	}
+
+	number := env.header.Number.Uint64()
+
+	if w.emptyBlocks.Heartbeat > 0 {
+		parent := w.chain.GetHeader(env.header.ParentHash, number-1)
+		if parent != nil && time.Duration(env.header.Time-parent.Time)*time.Second >= w.emptyBlocks.Heartbeat {
+			return false
+		}
+	}
+
+	if !w.chainConfig.Bor.IsSprintStart(number) {
+		return true
+	}
+
+	// Seal the sprint start block if it commits pending state sync events
+	ctx, cancel := context.WithTimeout(context.Background(), pendingStateSyncTimeout)
+	defer cancel()
+
+	pending, err := borEngine.HasPendingStateSyncEvents(ctx, env.state, env.header)
+	if err != nil {
+		log.Debug("Failed to check pending state sync events, sealing empty block", "number", number, "err", err)
+		return false
+	}
+
+	return !pending
+}
+
+// trackSkippedEmptyBlock records whether the block built in the given environment was
+// skipped for being empty. A skipped block is rebuilt as soon as transactions arrive, or
+// once its heartbeat deadline is reached.
+func (w *worker) trackSkippedEmptyBlock(env *environment, skipped bool) {
+	if !w.emptyBlocks.Skip {
+		return
+	}
+
+	w.emptySkipped.Store(skipped)
+
+	if w.emptyHeartbeat != nil {
+		w.emptyHeartbeat.Stop()
+		w.emptyHeartbeat = nil
+	}
+
+	if !skipped || w.emptyBlocks.Heartbeat == 0 {
+		return
+	}
+
+	parent := w.chain.GetHeader(env.header.ParentHash, env.header.Number.Uint64()-1)
+	if parent == nil {
+		return
+	}
+
+	deadline := time.Unix(int64(parent.Time), 0).Add(w.emptyBlocks.Heartbeat)
+	w.emptyHeartbeat = time.AfterFunc(time.Until(deadline), w.rebuildEmptyBlock)
+}
+
+// rebuildEmptyBlock requests the new work loop to rebuild the skipped empty block.
+func (w *worker) rebuildEmptyBlock() {
+	select {
+	case w.emptyBlockCh <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,184 @@
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/consensus/bor/clerk"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestParseEmptyBlockPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input  string
+		policy EmptyBlockPolicy
+		err    bool
+	}{
+		{input: "", policy: EmptyBlockPolicy{}},
+		{input: "always", policy: EmptyBlockPolicy{}},
+		{input: "skip", policy: EmptyBlockPolicy{Skip: true}},
+		{input: "heartbeat:30", policy: EmptyBlockPolicy{Skip: true, Heartbeat: 30 * time.Second}},
+		{input: "heartbeat:0", err: true},
+		{input: "heartbeat:", err: true},
+		{input: "heartbeat:1m", err: true},
+		{input: "never", err: true},
+	}
+
+	for _, test := range tests {
+		policy, err := ParseEmptyBlockPolicy(test.input)
+		if test.err {
+			require.Error(t, err, test.input)
+			continue
+		}
+
+		require.NoError(t, err, test.input)
+		require.Equal(t, test.policy, policy, test.input)
+
+		if test.input != "" {
+			require.Equal(t, test.input, policy.String())
+		}
+	}
+}
+
+func TestEmptyBlockPolicyChainConfig(t *testing.T) {
+	t.Parallel()
+
+	policy := EmptyBlockPolicy{Skip: true}
+
+	// Skipping empty blocks must be allowed by the chain config
+	require.Equal(t, EmptyBlockPolicy{}, emptyBlockPolicy(policy, params.BorUnittestChainConfig))
+	require.Equal(t, EmptyBlockPolicy{}, emptyBlockPolicy(policy, params.TestChainConfig))
+	require.Equal(t, policy, emptyBlockPolicy(policy, skipEmptyBlocksChainConfig()))
+}
+
+// skipEmptyBlocksChainConfig returns a single validator bor devnet config allowing to
+// skip empty blocks.
+func skipEmptyBlocksChainConfig() *params.ChainConfig {
+	chainConfig := *params.BorUnittestChainConfig
+	borConfig := *chainConfig.Bor
+	borConfig.SkipEmptyBlocks = true
+	chainConfig.Bor = &borConfig
+
+	return &chainConfig
+}
+
+// mineWithEmptyBlockPolicy starts mining on a single validator bor devnet with the given
+// empty block policy and returns the subscription to the mined blocks.
+func mineWithEmptyBlockPolicy(t *testing.T, policy string, events []*clerk.EventRecordWithTime) (*worker, *testWorkerBackend, *event.TypeMuxSubscription) {
+	t.Helper()
+
+	emptyBlocks, err := ParseEmptyBlockPolicy(policy)
+	require.NoError(t, err)
+
+	// Skipped empty blocks must be rebuilt without waiting for the recommit timer
+	config := DefaultTestConfig()
+	config.EmptyBlocks = emptyBlocks
+	config.Recommit = time.Hour
+
+	chainConfig := skipEmptyBlocksChainConfig()
+
+	engine, ctrl := getFakeBorWithStateSyncEvents(t, chainConfig, events)
+	t.Cleanup(func() {
+		engine.Close()
+		ctrl.Finish()
+	})
+
+	w, b, _ := newTestWorker(t, config, chainConfig, engine, rawdb.NewMemoryDatabase(), false, 0)
+	t.Cleanup(w.close)
+
+	sub := w.mux.Subscribe(core.NewMinedBlockEvent{})
+	t.Cleanup(sub.Unsubscribe)
+
+	w.start()
+
+	return w, b, sub
+}
+
+// waitMinedBlock returns the next mined block, or nil if none is mined in time.
+func waitMinedBlock(sub *event.TypeMuxSubscription, timeout time.Duration) *types.Block {
+	select {
+	case ev := <-sub.Chan():
+		return ev.Data.(core.NewMinedBlockEvent).Block
+	case <-time.After(timeout):
+		return nil
+	}
+}
+
+// nolint : paralleltest
+func TestEmptyBlocksAlways(t *testing.T) {
+	_, _, sub := mineWithEmptyBlockPolicy(t, "always", nil)
+
+	for i := uint64(1); i <= 2; i++ {
+		block := waitMinedBlock(sub, 5*time.Second)
+		require.NotNil(t, block, "empty block %d not mined", i)
+		require.Equal(t, i, block.NumberU64())
+		require.Empty(t, block.Transactions())
+	}
+}
+
+// nolint : paralleltest
+func TestEmptyBlocksSkip(t *testing.T) {
+	_, b, sub := mineWithEmptyBlockPolicy(t, "skip", nil)
+
+	require.Nil(t, waitMinedBlock(sub, 3*time.Second), "empty block mined")
+
+	err := b.txPool.Add([]*types.Transaction{b.newRandomTxWithNonce(false, 0)}, false)[0]
+	require.NoError(t, err)
+
+	block := waitMinedBlock(sub, 5*time.Second)
+	require.NotNil(t, block, "block with transaction not mined")
+	require.Equal(t, uint64(1), block.NumberU64())
+	require.Len(t, block.Transactions(), 1)
+
+	require.Nil(t, waitMinedBlock(sub, 3*time.Second), "empty block mined")
+}
+
+// nolint : paralleltest
+func TestEmptyBlocksSkipPendingStateSync(t *testing.T) {
+	events := []*clerk.EventRecordWithTime{{EventRecord: clerk.EventRecord{ID: 1}}}
+
+	w, _, sub := mineWithEmptyBlockPolicy(t, "skip", events)
+
+	// Only sprint start blocks commit state sync events, the others are still skipped
+	require.Nil(t, waitMinedBlock(sub, 3*time.Second), "empty block mined before the sprint start")
+
+	state, err := w.chain.State()
+	require.NoError(t, err)
+
+	genesis := w.chain.Genesis()
+	sprint := w.chainConfig.Bor.CalculateSprint(0)
+
+	for _, number := range []uint64{sprint - 1, sprint, 2*sprint + 1} {
+		env := &environment{
+			state:  state,
+			header: &types.Header{Number: new(big.Int).SetUint64(number), ParentHash: genesis.Hash(), Time: genesis.Time() + number},
+		}
+		require.Equal(t, !w.chainConfig.Bor.IsSprintStart(number), w.skipEmptyBlock(env), "block %d", number)
+	}
+}
+
+// nolint : paralleltest
+func TestEmptyBlocksHeartbeat(t *testing.T) {
+	const heartbeat = 2
+
+	w, _, sub := mineWithEmptyBlockPolicy(t, "heartbeat:2", nil)
+
+	for i := uint64(1); i <= 3; i++ {
+		block := waitMinedBlock(sub, 2*heartbeat*time.Second+3*time.Second)
+		require.NotNil(t, block, "empty block %d not mined", i)
+		require.Equal(t, i, block.NumberU64())
+		require.Empty(t, block.Transactions())
+
+		parent := w.chain.GetHeaderByHash(block.ParentHash())
+		require.GreaterOrEqual(t, block.Time()-parent.Time, uint64(heartbeat))
+	}
+}
@@ -58,6 +58,7 @@ func NewBorDefaultMiner(t *testing.T) *DefaultBorMiner {
 	heimdallWSClient := mocks.NewMockIHeimdallWSClient(ctrl)
 	heimdallClient.EXPECT().GetSpan(gomock.Any(), uint64(0)).Return(&span0, nil).AnyTimes()
 	heimdallClient.EXPECT().Close().Times(1)
+	heimdallWSClient.EXPECT().Close().Return(nil).Times(1)
 
 	genesisContracts := bor.NewMockGenesisContract(ctrl)
 
@@ -54,6 +54,9 @@ type Config struct {
 	CommitInterruptFlag bool           // Interrupt commit when time is up ( default = true)
 
 	NewPayloadTimeout time.Duration // The maximum time allowance for creating a new payload
+
+	EmptyBlocks    EmptyBlockPolicy // Whether blocks without transactions are sealed (bor only)
+	PrefetchBlocks uint64           // Blocks before a sprint start its heimdall data is prefetched, 0 to disable (bor only)
 }
 
 // DefaultConfig contains default settings for miner.
@@ -232,6 +232,7 @@ type worker struct {
 	exitCh             chan struct{}
 	resubmitIntervalCh chan time.Duration
 	resubmitAdjustCh   chan *intervalAdjust
+	emptyBlockCh       chan struct{} // Requests to rebuild the skipped empty block
 
 	wg sync.WaitGroup
 
@@ -286,6 +287,10 @@ type worker struct {
 	// in this case this feature will add all empty blocks into canonical chain
 	// non-stop and no real transaction will be included.
 	noempty atomic.Bool
+
+	emptyBlocks    EmptyBlockPolicy // Whether blocks without transactions are sealed
+	emptySkipped   atomic.Bool      // Whether the last block built was skipped for being empty
+	emptyHeartbeat *time.Timer      // Rebuilds the skipped empty block at its heartbeat deadline
 }
 
 //nolint:staticcheck
@@ -312,7 +317,9 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		exitCh:              make(chan struct{}),
 		resubmitIntervalCh:  make(chan time.Duration),
 		resubmitAdjustCh:    make(chan *intervalAdjust, resubmitAdjustChanSize),
+		emptyBlockCh:        make(chan struct{}, 1),
 		interruptCommitFlag: config.CommitInterruptFlag,
+		emptyBlocks:         emptyBlockPolicy(config.EmptyBlocks, chainConfig),
 	}
 	worker.noempty.Store(true)
 	// Subscribe for transaction insertion events (whether from network or resurrects)
@@ -531,6 +538,11 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 		case head := <-w.chainHeadCh:
 			clearPending(head.Header.Number.Uint64())
 
+			// Fetch the heimdall data of the upcoming sprint start ahead of building it
+			if borEngine, ok := w.engine.(*bor.Bor); ok && w.IsRunning() {
+				borEngine.PrefetchSprintStart(head.Header, w.config.PrefetchBlocks)
+			}
+
 			timestamp = time.Now().Unix()
 			commit(false, commitInterruptNewHead)
 
@@ -546,6 +558,12 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 				commit(true, commitInterruptResubmit)
 			}
 
+		case <-w.emptyBlockCh:
+			// Rebuild the skipped empty block, on new transactions or at its heartbeat
+			if w.IsRunning() {
+				commit(true, commitInterruptResubmit)
+			}
+
 		case interval := <-w.resubmitIntervalCh:
 			// Adjust resubmit interval explicitly by user.
 			if interval < minRecommitInterval {
@@ -676,6 +694,11 @@ func (w *worker) mainLoop() {
 
 			w.newTxs.Add(int32(len(ev.Txs)))
 
+			// Rebuild the skipped empty block right away to include them
+			if w.emptySkipped.Load() {
+				w.rebuildEmptyBlock()
+			}
+
 		// System stopped
 		case <-w.exitCh:
 			return
@@ -1578,7 +1601,12 @@ func createInterruptTimer(number, timestamp uint64, interruptBlockBuilding *atom
 // Note the assumption is held that the mutation is allowed to the passed env, do
 // the deep copy first.
 func (w *worker) commit(env *environment, interval func(), update bool, start time.Time) error {
-	if w.IsRunning() {
+	skipped := w.IsRunning() && w.skipEmptyBlock(env)
+	w.trackSkippedEmptyBlock(env, skipped)
+
+	if skipped {
+		log.Debug("Skipping empty block", "number", env.header.Number, "policy", w.emptyBlocks)
+	} else if w.IsRunning() {
 		if interval != nil {
 			interval()
 		}
@@ -28,10 +28,12 @@ import (
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/bor"
 	"github.com/ethereum/go-ethereum/consensus/bor/api"
+	"github.com/ethereum/go-ethereum/consensus/bor/clerk"
 	"github.com/ethereum/go-ethereum/consensus/bor/valset"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/blockstm"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
@@ -41,6 +43,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/tests/bor/mocks"
 	"github.com/ethereum/go-ethereum/triedb"
@@ -370,6 +373,14 @@ func TestGenerateAndImportBlock(t *testing.T) {
 func getFakeBorFromConfig(t *testing.T, chainConfig *params.ChainConfig) (consensus.Engine, *gomock.Controller) {
 	t.Helper()
 
+	return getFakeBorWithStateSyncEvents(t, chainConfig, nil)
+}
+
+// getFakeBorWithStateSyncEvents returns a fake bor engine for which heimdall reports the
+// given state sync events as pending.
+func getFakeBorWithStateSyncEvents(t *testing.T, chainConfig *params.ChainConfig, events []*clerk.EventRecordWithTime) (consensus.Engine, *gomock.Controller) {
+	t.Helper()
+
 	ctrl := gomock.NewController(t)
 
 	ethAPIMock := api.NewMockCaller(ctrl)
@@ -385,9 +396,12 @@ func getFakeBorFromConfig(t *testing.T, chainConfig *params.ChainConfig) (consen
 	heimdallWSClient := mocks.NewMockIHeimdallWSClient(ctrl)
 
 	heimdallClientMock.EXPECT().GetSpan(gomock.Any(), uint64(0)).Return(&span0, nil).AnyTimes()
+	heimdallClientMock.EXPECT().StateSyncEvents(gomock.Any(), gomock.Any(), gomock.Any()).Return(events, nil).AnyTimes()
 	heimdallClientMock.EXPECT().Close().AnyTimes()
+	heimdallWSClient.EXPECT().Close().Return(nil).AnyTimes()
 
 	contractMock := bor.NewMockGenesisContract(ctrl)
+	contractMock.EXPECT().LastStateId(gomock.Any(), gomock.Any(), gomock.Any()).Return(big.NewInt(0), nil).AnyTimes()
 
 	db, _, _ := NewDBForFakes(t)
 
@@ -998,6 +1012,7 @@ func BenchmarkBorMining(b *testing.B) {
 	heimdallWSClient := mocks.NewMockIHeimdallWSClient(ctrl)
 
 	heimdallClientMock.EXPECT().Close().Times(1)
+	heimdallWSClient.EXPECT().Close().Return(nil).Times(1)
 
 	contractMock := bor.NewMockGenesisContract(ctrl)
 
@@ -1097,6 +1112,7 @@ func BenchmarkBorMiningBlockSTMMetadata(b *testing.B) {
 	heimdallWSClient := mocks.NewMockIHeimdallWSClient(ctrl)
 
 	heimdallClientMock.EXPECT().Close().Times(1)
+	heimdallWSClient.EXPECT().Close().Return(nil).Times(1)
 
 	contractMock := bor.NewMockGenesisContract(ctrl)
 
@@ -1114,7 +1130,7 @@ func BenchmarkBorMiningBlockSTMMetadata(b *testing.B) {
 	db2 := rawdb.NewMemoryDatabase()
 	back.genesis.MustCommit(db2, triedb.NewDatabase(db2, triedb.HashDefaults))
 
-	chain, _ := core.NewParallelBlockChain(db2, nil, back.genesis, nil, engine, vm.Config{}, nil, nil, nil, 8, false)
+	chain, _ := core.NewParallelBlockChain(db2, nil, back.genesis, nil, engine, vm.Config{}, nil, nil, nil, 8, false, 0, blockstm.DefaultMVHashMapShards)
 	defer chain.Stop()
 
 	// Ignore empty commit here for less noise.
@@ -1196,3 +1212,92 @@ func BenchmarkBorMiningBlockSTMMetadata(b *testing.B) {
 		}
 	}
 }
+
+// nolint : paralleltest
+// TestPrefetchSprintStart tests that the state sync events of a sprint start are fetched
+// from a slow heimdall ahead of building it, leaving only local data to the sealing path.
+func TestPrefetchSprintStart(t *testing.T) {
+	const fetchDelay = 500 * time.Millisecond
+
+	chainConfig := *params.BorUnittestChainConfig
+	borConfig := *chainConfig.Bor
+	borConfig.Sprint = map[string]uint64{"0": 4}
+	borConfig.IndoreBlock = big.NewInt(0)
+	borConfig.StateSyncConfirmationDelay = map[string]uint64{"0": 20}
+	chainConfig.Bor = &borConfig
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ethAPIMock := api.NewMockCaller(ctrl)
+	ethAPIMock.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	span0 := createMockSpanForTest(TestBankAddress, chainConfig.ChainID.String())
+
+	spanner := bor.NewMockSpanner(ctrl)
+	spanner.EXPECT().GetCurrentValidatorsByHash(gomock.Any(), gomock.Any(), gomock.Any()).Return(borSpan.ConvertHeimdallValidatorsToBorValidatorsByRef(span0.ValidatorSet.Validators), nil).AnyTimes()
+	spanner.EXPECT().GetCurrentSpan(gomock.Any(), gomock.Any()).Return(&span0, nil).AnyTimes()
+
+	events := []*clerk.EventRecordWithTime{{
+		EventRecord: clerk.EventRecord{ID: 1, ChainID: chainConfig.ChainID.String()},
+		Time:        time.Now().Add(-time.Minute),
+	}}
+
+	// Heimdall takes a while to serve the state sync events
+	var fetches atomic.Int32
+
+	heimdallClientMock := mocks.NewMockIHeimdallClient(ctrl)
+	heimdallClientMock.EXPECT().GetSpan(gomock.Any(), uint64(0)).Return(&span0, nil).AnyTimes()
+	heimdallClientMock.EXPECT().StateSyncEvents(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ interface{}, _ uint64, _ int64) ([]*clerk.EventRecordWithTime, error) {
+		fetches.Add(1)
+		time.Sleep(fetchDelay)
+
+		return events, nil
+	}).AnyTimes()
+	heimdallClientMock.EXPECT().Close().AnyTimes()
+
+	heimdallWSClient := mocks.NewMockIHeimdallWSClient(ctrl)
+	heimdallWSClient.EXPECT().Close().Return(nil).AnyTimes()
+
+	var commits atomic.Int32
+
+	contractMock := bor.NewMockGenesisContract(ctrl)
+	contractMock.EXPECT().LastStateId(gomock.Any(), gomock.Any(), gomock.Any()).Return(big.NewInt(0), nil).AnyTimes()
+	contractMock.EXPECT().CommitState(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, _, _, _ interface{}) (uint64, error) {
+		commits.Add(1)
+		return 0, nil
+	}).AnyTimes()
+
+	db, _, _ := NewDBForFakes(t)
+
+	engine := NewFakeBor(t, db, &chainConfig, ethAPIMock, spanner, heimdallClientMock, heimdallWSClient, contractMock)
+	defer engine.Close()
+
+	config := DefaultTestConfig()
+	config.PrefetchBlocks = 2
+
+	w, _, _ := newTestWorker(t, config, &chainConfig, engine, rawdb.NewMemoryDatabase(), false, 0)
+	defer w.close()
+
+	sub := w.mux.Subscribe(core.NewMinedBlockEvent{})
+	defer sub.Unsubscribe()
+
+	hits := metrics.GetOrRegisterCounter("bor/prefetch/hit", nil).Snapshot().Count()
+
+	w.start()
+
+	// Mine up to the first sprint start after genesis
+	for {
+		block := waitMinedBlock(sub, 5*time.Second)
+		require.NotNil(t, block, "block not mined")
+
+		if block.NumberU64() >= 4 {
+			break
+		}
+	}
+
+	// The events were only fetched by the prefetch, and committed by the sprint start
+	require.Equal(t, int32(1), fetches.Load(), "state sync events fetched while building the sprint start")
+	require.Positive(t, commits.Load(), "state sync events not committed")
+	require.Greater(t, metrics.GetOrRegisterCounter("bor/prefetch/hit", nil).Snapshot().Count(), hits)
+}
@@ -220,6 +220,7 @@ func (api *adminAPI) StartHTTP(host *string, port *int, cors *string, apis *stri
 		rpcEndpointConfig: rpcEndpointConfig{
 			batchItemLimit:         api.node.config.BatchRequestLimit,
 			batchResponseSizeLimit: api.node.config.BatchResponseMaxSize,
+			methodTimeouts:         api.node.config.RPCMethodTimeouts,
 		},
 	}
 	if cors != nil {
@@ -305,6 +306,7 @@ func (api *adminAPI) StartWS(host *string, port *int, allowedOrigins *string, ap
 		rpcEndpointConfig: rpcEndpointConfig{
 			batchItemLimit:         api.node.config.BatchRequestLimit,
 			batchResponseSizeLimit: api.node.config.BatchResponseMaxSize,
+			methodTimeouts:         api.node.config.RPCMethodTimeouts,
 		},
 	}
 	if apis != nil {
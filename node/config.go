@@ -219,6 +219,8 @@ type Config struct {
 
 	// Maximum number of messages in a batch
 	RPCBatchLimit uint64 `toml:",omitempty"`
+	// Execution timeouts of the RPC methods, keyed by method name or namespace
+	RPCMethodTimeouts map[string]time.Duration `toml:",omitempty"`
 	// Configs for RPC execution pool
 	WSJsonRPCExecutionPoolSize             uint64        `toml:",omitempty"`
 	WSJsonRPCExecutionPoolRequestTimeout   time.Duration `toml:",omitempty"`
@@ -112,6 +112,7 @@ func New(conf *Config) (*Node, error) {
 	}
 	server := rpc.NewServer("", 0, 0)
 	server.SetBatchLimits(conf.BatchRequestLimit, conf.BatchResponseMaxSize)
+	server.SetMethodTimeouts(conf.RPCMethodTimeouts)
 	node := &Node{
 		config:        conf,
 		inprocHandler: server,
@@ -422,6 +423,7 @@ func (n *Node) startRPC() error {
 	rpcConfig := rpcEndpointConfig{
 		batchItemLimit:         n.config.BatchRequestLimit,
 		batchResponseSizeLimit: n.config.BatchResponseMaxSize,
+		methodTimeouts:         n.config.RPCMethodTimeouts,
 	}
 
 	initHttp := func(server *httpServer, port int) error {
@@ -64,6 +64,7 @@ type rpcEndpointConfig struct {
 	batchItemLimit         int
 	batchResponseSizeLimit int
 	httpBodyLimit          int
+	methodTimeouts         map[string]time.Duration // execution timeouts by method or namespace
 }
 
 type rpcHandler struct {
@@ -333,6 +334,7 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 	srv.SetRPCBatchLimit(h.RPCBatchLimit)
 
 	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
+	srv.SetMethodTimeouts(config.methodTimeouts)
 	if config.httpBodyLimit > 0 {
 		srv.SetHTTPBodyLimit(config.httpBodyLimit)
 	}
@@ -373,6 +375,7 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	srv.SetRPCBatchLimit(h.RPCBatchLimit)
 
 	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
+	srv.SetMethodTimeouts(config.methodTimeouts)
 	if config.httpBodyLimit > 0 {
 		srv.SetHTTPBodyLimit(config.httpBodyLimit)
 	}
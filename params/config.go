@@ -839,6 +839,12 @@ type BorConfig struct {
 	StateSyncConfirmationDelay      map[string]uint64      `json:"stateSyncConfirmationDelay"` // StateSync Confirmation Delay, in seconds, to calculate `to`
 	AhmedabadBlock                  *big.Int               `json:"ahmedabadBlock"`             // Ahmedabad switch block (nil = no fork, 0 = already on ahmedabad)
 	BhilaiBlock                     *big.Int               `json:"bhilaiBlock"`                // Bhilai switch block (nil = no fork, 0 = already on bhilai)
+	StateSyncMaxDataSize            map[string]uint64      `json:"stateSyncMaxDataSize"`       // Maximum size, in bytes, of the data of a state sync event (0 = no limit)
+	StateSyncMaxEventsPerBlock      map[string]uint64      `json:"stateSyncMaxEventsPerBlock"` // Maximum number of state sync events committed in a block (0 = no limit)
+	StateSyncAddressCheckBlock      *big.Int               `json:"stateSyncAddressCheckBlock"` // Block from which state sync events to the zero address are skipped (nil = never)
+	StateSyncWindowCheckBlock       *big.Int               `json:"stateSyncWindowCheckBlock"`  // Block from which state sync events outside the confirmation window fail the block being built (nil = never)
+	SystemSenderCheckBlock          *big.Int               `json:"systemSenderCheckBlock"`     // Block from which user transactions sent from the system address are invalid (nil = never)
+	SkipEmptyBlocks                 bool                   `json:"skipEmptyBlocks"`            // Allow producers to skip empty blocks, leaving gaps in block times (dev and app chains only)
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -862,6 +868,32 @@ func (c *BorConfig) CalculatePeriod(number uint64) uint64 {
 	return borKeyValueConfigHelper(c.Period, number)
 }
 
+// ValidateSchedules checks that the block timing schedules (period, producer delay,
+// sprint and backup multiplier) are keyed by block numbers, as the producer delay of
+// every header is verified against them. Zero sprint lengths are rejected as well.
+func (c *BorConfig) ValidateSchedules() error {
+	schedules := []struct {
+		name  string
+		field map[string]uint64
+	}{
+		{"period", c.Period},
+		{"producerDelay", c.ProducerDelay},
+		{"sprint", c.Sprint},
+		{"backupMultiplier", c.BackupMultiplier},
+	}
+	for _, schedule := range schedules {
+		for key, value := range schedule.field {
+			if _, err := strconv.ParseUint(key, 10, 64); err != nil {
+				return fmt.Errorf("invalid block number %q in %s schedule", key, schedule.name)
+			}
+			if schedule.name == "sprint" && value == 0 {
+				return fmt.Errorf("zero sprint length at block %s", key)
+			}
+		}
+	}
+	return nil
+}
+
 func (c *BorConfig) IsJaipur(number *big.Int) bool {
 	return isBlockForked(c.JaipurBlock, number)
 }
@@ -878,6 +910,45 @@ func (c *BorConfig) CalculateStateSyncDelay(number uint64) uint64 {
 	return borKeyValueConfigHelper(c.StateSyncConfirmationDelay, number)
 }
 
+// CalculateStateSyncMaxDataSize returns the maximum size of the data of a state sync event
+// committed in the given block, 0 meaning no limit.
+func (c *BorConfig) CalculateStateSyncMaxDataSize(number uint64) uint64 {
+	if len(c.StateSyncMaxDataSize) == 0 {
+		return 0
+	}
+
+	return borKeyValueConfigHelper(c.StateSyncMaxDataSize, number)
+}
+
+// CalculateStateSyncMaxEventsPerBlock returns the maximum number of state sync events
+// committed in the given block, 0 meaning no limit.
+func (c *BorConfig) CalculateStateSyncMaxEventsPerBlock(number uint64) uint64 {
+	if len(c.StateSyncMaxEventsPerBlock) == 0 {
+		return 0
+	}
+
+	return borKeyValueConfigHelper(c.StateSyncMaxEventsPerBlock, number)
+}
+
+// IsStateSyncAddressCheck returns whether state sync events to the zero address are
+// skipped in the given block.
+func (c *BorConfig) IsStateSyncAddressCheck(number *big.Int) bool {
+	return isBlockForked(c.StateSyncAddressCheckBlock, number)
+}
+
+// IsStateSyncWindowCheck returns whether a state sync event recorded after the end of
+// the confirmation window of the given block fails building it, instead of being left
+// for the next sprint.
+func (c *BorConfig) IsStateSyncWindowCheck(number *big.Int) bool {
+	return isBlockForked(c.StateSyncWindowCheckBlock, number)
+}
+
+// IsSystemSenderCheck returns whether user transactions sent from the system address
+// are invalid in the given block.
+func (c *BorConfig) IsSystemSenderCheck(number *big.Int) bool {
+	return isBlockForked(c.SystemSenderCheckBlock, number)
+}
+
 func (c *BorConfig) IsAhmedabad(number *big.Int) bool {
 	return isBlockForked(c.AhmedabadBlock, number)
 }
@@ -195,6 +195,28 @@ func TestBorKeyValueConfigHelper(t *testing.T) {
 	assert.Equal(t, borKeyValueConfigHelper(burntContract, 41824608+1), "0x617b94CCCC2511808A3C9478ebb96f455CF167aA")
 }
 
+func TestBorConfigValidateSchedules(t *testing.T) {
+	t.Parallel()
+
+	assert.NilError(t, BorMainnetChainConfig.Bor.ValidateSchedules())
+	assert.NilError(t, AmoyChainConfig.Bor.ValidateSchedules())
+
+	config := &BorConfig{
+		Period:           map[string]uint64{"0": 2},
+		ProducerDelay:    map[string]uint64{"0": 6},
+		Sprint:           map[string]uint64{"0": 16},
+		BackupMultiplier: map[string]uint64{"0": 2, "100": 4},
+	}
+	assert.NilError(t, config.ValidateSchedules())
+
+	config.BackupMultiplier["latest"] = 1
+	assert.ErrorContains(t, config.ValidateSchedules(), "backupMultiplier")
+
+	delete(config.BackupMultiplier, "latest")
+	config.Sprint["100"] = 0
+	assert.ErrorContains(t, config.ValidateSchedules(), "zero sprint length")
+}
+
 func TestOverrideStateSyncRecordsInRange(t *testing.T) {
 	t.Parallel()
 
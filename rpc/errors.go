@@ -16,7 +16,10 @@
 
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // HTTPError is returned by client operations when the HTTP status code of the
 // response is not a 2xx status.
@@ -57,6 +60,7 @@ var (
 	_ Error = new(invalidParamsError)
 	_ Error = new(internalServerError)
 	_ Error = new(CustomError)
+	_ Error = new(MethodTimeoutError)
 )
 
 const (
@@ -75,6 +79,19 @@ const (
 	errMsgBatchTooLarge    = "batch too large"
 )
 
+// MethodTimeoutError is returned when a method runs past the execution timeout
+// configured for it or its namespace.
+type MethodTimeoutError struct {
+	Method  string
+	Timeout time.Duration
+}
+
+func (e *MethodTimeoutError) ErrorCode() int { return errcodeTimeout }
+
+func (e *MethodTimeoutError) Error() string {
+	return fmt.Sprintf("%s: %s after %v", errMsgTimeout, e.Method, e.Timeout)
+}
+
 type methodNotFoundError struct{ method string }
 
 func (e *methodNotFoundError) ErrorCode() int { return -32601 }
@@ -548,8 +548,23 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
 
+	// Derive the context of the call from the execution timeout of the method, if any
+	ctx := cp.ctx
+	timeout, hasTimeout := h.reg.timeout(msg.Method)
+	if hasTimeout && callb != h.unsubscribeCb {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(cp.ctx, timeout)
+		defer cancel()
+	}
+
 	start := time.Now()
-	answer := h.runMethod(cp.ctx, msg, callb, args)
+	answer := h.runMethod(ctx, msg, callb, args)
+
+	// Report the methods which failed by running past their timeout
+	if hasTimeout && answer.Error != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && cp.ctx.Err() == nil {
+		answer = msg.errorResponse(&MethodTimeoutError{Method: msg.Method, Timeout: timeout})
+		updateMethodTimeouts(msg.Method)
+	}
 
 	// Collect the statistics for RPC calls if metrics is enabled.
 	// We only care about pure rpc call. Filter out subscription.
@@ -50,6 +50,12 @@ func updateServeTimeHistogram(method string, success bool, elapsed time.Duration
 	metrics.GetOrRegisterHistogramLazy(h, nil, sampler).Update(elapsed.Nanoseconds())
 }
 
+// updateMethodTimeouts counts the calls of a remote RPC method which ran past its
+// execution timeout.
+func updateMethodTimeouts(method string) {
+	metrics.GetOrRegisterCounter(fmt.Sprintf("rpc/timeouts/%s", method), nil).Inc(1)
+}
+
 func newEpMetrics(service string) (*metrics.Gauge, *metrics.Gauge, metrics.Histogram) {
 	epWorkerCount := fmt.Sprintf("rpc/ep/workers/%s", service)
 	epWaitingQueue := fmt.Sprintf("rpc/ep/queue/%s", service)
@@ -123,6 +123,17 @@ func (s *Server) SetHTTPBodyLimit(limit int) {
 	s.httpBodyLimit = limit
 }
 
+// SetMethodTimeouts sets the execution timeouts of the methods, keyed by method name
+// (e.g. "bor_getRootHash") or by namespace (e.g. "debug"). The timeout of a method takes
+// precedence over the one of its namespace. A method running past its timeout has its
+// context canceled and fails with a MethodTimeoutError.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetMethodTimeouts(timeouts map[string]time.Duration) {
+	s.services.setTimeouts(timeouts)
+}
+
 // RegisterName creates a service for the given receiver type under the given name. When no
 // methods on the given receiver match the criteria to be either an RPC method or a
 // subscription an error is returned. Otherwise a new service is created and added to the
@@ -23,9 +23,12 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
 )
 
 func TestServerRegisterName(t *testing.T) {
@@ -215,3 +218,59 @@ func TestServerBatchResponseSizeLimit(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the methods running past their configured execution timeout fail with a
+// timeout error, and that their calls don't outlive the timeout.
+// nolint : paralleltest
+func TestServerMethodTimeouts(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetMethodTimeouts(map[string]time.Duration{
+		"test":       time.Hour,
+		"test_block": 50 * time.Millisecond,
+	})
+	client := DialInProc(server)
+	defer client.Close()
+
+	var (
+		timeouts   = metrics.GetOrRegisterCounter("rpc/timeouts/test_block", nil)
+		before     = timeouts.Snapshot().Count()
+		goroutines = runtime.NumGoroutine()
+	)
+	for i := 0; i < 10; i++ {
+		start := time.Now()
+		err := client.Call(nil, "test_block")
+
+		re, ok := err.(Error)
+		if !ok {
+			t.Fatalf("call %d: wrong error: %v", i, err)
+		}
+		if re.ErrorCode() != errcodeTimeout {
+			t.Fatalf("call %d: wrong error code, have %d want %d", i, re.ErrorCode(), errcodeTimeout)
+		}
+		if !strings.Contains(re.Error(), "test_block") {
+			t.Fatalf("call %d: error doesn't name the method: %v", i, re)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Fatalf("call %d: timeout not enforced, took %v", i, elapsed)
+		}
+	}
+	if have := timeouts.Snapshot().Count(); have != before+10 {
+		t.Errorf("wrong timeout count, have %d want %d", have, before+10)
+	}
+
+	// The timeout of the namespace applies to the other methods
+	var result echoResult
+	if err := client.Call(&result, "test_echo", "x", 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The timed out calls don't leak goroutines
+	deadline := time.Now().Add(10 * time.Second)
+	for runtime.NumGoroutine() > goroutines {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines leaked: have %d, want at most %d", runtime.NumGoroutine(), goroutines)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
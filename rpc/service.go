@@ -23,6 +23,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 
 	"github.com/ethereum/go-ethereum/log"
@@ -38,6 +39,7 @@ var (
 type serviceRegistry struct {
 	mu       sync.Mutex
 	services map[string]service
+	timeouts map[string]time.Duration // execution timeouts by method or namespace
 }
 
 // service represents a registered object.
@@ -108,6 +110,37 @@ func (r *serviceRegistry) callback(method string) *callback {
 	return r.services[before].callbacks[after]
 }
 
+// setTimeouts sets the execution timeouts of the methods, keyed by method name or by
+// namespace.
+func (r *serviceRegistry) setTimeouts(timeouts map[string]time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.timeouts = timeouts
+}
+
+// timeout returns the execution timeout of the given RPC method. The timeout of the
+// method takes precedence over the one of its namespace.
+func (r *serviceRegistry) timeout(method string) (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if timeout, ok := r.timeouts[method]; ok && timeout > 0 {
+		return timeout, true
+	}
+
+	namespace, _, found := strings.Cut(method, serviceMethodSeparator)
+	if !found {
+		return 0, false
+	}
+
+	if timeout, ok := r.timeouts[namespace]; ok && timeout > 0 {
+		return timeout, true
+	}
+
+	return 0, false
+}
+
 // subscription returns a subscription callback in the given service.
 func (r *serviceRegistry) subscription(service, name string) *callback {
 	r.mu.Lock()
@@ -7,6 +7,7 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/big"
@@ -31,15 +32,20 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/downloader/whitelist"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/triedb"
 )
 
@@ -497,6 +503,260 @@ func TestFetchStateSyncEvents(t *testing.T) {
 	insertNewBlock(t, chain, block)
 }
 
+// TestStatelessSelfValidationWithStateSync tests that the witness of a sprint-boundary block
+// covers the state touched by the bor system calls (span commit and state sync) by replaying
+// it statelessly against the witness while inserting it.
+func TestStatelessSelfValidationWithStateSync(t *testing.T) {
+	t.Parallel()
+
+	stateSyncConfirmationDelay := int64(128)
+	updateGenesis := func(gen *core.Genesis) {
+		gen.Config.Bor.StateSyncConfirmationDelay = map[string]uint64{"0": uint64(stateSyncConfirmationDelay)}
+		gen.Config.Bor.Sprint = map[string]uint64{"0": sprintSize}
+	}
+	init := buildEthereumInstance(t, rawdb.NewMemoryDatabase(), updateGenesis)
+	chain := init.ethereum.BlockChain()
+	engine := init.ethereum.Engine()
+	_bor := engine.(*bor.Bor)
+	defer _bor.Close()
+
+	// Generate a witness for every inserted block and cross-check it statelessly
+	chain.GetVMConfig().StatelessSelfValidation = true
+
+	block := init.genesis.ToBlock()
+
+	span0 := createMockSpan(addr, chain.Config().ChainID.String())
+	borValSet := borSpan.ConvertHeimdallValSetToBorValSet(span0.ValidatorSet)
+	currentValidators := borValSet.Validators
+
+	res := loadSpanFromFile(t)
+
+	spanner := getMockedSpanner(t, currentValidators)
+	_bor.SetSpanner(spanner)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	h := createMockHeimdall(ctrl, &span0, res)
+
+	// Same timing assumptions as in TestFetchStateSyncEvents
+	fromID := uint64(1)
+	to := int64(chain.GetHeaderByNumber(0).Time) + 9 - stateSyncConfirmationDelay
+	eventCount := 5
+
+	sample := getSampleEventRecord(t)
+	sample.Time = time.Unix(to-int64(eventCount+1), 0)
+	eventRecords := generateFakeStateSyncEvents(sample, eventCount)
+
+	h.EXPECT().StateSyncEvents(gomock.Any(), fromID, to).Return(eventRecords, nil).AnyTimes()
+	h.EXPECT().GetLatestSpan(gomock.Any()).Return(nil, fmt.Errorf("span not found")).AnyTimes()
+	_bor.SetHeimdallClient(h)
+
+	for i := uint64(1); i < sprintSize; i++ {
+		block = buildNextBlock(t, _bor, chain, block, nil, init.genesis.Config.Bor, nil, currentValidators, false)
+		insertNewBlock(t, chain, block)
+	}
+
+	// The sprint-boundary block executes the state sync system calls. Its insertion fails
+	// if the stateless replay doesn't arrive at the same state root.
+	block = buildNextBlock(t, _bor, chain, block, nil, init.genesis.Config.Bor, nil, borValSet.Validators, false)
+	validateStateSyncEvents(t, eventRecords, chain.GetStateSync())
+
+	insertNewBlock(t, chain, block)
+	require.Equal(t, block.Hash(), chain.CurrentBlock().Hash())
+}
+
+// TestTraceBorBlockStateSync tests that tracing a sprint-boundary block replays and traces
+// each state sync system call separately.
+func TestTraceBorBlockStateSync(t *testing.T) {
+	t.Parallel()
+
+	stateSyncConfirmationDelay := int64(128)
+	updateGenesis := func(gen *core.Genesis) {
+		gen.Config.Bor.StateSyncConfirmationDelay = map[string]uint64{"0": uint64(stateSyncConfirmationDelay)}
+		gen.Config.Bor.Sprint = map[string]uint64{"0": sprintSize}
+	}
+	init := buildEthereumInstance(t, rawdb.NewMemoryDatabase(), updateGenesis)
+	chain := init.ethereum.BlockChain()
+	engine := init.ethereum.Engine()
+	_bor := engine.(*bor.Bor)
+	defer _bor.Close()
+
+	block := init.genesis.ToBlock()
+
+	span0 := createMockSpan(addr, chain.Config().ChainID.String())
+	borValSet := borSpan.ConvertHeimdallValSetToBorValSet(span0.ValidatorSet)
+	currentValidators := borValSet.Validators
+
+	res := loadSpanFromFile(t)
+
+	spanner := getMockedSpanner(t, currentValidators)
+	_bor.SetSpanner(spanner)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	h := createMockHeimdall(ctrl, &span0, res)
+
+	// Same timing assumptions as in TestFetchStateSyncEvents
+	fromID := uint64(1)
+	to := int64(chain.GetHeaderByNumber(0).Time) + 9 - stateSyncConfirmationDelay
+	eventCount := 5
+
+	sample := getSampleEventRecord(t)
+	sample.Time = time.Unix(to-int64(eventCount+1), 0)
+	eventRecords := generateFakeStateSyncEvents(sample, eventCount)
+
+	h.EXPECT().StateSyncEvents(gomock.Any(), fromID, to).Return(eventRecords, nil).AnyTimes()
+	h.EXPECT().GetLatestSpan(gomock.Any()).Return(nil, fmt.Errorf("span not found")).AnyTimes()
+	_bor.SetHeimdallClient(h)
+
+	for i := uint64(1); i < sprintSize; i++ {
+		block = buildNextBlock(t, _bor, chain, block, nil, init.genesis.Config.Bor, nil, currentValidators, false)
+		insertNewBlock(t, chain, block)
+	}
+
+	block = buildNextBlock(t, _bor, chain, block, nil, init.genesis.Config.Bor, nil, borValSet.Validators, false)
+	insertNewBlock(t, chain, block)
+
+	api := tracers.NewAPI(init.ethereum.APIBackend)
+	callTracer := "callTracer"
+
+	result, err := api.TraceBorBlock(context.Background(), rpc.BlockNumberOrHashWithHash(block.Hash(), true), &tracers.TraceConfig{Tracer: &callTracer})
+	require.NoError(t, err)
+	require.Empty(t, result.Transactions)
+	require.Len(t, result.SystemCalls, eventCount)
+
+	stateReceiver := common.HexToAddress(init.genesis.Config.Bor.StateReceiverContract)
+
+	for i, call := range result.SystemCalls {
+		require.Equal(t, "commitState", call.Type)
+		require.Nil(t, call.SpanID)
+		require.NotNil(t, call.StateSyncID)
+		require.Equal(t, eventRecords[i].ID, *call.StateSyncID)
+		require.Empty(t, call.Error)
+
+		var frame struct {
+			From common.Address `json:"from"`
+			To   common.Address `json:"to"`
+		}
+		require.NoError(t, json.Unmarshal(call.Result.(json.RawMessage), &frame))
+		require.Equal(t, stateReceiver, frame.To)
+	}
+
+	// Blocks without system calls only trace their transactions
+	result, err = api.TraceBorBlock(context.Background(), rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(sprintSize-1)), nil)
+	require.NoError(t, err)
+	require.Empty(t, result.SystemCalls)
+}
+
+// TestBorTracerHooks tests that a live tracer is notified of the span commit and the state
+// syncs of an imported sprint-boundary block, and of the whitelisted milestones.
+func TestBorTracerHooks(t *testing.T) {
+	t.Parallel()
+
+	stateSyncConfirmationDelay := int64(128)
+	updateGenesis := func(gen *core.Genesis) {
+		gen.Config.Bor.StateSyncConfirmationDelay = map[string]uint64{"0": uint64(stateSyncConfirmationDelay)}
+		gen.Config.Bor.Sprint = map[string]uint64{"0": sprintSize}
+	}
+	init := buildEthereumInstance(t, rawdb.NewMemoryDatabase(), updateGenesis)
+	chain := init.ethereum.BlockChain()
+	engine := init.ethereum.Engine()
+	_bor := engine.(*bor.Bor)
+	defer _bor.Close()
+
+	type spanCommit struct {
+		id, start, end uint64
+		producers      []common.Address
+	}
+	type stateSync struct {
+		id, number uint64
+		success    bool
+	}
+	type milestone struct {
+		number uint64
+		hash   common.Hash
+	}
+	var (
+		spans      []spanCommit
+		stateSyncs []stateSync
+		milestones []milestone
+	)
+	hooks := &tracing.Hooks{
+		OnSpanCommitted: func(spanId uint64, startBlock uint64, endBlock uint64, producers []common.Address) {
+			spans = append(spans, spanCommit{spanId, startBlock, endBlock, producers})
+		},
+		OnStateSyncApplied: func(eventId uint64, blockNumber uint64, success bool) {
+			stateSyncs = append(stateSyncs, stateSync{eventId, blockNumber, success})
+		},
+		OnMilestoneProcessed: func(number uint64, hash common.Hash) {
+			milestones = append(milestones, milestone{number, hash})
+		},
+	}
+	_bor.SetTracer(hooks)
+
+	checker := init.ethereum.Downloader().ChainValidator.(*whitelist.Service)
+	checker.SetTracer(hooks)
+
+	block := init.genesis.ToBlock()
+
+	span0 := createMockSpan(addr, chain.Config().ChainID.String())
+	borValSet := borSpan.ConvertHeimdallValSetToBorValSet(span0.ValidatorSet)
+	currentValidators := borValSet.Validators
+
+	res := loadSpanFromFile(t)
+
+	spanner := getMockedSpanner(t, currentValidators)
+	_bor.SetSpanner(spanner)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	h := createMockHeimdall(ctrl, &span0, res)
+
+	// Same timing assumptions as in TestFetchStateSyncEvents
+	fromID := uint64(1)
+	to := int64(chain.GetHeaderByNumber(0).Time) + 9 - stateSyncConfirmationDelay
+	eventCount := 5
+
+	sample := getSampleEventRecord(t)
+	sample.Time = time.Unix(to-int64(eventCount+1), 0)
+	eventRecords := generateFakeStateSyncEvents(sample, eventCount)
+
+	h.EXPECT().StateSyncEvents(gomock.Any(), fromID, to).Return(eventRecords, nil).AnyTimes()
+	h.EXPECT().GetLatestSpan(gomock.Any()).Return(nil, fmt.Errorf("span not found")).AnyTimes()
+	_bor.SetHeimdallClient(h)
+
+	for i := uint64(1); i < sprintSize; i++ {
+		block = buildNextBlock(t, _bor, chain, block, nil, init.genesis.Config.Bor, nil, currentValidators, false)
+		insertNewBlock(t, chain, block)
+	}
+	// Building the blocks doesn't notify the tracer, only importing them does
+	block = buildNextBlock(t, _bor, chain, block, nil, init.genesis.Config.Bor, nil, borValSet.Validators, false)
+	require.Empty(t, spans)
+	require.Empty(t, stateSyncs)
+
+	insertNewBlock(t, chain, block)
+
+	producers := make([]common.Address, len(res.SelectedProducers))
+	for i, producer := range res.SelectedProducers {
+		producers[i] = common.HexToAddress(producer.Signer)
+	}
+	require.Equal(t, []spanCommit{{res.Id, res.StartBlock, res.EndBlock, producers}}, spans)
+
+	require.Len(t, stateSyncs, eventCount)
+	for i, stateSync := range stateSyncs {
+		require.Equal(t, eventRecords[i].ID, stateSync.id)
+		require.Equal(t, sprintSize, stateSync.number)
+		require.True(t, stateSync.success)
+	}
+
+	// Whitelisting a milestone notifies the tracer
+	checker.ProcessMilestone("milestone", block.NumberU64(), block.Hash())
+	require.Equal(t, []milestone{{block.NumberU64(), block.Hash()}}, milestones)
+}
+
 func validateStateSyncEvents(t *testing.T, expected []*clerk.EventRecordWithTime, got []*types.StateSyncData) {
 	require.Equal(t, len(expected), len(got), "number of state sync events should be equal")
 
@@ -628,6 +888,8 @@ func TestOutOfTurnSigning(t *testing.T) {
 	updateGenesis := func(gen *core.Genesis) {
 		gen.Config.Bor.StateSyncConfirmationDelay = map[string]uint64{"0": 128}
 		gen.Config.Bor.Sprint = map[string]uint64{"0": sprintSize}
+		// Backup producers wait longer from the out-of-turn block onwards
+		gen.Config.Bor.BackupMultiplier = map[string]uint64{"0": 1, strconv.FormatUint(spanSize, 10): 3}
 	}
 	init := buildEthereumInstance(t, rawdb.NewMemoryDatabase(), updateGenesis)
 	chain := init.ethereum.BlockChain()
@@ -704,7 +966,14 @@ func TestOutOfTurnSigning(t *testing.T) {
 	block = buildNextBlock(t, _bor, chain, block, signerKey, init.genesis.Config.Bor, nil, borSpan.ConvertHeimdallValidatorsToBorValidatorsByRef(res.ValidatorSet.Validators), false, setParentTime, setDifficulty)
 	_, err := chain.InsertChain([]*types.Block{block})
 	require.Equal(t,
-		bor.BlockTooSoonError{Number: spanSize, Succession: expectedSuccessionNumber},
+		bor.BlockTooSoonError{
+			Number:           spanSize,
+			Succession:       expectedSuccessionNumber,
+			Time:             parentTime + 1,
+			ParentTime:       parentTime,
+			Delay:            6, // Producer delay of the sprint start
+			BackupMultiplier: 3, // Overridden from the out-of-turn block onwards
+		},
 		*err.(*bor.BlockTooSoonError))
 
 	expectedDifficulty := uint64(len(res.ValidatorSet.Validators) - expectedSuccessionNumber - turn) // len(validators) - succession
@@ -1886,7 +2155,15 @@ func TestEarlyBlockAnnouncementPostBhilai_NonPrimary(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 
 	err = engine.VerifyHeader(chain, block.Header())
+	parent := chain.GetHeaderByNumber(3)
 	require.Equal(t,
-		bor.BlockTooSoonError{Number: 4, Succession: 2},
+		bor.BlockTooSoonError{
+			Number:           4,
+			Succession:       2,
+			Time:             block.Time(),
+			ParentTime:       parent.Time,
+			Delay:            bor.CalcProducerDelay(4, 0, init.genesis.Config.Bor),
+			BackupMultiplier: init.genesis.Config.Bor.CalculateBackupMultiplier(4),
+		},
 		*err.(*bor.BlockTooSoonError))
 }
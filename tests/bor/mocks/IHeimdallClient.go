@@ -110,6 +110,21 @@ func (mr *MockIHeimdallClientMockRecorder) FetchMilestoneCount(ctx interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchMilestoneCount", reflect.TypeOf((*MockIHeimdallClient)(nil).FetchMilestoneCount), ctx)
 }
 
+// FetchMilestoneList mocks base method.
+func (m *MockIHeimdallClient) FetchMilestoneList(ctx context.Context, fromNumber, toNumber int64) ([]*milestone.Milestone, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchMilestoneList", ctx, fromNumber, toNumber)
+	ret0, _ := ret[0].([]*milestone.Milestone)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchMilestoneList indicates an expected call of FetchMilestoneList.
+func (mr *MockIHeimdallClientMockRecorder) FetchMilestoneList(ctx, fromNumber, toNumber interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchMilestoneList", reflect.TypeOf((*MockIHeimdallClient)(nil).FetchMilestoneList), ctx, fromNumber, toNumber)
+}
+
 // GetLatestSpan mocks base method.
 func (m *MockIHeimdallClient) GetLatestSpan(ctx context.Context) (*types.Span, error) {
 	m.ctrl.T.Helper()